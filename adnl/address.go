@@ -0,0 +1,77 @@
+package adnl
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net"
+	"strconv"
+
+	"github.com/tonkeeper/tongo/config"
+)
+
+// AddressListFromTL converts adnl.addressList to the form used by the global config.
+func AddressListFromTL(list AdnlAddressListC) (config.AddressList, error) {
+	res := config.AddressList{
+		Version:    int32(list.Version),
+		ReinitDate: int32(list.ReinitDate),
+		Priority:   int32(list.Priority),
+		ExpireAt:   int32(list.ExpireAt),
+	}
+	for _, addr := range list.Addrs {
+		var ip net.IP
+		var port uint32
+		switch addr.SumType {
+		case "AdnlAddressUdp":
+			ip = make(net.IP, net.IPv4len)
+			binary.BigEndian.PutUint32(ip, addr.AdnlAddressUdp.Ip)
+			port = addr.AdnlAddressUdp.Port
+		case "AdnlAddressUdp6":
+			ip = append(net.IP{}, addr.AdnlAddressUdp6.Ip[:]...)
+			port = addr.AdnlAddressUdp6.Port
+		default:
+			return config.AddressList{}, fmt.Errorf("unsupported address type: %v", addr.SumType)
+		}
+		if port == 0 || port > 0xFFFF {
+			return config.AddressList{}, fmt.Errorf("invalid port: %v", port)
+		}
+		res.Addrs = append(res.Addrs, net.JoinHostPort(ip.String(), strconv.Itoa(int(port))))
+	}
+	return res, nil
+}
+
+// AddressListToTL converts an address list of the global config to adnl.addressList.
+func AddressListToTL(list config.AddressList) (AdnlAddressListC, error) {
+	res := AdnlAddressListC{
+		Addrs:      []AdnlAddress{},
+		Version:    uint32(list.Version),
+		ReinitDate: uint32(list.ReinitDate),
+		Priority:   uint32(list.Priority),
+		ExpireAt:   uint32(list.ExpireAt),
+	}
+	for _, a := range list.Addrs {
+		host, portStr, err := net.SplitHostPort(a)
+		if err != nil {
+			return AdnlAddressListC{}, err
+		}
+		port, err := strconv.ParseUint(portStr, 10, 16)
+		if err != nil {
+			return AdnlAddressListC{}, fmt.Errorf("invalid port: %v", portStr)
+		}
+		ip := net.ParseIP(host)
+		if ip == nil {
+			return AdnlAddressListC{}, fmt.Errorf("invalid ip: %v", host)
+		}
+		var addr AdnlAddress
+		if ip4 := ip.To4(); ip4 != nil {
+			addr.SumType = "AdnlAddressUdp"
+			addr.AdnlAddressUdp.Ip = binary.BigEndian.Uint32(ip4)
+			addr.AdnlAddressUdp.Port = uint32(port)
+		} else {
+			addr.SumType = "AdnlAddressUdp6"
+			copy(addr.AdnlAddressUdp6.Ip[:], ip.To16())
+			addr.AdnlAddressUdp6.Port = uint32(port)
+		}
+		res.Addrs = append(res.Addrs, addr)
+	}
+	return res, nil
+}
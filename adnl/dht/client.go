@@ -0,0 +1,390 @@
+package dht
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/tonkeeper/tongo/adnl"
+	"github.com/tonkeeper/tongo/config"
+	"github.com/tonkeeper/tongo/tl"
+)
+
+const (
+	defaultK            = 10
+	defaultA            = 3
+	defaultQueryTimeout = 3 * time.Second
+	// addressKeyName is a name of a key an ADNL node stores its address list under.
+	addressKeyName = "address"
+	// overlayNodesKeyName is a name of a key nodes of an overlay are stored under.
+	overlayNodesKeyName = "nodes"
+)
+
+// ErrNotFound means that none of the queried DHT nodes has a value.
+var ErrNotFound = errors.New("dht value not found")
+
+type node struct {
+	id    tl.Int256
+	key   ed25519.PublicKey
+	addrs []string
+}
+
+// Client is a Kademlia client of the TON DHT.
+// It starts lookups from static nodes of the global config and remembers nodes it learns about.
+type Client struct {
+	gateway      *adnl.Gateway
+	k            int
+	a            int
+	queryTimeout time.Duration
+
+	// mu protects nodes.
+	mu    sync.Mutex
+	nodes map[tl.Int256]node
+}
+
+// NewClient returns a DHT client sending queries through the given gateway.
+// Static nodes with invalid signatures are ignored, at least one valid node is required.
+func NewClient(gateway *adnl.Gateway, conf config.DHTConfig) (*Client, error) {
+	c := &Client{
+		gateway:      gateway,
+		k:            conf.K,
+		a:            conf.A,
+		queryTimeout: defaultQueryTimeout,
+		nodes:        make(map[tl.Int256]node),
+	}
+	if c.k <= 0 {
+		c.k = defaultK
+	}
+	if c.a <= 0 {
+		c.a = defaultA
+	}
+	for _, n := range conf.StaticNodes {
+		dhtNode, err := nodeFromConfig(n)
+		if err != nil {
+			continue
+		}
+		c.addNode(dhtNode)
+	}
+	if len(c.nodes) == 0 {
+		return nil, fmt.Errorf("no valid DHT nodes in config")
+	}
+	return c, nil
+}
+
+// nodeFromConfig converts a static node of the global config to its TL form covered by the node's signature.
+func nodeFromConfig(n config.DHTNode) (adnl.DhtNodeC, error) {
+	addrList, err := adnl.AddressListToTL(n.AddrList)
+	if err != nil {
+		return adnl.DhtNodeC{}, err
+	}
+	return adnl.DhtNodeC{
+		Id:        adnl.PublicKeyEd25519(n.Key),
+		AddrList:  addrList,
+		Version:   uint32(n.Version),
+		Signature: n.Signature,
+	}, nil
+}
+
+// addNode verifies a node and adds it to the list of known nodes.
+func (c *Client) addNode(n adnl.DhtNodeC) (node, bool) {
+	if err := checkNode(n); err != nil {
+		return node{}, false
+	}
+	addrs, err := adnl.AddressListFromTL(n.AddrList)
+	if err != nil || len(addrs.Addrs) == 0 {
+		return node{}, false
+	}
+	id, err := adnl.ShortID(n.Id)
+	if err != nil {
+		return node{}, false
+	}
+	if id == c.gateway.ID() {
+		return node{}, false
+	}
+	key, err := adnl.Ed25519Key(n.Id)
+	if err != nil {
+		return node{}, false
+	}
+	res := node{id: id, key: key, addrs: addrs.Addrs}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.nodes[id] = res
+	return res, true
+}
+
+// FindValue looks up a value by its key.
+func (c *Client) FindValue(ctx context.Context, key Key) (Value, error) {
+	hash, err := key.Hash()
+	if err != nil {
+		return Value{}, err
+	}
+	request, err := adnl.MarshalBoxed(tagFindValue, adnl.DhtFindValueRequest{Key: hash, K: uint32(c.k)})
+	if err != nil {
+		return Value{}, err
+	}
+	var (
+		mu    sync.Mutex
+		found *Value
+	)
+	_, err = c.lookup(ctx, hash, func(ctx context.Context, n node) ([]adnl.DhtNodeC, bool, error) {
+		resp, err := c.query(ctx, n, request)
+		if err != nil {
+			return nil, false, err
+		}
+		var res adnl.DhtValueResult
+		if err := tl.Unmarshal(bytes.NewReader(resp), &res); err != nil {
+			return nil, false, err
+		}
+		if res.SumType == "DhtValueNotFound" {
+			return res.DhtValueNotFound.Nodes.Nodes, false, nil
+		}
+		value, err := checkValue(adnl.DhtValueC(res.DhtValueFound.Value), hash, time.Now())
+		if err != nil {
+			return nil, false, err
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		if found == nil || value.ExpireAt.After(found.ExpireAt) {
+			found = &value
+		}
+		return nil, true, nil
+	})
+	if err != nil {
+		return Value{}, err
+	}
+	if found == nil {
+		return Value{}, ErrNotFound
+	}
+	return *found, nil
+}
+
+// Store signs a value with the owner's key and stores it on the K nodes closest to the value's key.
+// The key's ID is the owner's ADNL address.
+// Store succeeds if at least one node has stored the value.
+func (c *Client) Store(ctx context.Context, owner ed25519.PrivateKey, name string, index uint32, data []byte, ttl time.Duration) error {
+	value, err := newValue(owner, name, index, data, time.Now().Add(ttl))
+	if err != nil {
+		return err
+	}
+	hash, err := keyHash(value.Key.Key)
+	if err != nil {
+		return err
+	}
+	request, err := adnl.MarshalBoxed(tagFindNode, adnl.DhtFindNodeRequest{Key: hash, K: uint32(c.k)})
+	if err != nil {
+		return err
+	}
+	closest, err := c.lookup(ctx, hash, func(ctx context.Context, n node) ([]adnl.DhtNodeC, bool, error) {
+		resp, err := c.query(ctx, n, request)
+		if err != nil {
+			return nil, false, err
+		}
+		var res adnl.DhtNodesC
+		if err := adnl.UnmarshalBoxed(resp, tagNodes, &res); err != nil {
+			return nil, false, err
+		}
+		return res.Nodes, false, nil
+	})
+	if err != nil {
+		return err
+	}
+	if len(closest) == 0 {
+		return fmt.Errorf("no DHT nodes answered")
+	}
+	store, err := adnl.MarshalBoxed(tagStore, adnl.DhtStoreRequest{Value: value})
+	if err != nil {
+		return err
+	}
+	var (
+		wg     sync.WaitGroup
+		mu     sync.Mutex
+		stored int
+		errs   []error
+	)
+	for _, n := range closest {
+		wg.Add(1)
+		go func(n node) {
+			defer wg.Done()
+			resp, err := c.query(ctx, n, store)
+			if err == nil {
+				err = adnl.UnmarshalBoxed(resp, tagStored, &adnl.DhtStoredC{})
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				errs = append(errs, err)
+				return
+			}
+			stored++
+		}(n)
+	}
+	wg.Wait()
+	if stored == 0 {
+		return fmt.Errorf("value is not stored: %w", errs[0])
+	}
+	return nil
+}
+
+// FindAddresses resolves an ADNL address to UDP addresses of the node and returns the node's public key.
+// It can be used to connect to a node of a DNSAdnlAddress record of tlb.DNSRecord.
+func (c *Client) FindAddresses(ctx context.Context, id tl.Int256) (config.AddressList, ed25519.PublicKey, error) {
+	value, err := c.FindValue(ctx, Key{ID: id, Name: addressKeyName})
+	if err != nil {
+		return config.AddressList{}, nil, err
+	}
+	var list adnl.AdnlAddressListC
+	if err := adnl.UnmarshalBoxed(value.Data, tagAddressList, &list); err != nil {
+		return config.AddressList{}, nil, err
+	}
+	addrs, err := adnl.AddressListFromTL(list)
+	if err != nil {
+		return config.AddressList{}, nil, err
+	}
+	if len(addrs.Addrs) == 0 {
+		return config.AddressList{}, nil, fmt.Errorf("node has no addresses")
+	}
+	return addrs, value.Owner, nil
+}
+
+// FindOverlayNodes returns nodes of an overlay network with the given short ID, nodes with invalid signatures are skipped.
+// Addresses of the nodes can be resolved with FindAddresses.
+func (c *Client) FindOverlayNodes(ctx context.Context, overlay tl.Int256) ([]adnl.OverlayNodeC, error) {
+	value, err := c.FindValue(ctx, Key{ID: overlay, Name: overlayNodesKeyName})
+	if err != nil {
+		return nil, err
+	}
+	var list adnl.OverlayNodesC
+	if err := adnl.UnmarshalBoxed(value.Data, tagOverlayNodes, &list); err != nil {
+		return nil, err
+	}
+	var nodes []adnl.OverlayNodeC
+	for _, n := range list.Nodes {
+		if err := checkOverlayNode(n, overlay); err == nil {
+			nodes = append(nodes, n)
+		}
+	}
+	if len(nodes) == 0 {
+		return nil, ErrNotFound
+	}
+	return nodes, nil
+}
+
+// StoreAddresses publishes UDP addresses of the owner's ADNL node, so other nodes can find them with FindAddresses.
+func (c *Client) StoreAddresses(ctx context.Context, owner ed25519.PrivateKey, addrs config.AddressList, ttl time.Duration) error {
+	list, err := adnl.AddressListToTL(addrs)
+	if err != nil {
+		return err
+	}
+	data, err := adnl.MarshalBoxed(tagAddressList, list)
+	if err != nil {
+		return err
+	}
+	return c.Store(ctx, owner, addressKeyName, 0, data, ttl)
+}
+
+// lookup queries nodes closest to the hash, up to A nodes at once, moving closer with nodes returned by queries.
+// It stops when a query reports it is done or when none of the K closest known nodes is left to query,
+// and returns the K closest nodes which have answered.
+func (c *Client) lookup(ctx context.Context, hash tl.Int256, query func(ctx context.Context, n node) ([]adnl.DhtNodeC, bool, error)) ([]node, error) {
+	c.mu.Lock()
+	candidates := make([]node, 0, len(c.nodes))
+	for _, n := range c.nodes {
+		candidates = append(candidates, n)
+	}
+	c.mu.Unlock()
+
+	seen := make(map[tl.Int256]struct{}, len(candidates))
+	for _, n := range candidates {
+		seen[n.id] = struct{}{}
+	}
+	queried := make(map[tl.Int256]struct{})
+	var answered []node
+	for {
+		sortByDistance(candidates, hash)
+		var batch []node
+		for i := 0; i < len(candidates) && i < c.k && len(batch) < c.a; i++ {
+			if _, ok := queried[candidates[i].id]; !ok {
+				batch = append(batch, candidates[i])
+				queried[candidates[i].id] = struct{}{}
+			}
+		}
+		if len(batch) == 0 {
+			break
+		}
+		var (
+			wg    sync.WaitGroup
+			mu    sync.Mutex
+			found []adnl.DhtNodeC
+			done  bool
+		)
+		for _, n := range batch {
+			wg.Add(1)
+			go func(n node) {
+				defer wg.Done()
+				nodes, ok, err := query(ctx, n)
+				if err != nil {
+					return
+				}
+				mu.Lock()
+				defer mu.Unlock()
+				answered = append(answered, n)
+				found = append(found, nodes...)
+				done = done || ok
+			}(n)
+		}
+		wg.Wait()
+		if done {
+			return nil, nil
+		}
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		for _, f := range found {
+			n, ok := c.addNode(f)
+			if !ok {
+				continue
+			}
+			if _, ok := seen[n.id]; ok {
+				continue
+			}
+			seen[n.id] = struct{}{}
+			candidates = append(candidates, n)
+		}
+	}
+	sortByDistance(answered, hash)
+	if len(answered) > c.k {
+		answered = answered[:c.k]
+	}
+	return answered, nil
+}
+
+// query sends a query to the first address of a node.
+func (c *Client) query(ctx context.Context, n node, request []byte) ([]byte, error) {
+	peer, err := c.gateway.Connect(n.key, n.addrs[0])
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(ctx, c.queryTimeout)
+	defer cancel()
+	return peer.Query(ctx, request)
+}
+
+// sortByDistance sorts nodes by XOR distance between their IDs and the hash.
+func sortByDistance(nodes []node, hash tl.Int256) {
+	sort.SliceStable(nodes, func(i, j int) bool {
+		return bytes.Compare(distance(nodes[i].id, hash), distance(nodes[j].id, hash)) < 0
+	})
+}
+
+func distance(a, b tl.Int256) []byte {
+	res := make([]byte, len(a))
+	for i := range a {
+		res[i] = a[i] ^ b[i]
+	}
+	return res
+}
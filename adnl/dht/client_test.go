@@ -0,0 +1,398 @@
+package dht
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/tonkeeper/tongo/adnl"
+	"github.com/tonkeeper/tongo/config"
+	"github.com/tonkeeper/tongo/tl"
+)
+
+// testNode is a DHT node answering queries with values it stores and nodes it knows.
+type testNode struct {
+	gateway *adnl.Gateway
+	key     ed25519.PrivateKey
+	info    adnl.DhtNodeC
+
+	mu     sync.Mutex
+	known  []adnl.DhtNodeC
+	values map[tl.Int256]adnl.DhtValueC
+}
+
+func startTestNode(t *testing.T) *testNode {
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey() failed: %v", err)
+	}
+	gateway, err := adnl.Listen("127.0.0.1:0", key)
+	if err != nil {
+		t.Fatalf("Listen() failed: %v", err)
+	}
+	t.Cleanup(func() { gateway.Close() })
+	addrList, err := adnl.AddressListToTL(config.AddressList{Addrs: []string{gateway.Addr().String()}})
+	if err != nil {
+		t.Fatalf("AddressListToTL() failed: %v", err)
+	}
+	info := adnl.DhtNodeC{
+		Id:       adnl.PublicKeyEd25519(key.Public().(ed25519.PublicKey)),
+		AddrList: addrList,
+		Version:  uint32(time.Now().Unix()),
+	}
+	b, err := adnl.MarshalBoxed(tagNode, info)
+	if err != nil {
+		t.Fatalf("MarshalBoxed() failed: %v", err)
+	}
+	info.Signature = ed25519.Sign(key, b)
+	n := &testNode{
+		gateway: gateway,
+		key:     key,
+		info:    info,
+		values:  map[tl.Int256]adnl.DhtValueC{},
+	}
+	gateway.SetHandler(n)
+	return n
+}
+
+func (n *testNode) configNode() config.DHTNode {
+	addrList, _ := adnl.AddressListFromTL(n.info.AddrList)
+	return config.DHTNode{
+		Key:       n.key.Public().(ed25519.PublicKey),
+		AddrList:  addrList,
+		Version:   int32(n.info.Version),
+		Signature: n.info.Signature,
+	}
+}
+
+func (n *testNode) HandleQuery(ctx context.Context, peer *adnl.Peer, query []byte) ([]byte, error) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if len(query) < 4 {
+		return nil, fmt.Errorf("invalid query")
+	}
+	switch binary.LittleEndian.Uint32(query) {
+	case tagFindValue:
+		var req adnl.DhtFindValueRequest
+		if err := adnl.UnmarshalBoxed(query, tagFindValue, &req); err != nil {
+			return nil, err
+		}
+		var res adnl.DhtValueResult
+		if value, ok := n.values[req.Key]; ok {
+			res.SumType = "DhtValueFound"
+			res.DhtValueFound.Value = adnl.DhtValue(value)
+		} else {
+			res.SumType = "DhtValueNotFound"
+			res.DhtValueNotFound.Nodes.Nodes = n.known
+		}
+		return tl.Marshal(res)
+	case tagFindNode:
+		return adnl.MarshalBoxed(tagNodes, adnl.DhtNodesC{Nodes: n.known})
+	case tagStore:
+		var req adnl.DhtStoreRequest
+		if err := adnl.UnmarshalBoxed(query, tagStore, &req); err != nil {
+			return nil, err
+		}
+		hash, err := keyHash(req.Value.Key.Key)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := checkValue(req.Value, hash, time.Now()); err != nil {
+			return nil, err
+		}
+		n.values[hash] = req.Value
+		return adnl.MarshalBoxed(tagStored, adnl.DhtStoredC{})
+	}
+	return nil, fmt.Errorf("unknown query")
+}
+
+func (n *testNode) HandleCustomMessage(peer *adnl.Peer, data []byte) {}
+
+func (n *testNode) setKnown(nodes ...*testNode) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	for _, k := range nodes {
+		n.known = append(n.known, k.info)
+	}
+}
+
+func (n *testNode) storedValues() int {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return len(n.values)
+}
+
+func newTestClient(t *testing.T, nodes ...*testNode) *Client {
+	gateway, err := adnl.Listen("127.0.0.1:0", nil)
+	if err != nil {
+		t.Fatalf("Listen() failed: %v", err)
+	}
+	t.Cleanup(func() { gateway.Close() })
+	conf := config.DHTConfig{K: 6, A: 3}
+	for _, n := range nodes {
+		conf.StaticNodes = append(conf.StaticNodes, n.configNode())
+	}
+	c, err := NewClient(gateway, conf)
+	if err != nil {
+		t.Fatalf("NewClient() failed: %v", err)
+	}
+	c.queryTimeout = time.Second
+	return c
+}
+
+func TestClient_FindValue(t *testing.T) {
+	first, second := startTestNode(t), startTestNode(t)
+	// only the first node is known to the client, it knows the second one
+	first.setKnown(second)
+
+	_, owner, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey() failed: %v", err)
+	}
+	value, err := newValue(owner, "test", 1, []byte("data"), time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatalf("newValue() failed: %v", err)
+	}
+	hash, err := keyHash(value.Key.Key)
+	if err != nil {
+		t.Fatalf("keyHash() failed: %v", err)
+	}
+	second.mu.Lock()
+	second.values[hash] = value
+	second.mu.Unlock()
+
+	c := newTestClient(t, first)
+	key := Key{ID: value.Key.Key.Id, Name: "test", Index: 1}
+	res, err := c.FindValue(context.Background(), key)
+	if err != nil {
+		t.Fatalf("FindValue() failed: %v", err)
+	}
+	if string(res.Data) != "data" || res.Key != key || !res.Owner.Equal(owner.Public()) {
+		t.Fatalf("unexpected value: %+v", res)
+	}
+
+	key.Index = 2
+	if _, err := c.FindValue(context.Background(), key); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("want ErrNotFound, got %v", err)
+	}
+
+	// a value signed by somebody else is rejected
+	_, other, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey() failed: %v", err)
+	}
+	forged, err := newValue(other, "test", 1, []byte("forged"), time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatalf("newValue() failed: %v", err)
+	}
+	forged.Key.Key = value.Key.Key
+	if _, err := checkValue(forged, hash, time.Now()); err == nil {
+		t.Fatalf("forged value must be rejected")
+	}
+	if _, err := checkValue(value, hash, time.Now().Add(2*time.Hour)); err == nil {
+		t.Fatalf("expired value must be rejected")
+	}
+}
+
+func TestClient_StoreAddresses(t *testing.T) {
+	first, second := startTestNode(t), startTestNode(t)
+	first.setKnown(second)
+	second.setKnown(first)
+
+	_, owner, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey() failed: %v", err)
+	}
+	addrs := config.AddressList{
+		Addrs:   []string{"1.2.3.4:3333", "[2001:db8::1]:4444"},
+		Version: 1,
+	}
+	if err := newTestClient(t, first).StoreAddresses(context.Background(), owner, addrs, time.Hour); err != nil {
+		t.Fatalf("StoreAddresses() failed: %v", err)
+	}
+	if first.storedValues() != 1 || second.storedValues() != 1 {
+		t.Fatalf("value must be stored on both nodes")
+	}
+
+	id, err := adnl.ShortID(adnl.PublicKeyEd25519(owner.Public().(ed25519.PublicKey)))
+	if err != nil {
+		t.Fatalf("ShortID() failed: %v", err)
+	}
+	res, key, err := newTestClient(t, second).FindAddresses(context.Background(), id)
+	if err != nil {
+		t.Fatalf("FindAddresses() failed: %v", err)
+	}
+	if !key.Equal(owner.Public()) {
+		t.Fatalf("public key mismatch")
+	}
+	if len(res.Addrs) != 2 || res.Addrs[0] != addrs.Addrs[0] || res.Addrs[1] != addrs.Addrs[1] || res.Version != 1 {
+		t.Fatalf("unexpected addresses: %+v", res)
+	}
+}
+
+func TestNewClient(t *testing.T) {
+	n := startTestNode(t)
+	node := n.configNode()
+	node.Signature = append([]byte{}, node.Signature...)
+	node.Signature[0] ^= 1
+	gateway, err := adnl.Listen("127.0.0.1:0", nil)
+	if err != nil {
+		t.Fatalf("Listen() failed: %v", err)
+	}
+	defer gateway.Close()
+	if _, err := NewClient(gateway, config.DHTConfig{StaticNodes: []config.DHTNode{node}}); err == nil {
+		t.Fatalf("node with invalid signature must be ignored")
+	}
+}
+
+// mainnetDHTConfig contains static DHT nodes of the mainnet global config.
+const mainnetDHTConfig = `{
+  "@type": "config.global",
+  "liteservers": [
+    {"ip": 84478511, "port": 19949, "id": {"@type": "pub.ed25519", "key": "n4VDnSCUuSpjnCyUk9e3QOOd6o0ItSWYbTnW3Wnn8wk="}}
+  ],
+  "dht": {
+    "@type": "dht.config.global",
+    "k": 6,
+    "a": 3,
+    "static_nodes": {
+      "@type": "dht.nodes",
+      "nodes": [
+        {
+          "@type": "dht.node",
+          "id": {"@type": "pub.ed25519", "key": "6PGkPQSbyFp12esf1NqmDOaLoFA8i9+Mp5+cAx5wtTU="},
+          "addr_list": {
+            "@type": "adnl.addressList",
+            "addrs": [{"@type": "adnl.address.udp", "ip": -1185526007, "port": 22096}],
+            "version": 0,
+            "reinit_date": 0,
+            "priority": 0,
+            "expire_at": 0
+          },
+          "version": -1,
+          "signature": "L4N1+dzXLlkmT5iPnvsmsixzXU0L6kPKApqMdcrGP5d9ssMhn69SzHFK+yIzvG6zQ9oRb4TnqPBaKShjjj2OBg=="
+        },
+        {
+          "@type": "dht.node",
+          "id": {"@type": "pub.ed25519", "key": "bn8klhFZgE2sfIDfvVI6m6+oVNi1nBRlnHoxKtR9WBU="},
+          "addr_list": {
+            "@type": "adnl.addressList",
+            "addrs": [{"@type": "adnl.address.udp", "ip": -1307380860, "port": 15888}],
+            "version": 0,
+            "reinit_date": 0,
+            "priority": 0,
+            "expire_at": 0
+          },
+          "version": -1,
+          "signature": "fQ5zAa6ot4pfFWzvuJOR8ijM5ELWndSDsRhFKstW1tqVSNfwAdOC7tDC8mc4vgTJ6fSYSWmhnXGK/+T5f6sDCw=="
+        }
+      ]
+    }
+  }
+}`
+
+func Test_checkNode_mainnet(t *testing.T) {
+	conf, err := config.ParseConfig(strings.NewReader(mainnetDHTConfig))
+	if err != nil {
+		t.Fatalf("ParseConfig() failed: %v", err)
+	}
+	if len(conf.DHT.StaticNodes) != 2 {
+		t.Fatalf("want 2 static nodes, got: %v", len(conf.DHT.StaticNodes))
+	}
+	for _, n := range conf.DHT.StaticNodes {
+		dhtNode, err := nodeFromConfig(n)
+		if err != nil {
+			t.Fatalf("nodeFromConfig() failed: %v", err)
+		}
+		if err := checkNode(dhtNode); err != nil {
+			t.Fatalf("checkNode() failed for %v: %v", n.AddrList.Addrs, err)
+		}
+		forged := dhtNode
+		forged.Version = 0
+		if err := checkNode(forged); err == nil {
+			t.Fatalf("want invalid signature of a modified node")
+		}
+	}
+	if addrs := conf.DHT.StaticNodes[0].AddrList.Addrs; len(addrs) != 1 || addrs[0] != "185.86.79.9:22096" {
+		t.Fatalf("unexpected addresses: %v", addrs)
+	}
+
+	gateway, err := adnl.Listen("127.0.0.1:0", nil)
+	if err != nil {
+		t.Fatalf("Listen() failed: %v", err)
+	}
+	defer gateway.Close()
+	c, err := NewClient(gateway, conf.DHT)
+	if err != nil {
+		t.Fatalf("NewClient() failed: %v", err)
+	}
+	if len(c.nodes) != 2 || c.k != 6 || c.a != 3 {
+		t.Fatalf("want 2 nodes with k=6 and a=3, got: %v nodes, k=%v, a=%v", len(c.nodes), c.k, c.a)
+	}
+}
+
+func TestClient_FindOverlayNodes(t *testing.T) {
+	n := startTestNode(t)
+	overlayKey := adnl.PublicKeyOverlay([]byte("overlay"))
+	overlay, err := adnl.ShortID(overlayKey)
+	if err != nil {
+		t.Fatalf("ShortID() failed: %v", err)
+	}
+	var nodes []adnl.OverlayNodeC
+	for i := 0; i < 3; i++ {
+		pub, key, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			t.Fatalf("GenerateKey() failed: %v", err)
+		}
+		node := adnl.OverlayNodeC{Id: adnl.PublicKeyEd25519(pub), Overlay: overlay, Version: 1}
+		id, err := adnl.ShortID(node.Id)
+		if err != nil {
+			t.Fatalf("ShortID() failed: %v", err)
+		}
+		b, err := adnl.MarshalBoxed(tagOverlayToSign, adnl.OverlayNodeToSignC{Id: adnl.AdnlIdShortC{Id: id}, Overlay: overlay, Version: 1})
+		if err != nil {
+			t.Fatalf("MarshalBoxed() failed: %v", err)
+		}
+		node.Signature = ed25519.Sign(key, b)
+		nodes = append(nodes, node)
+	}
+	// the last node is signed for another version
+	nodes[2].Version = 2
+	data, err := adnl.MarshalBoxed(tagOverlayNodes, adnl.OverlayNodesC{Nodes: nodes})
+	if err != nil {
+		t.Fatalf("MarshalBoxed() failed: %v", err)
+	}
+	var rule adnl.DhtUpdateRule
+	rule.SumType = "DhtUpdateRuleOverlayNodes"
+	value := adnl.DhtValueC{
+		Key: adnl.DhtKeyDescriptionC{
+			Key:        Key{ID: overlay, Name: "nodes"}.tl(),
+			Id:         overlayKey,
+			UpdateRule: rule,
+		},
+		Value: data,
+		Ttl:   uint32(time.Now().Add(time.Hour).Unix()),
+	}
+	hash, err := keyHash(value.Key.Key)
+	if err != nil {
+		t.Fatalf("keyHash() failed: %v", err)
+	}
+	n.mu.Lock()
+	n.values[hash] = value
+	n.mu.Unlock()
+
+	res, err := newTestClient(t, n).FindOverlayNodes(context.Background(), overlay)
+	if err != nil {
+		t.Fatalf("FindOverlayNodes() failed: %v", err)
+	}
+	if len(res) != 2 || res[0].Id.PubEd25519 != nodes[0].Id.PubEd25519 || res[1].Id.PubEd25519 != nodes[1].Id.PubEd25519 {
+		t.Fatalf("unexpected nodes: %+v", res)
+	}
+}
@@ -0,0 +1,203 @@
+package dht
+
+import (
+	"crypto/ed25519"
+	"crypto/sha256"
+	"fmt"
+	"time"
+
+	"github.com/tonkeeper/tongo/adnl"
+	"github.com/tonkeeper/tongo/tl"
+)
+
+const (
+	tagKey            uint32 = 0xf667de8f
+	tagKeyDescription uint32 = 0x281d4e05
+	tagValue          uint32 = 0x90ad27cb
+	tagNode           uint32 = 0x84533248
+	tagNodes          uint32 = 0x7974a0be
+	tagStored         uint32 = 0x7026fb08
+	tagPong           uint32 = 0x5a8aef81
+	tagAddressList    uint32 = 0x2227e658
+	tagOverlayNodes   uint32 = 0xe487290e
+	tagOverlayToSign  uint32 = 0x03d8a8e1
+
+	tagPing      uint32 = 0xcbeb3f18
+	tagStore     uint32 = 0x34934212
+	tagFindNode  uint32 = 0x6ce2ce6b
+	tagFindValue uint32 = 0xae4b6011
+)
+
+// Key identifies a value stored in DHT.
+type Key struct {
+	// ID is an ADNL address of the value's owner.
+	ID tl.Int256
+	// Name describes the value, for example, "address" for an address list of an ADNL node.
+	Name string
+	// Index distinguishes values with the same name.
+	Index uint32
+}
+
+func (k Key) tl() adnl.DhtKeyC {
+	return adnl.DhtKeyC{Id: k.ID, Name: []byte(k.Name), Idx: k.Index}
+}
+
+// Hash returns an ID of the key in DHT, the value is stored by nodes with IDs closest to it.
+func (k Key) Hash() (tl.Int256, error) {
+	return keyHash(k.tl())
+}
+
+func keyHash(key adnl.DhtKeyC) (tl.Int256, error) {
+	b, err := adnl.MarshalBoxed(tagKey, key)
+	if err != nil {
+		return tl.Int256{}, err
+	}
+	return sha256.Sum256(b), nil
+}
+
+// Value is a value stored in DHT and signed by its owner.
+type Value struct {
+	Key Key
+	// Owner is nil for values which can be updated by anybody and for nodes of overlays.
+	Owner    ed25519.PublicKey
+	Data     []byte
+	ExpireAt time.Time
+}
+
+// newValue returns dht.value signed by the owner, the value can be updated only by the owner.
+func newValue(owner ed25519.PrivateKey, name string, index uint32, data []byte, expireAt time.Time) (adnl.DhtValueC, error) {
+	ownerKey := adnl.PublicKeyEd25519(owner.Public().(ed25519.PublicKey))
+	id, err := adnl.ShortID(ownerKey)
+	if err != nil {
+		return adnl.DhtValueC{}, err
+	}
+	var rule adnl.DhtUpdateRule
+	rule.SumType = "DhtUpdateRuleSignature"
+	desc := adnl.DhtKeyDescriptionC{
+		Key:        Key{ID: id, Name: name, Index: index}.tl(),
+		Id:         ownerKey,
+		UpdateRule: rule,
+	}
+	b, err := adnl.MarshalBoxed(tagKeyDescription, desc)
+	if err != nil {
+		return adnl.DhtValueC{}, err
+	}
+	desc.Signature = ed25519.Sign(owner, b)
+	value := adnl.DhtValueC{
+		Key:   desc,
+		Value: data,
+		Ttl:   uint32(expireAt.Unix()),
+	}
+	b, err = adnl.MarshalBoxed(tagValue, value)
+	if err != nil {
+		return adnl.DhtValueC{}, err
+	}
+	value.Signature = ed25519.Sign(owner, b)
+	return value, nil
+}
+
+// checkValue verifies that a value is stored under the key with the given hash, is not expired and is signed properly.
+func checkValue(value adnl.DhtValueC, hash tl.Int256, now time.Time) (Value, error) {
+	h, err := keyHash(value.Key.Key)
+	if err != nil {
+		return Value{}, err
+	}
+	if h != hash {
+		return Value{}, fmt.Errorf("value key mismatch")
+	}
+	if int64(value.Ttl) <= now.Unix() {
+		return Value{}, fmt.Errorf("value is expired")
+	}
+	res := Value{
+		Key: Key{
+			ID:    value.Key.Key.Id,
+			Name:  string(value.Key.Key.Name),
+			Index: value.Key.Key.Idx,
+		},
+		Data:     value.Value,
+		ExpireAt: time.Unix(int64(value.Ttl), 0),
+	}
+	id, err := adnl.ShortID(value.Key.Id)
+	if err != nil {
+		return Value{}, err
+	}
+	switch value.Key.UpdateRule.SumType {
+	case "DhtUpdateRuleSignature":
+		if id != value.Key.Key.Id {
+			return Value{}, fmt.Errorf("value owner mismatch")
+		}
+		owner, err := adnl.Ed25519Key(value.Key.Id)
+		if err != nil {
+			return Value{}, err
+		}
+		res.Owner = owner
+		desc := value.Key
+		desc.Signature = nil
+		if err := verify(owner, tagKeyDescription, desc, value.Key.Signature); err != nil {
+			return Value{}, fmt.Errorf("key description: %w", err)
+		}
+		unsigned := value
+		unsigned.Signature = nil
+		if err := verify(owner, tagValue, unsigned, value.Signature); err != nil {
+			return Value{}, fmt.Errorf("value: %w", err)
+		}
+	case "DhtUpdateRuleAnybody":
+		if len(value.Signature) != 0 || len(value.Key.Signature) != 0 {
+			return Value{}, fmt.Errorf("value of anybody must not be signed")
+		}
+	case "DhtUpdateRuleOverlayNodes":
+		// nodes of an overlay are stored under the overlay's ID, every node is signed by itself
+		if value.Key.Id.SumType != "PubOverlay" || id != value.Key.Key.Id {
+			return Value{}, fmt.Errorf("value overlay mismatch")
+		}
+		if len(value.Signature) != 0 || len(value.Key.Signature) != 0 {
+			return Value{}, fmt.Errorf("value of overlay nodes must not be signed")
+		}
+	default:
+		return Value{}, fmt.Errorf("unsupported update rule: %v", value.Key.UpdateRule.SumType)
+	}
+	return res, nil
+}
+
+// checkOverlayNode verifies a signature of a node of the overlay with the given ID.
+func checkOverlayNode(node adnl.OverlayNodeC, overlay tl.Int256) error {
+	if node.Overlay != overlay {
+		return fmt.Errorf("overlay mismatch")
+	}
+	key, err := adnl.Ed25519Key(node.Id)
+	if err != nil {
+		return err
+	}
+	id, err := adnl.ShortID(node.Id)
+	if err != nil {
+		return err
+	}
+	toSign := adnl.OverlayNodeToSignC{
+		Id:      adnl.AdnlIdShortC{Id: id},
+		Overlay: overlay,
+		Version: node.Version,
+	}
+	return verify(key, tagOverlayToSign, toSign, node.Signature)
+}
+
+// checkNode verifies a signature of a DHT node.
+func checkNode(node adnl.DhtNodeC) error {
+	key, err := adnl.Ed25519Key(node.Id)
+	if err != nil {
+		return err
+	}
+	unsigned := node
+	unsigned.Signature = nil
+	return verify(key, tagNode, unsigned, node.Signature)
+}
+
+func verify(key ed25519.PublicKey, tag uint32, value tl.MarshalerTL, signature []byte) error {
+	b, err := adnl.MarshalBoxed(tag, value)
+	if err != nil {
+		return err
+	}
+	if !ed25519.Verify(key, b, signature) {
+		return fmt.Errorf("invalid signature")
+	}
+	return nil
+}
@@ -0,0 +1,66 @@
+package adnl
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+
+	"github.com/tonkeeper/tongo/tl"
+)
+
+const (
+	tagPacketContents uint32 = 0xd142cd89
+	tagDhtValue       uint32 = 0x90ad27cb
+)
+
+// DhtValue is a boxed dht.value.
+type DhtValue DhtValueC
+
+func (t DhtValue) MarshalTL() ([]byte, error) {
+	return MarshalBoxed(tagDhtValue, DhtValueC(t))
+}
+
+func (t *DhtValue) UnmarshalTL(r io.Reader) error {
+	var (
+		res DhtValueC
+		tag uint32
+	)
+	err := tl.Unmarshal(r, &tag)
+	if err != nil {
+		return err
+	}
+	if tag != tagDhtValue {
+		return fmt.Errorf("invalid tag")
+	}
+	err = tl.Unmarshal(r, &res)
+	if err != nil {
+		return err
+	}
+	*t = DhtValue(res)
+	return nil
+}
+
+// MarshalBoxed serializes a value of a type with a single constructor prefixed with the constructor's tag.
+func MarshalBoxed(tag uint32, value tl.MarshalerTL) ([]byte, error) {
+	b, err := value.MarshalTL()
+	if err != nil {
+		return nil, err
+	}
+	res := make([]byte, 4, 4+len(b))
+	binary.LittleEndian.PutUint32(res, tag)
+	return append(res, b...), nil
+}
+
+// UnmarshalBoxed parses a value of a type with a single constructor prefixed with the given tag.
+func UnmarshalBoxed(data []byte, tag uint32, value any) error {
+	r := bytes.NewReader(data)
+	var t uint32
+	if err := tl.Unmarshal(r, &t); err != nil {
+		return err
+	}
+	if t != tag {
+		return fmt.Errorf("invalid tag %x, expected %x", t, tag)
+	}
+	return tl.Unmarshal(r, value)
+}
@@ -0,0 +1,409 @@
+package adnl
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/tonkeeper/tongo/liteclient"
+	"github.com/tonkeeper/tongo/tl"
+)
+
+const (
+	maxPacketSize = 64 * 1024
+	// minReadErrorDelay and maxReadErrorDelay limit a delay before reading again after a socket error.
+	minReadErrorDelay = 10 * time.Millisecond
+	maxReadErrorDelay = time.Second
+	// maxPeers limits a number of peers known to a gateway.
+	// Any signed packet from a new key adds a peer, so idle peers are evicted to keep the limit.
+	maxPeers = 4096
+)
+
+// ErrClosed is returned by a peer when its gateway is closed.
+var ErrClosed = errors.New("adnl gateway is closed")
+
+// Handler processes queries and custom messages received from peers.
+// Methods can be called concurrently for different peers.
+type Handler interface {
+	// HandleQuery answers a TL-serialized query, the answer is not sent if an error is returned.
+	HandleQuery(ctx context.Context, peer *Peer, query []byte) ([]byte, error)
+	// HandleCustomMessage processes data of adnl.message.custom.
+	// It is called by a goroutine reading packets, so it must not block.
+	HandleCustomMessage(peer *Peer, data []byte)
+}
+
+// Gateway is an ADNL node communicating with peers over UDP.
+// It is identified by an ed25519 key, a short ID of the key is the node's ADNL address.
+type Gateway struct {
+	key        ed25519.PrivateKey
+	id         tl.Int256
+	conn       net.PacketConn
+	reinitDate uint32
+	observer   Observer
+	ctx        context.Context
+	cancel     context.CancelFunc
+
+	// mu protects all fields below.
+	mu       sync.Mutex
+	handler  Handler
+	peers    map[tl.Int256]*Peer
+	channels map[tl.Int256]*Peer
+}
+
+// Option configures a Gateway.
+type Option func(g *Gateway)
+
+// OptionObserver sets an observer to receive events of the gateway.
+func OptionObserver(o Observer) Option {
+	return func(g *Gateway) {
+		g.observer = o
+	}
+}
+
+// Listen starts a gateway listening for UDP packets on the given address.
+// Clients that don't accept connections can listen on ":0".
+// If key is nil, a random key is generated, so the gateway gets a new ADNL address.
+func Listen(address string, key ed25519.PrivateKey, opts ...Option) (*Gateway, error) {
+	if key == nil {
+		var err error
+		_, key, err = ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if len(key) != ed25519.PrivateKeySize {
+		return nil, fmt.Errorf("invalid private key length: %v", len(key))
+	}
+	conn, err := net.ListenPacket("udp", address)
+	if err != nil {
+		return nil, err
+	}
+	g, err := newGateway(conn, key, opts...)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return g, nil
+}
+
+// newGateway returns a gateway working over the given connection.
+func newGateway(conn net.PacketConn, key ed25519.PrivateKey, opts ...Option) (*Gateway, error) {
+	id, err := ShortID(PublicKeyEd25519(key.Public().(ed25519.PublicKey)))
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	g := &Gateway{
+		key:        key,
+		id:         id,
+		conn:       conn,
+		reinitDate: uint32(time.Now().Unix()),
+		observer:   NopObserver{},
+		ctx:        ctx,
+		cancel:     cancel,
+		peers:      make(map[tl.Int256]*Peer),
+		channels:   make(map[tl.Int256]*Peer),
+	}
+	for _, o := range opts {
+		o(g)
+	}
+	go g.reader()
+	return g, nil
+}
+
+// ID returns the gateway's ADNL address.
+func (g *Gateway) ID() tl.Int256 {
+	return g.id
+}
+
+// PublicKey returns the gateway's public key.
+func (g *Gateway) PublicKey() ed25519.PublicKey {
+	return g.key.Public().(ed25519.PublicKey)
+}
+
+// Addr returns a local address the gateway listens on.
+func (g *Gateway) Addr() net.Addr {
+	return g.conn.LocalAddr()
+}
+
+// SetHandler sets a handler for peers without their own handler set with Peer.SetHandler.
+// Queries and custom messages are ignored if there is no handler.
+func (g *Gateway) SetHandler(h Handler) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.handler = h
+}
+
+// Connect returns a peer with the given key reachable at the given "host:port" address.
+// No packets are sent until the first query or message,
+// and the same peer is returned for the same key, its address is updated if it differs.
+func (g *Gateway) Connect(key ed25519.PublicKey, address string) (*Peer, error) {
+	if len(key) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("invalid public key length: %v", len(key))
+	}
+	addr, err := net.ResolveUDPAddr("udp", address)
+	if err != nil {
+		return nil, err
+	}
+	if g.ctx.Err() != nil {
+		return nil, ErrClosed
+	}
+	p, err := g.peer(key)
+	if err != nil {
+		return nil, err
+	}
+	p.setAddr(addr)
+	return p, nil
+}
+
+// Close stops the gateway, pending queries of its peers fail with ErrClosed.
+func (g *Gateway) Close() error {
+	g.cancel()
+	return g.conn.Close()
+}
+
+// peer returns a peer with the given key, creating it if necessary.
+func (g *Gateway) peer(key ed25519.PublicKey) (*Peer, error) {
+	id, err := ShortID(PublicKeyEd25519(key))
+	if err != nil {
+		return nil, err
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if p, ok := g.peers[id]; ok {
+		return p, nil
+	}
+	if len(g.peers) >= maxPeers && !g.evictIdlePeer() {
+		return nil, fmt.Errorf("too many peers")
+	}
+	p := newPeer(g, key, id)
+	g.peers[id] = p
+	return p, nil
+}
+
+// restorePeer adds back an evicted peer which is still used to send packets,
+// so the gateway routes the peer's answers to it.
+// channelID is an ID of the peer's channel if the channel is set up.
+func (g *Gateway) restorePeer(p *Peer, channelID *tl.Int256) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if _, ok := g.peers[p.id]; ok {
+		return
+	}
+	if len(g.peers) >= maxPeers && !g.evictIdlePeer() {
+		return
+	}
+	g.peers[p.id] = p
+	if channelID != nil {
+		g.channels[*channelID] = p
+	}
+}
+
+// evictIdlePeer removes the least recently active peer which doesn't wait for answers.
+// It returns false if there is no such peer.
+// g.mu must be held.
+func (g *Gateway) evictIdlePeer() bool {
+	var idle *Peer
+	for _, p := range g.peers {
+		if p.pendingQueries.Load() > 0 {
+			continue
+		}
+		if idle == nil || p.lastActive.Load() < idle.lastActive.Load() {
+			idle = p
+		}
+	}
+	if idle == nil {
+		return false
+	}
+	delete(g.peers, idle.id)
+	for id, p := range g.channels {
+		if p == idle {
+			delete(g.channels, id)
+		}
+	}
+	return true
+}
+
+func (g *Gateway) peerHandler(p *Peer) Handler {
+	if h := p.getHandler(); h != nil {
+		return h
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.handler
+}
+
+// registerChannel routes packets with the given channel ID to the peer.
+func (g *Gateway) registerChannel(id tl.Int256, p *Peer) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.channels[id] = p
+}
+
+func (g *Gateway) unregisterChannel(id tl.Int256) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	delete(g.channels, id)
+}
+
+// reader processes incoming packets until the gateway is closed.
+// Consecutive read errors are reported to the observer and delay the next read.
+func (g *Gateway) reader() {
+	buf := make([]byte, maxPacketSize)
+	delay := minReadErrorDelay
+	for {
+		n, addr, err := g.conn.ReadFrom(buf)
+		if err != nil {
+			if g.ctx.Err() != nil || errors.Is(err, net.ErrClosed) {
+				return
+			}
+			g.observer.ReadError(err)
+			timer := time.NewTimer(delay)
+			select {
+			case <-g.ctx.Done():
+				timer.Stop()
+				return
+			case <-timer.C:
+			}
+			if delay *= 2; delay > maxReadErrorDelay {
+				delay = maxReadErrorDelay
+			}
+			continue
+		}
+		delay = minReadErrorDelay
+		udpAddr, ok := addr.(*net.UDPAddr)
+		if !ok {
+			continue
+		}
+		if err := g.handlePacket(buf[:n], udpAddr); err != nil {
+			g.observer.InvalidPacket(addr.String(), err)
+		}
+	}
+}
+
+func (g *Gateway) handlePacket(data []byte, addr *net.UDPAddr) error {
+	if len(data) < 64 {
+		return fmt.Errorf("packet is too short: %v", len(data))
+	}
+	var id tl.Int256
+	copy(id[:], data[:32])
+	if id == g.id {
+		return g.handleOwnPacket(data[32:], addr)
+	}
+	g.mu.Lock()
+	p, ok := g.channels[id]
+	g.mu.Unlock()
+	if !ok {
+		return fmt.Errorf("unknown destination %x", id[:])
+	}
+	return p.handleChannelPacket(id, data[32:], addr)
+}
+
+// handleOwnPacket processes a packet encrypted with the gateway's key.
+func (g *Gateway) handleOwnPacket(data []byte, addr *net.UDPAddr) error {
+	if len(data) < 64 {
+		return fmt.Errorf("packet is too short: %v", len(data))
+	}
+	shared, err := liteclient.SharedKey(g.key, data[:32])
+	if err != nil {
+		return err
+	}
+	plain, err := decrypt(shared, data[32:64], data[64:])
+	if err != nil {
+		return err
+	}
+	packet, err := parsePacket(plain)
+	if err != nil {
+		return err
+	}
+	var p *Peer
+	switch {
+	case packet.From != nil:
+		key, err := Ed25519Key(*packet.From)
+		if err != nil {
+			return err
+		}
+		if err := verifyPacket(packet, key); err != nil {
+			return err
+		}
+		p, err = g.peer(key)
+		if err != nil {
+			return err
+		}
+	case packet.FromShort != nil:
+		g.mu.Lock()
+		p = g.peers[packet.FromShort.Id]
+		g.mu.Unlock()
+		if p == nil {
+			return fmt.Errorf("unknown sender %x", packet.FromShort.Id[:])
+		}
+		if err := verifyPacket(packet, p.key); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("unknown sender")
+	}
+	return p.handlePacket(packet, addr, false)
+}
+
+func parsePacket(data []byte) (AdnlPacketContentsC, error) {
+	var packet AdnlPacketContentsC
+	if err := UnmarshalBoxed(data, tagPacketContents, &packet); err != nil {
+		return AdnlPacketContentsC{}, err
+	}
+	return packet, nil
+}
+
+// verifyPacket checks a signature of a packet sent without a channel.
+func verifyPacket(packet AdnlPacketContentsC, key ed25519.PublicKey) error {
+	if packet.Flags&flagSignature == 0 {
+		return fmt.Errorf("packet is not signed")
+	}
+	signature := packet.Signature
+	packet.Flags &^= flagSignature
+	packet.Signature = nil
+	b, err := MarshalBoxed(tagPacketContents, packet)
+	if err != nil {
+		return err
+	}
+	if !ed25519.Verify(key, b, signature) {
+		return fmt.Errorf("invalid packet signature")
+	}
+	return nil
+}
+
+// sendPacket encrypts a packet either for the peer's key or with the channel's key and sends it.
+func (g *Gateway) sendPacket(packet []byte, p *Peer, ch *channel, addr *net.UDPAddr) error {
+	var header []byte
+	var secret []byte
+	if ch != nil {
+		header = append([]byte{}, ch.outID[:]...)
+		secret = ch.encKey
+	} else {
+		public, private, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			return err
+		}
+		secret, err = liteclient.SharedKey(private, p.key)
+		if err != nil {
+			return err
+		}
+		header = append(append([]byte{}, p.id[:]...), public...)
+	}
+	checksum, encrypted, err := encrypt(secret, packet)
+	if err != nil {
+		return err
+	}
+	data := bytes.Join([][]byte{header, checksum[:], encrypted}, nil)
+	if g.ctx.Err() != nil {
+		return ErrClosed
+	}
+	_, err = g.conn.WriteTo(data, addr)
+	return err
+}
@@ -0,0 +1,351 @@
+package adnl
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/tonkeeper/tongo/tl"
+)
+
+type echoHandler struct {
+	custom chan []byte
+}
+
+func (h *echoHandler) HandleQuery(ctx context.Context, peer *Peer, query []byte) ([]byte, error) {
+	if bytes.Equal(query, []byte("fail")) {
+		return nil, fmt.Errorf("failed")
+	}
+	return append([]byte("echo:"), query...), nil
+}
+
+func (h *echoHandler) HandleCustomMessage(peer *Peer, data []byte) {
+	h.custom <- data
+}
+
+func startTestGateways(t *testing.T) (*Gateway, *Gateway, *echoHandler) {
+	server, err := Listen("127.0.0.1:0", nil)
+	if err != nil {
+		t.Fatalf("Listen() failed: %v", err)
+	}
+	t.Cleanup(func() { server.Close() })
+	handler := &echoHandler{custom: make(chan []byte, 10)}
+	server.SetHandler(handler)
+	client, err := Listen("127.0.0.1:0", nil)
+	if err != nil {
+		t.Fatalf("Listen() failed: %v", err)
+	}
+	t.Cleanup(func() { client.Close() })
+	return server, client, handler
+}
+
+func TestGateway_Query(t *testing.T) {
+	server, client, handler := startTestGateways(t)
+	peer, err := client.Connect(server.PublicKey(), server.Addr().String())
+	if err != nil {
+		t.Fatalf("Connect() failed: %v", err)
+	}
+	if peer.ID() != server.ID() {
+		t.Fatalf("peer ID mismatch")
+	}
+	huge := bytes.Repeat([]byte{1, 2, 3}, 3000)
+	for i, query := range [][]byte{[]byte("ping"), []byte("pong"), huge, []byte("ping")} {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		res, err := peer.Query(ctx, query)
+		cancel()
+		if err != nil {
+			t.Fatalf("query %v failed: %v", i, err)
+		}
+		if want := append([]byte("echo:"), query...); !bytes.Equal(res, want) {
+			t.Fatalf("query %v: want %v bytes, got %v bytes", i, len(want), len(res))
+		}
+	}
+
+	peer.mu.Lock()
+	ready := peer.channel != nil && peer.channel.ready
+	peer.mu.Unlock()
+	if !ready {
+		t.Fatalf("channel must be ready")
+	}
+	serverPeer, err := server.peer(client.PublicKey())
+	if err != nil {
+		t.Fatalf("peer() failed: %v", err)
+	}
+	serverPeer.mu.Lock()
+	ready = serverPeer.channel != nil && serverPeer.channel.ready
+	serverPeer.mu.Unlock()
+	if !ready {
+		t.Fatalf("server channel must be ready")
+	}
+
+	if err := peer.SendCustomMessage([]byte("custom")); err != nil {
+		t.Fatalf("SendCustomMessage() failed: %v", err)
+	}
+	select {
+	case data := <-handler.custom:
+		if string(data) != "custom" {
+			t.Fatalf("want custom message, got %q", data)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("custom message is not received")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	if _, err := peer.Query(ctx, []byte("fail")); err != context.DeadlineExceeded {
+		t.Fatalf("want deadline exceeded, got %v", err)
+	}
+}
+
+func TestGateway_Close(t *testing.T) {
+	_, client, _ := startTestGateways(t)
+	silent, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("ListenPacket() failed: %v", err)
+	}
+	defer silent.Close()
+	peer, err := client.Connect(client.PublicKey(), silent.LocalAddr().String())
+	if err != nil {
+		t.Fatalf("Connect() failed: %v", err)
+	}
+	errCh := make(chan error, 1)
+	go func() {
+		_, err := peer.Query(context.Background(), []byte("ping"))
+		errCh <- err
+	}()
+	time.Sleep(50 * time.Millisecond)
+	client.Close()
+	select {
+	case err := <-errCh:
+		if err != ErrClosed {
+			t.Fatalf("want ErrClosed, got %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("query is not interrupted")
+	}
+}
+
+// failingConn returns the given errors from ReadFrom.
+type failingConn struct {
+	net.PacketConn
+	errs chan error
+
+	mu    sync.Mutex
+	reads int
+}
+
+func (c *failingConn) ReadFrom(b []byte) (int, net.Addr, error) {
+	c.mu.Lock()
+	c.reads += 1
+	c.mu.Unlock()
+	return 0, nil, <-c.errs
+}
+
+func (c *failingConn) readsNumber() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.reads
+}
+
+type readErrorObserver struct {
+	NopObserver
+	errs chan error
+}
+
+func (o *readErrorObserver) ReadError(err error) {
+	o.errs <- err
+}
+
+func TestGateway_readErrors(t *testing.T) {
+	udpConn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("ListenPacket() failed: %v", err)
+	}
+	defer udpConn.Close()
+	conn := &failingConn{PacketConn: udpConn, errs: make(chan error, 4)}
+	observer := &readErrorObserver{errs: make(chan error, 4)}
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey() failed: %v", err)
+	}
+	start := time.Now()
+	if _, err := newGateway(conn, key, OptionObserver(observer)); err != nil {
+		t.Fatalf("newGateway() failed: %v", err)
+	}
+	readErr := errors.New("temporary error")
+	for i := 0; i < 3; i++ {
+		conn.errs <- readErr
+	}
+	for i := 0; i < 3; i++ {
+		if err := <-observer.errs; err != readErr {
+			t.Fatalf("want read error, got: %v", err)
+		}
+	}
+	conn.errs <- fmt.Errorf("read: %w", net.ErrClosed)
+	time.Sleep(100 * time.Millisecond)
+	// reads are delayed by 10ms, 20ms and 40ms after consecutive errors.
+	if elapsed := time.Since(start); elapsed < 70*time.Millisecond {
+		t.Fatalf("want backoff between reads, got all reads in %v", elapsed)
+	}
+	if n := conn.readsNumber(); n != 4 {
+		t.Fatalf("want the reader to stop on a closed connection after 4 reads, got: %v", n)
+	}
+	if len(observer.errs) != 0 {
+		t.Fatalf("a closed connection must not be reported as a read error")
+	}
+}
+
+func TestPeer_acceptPacket(t *testing.T) {
+	g := &Gateway{reinitDate: 100}
+	p := newPeer(g, nil, [32]byte{})
+	seqno := func(s uint64) AdnlPacketContentsC {
+		reinit := uint32(10)
+		return AdnlPacketContentsC{Seqno: &s, ReinitDate: &reinit}
+	}
+	for _, tt := range []struct {
+		seqno   uint64
+		wantErr bool
+	}{
+		{seqno: 1},
+		{seqno: 3},
+		{seqno: 2},
+		{seqno: 2, wantErr: true},
+		{seqno: 3, wantErr: true},
+		{seqno: 100},
+		{seqno: 30, wantErr: true},
+		{seqno: 50},
+	} {
+		err := p.acceptPacket(seqno(tt.seqno), nil, false)
+		if (err != nil) != tt.wantErr {
+			t.Fatalf("seqno %v: want error %v, got %v", tt.seqno, tt.wantErr, err)
+		}
+	}
+	dst := uint32(99)
+	packet := seqno(101)
+	packet.DstReinitDate = &dst
+	if err := p.acceptPacket(packet, nil, false); err == nil {
+		t.Fatalf("packet to a previous instance must be rejected")
+	}
+}
+
+func TestPeer_addPart(t *testing.T) {
+	p := newPeer(&Gateway{}, nil, [32]byte{})
+	parts := func(data []byte) []AdnlMessage {
+		hash := sha256.Sum256(data)
+		var parts []AdnlMessage
+		for offset := 0; offset < len(data); offset += maxMessageSize {
+			end := offset + maxMessageSize
+			if end > len(data) {
+				end = len(data)
+			}
+			var part AdnlMessage
+			part.SumType = "AdnlMessagePart"
+			part.AdnlMessagePart.Hash = hash
+			part.AdnlMessagePart.TotalSize = uint32(len(data))
+			part.AdnlMessagePart.Offset = uint32(offset)
+			part.AdnlMessagePart.Data = data[offset:end]
+			parts = append(parts, part)
+		}
+		return parts
+	}
+	assemble := func(data []byte) error {
+		for i, part := range parts(data) {
+			res, err := p.addPart(part)
+			if err != nil {
+				return err
+			}
+			if last := i == len(data)/maxMessageSize; last != (res != nil) {
+				return fmt.Errorf("part %v: unexpected result of %v bytes", i, len(res))
+			}
+			if res != nil && !bytes.Equal(res, data) {
+				return fmt.Errorf("assembled message doesn't match")
+			}
+		}
+		return nil
+	}
+	// the second part of each message is lost.
+	for i := 0; i < maxPartialMessages; i++ {
+		data := bytes.Repeat([]byte{byte(i)}, 2*maxMessageSize+1)
+		if _, err := p.addPart(parts(data)[0]); err != nil {
+			t.Fatalf("addPart() failed: %v", err)
+		}
+	}
+	data := bytes.Repeat([]byte{0xff}, 2*maxMessageSize+1)
+	if err := assemble(data); err == nil {
+		t.Fatalf("want error when there are too many partial messages")
+	}
+	p.mu.Lock()
+	for _, m := range p.parts {
+		m.createdAt = m.createdAt.Add(-partialMessageTimeout)
+	}
+	p.mu.Unlock()
+	if err := assemble(data); err != nil {
+		t.Fatalf("stale partial messages must be dropped: %v", err)
+	}
+	if len(p.parts) != 0 {
+		t.Fatalf("want no partial messages left, got: %v", len(p.parts))
+	}
+}
+
+func TestGateway_evictIdlePeer(t *testing.T) {
+	g := &Gateway{
+		peers:    make(map[tl.Int256]*Peer),
+		channels: make(map[tl.Int256]*Peer),
+	}
+	newKey := func() ed25519.PublicKey {
+		key, _, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			t.Fatalf("GenerateKey() failed: %v", err)
+		}
+		return key
+	}
+	busy, err := g.peer(newKey())
+	if err != nil {
+		t.Fatalf("peer() failed: %v", err)
+	}
+	busy.lastActive.Store(0)
+	busy.pendingQueries.Add(1)
+	idle, err := g.peer(newKey())
+	if err != nil {
+		t.Fatalf("peer() failed: %v", err)
+	}
+	idle.lastActive.Store(1)
+	g.channels[tl.Int256{1}] = idle
+	for len(g.peers) < maxPeers {
+		if _, err := g.peer(newKey()); err != nil {
+			t.Fatalf("peer() failed: %v", err)
+		}
+	}
+	if _, err := g.peer(newKey()); err != nil {
+		t.Fatalf("peer() failed: %v", err)
+	}
+	if len(g.peers) != maxPeers {
+		t.Fatalf("want %v peers, got: %v", maxPeers, len(g.peers))
+	}
+	if _, ok := g.peers[idle.id]; ok {
+		t.Fatalf("the least recently active peer must be evicted")
+	}
+	if len(g.channels) != 0 {
+		t.Fatalf("channels of an evicted peer must be removed")
+	}
+	if g.peers[busy.id] != busy {
+		t.Fatalf("peers waiting for answers must be kept")
+	}
+	// the evicted peer is used again.
+	idle.lastActive.Store(time.Now().UnixNano())
+	g.restorePeer(idle, &tl.Int256{1})
+	if g.peers[idle.id] != idle || g.channels[tl.Int256{1}] != idle {
+		t.Fatalf("a peer sending packets must be restored with its channel")
+	}
+	if len(g.peers) != maxPeers {
+		t.Fatalf("want %v peers, got: %v", maxPeers, len(g.peers))
+	}
+}
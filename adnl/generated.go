@@ -0,0 +1,2936 @@
+package adnl
+
+// Code autogenerated. DO NOT EDIT.
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"github.com/tonkeeper/tongo/tl"
+	"io"
+)
+
+type PublicKey struct {
+	tl.SumType
+	PubUnenc struct {
+		Data []byte
+	}
+	PubEd25519 struct {
+		Key tl.Int256
+	}
+	PubAes struct {
+		Key tl.Int256
+	}
+	PubOverlay struct {
+		Name []byte
+	}
+}
+
+func (t PublicKey) MarshalTL() ([]byte, error) {
+	var (
+		err error
+		b   []byte
+	)
+	buf := new(bytes.Buffer)
+	switch t.SumType {
+	case "PubUnenc":
+		b, err = tl.Marshal(uint32(0xb61f450a))
+		if err != nil {
+			return nil, err
+		}
+		_, err = buf.Write(b)
+		b, err = tl.Marshal(t.PubUnenc.Data)
+		if err != nil {
+			return nil, err
+		}
+		_, err = buf.Write(b)
+		if err != nil {
+			return nil, err
+		}
+	case "PubEd25519":
+		b, err = tl.Marshal(uint32(0x4813b4c6))
+		if err != nil {
+			return nil, err
+		}
+		_, err = buf.Write(b)
+		b, err = tl.Marshal(t.PubEd25519.Key)
+		if err != nil {
+			return nil, err
+		}
+		_, err = buf.Write(b)
+		if err != nil {
+			return nil, err
+		}
+	case "PubAes":
+		b, err = tl.Marshal(uint32(0x2dbcadd4))
+		if err != nil {
+			return nil, err
+		}
+		_, err = buf.Write(b)
+		b, err = tl.Marshal(t.PubAes.Key)
+		if err != nil {
+			return nil, err
+		}
+		_, err = buf.Write(b)
+		if err != nil {
+			return nil, err
+		}
+	case "PubOverlay":
+		b, err = tl.Marshal(uint32(0x34ba45cb))
+		if err != nil {
+			return nil, err
+		}
+		_, err = buf.Write(b)
+		b, err = tl.Marshal(t.PubOverlay.Name)
+		if err != nil {
+			return nil, err
+		}
+		_, err = buf.Write(b)
+		if err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("invalid sum type")
+	}
+	return buf.Bytes(), nil
+}
+
+func (t *PublicKey) UnmarshalTL(r io.Reader) error {
+	var err error
+	var b [4]byte
+	_, err = io.ReadFull(r, b[:])
+	if err != nil {
+		return err
+	}
+	tag := int(binary.LittleEndian.Uint32(b[:]))
+	switch tag {
+	case 0xb61f450a:
+		t.SumType = "PubUnenc"
+		err = tl.Unmarshal(r, &t.PubUnenc.Data)
+		if err != nil {
+			return err
+		}
+	case 0x4813b4c6:
+		t.SumType = "PubEd25519"
+		err = tl.Unmarshal(r, &t.PubEd25519.Key)
+		if err != nil {
+			return err
+		}
+	case 0x2dbcadd4:
+		t.SumType = "PubAes"
+		err = tl.Unmarshal(r, &t.PubAes.Key)
+		if err != nil {
+			return err
+		}
+	case 0x34ba45cb:
+		t.SumType = "PubOverlay"
+		err = tl.Unmarshal(r, &t.PubOverlay.Name)
+		if err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("invalid tag")
+	}
+	return nil
+}
+
+type AdnlIdShortC struct {
+	Id tl.Int256
+}
+
+func (t AdnlIdShortC) MarshalTL() ([]byte, error) {
+	var (
+		err error
+		b   []byte
+	)
+	buf := new(bytes.Buffer)
+	b, err = tl.Marshal(t.Id)
+	if err != nil {
+		return nil, err
+	}
+	_, err = buf.Write(b)
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (t *AdnlIdShortC) UnmarshalTL(r io.Reader) error {
+	var err error
+	err = tl.Unmarshal(r, &t.Id)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+type AdnlAddress struct {
+	tl.SumType
+	AdnlAddressUdp struct {
+		Ip   uint32
+		Port uint32
+	}
+	AdnlAddressUdp6 struct {
+		Ip   tl.Int128
+		Port uint32
+	}
+}
+
+func (t AdnlAddress) MarshalTL() ([]byte, error) {
+	var (
+		err error
+		b   []byte
+	)
+	buf := new(bytes.Buffer)
+	switch t.SumType {
+	case "AdnlAddressUdp":
+		b, err = tl.Marshal(uint32(0x670da6e7))
+		if err != nil {
+			return nil, err
+		}
+		_, err = buf.Write(b)
+		b, err = tl.Marshal(t.AdnlAddressUdp.Ip)
+		if err != nil {
+			return nil, err
+		}
+		_, err = buf.Write(b)
+		if err != nil {
+			return nil, err
+		}
+		b, err = tl.Marshal(t.AdnlAddressUdp.Port)
+		if err != nil {
+			return nil, err
+		}
+		_, err = buf.Write(b)
+		if err != nil {
+			return nil, err
+		}
+	case "AdnlAddressUdp6":
+		b, err = tl.Marshal(uint32(0xe31d63fa))
+		if err != nil {
+			return nil, err
+		}
+		_, err = buf.Write(b)
+		b, err = tl.Marshal(t.AdnlAddressUdp6.Ip)
+		if err != nil {
+			return nil, err
+		}
+		_, err = buf.Write(b)
+		if err != nil {
+			return nil, err
+		}
+		b, err = tl.Marshal(t.AdnlAddressUdp6.Port)
+		if err != nil {
+			return nil, err
+		}
+		_, err = buf.Write(b)
+		if err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("invalid sum type")
+	}
+	return buf.Bytes(), nil
+}
+
+func (t *AdnlAddress) UnmarshalTL(r io.Reader) error {
+	var err error
+	var b [4]byte
+	_, err = io.ReadFull(r, b[:])
+	if err != nil {
+		return err
+	}
+	tag := int(binary.LittleEndian.Uint32(b[:]))
+	switch tag {
+	case 0x670da6e7:
+		t.SumType = "AdnlAddressUdp"
+		err = tl.Unmarshal(r, &t.AdnlAddressUdp.Ip)
+		if err != nil {
+			return err
+		}
+		err = tl.Unmarshal(r, &t.AdnlAddressUdp.Port)
+		if err != nil {
+			return err
+		}
+	case 0xe31d63fa:
+		t.SumType = "AdnlAddressUdp6"
+		err = tl.Unmarshal(r, &t.AdnlAddressUdp6.Ip)
+		if err != nil {
+			return err
+		}
+		err = tl.Unmarshal(r, &t.AdnlAddressUdp6.Port)
+		if err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("invalid tag")
+	}
+	return nil
+}
+
+type AdnlAddressListC struct {
+	Addrs      []AdnlAddress
+	Version    uint32
+	ReinitDate uint32
+	Priority   uint32
+	ExpireAt   uint32
+}
+
+func (t AdnlAddressListC) MarshalTL() ([]byte, error) {
+	var (
+		err error
+		b   []byte
+	)
+	buf := new(bytes.Buffer)
+	b, err = tl.Marshal(t.Addrs)
+	if err != nil {
+		return nil, err
+	}
+	_, err = buf.Write(b)
+	if err != nil {
+		return nil, err
+	}
+	b, err = tl.Marshal(t.Version)
+	if err != nil {
+		return nil, err
+	}
+	_, err = buf.Write(b)
+	if err != nil {
+		return nil, err
+	}
+	b, err = tl.Marshal(t.ReinitDate)
+	if err != nil {
+		return nil, err
+	}
+	_, err = buf.Write(b)
+	if err != nil {
+		return nil, err
+	}
+	b, err = tl.Marshal(t.Priority)
+	if err != nil {
+		return nil, err
+	}
+	_, err = buf.Write(b)
+	if err != nil {
+		return nil, err
+	}
+	b, err = tl.Marshal(t.ExpireAt)
+	if err != nil {
+		return nil, err
+	}
+	_, err = buf.Write(b)
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (t *AdnlAddressListC) UnmarshalTL(r io.Reader) error {
+	var err error
+	err = tl.Unmarshal(r, &t.Addrs)
+	if err != nil {
+		return err
+	}
+	err = tl.Unmarshal(r, &t.Version)
+	if err != nil {
+		return err
+	}
+	err = tl.Unmarshal(r, &t.ReinitDate)
+	if err != nil {
+		return err
+	}
+	err = tl.Unmarshal(r, &t.Priority)
+	if err != nil {
+		return err
+	}
+	err = tl.Unmarshal(r, &t.ExpireAt)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+type AdnlMessage struct {
+	tl.SumType
+	AdnlMessageCreateChannel struct {
+		Key  tl.Int256
+		Date uint32
+	}
+	AdnlMessageConfirmChannel struct {
+		Key     tl.Int256
+		PeerKey tl.Int256
+		Date    uint32
+	}
+	AdnlMessageCustom struct {
+		Data []byte
+	}
+	AdnlMessageNop    struct{}
+	AdnlMessageReinit struct {
+		Date uint32
+	}
+	AdnlMessageQuery struct {
+		QueryId tl.Int256
+		Query   []byte
+	}
+	AdnlMessageAnswer struct {
+		QueryId tl.Int256
+		Answer  []byte
+	}
+	AdnlMessagePart struct {
+		Hash      tl.Int256
+		TotalSize uint32
+		Offset    uint32
+		Data      []byte
+	}
+}
+
+func (t AdnlMessage) MarshalTL() ([]byte, error) {
+	var (
+		err error
+		b   []byte
+	)
+	buf := new(bytes.Buffer)
+	switch t.SumType {
+	case "AdnlMessageCreateChannel":
+		b, err = tl.Marshal(uint32(0xe673c3bb))
+		if err != nil {
+			return nil, err
+		}
+		_, err = buf.Write(b)
+		b, err = tl.Marshal(t.AdnlMessageCreateChannel.Key)
+		if err != nil {
+			return nil, err
+		}
+		_, err = buf.Write(b)
+		if err != nil {
+			return nil, err
+		}
+		b, err = tl.Marshal(t.AdnlMessageCreateChannel.Date)
+		if err != nil {
+			return nil, err
+		}
+		_, err = buf.Write(b)
+		if err != nil {
+			return nil, err
+		}
+	case "AdnlMessageConfirmChannel":
+		b, err = tl.Marshal(uint32(0x60dd1d69))
+		if err != nil {
+			return nil, err
+		}
+		_, err = buf.Write(b)
+		b, err = tl.Marshal(t.AdnlMessageConfirmChannel.Key)
+		if err != nil {
+			return nil, err
+		}
+		_, err = buf.Write(b)
+		if err != nil {
+			return nil, err
+		}
+		b, err = tl.Marshal(t.AdnlMessageConfirmChannel.PeerKey)
+		if err != nil {
+			return nil, err
+		}
+		_, err = buf.Write(b)
+		if err != nil {
+			return nil, err
+		}
+		b, err = tl.Marshal(t.AdnlMessageConfirmChannel.Date)
+		if err != nil {
+			return nil, err
+		}
+		_, err = buf.Write(b)
+		if err != nil {
+			return nil, err
+		}
+	case "AdnlMessageCustom":
+		b, err = tl.Marshal(uint32(0x204818f5))
+		if err != nil {
+			return nil, err
+		}
+		_, err = buf.Write(b)
+		b, err = tl.Marshal(t.AdnlMessageCustom.Data)
+		if err != nil {
+			return nil, err
+		}
+		_, err = buf.Write(b)
+		if err != nil {
+			return nil, err
+		}
+	case "AdnlMessageNop":
+		b, err = tl.Marshal(uint32(0x17f8dfda))
+		if err != nil {
+			return nil, err
+		}
+		_, err = buf.Write(b)
+	case "AdnlMessageReinit":
+		b, err = tl.Marshal(uint32(0x10c20520))
+		if err != nil {
+			return nil, err
+		}
+		_, err = buf.Write(b)
+		b, err = tl.Marshal(t.AdnlMessageReinit.Date)
+		if err != nil {
+			return nil, err
+		}
+		_, err = buf.Write(b)
+		if err != nil {
+			return nil, err
+		}
+	case "AdnlMessageQuery":
+		b, err = tl.Marshal(uint32(0xb48bf97a))
+		if err != nil {
+			return nil, err
+		}
+		_, err = buf.Write(b)
+		b, err = tl.Marshal(t.AdnlMessageQuery.QueryId)
+		if err != nil {
+			return nil, err
+		}
+		_, err = buf.Write(b)
+		if err != nil {
+			return nil, err
+		}
+		b, err = tl.Marshal(t.AdnlMessageQuery.Query)
+		if err != nil {
+			return nil, err
+		}
+		_, err = buf.Write(b)
+		if err != nil {
+			return nil, err
+		}
+	case "AdnlMessageAnswer":
+		b, err = tl.Marshal(uint32(0xfac8416))
+		if err != nil {
+			return nil, err
+		}
+		_, err = buf.Write(b)
+		b, err = tl.Marshal(t.AdnlMessageAnswer.QueryId)
+		if err != nil {
+			return nil, err
+		}
+		_, err = buf.Write(b)
+		if err != nil {
+			return nil, err
+		}
+		b, err = tl.Marshal(t.AdnlMessageAnswer.Answer)
+		if err != nil {
+			return nil, err
+		}
+		_, err = buf.Write(b)
+		if err != nil {
+			return nil, err
+		}
+	case "AdnlMessagePart":
+		b, err = tl.Marshal(uint32(0xfd452d39))
+		if err != nil {
+			return nil, err
+		}
+		_, err = buf.Write(b)
+		b, err = tl.Marshal(t.AdnlMessagePart.Hash)
+		if err != nil {
+			return nil, err
+		}
+		_, err = buf.Write(b)
+		if err != nil {
+			return nil, err
+		}
+		b, err = tl.Marshal(t.AdnlMessagePart.TotalSize)
+		if err != nil {
+			return nil, err
+		}
+		_, err = buf.Write(b)
+		if err != nil {
+			return nil, err
+		}
+		b, err = tl.Marshal(t.AdnlMessagePart.Offset)
+		if err != nil {
+			return nil, err
+		}
+		_, err = buf.Write(b)
+		if err != nil {
+			return nil, err
+		}
+		b, err = tl.Marshal(t.AdnlMessagePart.Data)
+		if err != nil {
+			return nil, err
+		}
+		_, err = buf.Write(b)
+		if err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("invalid sum type")
+	}
+	return buf.Bytes(), nil
+}
+
+func (t *AdnlMessage) UnmarshalTL(r io.Reader) error {
+	var err error
+	var b [4]byte
+	_, err = io.ReadFull(r, b[:])
+	if err != nil {
+		return err
+	}
+	tag := int(binary.LittleEndian.Uint32(b[:]))
+	switch tag {
+	case 0xe673c3bb:
+		t.SumType = "AdnlMessageCreateChannel"
+		err = tl.Unmarshal(r, &t.AdnlMessageCreateChannel.Key)
+		if err != nil {
+			return err
+		}
+		err = tl.Unmarshal(r, &t.AdnlMessageCreateChannel.Date)
+		if err != nil {
+			return err
+		}
+	case 0x60dd1d69:
+		t.SumType = "AdnlMessageConfirmChannel"
+		err = tl.Unmarshal(r, &t.AdnlMessageConfirmChannel.Key)
+		if err != nil {
+			return err
+		}
+		err = tl.Unmarshal(r, &t.AdnlMessageConfirmChannel.PeerKey)
+		if err != nil {
+			return err
+		}
+		err = tl.Unmarshal(r, &t.AdnlMessageConfirmChannel.Date)
+		if err != nil {
+			return err
+		}
+	case 0x204818f5:
+		t.SumType = "AdnlMessageCustom"
+		err = tl.Unmarshal(r, &t.AdnlMessageCustom.Data)
+		if err != nil {
+			return err
+		}
+	case 0x17f8dfda:
+		t.SumType = "AdnlMessageNop"
+	case 0x10c20520:
+		t.SumType = "AdnlMessageReinit"
+		err = tl.Unmarshal(r, &t.AdnlMessageReinit.Date)
+		if err != nil {
+			return err
+		}
+	case 0xb48bf97a:
+		t.SumType = "AdnlMessageQuery"
+		err = tl.Unmarshal(r, &t.AdnlMessageQuery.QueryId)
+		if err != nil {
+			return err
+		}
+		err = tl.Unmarshal(r, &t.AdnlMessageQuery.Query)
+		if err != nil {
+			return err
+		}
+	case 0xfac8416:
+		t.SumType = "AdnlMessageAnswer"
+		err = tl.Unmarshal(r, &t.AdnlMessageAnswer.QueryId)
+		if err != nil {
+			return err
+		}
+		err = tl.Unmarshal(r, &t.AdnlMessageAnswer.Answer)
+		if err != nil {
+			return err
+		}
+	case 0xfd452d39:
+		t.SumType = "AdnlMessagePart"
+		err = tl.Unmarshal(r, &t.AdnlMessagePart.Hash)
+		if err != nil {
+			return err
+		}
+		err = tl.Unmarshal(r, &t.AdnlMessagePart.TotalSize)
+		if err != nil {
+			return err
+		}
+		err = tl.Unmarshal(r, &t.AdnlMessagePart.Offset)
+		if err != nil {
+			return err
+		}
+		err = tl.Unmarshal(r, &t.AdnlMessagePart.Data)
+		if err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("invalid tag")
+	}
+	return nil
+}
+
+type AdnlPacketContentsC struct {
+	Rand1                       []byte
+	Flags                       uint32
+	From                        *PublicKey
+	FromShort                   *AdnlIdShortC
+	Message                     *AdnlMessage
+	Messages                    []AdnlMessage
+	Address                     *AdnlAddressListC
+	PriorityAddress             *AdnlAddressListC
+	Seqno                       *uint64
+	ConfirmSeqno                *uint64
+	RecvAddrListVersion         *uint32
+	RecvPriorityAddrListVersion *uint32
+	ReinitDate                  *uint32
+	DstReinitDate               *uint32
+	Signature                   []byte
+	Rand2                       []byte
+}
+
+func (t AdnlPacketContentsC) MarshalTL() ([]byte, error) {
+	var (
+		err error
+		b   []byte
+	)
+	buf := new(bytes.Buffer)
+	b, err = tl.Marshal(t.Rand1)
+	if err != nil {
+		return nil, err
+	}
+	_, err = buf.Write(b)
+	if err != nil {
+		return nil, err
+	}
+	b, err = tl.Marshal(t.Flags)
+	if err != nil {
+		return nil, err
+	}
+	_, err = buf.Write(b)
+	if err != nil {
+		return nil, err
+	}
+	if (t.Flags>>0)&1 == 1 {
+		b, err = tl.Marshal(t.From)
+		if err != nil {
+			return nil, err
+		}
+		_, err = buf.Write(b)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if (t.Flags>>1)&1 == 1 {
+		b, err = tl.Marshal(t.FromShort)
+		if err != nil {
+			return nil, err
+		}
+		_, err = buf.Write(b)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if (t.Flags>>2)&1 == 1 {
+		b, err = tl.Marshal(t.Message)
+		if err != nil {
+			return nil, err
+		}
+		_, err = buf.Write(b)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if (t.Flags>>3)&1 == 1 {
+		b, err = tl.Marshal(t.Messages)
+		if err != nil {
+			return nil, err
+		}
+		_, err = buf.Write(b)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if (t.Flags>>4)&1 == 1 {
+		b, err = tl.Marshal(t.Address)
+		if err != nil {
+			return nil, err
+		}
+		_, err = buf.Write(b)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if (t.Flags>>5)&1 == 1 {
+		b, err = tl.Marshal(t.PriorityAddress)
+		if err != nil {
+			return nil, err
+		}
+		_, err = buf.Write(b)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if (t.Flags>>6)&1 == 1 {
+		b, err = tl.Marshal(t.Seqno)
+		if err != nil {
+			return nil, err
+		}
+		_, err = buf.Write(b)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if (t.Flags>>7)&1 == 1 {
+		b, err = tl.Marshal(t.ConfirmSeqno)
+		if err != nil {
+			return nil, err
+		}
+		_, err = buf.Write(b)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if (t.Flags>>8)&1 == 1 {
+		b, err = tl.Marshal(t.RecvAddrListVersion)
+		if err != nil {
+			return nil, err
+		}
+		_, err = buf.Write(b)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if (t.Flags>>9)&1 == 1 {
+		b, err = tl.Marshal(t.RecvPriorityAddrListVersion)
+		if err != nil {
+			return nil, err
+		}
+		_, err = buf.Write(b)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if (t.Flags>>10)&1 == 1 {
+		b, err = tl.Marshal(t.ReinitDate)
+		if err != nil {
+			return nil, err
+		}
+		_, err = buf.Write(b)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if (t.Flags>>10)&1 == 1 {
+		b, err = tl.Marshal(t.DstReinitDate)
+		if err != nil {
+			return nil, err
+		}
+		_, err = buf.Write(b)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if (t.Flags>>11)&1 == 1 {
+		b, err = tl.Marshal(t.Signature)
+		if err != nil {
+			return nil, err
+		}
+		_, err = buf.Write(b)
+		if err != nil {
+			return nil, err
+		}
+	}
+	b, err = tl.Marshal(t.Rand2)
+	if err != nil {
+		return nil, err
+	}
+	_, err = buf.Write(b)
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (t *AdnlPacketContentsC) UnmarshalTL(r io.Reader) error {
+	var err error
+	err = tl.Unmarshal(r, &t.Rand1)
+	if err != nil {
+		return err
+	}
+	err = tl.Unmarshal(r, &t.Flags)
+	if err != nil {
+		return err
+	}
+	if (t.Flags>>0)&1 == 1 {
+		var tempFrom PublicKey
+		err = tl.Unmarshal(r, &tempFrom)
+		if err != nil {
+			return err
+		}
+		t.From = &tempFrom
+	}
+	if (t.Flags>>1)&1 == 1 {
+		var tempFromShort AdnlIdShortC
+		err = tl.Unmarshal(r, &tempFromShort)
+		if err != nil {
+			return err
+		}
+		t.FromShort = &tempFromShort
+	}
+	if (t.Flags>>2)&1 == 1 {
+		var tempMessage AdnlMessage
+		err = tl.Unmarshal(r, &tempMessage)
+		if err != nil {
+			return err
+		}
+		t.Message = &tempMessage
+	}
+	if (t.Flags>>3)&1 == 1 {
+		var tempMessages []AdnlMessage
+		err = tl.Unmarshal(r, &tempMessages)
+		if err != nil {
+			return err
+		}
+		t.Messages = tempMessages
+	}
+	if (t.Flags>>4)&1 == 1 {
+		var tempAddress AdnlAddressListC
+		err = tl.Unmarshal(r, &tempAddress)
+		if err != nil {
+			return err
+		}
+		t.Address = &tempAddress
+	}
+	if (t.Flags>>5)&1 == 1 {
+		var tempPriorityAddress AdnlAddressListC
+		err = tl.Unmarshal(r, &tempPriorityAddress)
+		if err != nil {
+			return err
+		}
+		t.PriorityAddress = &tempPriorityAddress
+	}
+	if (t.Flags>>6)&1 == 1 {
+		var tempSeqno uint64
+		err = tl.Unmarshal(r, &tempSeqno)
+		if err != nil {
+			return err
+		}
+		t.Seqno = &tempSeqno
+	}
+	if (t.Flags>>7)&1 == 1 {
+		var tempConfirmSeqno uint64
+		err = tl.Unmarshal(r, &tempConfirmSeqno)
+		if err != nil {
+			return err
+		}
+		t.ConfirmSeqno = &tempConfirmSeqno
+	}
+	if (t.Flags>>8)&1 == 1 {
+		var tempRecvAddrListVersion uint32
+		err = tl.Unmarshal(r, &tempRecvAddrListVersion)
+		if err != nil {
+			return err
+		}
+		t.RecvAddrListVersion = &tempRecvAddrListVersion
+	}
+	if (t.Flags>>9)&1 == 1 {
+		var tempRecvPriorityAddrListVersion uint32
+		err = tl.Unmarshal(r, &tempRecvPriorityAddrListVersion)
+		if err != nil {
+			return err
+		}
+		t.RecvPriorityAddrListVersion = &tempRecvPriorityAddrListVersion
+	}
+	if (t.Flags>>10)&1 == 1 {
+		var tempReinitDate uint32
+		err = tl.Unmarshal(r, &tempReinitDate)
+		if err != nil {
+			return err
+		}
+		t.ReinitDate = &tempReinitDate
+	}
+	if (t.Flags>>10)&1 == 1 {
+		var tempDstReinitDate uint32
+		err = tl.Unmarshal(r, &tempDstReinitDate)
+		if err != nil {
+			return err
+		}
+		t.DstReinitDate = &tempDstReinitDate
+	}
+	if (t.Flags>>11)&1 == 1 {
+		var tempSignature []byte
+		err = tl.Unmarshal(r, &tempSignature)
+		if err != nil {
+			return err
+		}
+		t.Signature = tempSignature
+	}
+	err = tl.Unmarshal(r, &t.Rand2)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+type AdnlPongC struct {
+	Value uint64
+}
+
+func (t AdnlPongC) MarshalTL() ([]byte, error) {
+	var (
+		err error
+		b   []byte
+	)
+	buf := new(bytes.Buffer)
+	b, err = tl.Marshal(t.Value)
+	if err != nil {
+		return nil, err
+	}
+	_, err = buf.Write(b)
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (t *AdnlPongC) UnmarshalTL(r io.Reader) error {
+	var err error
+	err = tl.Unmarshal(r, &t.Value)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+type DhtNodeC struct {
+	Id        PublicKey
+	AddrList  AdnlAddressListC
+	Version   uint32
+	Signature []byte
+}
+
+func (t DhtNodeC) MarshalTL() ([]byte, error) {
+	var (
+		err error
+		b   []byte
+	)
+	buf := new(bytes.Buffer)
+	b, err = tl.Marshal(t.Id)
+	if err != nil {
+		return nil, err
+	}
+	_, err = buf.Write(b)
+	if err != nil {
+		return nil, err
+	}
+	b, err = tl.Marshal(t.AddrList)
+	if err != nil {
+		return nil, err
+	}
+	_, err = buf.Write(b)
+	if err != nil {
+		return nil, err
+	}
+	b, err = tl.Marshal(t.Version)
+	if err != nil {
+		return nil, err
+	}
+	_, err = buf.Write(b)
+	if err != nil {
+		return nil, err
+	}
+	b, err = tl.Marshal(t.Signature)
+	if err != nil {
+		return nil, err
+	}
+	_, err = buf.Write(b)
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (t *DhtNodeC) UnmarshalTL(r io.Reader) error {
+	var err error
+	err = tl.Unmarshal(r, &t.Id)
+	if err != nil {
+		return err
+	}
+	err = tl.Unmarshal(r, &t.AddrList)
+	if err != nil {
+		return err
+	}
+	err = tl.Unmarshal(r, &t.Version)
+	if err != nil {
+		return err
+	}
+	err = tl.Unmarshal(r, &t.Signature)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+type DhtNodesC struct {
+	Nodes []DhtNodeC
+}
+
+func (t DhtNodesC) MarshalTL() ([]byte, error) {
+	var (
+		err error
+		b   []byte
+	)
+	buf := new(bytes.Buffer)
+	b, err = tl.Marshal(t.Nodes)
+	if err != nil {
+		return nil, err
+	}
+	_, err = buf.Write(b)
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (t *DhtNodesC) UnmarshalTL(r io.Reader) error {
+	var err error
+	err = tl.Unmarshal(r, &t.Nodes)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+type DhtKeyC struct {
+	Id   tl.Int256
+	Name []byte
+	Idx  uint32
+}
+
+func (t DhtKeyC) MarshalTL() ([]byte, error) {
+	var (
+		err error
+		b   []byte
+	)
+	buf := new(bytes.Buffer)
+	b, err = tl.Marshal(t.Id)
+	if err != nil {
+		return nil, err
+	}
+	_, err = buf.Write(b)
+	if err != nil {
+		return nil, err
+	}
+	b, err = tl.Marshal(t.Name)
+	if err != nil {
+		return nil, err
+	}
+	_, err = buf.Write(b)
+	if err != nil {
+		return nil, err
+	}
+	b, err = tl.Marshal(t.Idx)
+	if err != nil {
+		return nil, err
+	}
+	_, err = buf.Write(b)
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (t *DhtKeyC) UnmarshalTL(r io.Reader) error {
+	var err error
+	err = tl.Unmarshal(r, &t.Id)
+	if err != nil {
+		return err
+	}
+	err = tl.Unmarshal(r, &t.Name)
+	if err != nil {
+		return err
+	}
+	err = tl.Unmarshal(r, &t.Idx)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+type DhtUpdateRule struct {
+	tl.SumType
+	DhtUpdateRuleSignature    struct{}
+	DhtUpdateRuleAnybody      struct{}
+	DhtUpdateRuleOverlayNodes struct{}
+}
+
+func (t DhtUpdateRule) MarshalTL() ([]byte, error) {
+	var (
+		err error
+		b   []byte
+	)
+	buf := new(bytes.Buffer)
+	switch t.SumType {
+	case "DhtUpdateRuleSignature":
+		b, err = tl.Marshal(uint32(0xcc9f31f7))
+		if err != nil {
+			return nil, err
+		}
+		_, err = buf.Write(b)
+	case "DhtUpdateRuleAnybody":
+		b, err = tl.Marshal(uint32(0x61578e14))
+		if err != nil {
+			return nil, err
+		}
+		_, err = buf.Write(b)
+	case "DhtUpdateRuleOverlayNodes":
+		b, err = tl.Marshal(uint32(0x26779383))
+		if err != nil {
+			return nil, err
+		}
+		_, err = buf.Write(b)
+	default:
+		return nil, fmt.Errorf("invalid sum type")
+	}
+	return buf.Bytes(), nil
+}
+
+func (t *DhtUpdateRule) UnmarshalTL(r io.Reader) error {
+	var err error
+	var b [4]byte
+	_, err = io.ReadFull(r, b[:])
+	if err != nil {
+		return err
+	}
+	tag := int(binary.LittleEndian.Uint32(b[:]))
+	switch tag {
+	case 0xcc9f31f7:
+		t.SumType = "DhtUpdateRuleSignature"
+	case 0x61578e14:
+		t.SumType = "DhtUpdateRuleAnybody"
+	case 0x26779383:
+		t.SumType = "DhtUpdateRuleOverlayNodes"
+	default:
+		return fmt.Errorf("invalid tag")
+	}
+	return nil
+}
+
+type DhtKeyDescriptionC struct {
+	Key        DhtKeyC
+	Id         PublicKey
+	UpdateRule DhtUpdateRule
+	Signature  []byte
+}
+
+func (t DhtKeyDescriptionC) MarshalTL() ([]byte, error) {
+	var (
+		err error
+		b   []byte
+	)
+	buf := new(bytes.Buffer)
+	b, err = tl.Marshal(t.Key)
+	if err != nil {
+		return nil, err
+	}
+	_, err = buf.Write(b)
+	if err != nil {
+		return nil, err
+	}
+	b, err = tl.Marshal(t.Id)
+	if err != nil {
+		return nil, err
+	}
+	_, err = buf.Write(b)
+	if err != nil {
+		return nil, err
+	}
+	b, err = tl.Marshal(t.UpdateRule)
+	if err != nil {
+		return nil, err
+	}
+	_, err = buf.Write(b)
+	if err != nil {
+		return nil, err
+	}
+	b, err = tl.Marshal(t.Signature)
+	if err != nil {
+		return nil, err
+	}
+	_, err = buf.Write(b)
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (t *DhtKeyDescriptionC) UnmarshalTL(r io.Reader) error {
+	var err error
+	err = tl.Unmarshal(r, &t.Key)
+	if err != nil {
+		return err
+	}
+	err = tl.Unmarshal(r, &t.Id)
+	if err != nil {
+		return err
+	}
+	err = tl.Unmarshal(r, &t.UpdateRule)
+	if err != nil {
+		return err
+	}
+	err = tl.Unmarshal(r, &t.Signature)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+type DhtValueC struct {
+	Key       DhtKeyDescriptionC
+	Value     []byte
+	Ttl       uint32
+	Signature []byte
+}
+
+func (t DhtValueC) MarshalTL() ([]byte, error) {
+	var (
+		err error
+		b   []byte
+	)
+	buf := new(bytes.Buffer)
+	b, err = tl.Marshal(t.Key)
+	if err != nil {
+		return nil, err
+	}
+	_, err = buf.Write(b)
+	if err != nil {
+		return nil, err
+	}
+	b, err = tl.Marshal(t.Value)
+	if err != nil {
+		return nil, err
+	}
+	_, err = buf.Write(b)
+	if err != nil {
+		return nil, err
+	}
+	b, err = tl.Marshal(t.Ttl)
+	if err != nil {
+		return nil, err
+	}
+	_, err = buf.Write(b)
+	if err != nil {
+		return nil, err
+	}
+	b, err = tl.Marshal(t.Signature)
+	if err != nil {
+		return nil, err
+	}
+	_, err = buf.Write(b)
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (t *DhtValueC) UnmarshalTL(r io.Reader) error {
+	var err error
+	err = tl.Unmarshal(r, &t.Key)
+	if err != nil {
+		return err
+	}
+	err = tl.Unmarshal(r, &t.Value)
+	if err != nil {
+		return err
+	}
+	err = tl.Unmarshal(r, &t.Ttl)
+	if err != nil {
+		return err
+	}
+	err = tl.Unmarshal(r, &t.Signature)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+type DhtPongC struct {
+	RandomId uint64
+}
+
+func (t DhtPongC) MarshalTL() ([]byte, error) {
+	var (
+		err error
+		b   []byte
+	)
+	buf := new(bytes.Buffer)
+	b, err = tl.Marshal(t.RandomId)
+	if err != nil {
+		return nil, err
+	}
+	_, err = buf.Write(b)
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (t *DhtPongC) UnmarshalTL(r io.Reader) error {
+	var err error
+	err = tl.Unmarshal(r, &t.RandomId)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+type DhtValueResult struct {
+	tl.SumType
+	DhtValueNotFound struct {
+		Nodes DhtNodesC
+	}
+	DhtValueFound struct {
+		Value DhtValue
+	}
+}
+
+func (t DhtValueResult) MarshalTL() ([]byte, error) {
+	var (
+		err error
+		b   []byte
+	)
+	buf := new(bytes.Buffer)
+	switch t.SumType {
+	case "DhtValueNotFound":
+		b, err = tl.Marshal(uint32(0xa2620568))
+		if err != nil {
+			return nil, err
+		}
+		_, err = buf.Write(b)
+		b, err = tl.Marshal(t.DhtValueNotFound.Nodes)
+		if err != nil {
+			return nil, err
+		}
+		_, err = buf.Write(b)
+		if err != nil {
+			return nil, err
+		}
+	case "DhtValueFound":
+		b, err = tl.Marshal(uint32(0xe40cf774))
+		if err != nil {
+			return nil, err
+		}
+		_, err = buf.Write(b)
+		b, err = tl.Marshal(t.DhtValueFound.Value)
+		if err != nil {
+			return nil, err
+		}
+		_, err = buf.Write(b)
+		if err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("invalid sum type")
+	}
+	return buf.Bytes(), nil
+}
+
+func (t *DhtValueResult) UnmarshalTL(r io.Reader) error {
+	var err error
+	var b [4]byte
+	_, err = io.ReadFull(r, b[:])
+	if err != nil {
+		return err
+	}
+	tag := int(binary.LittleEndian.Uint32(b[:]))
+	switch tag {
+	case 0xa2620568:
+		t.SumType = "DhtValueNotFound"
+		err = tl.Unmarshal(r, &t.DhtValueNotFound.Nodes)
+		if err != nil {
+			return err
+		}
+	case 0xe40cf774:
+		t.SumType = "DhtValueFound"
+		err = tl.Unmarshal(r, &t.DhtValueFound.Value)
+		if err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("invalid tag")
+	}
+	return nil
+}
+
+type DhtStoredC struct{}
+
+func (t DhtStoredC) MarshalTL() ([]byte, error) {
+	return nil, nil
+}
+
+func (t *DhtStoredC) UnmarshalTL(r io.Reader) error {
+	return nil
+}
+
+type FecType struct {
+	tl.SumType
+	FecRaptorQ struct {
+		DataSize     uint32
+		SymbolSize   uint32
+		SymbolsCount uint32
+	}
+	FecRoundRobin struct {
+		DataSize     uint32
+		SymbolSize   uint32
+		SymbolsCount uint32
+	}
+	FecOnline struct {
+		DataSize     uint32
+		SymbolSize   uint32
+		SymbolsCount uint32
+	}
+}
+
+func (t FecType) MarshalTL() ([]byte, error) {
+	var (
+		err error
+		b   []byte
+	)
+	buf := new(bytes.Buffer)
+	switch t.SumType {
+	case "FecRaptorQ":
+		b, err = tl.Marshal(uint32(0x8b93a7e0))
+		if err != nil {
+			return nil, err
+		}
+		_, err = buf.Write(b)
+		b, err = tl.Marshal(t.FecRaptorQ.DataSize)
+		if err != nil {
+			return nil, err
+		}
+		_, err = buf.Write(b)
+		if err != nil {
+			return nil, err
+		}
+		b, err = tl.Marshal(t.FecRaptorQ.SymbolSize)
+		if err != nil {
+			return nil, err
+		}
+		_, err = buf.Write(b)
+		if err != nil {
+			return nil, err
+		}
+		b, err = tl.Marshal(t.FecRaptorQ.SymbolsCount)
+		if err != nil {
+			return nil, err
+		}
+		_, err = buf.Write(b)
+		if err != nil {
+			return nil, err
+		}
+	case "FecRoundRobin":
+		b, err = tl.Marshal(uint32(0x32f528e4))
+		if err != nil {
+			return nil, err
+		}
+		_, err = buf.Write(b)
+		b, err = tl.Marshal(t.FecRoundRobin.DataSize)
+		if err != nil {
+			return nil, err
+		}
+		_, err = buf.Write(b)
+		if err != nil {
+			return nil, err
+		}
+		b, err = tl.Marshal(t.FecRoundRobin.SymbolSize)
+		if err != nil {
+			return nil, err
+		}
+		_, err = buf.Write(b)
+		if err != nil {
+			return nil, err
+		}
+		b, err = tl.Marshal(t.FecRoundRobin.SymbolsCount)
+		if err != nil {
+			return nil, err
+		}
+		_, err = buf.Write(b)
+		if err != nil {
+			return nil, err
+		}
+	case "FecOnline":
+		b, err = tl.Marshal(uint32(0x127660c))
+		if err != nil {
+			return nil, err
+		}
+		_, err = buf.Write(b)
+		b, err = tl.Marshal(t.FecOnline.DataSize)
+		if err != nil {
+			return nil, err
+		}
+		_, err = buf.Write(b)
+		if err != nil {
+			return nil, err
+		}
+		b, err = tl.Marshal(t.FecOnline.SymbolSize)
+		if err != nil {
+			return nil, err
+		}
+		_, err = buf.Write(b)
+		if err != nil {
+			return nil, err
+		}
+		b, err = tl.Marshal(t.FecOnline.SymbolsCount)
+		if err != nil {
+			return nil, err
+		}
+		_, err = buf.Write(b)
+		if err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("invalid sum type")
+	}
+	return buf.Bytes(), nil
+}
+
+func (t *FecType) UnmarshalTL(r io.Reader) error {
+	var err error
+	var b [4]byte
+	_, err = io.ReadFull(r, b[:])
+	if err != nil {
+		return err
+	}
+	tag := int(binary.LittleEndian.Uint32(b[:]))
+	switch tag {
+	case 0x8b93a7e0:
+		t.SumType = "FecRaptorQ"
+		err = tl.Unmarshal(r, &t.FecRaptorQ.DataSize)
+		if err != nil {
+			return err
+		}
+		err = tl.Unmarshal(r, &t.FecRaptorQ.SymbolSize)
+		if err != nil {
+			return err
+		}
+		err = tl.Unmarshal(r, &t.FecRaptorQ.SymbolsCount)
+		if err != nil {
+			return err
+		}
+	case 0x32f528e4:
+		t.SumType = "FecRoundRobin"
+		err = tl.Unmarshal(r, &t.FecRoundRobin.DataSize)
+		if err != nil {
+			return err
+		}
+		err = tl.Unmarshal(r, &t.FecRoundRobin.SymbolSize)
+		if err != nil {
+			return err
+		}
+		err = tl.Unmarshal(r, &t.FecRoundRobin.SymbolsCount)
+		if err != nil {
+			return err
+		}
+	case 0x127660c:
+		t.SumType = "FecOnline"
+		err = tl.Unmarshal(r, &t.FecOnline.DataSize)
+		if err != nil {
+			return err
+		}
+		err = tl.Unmarshal(r, &t.FecOnline.SymbolSize)
+		if err != nil {
+			return err
+		}
+		err = tl.Unmarshal(r, &t.FecOnline.SymbolsCount)
+		if err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("invalid tag")
+	}
+	return nil
+}
+
+type RldpMessagePart struct {
+	tl.SumType
+	RldpMessagePart struct {
+		TransferId tl.Int256
+		FecType    FecType
+		Part       uint32
+		TotalSize  uint64
+		Seqno      uint32
+		Data       []byte
+	}
+	RldpConfirm struct {
+		TransferId tl.Int256
+		Part       uint32
+		Seqno      uint32
+	}
+	RldpComplete struct {
+		TransferId tl.Int256
+		Part       uint32
+	}
+}
+
+func (t RldpMessagePart) MarshalTL() ([]byte, error) {
+	var (
+		err error
+		b   []byte
+	)
+	buf := new(bytes.Buffer)
+	switch t.SumType {
+	case "RldpMessagePart":
+		b, err = tl.Marshal(uint32(0x185c22cc))
+		if err != nil {
+			return nil, err
+		}
+		_, err = buf.Write(b)
+		b, err = tl.Marshal(t.RldpMessagePart.TransferId)
+		if err != nil {
+			return nil, err
+		}
+		_, err = buf.Write(b)
+		if err != nil {
+			return nil, err
+		}
+		b, err = tl.Marshal(t.RldpMessagePart.FecType)
+		if err != nil {
+			return nil, err
+		}
+		_, err = buf.Write(b)
+		if err != nil {
+			return nil, err
+		}
+		b, err = tl.Marshal(t.RldpMessagePart.Part)
+		if err != nil {
+			return nil, err
+		}
+		_, err = buf.Write(b)
+		if err != nil {
+			return nil, err
+		}
+		b, err = tl.Marshal(t.RldpMessagePart.TotalSize)
+		if err != nil {
+			return nil, err
+		}
+		_, err = buf.Write(b)
+		if err != nil {
+			return nil, err
+		}
+		b, err = tl.Marshal(t.RldpMessagePart.Seqno)
+		if err != nil {
+			return nil, err
+		}
+		_, err = buf.Write(b)
+		if err != nil {
+			return nil, err
+		}
+		b, err = tl.Marshal(t.RldpMessagePart.Data)
+		if err != nil {
+			return nil, err
+		}
+		_, err = buf.Write(b)
+		if err != nil {
+			return nil, err
+		}
+	case "RldpConfirm":
+		b, err = tl.Marshal(uint32(0xf582dc58))
+		if err != nil {
+			return nil, err
+		}
+		_, err = buf.Write(b)
+		b, err = tl.Marshal(t.RldpConfirm.TransferId)
+		if err != nil {
+			return nil, err
+		}
+		_, err = buf.Write(b)
+		if err != nil {
+			return nil, err
+		}
+		b, err = tl.Marshal(t.RldpConfirm.Part)
+		if err != nil {
+			return nil, err
+		}
+		_, err = buf.Write(b)
+		if err != nil {
+			return nil, err
+		}
+		b, err = tl.Marshal(t.RldpConfirm.Seqno)
+		if err != nil {
+			return nil, err
+		}
+		_, err = buf.Write(b)
+		if err != nil {
+			return nil, err
+		}
+	case "RldpComplete":
+		b, err = tl.Marshal(uint32(0xbc0cb2bf))
+		if err != nil {
+			return nil, err
+		}
+		_, err = buf.Write(b)
+		b, err = tl.Marshal(t.RldpComplete.TransferId)
+		if err != nil {
+			return nil, err
+		}
+		_, err = buf.Write(b)
+		if err != nil {
+			return nil, err
+		}
+		b, err = tl.Marshal(t.RldpComplete.Part)
+		if err != nil {
+			return nil, err
+		}
+		_, err = buf.Write(b)
+		if err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("invalid sum type")
+	}
+	return buf.Bytes(), nil
+}
+
+func (t *RldpMessagePart) UnmarshalTL(r io.Reader) error {
+	var err error
+	var b [4]byte
+	_, err = io.ReadFull(r, b[:])
+	if err != nil {
+		return err
+	}
+	tag := int(binary.LittleEndian.Uint32(b[:]))
+	switch tag {
+	case 0x185c22cc:
+		t.SumType = "RldpMessagePart"
+		err = tl.Unmarshal(r, &t.RldpMessagePart.TransferId)
+		if err != nil {
+			return err
+		}
+		err = tl.Unmarshal(r, &t.RldpMessagePart.FecType)
+		if err != nil {
+			return err
+		}
+		err = tl.Unmarshal(r, &t.RldpMessagePart.Part)
+		if err != nil {
+			return err
+		}
+		err = tl.Unmarshal(r, &t.RldpMessagePart.TotalSize)
+		if err != nil {
+			return err
+		}
+		err = tl.Unmarshal(r, &t.RldpMessagePart.Seqno)
+		if err != nil {
+			return err
+		}
+		err = tl.Unmarshal(r, &t.RldpMessagePart.Data)
+		if err != nil {
+			return err
+		}
+	case 0xf582dc58:
+		t.SumType = "RldpConfirm"
+		err = tl.Unmarshal(r, &t.RldpConfirm.TransferId)
+		if err != nil {
+			return err
+		}
+		err = tl.Unmarshal(r, &t.RldpConfirm.Part)
+		if err != nil {
+			return err
+		}
+		err = tl.Unmarshal(r, &t.RldpConfirm.Seqno)
+		if err != nil {
+			return err
+		}
+	case 0xbc0cb2bf:
+		t.SumType = "RldpComplete"
+		err = tl.Unmarshal(r, &t.RldpComplete.TransferId)
+		if err != nil {
+			return err
+		}
+		err = tl.Unmarshal(r, &t.RldpComplete.Part)
+		if err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("invalid tag")
+	}
+	return nil
+}
+
+type RldpMessage struct {
+	tl.SumType
+	RldpMessage struct {
+		Id   tl.Int256
+		Data []byte
+	}
+	RldpQuery struct {
+		QueryId       tl.Int256
+		MaxAnswerSize uint64
+		Timeout       uint32
+		Data          []byte
+	}
+	RldpAnswer struct {
+		QueryId tl.Int256
+		Data    []byte
+	}
+}
+
+func (t RldpMessage) MarshalTL() ([]byte, error) {
+	var (
+		err error
+		b   []byte
+	)
+	buf := new(bytes.Buffer)
+	switch t.SumType {
+	case "RldpMessage":
+		b, err = tl.Marshal(uint32(0x7d1bcd1e))
+		if err != nil {
+			return nil, err
+		}
+		_, err = buf.Write(b)
+		b, err = tl.Marshal(t.RldpMessage.Id)
+		if err != nil {
+			return nil, err
+		}
+		_, err = buf.Write(b)
+		if err != nil {
+			return nil, err
+		}
+		b, err = tl.Marshal(t.RldpMessage.Data)
+		if err != nil {
+			return nil, err
+		}
+		_, err = buf.Write(b)
+		if err != nil {
+			return nil, err
+		}
+	case "RldpQuery":
+		b, err = tl.Marshal(uint32(0x8a794d69))
+		if err != nil {
+			return nil, err
+		}
+		_, err = buf.Write(b)
+		b, err = tl.Marshal(t.RldpQuery.QueryId)
+		if err != nil {
+			return nil, err
+		}
+		_, err = buf.Write(b)
+		if err != nil {
+			return nil, err
+		}
+		b, err = tl.Marshal(t.RldpQuery.MaxAnswerSize)
+		if err != nil {
+			return nil, err
+		}
+		_, err = buf.Write(b)
+		if err != nil {
+			return nil, err
+		}
+		b, err = tl.Marshal(t.RldpQuery.Timeout)
+		if err != nil {
+			return nil, err
+		}
+		_, err = buf.Write(b)
+		if err != nil {
+			return nil, err
+		}
+		b, err = tl.Marshal(t.RldpQuery.Data)
+		if err != nil {
+			return nil, err
+		}
+		_, err = buf.Write(b)
+		if err != nil {
+			return nil, err
+		}
+	case "RldpAnswer":
+		b, err = tl.Marshal(uint32(0xa3fc5c03))
+		if err != nil {
+			return nil, err
+		}
+		_, err = buf.Write(b)
+		b, err = tl.Marshal(t.RldpAnswer.QueryId)
+		if err != nil {
+			return nil, err
+		}
+		_, err = buf.Write(b)
+		if err != nil {
+			return nil, err
+		}
+		b, err = tl.Marshal(t.RldpAnswer.Data)
+		if err != nil {
+			return nil, err
+		}
+		_, err = buf.Write(b)
+		if err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("invalid sum type")
+	}
+	return buf.Bytes(), nil
+}
+
+func (t *RldpMessage) UnmarshalTL(r io.Reader) error {
+	var err error
+	var b [4]byte
+	_, err = io.ReadFull(r, b[:])
+	if err != nil {
+		return err
+	}
+	tag := int(binary.LittleEndian.Uint32(b[:]))
+	switch tag {
+	case 0x7d1bcd1e:
+		t.SumType = "RldpMessage"
+		err = tl.Unmarshal(r, &t.RldpMessage.Id)
+		if err != nil {
+			return err
+		}
+		err = tl.Unmarshal(r, &t.RldpMessage.Data)
+		if err != nil {
+			return err
+		}
+	case 0x8a794d69:
+		t.SumType = "RldpQuery"
+		err = tl.Unmarshal(r, &t.RldpQuery.QueryId)
+		if err != nil {
+			return err
+		}
+		err = tl.Unmarshal(r, &t.RldpQuery.MaxAnswerSize)
+		if err != nil {
+			return err
+		}
+		err = tl.Unmarshal(r, &t.RldpQuery.Timeout)
+		if err != nil {
+			return err
+		}
+		err = tl.Unmarshal(r, &t.RldpQuery.Data)
+		if err != nil {
+			return err
+		}
+	case 0xa3fc5c03:
+		t.SumType = "RldpAnswer"
+		err = tl.Unmarshal(r, &t.RldpAnswer.QueryId)
+		if err != nil {
+			return err
+		}
+		err = tl.Unmarshal(r, &t.RldpAnswer.Data)
+		if err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("invalid tag")
+	}
+	return nil
+}
+
+type OverlayNodeToSignC struct {
+	Id      AdnlIdShortC
+	Overlay tl.Int256
+	Version uint32
+}
+
+func (t OverlayNodeToSignC) MarshalTL() ([]byte, error) {
+	var (
+		err error
+		b   []byte
+	)
+	buf := new(bytes.Buffer)
+	b, err = tl.Marshal(t.Id)
+	if err != nil {
+		return nil, err
+	}
+	_, err = buf.Write(b)
+	if err != nil {
+		return nil, err
+	}
+	b, err = tl.Marshal(t.Overlay)
+	if err != nil {
+		return nil, err
+	}
+	_, err = buf.Write(b)
+	if err != nil {
+		return nil, err
+	}
+	b, err = tl.Marshal(t.Version)
+	if err != nil {
+		return nil, err
+	}
+	_, err = buf.Write(b)
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (t *OverlayNodeToSignC) UnmarshalTL(r io.Reader) error {
+	var err error
+	err = tl.Unmarshal(r, &t.Id)
+	if err != nil {
+		return err
+	}
+	err = tl.Unmarshal(r, &t.Overlay)
+	if err != nil {
+		return err
+	}
+	err = tl.Unmarshal(r, &t.Version)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+type OverlayNodeC struct {
+	Id        PublicKey
+	Overlay   tl.Int256
+	Version   uint32
+	Signature []byte
+}
+
+func (t OverlayNodeC) MarshalTL() ([]byte, error) {
+	var (
+		err error
+		b   []byte
+	)
+	buf := new(bytes.Buffer)
+	b, err = tl.Marshal(t.Id)
+	if err != nil {
+		return nil, err
+	}
+	_, err = buf.Write(b)
+	if err != nil {
+		return nil, err
+	}
+	b, err = tl.Marshal(t.Overlay)
+	if err != nil {
+		return nil, err
+	}
+	_, err = buf.Write(b)
+	if err != nil {
+		return nil, err
+	}
+	b, err = tl.Marshal(t.Version)
+	if err != nil {
+		return nil, err
+	}
+	_, err = buf.Write(b)
+	if err != nil {
+		return nil, err
+	}
+	b, err = tl.Marshal(t.Signature)
+	if err != nil {
+		return nil, err
+	}
+	_, err = buf.Write(b)
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (t *OverlayNodeC) UnmarshalTL(r io.Reader) error {
+	var err error
+	err = tl.Unmarshal(r, &t.Id)
+	if err != nil {
+		return err
+	}
+	err = tl.Unmarshal(r, &t.Overlay)
+	if err != nil {
+		return err
+	}
+	err = tl.Unmarshal(r, &t.Version)
+	if err != nil {
+		return err
+	}
+	err = tl.Unmarshal(r, &t.Signature)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+type OverlayNodesC struct {
+	Nodes []OverlayNodeC
+}
+
+func (t OverlayNodesC) MarshalTL() ([]byte, error) {
+	var (
+		err error
+		b   []byte
+	)
+	buf := new(bytes.Buffer)
+	b, err = tl.Marshal(t.Nodes)
+	if err != nil {
+		return nil, err
+	}
+	_, err = buf.Write(b)
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (t *OverlayNodesC) UnmarshalTL(r io.Reader) error {
+	var err error
+	err = tl.Unmarshal(r, &t.Nodes)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+type StoragePieceC struct {
+	Proof []byte
+	Data  []byte
+}
+
+func (t StoragePieceC) MarshalTL() ([]byte, error) {
+	var (
+		err error
+		b   []byte
+	)
+	buf := new(bytes.Buffer)
+	b, err = tl.Marshal(t.Proof)
+	if err != nil {
+		return nil, err
+	}
+	_, err = buf.Write(b)
+	if err != nil {
+		return nil, err
+	}
+	b, err = tl.Marshal(t.Data)
+	if err != nil {
+		return nil, err
+	}
+	_, err = buf.Write(b)
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (t *StoragePieceC) UnmarshalTL(r io.Reader) error {
+	var err error
+	err = tl.Unmarshal(r, &t.Proof)
+	if err != nil {
+		return err
+	}
+	err = tl.Unmarshal(r, &t.Data)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+type StorageTorrentInfoC struct {
+	Data []byte
+}
+
+func (t StorageTorrentInfoC) MarshalTL() ([]byte, error) {
+	var (
+		err error
+		b   []byte
+	)
+	buf := new(bytes.Buffer)
+	b, err = tl.Marshal(t.Data)
+	if err != nil {
+		return nil, err
+	}
+	_, err = buf.Write(b)
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (t *StorageTorrentInfoC) UnmarshalTL(r io.Reader) error {
+	var err error
+	err = tl.Unmarshal(r, &t.Data)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+type StorageStateC struct {
+	WillUpload   bool
+	WantDownload bool
+}
+
+func (t StorageStateC) MarshalTL() ([]byte, error) {
+	var (
+		err error
+		b   []byte
+	)
+	buf := new(bytes.Buffer)
+	b, err = tl.Marshal(t.WillUpload)
+	if err != nil {
+		return nil, err
+	}
+	_, err = buf.Write(b)
+	if err != nil {
+		return nil, err
+	}
+	b, err = tl.Marshal(t.WantDownload)
+	if err != nil {
+		return nil, err
+	}
+	_, err = buf.Write(b)
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (t *StorageStateC) UnmarshalTL(r io.Reader) error {
+	var err error
+	err = tl.Unmarshal(r, &t.WillUpload)
+	if err != nil {
+		return err
+	}
+	err = tl.Unmarshal(r, &t.WantDownload)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+type StorageOkC struct{}
+
+func (t StorageOkC) MarshalTL() ([]byte, error) {
+	return nil, nil
+}
+
+func (t *StorageOkC) UnmarshalTL(r io.Reader) error {
+	return nil
+}
+
+type StoragePongC struct{}
+
+func (t StoragePongC) MarshalTL() ([]byte, error) {
+	return nil, nil
+}
+
+func (t *StoragePongC) UnmarshalTL(r io.Reader) error {
+	return nil
+}
+
+type HttpHeaderC struct {
+	Name  string
+	Value string
+}
+
+func (t HttpHeaderC) MarshalTL() ([]byte, error) {
+	var (
+		err error
+		b   []byte
+	)
+	buf := new(bytes.Buffer)
+	b, err = tl.Marshal(t.Name)
+	if err != nil {
+		return nil, err
+	}
+	_, err = buf.Write(b)
+	if err != nil {
+		return nil, err
+	}
+	b, err = tl.Marshal(t.Value)
+	if err != nil {
+		return nil, err
+	}
+	_, err = buf.Write(b)
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (t *HttpHeaderC) UnmarshalTL(r io.Reader) error {
+	var err error
+	err = tl.Unmarshal(r, &t.Name)
+	if err != nil {
+		return err
+	}
+	err = tl.Unmarshal(r, &t.Value)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+type HttpPayloadPartC struct {
+	Data    []byte
+	Trailer []HttpHeaderC
+	Last    bool
+}
+
+func (t HttpPayloadPartC) MarshalTL() ([]byte, error) {
+	var (
+		err error
+		b   []byte
+	)
+	buf := new(bytes.Buffer)
+	b, err = tl.Marshal(t.Data)
+	if err != nil {
+		return nil, err
+	}
+	_, err = buf.Write(b)
+	if err != nil {
+		return nil, err
+	}
+	b, err = tl.Marshal(t.Trailer)
+	if err != nil {
+		return nil, err
+	}
+	_, err = buf.Write(b)
+	if err != nil {
+		return nil, err
+	}
+	b, err = tl.Marshal(t.Last)
+	if err != nil {
+		return nil, err
+	}
+	_, err = buf.Write(b)
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (t *HttpPayloadPartC) UnmarshalTL(r io.Reader) error {
+	var err error
+	err = tl.Unmarshal(r, &t.Data)
+	if err != nil {
+		return err
+	}
+	err = tl.Unmarshal(r, &t.Trailer)
+	if err != nil {
+		return err
+	}
+	err = tl.Unmarshal(r, &t.Last)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+type HttpResponseC struct {
+	HttpVersion string
+	StatusCode  uint32
+	Reason      string
+	Headers     []HttpHeaderC
+	NoPayload   bool
+}
+
+func (t HttpResponseC) MarshalTL() ([]byte, error) {
+	var (
+		err error
+		b   []byte
+	)
+	buf := new(bytes.Buffer)
+	b, err = tl.Marshal(t.HttpVersion)
+	if err != nil {
+		return nil, err
+	}
+	_, err = buf.Write(b)
+	if err != nil {
+		return nil, err
+	}
+	b, err = tl.Marshal(t.StatusCode)
+	if err != nil {
+		return nil, err
+	}
+	_, err = buf.Write(b)
+	if err != nil {
+		return nil, err
+	}
+	b, err = tl.Marshal(t.Reason)
+	if err != nil {
+		return nil, err
+	}
+	_, err = buf.Write(b)
+	if err != nil {
+		return nil, err
+	}
+	b, err = tl.Marshal(t.Headers)
+	if err != nil {
+		return nil, err
+	}
+	_, err = buf.Write(b)
+	if err != nil {
+		return nil, err
+	}
+	b, err = tl.Marshal(t.NoPayload)
+	if err != nil {
+		return nil, err
+	}
+	_, err = buf.Write(b)
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (t *HttpResponseC) UnmarshalTL(r io.Reader) error {
+	var err error
+	err = tl.Unmarshal(r, &t.HttpVersion)
+	if err != nil {
+		return err
+	}
+	err = tl.Unmarshal(r, &t.StatusCode)
+	if err != nil {
+		return err
+	}
+	err = tl.Unmarshal(r, &t.Reason)
+	if err != nil {
+		return err
+	}
+	err = tl.Unmarshal(r, &t.Headers)
+	if err != nil {
+		return err
+	}
+	err = tl.Unmarshal(r, &t.NoPayload)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+type AdnlPingRequest struct {
+	Value uint64
+}
+
+func (t AdnlPingRequest) MarshalTL() ([]byte, error) {
+	var (
+		err error
+		b   []byte
+	)
+	buf := new(bytes.Buffer)
+	b, err = tl.Marshal(t.Value)
+	if err != nil {
+		return nil, err
+	}
+	_, err = buf.Write(b)
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (t *AdnlPingRequest) UnmarshalTL(r io.Reader) error {
+	var err error
+	err = tl.Unmarshal(r, &t.Value)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+type DhtPingRequest struct {
+	RandomId uint64
+}
+
+func (t DhtPingRequest) MarshalTL() ([]byte, error) {
+	var (
+		err error
+		b   []byte
+	)
+	buf := new(bytes.Buffer)
+	b, err = tl.Marshal(t.RandomId)
+	if err != nil {
+		return nil, err
+	}
+	_, err = buf.Write(b)
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (t *DhtPingRequest) UnmarshalTL(r io.Reader) error {
+	var err error
+	err = tl.Unmarshal(r, &t.RandomId)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+type DhtStoreRequest struct {
+	Value DhtValueC
+}
+
+func (t DhtStoreRequest) MarshalTL() ([]byte, error) {
+	var (
+		err error
+		b   []byte
+	)
+	buf := new(bytes.Buffer)
+	b, err = tl.Marshal(t.Value)
+	if err != nil {
+		return nil, err
+	}
+	_, err = buf.Write(b)
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (t *DhtStoreRequest) UnmarshalTL(r io.Reader) error {
+	var err error
+	err = tl.Unmarshal(r, &t.Value)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+type DhtFindNodeRequest struct {
+	Key tl.Int256
+	K   uint32
+}
+
+func (t DhtFindNodeRequest) MarshalTL() ([]byte, error) {
+	var (
+		err error
+		b   []byte
+	)
+	buf := new(bytes.Buffer)
+	b, err = tl.Marshal(t.Key)
+	if err != nil {
+		return nil, err
+	}
+	_, err = buf.Write(b)
+	if err != nil {
+		return nil, err
+	}
+	b, err = tl.Marshal(t.K)
+	if err != nil {
+		return nil, err
+	}
+	_, err = buf.Write(b)
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (t *DhtFindNodeRequest) UnmarshalTL(r io.Reader) error {
+	var err error
+	err = tl.Unmarshal(r, &t.Key)
+	if err != nil {
+		return err
+	}
+	err = tl.Unmarshal(r, &t.K)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+type DhtFindValueRequest struct {
+	Key tl.Int256
+	K   uint32
+}
+
+func (t DhtFindValueRequest) MarshalTL() ([]byte, error) {
+	var (
+		err error
+		b   []byte
+	)
+	buf := new(bytes.Buffer)
+	b, err = tl.Marshal(t.Key)
+	if err != nil {
+		return nil, err
+	}
+	_, err = buf.Write(b)
+	if err != nil {
+		return nil, err
+	}
+	b, err = tl.Marshal(t.K)
+	if err != nil {
+		return nil, err
+	}
+	_, err = buf.Write(b)
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (t *DhtFindValueRequest) UnmarshalTL(r io.Reader) error {
+	var err error
+	err = tl.Unmarshal(r, &t.Key)
+	if err != nil {
+		return err
+	}
+	err = tl.Unmarshal(r, &t.K)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+type DhtGetSignedAddressListRequest struct{}
+
+func (t DhtGetSignedAddressListRequest) MarshalTL() ([]byte, error) {
+	return nil, nil
+}
+
+func (t *DhtGetSignedAddressListRequest) UnmarshalTL(r io.Reader) error {
+	return nil
+}
+
+type OverlayQueryRequest struct {
+	Overlay tl.Int256
+}
+
+func (t OverlayQueryRequest) MarshalTL() ([]byte, error) {
+	var (
+		err error
+		b   []byte
+	)
+	buf := new(bytes.Buffer)
+	b, err = tl.Marshal(t.Overlay)
+	if err != nil {
+		return nil, err
+	}
+	_, err = buf.Write(b)
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (t *OverlayQueryRequest) UnmarshalTL(r io.Reader) error {
+	var err error
+	err = tl.Unmarshal(r, &t.Overlay)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+type OverlayGetRandomPeersRequest struct {
+	Peers OverlayNodesC
+}
+
+func (t OverlayGetRandomPeersRequest) MarshalTL() ([]byte, error) {
+	var (
+		err error
+		b   []byte
+	)
+	buf := new(bytes.Buffer)
+	b, err = tl.Marshal(t.Peers)
+	if err != nil {
+		return nil, err
+	}
+	_, err = buf.Write(b)
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (t *OverlayGetRandomPeersRequest) UnmarshalTL(r io.Reader) error {
+	var err error
+	err = tl.Unmarshal(r, &t.Peers)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+type StoragePingRequest struct {
+	SessionId uint64
+}
+
+func (t StoragePingRequest) MarshalTL() ([]byte, error) {
+	var (
+		err error
+		b   []byte
+	)
+	buf := new(bytes.Buffer)
+	b, err = tl.Marshal(t.SessionId)
+	if err != nil {
+		return nil, err
+	}
+	_, err = buf.Write(b)
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (t *StoragePingRequest) UnmarshalTL(r io.Reader) error {
+	var err error
+	err = tl.Unmarshal(r, &t.SessionId)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+type StorageGetTorrentInfoRequest struct{}
+
+func (t StorageGetTorrentInfoRequest) MarshalTL() ([]byte, error) {
+	return nil, nil
+}
+
+func (t *StorageGetTorrentInfoRequest) UnmarshalTL(r io.Reader) error {
+	return nil
+}
+
+type StorageGetPieceRequest struct {
+	PieceId uint32
+}
+
+func (t StorageGetPieceRequest) MarshalTL() ([]byte, error) {
+	var (
+		err error
+		b   []byte
+	)
+	buf := new(bytes.Buffer)
+	b, err = tl.Marshal(t.PieceId)
+	if err != nil {
+		return nil, err
+	}
+	_, err = buf.Write(b)
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (t *StorageGetPieceRequest) UnmarshalTL(r io.Reader) error {
+	var err error
+	err = tl.Unmarshal(r, &t.PieceId)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+type HttpRequestRequest struct {
+	Id          tl.Int256
+	Method      string
+	Url         string
+	HttpVersion string
+	Headers     []HttpHeaderC
+}
+
+func (t HttpRequestRequest) MarshalTL() ([]byte, error) {
+	var (
+		err error
+		b   []byte
+	)
+	buf := new(bytes.Buffer)
+	b, err = tl.Marshal(t.Id)
+	if err != nil {
+		return nil, err
+	}
+	_, err = buf.Write(b)
+	if err != nil {
+		return nil, err
+	}
+	b, err = tl.Marshal(t.Method)
+	if err != nil {
+		return nil, err
+	}
+	_, err = buf.Write(b)
+	if err != nil {
+		return nil, err
+	}
+	b, err = tl.Marshal(t.Url)
+	if err != nil {
+		return nil, err
+	}
+	_, err = buf.Write(b)
+	if err != nil {
+		return nil, err
+	}
+	b, err = tl.Marshal(t.HttpVersion)
+	if err != nil {
+		return nil, err
+	}
+	_, err = buf.Write(b)
+	if err != nil {
+		return nil, err
+	}
+	b, err = tl.Marshal(t.Headers)
+	if err != nil {
+		return nil, err
+	}
+	_, err = buf.Write(b)
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (t *HttpRequestRequest) UnmarshalTL(r io.Reader) error {
+	var err error
+	err = tl.Unmarshal(r, &t.Id)
+	if err != nil {
+		return err
+	}
+	err = tl.Unmarshal(r, &t.Method)
+	if err != nil {
+		return err
+	}
+	err = tl.Unmarshal(r, &t.Url)
+	if err != nil {
+		return err
+	}
+	err = tl.Unmarshal(r, &t.HttpVersion)
+	if err != nil {
+		return err
+	}
+	err = tl.Unmarshal(r, &t.Headers)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+type HttpGetNextPayloadPartRequest struct {
+	Id           tl.Int256
+	Seqno        uint32
+	MaxChunkSize uint32
+}
+
+func (t HttpGetNextPayloadPartRequest) MarshalTL() ([]byte, error) {
+	var (
+		err error
+		b   []byte
+	)
+	buf := new(bytes.Buffer)
+	b, err = tl.Marshal(t.Id)
+	if err != nil {
+		return nil, err
+	}
+	_, err = buf.Write(b)
+	if err != nil {
+		return nil, err
+	}
+	b, err = tl.Marshal(t.Seqno)
+	if err != nil {
+		return nil, err
+	}
+	_, err = buf.Write(b)
+	if err != nil {
+		return nil, err
+	}
+	b, err = tl.Marshal(t.MaxChunkSize)
+	if err != nil {
+		return nil, err
+	}
+	_, err = buf.Write(b)
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (t *HttpGetNextPayloadPartRequest) UnmarshalTL(r io.Reader) error {
+	var err error
+	err = tl.Unmarshal(r, &t.Id)
+	if err != nil {
+		return err
+	}
+	err = tl.Unmarshal(r, &t.Seqno)
+	if err != nil {
+		return err
+	}
+	err = tl.Unmarshal(r, &t.MaxChunkSize)
+	if err != nil {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,59 @@
+//go:build ignore
+
+package main
+
+import (
+	"fmt"
+	"github.com/tonkeeper/tongo/tl/parser"
+	"os"
+)
+
+func main() {
+	scheme, err := os.ReadFile("ton_api.tl")
+	if err != nil {
+		panic(err)
+	}
+	parsed, err := parser.Parse(string(scheme))
+	if err != nil {
+		panic(err)
+	}
+
+	g := parser.NewGenerator(nil, "")
+
+	types, err := g.LoadTypes(parsed.Declarations)
+	if err != nil {
+		panic(err)
+	}
+	requests, err := g.LoadRequestTypes(parsed.Functions)
+	if err != nil {
+		panic(err)
+	}
+
+	f, err := os.Create("generated.go")
+	if err != nil {
+		panic(err)
+	}
+	_, err = fmt.Fprint(f, `package adnl
+
+// Code autogenerated. DO NOT EDIT.
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"github.com/tonkeeper/tongo/tl"
+	"io"
+)
+`)
+	if err != nil {
+		panic(err)
+	}
+	_, err = f.WriteString(types)
+	if err != nil {
+		panic(err)
+	}
+	_, err = f.WriteString(requests)
+	if err != nil {
+		panic(err)
+	}
+}
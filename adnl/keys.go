@@ -0,0 +1,91 @@
+package adnl
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"fmt"
+
+	"github.com/tonkeeper/tongo/tl"
+)
+
+// PublicKeyEd25519 returns a pub.ed25519 key, ADNL peers and DHT values are identified by such keys.
+func PublicKeyEd25519(key ed25519.PublicKey) PublicKey {
+	var k PublicKey
+	k.SumType = "PubEd25519"
+	copy(k.PubEd25519.Key[:], key)
+	return k
+}
+
+// PublicKeyOverlay returns a pub.overlay key, its short ID identifies an overlay network with the given name.
+func PublicKeyOverlay(name []byte) PublicKey {
+	var k PublicKey
+	k.SumType = "PubOverlay"
+	k.PubOverlay.Name = name
+	return k
+}
+
+func publicKeyAES(key []byte) PublicKey {
+	var k PublicKey
+	k.SumType = "PubAes"
+	copy(k.PubAes.Key[:], key)
+	return k
+}
+
+// Ed25519Key returns an ed25519 key described by the given public key.
+func Ed25519Key(key PublicKey) (ed25519.PublicKey, error) {
+	if key.SumType != "PubEd25519" {
+		return nil, fmt.Errorf("not an ed25519 key: %v", key.SumType)
+	}
+	return append(ed25519.PublicKey{}, key.PubEd25519.Key[:]...), nil
+}
+
+// ShortID returns an ADNL short ID of the given key, it is a hash of the key's TL serialization.
+// A short ID of a peer's pub.ed25519 key is the peer's ADNL address.
+func ShortID(key PublicKey) (tl.Int256, error) {
+	b, err := tl.Marshal(key)
+	if err != nil {
+		return tl.Int256{}, err
+	}
+	return sha256.Sum256(b), nil
+}
+
+// encrypt encrypts data with AES-CTR the same way as ADNL does,
+// a key and an IV are derived from the secret and a checksum of data.
+func encrypt(secret []byte, data []byte) (checksum [32]byte, encrypted []byte, err error) {
+	checksum = sha256.Sum256(data)
+	stream, err := sharedCipher(secret, checksum)
+	if err != nil {
+		return checksum, nil, err
+	}
+	encrypted = make([]byte, len(data))
+	stream.XORKeyStream(encrypted, data)
+	return checksum, encrypted, nil
+}
+
+// decrypt reverts encrypt and verifies a checksum of decrypted data.
+func decrypt(secret []byte, checksum []byte, encrypted []byte) ([]byte, error) {
+	var sum [32]byte
+	copy(sum[:], checksum)
+	stream, err := sharedCipher(secret, sum)
+	if err != nil {
+		return nil, err
+	}
+	data := make([]byte, len(encrypted))
+	stream.XORKeyStream(data, encrypted)
+	if sha256.Sum256(data) != sum {
+		return nil, fmt.Errorf("checksum error")
+	}
+	return data, nil
+}
+
+func sharedCipher(secret []byte, checksum [32]byte) (cipher.Stream, error) {
+	key := append(append([]byte{}, secret[:16]...), checksum[16:32]...)
+	iv := append(append([]byte{}, checksum[0:4]...), secret[20:32]...)
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewCTR(block, iv), nil
+}
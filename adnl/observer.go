@@ -0,0 +1,51 @@
+package adnl
+
+import (
+	"log/slog"
+)
+
+// Observer receives events of a gateway.
+// It can be used to wire a gateway to a logger or metrics.
+// Methods are called synchronously by a goroutine reading packets, so they must not block.
+// Embed NopObserver to handle only some of the events.
+type Observer interface {
+	// ReadError is called when reading from the UDP socket fails.
+	// The gateway keeps reading after a delay growing with consecutive errors.
+	ReadError(err error)
+	// InvalidPacket is called when a received packet is dropped because it can't be decoded or verified.
+	InvalidPacket(remote string, err error)
+}
+
+// NopObserver ignores all events.
+type NopObserver struct{}
+
+var _ Observer = NopObserver{}
+
+func (NopObserver) ReadError(err error) {}
+
+func (NopObserver) InvalidPacket(remote string, err error) {}
+
+// SlogObserver writes events to a structured logger.
+// Read errors are logged at the warning level, invalid packets at the debug level.
+type SlogObserver struct {
+	Logger *slog.Logger
+}
+
+var _ Observer = SlogObserver{}
+
+// NewSlogObserver returns an observer writing events to the given logger.
+// A nil logger means slog.Default().
+func NewSlogObserver(logger *slog.Logger) SlogObserver {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return SlogObserver{Logger: logger}
+}
+
+func (o SlogObserver) ReadError(err error) {
+	o.Logger.Warn("adnl gateway read error", "err", err)
+}
+
+func (o SlogObserver) InvalidPacket(remote string, err error) {
+	o.Logger.Debug("adnl gateway invalid packet", "remote", remote, "err", err)
+}
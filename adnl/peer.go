@@ -0,0 +1,612 @@
+package adnl
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/tonkeeper/tongo/liteclient"
+	"github.com/tonkeeper/tongo/tl"
+)
+
+const (
+	flagFrom         = 1 << 0
+	flagMessage      = 1 << 2
+	flagMessages     = 1 << 3
+	flagAddress      = 1 << 4
+	flagSeqno        = 1 << 6
+	flagConfirmSeqno = 1 << 7
+	flagReinitDate   = 1 << 10
+	flagSignature    = 1 << 11
+
+	// maxMessageSize is a size of a serialized message which is split into adnl.message.part messages.
+	maxMessageSize = 1024
+	// maxHugeMessageSize limits a size of a message assembled from parts.
+	maxHugeMessageSize = 1 << 20
+	// maxPartialMessages limits a number of messages being assembled from parts at once.
+	maxPartialMessages = 16
+	// partialMessageTimeout is a time after which a message with missing parts can be dropped.
+	partialMessageTimeout = 10 * time.Second
+	// seqnoWindow is a number of recent seqnos tracked to drop duplicated packets.
+	seqnoWindow = 64
+)
+
+// channel is a pair of keys peers use to encrypt packets to each other
+// after exchanging adnl.message.createChannel and adnl.message.confirmChannel.
+type channel struct {
+	key     ed25519.PrivateKey
+	peerKey ed25519.PublicKey
+	date    uint32
+	encKey  []byte
+	decKey  []byte
+	outID   tl.Int256
+	inID    tl.Int256
+	// ready is true once the peer is known to have the channel too,
+	// only then packets are sent through the channel.
+	ready bool
+}
+
+func newChannel() (*channel, error) {
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	return &channel{key: key, date: uint32(time.Now().Unix())}, nil
+}
+
+func (c *channel) publicKey() tl.Int256 {
+	var k tl.Int256
+	copy(k[:], c.key.Public().(ed25519.PublicKey))
+	return k
+}
+
+// setup derives keys of the channel.
+// Peers use the same secret, the peer with the smaller ADNL address encrypts with it and decrypts with the reversed one.
+func (c *channel) setup(peerKey ed25519.PublicKey, localID, peerID tl.Int256) error {
+	secret, err := liteclient.SharedKey(c.key, peerKey)
+	if err != nil {
+		return err
+	}
+	reversed := make([]byte, len(secret))
+	for i := range secret {
+		reversed[len(secret)-1-i] = secret[i]
+	}
+	switch bytes.Compare(localID[:], peerID[:]) {
+	case -1:
+		c.encKey, c.decKey = secret, reversed
+	case 1:
+		c.encKey, c.decKey = reversed, secret
+	default:
+		c.encKey, c.decKey = secret, secret
+	}
+	if c.outID, err = ShortID(publicKeyAES(c.encKey)); err != nil {
+		return err
+	}
+	if c.inID, err = ShortID(publicKeyAES(c.decKey)); err != nil {
+		return err
+	}
+	c.peerKey = peerKey
+	return nil
+}
+
+type partialMessage struct {
+	data     []byte
+	offsets  map[uint32]struct{}
+	received int
+	// createdAt is a time when the first part of the message is received.
+	createdAt time.Time
+}
+
+// Peer is a remote ADNL node.
+// Packets to a peer are encrypted with its key until peers set up a channel.
+type Peer struct {
+	gateway *Gateway
+	key     ed25519.PublicKey
+	id      tl.Int256
+	// lastActive is a unix time in nanoseconds of the last packet exchanged with the peer.
+	lastActive atomic.Int64
+	// pendingQueries is a number of queries waiting for answers of the peer.
+	pendingQueries atomic.Int32
+
+	// mu protects all fields below.
+	mu           sync.Mutex
+	addr         *net.UDPAddr
+	handler      Handler
+	seqno        uint64
+	confirmSeqno uint64
+	// received is a bit mask of received seqnos, bit i is set if confirmSeqno-i is received.
+	received       uint64
+	peerReinitDate uint32
+	channel        *channel
+	confirmChannel bool
+	queries        map[tl.Int256]chan []byte
+	parts          map[tl.Int256]*partialMessage
+}
+
+func newPeer(g *Gateway, key ed25519.PublicKey, id tl.Int256) *Peer {
+	p := &Peer{
+		gateway: g,
+		key:     key,
+		id:      id,
+		queries: make(map[tl.Int256]chan []byte),
+		parts:   make(map[tl.Int256]*partialMessage),
+	}
+	p.lastActive.Store(time.Now().UnixNano())
+	return p
+}
+
+// ID returns the peer's ADNL address.
+func (p *Peer) ID() tl.Int256 {
+	return p.id
+}
+
+// PublicKey returns the peer's public key.
+func (p *Peer) PublicKey() ed25519.PublicKey {
+	return p.key
+}
+
+// Addr returns the peer's UDP address, it is updated when packets of the peer come from another address.
+func (p *Peer) Addr() *net.UDPAddr {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.addr
+}
+
+// SetHandler sets a handler of the peer's queries and custom messages overriding the gateway's handler.
+func (p *Peer) SetHandler(h Handler) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.handler = h
+}
+
+func (p *Peer) getHandler() Handler {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.handler
+}
+
+func (p *Peer) setAddr(addr *net.UDPAddr) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.addr = addr
+}
+
+// Query sends a TL-serialized query to the peer and waits for an answer.
+// ADNL doesn't retransmit lost packets, so ctx should have a deadline.
+func (p *Peer) Query(ctx context.Context, query []byte) ([]byte, error) {
+	var queryID tl.Int256
+	if _, err := io.ReadFull(rand.Reader, queryID[:]); err != nil {
+		return nil, err
+	}
+	answer := make(chan []byte, 1)
+	p.pendingQueries.Add(1)
+	p.mu.Lock()
+	p.queries[queryID] = answer
+	p.mu.Unlock()
+	defer func() {
+		p.mu.Lock()
+		delete(p.queries, queryID)
+		p.mu.Unlock()
+		p.pendingQueries.Add(-1)
+	}()
+	var msg AdnlMessage
+	msg.SumType = "AdnlMessageQuery"
+	msg.AdnlMessageQuery.QueryId = queryID
+	msg.AdnlMessageQuery.Query = query
+	if err := p.sendMessage(msg); err != nil {
+		return nil, err
+	}
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-p.gateway.ctx.Done():
+		return nil, ErrClosed
+	case res := <-answer:
+		return res, nil
+	}
+}
+
+// SendCustomMessage sends data to the peer as adnl.message.custom without waiting for an answer.
+func (p *Peer) SendCustomMessage(data []byte) error {
+	var msg AdnlMessage
+	msg.SumType = "AdnlMessageCustom"
+	msg.AdnlMessageCustom.Data = data
+	return p.sendMessage(msg)
+}
+
+// sendMessage sends a message, splitting it into adnl.message.part messages if it is too big for a single packet.
+func (p *Peer) sendMessage(msg AdnlMessage) error {
+	b, err := tl.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	if len(b) <= maxMessageSize {
+		return p.send([]AdnlMessage{msg})
+	}
+	if len(b) > maxHugeMessageSize {
+		return fmt.Errorf("message is too big: %v", len(b))
+	}
+	hash := sha256.Sum256(b)
+	for offset := 0; offset < len(b); offset += maxMessageSize {
+		end := offset + maxMessageSize
+		if end > len(b) {
+			end = len(b)
+		}
+		var part AdnlMessage
+		part.SumType = "AdnlMessagePart"
+		part.AdnlMessagePart.Hash = hash
+		part.AdnlMessagePart.TotalSize = uint32(len(b))
+		part.AdnlMessagePart.Offset = uint32(offset)
+		part.AdnlMessagePart.Data = b[offset:end]
+		if err := p.send([]AdnlMessage{part}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// send sends messages in a single packet.
+// Until a channel is ready, the packet is signed and asks the peer to create a channel.
+func (p *Peer) send(messages []AdnlMessage) error {
+	packet, ch, addr, err := p.preparePacket(messages)
+	if err != nil {
+		return err
+	}
+	if ch == nil {
+		b, err := MarshalBoxed(tagPacketContents, packet)
+		if err != nil {
+			return err
+		}
+		packet.Signature = ed25519.Sign(p.gateway.key, b)
+		packet.Flags |= flagSignature
+	}
+	b, err := MarshalBoxed(tagPacketContents, packet)
+	if err != nil {
+		return err
+	}
+	return p.gateway.sendPacket(b, p, ch, addr)
+}
+
+func (p *Peer) preparePacket(messages []AdnlMessage) (AdnlPacketContentsC, *channel, *net.UDPAddr, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.addr == nil {
+		return AdnlPacketContentsC{}, nil, nil, fmt.Errorf("peer address is unknown")
+	}
+	var channelID *tl.Int256
+	if p.channel != nil && p.channel.peerKey != nil {
+		channelID = &p.channel.inID
+	}
+	p.gateway.restorePeer(p, channelID)
+	p.lastActive.Store(time.Now().UnixNano())
+	var ch *channel
+	if p.channel != nil && p.channel.ready {
+		ch = p.channel
+	} else {
+		if p.channel == nil {
+			c, err := newChannel()
+			if err != nil {
+				return AdnlPacketContentsC{}, nil, nil, err
+			}
+			p.channel = c
+		}
+		var msg AdnlMessage
+		if p.confirmChannel {
+			msg.SumType = "AdnlMessageConfirmChannel"
+			msg.AdnlMessageConfirmChannel.Key = p.channel.publicKey()
+			copy(msg.AdnlMessageConfirmChannel.PeerKey[:], p.channel.peerKey)
+			msg.AdnlMessageConfirmChannel.Date = p.channel.date
+		} else {
+			msg.SumType = "AdnlMessageCreateChannel"
+			msg.AdnlMessageCreateChannel.Key = p.channel.publicKey()
+			msg.AdnlMessageCreateChannel.Date = p.channel.date
+		}
+		messages = append([]AdnlMessage{msg}, messages...)
+	}
+
+	p.seqno++
+	seqno, confirmSeqno := p.seqno, p.confirmSeqno
+	reinitDate, dstReinitDate := p.gateway.reinitDate, p.peerReinitDate
+	packet := AdnlPacketContentsC{
+		Rand1:         randomBytes(),
+		Flags:         flagSeqno | flagConfirmSeqno | flagReinitDate,
+		Seqno:         &seqno,
+		ConfirmSeqno:  &confirmSeqno,
+		ReinitDate:    &reinitDate,
+		DstReinitDate: &dstReinitDate,
+		Rand2:         randomBytes(),
+	}
+	if len(messages) == 1 {
+		packet.Flags |= flagMessage
+		packet.Message = &messages[0]
+	} else {
+		packet.Flags |= flagMessages
+		packet.Messages = messages
+	}
+	if ch == nil {
+		from := PublicKeyEd25519(p.gateway.PublicKey())
+		packet.Flags |= flagFrom | flagAddress
+		packet.From = &from
+		packet.Address = &AdnlAddressListC{
+			Version:    p.gateway.reinitDate,
+			ReinitDate: p.gateway.reinitDate,
+		}
+	}
+	return packet, ch, p.addr, nil
+}
+
+func randomBytes() []byte {
+	b := make([]byte, 15)
+	_, _ = io.ReadFull(rand.Reader, b)
+	return b
+}
+
+// handleChannelPacket processes a packet sent through a channel with the given ID.
+func (p *Peer) handleChannelPacket(id tl.Int256, data []byte, addr *net.UDPAddr) error {
+	p.mu.Lock()
+	ch := p.channel
+	p.mu.Unlock()
+	if ch == nil || ch.inID != id {
+		return fmt.Errorf("unknown channel")
+	}
+	plain, err := decrypt(ch.decKey, data[:32], data[32:])
+	if err != nil {
+		return err
+	}
+	packet, err := parsePacket(plain)
+	if err != nil {
+		return err
+	}
+	return p.handlePacket(packet, addr, true)
+}
+
+func (p *Peer) handlePacket(packet AdnlPacketContentsC, addr *net.UDPAddr, viaChannel bool) error {
+	var messages []AdnlMessage
+	if packet.Message != nil {
+		messages = append(messages, *packet.Message)
+	}
+	messages = append(messages, packet.Messages...)
+
+	if err := p.acceptPacket(packet, addr, viaChannel); err != nil {
+		return err
+	}
+	p.lastActive.Store(time.Now().UnixNano())
+	for _, msg := range messages {
+		if err := p.handleMessage(msg, false); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// acceptPacket checks reinit dates and a seqno of a packet.
+func (p *Peer) acceptPacket(packet AdnlPacketContentsC, addr *net.UDPAddr, viaChannel bool) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if packet.DstReinitDate != nil && *packet.DstReinitDate != 0 && *packet.DstReinitDate != p.gateway.reinitDate {
+		return fmt.Errorf("packet is sent to a previous instance of the gateway")
+	}
+	if packet.ReinitDate != nil {
+		switch {
+		case *packet.ReinitDate < p.peerReinitDate:
+			return fmt.Errorf("packet is sent by a previous instance of the peer")
+		case *packet.ReinitDate > p.peerReinitDate:
+			// the peer has restarted, so it has forgotten seqnos and channels
+			p.peerReinitDate = *packet.ReinitDate
+			p.confirmSeqno, p.received = 0, 0
+			if !viaChannel && p.channel != nil {
+				p.gateway.unregisterChannel(p.channel.inID)
+				p.channel = nil
+				p.confirmChannel = false
+			}
+		}
+	}
+	if packet.Seqno != nil {
+		seqno := *packet.Seqno
+		switch {
+		case seqno > p.confirmSeqno:
+			shift := seqno - p.confirmSeqno
+			if shift >= seqnoWindow {
+				p.received = 0
+			} else {
+				p.received <<= shift
+			}
+			p.received |= 1
+			p.confirmSeqno = seqno
+		case p.confirmSeqno-seqno >= seqnoWindow:
+			return fmt.Errorf("packet is too old")
+		default:
+			bit := uint64(1) << (p.confirmSeqno - seqno)
+			if p.received&bit != 0 {
+				return fmt.Errorf("duplicated packet")
+			}
+			p.received |= bit
+		}
+	}
+	if viaChannel && p.channel != nil {
+		p.channel.ready = true
+		p.confirmChannel = false
+	}
+	p.addr = addr
+	return nil
+}
+
+func (p *Peer) handleMessage(msg AdnlMessage, assembled bool) error {
+	switch msg.SumType {
+	case "AdnlMessageCreateChannel":
+		return p.handleCreateChannel(ed25519.PublicKey(msg.AdnlMessageCreateChannel.Key[:]))
+	case "AdnlMessageConfirmChannel":
+		m := msg.AdnlMessageConfirmChannel
+		return p.handleConfirmChannel(ed25519.PublicKey(m.Key[:]), m.PeerKey)
+	case "AdnlMessageQuery":
+		h := p.gateway.peerHandler(p)
+		if h == nil {
+			return nil
+		}
+		go p.answer(h, msg.AdnlMessageQuery.QueryId, msg.AdnlMessageQuery.Query)
+	case "AdnlMessageAnswer":
+		p.mu.Lock()
+		ch, ok := p.queries[msg.AdnlMessageAnswer.QueryId]
+		delete(p.queries, msg.AdnlMessageAnswer.QueryId)
+		p.mu.Unlock()
+		if ok {
+			ch <- msg.AdnlMessageAnswer.Answer
+		}
+	case "AdnlMessageCustom":
+		if h := p.gateway.peerHandler(p); h != nil {
+			h.HandleCustomMessage(p, msg.AdnlMessageCustom.Data)
+		}
+	case "AdnlMessagePart":
+		if assembled {
+			return fmt.Errorf("nested message part")
+		}
+		data, err := p.addPart(msg)
+		if err != nil || data == nil {
+			return err
+		}
+		var inner AdnlMessage
+		if err := tl.Unmarshal(bytes.NewReader(data), &inner); err != nil {
+			return err
+		}
+		return p.handleMessage(inner, true)
+	}
+	return nil
+}
+
+func (p *Peer) answer(h Handler, queryID tl.Int256, query []byte) {
+	res, err := h.HandleQuery(p.gateway.ctx, p, query)
+	if err != nil {
+		return
+	}
+	var msg AdnlMessage
+	msg.SumType = "AdnlMessageAnswer"
+	msg.AdnlMessageAnswer.QueryId = queryID
+	msg.AdnlMessageAnswer.Answer = res
+	_ = p.sendMessage(msg)
+}
+
+// handleCreateChannel sets up a channel with the peer's key and confirms it with the next packet.
+func (p *Peer) handleCreateChannel(peerKey ed25519.PublicKey) error {
+	p.mu.Lock()
+	if p.channel != nil && bytes.Equal(p.channel.peerKey, peerKey) {
+		p.mu.Unlock()
+		return nil
+	}
+	if p.channel != nil && p.channel.peerKey != nil {
+		// the peer has created a new channel, so the current one is abandoned
+		p.gateway.unregisterChannel(p.channel.inID)
+		p.channel = nil
+	}
+	if p.channel == nil {
+		c, err := newChannel()
+		if err != nil {
+			p.mu.Unlock()
+			return err
+		}
+		p.channel = c
+	}
+	if err := p.channel.setup(peerKey, p.gateway.id, p.id); err != nil {
+		p.mu.Unlock()
+		return err
+	}
+	p.confirmChannel = true
+	p.gateway.registerChannel(p.channel.inID, p)
+	p.mu.Unlock()
+
+	var nop AdnlMessage
+	nop.SumType = "AdnlMessageNop"
+	return p.send([]AdnlMessage{nop})
+}
+
+// handleConfirmChannel makes a channel ready if the peer confirms our key.
+func (p *Peer) handleConfirmChannel(peerKey ed25519.PublicKey, ourKey tl.Int256) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.channel == nil || p.channel.publicKey() != ourKey {
+		return nil
+	}
+	if p.channel.peerKey == nil {
+		if err := p.channel.setup(peerKey, p.gateway.id, p.id); err != nil {
+			return err
+		}
+		p.gateway.registerChannel(p.channel.inID, p)
+	} else if !bytes.Equal(p.channel.peerKey, peerKey) {
+		return fmt.Errorf("channel is confirmed with another key")
+	}
+	p.channel.ready = true
+	p.confirmChannel = false
+	return nil
+}
+
+// addPart stores a part of a message and returns the whole message once all parts are received.
+func (p *Peer) addPart(msg AdnlMessage) ([]byte, error) {
+	part := msg.AdnlMessagePart
+	if part.TotalSize > maxHugeMessageSize {
+		return nil, fmt.Errorf("message is too big: %v", part.TotalSize)
+	}
+	if uint64(part.Offset)+uint64(len(part.Data)) > uint64(part.TotalSize) {
+		return nil, fmt.Errorf("invalid message part")
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	m, ok := p.parts[part.Hash]
+	if !ok {
+		now := time.Now()
+		if len(p.parts) >= maxPartialMessages {
+			p.dropStaleParts(now)
+		}
+		if len(p.parts) >= maxPartialMessages {
+			return nil, fmt.Errorf("too many partial messages")
+		}
+		m = &partialMessage{data: make([]byte, part.TotalSize), offsets: map[uint32]struct{}{}, createdAt: now}
+		p.parts[part.Hash] = m
+	}
+	if len(m.data) != int(part.TotalSize) {
+		return nil, fmt.Errorf("invalid message part")
+	}
+	if _, ok := m.offsets[part.Offset]; ok {
+		return nil, nil
+	}
+	m.offsets[part.Offset] = struct{}{}
+	copy(m.data[part.Offset:], part.Data)
+	m.received += len(part.Data)
+	if m.received < len(m.data) {
+		return nil, nil
+	}
+	delete(p.parts, part.Hash)
+	if sha256.Sum256(m.data) != part.Hash {
+		return nil, fmt.Errorf("invalid message hash")
+	}
+	return m.data, nil
+}
+
+// dropStaleParts drops messages which parts are lost, the oldest one first.
+// A part can be lost as UDP doesn't guarantee delivery, so its message would never be assembled.
+// p.mu must be held.
+func (p *Peer) dropStaleParts(now time.Time) {
+	for len(p.parts) > 0 {
+		var (
+			oldestHash tl.Int256
+			oldest     *partialMessage
+		)
+		for hash, m := range p.parts {
+			if oldest == nil || m.createdAt.Before(oldest.createdAt) {
+				oldestHash, oldest = hash, m
+			}
+		}
+		if now.Sub(oldest.createdAt) < partialMessageTimeout {
+			return
+		}
+		delete(p.parts, oldestHash)
+	}
+}
@@ -0,0 +1,101 @@
+package rldp
+
+import (
+	"fmt"
+
+	"github.com/tonkeeper/tongo/adnl"
+)
+
+const (
+	// symbolSize is a size of FEC symbols of outbound transfers, so a message part fits into a single ADNL packet.
+	symbolSize = 768
+	// maxSymbolSize limits a size of symbols of inbound transfers, a symbol is delivered in a single UDP datagram.
+	maxSymbolSize = 64 * 1024
+)
+
+// decoder restores a part of a transfer from FEC symbols.
+type decoder interface {
+	// addSymbol adds a symbol with the given seqno and reports if the part is restored.
+	addSymbol(seqno uint32, data []byte) (bool, error)
+	// data returns the restored part.
+	data() []byte
+}
+
+// fecParams returns parameters of a FEC type.
+func fecParams(fec adnl.FecType) (dataSize, symbolSize, symbolsCount uint32, err error) {
+	switch fec.SumType {
+	case "FecRaptorQ":
+		p := fec.FecRaptorQ
+		return p.DataSize, p.SymbolSize, p.SymbolsCount, nil
+	case "FecRoundRobin":
+		p := fec.FecRoundRobin
+		return p.DataSize, p.SymbolSize, p.SymbolsCount, nil
+	case "FecOnline":
+		p := fec.FecOnline
+		return p.DataSize, p.SymbolSize, p.SymbolsCount, nil
+	}
+	return 0, 0, 0, fmt.Errorf("invalid FEC type: %v", fec.SumType)
+}
+
+// newDecoder returns a decoder of the given FEC type.
+// Parts encoded with fec.raptorQ and fec.roundRobin can be restored, fec.online is not supported.
+func newDecoder(fec adnl.FecType) (decoder, error) {
+	dataSize, symbolSize, symbolsCount, err := fecParams(fec)
+	if err != nil {
+		return nil, err
+	}
+	if dataSize == 0 || symbolSize == 0 || symbolSize > maxSymbolSize || symbolsCount != (dataSize+symbolSize-1)/symbolSize {
+		return nil, fmt.Errorf("invalid FEC parameters")
+	}
+	switch fec.SumType {
+	case "FecRaptorQ":
+		return newRaptorQDecoder(int(dataSize), int(symbolSize))
+	case "FecRoundRobin":
+		return &roundRobinDecoder{
+			symbolSize: int(symbolSize),
+			received:   make([]bool, symbolsCount),
+			left:       int(symbolsCount),
+			buf:        make([]byte, dataSize),
+		}, nil
+	}
+	return nil, fmt.Errorf("unsupported FEC type: %v", fec.SumType)
+}
+
+// raptorQFEC returns parameters of fec.raptorQ for data of the given size.
+func raptorQFEC(dataSize int) adnl.FecType {
+	var fec adnl.FecType
+	fec.SumType = "FecRaptorQ"
+	fec.FecRaptorQ.DataSize = uint32(dataSize)
+	fec.FecRaptorQ.SymbolSize = symbolSize
+	fec.FecRaptorQ.SymbolsCount = uint32((dataSize + symbolSize - 1) / symbolSize)
+	return fec
+}
+
+type roundRobinDecoder struct {
+	symbolSize int
+	received   []bool
+	left       int
+	buf        []byte
+}
+
+func (d *roundRobinDecoder) addSymbol(seqno uint32, data []byte) (bool, error) {
+	i := int(seqno % uint32(len(d.received)))
+	start := i * d.symbolSize
+	end := start + d.symbolSize
+	if end > len(d.buf) {
+		end = len(d.buf)
+	}
+	if len(data) < end-start || len(data) > d.symbolSize {
+		return false, fmt.Errorf("invalid symbol size: %v", len(data))
+	}
+	if !d.received[i] {
+		copy(d.buf[start:end], data)
+		d.received[i] = true
+		d.left--
+	}
+	return d.left == 0, nil
+}
+
+func (d *roundRobinDecoder) data() []byte {
+	return d.buf
+}
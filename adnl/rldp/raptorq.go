@@ -0,0 +1,658 @@
+package rldp
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math/bits"
+)
+
+// maxRaptorQSymbols limits a number of source symbols of fec.raptorQ parts,
+// a decoder solves a dense system of about this size, so bigger parts are too expensive to restore.
+const maxRaptorQSymbols = 4096
+
+// raptorQParams are parameters of the RFC 6330 code for a source block of k symbols.
+type raptorQParams struct {
+	// k is a number of source symbols, kPadded is k' of the smallest systematic index not less than k.
+	k, kPadded uint32
+	j, s, h, w uint32
+	// l is a number of intermediate symbols: w LT symbols followed by p permanently inactivated ones.
+	l, p, p1, b uint32
+}
+
+func newRaptorQParams(k uint32) (raptorQParams, error) {
+	if k == 0 || k > maxRaptorQSymbols {
+		return raptorQParams{}, fmt.Errorf("unsupported number of symbols: %v", k)
+	}
+	for _, row := range raptorQParamsTable {
+		if row[0] < k {
+			continue
+		}
+		p := raptorQParams{k: k, kPadded: row[0], j: row[1], s: row[2], h: row[3], w: row[4]}
+		p.l = p.kPadded + p.s + p.h
+		p.p = p.l - p.w
+		p.b = p.w - p.s
+		p.p1 = p.p + 1
+		for !isPrime(p.p1) {
+			p.p1++
+		}
+		return p, nil
+	}
+	return raptorQParams{}, fmt.Errorf("unsupported number of symbols: %v", k)
+}
+
+func isPrime(n uint32) bool {
+	if n < 2 {
+		return false
+	}
+	for i := uint32(2); i*i <= n; i++ {
+		if n%i == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// raptorQRand is Rand[y, i, m] of RFC 6330, section 5.3.5.1.
+func raptorQRand(y, i, m uint32) uint32 {
+	x0 := (y + i) % 256
+	x1 := (y>>8 + i) % 256
+	x2 := (y>>16 + i) % 256
+	x3 := (y>>24 + i) % 256
+	return (raptorQV0[x0] ^ raptorQV1[x1] ^ raptorQV2[x2] ^ raptorQV3[x3]) % m
+}
+
+// raptorQDegrees is the degree distribution of RFC 6330, section 5.3.5.2.
+var raptorQDegrees = [...]uint32{
+	0, 5243, 529531, 704294, 791675, 844104, 879057, 904023, 922747, 937311, 948962,
+	958494, 966438, 973160, 978921, 983914, 988283, 992138, 995565, 998631, 1001391, 1003887,
+	1006157, 1008229, 1010129, 1011876, 1013490, 1014983, 1016370, 1017662, 1048576,
+}
+
+// columns returns indices of intermediate symbols the encoding symbol with the given internal symbol id is a sum of,
+// they are defined by Tuple[K', X] and Enc[K', C, (d, a, b, d1, a1, b1)] of RFC 6330, sections 5.3.5.3 and 5.3.5.4.
+func (p *raptorQParams) columns(isi uint32) []uint32 {
+	a := 53591 + p.j*997
+	if a%2 == 0 {
+		a++
+	}
+	y := 10267*(p.j+1) + isi*a
+	v := raptorQRand(y, 0, 1<<20)
+	d := uint32(1)
+	for d < uint32(len(raptorQDegrees)) && raptorQDegrees[d] <= v {
+		d++
+	}
+	if d > p.w-2 {
+		d = p.w - 2
+	}
+	a = 1 + raptorQRand(y, 1, p.w-1)
+	b := raptorQRand(y, 2, p.w)
+	d1 := uint32(2)
+	if d < 4 {
+		d1 += raptorQRand(isi, 3, 2)
+	}
+	a1 := 1 + raptorQRand(isi, 4, p.p1-1)
+	b1 := raptorQRand(isi, 5, p.p1)
+
+	cols := make([]uint32, 0, d+d1)
+	cols = append(cols, b)
+	for i := uint32(1); i < d; i++ {
+		b = (b + a) % p.w
+		cols = append(cols, b)
+	}
+	for b1 >= p.p {
+		b1 = (b1 + a1) % p.p1
+	}
+	cols = append(cols, p.w+b1)
+	for i := uint32(1); i < d1; i++ {
+		b1 = (b1 + a1) % p.p1
+		for b1 >= p.p {
+			b1 = (b1 + a1) % p.p1
+		}
+		cols = append(cols, p.w+b1)
+	}
+	return cols
+}
+
+// hdpcRows returns H rows of the constraint matrix defined by MT*GAMMA | I_H in RFC 6330, section 5.3.3.3.
+func (p *raptorQParams) hdpcRows() [][]byte {
+	n := p.kPadded + p.s
+	rows := make([][]byte, p.h)
+	for i := range rows {
+		rows[i] = make([]byte, p.l)
+	}
+	mt := make([][]byte, p.h)
+	for i := range mt {
+		mt[i] = make([]byte, n)
+		mt[i][n-1] = gfExp[i]
+	}
+	for j := uint32(0); j+1 < n; j++ {
+		a := raptorQRand(j+1, 6, p.h)
+		b := (a + raptorQRand(j+1, 7, p.h-1) + 1) % p.h
+		mt[a][j] = 1
+		mt[b][j] = 1
+	}
+	for i, row := range rows {
+		// (MT*GAMMA)[i][j] = sum of MT[i][m] * alpha^(m-j) for m >= j
+		row[n-1] = mt[i][n-1]
+		for j := int(n) - 2; j >= 0; j-- {
+			row[j] = mt[i][j] ^ gfMul(2, row[j+1])
+		}
+		row[n+uint32(i)] = 1
+	}
+	return rows
+}
+
+// binaryRow is a row of the constraint matrix with coefficients from GF(2) and its right-hand side.
+type binaryRow struct {
+	bits []uint64
+	data []byte
+}
+
+func (r *binaryRow) has(col uint32) bool {
+	return r.bits[col/64]&(1<<(col%64)) != 0
+}
+
+func (r *binaryRow) toggle(col uint32) {
+	r.bits[col/64] ^= 1 << (col % 64)
+}
+
+// each calls f for every column with a non-zero coefficient.
+func (r *binaryRow) each(f func(col uint32)) {
+	for i, word := range r.bits {
+		for word != 0 {
+			f(uint32(i*64 + bits.TrailingZeros64(word)))
+			word &= word - 1
+		}
+	}
+}
+
+func (r *binaryRow) add(other *binaryRow) {
+	for i, w := range other.bits {
+		r.bits[i] ^= w
+	}
+	xorBytes(r.data, other.data)
+}
+
+// solve restores intermediate symbols from encoding symbols with the given internal symbol ids.
+// It returns false if the symbols don't determine the intermediate ones.
+//
+// Columns of LT symbols are eliminated one by one with rows having a single active column as in the first phase of RFC 6330, section 5.4.2.2,
+// so the sparse part of the matrix is never filled in. When there is no such row, the other active columns of a row with the fewest of them are inactivated.
+// Inactive columns, PI symbols being inactive from the start, are solved by a small dense system with HDPC rows.
+func (p *raptorQParams) solve(isis []uint32, symbols [][]byte, symbolSize int) ([][]byte, bool) {
+	words := int(p.l+63) / 64
+	rows := make([]binaryRow, int(p.s)+len(isis))
+	slab := make([]uint64, words*len(rows))
+	for i := range rows {
+		rows[i].bits = slab[i*words : (i+1)*words]
+		rows[i].data = make([]byte, symbolSize)
+	}
+	// LDPC rows of RFC 6330, section 5.3.3.3
+	for i := uint32(0); i < p.b; i++ {
+		a := 1 + i/p.s
+		b := i % p.s
+		rows[b].toggle(i)
+		b = (b + a) % p.s
+		rows[b].toggle(i)
+		b = (b + a) % p.s
+		rows[b].toggle(i)
+	}
+	for i := uint32(0); i < p.s; i++ {
+		rows[i].toggle(p.b + i)
+		rows[i].toggle(p.w + i%p.p)
+		rows[i].toggle(p.w + (i+1)%p.p)
+	}
+	for i, isi := range isis {
+		row := &rows[int(p.s)+i]
+		for _, col := range p.columns(isi) {
+			row.toggle(col)
+		}
+		copy(row.data, symbols[i])
+	}
+
+	// rows are eliminated in place, original ones restore pivot columns afterwards
+	original := append([]uint64(nil), slab...)
+
+	active := make([]bool, p.l)
+	columnRows := make([][]int, p.w)
+	degrees := make([]int, len(rows))
+	var queue []int
+	for i := range rows {
+		rows[i].each(func(col uint32) {
+			if col < p.w {
+				columnRows[col] = append(columnRows[col], i)
+				degrees[i]++
+			}
+		})
+		if degrees[i] == 1 {
+			queue = append(queue, i)
+		}
+	}
+	for col := uint32(0); col < p.w; col++ {
+		active[col] = true
+	}
+	pivoted := make([]bool, len(rows))
+	pivots := make([]int, p.l)
+	var order []uint32
+	for i := range pivots {
+		pivots[i] = -1
+	}
+	// deactivate removes an active column from rows that are not pivoted yet.
+	deactivate := func(col uint32) {
+		active[col] = false
+		for _, i := range columnRows[col] {
+			if pivoted[i] || !rows[i].has(col) {
+				continue
+			}
+			degrees[i]--
+			if degrees[i] == 1 {
+				queue = append(queue, i)
+			}
+		}
+	}
+	for {
+		pivot := -1
+		for len(queue) > 0 && pivot < 0 {
+			i := queue[len(queue)-1]
+			queue = queue[:len(queue)-1]
+			if !pivoted[i] && degrees[i] == 1 {
+				pivot = i
+			}
+		}
+		if pivot < 0 {
+			for i := range rows {
+				if !pivoted[i] && degrees[i] > 0 && (pivot < 0 || degrees[i] < degrees[pivot]) {
+					pivot = i
+				}
+			}
+			if pivot < 0 {
+				break
+			}
+			var inactivated []uint32
+			rows[pivot].each(func(col uint32) {
+				if active[col] {
+					inactivated = append(inactivated, col)
+				}
+			})
+			for _, col := range inactivated[1:] {
+				deactivate(col)
+			}
+		}
+		var col uint32
+		rows[pivot].each(func(c uint32) {
+			if active[c] {
+				col = c
+			}
+		})
+		pivoted[pivot] = true
+		pivots[col] = pivot
+		order = append(order, col)
+		deactivate(col)
+		for _, i := range columnRows[col] {
+			if !pivoted[i] && rows[i].has(col) {
+				rows[i].add(&rows[pivot])
+			}
+		}
+	}
+
+	// rows left after elimination and HDPC rows with eliminated pivot columns form a dense system of inactive columns
+	var inactive []uint32
+	index := make([]int, p.l)
+	for col := uint32(0); col < p.l; col++ {
+		if pivots[col] < 0 {
+			index[col] = len(inactive)
+			inactive = append(inactive, col)
+		}
+	}
+	var system, rhs [][]byte
+	for i := range rows {
+		if pivoted[i] {
+			continue
+		}
+		coefs := make([]byte, len(inactive))
+		rows[i].each(func(col uint32) {
+			coefs[index[col]] = 1
+		})
+		system = append(system, coefs)
+		rhs = append(rhs, rows[i].data)
+	}
+	hdpc := p.hdpcRows()
+	reduced := make([][]byte, len(hdpc))
+	for h, row := range hdpc {
+		reduced[h] = make([]byte, len(inactive))
+		for k, col := range inactive {
+			reduced[h][k] = row[col]
+		}
+	}
+	for col, i := range pivots {
+		if i < 0 {
+			continue
+		}
+		rows[i].each(func(c uint32) {
+			if c == uint32(col) {
+				return
+			}
+			for h, row := range hdpc {
+				reduced[h][index[c]] ^= row[col]
+			}
+		})
+	}
+	system = append(system, reduced...)
+	rhs = append(rhs, p.hdpcData(rows, pivots, symbolSize)...)
+	values, ok := solveGF256(system, rhs, len(inactive))
+	if !ok {
+		return nil, false
+	}
+
+	intermediate := make([][]byte, p.l)
+	for k, col := range inactive {
+		intermediate[col] = values[k]
+	}
+	// a row of a pivot column has other pivot columns only if they are pivoted earlier
+	for _, col := range order {
+		i := pivots[col]
+		row := binaryRow{bits: original[i*words : (i+1)*words], data: make([]byte, symbolSize)}
+		if i >= int(p.s) {
+			copy(row.data, symbols[i-int(p.s)])
+		}
+		row.toggle(col)
+		row.each(func(c uint32) {
+			xorBytes(row.data, intermediate[c])
+		})
+		intermediate[col] = row.data
+	}
+	return intermediate, true
+}
+
+// hdpcData returns right-hand sides of HDPC rows after pivot columns are eliminated with their rows,
+// that is MT*GAMMA*D for a vector D of data of pivot rows. GAMMA*D is computed as g[i] = alpha*g[i-1] + D[i].
+func (p *raptorQParams) hdpcData(rows []binaryRow, pivots []int, symbolSize int) [][]byte {
+	n := p.kPadded + p.s
+	data := make([][]byte, p.h)
+	for i := range data {
+		data[i] = make([]byte, symbolSize)
+	}
+	g := make([]byte, symbolSize)
+	for j := uint32(0); j < n; j++ {
+		gfMulAlpha(g)
+		if i := pivots[j]; i >= 0 {
+			xorBytes(g, rows[i].data)
+		}
+		if j+1 == n {
+			for h := range data {
+				gfMulAdd(data[h], g, gfExp[h])
+			}
+			break
+		}
+		a := raptorQRand(j+1, 6, p.h)
+		b := (a + raptorQRand(j+1, 7, p.h-1) + 1) % p.h
+		xorBytes(data[a], g)
+		xorBytes(data[b], g)
+	}
+	return data
+}
+
+// solveGF256 solves a system of linear equations over GF(256) with n unknowns by Gaussian elimination.
+func solveGF256(system, rhs [][]byte, n int) ([][]byte, bool) {
+	if len(system) < n {
+		return nil, false
+	}
+	for col := 0; col < n; col++ {
+		pivot := -1
+		for i := col; i < len(system); i++ {
+			if system[i][col] != 0 {
+				pivot = i
+				break
+			}
+		}
+		if pivot < 0 {
+			return nil, false
+		}
+		system[col], system[pivot] = system[pivot], system[col]
+		rhs[col], rhs[pivot] = rhs[pivot], rhs[col]
+		if v := system[col][col]; v != 1 {
+			inv := gfInv(v)
+			for k := col; k < n; k++ {
+				system[col][k] = gfMul(system[col][k], inv)
+			}
+			gfScale(rhs[col], inv)
+		}
+		for i := range system {
+			v := system[i][col]
+			if i == col || v == 0 {
+				continue
+			}
+			for k := col; k < n; k++ {
+				system[i][k] ^= gfMul(system[col][k], v)
+			}
+			gfMulAdd(rhs[i], rhs[col], v)
+		}
+	}
+	return rhs[:n], true
+}
+
+// gfExp and gfLog are exponents and logarithms of GF(256) with the polynomial x^8 + x^4 + x^3 + x^2 + 1.
+var gfExp, gfLog = gfTables()
+
+func gfTables() (exp [510]byte, log [256]byte) {
+	x := 1
+	for i := 0; i < 255; i++ {
+		exp[i] = byte(x)
+		exp[i+255] = byte(x)
+		log[x] = byte(i)
+		x <<= 1
+		if x&0x100 != 0 {
+			x ^= 0x11d
+		}
+	}
+	return exp, log
+}
+
+func gfMul(a, b byte) byte {
+	if a == 0 || b == 0 {
+		return 0
+	}
+	return gfExp[int(gfLog[a])+int(gfLog[b])]
+}
+
+func gfInv(a byte) byte {
+	return gfExp[255-int(gfLog[a])]
+}
+
+// gfMulAdd adds src multiplied by v to dst.
+func gfMulAdd(dst, src []byte, v byte) {
+	switch v {
+	case 0:
+	case 1:
+		xorBytes(dst, src)
+	default:
+		lv := int(gfLog[v])
+		for i, s := range src {
+			if s != 0 {
+				dst[i] ^= gfExp[int(gfLog[s])+lv]
+			}
+		}
+	}
+}
+
+func gfScale(data []byte, v byte) {
+	for i, s := range data {
+		data[i] = gfMul(s, v)
+	}
+}
+
+// gfMulAlpha multiplies every byte of data by alpha, 8 bytes at once.
+func gfMulAlpha(data []byte) {
+	n := len(data) &^ 7
+	for i := 0; i < n; i += 8 {
+		w := binary.LittleEndian.Uint64(data[i:])
+		hi := w & 0x8080808080808080
+		binary.LittleEndian.PutUint64(data[i:], (w&^hi)<<1^(hi>>7)*0x1d)
+	}
+	for i := n; i < len(data); i++ {
+		data[i] = gfMul(data[i], 2)
+	}
+}
+
+func xorBytes(dst, src []byte) {
+	n := len(src) &^ 7
+	for i := 0; i < n; i += 8 {
+		binary.LittleEndian.PutUint64(dst[i:], binary.LittleEndian.Uint64(dst[i:])^binary.LittleEndian.Uint64(src[i:]))
+	}
+	for i := n; i < len(src); i++ {
+		dst[i] ^= src[i]
+	}
+}
+
+// raptorQEncoder produces symbols of fec.raptorQ, the first k of them are chunks of the data.
+type raptorQEncoder struct {
+	params     raptorQParams
+	symbolSize int
+	source     []byte
+	// intermediate symbols are restored on the first request of a repair symbol.
+	intermediate [][]byte
+}
+
+func newRaptorQEncoder(data []byte, symbolSize int) (*raptorQEncoder, error) {
+	k := (len(data) + symbolSize - 1) / symbolSize
+	params, err := newRaptorQParams(uint32(k))
+	if err != nil {
+		return nil, err
+	}
+	source := make([]byte, k*symbolSize)
+	copy(source, data)
+	return &raptorQEncoder{params: params, symbolSize: symbolSize, source: source}, nil
+}
+
+// symbol returns a symbol with the given encoding symbol id.
+func (e *raptorQEncoder) symbol(esi uint32) []byte {
+	if esi < e.params.k {
+		return e.source[int(esi)*e.symbolSize : int(esi+1)*e.symbolSize]
+	}
+	if e.intermediate == nil {
+		p := &e.params
+		isis := make([]uint32, p.kPadded)
+		symbols := make([][]byte, p.kPadded)
+		padding := make([]byte, e.symbolSize)
+		for i := range isis {
+			isis[i] = uint32(i)
+			symbols[i] = padding
+			if uint32(i) < p.k {
+				symbols[i] = e.source[i*e.symbolSize : (i+1)*e.symbolSize]
+			}
+		}
+		// source symbols with padding always determine intermediate ones, K' is chosen so
+		intermediate, ok := p.solve(isis, symbols, e.symbolSize)
+		if !ok {
+			panic("raptorQ source symbols are not solvable")
+		}
+		e.intermediate = intermediate
+	}
+	return e.params.symbol(e.intermediate, esi+e.params.kPadded-e.params.k, e.symbolSize)
+}
+
+// symbol returns an encoding symbol with the given internal symbol id.
+func (p *raptorQParams) symbol(intermediate [][]byte, isi uint32, symbolSize int) []byte {
+	s := make([]byte, symbolSize)
+	for _, col := range p.columns(isi) {
+		xorBytes(s, intermediate[col])
+	}
+	return s
+}
+
+// raptorQDecoder restores a part from fec.raptorQ symbols,
+// missing source symbols are restored once at least k symbols are received.
+type raptorQDecoder struct {
+	params     raptorQParams
+	symbolSize int
+	buf        []byte
+	dataSize   int
+	received   []bool
+	left       int
+	repair     map[uint32][]byte
+	done       bool
+}
+
+func newRaptorQDecoder(dataSize, symbolSize int) (*raptorQDecoder, error) {
+	k := (dataSize + symbolSize - 1) / symbolSize
+	params, err := newRaptorQParams(uint32(k))
+	if err != nil {
+		return nil, err
+	}
+	return &raptorQDecoder{
+		params:     params,
+		symbolSize: symbolSize,
+		buf:        make([]byte, k*symbolSize),
+		dataSize:   dataSize,
+		received:   make([]bool, k),
+		left:       k,
+		repair:     make(map[uint32][]byte),
+	}, nil
+}
+
+// maxRepairSymbols limits a number of repair symbols kept by a decoder above the number of missing source ones,
+// a decoder fails with a negligible probability given a few extra symbols.
+const maxRepairSymbols = 32
+
+func (d *raptorQDecoder) addSymbol(seqno uint32, data []byte) (bool, error) {
+	if len(data) != d.symbolSize {
+		return false, fmt.Errorf("invalid symbol size: %v", len(data))
+	}
+	if d.done {
+		return true, nil
+	}
+	if seqno < d.params.k {
+		if !d.received[seqno] {
+			copy(d.buf[int(seqno)*d.symbolSize:], data)
+			d.received[seqno] = true
+			d.left--
+		}
+	} else if _, ok := d.repair[seqno]; !ok && len(d.repair) < d.left+maxRepairSymbols {
+		d.repair[seqno] = append([]byte(nil), data...)
+	}
+	if d.left == 0 {
+		d.done = true
+		return true, nil
+	}
+	if len(d.repair) < d.left {
+		return false, nil
+	}
+	return d.decode(), nil
+}
+
+func (d *raptorQDecoder) decode() bool {
+	p := &d.params
+	isis := make([]uint32, 0, p.kPadded+uint32(len(d.repair)))
+	symbols := make([][]byte, 0, cap(isis))
+	for i, ok := range d.received {
+		if ok {
+			isis = append(isis, uint32(i))
+			symbols = append(symbols, d.buf[i*d.symbolSize:(i+1)*d.symbolSize])
+		}
+	}
+	padding := make([]byte, d.symbolSize)
+	for i := p.k; i < p.kPadded; i++ {
+		isis = append(isis, i)
+		symbols = append(symbols, padding)
+	}
+	for esi, data := range d.repair {
+		isis = append(isis, esi+p.kPadded-p.k)
+		symbols = append(symbols, data)
+	}
+	intermediate, ok := p.solve(isis, symbols, d.symbolSize)
+	if !ok {
+		return false
+	}
+	for i, ok := range d.received {
+		if !ok {
+			copy(d.buf[i*d.symbolSize:], p.symbol(intermediate, uint32(i), d.symbolSize))
+		}
+	}
+	d.done = true
+	d.repair = nil
+	return true
+}
+
+func (d *raptorQDecoder) data() []byte {
+	return d.buf[:d.dataSize]
+}
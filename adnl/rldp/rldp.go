@@ -0,0 +1,477 @@
+package rldp
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/tonkeeper/tongo/adnl"
+	"github.com/tonkeeper/tongo/tl"
+)
+
+const (
+	// maxPartSize is a size of parts outbound transfers are split into.
+	maxPartSize = 1 << 20
+	// DefaultMaxMessageSize limits a size of inbound queries and messages.
+	DefaultMaxMessageSize = 1 << 20
+	// window is a number of symbols sent ahead of the last one confirmed by a receiver.
+	window = 128
+	// confirmInterval is a number of received symbols a receiver confirms at once.
+	confirmInterval = 32
+	// retryInterval is a period a sender waits for a confirmation before sending more symbols.
+	retryInterval = 50 * time.Millisecond
+	// transferTTL is a period an inbound transfer is kept after its last symbol.
+	transferTTL = time.Minute
+	// maxInboundTransfers limits a number of inbound transfers being received at once.
+	maxInboundTransfers = 1024
+	defaultQueryTimeout = 10 * time.Second
+	// answerOverhead is a size of rldp.answer without data.
+	answerOverhead = 64
+)
+
+// ErrClosed is returned when an RLDP instance is closed.
+var ErrClosed = errors.New("rldp is closed")
+
+// Handler answers queries received over RLDP and ADNL.
+type Handler interface {
+	HandleQuery(ctx context.Context, peer *adnl.Peer, query []byte) ([]byte, error)
+}
+
+type transferKey struct {
+	peer tl.Int256
+	id   tl.Int256
+}
+
+type inboundTransfer struct {
+	totalSize    uint64
+	data         []byte
+	part         uint32
+	decoder      decoder
+	maxSeqno     uint32
+	sinceConfirm int
+	done         bool
+	updated      time.Time
+}
+
+type outboundTransfer struct {
+	events chan struct{}
+	// mu protects fields below.
+	mu        sync.Mutex
+	part      uint32
+	confirmed uint32
+	completed uint32
+}
+
+func (t *outboundTransfer) notify() {
+	select {
+	case t.events <- struct{}{}:
+	default:
+	}
+}
+
+type pendingQuery struct {
+	answer chan []byte
+}
+
+// RLDP implements the reliable large datagram protocol on top of ADNL.
+// Messages are split into parts, every part is sent as FEC symbols in adnl.message.custom messages
+// until the receiver restores it and reports with rldp.complete.
+//
+// Outbound parts are encoded with fec.raptorQ as TON nodes do, inbound parts can be encoded with fec.raptorQ or fec.roundRobin.
+type RLDP struct {
+	gateway *adnl.Gateway
+	handler Handler
+	ctx     context.Context
+	cancel  context.CancelFunc
+
+	// mu protects all fields below.
+	mu           sync.Mutex
+	inbound      map[transferKey]*inboundTransfer
+	outbound     map[transferKey]*outboundTransfer
+	queries      map[tl.Int256]pendingQuery
+	answerLimits map[transferKey]uint64
+}
+
+var _ adnl.Handler = (*RLDP)(nil)
+
+// New attaches RLDP to the gateway, so custom messages of peers without their own handlers are processed as RLDP messages.
+// Queries received over RLDP and plain ADNL are answered by the given handler, it can be nil for clients.
+func New(gateway *adnl.Gateway, handler Handler) *RLDP {
+	ctx, cancel := context.WithCancel(context.Background())
+	r := &RLDP{
+		gateway:      gateway,
+		handler:      handler,
+		ctx:          ctx,
+		cancel:       cancel,
+		inbound:      make(map[transferKey]*inboundTransfer),
+		outbound:     make(map[transferKey]*outboundTransfer),
+		queries:      make(map[tl.Int256]pendingQuery),
+		answerLimits: make(map[transferKey]uint64),
+	}
+	gateway.SetHandler(r)
+	return r
+}
+
+// Close stops sending transfers and answering queries, the gateway is left open.
+func (r *RLDP) Close() {
+	r.cancel()
+}
+
+// Query sends a query to the peer and waits for an answer of at most maxAnswerSize bytes.
+// The peer is asked to answer before ctx's deadline.
+func (r *RLDP) Query(ctx context.Context, peer *adnl.Peer, query []byte, maxAnswerSize uint64) ([]byte, error) {
+	var queryID, transferID tl.Int256
+	if _, err := io.ReadFull(rand.Reader, queryID[:]); err != nil {
+		return nil, err
+	}
+	if _, err := io.ReadFull(rand.Reader, transferID[:]); err != nil {
+		return nil, err
+	}
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(defaultQueryTimeout)
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithDeadline(ctx, deadline)
+		defer cancel()
+	}
+	var msg adnl.RldpMessage
+	msg.SumType = "RldpQuery"
+	msg.RldpQuery.QueryId = queryID
+	msg.RldpQuery.MaxAnswerSize = maxAnswerSize
+	msg.RldpQuery.Timeout = uint32(deadline.Unix())
+	msg.RldpQuery.Data = query
+	data, err := tl.Marshal(msg)
+	if err != nil {
+		return nil, err
+	}
+
+	answer := make(chan []byte, 1)
+	answerKey := transferKey{peer: peer.ID(), id: answerTransferID(transferID)}
+	r.mu.Lock()
+	r.queries[queryID] = pendingQuery{answer: answer}
+	r.answerLimits[answerKey] = maxAnswerSize + answerOverhead
+	r.mu.Unlock()
+	defer func() {
+		r.mu.Lock()
+		delete(r.queries, queryID)
+		delete(r.answerLimits, answerKey)
+		r.mu.Unlock()
+	}()
+
+	if err := r.send(ctx, peer, transferID, data); err != nil {
+		return nil, err
+	}
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-r.ctx.Done():
+		return nil, ErrClosed
+	case res := <-answer:
+		return res, nil
+	}
+}
+
+// answerTransferID returns an ID of a transfer with an answer to a query sent with the given transfer.
+func answerTransferID(id tl.Int256) tl.Int256 {
+	for i := range id {
+		id[i] = ^id[i]
+	}
+	return id
+}
+
+// send sends data as a transfer with the given ID and waits until the peer receives it.
+func (r *RLDP) send(ctx context.Context, peer *adnl.Peer, id tl.Int256, data []byte) error {
+	key := transferKey{peer: peer.ID(), id: id}
+	t := &outboundTransfer{events: make(chan struct{}, 1)}
+	r.mu.Lock()
+	r.outbound[key] = t
+	r.mu.Unlock()
+	defer func() {
+		r.mu.Lock()
+		delete(r.outbound, key)
+		r.mu.Unlock()
+	}()
+
+	timer := time.NewTimer(retryInterval)
+	defer timer.Stop()
+	for part := uint32(0); uint64(part)*maxPartSize < uint64(len(data)); part++ {
+		chunk := data[uint64(part)*maxPartSize:]
+		if len(chunk) > maxPartSize {
+			chunk = chunk[:maxPartSize]
+		}
+		fec := raptorQFEC(len(chunk))
+		encoder, err := newRaptorQEncoder(chunk, symbolSize)
+		if err != nil {
+			return err
+		}
+		inFlight := fec.FecRaptorQ.SymbolsCount
+		if inFlight > window {
+			inFlight = window
+		}
+		t.mu.Lock()
+		t.part, t.confirmed = part, 0
+		t.mu.Unlock()
+
+		var seqno uint32
+		for {
+			t.mu.Lock()
+			completed, confirmed := t.completed, t.confirmed
+			t.mu.Unlock()
+			if completed > part {
+				break
+			}
+			for ; seqno < confirmed+inFlight; seqno++ {
+				var msg adnl.RldpMessagePart
+				msg.SumType = "RldpMessagePart"
+				msg.RldpMessagePart.TransferId = id
+				msg.RldpMessagePart.FecType = fec
+				msg.RldpMessagePart.Part = part
+				msg.RldpMessagePart.TotalSize = uint64(len(data))
+				msg.RldpMessagePart.Seqno = seqno
+				msg.RldpMessagePart.Data = encoder.symbol(seqno)
+				if err := r.sendPart(peer, msg); err != nil {
+					return err
+				}
+			}
+			if !timer.Stop() {
+				select {
+				case <-timer.C:
+				default:
+				}
+			}
+			timer.Reset(retryInterval)
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-r.ctx.Done():
+				return ErrClosed
+			case <-t.events:
+			case <-timer.C:
+				// symbols are lost or the receiver is slow, send next ones anyway
+				t.mu.Lock()
+				if t.part == part && t.confirmed < seqno {
+					t.confirmed = seqno
+				}
+				t.mu.Unlock()
+			}
+		}
+	}
+	return nil
+}
+
+func (r *RLDP) sendPart(peer *adnl.Peer, msg adnl.RldpMessagePart) error {
+	b, err := tl.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	return peer.SendCustomMessage(b)
+}
+
+// HandleQuery answers a plain ADNL query with the handler.
+func (r *RLDP) HandleQuery(ctx context.Context, peer *adnl.Peer, query []byte) ([]byte, error) {
+	if r.handler == nil {
+		return nil, fmt.Errorf("no handler")
+	}
+	return r.handler.HandleQuery(ctx, peer, query)
+}
+
+// HandleCustomMessage processes a part of an RLDP transfer.
+func (r *RLDP) HandleCustomMessage(peer *adnl.Peer, data []byte) {
+	var msg adnl.RldpMessagePart
+	if err := tl.Unmarshal(bytes.NewReader(data), &msg); err != nil {
+		return
+	}
+	switch msg.SumType {
+	case "RldpMessagePart":
+		if err := r.handleMessagePart(peer, msg); err != nil {
+			slog.Debug("rldp invalid message part", "peer", fmt.Sprintf("%x", peer.ID()), "err", err)
+		}
+	case "RldpConfirm":
+		r.mu.Lock()
+		t := r.outbound[transferKey{peer: peer.ID(), id: msg.RldpConfirm.TransferId}]
+		r.mu.Unlock()
+		if t == nil {
+			return
+		}
+		t.mu.Lock()
+		if t.part == msg.RldpConfirm.Part && t.confirmed < msg.RldpConfirm.Seqno+1 {
+			t.confirmed = msg.RldpConfirm.Seqno + 1
+		}
+		t.mu.Unlock()
+		t.notify()
+	case "RldpComplete":
+		r.mu.Lock()
+		t := r.outbound[transferKey{peer: peer.ID(), id: msg.RldpComplete.TransferId}]
+		r.mu.Unlock()
+		if t == nil {
+			return
+		}
+		t.mu.Lock()
+		if t.completed < msg.RldpComplete.Part+1 {
+			t.completed = msg.RldpComplete.Part + 1
+		}
+		t.mu.Unlock()
+		t.notify()
+	}
+}
+
+func (r *RLDP) handleMessagePart(peer *adnl.Peer, msg adnl.RldpMessagePart) error {
+	part := msg.RldpMessagePart
+	key := transferKey{peer: peer.ID(), id: part.TransferId}
+	reply, message, err := r.addSymbol(key, msg)
+	if err != nil {
+		return err
+	}
+	if reply != nil {
+		if err := r.sendPart(peer, *reply); err != nil {
+			return err
+		}
+	}
+	if message != nil {
+		go r.handleMessage(peer, part.TransferId, message)
+	}
+	return nil
+}
+
+// addSymbol adds a symbol to an inbound transfer.
+// It returns rldp.confirm or rldp.complete to send back and a received message once the transfer is complete.
+func (r *RLDP) addSymbol(key transferKey, msg adnl.RldpMessagePart) (*adnl.RldpMessagePart, []byte, error) {
+	part := msg.RldpMessagePart
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	t, ok := r.inbound[key]
+	if !ok {
+		limit := uint64(DefaultMaxMessageSize)
+		if l, ok := r.answerLimits[key]; ok {
+			limit = l
+		}
+		if part.TotalSize > limit {
+			return nil, nil, fmt.Errorf("transfer is too big: %v", part.TotalSize)
+		}
+		r.removeStaleTransfers(now)
+		if len(r.inbound) >= maxInboundTransfers {
+			return nil, nil, fmt.Errorf("too many transfers")
+		}
+		t = &inboundTransfer{totalSize: part.TotalSize}
+		r.inbound[key] = t
+	}
+	t.updated = now
+	if part.TotalSize != t.totalSize {
+		return nil, nil, fmt.Errorf("transfer size mismatch")
+	}
+	if t.done || part.Part < t.part {
+		return complete(part.TransferId, part.Part), nil, nil
+	}
+	if part.Part > t.part {
+		return nil, nil, nil
+	}
+	if t.decoder == nil {
+		dataSize, _, _, err := fecParams(part.FecType)
+		if err != nil {
+			return nil, nil, err
+		}
+		if uint64(dataSize) > t.totalSize-uint64(len(t.data)) {
+			return nil, nil, fmt.Errorf("part is too big: %v", dataSize)
+		}
+		if t.decoder, err = newDecoder(part.FecType); err != nil {
+			return nil, nil, err
+		}
+	}
+	restored, err := t.decoder.addSymbol(part.Seqno, part.Data)
+	if err != nil {
+		return nil, nil, err
+	}
+	if part.Seqno > t.maxSeqno {
+		t.maxSeqno = part.Seqno
+	}
+	if !restored {
+		t.sinceConfirm++
+		if t.sinceConfirm < confirmInterval {
+			return nil, nil, nil
+		}
+		t.sinceConfirm = 0
+		var confirm adnl.RldpMessagePart
+		confirm.SumType = "RldpConfirm"
+		confirm.RldpConfirm.TransferId = part.TransferId
+		confirm.RldpConfirm.Part = part.Part
+		confirm.RldpConfirm.Seqno = t.maxSeqno
+		return &confirm, nil, nil
+	}
+	t.data = append(t.data, t.decoder.data()...)
+	t.decoder = nil
+	t.part++
+	t.maxSeqno, t.sinceConfirm = 0, 0
+	if uint64(len(t.data)) < t.totalSize {
+		return complete(part.TransferId, part.Part), nil, nil
+	}
+	t.done = true
+	data := t.data
+	t.data = nil
+	return complete(part.TransferId, part.Part), data, nil
+}
+
+func complete(id tl.Int256, part uint32) *adnl.RldpMessagePart {
+	var msg adnl.RldpMessagePart
+	msg.SumType = "RldpComplete"
+	msg.RldpComplete.TransferId = id
+	msg.RldpComplete.Part = part
+	return &msg
+}
+
+func (r *RLDP) removeStaleTransfers(now time.Time) {
+	for key, t := range r.inbound {
+		if now.Sub(t.updated) > transferTTL {
+			delete(r.inbound, key)
+		}
+	}
+}
+
+// handleMessage processes a message received with an inbound transfer.
+func (r *RLDP) handleMessage(peer *adnl.Peer, transferID tl.Int256, data []byte) {
+	var msg adnl.RldpMessage
+	if err := tl.Unmarshal(bytes.NewReader(data), &msg); err != nil {
+		return
+	}
+	switch msg.SumType {
+	case "RldpAnswer":
+		r.mu.Lock()
+		q, ok := r.queries[msg.RldpAnswer.QueryId]
+		delete(r.queries, msg.RldpAnswer.QueryId)
+		r.mu.Unlock()
+		if ok {
+			q.answer <- msg.RldpAnswer.Data
+		}
+	case "RldpQuery":
+		if r.handler == nil {
+			return
+		}
+		query := msg.RldpQuery
+		ctx, cancel := context.WithDeadline(r.ctx, time.Unix(int64(query.Timeout), 0))
+		defer cancel()
+		res, err := r.handler.HandleQuery(ctx, peer, query.Data)
+		if err != nil {
+			return
+		}
+		if uint64(len(res)) > query.MaxAnswerSize {
+			slog.Debug("rldp answer is too big", "size", len(res), "max", query.MaxAnswerSize)
+			return
+		}
+		var answer adnl.RldpMessage
+		answer.SumType = "RldpAnswer"
+		answer.RldpAnswer.QueryId = query.QueryId
+		answer.RldpAnswer.Data = res
+		b, err := tl.Marshal(answer)
+		if err != nil {
+			return
+		}
+		_ = r.send(ctx, peer, answerTransferID(transferID), b)
+	}
+}
@@ -0,0 +1,214 @@
+package rldp
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/tonkeeper/tongo/adnl"
+)
+
+type echoHandler struct {
+	repeat int
+}
+
+func (h echoHandler) HandleQuery(ctx context.Context, peer *adnl.Peer, query []byte) ([]byte, error) {
+	return bytes.Repeat(query, h.repeat), nil
+}
+
+func startPair(t *testing.T, handler Handler) (*RLDP, *adnl.Peer) {
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey() failed: %v", err)
+	}
+	server, err := adnl.Listen("127.0.0.1:0", key)
+	if err != nil {
+		t.Fatalf("Listen() failed: %v", err)
+	}
+	t.Cleanup(func() { server.Close() })
+	New(server, handler)
+
+	client, err := adnl.Listen("127.0.0.1:0", nil)
+	if err != nil {
+		t.Fatalf("Listen() failed: %v", err)
+	}
+	t.Cleanup(func() { client.Close() })
+	r := New(client, nil)
+	t.Cleanup(r.Close)
+	peer, err := client.Connect(key.Public().(ed25519.PublicKey), server.Addr().String())
+	if err != nil {
+		t.Fatalf("Connect() failed: %v", err)
+	}
+	return r, peer
+}
+
+func TestRLDP_Query(t *testing.T) {
+	tests := []struct {
+		name          string
+		querySize     int
+		repeat        int
+		maxAnswerSize uint64
+		wantErr       bool
+	}{
+		{name: "small", querySize: 10, repeat: 2, maxAnswerSize: 100},
+		{name: "several symbols", querySize: 5000, repeat: 1, maxAnswerSize: 5000},
+		{name: "several parts", querySize: 1000, repeat: 3000, maxAnswerSize: 3000000},
+		{name: "too big answer", querySize: 100, repeat: 2, maxAnswerSize: 199, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, peer := startPair(t, echoHandler{repeat: tt.repeat})
+			query := make([]byte, tt.querySize)
+			if _, err := rand.Read(query); err != nil {
+				t.Fatalf("Read() failed: %v", err)
+			}
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+			if tt.wantErr {
+				ctx, cancel = context.WithTimeout(ctx, time.Second)
+				defer cancel()
+			}
+			res, err := r.Query(ctx, peer, query, tt.maxAnswerSize)
+			if tt.wantErr {
+				if !errors.Is(err, context.DeadlineExceeded) {
+					t.Fatalf("want DeadlineExceeded, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Query() failed: %v", err)
+			}
+			if !bytes.Equal(res, bytes.Repeat(query, tt.repeat)) {
+				t.Fatalf("answer mismatch")
+			}
+		})
+	}
+}
+
+func TestRLDP_tooBigQuery(t *testing.T) {
+	r, peer := startPair(t, echoHandler{repeat: 1})
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	_, err := r.Query(ctx, peer, make([]byte, DefaultMaxMessageSize+1), DefaultMaxMessageSize+1)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("want DeadlineExceeded, got %v", err)
+	}
+}
+
+// roundRobinSymbol returns a symbol of fec.roundRobin with the given seqno, symbols are chunks of data repeated in a cycle.
+func roundRobinSymbol(data []byte, seqno uint32) []byte {
+	count := (len(data) + symbolSize - 1) / symbolSize
+	start := int(seqno%uint32(count)) * symbolSize
+	end := start + symbolSize
+	if end > len(data) {
+		end = len(data)
+	}
+	return data[start:end]
+}
+
+func TestNewDecoder(t *testing.T) {
+	data := make([]byte, 2000)
+	if _, err := rand.Read(data); err != nil {
+		t.Fatalf("Read() failed: %v", err)
+	}
+	var fec adnl.FecType
+	fec.SumType = "FecRoundRobin"
+	fec.FecRoundRobin.DataSize = uint32(len(data))
+	fec.FecRoundRobin.SymbolSize = symbolSize
+	fec.FecRoundRobin.SymbolsCount = 3
+	d, err := newDecoder(fec)
+	if err != nil {
+		t.Fatalf("newDecoder() failed: %v", err)
+	}
+	// symbols arrive out of order and repeated
+	for i, seqno := range []uint32{4, 1, 6, 2} {
+		done, err := d.addSymbol(seqno, roundRobinSymbol(data, seqno))
+		if err != nil {
+			t.Fatalf("addSymbol() failed: %v", err)
+		}
+		if done != (i == 3) {
+			t.Fatalf("symbol %v: want done %v", seqno, i == 3)
+		}
+	}
+	if !bytes.Equal(d.data(), data) {
+		t.Fatalf("data mismatch")
+	}
+
+	fec.FecRoundRobin.SymbolsCount++
+	if _, err := newDecoder(fec); err == nil {
+		t.Fatalf("invalid symbols count must be rejected")
+	}
+	var online adnl.FecType
+	online.SumType = "FecOnline"
+	online.FecOnline.DataSize = 2000
+	online.FecOnline.SymbolSize = symbolSize
+	online.FecOnline.SymbolsCount = 3
+	if _, err := newDecoder(online); err == nil {
+		t.Fatalf("fec.online must be rejected")
+	}
+	raptorQ := raptorQFEC(maxRaptorQSymbols*symbolSize + 1)
+	if _, err := newDecoder(raptorQ); err == nil {
+		t.Fatalf("too many symbols must be rejected")
+	}
+}
+
+func TestRaptorQ(t *testing.T) {
+	// the vector of github.com/xssnick/raptorq used by tonutils-go
+	e, err := newRaptorQEncoder([]byte("hello world bro! keke meme 881"), 20)
+	if err != nil {
+		t.Fatalf("newRaptorQEncoder() failed: %v", err)
+	}
+	if got := hex.EncodeToString(e.symbol(68238283)); got != "05e6ddeb1f820e0a0f318b23128d889623663e66" {
+		t.Fatalf("symbol mismatch: %v", got)
+	}
+
+	data := make([]byte, 1000000)
+	for i := range data {
+		data[i] = byte(i % 251)
+	}
+	e, err = newRaptorQEncoder(data, symbolSize)
+	if err != nil {
+		t.Fatalf("newRaptorQEncoder() failed: %v", err)
+	}
+	// hashes of repair symbols produced by github.com/xssnick/raptorq
+	for esi, want := range map[uint32]string{
+		1303:   "d9b0ca3cfeb7f71fadb916df7e8ef95fec6af6e9c584aa276e423b66475514a4",
+		100000: "19e2fc8fc38f86e0cb08a140f524a65cacc43d3f03d9afb4875b9ada95a124ec",
+	} {
+		if got := sha256.Sum256(e.symbol(esi)); hex.EncodeToString(got[:]) != want {
+			t.Fatalf("symbol %v mismatch", esi)
+		}
+	}
+
+	fec := raptorQFEC(len(data))
+	d, err := newDecoder(fec)
+	if err != nil {
+		t.Fatalf("newDecoder() failed: %v", err)
+	}
+	// every third source symbol is lost and replaced by a repair one
+	k := fec.FecRaptorQ.SymbolsCount
+	var done bool
+	for seqno := uint32(0); !done; seqno++ {
+		if seqno < k && seqno%3 == 0 {
+			continue
+		}
+		if seqno > 2*k {
+			t.Fatalf("data is not restored")
+		}
+		if done, err = d.addSymbol(seqno, e.symbol(seqno)); err != nil {
+			t.Fatalf("addSymbol() failed: %v", err)
+		}
+	}
+	if !bytes.Equal(d.data(), data) {
+		t.Fatalf("data mismatch")
+	}
+	if _, err := d.addSymbol(0, make([]byte, symbolSize-1)); err == nil {
+		t.Fatalf("invalid symbol size must be rejected")
+	}
+}
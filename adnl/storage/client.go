@@ -0,0 +1,332 @@
+package storage
+
+import (
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/tonkeeper/tongo/adnl"
+	"github.com/tonkeeper/tongo/adnl/dht"
+	"github.com/tonkeeper/tongo/adnl/rldp"
+	"github.com/tonkeeper/tongo/boc"
+	"github.com/tonkeeper/tongo/tl"
+	"github.com/tonkeeper/tongo/tlb"
+)
+
+const (
+	defaultQueryTimeout = 10 * time.Second
+	// maxPieceSize limits a piece size of bags the client downloads.
+	maxPieceSize = 8 << 20
+	// maxProofSize limits a size of a merkle proof of a piece.
+	maxProofSize = 64 << 10
+	// maxHeaderSize limits a size of a header of bags the client downloads.
+	maxHeaderSize = 16 << 20
+	// maxTorrentInfoSize limits a size of a serialized torrent info.
+	maxTorrentInfoSize = 64 << 10
+	// maxCachedPieces is a number of verified pieces a bag keeps in memory.
+	maxCachedPieces = 64
+)
+
+// ErrNoPeers means that there are no peers to download a bag from.
+var ErrNoPeers = errors.New("no peers")
+
+// Client downloads bags from TON Storage peers over RLDP.
+type Client struct {
+	gateway      *adnl.Gateway
+	rldp         *rldp.RLDP
+	dht          *dht.Client
+	queryTimeout time.Duration
+}
+
+// NewClient attaches a client to the gateway, so a Server can't be attached to the same gateway.
+// dht is used to find peers of bags and can be nil if peers are always passed to OpenBag.
+func NewClient(gateway *adnl.Gateway, dht *dht.Client) *Client {
+	return &Client{
+		gateway:      gateway,
+		rldp:         rldp.New(gateway, nil),
+		dht:          dht,
+		queryTimeout: defaultQueryTimeout,
+	}
+}
+
+// FindPeers looks up nodes of the bag's overlay in DHT and connects to them.
+func (c *Client) FindPeers(ctx context.Context, bagID tl.Int256) ([]*adnl.Peer, error) {
+	if c.dht == nil {
+		return nil, fmt.Errorf("dht client is not set")
+	}
+	overlay, err := OverlayID(bagID)
+	if err != nil {
+		return nil, err
+	}
+	nodes, err := c.dht.FindOverlayNodes(ctx, overlay)
+	if err != nil {
+		return nil, err
+	}
+	var peers []*adnl.Peer
+	for _, n := range nodes {
+		id, err := adnl.ShortID(n.Id)
+		if err != nil {
+			continue
+		}
+		addrs, key, err := c.dht.FindAddresses(ctx, id)
+		if err != nil {
+			continue
+		}
+		peer, err := c.gateway.Connect(key, addrs.Addrs[0])
+		if err != nil {
+			continue
+		}
+		peers = append(peers, peer)
+	}
+	if len(peers) == 0 {
+		return nil, ErrNoPeers
+	}
+	return peers, nil
+}
+
+// OpenBag downloads a description and a header of the bag.
+// If no peers are given, they are looked up with FindPeers.
+// Pieces are downloaded later when files are read, every piece is verified against the bag's merkle tree.
+func (c *Client) OpenBag(ctx context.Context, bagID tl.Int256, peers ...*adnl.Peer) (*Bag, error) {
+	if len(peers) == 0 {
+		var err error
+		if peers, err = c.FindPeers(ctx, bagID); err != nil {
+			return nil, err
+		}
+	}
+	overlay, err := OverlayID(bagID)
+	if err != nil {
+		return nil, err
+	}
+	b := &Bag{
+		client:  c,
+		id:      bagID,
+		overlay: overlay,
+		peers:   peers,
+		cache:   make(map[uint32][]byte),
+	}
+	var errs []error
+	for _, peer := range peers {
+		info, err := b.torrentInfo(ctx, peer)
+		if err == nil {
+			b.info = info
+			break
+		}
+		errs = append(errs, err)
+	}
+	if len(errs) == len(peers) {
+		return nil, fmt.Errorf("failed to get torrent info: %w", errs[0])
+	}
+	headerData := make([]byte, b.info.HeaderSize)
+	if _, err := b.readAt(ctx, headerData, 0); err != nil {
+		return nil, err
+	}
+	if sha256.Sum256(headerData) != b.info.HeaderHash {
+		return nil, fmt.Errorf("header hash mismatch")
+	}
+	if b.header, err = parseHeader(headerData, b.info.FileSize); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// Bag is a bag being downloaded.
+type Bag struct {
+	client  *Client
+	id      tl.Int256
+	overlay tl.Int256
+	peers   []*adnl.Peer
+	info    TorrentInfo
+	header  header
+
+	// mu protects fields below.
+	mu       sync.Mutex
+	cache    map[uint32][]byte
+	order    []uint32
+	nextPeer int
+}
+
+// ID returns the bag ID.
+func (b *Bag) ID() tl.Int256 {
+	return b.id
+}
+
+// Info returns a description of the bag.
+func (b *Bag) Info() TorrentInfo {
+	return b.info
+}
+
+// DirName returns a name of a directory the bag's files are put into.
+func (b *Bag) DirName() string {
+	return b.header.dirName
+}
+
+// Files returns files of the bag.
+func (b *Bag) Files() []FileInfo {
+	return append([]FileInfo{}, b.header.files...)
+}
+
+// Open returns a file of the bag with the given name.
+func (b *Bag) Open(name string) (*File, error) {
+	for _, f := range b.header.files {
+		if f.Name == name {
+			return &File{bag: b, info: f}, nil
+		}
+	}
+	return nil, fmt.Errorf("file not found: %v", name)
+}
+
+func (b *Bag) torrentInfo(ctx context.Context, peer *adnl.Peer) (TorrentInfo, error) {
+	var res adnl.StorageTorrentInfoC
+	if err := b.query(ctx, peer, tagGetTorrentInfo, adnl.StorageGetTorrentInfoRequest{}, tagTorrentInfo, &res, maxTorrentInfoSize); err != nil {
+		return TorrentInfo{}, err
+	}
+	cell, err := boc.DeserializeSingleRootBoc(res.Data)
+	if err != nil {
+		return TorrentInfo{}, err
+	}
+	hash, err := cell.Hash256()
+	if err != nil {
+		return TorrentInfo{}, err
+	}
+	if hash != b.id {
+		return TorrentInfo{}, fmt.Errorf("torrent info hash mismatch")
+	}
+	var info TorrentInfo
+	if err := tlb.Unmarshal(cell, &info); err != nil {
+		return TorrentInfo{}, err
+	}
+	if info.PieceSize == 0 || info.PieceSize > maxPieceSize || info.HeaderSize > info.FileSize || info.HeaderSize > maxHeaderSize {
+		return TorrentInfo{}, fmt.Errorf("invalid torrent info")
+	}
+	return info, nil
+}
+
+// query sends a storage query within the bag's overlay.
+func (b *Bag) query(ctx context.Context, peer *adnl.Peer, tag uint32, req tl.MarshalerTL, resTag uint32, res any, maxSize uint64) error {
+	prefix, err := adnl.MarshalBoxed(tagOverlayQuery, adnl.OverlayQueryRequest{Overlay: b.overlay})
+	if err != nil {
+		return err
+	}
+	q, err := adnl.MarshalBoxed(tag, req)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(ctx, b.client.queryTimeout)
+	defer cancel()
+	answer, err := b.client.rldp.Query(ctx, peer, append(prefix, q...), maxSize)
+	if err != nil {
+		return err
+	}
+	return adnl.UnmarshalBoxed(answer, resTag, res)
+}
+
+// piece returns a verified piece, downloading it from peers one by one until one of them returns a valid piece.
+func (b *Bag) piece(ctx context.Context, piece uint32) ([]byte, error) {
+	b.mu.Lock()
+	data, ok := b.cache[piece]
+	first := b.nextPeer
+	b.nextPeer = (b.nextPeer + 1) % len(b.peers)
+	b.mu.Unlock()
+	if ok {
+		return data, nil
+	}
+	size := uint64(b.info.PieceSize)
+	if last := b.info.FileSize - uint64(piece)*size; last < size {
+		size = last
+	}
+	var lastErr error
+	for i := range b.peers {
+		peer := b.peers[(first+i)%len(b.peers)]
+		var res adnl.StoragePieceC
+		err := b.query(ctx, peer, tagGetPiece, adnl.StorageGetPieceRequest{PieceId: piece}, tagPiece, &res, uint64(b.info.PieceSize)+maxProofSize)
+		if err == nil && uint64(len(res.Data)) != size {
+			err = fmt.Errorf("invalid piece size")
+		}
+		if err == nil {
+			err = checkPieceProof(res.Proof, b.info.RootHash, b.info.PiecesCount(), piece, res.Data)
+		}
+		if err != nil {
+			lastErr = err
+			if ctx.Err() != nil {
+				break
+			}
+			continue
+		}
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		if _, ok := b.cache[piece]; !ok {
+			if len(b.order) >= maxCachedPieces {
+				delete(b.cache, b.order[0])
+				b.order = b.order[1:]
+			}
+			b.cache[piece] = res.Data
+			b.order = append(b.order, piece)
+		}
+		return res.Data, nil
+	}
+	return nil, fmt.Errorf("failed to download piece %v: %w", piece, lastErr)
+}
+
+// readAt reads bag data.
+func (b *Bag) readAt(ctx context.Context, p []byte, off uint64) (int, error) {
+	if off >= b.info.FileSize {
+		return 0, io.EOF
+	}
+	if uint64(len(p)) > b.info.FileSize-off {
+		p = p[:b.info.FileSize-off]
+	}
+	pieceSize := uint64(b.info.PieceSize)
+	var n int
+	for n < len(p) {
+		pos := off + uint64(n)
+		data, err := b.piece(ctx, uint32(pos/pieceSize))
+		if err != nil {
+			return n, err
+		}
+		n += copy(p[n:], data[pos%pieceSize:])
+	}
+	return n, nil
+}
+
+// File is a file of a bag, its data is downloaded when it is read.
+type File struct {
+	bag  *Bag
+	info FileInfo
+}
+
+var _ io.ReaderAt = (*File)(nil)
+
+// Name returns a name of the file.
+func (f *File) Name() string {
+	return f.info.Name
+}
+
+// Size returns a size of the file.
+func (f *File) Size() int64 {
+	return int64(f.info.Size)
+}
+
+// ReadAt reads the file's data downloading pieces it is stored in.
+func (f *File) ReadAt(p []byte, off int64) (int, error) {
+	if off < 0 {
+		return 0, fmt.Errorf("negative offset")
+	}
+	if uint64(off) >= f.info.Size {
+		return 0, io.EOF
+	}
+	var eof error
+	if left := f.info.Size - uint64(off); uint64(len(p)) > left {
+		p = p[:left]
+		eof = io.EOF
+	}
+	n, err := f.bag.readAt(context.Background(), p, f.info.Offset+uint64(off))
+	if err != nil {
+		return n, err
+	}
+	return n, eof
+}
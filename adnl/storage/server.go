@@ -0,0 +1,242 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/tonkeeper/tongo/adnl"
+	"github.com/tonkeeper/tongo/adnl/rldp"
+	"github.com/tonkeeper/tongo/boc"
+	"github.com/tonkeeper/tongo/tl"
+	"github.com/tonkeeper/tongo/tlb"
+)
+
+const (
+	tagOverlayQuery   uint32 = 0xccfd8443
+	tagGetTorrentInfo uint32 = 0x91c4962a
+	tagGetPiece       uint32 = 0x807ae660
+	tagTorrentInfo    uint32 = 0x14ced0ee
+	tagPiece          uint32 = 0x80b4fa0d
+
+	// overlayQuerySize is a size of a boxed overlay.query prefixing storage queries.
+	overlayQuerySize = 36
+	// DefaultPieceSize is a piece size TON Storage uses for new bags.
+	DefaultPieceSize = 128 * 1024
+)
+
+// Source is a file added to a bag.
+type Source struct {
+	Name string
+	Data io.ReaderAt
+	Size int64
+}
+
+// Torrent is a bag served by a Server.
+type Torrent struct {
+	id      tl.Int256
+	info    TorrentInfo
+	infoBoc []byte
+	tree    *merkleTree
+	files   []FileInfo
+	data    *bagReader
+}
+
+// CreateTorrent splits files into pieces and builds a merkle tree of them.
+// Files are read when the bag is created and every time a piece is requested, so they must not change.
+func CreateTorrent(dirName string, files []Source, pieceSize uint32, description string) (*Torrent, error) {
+	if pieceSize == 0 {
+		return nil, fmt.Errorf("piece size must be positive")
+	}
+	h := header{dirName: dirName}
+	for _, f := range files {
+		h.files = append(h.files, FileInfo{Name: f.Name, Size: uint64(f.Size)})
+	}
+	headerData := h.marshal()
+	data := &bagReader{}
+	data.add(bytes.NewReader(headerData), uint64(len(headerData)))
+	for _, f := range files {
+		data.add(f.Data, uint64(f.Size))
+	}
+	info := TorrentInfo{
+		PieceSize:   pieceSize,
+		FileSize:    data.size,
+		HeaderSize:  uint64(len(headerData)),
+		HeaderHash:  sha256.Sum256(headerData),
+		Description: tlb.DNSText(description),
+	}
+	var hashes [][32]byte
+	buf := make([]byte, pieceSize)
+	for i := uint64(0); i < info.PiecesCount(); i++ {
+		piece, err := data.piece(buf, pieceSize, uint32(i))
+		if err != nil {
+			return nil, err
+		}
+		hashes = append(hashes, sha256.Sum256(piece))
+	}
+	tree, err := newMerkleTree(hashes)
+	if err != nil {
+		return nil, err
+	}
+	if info.RootHash, err = tree.root.Hash256(); err != nil {
+		return nil, err
+	}
+	cell := boc.NewCell()
+	if err := tlb.Marshal(cell, info); err != nil {
+		return nil, err
+	}
+	t := &Torrent{info: info, tree: tree, files: h.files, data: data}
+	if t.id, err = cell.Hash256(); err != nil {
+		return nil, err
+	}
+	if t.infoBoc, err = cell.ToBoc(); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// ID returns the bag ID.
+func (t *Torrent) ID() tl.Int256 {
+	return t.id
+}
+
+// Info returns a description of the bag.
+func (t *Torrent) Info() TorrentInfo {
+	return t.info
+}
+
+// Files returns files of the bag.
+func (t *Torrent) Files() []FileInfo {
+	return append([]FileInfo{}, t.files...)
+}
+
+// Server seeds bags to TON Storage peers.
+// It answers storage.getTorrentInfo and storage.getPiece queries, other queries are rejected.
+type Server struct {
+	rldp *rldp.RLDP
+
+	// mu protects torrents.
+	mu sync.RWMutex
+	// torrents are indexed by IDs of their overlays.
+	torrents map[tl.Int256]*Torrent
+}
+
+// NewServer attaches a server to the gateway.
+func NewServer(gateway *adnl.Gateway) *Server {
+	s := &Server{torrents: make(map[tl.Int256]*Torrent)}
+	s.rldp = rldp.New(gateway, s)
+	return s
+}
+
+// Add starts seeding the torrent.
+func (s *Server) Add(t *Torrent) error {
+	overlay, err := OverlayID(t.id)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.torrents[overlay] = t
+	return nil
+}
+
+// Close stops answering queries.
+func (s *Server) Close() {
+	s.rldp.Close()
+}
+
+// HandleQuery answers a query of a peer.
+func (s *Server) HandleQuery(ctx context.Context, peer *adnl.Peer, query []byte) ([]byte, error) {
+	if len(query) < overlayQuerySize+4 {
+		return nil, fmt.Errorf("invalid query")
+	}
+	var req adnl.OverlayQueryRequest
+	if err := adnl.UnmarshalBoxed(query[:overlayQuerySize], tagOverlayQuery, &req); err != nil {
+		return nil, err
+	}
+	s.mu.RLock()
+	t, ok := s.torrents[req.Overlay]
+	s.mu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown overlay")
+	}
+	query = query[overlayQuerySize:]
+	switch binary.LittleEndian.Uint32(query) {
+	case tagGetTorrentInfo:
+		return adnl.MarshalBoxed(tagTorrentInfo, adnl.StorageTorrentInfoC{Data: t.infoBoc})
+	case tagGetPiece:
+		var req adnl.StorageGetPieceRequest
+		if err := adnl.UnmarshalBoxed(query, tagGetPiece, &req); err != nil {
+			return nil, err
+		}
+		if uint64(req.PieceId) >= t.info.PiecesCount() {
+			return nil, fmt.Errorf("invalid piece: %v", req.PieceId)
+		}
+		data, err := t.data.piece(make([]byte, t.info.PieceSize), t.info.PieceSize, req.PieceId)
+		if err != nil {
+			return nil, err
+		}
+		proof, err := t.tree.proof(req.PieceId)
+		if err != nil {
+			return nil, err
+		}
+		return adnl.MarshalBoxed(tagPiece, adnl.StoragePieceC{Proof: proof, Data: data})
+	}
+	return nil, fmt.Errorf("unknown query")
+}
+
+// OverlayID returns an ID of an overlay network peers sharing the bag join.
+func OverlayID(bagID tl.Int256) (tl.Int256, error) {
+	return adnl.ShortID(adnl.PublicKeyOverlay(bagID[:]))
+}
+
+// bagReader reads bag data concatenated from the header and files.
+type bagReader struct {
+	parts []bagPart
+	size  uint64
+}
+
+type bagPart struct {
+	offset uint64
+	size   uint64
+	r      io.ReaderAt
+}
+
+func (b *bagReader) add(r io.ReaderAt, size uint64) {
+	b.parts = append(b.parts, bagPart{offset: b.size, size: size, r: r})
+	b.size += size
+}
+
+// piece reads a piece of bag data into buf.
+func (b *bagReader) piece(buf []byte, pieceSize uint32, piece uint32) ([]byte, error) {
+	start := uint64(piece) * uint64(pieceSize)
+	end := start + uint64(pieceSize)
+	if end > b.size {
+		end = b.size
+	}
+	buf = buf[:end-start]
+	for _, p := range b.parts {
+		if p.offset+p.size <= start || p.offset >= end {
+			continue
+		}
+		from, to := start, end
+		if from < p.offset {
+			from = p.offset
+		}
+		if to > p.offset+p.size {
+			to = p.offset + p.size
+		}
+		n, err := p.r.ReadAt(buf[from-start:to-start], int64(from-p.offset))
+		if n < int(to-from) {
+			if err == nil || err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			return nil, err
+		}
+	}
+	return buf, nil
+}
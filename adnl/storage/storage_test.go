@@ -0,0 +1,151 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/tonkeeper/tongo/adnl"
+)
+
+func randomBytes(t *testing.T, size int) []byte {
+	b := make([]byte, size)
+	if _, err := rand.Read(b); err != nil {
+		t.Fatalf("Read() failed: %v", err)
+	}
+	return b
+}
+
+func startServer(t *testing.T, torrents ...*Torrent) (ed25519.PublicKey, string) {
+	pub, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey() failed: %v", err)
+	}
+	gateway, err := adnl.Listen("127.0.0.1:0", key)
+	if err != nil {
+		t.Fatalf("Listen() failed: %v", err)
+	}
+	t.Cleanup(func() { gateway.Close() })
+	s := NewServer(gateway)
+	t.Cleanup(s.Close)
+	for _, torrent := range torrents {
+		if err := s.Add(torrent); err != nil {
+			t.Fatalf("Add() failed: %v", err)
+		}
+	}
+	return pub, gateway.Addr().String()
+}
+
+func newTestClient(t *testing.T, key ed25519.PublicKey, addr string) (*Client, *adnl.Peer) {
+	gateway, err := adnl.Listen("127.0.0.1:0", nil)
+	if err != nil {
+		t.Fatalf("Listen() failed: %v", err)
+	}
+	t.Cleanup(func() { gateway.Close() })
+	c := NewClient(gateway, nil)
+	c.queryTimeout = 2 * time.Second
+	peer, err := gateway.Connect(key, addr)
+	if err != nil {
+		t.Fatalf("Connect() failed: %v", err)
+	}
+	return c, peer
+}
+
+func TestClient_OpenBag(t *testing.T) {
+	files := map[string][]byte{
+		"big.bin":       randomBytes(t, 300_000),
+		"dir/small.txt": []byte("hello"),
+		"empty":         {},
+	}
+	var sources []Source
+	for _, name := range []string{"big.bin", "dir/small.txt", "empty"} {
+		sources = append(sources, Source{Name: name, Data: bytes.NewReader(files[name]), Size: int64(len(files[name]))})
+	}
+	torrent, err := CreateTorrent("bag", sources, 64*1024, "test bag")
+	if err != nil {
+		t.Fatalf("CreateTorrent() failed: %v", err)
+	}
+	key, addr := startServer(t, torrent)
+	c, peer := newTestClient(t, key, addr)
+
+	ctx := context.Background()
+	bag, err := c.OpenBag(ctx, torrent.ID(), peer)
+	if err != nil {
+		t.Fatalf("OpenBag() failed: %v", err)
+	}
+	if bag.DirName() != "bag" || string(bag.Info().Description) != "test bag" || len(bag.Files()) != 3 {
+		t.Fatalf("unexpected bag: %v %+v %+v", bag.DirName(), bag.Info(), bag.Files())
+	}
+	for name, data := range files {
+		f, err := bag.Open(name)
+		if err != nil {
+			t.Fatalf("Open() failed: %v", err)
+		}
+		if f.Size() != int64(len(data)) {
+			t.Fatalf("%v: want size %v, got %v", name, len(data), f.Size())
+		}
+		res, err := io.ReadAll(io.NewSectionReader(f, 0, f.Size()))
+		if err != nil {
+			t.Fatalf("ReadAll() failed: %v", err)
+		}
+		if !bytes.Equal(res, data) {
+			t.Fatalf("%v: data mismatch", name)
+		}
+	}
+
+	f, err := bag.Open("big.bin")
+	if err != nil {
+		t.Fatalf("Open() failed: %v", err)
+	}
+	// the range crosses a piece boundary and the end of the file
+	buf := make([]byte, 100)
+	n, err := f.ReadAt(buf, int64(len(files["big.bin"])-50))
+	if n != 50 || err != io.EOF || !bytes.Equal(buf[:n], files["big.bin"][len(files["big.bin"])-50:]) {
+		t.Fatalf("unexpected ReadAt() result: %v %v", n, err)
+	}
+	if _, err := bag.Open("missing"); err == nil {
+		t.Fatalf("missing file must not be found")
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, time.Second)
+	defer cancel()
+	if _, err := c.OpenBag(ctx, [32]byte{1}, peer); err == nil {
+		t.Fatalf("unknown bag must not be opened")
+	}
+}
+
+func TestCheckPieceProof(t *testing.T) {
+	pieces := [][]byte{[]byte("a"), []byte("b"), []byte("c")}
+	var hashes [][32]byte
+	for _, p := range pieces {
+		hashes = append(hashes, sha256.Sum256(p))
+	}
+	tree, err := newMerkleTree(hashes)
+	if err != nil {
+		t.Fatalf("newMerkleTree() failed: %v", err)
+	}
+	root, err := tree.root.Hash256()
+	if err != nil {
+		t.Fatalf("Hash256() failed: %v", err)
+	}
+	for i, p := range pieces {
+		proof, err := tree.proof(uint32(i))
+		if err != nil {
+			t.Fatalf("proof() failed: %v", err)
+		}
+		if err := checkPieceProof(proof, root, 3, uint32(i), p); err != nil {
+			t.Fatalf("checkPieceProof() failed: %v", err)
+		}
+		if err := checkPieceProof(proof, root, 3, uint32(i), []byte("x")); err == nil {
+			t.Fatalf("modified piece must be rejected")
+		}
+		if err := checkPieceProof(proof, root, 3, uint32(2-i), pieces[2-i]); i != 1 && err == nil {
+			t.Fatalf("proof of another piece must be rejected")
+		}
+	}
+}
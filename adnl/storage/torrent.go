@@ -0,0 +1,240 @@
+package storage
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"io"
+
+	"github.com/tonkeeper/tongo/boc"
+	"github.com/tonkeeper/tongo/tl"
+	"github.com/tonkeeper/tongo/tlb"
+)
+
+const (
+	headerMagic uint32 = 0x9128aab7
+	tagFecNone  uint32 = 0xc82a1964
+)
+
+// TorrentInfo describes a bag, a hash of its cell is the bag ID.
+//
+// torrent_info piece_size:uint32 file_size:uint64 root_hash:(## 256) header_size:uint64 header_hash:(## 256)
+// microchunk_hash:(Maybe (## 256)) description:Text = TorrentInfo;
+type TorrentInfo struct {
+	PieceSize uint32
+	// FileSize is a size of bag data including the header.
+	FileSize uint64
+	// RootHash is a hash of the root of a merkle tree of pieces.
+	RootHash       tlb.Bits256
+	HeaderSize     uint64
+	HeaderHash     tlb.Bits256
+	MicrochunkHash tlb.Maybe[tlb.Bits256]
+	Description    tlb.DNSText
+}
+
+// BagID returns an ID of the described bag.
+func (i TorrentInfo) BagID() (tl.Int256, error) {
+	cell := boc.NewCell()
+	if err := tlb.Marshal(cell, i); err != nil {
+		return tl.Int256{}, err
+	}
+	return cell.Hash256()
+}
+
+// PiecesCount returns a number of pieces bag data is split into.
+func (i TorrentInfo) PiecesCount() uint64 {
+	if i.PieceSize == 0 {
+		return 0
+	}
+	return (i.FileSize + uint64(i.PieceSize) - 1) / uint64(i.PieceSize)
+}
+
+// FileInfo describes a file of a bag.
+type FileInfo struct {
+	Name string
+	// Offset is an offset of the file's data in bag data.
+	Offset uint64
+	Size   uint64
+}
+
+// header describes files of a bag, it is stored at the beginning of bag data.
+type header struct {
+	dirName string
+	files   []FileInfo
+}
+
+// marshal encodes the header the same way as TON Storage does.
+// Offsets of files are set during encoding, so the header has to be encoded before bag data is built.
+func (h *header) marshal() []byte {
+	var names, nameIndex, dataIndex bytes.Buffer
+	var dataSize uint64
+	for i := range h.files {
+		names.WriteString(h.files[i].Name)
+		dataSize += h.files[i].Size
+		binary.Write(&nameIndex, binary.LittleEndian, uint64(names.Len()))
+		binary.Write(&dataIndex, binary.LittleEndian, dataSize)
+	}
+	var buf bytes.Buffer
+	binary.Write(&buf, binary.LittleEndian, headerMagic)
+	binary.Write(&buf, binary.LittleEndian, uint32(len(h.files)))
+	binary.Write(&buf, binary.LittleEndian, uint64(names.Len()))
+	binary.Write(&buf, binary.LittleEndian, dataSize)
+	binary.Write(&buf, binary.LittleEndian, tagFecNone)
+	binary.Write(&buf, binary.LittleEndian, uint32(len(h.dirName)))
+	buf.WriteString(h.dirName)
+	buf.Write(nameIndex.Bytes())
+	buf.Write(dataIndex.Bytes())
+	buf.Write(names.Bytes())
+	offset := uint64(buf.Len())
+	for i := range h.files {
+		h.files[i].Offset = offset
+		offset += h.files[i].Size
+	}
+	return buf.Bytes()
+}
+
+// parseHeader decodes a header of a bag of the given size.
+func parseHeader(data []byte, bagSize uint64) (header, error) {
+	r := bytes.NewReader(data)
+	var fixed struct {
+		Magic       uint32
+		FilesCount  uint32
+		NamesSize   uint64
+		DataSize    uint64
+		Fec         uint32
+		DirNameSize uint32
+	}
+	if err := binary.Read(r, binary.LittleEndian, &fixed); err != nil {
+		return header{}, err
+	}
+	if fixed.Magic != headerMagic {
+		return header{}, fmt.Errorf("invalid header magic: %x", fixed.Magic)
+	}
+	if fixed.Fec != tagFecNone {
+		return header{}, fmt.Errorf("unsupported header FEC: %x", fixed.Fec)
+	}
+	rest := uint64(r.Len())
+	if uint64(fixed.DirNameSize)+16*uint64(fixed.FilesCount)+fixed.NamesSize != rest {
+		return header{}, fmt.Errorf("invalid header size")
+	}
+	if fixed.DataSize+uint64(len(data)) != bagSize {
+		return header{}, fmt.Errorf("header doesn't match bag size")
+	}
+	h := header{files: make([]FileInfo, fixed.FilesCount)}
+	dirName := make([]byte, fixed.DirNameSize)
+	if _, err := io.ReadFull(r, dirName); err != nil {
+		return header{}, err
+	}
+	h.dirName = string(dirName)
+	nameIndex := make([]uint64, fixed.FilesCount)
+	dataIndex := make([]uint64, fixed.FilesCount)
+	if err := binary.Read(r, binary.LittleEndian, nameIndex); err != nil {
+		return header{}, err
+	}
+	if err := binary.Read(r, binary.LittleEndian, dataIndex); err != nil {
+		return header{}, err
+	}
+	names := make([]byte, fixed.NamesSize)
+	if _, err := io.ReadFull(r, names); err != nil {
+		return header{}, err
+	}
+	var nameStart, dataStart uint64
+	for i := range h.files {
+		if nameIndex[i] < nameStart || nameIndex[i] > fixed.NamesSize || dataIndex[i] < dataStart || dataIndex[i] > fixed.DataSize {
+			return header{}, fmt.Errorf("invalid file index")
+		}
+		h.files[i] = FileInfo{
+			Name:   string(names[nameStart:nameIndex[i]]),
+			Offset: uint64(len(data)) + dataStart,
+			Size:   dataIndex[i] - dataStart,
+		}
+		nameStart, dataStart = nameIndex[i], dataIndex[i]
+	}
+	return h, nil
+}
+
+// merkleTree is a tree of hashes of pieces, a leaf cell keeps a hash of a piece and an inner cell refers to two children.
+// A number of leaves is padded to a power of two with zero hashes.
+type merkleTree struct {
+	root  *boc.Cell
+	depth int
+}
+
+func newMerkleTree(hashes [][32]byte) (*merkleTree, error) {
+	depth := 0
+	for 1<<depth < len(hashes) {
+		depth++
+	}
+	level := make([]*boc.Cell, 1<<depth)
+	for i := range level {
+		level[i] = boc.NewCell()
+		if i < len(hashes) {
+			if err := level[i].WriteBytes(hashes[i][:]); err != nil {
+				return nil, err
+			}
+		} else if err := level[i].WriteUint(0, 256); err != nil {
+			return nil, err
+		}
+	}
+	for len(level) > 1 {
+		next := make([]*boc.Cell, len(level)/2)
+		for i := range next {
+			next[i] = boc.NewCell()
+			if err := next[i].AddRef(level[2*i]); err != nil {
+				return nil, err
+			}
+			if err := next[i].AddRef(level[2*i+1]); err != nil {
+				return nil, err
+			}
+		}
+		level = next
+	}
+	return &merkleTree{root: level[0], depth: depth}, nil
+}
+
+// proof returns a merkle proof of a hash of the given piece.
+func (t *merkleTree) proof(piece uint32) ([]byte, error) {
+	return tlb.CreateMerkleProof(t.root, func(root *boc.Cell, usage *boc.CellUsage) error {
+		c := root
+		for i := t.depth - 1; i >= 0; i-- {
+			c = c.Refs()[(piece>>i)&1]
+			usage.Visit(c)
+		}
+		return nil
+	})
+}
+
+// checkPieceProof verifies that a merkle proof of a tree with the given root hash contains the piece's hash.
+func checkPieceProof(proof []byte, rootHash tlb.Bits256, pieces uint64, piece uint32, data []byte) error {
+	cells, err := boc.DeserializeBoc(proof)
+	if err != nil {
+		return err
+	}
+	cell, err := boc.FindMerkleProof(cells, rootHash)
+	if err != nil {
+		return err
+	}
+	depth := 0
+	for uint64(1)<<depth < pieces {
+		depth++
+	}
+	c := cell.Refs()[0]
+	for i := depth - 1; i >= 0; i-- {
+		if c.IsExotic() || c.RefsSize() != 2 {
+			return fmt.Errorf("invalid merkle proof")
+		}
+		c = c.Refs()[(piece>>i)&1]
+	}
+	if c.IsExotic() || c.BitSize() != 256 || c.RefsSize() != 0 {
+		return fmt.Errorf("invalid merkle proof")
+	}
+	hash, err := c.ReadBytes(32)
+	if err != nil {
+		return err
+	}
+	if sum := sha256.Sum256(data); !bytes.Equal(hash, sum[:]) {
+		return fmt.Errorf("piece hash mismatch")
+	}
+	return nil
+}
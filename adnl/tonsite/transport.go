@@ -0,0 +1,369 @@
+package tonsite
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/tonkeeper/tongo/adnl"
+	"github.com/tonkeeper/tongo/adnl/rldp"
+	"github.com/tonkeeper/tongo/config"
+	"github.com/tonkeeper/tongo/tl"
+	"github.com/tonkeeper/tongo/tlb"
+)
+
+const (
+	tagRequest        uint32 = 0x61b191e1
+	tagGetNextPayload uint32 = 0x90745d0c
+	tagResponse       uint32 = 0xca48a74a
+	tagPayloadPart    uint32 = 0x295ad764
+
+	httpVersion = "HTTP/1.1"
+	// maxResponseSize limits a size of http.response without payload.
+	maxResponseSize = 1 << 20
+	// chunkSize is a size of payload parts requested from a site.
+	chunkSize = 128 << 10
+	// maxChunkSize limits a size of payload parts sent to a site.
+	maxChunkSize = 1 << 20
+)
+
+// ErrNoSite means that a domain has no dns_adnl_address record.
+var ErrNoSite = errors.New("domain has no site record")
+
+// Resolver resolves DNS records of a domain, it is implemented by contract/dns.DNS.
+type Resolver interface {
+	Resolve(ctx context.Context, domain string) ([]tlb.DNSRecord, error)
+}
+
+// AddressResolver resolves an ADNL address to UDP addresses of the node, it is implemented by dht.Client.
+type AddressResolver interface {
+	FindAddresses(ctx context.Context, id tl.Int256) (config.AddressList, ed25519.PublicKey, error)
+}
+
+// Transport is an http.RoundTripper fetching TON sites.
+// A host of a request is resolved to an ADNL address with a dns_adnl_address record,
+// and the request is sent to the site's node as http.request over RLDP.
+// Bodies of requests and responses are transferred with http.getNextPayloadPart queries.
+// When a query to a site fails, the next request to it is sent to the next UDP address of the site's node.
+//
+//	client := &http.Client{Transport: tonsite.NewTransport(gateway, dhtClient, dns.NewDNS(root, liteClient))}
+//	resp, err := client.Get("http://foundation.ton/")
+type Transport struct {
+	gateway   *adnl.Gateway
+	rldp      *rldp.RLDP
+	addresses AddressResolver
+	resolver  Resolver
+
+	// mu protects fields below.
+	mu sync.Mutex
+	// peers are indexed by ADNL addresses of sites.
+	peers map[tl.Int256]sitePeer
+	// next are indices of UDP addresses tried first when sites are connected again after failed queries.
+	next map[tl.Int256]int
+	// bodies are bodies of requests being sent indexed by request IDs.
+	bodies map[tl.Int256]*requestBody
+}
+
+var _ http.RoundTripper = (*Transport)(nil)
+
+type sitePeer struct {
+	peer *adnl.Peer
+	// addr is an index of the UDP address the peer is connected to.
+	addr int
+}
+
+type requestBody struct {
+	// mu protects fields below.
+	mu    sync.Mutex
+	body  io.ReadCloser
+	seqno uint32
+	done  bool
+}
+
+// NewTransport attaches a transport to the gateway.
+func NewTransport(gateway *adnl.Gateway, addresses AddressResolver, resolver Resolver) *Transport {
+	t := &Transport{
+		gateway:   gateway,
+		addresses: addresses,
+		resolver:  resolver,
+		peers:     make(map[tl.Int256]sitePeer),
+		next:      make(map[tl.Int256]int),
+		bodies:    make(map[tl.Int256]*requestBody),
+	}
+	t.rldp = rldp.New(gateway, t)
+	return t
+}
+
+// RoundTrip sends a request to a TON site.
+// Only http URLs with hosts in the .ton zone are accepted.
+// The request's context limits the whole exchange including reading the response body.
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	body := req.Body
+	if body == nil {
+		body = http.NoBody
+	}
+	defer body.Close()
+	if req.URL.Scheme != "http" {
+		return nil, fmt.Errorf("unsupported scheme: %q", req.URL.Scheme)
+	}
+	domain := strings.ToLower(strings.TrimSuffix(req.URL.Hostname(), "."))
+	if !strings.HasSuffix(domain, ".ton") {
+		return nil, fmt.Errorf("not a TON site: %q", req.URL.Hostname())
+	}
+	ctx := req.Context()
+	peer, err := t.peer(ctx, domain)
+	if err != nil {
+		return nil, err
+	}
+	var id tl.Int256
+	if _, err := io.ReadFull(rand.Reader, id[:]); err != nil {
+		return nil, err
+	}
+	request := adnl.HttpRequestRequest{
+		Id:          id,
+		Method:      req.Method,
+		Url:         req.URL.String(),
+		HttpVersion: httpVersion,
+		Headers:     []adnl.HttpHeaderC{{Name: "Host", Value: req.Host}},
+	}
+	if request.Headers[0].Value == "" {
+		request.Headers[0].Value = req.URL.Host
+	}
+	for name, values := range req.Header {
+		// the body framing is defined by the request itself like in net/http.
+		if name == "Content-Length" || name == "Transfer-Encoding" {
+			continue
+		}
+		for _, v := range values {
+			request.Headers = append(request.Headers, adnl.HttpHeaderC{Name: name, Value: v})
+		}
+	}
+	switch {
+	case req.ContentLength > 0:
+		request.Headers = append(request.Headers, adnl.HttpHeaderC{Name: "Content-Length", Value: strconv.FormatInt(req.ContentLength, 10)})
+	case body != http.NoBody:
+		// a body of an unknown length, a zero ContentLength with a body is unknown as well in net/http.
+		request.Headers = append(request.Headers, adnl.HttpHeaderC{Name: "Transfer-Encoding", Value: "chunked"})
+	}
+	t.mu.Lock()
+	t.bodies[id] = &requestBody{body: body}
+	t.mu.Unlock()
+	defer func() {
+		t.mu.Lock()
+		delete(t.bodies, id)
+		t.mu.Unlock()
+	}()
+
+	query, err := adnl.MarshalBoxed(tagRequest, request)
+	if err != nil {
+		return nil, err
+	}
+	answer, err := t.rldp.Query(ctx, peer, query, maxResponseSize)
+	if err != nil {
+		t.dropPeer(peer)
+		return nil, err
+	}
+	var res adnl.HttpResponseC
+	if err := adnl.UnmarshalBoxed(answer, tagResponse, &res); err != nil {
+		return nil, err
+	}
+	resp := &http.Response{
+		Status:        fmt.Sprintf("%d %s", res.StatusCode, res.Reason),
+		StatusCode:    int(res.StatusCode),
+		Proto:         res.HttpVersion,
+		Header:        make(http.Header),
+		Trailer:       make(http.Header),
+		ContentLength: -1,
+		Body:          http.NoBody,
+		Request:       req,
+	}
+	var ok bool
+	if resp.ProtoMajor, resp.ProtoMinor, ok = http.ParseHTTPVersion(res.HttpVersion); !ok {
+		resp.ProtoMajor, resp.ProtoMinor = 1, 1
+	}
+	for _, h := range res.Headers {
+		resp.Header.Add(h.Name, h.Value)
+	}
+	if n, err := strconv.ParseInt(resp.Header.Get("Content-Length"), 10, 64); err == nil && n >= 0 {
+		resp.ContentLength = n
+	}
+	if !res.NoPayload {
+		resp.Body = &responseBody{ctx: ctx, transport: t, peer: peer, id: id, trailer: resp.Trailer}
+	}
+	return resp, nil
+}
+
+// peer returns a peer serving a site with the given domain.
+func (t *Transport) peer(ctx context.Context, domain string) (*adnl.Peer, error) {
+	records, err := t.resolver.Resolve(ctx, domain)
+	if err != nil {
+		return nil, err
+	}
+	var (
+		id    tl.Int256
+		found bool
+	)
+	for _, r := range records {
+		if r.SumType == "DNSAdnlAddress" {
+			id, found = r.DNSAdnlAddress.Address, true
+			break
+		}
+	}
+	if !found {
+		return nil, fmt.Errorf("%w: %v", ErrNoSite, domain)
+	}
+	t.mu.Lock()
+	site, ok := t.peers[id]
+	next := t.next[id]
+	t.mu.Unlock()
+	if ok {
+		return site.peer, nil
+	}
+	addrs, key, err := t.addresses.FindAddresses(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if len(addrs.Addrs) == 0 {
+		return nil, fmt.Errorf("site has no addresses: %v", domain)
+	}
+	// addresses are tried starting from the one after the address of a failed peer
+	for i := range addrs.Addrs {
+		site.addr = (next + i) % len(addrs.Addrs)
+		if site.peer, err = t.gateway.Connect(key, addrs.Addrs[site.addr]); err == nil {
+			break
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.peers[id] = site
+	return site.peer, nil
+}
+
+// dropPeer forgets a peer after its query fails, so the site is connected again with its next address.
+func (t *Transport) dropPeer(peer *adnl.Peer) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	id := peer.ID()
+	if site, ok := t.peers[id]; ok && site.peer == peer {
+		delete(t.peers, id)
+		t.next[id] = site.addr + 1
+	}
+}
+
+// HandleQuery answers http.getNextPayloadPart queries of sites reading bodies of requests.
+func (t *Transport) HandleQuery(ctx context.Context, peer *adnl.Peer, query []byte) ([]byte, error) {
+	if len(query) < 4 || binary.LittleEndian.Uint32(query) != tagGetNextPayload {
+		return nil, fmt.Errorf("unknown query")
+	}
+	var req adnl.HttpGetNextPayloadPartRequest
+	if err := adnl.UnmarshalBoxed(query, tagGetNextPayload, &req); err != nil {
+		return nil, err
+	}
+	t.mu.Lock()
+	body, ok := t.bodies[req.Id]
+	t.mu.Unlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown request")
+	}
+	body.mu.Lock()
+	defer body.mu.Unlock()
+	if body.seqno != req.Seqno {
+		return nil, fmt.Errorf("unexpected seqno: %v", req.Seqno)
+	}
+	size := req.MaxChunkSize
+	if size > maxChunkSize {
+		size = maxChunkSize
+	}
+	var part adnl.HttpPayloadPartC
+	if !body.done {
+		part.Data = make([]byte, size)
+		n, err := io.ReadFull(body.body, part.Data)
+		part.Data = part.Data[:n]
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			body.done = true
+		} else if err != nil {
+			return nil, err
+		}
+	}
+	part.Last = body.done
+	body.seqno++
+	return adnl.MarshalBoxed(tagPayloadPart, part)
+}
+
+// responseBody downloads a body of a response with http.getNextPayloadPart queries.
+type responseBody struct {
+	ctx       context.Context
+	transport *Transport
+	peer      *adnl.Peer
+	id        tl.Int256
+	trailer   http.Header
+
+	seqno uint32
+	buf   []byte
+	last  bool
+	err   error
+}
+
+func (b *responseBody) Read(p []byte) (int, error) {
+	for len(b.buf) == 0 {
+		if b.err != nil {
+			return 0, b.err
+		}
+		if b.last {
+			return 0, io.EOF
+		}
+		b.err = b.next()
+	}
+	n := copy(p, b.buf)
+	b.buf = b.buf[n:]
+	return n, nil
+}
+
+func (b *responseBody) next() error {
+	query, err := adnl.MarshalBoxed(tagGetNextPayload, adnl.HttpGetNextPayloadPartRequest{
+		Id:           b.id,
+		Seqno:        b.seqno,
+		MaxChunkSize: chunkSize,
+	})
+	if err != nil {
+		return err
+	}
+	// a trailer is limited the same way as headers of a response
+	answer, err := b.transport.rldp.Query(b.ctx, b.peer, query, chunkSize+maxResponseSize)
+	if err != nil {
+		b.transport.dropPeer(b.peer)
+		return err
+	}
+	var part adnl.HttpPayloadPartC
+	if err := adnl.UnmarshalBoxed(answer, tagPayloadPart, &part); err != nil {
+		return err
+	}
+	if len(part.Data) > chunkSize {
+		return fmt.Errorf("payload part is too big: %v", len(part.Data))
+	}
+	b.seqno++
+	b.buf = part.Data
+	b.last = part.Last
+	for _, h := range part.Trailer {
+		b.trailer.Add(h.Name, h.Value)
+	}
+	return nil
+}
+
+func (b *responseBody) Close() error {
+	if b.err == nil {
+		b.err = errors.New("body is closed")
+	}
+	return nil
+}
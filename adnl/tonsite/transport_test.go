@@ -0,0 +1,400 @@
+package tonsite
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/tonkeeper/tongo/adnl"
+	"github.com/tonkeeper/tongo/adnl/rldp"
+	"github.com/tonkeeper/tongo/config"
+	"github.com/tonkeeper/tongo/tl"
+	"github.com/tonkeeper/tongo/tlb"
+)
+
+// testSite serves an http.Handler as a TON site.
+type testSite struct {
+	handler http.Handler
+	rldp    *rldp.RLDP
+
+	mu        sync.Mutex
+	responses map[tl.Int256][]byte
+}
+
+func (s *testSite) HandleQuery(ctx context.Context, peer *adnl.Peer, query []byte) ([]byte, error) {
+	switch binary.LittleEndian.Uint32(query) {
+	case tagRequest:
+		var req adnl.HttpRequestRequest
+		if err := adnl.UnmarshalBoxed(query, tagRequest, &req); err != nil {
+			return nil, err
+		}
+		var body bytes.Buffer
+		if req.Method == http.MethodPost {
+			for seqno := uint32(0); ; seqno++ {
+				q, err := adnl.MarshalBoxed(tagGetNextPayload, adnl.HttpGetNextPayloadPartRequest{Id: req.Id, Seqno: seqno, MaxChunkSize: 1000})
+				if err != nil {
+					return nil, err
+				}
+				answer, err := s.rldp.Query(ctx, peer, q, 2000)
+				if err != nil {
+					return nil, err
+				}
+				var part adnl.HttpPayloadPartC
+				if err := adnl.UnmarshalBoxed(answer, tagPayloadPart, &part); err != nil {
+					return nil, err
+				}
+				body.Write(part.Data)
+				if part.Last {
+					break
+				}
+			}
+		}
+		r := httptest.NewRequest(req.Method, req.Url, &body)
+		for _, h := range req.Headers {
+			r.Header.Add(h.Name, h.Value)
+		}
+		r.Host = r.Header.Get("Host")
+		w := httptest.NewRecorder()
+		s.handler.ServeHTTP(w, r)
+		res := adnl.HttpResponseC{
+			HttpVersion: "HTTP/1.1",
+			StatusCode:  uint32(w.Code),
+			Reason:      http.StatusText(w.Code),
+			NoPayload:   w.Body.Len() == 0,
+		}
+		for name, values := range w.Header() {
+			for _, v := range values {
+				res.Headers = append(res.Headers, adnl.HttpHeaderC{Name: name, Value: v})
+			}
+		}
+		s.mu.Lock()
+		s.responses[req.Id] = w.Body.Bytes()
+		s.mu.Unlock()
+		return adnl.MarshalBoxed(tagResponse, res)
+	case tagGetNextPayload:
+		var req adnl.HttpGetNextPayloadPartRequest
+		if err := adnl.UnmarshalBoxed(query, tagGetNextPayload, &req); err != nil {
+			return nil, err
+		}
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		body := s.responses[req.Id]
+		var part adnl.HttpPayloadPartC
+		n := int(req.MaxChunkSize)
+		if n >= len(body) {
+			n = len(body)
+			part.Last = true
+			part.Trailer = []adnl.HttpHeaderC{{Name: "X-Trailer", Value: "done"}}
+		}
+		part.Data = body[:n]
+		s.responses[req.Id] = body[n:]
+		return adnl.MarshalBoxed(tagPayloadPart, part)
+	}
+	return nil, fmt.Errorf("unknown query")
+}
+
+type testResolver map[string]tl.Int256
+
+func (r testResolver) Resolve(ctx context.Context, domain string) ([]tlb.DNSRecord, error) {
+	var wallet, site tlb.DNSRecord
+	wallet.SumType = "DNSSmcAddress"
+	if domain == "wallet.ton" {
+		return []tlb.DNSRecord{wallet}, nil
+	}
+	id, ok := r[domain]
+	if !ok {
+		return nil, errors.New("not resolved")
+	}
+	site.SumType = "DNSAdnlAddress"
+	site.DNSAdnlAddress.Address = id
+	return []tlb.DNSRecord{wallet, site}, nil
+}
+
+type testAddresses map[tl.Int256]struct {
+	key   ed25519.PublicKey
+	addrs []string
+}
+
+func (a testAddresses) FindAddresses(ctx context.Context, id tl.Int256) (config.AddressList, ed25519.PublicKey, error) {
+	node, ok := a[id]
+	if !ok {
+		return config.AddressList{}, nil, errors.New("not found")
+	}
+	return config.AddressList{Addrs: node.addrs}, node.key, nil
+}
+
+// startSite serves the handler as a TON site and returns a gateway of the site's node.
+func startSite(t *testing.T, handler http.Handler) *adnl.Gateway {
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey() failed: %v", err)
+	}
+	gateway, err := adnl.Listen("127.0.0.1:0", key)
+	if err != nil {
+		t.Fatalf("Listen() failed: %v", err)
+	}
+	t.Cleanup(func() { gateway.Close() })
+	site := &testSite{handler: handler, responses: map[tl.Int256][]byte{}}
+	site.rldp = rldp.New(gateway, site)
+	t.Cleanup(site.rldp.Close)
+	return gateway
+}
+
+// lossyRelay forwards datagrams between a client and the target dropping every third datagram of the target
+// big enough to carry a FEC symbol, so transfers are restored from the following symbols.
+func lossyRelay(t *testing.T, target string) string {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("ListenPacket() failed: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	targetAddr, err := net.ResolveUDPAddr("udp", target)
+	if err != nil {
+		t.Fatalf("ResolveUDPAddr() failed: %v", err)
+	}
+	go func() {
+		buf := make([]byte, 64*1024)
+		var client net.Addr
+		var symbols int
+		for {
+			n, addr, err := conn.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			if addr.String() != targetAddr.String() {
+				client = addr
+				conn.WriteTo(buf[:n], targetAddr)
+				continue
+			}
+			if n > 768 {
+				symbols++
+				if symbols%3 == 0 {
+					continue
+				}
+			}
+			if client != nil {
+				conn.WriteTo(buf[:n], client)
+			}
+		}
+	}()
+	return conn.LocalAddr().String()
+}
+
+func TestTransport_RoundTrip(t *testing.T) {
+	big := make([]byte, 300_000)
+	if _, err := rand.Read(big); err != nil {
+		t.Fatalf("Read() failed: %v", err)
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/hello", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		fmt.Fprintf(w, "hello from %v, %v", r.Host, r.URL.Query().Get("name"))
+	})
+	mux.HandleFunc("/big", func(w http.ResponseWriter, r *http.Request) {
+		w.Write(big)
+	})
+	mux.HandleFunc("/echo", func(w http.ResponseWriter, r *http.Request) {
+		io.Copy(w, r.Body)
+	})
+	mux.HandleFunc("/empty", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+	mux.HandleFunc("/framing", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "%q %q", r.Header.Get("Content-Length"), r.Header.Get("Transfer-Encoding"))
+	})
+
+	siteGateway := startSite(t, mux)
+	gateway, err := adnl.Listen("127.0.0.1:0", nil)
+	if err != nil {
+		t.Fatalf("Listen() failed: %v", err)
+	}
+	defer gateway.Close()
+	addresses := testAddresses{siteGateway.ID(): {key: siteGateway.PublicKey(), addrs: []string{siteGateway.Addr().String()}}}
+	client := &http.Client{Transport: NewTransport(gateway, addresses, testResolver{"site.ton": siteGateway.ID()})}
+
+	resp, err := client.Get("http://site.ton/hello?name=test")
+	if err != nil {
+		t.Fatalf("Get() failed: %v", err)
+	}
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		t.Fatalf("ReadAll() failed: %v", err)
+	}
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != "text/plain" || string(body) != "hello from site.ton, test" {
+		t.Fatalf("unexpected response: %v %v %q", resp.Status, resp.Header, body)
+	}
+	if resp.Trailer.Get("X-Trailer") != "done" {
+		t.Fatalf("trailer is not set: %v", resp.Trailer)
+	}
+
+	resp, err = client.Get("http://site.ton/big")
+	if err != nil {
+		t.Fatalf("Get() failed: %v", err)
+	}
+	body, err = io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil || !bytes.Equal(body, big) {
+		t.Fatalf("big body mismatch: %v", err)
+	}
+
+	data := strings.Repeat("payload", 1000)
+	resp, err = client.Post("http://site.ton/echo", "text/plain", strings.NewReader(data))
+	if err != nil {
+		t.Fatalf("Post() failed: %v", err)
+	}
+	body, err = io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil || string(body) != data {
+		t.Fatalf("echoed body mismatch: %v", err)
+	}
+
+	for _, tt := range []struct {
+		body          io.Reader
+		contentLength int64
+		want          string
+	}{
+		{body: strings.NewReader(data), contentLength: int64(len(data)), want: fmt.Sprintf("%q \"\"", strconv.Itoa(len(data)))},
+		{body: io.NopCloser(strings.NewReader(data)), contentLength: -1, want: `"" "chunked"`},
+		{body: http.NoBody, want: `"" ""`},
+	} {
+		req, err := http.NewRequest(http.MethodPost, "http://site.ton/framing", tt.body)
+		if err != nil {
+			t.Fatalf("NewRequest() failed: %v", err)
+		}
+		req.ContentLength = tt.contentLength
+		resp, err = client.Do(req)
+		if err != nil {
+			t.Fatalf("Do() failed: %v", err)
+		}
+		body, err = io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil || string(body) != tt.want {
+			t.Fatalf("want framing headers: %v, got: %s, %v", tt.want, body, err)
+		}
+	}
+
+	resp, err = client.Get("http://site.ton/empty")
+	if err != nil {
+		t.Fatalf("Get() failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNoContent || resp.Body != http.NoBody {
+		t.Fatalf("unexpected response: %v", resp.Status)
+	}
+
+	if _, err := client.Get("http://unknown.ton/"); err == nil {
+		t.Fatalf("unknown domain must not be resolved")
+	}
+	if _, err := client.Get("http://wallet.ton/"); !errors.Is(err, ErrNoSite) {
+		t.Fatalf("want ErrNoSite, got %v", err)
+	}
+	// the site is resolved neither for another scheme nor outside of the .ton zone.
+	for _, u := range []string{"https://site.ton/hello", "ftp://site.ton/hello", "http://site.com/hello", "http://site.ton.com/hello"} {
+		if _, err := client.Get(u); err == nil {
+			t.Fatalf("%v must be rejected", u)
+		}
+	}
+	resp, err = client.Get("http://SITE.TON./hello")
+	if err != nil {
+		t.Fatalf("Get() failed: %v", err)
+	}
+	resp.Body.Close()
+}
+
+func TestTransport_raptorQResponse(t *testing.T) {
+	// the response doesn't fit into a single symbol, so it is restored from repair symbols of fec.raptorQ
+	// replacing the source ones dropped by the relay
+	header := strings.Repeat("raptorq", 1000)
+	body := make([]byte, 50_000)
+	if _, err := rand.Read(body); err != nil {
+		t.Fatalf("Read() failed: %v", err)
+	}
+	siteGateway := startSite(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Big", header)
+		w.Write(body)
+	}))
+	gateway, err := adnl.Listen("127.0.0.1:0", nil)
+	if err != nil {
+		t.Fatalf("Listen() failed: %v", err)
+	}
+	defer gateway.Close()
+	relay := lossyRelay(t, siteGateway.Addr().String())
+	addresses := testAddresses{siteGateway.ID(): {key: siteGateway.PublicKey(), addrs: []string{relay}}}
+	client := &http.Client{Transport: NewTransport(gateway, addresses, testResolver{"site.ton": siteGateway.ID()})}
+
+	resp, err := client.Get("http://site.ton/")
+	if err != nil {
+		t.Fatalf("Get() failed: %v", err)
+	}
+	got, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		t.Fatalf("ReadAll() failed: %v", err)
+	}
+	if resp.Header.Get("X-Big") != header || !bytes.Equal(got, body) {
+		t.Fatalf("response mismatch")
+	}
+}
+
+func TestTransport_addressFallback(t *testing.T) {
+	siteGateway := startSite(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "hello")
+	}))
+	gateway, err := adnl.Listen("127.0.0.1:0", nil)
+	if err != nil {
+		t.Fatalf("Listen() failed: %v", err)
+	}
+	defer gateway.Close()
+	dead, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("ListenPacket() failed: %v", err)
+	}
+	dead.Close()
+	addresses := testAddresses{siteGateway.ID(): {
+		key:   siteGateway.PublicKey(),
+		addrs: []string{"invalid address", dead.LocalAddr().String(), siteGateway.Addr().String()},
+	}}
+	transport := NewTransport(gateway, addresses, testResolver{"site.ton": siteGateway.ID()})
+	client := &http.Client{Transport: transport}
+
+	// the invalid address is skipped and the query to the dead one fails
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "http://site.ton/", nil)
+	if err != nil {
+		t.Fatalf("NewRequestWithContext() failed: %v", err)
+	}
+	if _, err := client.Do(req); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("want DeadlineExceeded, got %v", err)
+	}
+	transport.mu.Lock()
+	_, ok := transport.peers[siteGateway.ID()]
+	transport.mu.Unlock()
+	if ok {
+		t.Fatalf("failed peer must be dropped")
+	}
+
+	resp, err := client.Get("http://site.ton/")
+	if err != nil {
+		t.Fatalf("Get() failed: %v", err)
+	}
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil || string(body) != "hello" {
+		t.Fatalf("unexpected body: %q, %v", body, err)
+	}
+}
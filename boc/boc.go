@@ -272,6 +272,17 @@ func SerializeBoc(cell *Cell, idx bool, hasCrc32 bool, cacheBits bool, flags uin
 	return bag.serializeBoc([]*Cell{cell}, idx, hasCrc32, cacheBits, flags)
 }
 
+// SerializeBocMulti works the same way as SerializeBoc but puts several root cells into one bag of cells.
+// Subtrees shared by the roots are stored only once.
+// The roots are stored in the given order, so DeserializeBoc returns them in the same order.
+func SerializeBocMulti(roots []*Cell, idx bool, hasCrc32 bool, cacheBits bool, flags uint) ([]byte, error) {
+	if len(roots) == 0 {
+		return nil, errors.New("no root cells to serialize")
+	}
+	bag := newBagOfCells()
+	return bag.serializeBoc(roots, idx, hasCrc32, cacheBits, flags)
+}
+
 // bagOfCells serializes cells to a boc.
 //
 // the serialization algorithms is a golang version of
@@ -390,3 +390,66 @@ func Test_bagOfCells_serializeBoc(t *testing.T) {
 		})
 	}
 }
+
+func TestSerializeBocMulti(t *testing.T) {
+	// the same roots as in TestDecodeTreeWithDuplicates, serialized by the TON node:
+	//  for (int i = 0;i<50;i++) {
+	//    roots.emplace_back(vm::CellBuilder()
+	//                           .store_long(i)
+	//                           .store_ref(vm::CellBuilder().store_long(25).finalize())
+	//                           .store_ref(vm::CellBuilder().store_long(26).finalize())
+	//                           .finalize());
+	//  }
+	want := "b5ee9c720102343200026c31302f2e2d2c2b2a292827262524232221201f1e1d1c1b1a191817161514131211100f0e0d0c0b0a090807060504030201000210000000000000003132330210000000000000003032330210000000000000002f32330210000000000000002e32330210000000000000002d32330210000000000000002c32330210000000000000002b32330210000000000000002a32330210000000000000002932330210000000000000002832330210000000000000002732330210000000000000002632330210000000000000002532330210000000000000002432330210000000000000002332330210000000000000002232330210000000000000002132330210000000000000002032330210000000000000001f32330210000000000000001e32330210000000000000001d32330210000000000000001c32330210000000000000001b32330210000000000000001a32330210000000000000001932330210000000000000001832330210000000000000001732330210000000000000001632330210000000000000001532330210000000000000001432330210000000000000001332330210000000000000001232330210000000000000001132330210000000000000001032330210000000000000000f32330210000000000000000e32330210000000000000000d32330210000000000000000c32330210000000000000000b32330210000000000000000a3233021000000000000000093233021000000000000000083233021000000000000000073233021000000000000000063233021000000000000000053233021000000000000000043233021000000000000000033233021000000000000000023233021000000000000000013233021000000000000000003233001000000000000000190010000000000000001a"
+	var roots []*Cell
+	for i := 0; i < 50; i++ {
+		root := NewCell()
+		if err := root.WriteUint(uint64(i), 64); err != nil {
+			t.Fatalf("WriteUint() failed: %v", err)
+		}
+		for _, v := range []uint64{25, 26} {
+			ref := NewCell()
+			if err := ref.WriteUint(v, 64); err != nil {
+				t.Fatalf("WriteUint() failed: %v", err)
+			}
+			if err := root.AddRef(ref); err != nil {
+				t.Fatalf("AddRef() failed: %v", err)
+			}
+		}
+		roots = append(roots, root)
+	}
+	bs, err := SerializeBocMulti(roots, false, false, false, 0)
+	if err != nil {
+		t.Fatalf("SerializeBocMulti() failed: %v", err)
+	}
+	if hex.EncodeToString(bs) != want {
+		t.Fatalf("SerializeBocMulti() differs from the TON node serialization")
+	}
+
+	bs, err = SerializeBocMulti(roots, true, true, true, 0)
+	if err != nil {
+		t.Fatalf("SerializeBocMulti() failed: %v", err)
+	}
+	cells, err := DeserializeBoc(bs)
+	if err != nil {
+		t.Fatalf("DeserializeBoc() failed: %v", err)
+	}
+	if len(cells) != len(roots) {
+		t.Fatalf("want %v roots, got %v", len(roots), len(cells))
+	}
+	for i, cell := range cells {
+		want, _ := roots[i].Hash256()
+		got, _ := cell.Hash256()
+		if want != got {
+			t.Fatalf("root %v hash mismatch", i)
+		}
+	}
+	// shared subtrees are stored once
+	if cells[0].Refs()[0] != cells[49].Refs()[0] {
+		t.Fatalf("shared subtree must be deduplicated")
+	}
+
+	if _, err := SerializeBocMulti(nil, false, false, false, 0); err == nil {
+		t.Fatalf("want error for empty roots")
+	}
+}
@@ -32,8 +32,11 @@ type Cell struct {
 	bits      BitString
 	refs      [4]*Cell
 	refCursor int
-	cellType  CellType
-	mask      levelMask
+	// readRefs is a bitmap of refs returned by NextRef or RefAt.
+	// Unlike refCursor, it isn't reset by ResetCounters, so CellUsage can find all refs read from this cell.
+	readRefs uint8
+	cellType CellType
+	mask     levelMask
 	// TODO: add capacity checking
 }
 
@@ -116,6 +119,19 @@ func (c *Cell) Hash256() ([32]byte, error) {
 	return h, err
 }
 
+// Hash256WithLevel returns a hash of this cell for the given level.
+// A level 0 hash of a cell containing pruned branches is a hash of the original cell,
+// so it is used to check merkle proofs.
+func (c *Cell) Hash256WithLevel(level int) ([32]byte, error) {
+	imc, err := newImmutableCell(c, map[*Cell]*immutableCell{})
+	if err != nil {
+		return [32]byte{}, err
+	}
+	var h [32]byte
+	copy(h[:], imc.Hash(level))
+	return h, nil
+}
+
 func (c *Cell) HashString() (string, error) {
 	h, err := c.hash(map[*Cell]*immutableCell{})
 	if err != nil {
@@ -194,6 +210,7 @@ func (c *Cell) NextRef() (*Cell, error) {
 	}
 	ref := c.refs[c.refCursor]
 	if ref != nil {
+		c.readRefs |= 1 << c.refCursor
 		c.refCursor++
 		ref.ResetCounters()
 		return ref, nil
@@ -201,6 +218,18 @@ func (c *Cell) NextRef() (*Cell, error) {
 	return nil, ErrNotEnoughRefs
 }
 
+// RefAt returns a ref with the given index and resets its counters the same way as NextRef does.
+// Unlike NextRef, it doesn't move the ref cursor of this cell.
+func (c *Cell) RefAt(index int) (*Cell, error) {
+	if index < 0 || index > 3 || c.refs[index] == nil {
+		return nil, ErrNotEnoughRefs
+	}
+	ref := c.refs[index]
+	c.readRefs |= 1 << index
+	ref.ResetCounters()
+	return ref, nil
+}
+
 func (c *Cell) toStringImpl(ident string, iterationsLimit *int) string {
 	var s string
 	if c.IsExotic() {
@@ -162,11 +162,8 @@ func (ic *immutableCell) Depth(level int) int {
 
 // pruneCells return the current subtree (which this cell represents) with pruned cells.
 // if this cell is pruned, "pruneCells" returns a new pruned branch cell instead of this cell.
-// As of now, this function doesn't work with MerkleProofCell and MerkleUpdateCell.
+// As of now, this function doesn't work with MerkleProofCell and MerkleUpdateCell unless they are pruned.
 func (ic *immutableCell) pruneCells(pruned map[*immutableCell]struct{}) (*Cell, error) {
-	if ic.cellType == MerkleProofCell || ic.cellType == MerkleUpdateCell {
-		return nil, fmt.Errorf("unsupported cell type: %v", ic.cellType)
-	}
 	if _, ok := pruned[ic]; ok {
 		// we are pruned
 		// let's replace this cell with a pruned branch cell
@@ -188,6 +185,9 @@ func (ic *immutableCell) pruneCells(pruned map[*immutableCell]struct{}) (*Cell,
 		prunedCell.ResetCounters()
 		return prunedCell, nil
 	}
+	if ic.cellType == MerkleProofCell || ic.cellType == MerkleUpdateCell {
+		return nil, fmt.Errorf("unsupported cell type: %v", ic.cellType)
+	}
 	// all good,
 	// going down the tree
 	bits := BitString{
@@ -1,5 +1,9 @@
 package boc
 
+import "errors"
+
+var ErrMerkleProofNotFound = errors.New("merkle proof not found")
+
 type MerkleProver struct {
 	root *immutableCell
 }
@@ -44,6 +48,24 @@ func (p *MerkleProver) CreateProof(cursor *Cursor) ([]byte, error) {
 	return SerializeBoc(mp, false, false, false, 0)
 }
 
+// FindMerkleProof returns a merkle proof cell among the given roots which proves a cell tree with the given hash.
+// Lite servers often put several proofs into one bag of cells, so the proof can't be chosen by its position.
+func FindMerkleProof(roots []*Cell, hash [32]byte) (*Cell, error) {
+	for _, root := range roots {
+		if root.CellType() != MerkleProofCell || root.RefsSize() != 1 {
+			continue
+		}
+		virtualHash, err := root.Refs()[0].Hash256WithLevel(0)
+		if err != nil {
+			return nil, err
+		}
+		if virtualHash == hash {
+			return root, nil
+		}
+	}
+	return nil, ErrMerkleProofNotFound
+}
+
 func (c *Cursor) Prune() {
 	c.pruned[c.cell] = struct{}{}
 }
@@ -51,3 +73,126 @@ func (c *Cursor) Prune() {
 func (c *Cursor) Ref(ref int) *Cursor {
 	return &Cursor{cell: c.cell.refs[ref], pruned: c.pruned}
 }
+
+// CellUsage keeps track of used cells of a cell tree.
+// It is used to create a merkle proof containing only the cells required to read the accessed data,
+// so the proof can be decoded the same way as the original tree.
+// The tree itself is not modified, the used cells are kept in the CellUsage.
+type CellUsage struct {
+	root    *Cell
+	visited map[*Cell]struct{}
+	kept    []*Cell
+}
+
+// NewCellUsage returns a CellUsage of the given tree with the root marked as used.
+// Refs read from the tree before are forgotten.
+func NewCellUsage(root *Cell) *CellUsage {
+	forgetReadRefs(root, make(map[*Cell]struct{}))
+	return &CellUsage{
+		root:    root,
+		visited: map[*Cell]struct{}{root: {}},
+	}
+}
+
+func forgetReadRefs(c *Cell, seen map[*Cell]struct{}) {
+	if _, ok := seen[c]; ok {
+		return
+	}
+	seen[c] = struct{}{}
+	c.readRefs = 0
+	for _, ref := range c.Refs() {
+		forgetReadRefs(ref, seen)
+	}
+}
+
+// Visit marks the given cell as used and resets its counters the same way as Cell.NextRef does.
+// It is useful to skip refs which are not required to read the data.
+func (u *CellUsage) Visit(c *Cell) {
+	c.ResetCounters()
+	u.visited[c] = struct{}{}
+}
+
+// Keep marks the given cell with all its descendants as used.
+func (u *CellUsage) Keep(c *Cell) {
+	u.kept = append(u.kept, c)
+}
+
+// Collect marks as used all cells read with Cell.NextRef or Cell.RefAt from the used cells.
+// A cell remembers all refs read from it even if its counters were reset,
+// so Collect can be called once after the tree is read.
+func (u *CellUsage) Collect() {
+	queue := make([]*Cell, 0, len(u.visited))
+	for c := range u.visited {
+		queue = append(queue, c)
+	}
+	for len(queue) > 0 {
+		c := queue[len(queue)-1]
+		queue = queue[:len(queue)-1]
+		for i, ref := range c.refs {
+			if ref == nil || c.readRefs&(1<<i) == 0 {
+				continue
+			}
+			if _, ok := u.visited[ref]; !ok {
+				u.visited[ref] = struct{}{}
+				queue = append(queue, ref)
+			}
+		}
+	}
+}
+
+// CreateProof returns a merkle proof of the tree containing all used cells.
+// Refs of used cells which are not used themselves are replaced with pruned branch cells.
+// Cells read after the last Collect are not included.
+func (u *CellUsage) CreateProof() ([]byte, error) {
+	cache := make(map[*Cell]*immutableCell)
+	immRoot, err := newImmutableCell(u.root, cache)
+	if err != nil {
+		return nil, err
+	}
+	required := make(map[*Cell]struct{})
+	for _, c := range u.kept {
+		keepSubtree(c, required)
+	}
+	computed := make(map[*Cell]bool)
+	var isRequired func(c *Cell) bool
+	isRequired = func(c *Cell) bool {
+		if res, ok := computed[c]; ok {
+			return res
+		}
+		_, kept := required[c]
+		_, visited := u.visited[c]
+		res := kept || visited
+		for _, ref := range c.Refs() {
+			// all refs have to be checked to find required cells deeper in the tree.
+			if isRequired(ref) {
+				res = true
+			}
+		}
+		computed[c] = res
+		return res
+	}
+	isRequired(u.root)
+	pruned := make(map[*immutableCell]struct{})
+	for c, res := range computed {
+		if !res {
+			continue
+		}
+		for _, ref := range c.Refs() {
+			if !computed[ref] {
+				pruned[cache[ref]] = struct{}{}
+			}
+		}
+	}
+	prover := &MerkleProver{root: immRoot}
+	return prover.CreateProof(&Cursor{cell: immRoot, pruned: pruned})
+}
+
+func keepSubtree(c *Cell, required map[*Cell]struct{}) {
+	if _, ok := required[c]; ok {
+		return
+	}
+	required[c] = struct{}{}
+	for _, ref := range c.Refs() {
+		keepSubtree(ref, required)
+	}
+}
@@ -0,0 +1,103 @@
+package boc
+
+import "testing"
+
+func TestCellUsage_CreateProof(t *testing.T) {
+	root := newTestTree(t, 6, 0, 0)
+	hash, err := root.Hash256()
+	if err != nil {
+		t.Fatalf("Hash256() failed: %v", err)
+	}
+	usage := NewCellUsage(root)
+	// read the leftmost path down to a leaf
+	c := root
+	for c.RefsSize() > 0 {
+		if c, err = c.NextRef(); err != nil {
+			t.Fatalf("NextRef() failed: %v", err)
+		}
+	}
+	usage.Collect()
+	// keep the whole rightmost subtree of depth 2
+	kept := root.Refs()[1].Refs()[1].Refs()[1].Refs()[1]
+	usage.Keep(kept)
+	// cells read after Collect are not tracked
+	root.ResetCounters()
+	root.NextRef()
+	root.NextRef()
+
+	data, err := usage.CreateProof()
+	if err != nil {
+		t.Fatalf("CreateProof() failed: %v", err)
+	}
+	proof, err := DeserializeSingleRootBoc(data)
+	if err != nil {
+		t.Fatalf("DeserializeSingleRootBoc() failed: %v", err)
+	}
+	if proof.CellType() != MerkleProofCell {
+		t.Fatalf("want merkle proof cell, got: %v", proof.CellType())
+	}
+	virtualRoot := proof.Refs()[0]
+	virtualHash, err := virtualRoot.Hash256WithLevel(0)
+	if err != nil {
+		t.Fatalf("Hash256WithLevel() failed: %v", err)
+	}
+	if virtualHash != hash {
+		t.Fatalf("want hash: %x, got: %x", hash, virtualHash)
+	}
+	// 7 cells of the path, 3 more cells on the way to the kept subtree, 7 kept cells and 8 pruned branches.
+	if n := countCells(virtualRoot); n != 25 {
+		t.Fatalf("want 25 cells, got: %v", n)
+	}
+	c = virtualRoot
+	for c.RefsSize() > 0 {
+		c = c.Refs()[0]
+	}
+	if c.CellType() != OrdinaryCell || c.BitSize() != 32 {
+		t.Fatalf("leftmost leaf must be kept")
+	}
+	if c := virtualRoot.Refs()[0].Refs()[1]; c.CellType() != PrunedBranchCell {
+		t.Fatalf("unused cell must be pruned")
+	}
+}
+
+func TestCellUsage_Collect_refAt(t *testing.T) {
+	root := newTestTree(t, 2, 0, 0)
+	// a previous read of the whole tree must not end up in the proof.
+	for _, ref := range []int{0, 1} {
+		if _, err := root.RefAt(ref); err != nil {
+			t.Fatalf("RefAt() failed: %v", err)
+		}
+	}
+	usage := NewCellUsage(root)
+	second, err := root.RefAt(1)
+	if err != nil {
+		t.Fatalf("RefAt() failed: %v", err)
+	}
+	// reading the first ref of the second ref, then resetting counters and reading it again.
+	if _, err := second.NextRef(); err != nil {
+		t.Fatalf("NextRef() failed: %v", err)
+	}
+	second.ResetCounters()
+	if _, err := second.RefAt(1); err != nil {
+		t.Fatalf("RefAt() failed: %v", err)
+	}
+	usage.Collect()
+
+	data, err := usage.CreateProof()
+	if err != nil {
+		t.Fatalf("CreateProof() failed: %v", err)
+	}
+	proof, err := DeserializeSingleRootBoc(data)
+	if err != nil {
+		t.Fatalf("DeserializeSingleRootBoc() failed: %v", err)
+	}
+	virtualRoot := proof.Refs()[0]
+	if c := virtualRoot.Refs()[0]; c.CellType() != PrunedBranchCell {
+		t.Fatalf("unread first ref must be pruned")
+	}
+	for i, c := range virtualRoot.Refs()[1].Refs() {
+		if c.CellType() != OrdinaryCell {
+			t.Fatalf("ref %v of the second ref must be kept", i)
+		}
+	}
+}
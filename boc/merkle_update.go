@@ -0,0 +1,230 @@
+package boc
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+)
+
+var ErrMerkleUpdateHashMismatch = errors.New("merkle update hash mismatch")
+
+// CreateMerkleUpdate returns a merkle update cell which transforms the "from" cell tree into the "to" cell tree.
+// Subtrees shared by both trees are replaced with pruned branch cells,
+// so the update contains only cells that were changed.
+// Cells containing pruned branches are never pruned themselves, they are kept as is.
+func CreateMerkleUpdate(from, to *Cell) (*Cell, error) {
+	cache := make(map[*Cell]*immutableCell)
+	immFrom, err := newImmutableCell(from, cache)
+	if err != nil {
+		return nil, err
+	}
+	immTo, err := newImmutableCell(to, cache)
+	if err != nil {
+		return nil, err
+	}
+	toHashes := make(map[string]struct{})
+	collectHashes(immTo, toHashes, make(map[*immutableCell]struct{}))
+
+	shared := make(map[string]struct{})
+	prunedFrom := make(map[*immutableCell]struct{})
+	markSharedCells(immFrom, toHashes, shared, prunedFrom, make(map[*immutableCell]struct{}))
+	prunedTo := make(map[*immutableCell]struct{})
+	markSharedCells(immTo, shared, nil, prunedTo, make(map[*immutableCell]struct{}))
+
+	fromRoot, err := immFrom.pruneCells(prunedFrom)
+	if err != nil {
+		return nil, err
+	}
+	toRoot, err := immTo.pruneCells(prunedTo)
+	if err != nil {
+		return nil, err
+	}
+	update := NewCellExotic(MerkleUpdateCell)
+	if err := update.WriteUint(0x04, 8); err != nil {
+		return nil, err
+	}
+	for _, hash := range [][]byte{immFrom.Hash(0), immTo.Hash(0)} {
+		if err := update.WriteBytes(hash); err != nil {
+			return nil, err
+		}
+	}
+	for _, depth := range []int{immFrom.Depth(0), immTo.Depth(0)} {
+		if err := update.WriteUint(uint64(depth), 16); err != nil {
+			return nil, err
+		}
+	}
+	for _, root := range []*Cell{fromRoot, toRoot} {
+		if err := update.AddRef(root); err != nil {
+			return nil, err
+		}
+	}
+	update.mask = (fromRoot.mask | toRoot.mask) >> 1
+	update.ResetCounters()
+	return update, nil
+}
+
+// collectHashes collects level 0 hashes of all cells of the given tree.
+func collectHashes(imm *immutableCell, hashes map[string]struct{}, visited map[*immutableCell]struct{}) {
+	if _, ok := visited[imm]; ok {
+		return
+	}
+	visited[imm] = struct{}{}
+	hashes[string(imm.Hash(0))] = struct{}{}
+	for _, ref := range imm.refs {
+		collectHashes(ref, hashes, visited)
+	}
+}
+
+// markSharedCells walks the given tree and marks the topmost cells whose level 0 hashes are present in "hashes" as pruned.
+// The hashes of pruned cells are added to "shared" if it isn't nil.
+func markSharedCells(imm *immutableCell, hashes, shared map[string]struct{}, pruned, visited map[*immutableCell]struct{}) {
+	if _, ok := visited[imm]; ok {
+		return
+	}
+	visited[imm] = struct{}{}
+	if imm.mask == 0 {
+		hash := string(imm.Hash(0))
+		if _, ok := hashes[hash]; ok {
+			pruned[imm] = struct{}{}
+			if shared != nil {
+				shared[hash] = struct{}{}
+			}
+			return
+		}
+	}
+	for _, ref := range imm.refs {
+		markSharedCells(ref, hashes, shared, pruned, visited)
+	}
+}
+
+// ApplyMerkleUpdate applies the given merkle update to the "from" cell tree and returns a new cell tree.
+// It checks that "from" is the tree the update was created for
+// and that the new tree has the hash declared by the update.
+// The new tree reuses unchanged subtrees of "from".
+func ApplyMerkleUpdate(from, update *Cell) (*Cell, error) {
+	return applyMerkleUpdate(from, update, make(map[*Cell]*immutableCell))
+}
+
+// ApplyMerkleUpdate works the same way as ApplyMerkleUpdate function,
+// but reuses hashes calculated by this hasher.
+// When a chain of merkle updates is applied with the same hasher,
+// only cells rebuilt by each update are hashed, unchanged subtrees are hashed once.
+func (h *Hasher) ApplyMerkleUpdate(from, update *Cell) (*Cell, error) {
+	return applyMerkleUpdate(from, update, h.cache)
+}
+
+func applyMerkleUpdate(from, update *Cell, cache map[*Cell]*immutableCell) (*Cell, error) {
+	if update.CellType() != MerkleUpdateCell {
+		return nil, fmt.Errorf("not a merkle update cell")
+	}
+	if update.RefsSize() != 2 || update.BitSize() != 8+2*256+2*16 {
+		return nil, fmt.Errorf("invalid merkle update cell")
+	}
+	data := update.getBuffer()
+	fromHash, toHash := data[1:33], data[33:65]
+
+	immFrom, err := newImmutableCell(from, cache)
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(immFrom.Hash(0), fromHash) {
+		return nil, fmt.Errorf("%w: unexpected old tree", ErrMerkleUpdateHashMismatch)
+	}
+	updateFrom, updateTo := update.refs[0], update.refs[1]
+	immUpdateFrom, err := newImmutableCell(updateFrom, cache)
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(immUpdateFrom.Hash(0), fromHash) {
+		return nil, fmt.Errorf("%w: invalid old tree in update", ErrMerkleUpdateHashMismatch)
+	}
+	immUpdateTo, err := newImmutableCell(updateTo, cache)
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(immUpdateTo.Hash(0), toHash) {
+		return nil, fmt.Errorf("%w: invalid new tree in update", ErrMerkleUpdateHashMismatch)
+	}
+	known := make(map[string]*Cell)
+	if err := collectKnownCells(updateFrom, immUpdateFrom, from, known, make(map[*Cell]struct{})); err != nil {
+		return nil, err
+	}
+	to, err := buildUpdatedTree(updateTo, immUpdateTo, known, make(map[*Cell]*Cell))
+	if err != nil {
+		return nil, err
+	}
+	// the new tree shares unchanged subtrees with "from" whose hashes are already cached,
+	// so only the cells rebuilt by buildUpdatedTree are hashed here.
+	immTo, err := newImmutableCell(to, cache)
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(immTo.Hash(0), toHash) {
+		return nil, fmt.Errorf("%w: unexpected new tree", ErrMerkleUpdateHashMismatch)
+	}
+	return to, nil
+}
+
+// collectKnownCells walks the old tree of a merkle update along with the original old tree
+// and remembers the original cells by their level 0 hashes.
+// The new tree of the update can refer to any of them with a pruned branch.
+func collectKnownCells(c *Cell, imm *immutableCell, original *Cell, known map[string]*Cell, visited map[*Cell]struct{}) error {
+	if _, ok := visited[c]; ok {
+		return nil
+	}
+	visited[c] = struct{}{}
+	known[string(imm.Hash(0))] = original
+	if c.CellType() == PrunedBranchCell {
+		return nil
+	}
+	refs := c.Refs()
+	originalRefs := original.Refs()
+	if len(refs) != len(originalRefs) {
+		return fmt.Errorf("%w: old tree structure mismatch", ErrMerkleUpdateHashMismatch)
+	}
+	for i, ref := range refs {
+		if err := collectKnownCells(ref, imm.refs[i], originalRefs[i], known, visited); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// buildUpdatedTree copies the new tree of a merkle update replacing pruned branches with original cells.
+func buildUpdatedTree(c *Cell, imm *immutableCell, known map[string]*Cell, built map[*Cell]*Cell) (*Cell, error) {
+	if res, ok := built[c]; ok {
+		return res, nil
+	}
+	if c.CellType() == PrunedBranchCell {
+		original, ok := known[string(imm.Hash(0))]
+		if !ok {
+			return nil, fmt.Errorf("%w: pruned branch %x is not found in old tree", ErrMerkleUpdateHashMismatch, imm.Hash(0))
+		}
+		built[c] = original
+		return original, nil
+	}
+	res := &Cell{
+		bits:     c.bits.Copy(),
+		cellType: c.cellType,
+	}
+	res.bits.ResetCounter()
+	var mask levelMask
+	for i, ref := range c.Refs() {
+		cell, err := buildUpdatedTree(ref, imm.refs[i], known, built)
+		if err != nil {
+			return nil, err
+		}
+		res.refs[i] = cell
+		mask |= cell.mask
+	}
+	switch c.cellType {
+	case OrdinaryCell:
+		res.mask = mask
+	case MerkleProofCell, MerkleUpdateCell:
+		res.mask = mask >> 1
+	default:
+		res.mask = c.mask
+	}
+	built[c] = res
+	return res, nil
+}
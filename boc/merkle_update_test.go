@@ -0,0 +1,206 @@
+package boc
+
+import (
+	"errors"
+	"os"
+	"testing"
+)
+
+// newTestTree returns a full binary tree of the given depth.
+// Leaves contain their indexes, a leaf at index "changed" contains "value" instead.
+func newTestTree(t *testing.T, depth int, changed int, value uint64) *Cell {
+	var build func(depth int, index int) *Cell
+	build = func(depth int, index int) *Cell {
+		c := NewCell()
+		if depth == 0 {
+			v := uint64(index)
+			if index == changed {
+				v = value
+			}
+			if err := c.WriteUint(v, 32); err != nil {
+				t.Fatalf("WriteUint() failed: %v", err)
+			}
+			return c
+		}
+		if err := c.WriteUint(uint64(depth), 8); err != nil {
+			t.Fatalf("WriteUint() failed: %v", err)
+		}
+		for i := 0; i < 2; i++ {
+			if err := c.AddRef(build(depth-1, index*2+i)); err != nil {
+				t.Fatalf("AddRef() failed: %v", err)
+			}
+		}
+		return c
+	}
+	return build(depth, 0)
+}
+
+func countCells(c *Cell) int {
+	count := 1
+	for _, ref := range c.Refs() {
+		count += countCells(ref)
+	}
+	return count
+}
+
+func TestCreateMerkleUpdate(t *testing.T) {
+	from := newTestTree(t, 8, 10, 10)
+	to := newTestTree(t, 8, 10, 1000)
+	fromHash, err := from.Hash256()
+	if err != nil {
+		t.Fatalf("Hash256() failed: %v", err)
+	}
+	toHash, err := to.Hash256()
+	if err != nil {
+		t.Fatalf("Hash256() failed: %v", err)
+	}
+
+	update, err := CreateMerkleUpdate(from, to)
+	if err != nil {
+		t.Fatalf("CreateMerkleUpdate() failed: %v", err)
+	}
+	if update.Level() != 0 {
+		t.Fatalf("want level 0, got %v", update.Level())
+	}
+	if n := countCells(update); n > 40 {
+		t.Fatalf("merkle update is too big: %v cells", n)
+	}
+	// make sure the update survives serialization
+	data, err := update.ToBoc()
+	if err != nil {
+		t.Fatalf("ToBoc() failed: %v", err)
+	}
+	update, err = DeserializeSingleRootBoc(data)
+	if err != nil {
+		t.Fatalf("DeserializeSingleRootBoc() failed: %v", err)
+	}
+	result, err := ApplyMerkleUpdate(from, update)
+	if err != nil {
+		t.Fatalf("ApplyMerkleUpdate() failed: %v", err)
+	}
+	hash, err := result.Hash256()
+	if err != nil {
+		t.Fatalf("Hash256() failed: %v", err)
+	}
+	if hash != toHash {
+		t.Fatalf("want hash: %x, got: %x", toHash, hash)
+	}
+
+	if _, err := ApplyMerkleUpdate(to, update); !errors.Is(err, ErrMerkleUpdateHashMismatch) {
+		t.Fatalf("want ErrMerkleUpdateHashMismatch, got: %v", err)
+	}
+
+	reverse, err := CreateMerkleUpdate(to, from)
+	if err != nil {
+		t.Fatalf("CreateMerkleUpdate() failed: %v", err)
+	}
+	result, err = ApplyMerkleUpdate(to, reverse)
+	if err != nil {
+		t.Fatalf("ApplyMerkleUpdate() failed: %v", err)
+	}
+	if hash, _ := result.Hash256(); hash != fromHash {
+		t.Fatalf("want hash: %x, got: %x", fromHash, hash)
+	}
+
+	same, err := CreateMerkleUpdate(from, from)
+	if err != nil {
+		t.Fatalf("CreateMerkleUpdate() failed: %v", err)
+	}
+	result, err = ApplyMerkleUpdate(from, same)
+	if err != nil {
+		t.Fatalf("ApplyMerkleUpdate() failed: %v", err)
+	}
+	if hash, _ := result.Hash256(); hash != fromHash {
+		t.Fatalf("want hash: %x, got: %x", fromHash, hash)
+	}
+}
+
+func TestApplyMerkleUpdate_forged(t *testing.T) {
+	from := newTestTree(t, 4, 3, 3)
+	to := newTestTree(t, 4, 3, 300)
+	update, err := CreateMerkleUpdate(from, to)
+	if err != nil {
+		t.Fatalf("CreateMerkleUpdate() failed: %v", err)
+	}
+	// replace the new tree but keep the declared hash
+	forged := NewCellExotic(MerkleUpdateCell)
+	forged.bits = update.bits.Copy()
+	forged.refs[0] = update.refs[0]
+	forged.refs[1] = newTestTree(t, 4, 3, 500)
+	forged.mask = update.mask
+	if _, err := ApplyMerkleUpdate(from, forged); !errors.Is(err, ErrMerkleUpdateHashMismatch) {
+		t.Fatalf("want ErrMerkleUpdateHashMismatch, got: %v", err)
+	}
+}
+
+func TestApplyMerkleUpdate_block(t *testing.T) {
+	data, err := os.ReadFile("../tlb/testdata/block-1/block.bin")
+	if err != nil {
+		t.Fatalf("ReadFile() failed: %v", err)
+	}
+	block, err := DeserializeSingleRootBoc(data)
+	if err != nil {
+		t.Fatalf("DeserializeSingleRootBoc() failed: %v", err)
+	}
+	// block#11ef55aa global_id:int32 info:^BlockInfo value_flow:^ValueFlow state_update:^(MERKLE_UPDATE ShardState) extra:^BlockExtra
+	update := block.Refs()[2]
+	// the old state in a block is a merkle proof of the state, so it can be used as the old state.
+	from := update.Refs()[0]
+	to, err := ApplyMerkleUpdate(from, update)
+	if err != nil {
+		t.Fatalf("ApplyMerkleUpdate() failed: %v", err)
+	}
+	toHash, err := to.Hash256WithLevel(0)
+	if err != nil {
+		t.Fatalf("Hash256WithLevel() failed: %v", err)
+	}
+	wantHash, err := update.Refs()[1].Hash256WithLevel(0)
+	if err != nil {
+		t.Fatalf("Hash256WithLevel() failed: %v", err)
+	}
+	if toHash != wantHash {
+		t.Fatalf("want hash: %x, got: %x", wantHash, toHash)
+	}
+}
+
+func TestHasher_ApplyMerkleUpdate(t *testing.T) {
+	states := []*Cell{
+		newTestTree(t, 8, 10, 10),
+		newTestTree(t, 8, 10, 1000),
+		newTestTree(t, 8, 10, 2000),
+	}
+	h := NewHasher()
+	current := states[0]
+	if _, err := h.Hash(current); err != nil {
+		t.Fatalf("Hash() failed: %v", err)
+	}
+	for i := 1; i < len(states); i++ {
+		update, err := CreateMerkleUpdate(states[i-1], states[i])
+		if err != nil {
+			t.Fatalf("CreateMerkleUpdate() failed: %v", err)
+		}
+		before := len(h.cache)
+		next, err := h.ApplyMerkleUpdate(current, update)
+		if err != nil {
+			t.Fatalf("ApplyMerkleUpdate() failed: %v", err)
+		}
+		// only cells of the old and new trees of the update and the rebuilt path of the new tree are hashed.
+		// the path goes from the root to the changed leaf: 9 cells of a tree of depth 8.
+		updateCells := countCells(update.Refs()[0]) + countCells(update.Refs()[1])
+		if hashed, want := len(h.cache)-before, updateCells+9; hashed != want {
+			t.Fatalf("want %v hashed cells, got: %v", want, hashed)
+		}
+		hash, err := next.Hash256()
+		if err != nil {
+			t.Fatalf("Hash256() failed: %v", err)
+		}
+		wantHash, err := states[i].Hash256()
+		if err != nil {
+			t.Fatalf("Hash256() failed: %v", err)
+		}
+		if hash != wantHash {
+			t.Fatalf("want hash: %x, got: %x", wantHash, hash)
+		}
+		current = next
+	}
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/tonkeeper/tongo/config"
+	"github.com/tonkeeper/tongo/liteapi/pool"
+	"github.com/tonkeeper/tongo/liteapi/proxy"
+	"github.com/tonkeeper/tongo/liteclient"
+)
+
+func main() {
+	listen := flag.String("listen", ":4924", "address to accept lite client connections on")
+	key := flag.String("key", os.Getenv("LITEPROXY_KEY"), "base64 encoded ed25519 private key seed, a random key is generated if empty")
+	configPath := flag.String("config", "https://ton.org/global.config.json", "path or url of a global config, ignored if LITE_SERVERS is set")
+	connections := flag.Int("connections", 4, "number of connections to upstream lite servers")
+	timeout := flag.Duration("timeout", 30*time.Second, "timeout of an upstream query")
+	cacheBytes := flag.Int("cache-bytes", 64<<20, "maximum total size in bytes of cached immutable answers, 0 disables caching")
+	metrics := flag.String("metrics", "", "address to serve metrics on, disabled if empty")
+	flag.Parse()
+
+	privateKey, err := loadKey(*key)
+	if err != nil {
+		log.Fatal(err)
+	}
+	servers, err := loadServers(*configPath)
+	if err != nil {
+		log.Fatal(err)
+	}
+	connPool := pool.New(pool.BestPingStrategy, pool.WithObserver(pool.NewSlogObserver(nil)))
+	if err := <-connPool.InitializeConnections(context.Background(), *timeout, *connections, 1, false, servers); err != nil {
+		log.Fatal(err)
+	}
+	go connPool.Run(context.Background())
+
+	p := proxy.New(connPool, proxy.WithCacheSize(*cacheBytes))
+	server, err := liteclient.NewServer(privateKey, p)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if *metrics != "" {
+		go serveMetrics(*metrics, p)
+	}
+	log.Printf("listening on %v, public key: %v", *listen, base64.StdEncoding.EncodeToString(privateKey.Public().(ed25519.PublicKey)))
+	log.Fatal(server.ListenAndServe(*listen))
+}
+
+func loadKey(seed string) (ed25519.PrivateKey, error) {
+	if seed == "" {
+		_, key, err := ed25519.GenerateKey(rand.Reader)
+		return key, err
+	}
+	b, err := base64.StdEncoding.DecodeString(seed)
+	if err != nil {
+		return nil, err
+	}
+	if len(b) != ed25519.SeedSize {
+		return nil, fmt.Errorf("invalid key seed length: %v", len(b))
+	}
+	return ed25519.NewKeyFromSeed(b), nil
+}
+
+func loadServers(path string) ([]config.LiteServer, error) {
+	if value, ok := os.LookupEnv("LITE_SERVERS"); ok {
+		return config.ParseLiteServersEnvVar(value)
+	}
+	if !strings.HasPrefix(path, "http://") && !strings.HasPrefix(path, "https://") {
+		file, err := config.ParseConfigFile(path)
+		if err != nil {
+			return nil, err
+		}
+		return file.LiteServers, nil
+	}
+	resp, err := http.Get(path)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	file, err := config.ParseConfig(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	return file.LiteServers, nil
+}
+
+// serveMetrics exposes the proxy's counters in the prometheus text format.
+func serveMetrics(address string, p *proxy.Proxy) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/metrics", func(w http.ResponseWriter, r *http.Request) {
+		stats := p.Stats()
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+		fmt.Fprintf(w, "liteproxy_cache_hits_total %v\n", stats.Hits)
+		fmt.Fprintf(w, "liteproxy_cache_misses_total %v\n", stats.Misses)
+		fmt.Fprintf(w, "liteproxy_coalesced_total %v\n", stats.Coalesced)
+		fmt.Fprintf(w, "liteproxy_upstream_errors_total %v\n", stats.Errors)
+	})
+	log.Fatal(http.ListenAndServe(address, mux))
+}
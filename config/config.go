@@ -1,17 +1,25 @@
 package config
 
 import (
+	"bytes"
+	"crypto/ed25519"
+	"encoding/base64"
 	"encoding/binary"
 	"encoding/json"
 	"fmt"
 	"io"
+	"net"
 	"os"
+	"strconv"
+
+	"github.com/tonkeeper/tongo/ton"
 )
 
 type liteServerConfig struct {
-	Ip   int64        `json:"ip"`
-	Port int64        `json:"port"`
-	ID   liteServerId `json:"id"`
+	// Ip is either a signed or unsigned 32-bit integer for IPv4 or a string with a textual IPv4 or IPv6 address.
+	Ip   json.RawMessage `json:"ip"`
+	Port int64           `json:"port"`
+	ID   liteServerId    `json:"id"`
 }
 
 type liteServerId struct {
@@ -19,21 +27,163 @@ type liteServerId struct {
 	Key  string `json:"key"`
 }
 
+type blockIdConfig struct {
+	Workchain int32  `json:"workchain"`
+	Shard     int64  `json:"shard"`
+	Seqno     uint32 `json:"seqno"`
+	RootHash  string `json:"root_hash"`
+	FileHash  string `json:"file_hash"`
+}
+
+type validatorConfig struct {
+	ZeroState blockIdConfig   `json:"zero_state"`
+	InitBlock *blockIdConfig  `json:"init_block"`
+	Hardforks []blockIdConfig `json:"hardforks"`
+}
+
+type addressConfig struct {
+	Type string          `json:"@type"`
+	Ip   json.RawMessage `json:"ip"`
+	Port int64           `json:"port"`
+}
+
+type addressListConfig struct {
+	Addrs      []addressConfig `json:"addrs"`
+	Version    int32           `json:"version"`
+	ReinitDate int32           `json:"reinit_date"`
+	Priority   int32           `json:"priority"`
+	ExpireAt   int32           `json:"expire_at"`
+}
+
+type dhtNodeConfig struct {
+	ID        liteServerId      `json:"id"`
+	AddrList  addressListConfig `json:"addr_list"`
+	Version   int32             `json:"version"`
+	Signature string            `json:"signature"`
+}
+
+type dhtConfig struct {
+	K           int `json:"k"`
+	A           int `json:"a"`
+	StaticNodes struct {
+		Nodes []dhtNodeConfig `json:"nodes"`
+	} `json:"static_nodes"`
+}
+
 type configGlobal struct {
 	LiteServers []liteServerConfig `json:"liteservers"`
-	//Validator   ValidatorConfig  `json:"validator"`
+	Validator   *validatorConfig   `json:"validator"`
+	DHT         *dhtConfig         `json:"dht"`
 }
 
 // GlobalConfigurationFile contains global configuration of the TON Blockchain.
 // It is shared by all nodes and includes information about network, init block, hardforks, etc.
 type GlobalConfigurationFile struct {
 	LiteServers []LiteServer
+	Validator   ValidatorConfig
+	DHT         DHTConfig
+}
+
+// DHTConfig contains parameters of the DHT network and nodes used to join it.
+type DHTConfig struct {
+	// K is a number of nodes storing a value.
+	K int
+	// A is a number of parallel requests during a lookup.
+	A int
+	// StaticNodes are well-known DHT nodes.
+	StaticNodes []DHTNode
+}
+
+// DHTNode is a signed description of a DHT node.
+type DHTNode struct {
+	Key       ed25519.PublicKey
+	AddrList  AddressList
+	Version   int32
+	Signature []byte
+}
+
+// AddressList contains UDP addresses of an ADNL node.
+type AddressList struct {
+	// Addrs is a list of addresses in the "host:port" form, IPv6 hosts are enclosed in square brackets.
+	Addrs      []string
+	Version    int32
+	ReinitDate int32
+	Priority   int32
+	ExpireAt   int32
+}
+
+// ValidatorConfig contains blocks of the masterchain trusted by all nodes of the network.
+type ValidatorConfig struct {
+	ZeroState ton.BlockIDExt
+	// InitBlock is a key block used as a starting point to verify the masterchain.
+	// If the configuration doesn't contain init block, InitBlock is equal to ZeroState.
+	InitBlock ton.BlockIDExt
+	Hardforks []ton.BlockIDExt
+}
+
+func convertToBlockIDExt(id blockIdConfig) (ton.BlockIDExt, error) {
+	blockID := ton.BlockIDExt{
+		BlockID: ton.BlockID{
+			Workchain: id.Workchain,
+			Shard:     uint64(id.Shard),
+			Seqno:     id.Seqno,
+		},
+	}
+	for _, h := range []struct {
+		value string
+		dest  *ton.Bits256
+	}{
+		{value: id.RootHash, dest: &blockID.RootHash},
+		{value: id.FileHash, dest: &blockID.FileHash},
+	} {
+		b, err := base64.StdEncoding.DecodeString(h.value)
+		if err != nil {
+			return ton.BlockIDExt{}, err
+		}
+		if len(b) != 32 {
+			return ton.BlockIDExt{}, fmt.Errorf("invalid hash length: %v", len(b))
+		}
+		copy(h.dest[:], b)
+	}
+	return blockID, nil
 }
 
-// LiteServer TODO: clarify struct
+func convertToValidatorConfig(conf validatorConfig) (ValidatorConfig, error) {
+	var res ValidatorConfig
+	var err error
+	res.ZeroState, err = convertToBlockIDExt(conf.ZeroState)
+	if err != nil {
+		return ValidatorConfig{}, fmt.Errorf("invalid zero state: %w", err)
+	}
+	if res.ZeroState.Workchain != -1 || res.ZeroState.Seqno != 0 {
+		return ValidatorConfig{}, fmt.Errorf("zero state must be the first masterchain block")
+	}
+	res.InitBlock = res.ZeroState
+	if conf.InitBlock != nil {
+		res.InitBlock, err = convertToBlockIDExt(*conf.InitBlock)
+		if err != nil {
+			return ValidatorConfig{}, fmt.Errorf("invalid init block: %w", err)
+		}
+		if res.InitBlock.Workchain != -1 {
+			return ValidatorConfig{}, fmt.Errorf("init block must be a masterchain block")
+		}
+	}
+	for _, hardfork := range conf.Hardforks {
+		blockID, err := convertToBlockIDExt(hardfork)
+		if err != nil {
+			return ValidatorConfig{}, fmt.Errorf("invalid hardfork: %w", err)
+		}
+		res.Hardforks = append(res.Hardforks, blockID)
+	}
+	return res, nil
+}
+
+// LiteServer describes a lite server.
 type LiteServer struct {
+	// Host is an address of a lite server in the "host:port" form, IPv6 hosts are enclosed in square brackets.
 	Host string
-	Key  string
+	// Key is a base64-encoded ed25519 public key of a lite server.
+	Key string
 }
 
 func ParseConfigFile(path string) (*GlobalConfigurationFile, error) {
@@ -49,17 +199,146 @@ func convertToLiteServerOptions(server liteServerConfig) (LiteServer, error) {
 	if server.ID.Type != "pub.ed25519" {
 		return LiteServer{}, fmt.Errorf("not pub.ed25519 liteserver ID. Other types not supported")
 	}
-	if server.Ip > 0xFFFF_FFFF {
-		return LiteServer{}, fmt.Errorf("only IPv4 supported")
+	if _, err := decodePublicKey(server.ID.Key); err != nil {
+		return LiteServer{}, err
+	}
+	ip, err := parseIP(server.Ip)
+	if err != nil {
+		return LiteServer{}, err
+	}
+	host, err := joinHostPort(ip, server.Port)
+	if err != nil {
+		return LiteServer{}, err
 	}
-	ipBytes := make([]byte, 4)
-	binary.BigEndian.PutUint32(ipBytes, uint32(server.Ip))
 	return LiteServer{
-		Host: fmt.Sprintf("%v.%v.%v.%v:%d", ipBytes[0], ipBytes[1], ipBytes[2], ipBytes[3], server.Port),
+		Host: host,
 		Key:  server.ID.Key,
 	}, nil
 }
 
+func convertToDHTConfig(conf dhtConfig) DHTConfig {
+	res := DHTConfig{K: conf.K, A: conf.A}
+	for _, node := range conf.StaticNodes.Nodes {
+		dhtNode, err := convertToDHTNode(node)
+		if err != nil {
+			continue
+		}
+		res.StaticNodes = append(res.StaticNodes, dhtNode)
+	}
+	return res
+}
+
+func convertToDHTNode(node dhtNodeConfig) (DHTNode, error) {
+	if node.ID.Type != "pub.ed25519" {
+		return DHTNode{}, fmt.Errorf("not pub.ed25519 DHT node ID. Other types not supported")
+	}
+	key, err := decodePublicKey(node.ID.Key)
+	if err != nil {
+		return DHTNode{}, err
+	}
+	signature, err := base64.StdEncoding.DecodeString(node.Signature)
+	if err != nil {
+		return DHTNode{}, err
+	}
+	res := DHTNode{
+		Key:       key,
+		Version:   node.Version,
+		Signature: signature,
+		AddrList: AddressList{
+			Version:    node.AddrList.Version,
+			ReinitDate: node.AddrList.ReinitDate,
+			Priority:   node.AddrList.Priority,
+			ExpireAt:   node.AddrList.ExpireAt,
+		},
+	}
+	for _, addr := range node.AddrList.Addrs {
+		var ip net.IP
+		switch addr.Type {
+		case "adnl.address.udp":
+			ip, err = parseIP(addr.Ip)
+		case "adnl.address.udp6":
+			ip, err = parseIPv6(addr.Ip)
+		default:
+			err = fmt.Errorf("unsupported address type %v", addr.Type)
+		}
+		if err != nil {
+			return DHTNode{}, err
+		}
+		host, err := joinHostPort(ip, addr.Port)
+		if err != nil {
+			return DHTNode{}, err
+		}
+		res.AddrList.Addrs = append(res.AddrList.Addrs, host)
+	}
+	if len(res.AddrList.Addrs) == 0 {
+		return DHTNode{}, fmt.Errorf("DHT node without addresses")
+	}
+	return res, nil
+}
+
+func decodePublicKey(key string) (ed25519.PublicKey, error) {
+	b, err := base64.StdEncoding.DecodeString(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(b) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("invalid public key length: %v", len(b))
+	}
+	return b, nil
+}
+
+// parseIP parses an IP address which is either a 32-bit integer for IPv4
+// or a string with a textual IPv4 or IPv6 address.
+// Global configs encode IPv4 addresses as signed integers, but unsigned ones are accepted as well.
+func parseIP(raw json.RawMessage) (net.IP, error) {
+	raw = bytes.TrimSpace(raw)
+	if len(raw) > 0 && raw[0] == '"' {
+		var str string
+		if err := json.Unmarshal(raw, &str); err != nil {
+			return nil, err
+		}
+		ip := net.ParseIP(str)
+		if ip == nil {
+			return nil, fmt.Errorf("invalid ip: %v", str)
+		}
+		return ip, nil
+	}
+	value, err := strconv.ParseInt(string(raw), 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid ip: %s", raw)
+	}
+	if value < -0x8000_0000 || value > 0xFFFF_FFFF {
+		return nil, fmt.Errorf("invalid IPv4: %v", value)
+	}
+	ip := make(net.IP, 4)
+	binary.BigEndian.PutUint32(ip, uint32(value))
+	return ip, nil
+}
+
+// parseIPv6 parses an int128 IPv6 address of adnl.address.udp6 which is encoded as a base64 string,
+// a textual IPv6 address is accepted as well.
+func parseIPv6(raw json.RawMessage) (net.IP, error) {
+	var str string
+	if err := json.Unmarshal(raw, &str); err != nil {
+		return nil, err
+	}
+	if b, err := base64.StdEncoding.DecodeString(str); err == nil && len(b) == net.IPv6len {
+		return b, nil
+	}
+	ip := net.ParseIP(str)
+	if ip == nil {
+		return nil, fmt.Errorf("invalid IPv6: %v", str)
+	}
+	return ip, nil
+}
+
+func joinHostPort(ip net.IP, port int64) (string, error) {
+	if port <= 0 || port > 0xFFFF {
+		return "", fmt.Errorf("invalid port: %v", port)
+	}
+	return net.JoinHostPort(ip.String(), strconv.FormatInt(port, 10)), nil
+}
+
 func ParseConfig(data io.Reader) (*GlobalConfigurationFile, error) {
 	var conf configGlobal
 	err := json.NewDecoder(data).Decode(&conf)
@@ -67,6 +346,15 @@ func ParseConfig(data io.Reader) (*GlobalConfigurationFile, error) {
 		return nil, err
 	}
 	var options GlobalConfigurationFile
+	if conf.Validator != nil {
+		options.Validator, err = convertToValidatorConfig(*conf.Validator)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if conf.DHT != nil {
+		options.DHT = convertToDHTConfig(*conf.DHT)
+	}
 	for _, server := range conf.LiteServers {
 		ls, err := convertToLiteServerOptions(server)
 		if err != nil {
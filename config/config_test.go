@@ -0,0 +1,112 @@
+package config
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseConfig(t *testing.T) {
+	data := `{
+	  "liteservers": [
+	    {"ip": 84478511, "port": 19949, "id": {"@type": "pub.ed25519", "key": "n4VDnSCUuSpjnCyUk9e3QOOd6o0ItSWYbTnW3Wnn8wk="}},
+	    {"ip": -1185526007, "port": 4924, "id": {"@type": "pub.ed25519", "key": "n4VDnSCUuSpjnCyUk9e3QOOd6o0ItSWYbTnW3Wnn8wk="}},
+	    {"ip": "2001:db8::17", "port": 4925, "id": {"@type": "pub.ed25519", "key": "n4VDnSCUuSpjnCyUk9e3QOOd6o0ItSWYbTnW3Wnn8wk="}},
+	    {"ip": 84478511, "port": 19950, "id": {"@type": "pub.ed25519", "key": "c2hvcnQ="}},
+	    {"ip": 84478511, "port": 19951, "id": {"@type": "pub.aes", "key": "n4VDnSCUuSpjnCyUk9e3QOOd6o0ItSWYbTnW3Wnn8wk="}}
+	  ],
+	  "dht": {
+	    "@type": "dht.config.global",
+	    "k": 6,
+	    "a": 3,
+	    "static_nodes": {
+	      "@type": "dht.nodes",
+	      "nodes": [
+	        {
+	          "@type": "dht.node",
+	          "id": {"@type": "pub.ed25519", "key": "6PGkPQSbyFp12esf1NqmDOaLoFA8i9+Mp5+cAx5wtTU="},
+	          "addr_list": {
+	            "@type": "adnl.addressList",
+	            "addrs": [
+	              {"@type": "adnl.address.udp", "ip": -1185526389, "port": 14058},
+	              {"@type": "adnl.address.udp6", "ip": "IAENuAAAAAAAAAAAAAAAFw==", "port": 14059}
+	            ],
+	            "version": 0,
+	            "reinit_date": 0,
+	            "priority": 0,
+	            "expire_at": 0
+	          },
+	          "version": -1,
+	          "signature": "nNQKRSTpcRKLbIz4G0zAuOsl8BA+zy/9vXl+9qqjmr7a8fKVvbWXONpa/E0uKe4pmOjRkbyS8tDwT2I0VbHQCA=="
+	        },
+	        {
+	          "@type": "dht.node",
+	          "id": {"@type": "pub.ed25519", "key": "6PGkPQSbyFp12esf1NqmDOaLoFA8i9+Mp5+cAx5wtTU="},
+	          "addr_list": {"@type": "adnl.addressList", "addrs": []},
+	          "version": -1,
+	          "signature": ""
+	        }
+	      ]
+	    }
+	  },
+	  "validator": {
+	    "@type": "validator.config.global",
+	    "zero_state": {
+	      "workchain": -1,
+	      "shard": -9223372036854775808,
+	      "seqno": 0,
+	      "root_hash": "F6OpKZKqvqeFp6CQmFomXNMfMj2EnaUSOXN+Mh+wVWk=",
+	      "file_hash": "XplPz01CXAps5qeSWUtxcyBfdAo5zVb1N979KLSKD24="
+	    },
+	    "init_block": {
+	      "root_hash": "YRkrcmZMvLBvjanwKCyL3w4oceGPtFfgx8ym1QKCK/4=",
+	      "seqno": 27747086,
+	      "file_hash": "N42xzPnJjDlE3hxPXOb+pNzXomgRtpX5AZzMPnIA41s=",
+	      "workchain": -1,
+	      "shard": -9223372036854775808
+	    },
+	    "hardforks": [
+	      {
+	        "file_hash": "t/9VBPODF7Zdh4nsnA49dprO69nQNMqYL+zk5bCjV/8=",
+	        "seqno": 8536841,
+	        "root_hash": "08Kpc9XxrMKC6BF/FeNHPS3MEL1/Vi/fQU/C9ELUrkc=",
+	        "workchain": -1,
+	        "shard": -9223372036854775808
+	      }
+	    ]
+	  }
+	}`
+	conf, err := ParseConfig(strings.NewReader(data))
+	if err != nil {
+		t.Fatalf("ParseConfig() failed: %v", err)
+	}
+	wantServers := []LiteServer{
+		{Host: "5.9.10.47:19949", Key: "n4VDnSCUuSpjnCyUk9e3QOOd6o0ItSWYbTnW3Wnn8wk="},
+		{Host: "185.86.79.9:4924", Key: "n4VDnSCUuSpjnCyUk9e3QOOd6o0ItSWYbTnW3Wnn8wk="},
+		{Host: "[2001:db8::17]:4925", Key: "n4VDnSCUuSpjnCyUk9e3QOOd6o0ItSWYbTnW3Wnn8wk="},
+	}
+	if !reflect.DeepEqual(conf.LiteServers, wantServers) {
+		t.Fatalf("invalid lite servers: %v", conf.LiteServers)
+	}
+	if conf.DHT.K != 6 || conf.DHT.A != 3 || len(conf.DHT.StaticNodes) != 1 {
+		t.Fatalf("invalid DHT config: %v", conf.DHT)
+	}
+	node := conf.DHT.StaticNodes[0]
+	wantAddrs := []string{"185.86.77.139:14058", "[2001:db8::17]:14059"}
+	if !reflect.DeepEqual(node.AddrList.Addrs, wantAddrs) || node.Version != -1 || len(node.Key) != 32 || len(node.Signature) != 64 {
+		t.Fatalf("invalid DHT node: %v", node)
+	}
+	zeroState := conf.Validator.ZeroState
+	if zeroState.Workchain != -1 || zeroState.Shard != 0x8000000000000000 || zeroState.Seqno != 0 {
+		t.Fatalf("invalid zero state: %v", zeroState)
+	}
+	if zeroState.RootHash.Hex() != "17a3a92992aabea785a7a090985a265cd31f323d849da51239737e321fb05569" {
+		t.Fatalf("invalid zero state root hash: %x", zeroState.RootHash)
+	}
+	if conf.Validator.InitBlock.Seqno != 27747086 {
+		t.Fatalf("invalid init block: %v", conf.Validator.InitBlock)
+	}
+	if len(conf.Validator.Hardforks) != 1 || conf.Validator.Hardforks[0].Seqno != 8536841 {
+		t.Fatalf("invalid hardforks: %v", conf.Validator.Hardforks)
+	}
+}
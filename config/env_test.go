@@ -25,6 +25,18 @@ func TestParseLiteServersEnvVar(t *testing.T) {
 				{Host: "192.168.0.17:14095", Key: "NqmDOaLoFA8i9+Mp5+cAx5wtTU="},
 			},
 		},
+		{
+			name: "IPv6 server",
+			str:  "[2001:db8::17]:14095:NqmDOaLoFA8i9+Mp5+cAx5wtTU=",
+			want: []LiteServer{
+				{Host: "[2001:db8::17]:14095", Key: "NqmDOaLoFA8i9+Mp5+cAx5wtTU="},
+			},
+		},
+		{
+			name:    "error - IPv6 without brackets",
+			str:     "2001:db8::17:14095:NqmDOaLoFA8i9+Mp5+cAx5wtTU=",
+			wantErr: `invalid liteserver string: 2001:db8::17:14095:NqmDOaLoFA8i9+Mp5+cAx5wtTU=`,
+		},
 		{
 			name:    "error - invalid port",
 			str:     "127.0.0.1:xxx:6PGkPQSbyFp12esf1+Mp5+cAx5wtTU=",
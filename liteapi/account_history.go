@@ -0,0 +1,76 @@
+package liteapi
+
+import (
+	"context"
+
+	"github.com/tonkeeper/tongo/ton"
+)
+
+// AccountHistoryOptions specifies transactions emitted by AccountHistory.
+// Zero values mean no bounds.
+type AccountHistoryOptions struct {
+	// Lt and Hash identify the newest transaction to start from.
+	// The history starts from the last transaction of the account if Lt is zero.
+	Lt   uint64
+	Hash ton.Bits256
+	// MaxLt and MaxUtime skip transactions newer than the given logical time and unix time.
+	MaxLt    uint64
+	MaxUtime uint32
+	// MinLt and MinUtime stop the history at transactions older than the given logical time and unix time.
+	MinLt    uint64
+	MinUtime uint32
+	// PageSize is a number of transactions requested at once, it can't be greater than 16.
+	PageSize uint32
+}
+
+// AccountHistory emits transactions of the account from the newest to the oldest one.
+// When a lite server reports that it doesn't keep the requested part of the history,
+// the rest of the history is requested from an archive node of the pool the same way as GetTransactions does.
+// Archive nodes are known only if the client is created with WithDetectArchiveNodes.
+func (c *Client) AccountHistory(ctx context.Context, accountID ton.AccountID, opts AccountHistoryOptions) *Subscription[ton.Transaction] {
+	return newSubscription(ctx, func(ctx context.Context, emit func(ton.Transaction) error) error {
+		return c.accountHistory(ctx, accountID, opts, emit)
+	})
+}
+
+func (c *Client) accountHistory(ctx context.Context, accountID ton.AccountID, opts AccountHistoryOptions, emit func(ton.Transaction) error) error {
+	pageSize := opts.PageSize
+	if pageSize == 0 || pageSize > maxTransactionCount {
+		pageSize = maxTransactionCount
+	}
+	lt, hash := opts.Lt, opts.Hash
+	if lt == 0 {
+		state, err := c.GetAccountState(ctx, accountID)
+		if err != nil {
+			return err
+		}
+		lt, hash = state.LastTransLt, ton.Bits256(state.LastTransHash)
+	}
+	for lt != 0 && lt >= opts.MinLt {
+		res, err := c.GetTransactionsRaw(ctx, pageSize, accountID, lt, hash)
+		if err != nil {
+			return err
+		}
+		txs, err := c.decodeTransactions(res, accountID, lt, hash)
+		if err != nil {
+			return err
+		}
+		if len(txs) == 0 {
+			return nil
+		}
+		for _, tx := range txs {
+			if tx.Lt < opts.MinLt || tx.Now < opts.MinUtime {
+				return nil
+			}
+			if (opts.MaxLt > 0 && tx.Lt > opts.MaxLt) || (opts.MaxUtime > 0 && tx.Now > opts.MaxUtime) {
+				continue
+			}
+			if err := emit(tx); err != nil {
+				return err
+			}
+		}
+		last := txs[len(txs)-1]
+		lt, hash = last.PrevTransLt, ton.Bits256(last.PrevTransHash)
+	}
+	return nil
+}
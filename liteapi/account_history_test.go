@@ -0,0 +1,188 @@
+package liteapi
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/tonkeeper/tongo/boc"
+	"github.com/tonkeeper/tongo/config"
+	"github.com/tonkeeper/tongo/liteclient"
+	"github.com/tonkeeper/tongo/tlb"
+	"github.com/tonkeeper/tongo/ton"
+)
+
+// historyHandler is a lite server which keeps transactions of a single account
+// starting from the transaction with minLt.
+type historyHandler struct {
+	liteclient.UnimplementedLiteServerHandler
+
+	head  uint32
+	minLt uint64
+	txs   []*boc.Cell // newest first
+	lts   []uint64
+
+	mu       sync.Mutex
+	requests int
+}
+
+func (h *historyHandler) LiteServerGetMasterchainInfo(ctx context.Context) (liteclient.LiteServerMasterchainInfoC, error) {
+	return liteclient.LiteServerMasterchainInfoC{
+		Last: liteclient.TonNodeBlockIdExtC{Workchain: uint32(0xffffffff), Shard: masterchainShard, Seqno: h.head},
+	}, nil
+}
+
+func (h *historyHandler) LiteServerLookupBlock(ctx context.Context, request liteclient.LiteServerLookupBlockRequest) (liteclient.LiteServerBlockHeaderC, error) {
+	// an archive node keeps all blocks, other nodes keep the last ten ones.
+	if request.Id.Seqno > h.head || (h.minLt > 0 && request.Id.Seqno+10 < h.head) {
+		return liteclient.LiteServerBlockHeaderC{}, liteclient.LiteServerErrorC{Code: 651, Message: "block not found"}
+	}
+	return liteclient.LiteServerBlockHeaderC{Id: liteclient.TonNodeBlockIdExtC{Workchain: request.Id.Workchain, Shard: request.Id.Shard, Seqno: request.Id.Seqno}}, nil
+}
+
+func (h *historyHandler) LiteServerGetTransactions(ctx context.Context, request liteclient.LiteServerGetTransactionsRequest) (liteclient.LiteServerTransactionListC, error) {
+	h.mu.Lock()
+	h.requests += 1
+	h.mu.Unlock()
+	if request.Lt < h.minLt {
+		truncated := int32(-400)
+		return liteclient.LiteServerTransactionListC{}, liteclient.LiteServerErrorC{Code: uint32(truncated), Message: "cannot compute block with specified transaction: lt not in db"}
+	}
+	var res liteclient.LiteServerTransactionListC
+	var cells []*boc.Cell
+	for i, lt := range h.lts {
+		if lt > request.Lt || len(cells) == int(request.Count) {
+			continue
+		}
+		cells = append(cells, h.txs[i])
+		res.Ids = append(res.Ids, liteclient.TonNodeBlockIdExtC{Shard: masterchainShard, Seqno: uint32(lt)})
+	}
+	data, err := boc.SerializeBocMulti(cells, false, false, false, 0)
+	if err != nil {
+		return liteclient.LiteServerTransactionListC{}, err
+	}
+	res.Transactions = data
+	return res, nil
+}
+
+func (h *historyHandler) requestsNumber() int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.requests
+}
+
+func startTestLiteServer(t *testing.T, handler liteclient.LiteServerHandler) config.LiteServer {
+	public, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey() failed: %v", err)
+	}
+	server, err := liteclient.NewServer(private, handler)
+	if err != nil {
+		t.Fatalf("NewServer() failed: %v", err)
+	}
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen() failed: %v", err)
+	}
+	go server.Serve(l)
+	t.Cleanup(func() { server.Close() })
+	return config.LiteServer{Host: l.Addr().String(), Key: base64.StdEncoding.EncodeToString(public)}
+}
+
+func TestClient_AccountHistory(t *testing.T) {
+	_, block := readTestBlock(t, "../tlb/testdata/block-1/block.bin")
+	template := block.Extra.AccountBlocks.Values()[0].Transactions.Values()[0].Value
+	accountID := ton.AccountID{Workchain: 0, Address: ton.Bits256(template.AccountAddr)}
+	// transactions with lt 10, 20, ..., 100, newest first.
+	var (
+		cells    []*boc.Cell
+		lts      []uint64
+		prevLt   uint64
+		prevHash ton.Bits256
+	)
+	for lt := uint64(10); lt <= 100; lt += 10 {
+		tx := template
+		tx.Lt, tx.PrevTransLt, tx.PrevTransHash, tx.Now = lt, prevLt, tlb.Bits256(prevHash), uint32(lt)
+		cell := boc.NewCell()
+		if err := tlb.Marshal(cell, tx); err != nil {
+			t.Fatalf("Marshal() failed: %v", err)
+		}
+		hash, err := cell.Hash256()
+		if err != nil {
+			t.Fatalf("Hash256() failed: %v", err)
+		}
+		cells = append([]*boc.Cell{cell}, cells...)
+		lts = append([]uint64{lt}, lts...)
+		prevLt, prevHash = lt, hash
+	}
+	recent := &historyHandler{head: 100, minLt: 55, txs: cells, lts: lts}
+	archive := &historyHandler{head: 3, txs: cells, lts: lts}
+	client, err := NewClient(
+		WithLiteServers([]config.LiteServer{startTestLiteServer(t, recent), startTestLiteServer(t, archive)}),
+		WithMaxConnectionsNumber(2),
+		WithDetectArchiveNodes(),
+		WithProofPolicy(ProofPolicyFast),
+	)
+	if err != nil {
+		t.Fatalf("NewClient() failed: %v", err)
+	}
+	deadline := time.Now().Add(30 * time.Second)
+	for {
+		// wait for the archive node to be detected and the recent node to become the best one.
+		_, _, err := client.pool.BestArchiveClient(context.Background())
+		_, head, _ := client.pool.BestMasterchainClient(context.Background())
+		if err == nil && head.Seqno == recent.head {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("archive node is not detected")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	history := func(opts AccountHistoryOptions) ([]uint64, error) {
+		opts.Lt, opts.Hash = prevLt, prevHash
+		sub := client.AccountHistory(context.Background(), accountID, opts)
+		var got []uint64
+		for tx := range sub.Events() {
+			got = append(got, tx.Lt)
+		}
+		return got, sub.Err()
+	}
+
+	got, err := history(AccountHistoryOptions{PageSize: 3})
+	if err != nil {
+		t.Fatalf("AccountHistory() failed: %v", err)
+	}
+	if want := []uint64{100, 90, 80, 70, 60, 50, 40, 30, 20, 10}; !equalLts(got, want) {
+		t.Fatalf("want lts: %v, got: %v", want, got)
+	}
+	if recent.requestsNumber() == 0 || archive.requestsNumber() == 0 {
+		t.Fatalf("want requests to both nodes, got: %v and %v", recent.requestsNumber(), archive.requestsNumber())
+	}
+
+	got, err = history(AccountHistoryOptions{MaxLt: 80, MinUtime: 60})
+	if err != nil {
+		t.Fatalf("AccountHistory() failed: %v", err)
+	}
+	if want := []uint64{80, 70, 60}; !equalLts(got, want) {
+		t.Fatalf("want lts: %v, got: %v", want, got)
+	}
+}
+
+func equalLts(a, b []uint64) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,438 @@
+package liteapi
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"math/bits"
+	"sync"
+
+	"github.com/tonkeeper/tongo/boc"
+	"github.com/tonkeeper/tongo/liteclient"
+	"github.com/tonkeeper/tongo/tlb"
+	"github.com/tonkeeper/tongo/ton"
+)
+
+const (
+	magicTonBlockID           = 0xc50b6e70 // crc32(ton.blockId root_cell_hash:int256 file_hash:int256 = ton.BlockId)
+	magicPubKeyEd25519        = 0x4813b4c6 // crc32(pub.ed25519 key:int256 = PublicKey)
+	magicValidatorSet         = 0x901660ed // crc32(test0.validatorSet catchain_seqno:int validators:vector test0.validatorSetItem = test0.ValidatorSet)
+	masterchainShard   uint64 = 0x8000000000000000
+)
+
+// blockHeaderProof is a part of a block which is left unpruned in a block header proof.
+type blockHeaderProof struct {
+	Magic    tlb.Magic `tlb:"block#11ef55aa"`
+	GlobalId int32
+	Info     struct {
+		Magic tlb.Magic `tlb:"block_info#9bc7a987"`
+		Info  tlb.BlockInfoPart
+	} `tlb:"^"`
+}
+
+// keyBlockConfigProof is a part of a key block which is left unpruned in a config proof.
+type keyBlockConfigProof struct {
+	Magic       tlb.Magic `tlb:"block#11ef55aa"`
+	GlobalId    int32
+	Info        boc.Cell `tlb:"^"`
+	ValueFlow   boc.Cell `tlb:"^"`
+	StateUpdate boc.Cell `tlb:"^"`
+	Extra       struct {
+		Magic         tlb.Magic `tlb:"block_extra#4a33f6fd"`
+		InMsgDescr    boc.Cell  `tlb:"^"`
+		OutMsgDescr   boc.Cell  `tlb:"^"`
+		AccountBlocks boc.Cell  `tlb:"^"`
+		RandSeed      tlb.Bits256
+		CreatedBy     tlb.Bits256
+		Custom        tlb.Maybe[tlb.Ref[tlb.McBlockExtra]]
+	} `tlb:"^"`
+}
+
+// checkBlockHeaderProof checks that the given proof is a proof of the block's header and returns the header.
+func checkBlockHeaderProof(proof []byte, blockID ton.BlockIDExt) (tlb.BlockInfoPart, error) {
+	header, err := decodeMerkleProofOf[blockHeaderProof](proof, blockID.RootHash)
+	if err != nil {
+		return tlb.BlockInfoPart{}, fmt.Errorf("block header proof is not for block %v: %w", blockID.BlockID, err)
+	}
+	info := header.Info.Info
+	if info.SeqNo != blockID.Seqno || info.Shard.WorkchainID != blockID.Workchain {
+		return tlb.BlockInfoPart{}, fmt.Errorf("block header doesn't match block %v", blockID.BlockID)
+	}
+	return info, nil
+}
+
+// checkKeyBlockConfigProof checks that the given proof is a proof of the key block's config and returns the config.
+func checkKeyBlockConfigProof(proof []byte, blockID ton.BlockIDExt) (*ton.BlockchainConfig, error) {
+	block, err := decodeMerkleProofOf[keyBlockConfigProof](proof, blockID.RootHash)
+	if err != nil {
+		return nil, fmt.Errorf("config proof is not for block %v: %w", blockID.BlockID, err)
+	}
+	if !block.Extra.Custom.Exists || !block.Extra.Custom.Value.Value.KeyBlock {
+		return nil, fmt.Errorf("block %v is not a key block", blockID.BlockID)
+	}
+	config, _, err := ton.ConvertBlockchainConfig(block.Extra.Custom.Value.Value.Config, true)
+	if err != nil {
+		return nil, err
+	}
+	if config.ConfigParam34 == nil {
+		return nil, fmt.Errorf("key block %v has no current validator set", blockID.BlockID)
+	}
+	return config, nil
+}
+
+// checkBlockProofChain checks each link of the given chain
+// and returns the last block of the chain and the last key block met in the chain if any.
+func checkBlockProofChain(proof liteclient.LiteServerPartialBlockProofC, from ton.BlockIDExt) (ton.BlockIDExt, *ton.BlockIDExt, error) {
+	if proof.From.ToBlockIdExt() != from {
+		return ton.BlockIDExt{}, nil, fmt.Errorf("block proof chain starts from unexpected block %v", proof.From.ToBlockIdExt().BlockID)
+	}
+	current := from
+	var keyBlock *ton.BlockIDExt
+	for _, step := range proof.Steps {
+		var (
+			to         ton.BlockIDExt
+			toKeyBlock bool
+			err        error
+		)
+		switch step.SumType {
+		case "LiteServerBlockLinkBack":
+			link := step.LiteServerBlockLinkBack
+			to, toKeyBlock = link.To.ToBlockIdExt(), link.ToKeyBlock
+			if link.From.ToBlockIdExt() != current {
+				return ton.BlockIDExt{}, nil, fmt.Errorf("block proof link starts from unexpected block %v", link.From.ToBlockIdExt().BlockID)
+			}
+			err = checkBackwardLink(current, to, toKeyBlock, link.Proof, link.StateProof, link.DestProof)
+		case "LiteServerBlockLinkForward":
+			link := step.LiteServerBlockLinkForward
+			to, toKeyBlock = link.To.ToBlockIdExt(), link.ToKeyBlock
+			if link.From.ToBlockIdExt() != current {
+				return ton.BlockIDExt{}, nil, fmt.Errorf("block proof link starts from unexpected block %v", link.From.ToBlockIdExt().BlockID)
+			}
+			err = checkForwardLink(current, to, toKeyBlock, link.ConfigProof, link.DestProof, liteclient.LiteServerSignatureSetC(link.Signatures))
+		default:
+			return ton.BlockIDExt{}, nil, fmt.Errorf("unknown block link type: %v", step.SumType)
+		}
+		if err != nil {
+			return ton.BlockIDExt{}, nil, fmt.Errorf("invalid link from %v to %v: %w", current.BlockID, to.BlockID, err)
+		}
+		if toKeyBlock {
+			keyBlock = &to
+		}
+		current = to
+	}
+	if current != proof.To.ToBlockIdExt() {
+		return ton.BlockIDExt{}, nil, fmt.Errorf("block proof chain ends at unexpected block %v", current.BlockID)
+	}
+	return current, keyBlock, nil
+}
+
+// checkBackwardLink checks that "to" is an ancestor of "from" using the previous blocks dictionary of the "from" state.
+func checkBackwardLink(from, to ton.BlockIDExt, toKeyBlock bool, blockProof, stateProof, destProof []byte) error {
+	if from.Workchain != -1 || to.Workchain != -1 {
+		return fmt.Errorf("both blocks must be masterchain blocks")
+	}
+	if to.Seqno >= from.Seqno {
+		return fmt.Errorf("backward link must decrease seqno")
+	}
+	state, err := checkStateProof(from, blockProof, stateProof)
+	if err != nil {
+		return err
+	}
+	if !state.ShardStateUnsplit.Custom.Exists {
+		return fmt.Errorf("not a masterchain state")
+	}
+	prevBlocks := state.ShardStateUnsplit.Custom.Value.Value.Other.PrevBlocks
+	found := false
+	for i, key := range prevBlocks.Keys() {
+		if uint32(key) != to.Seqno {
+			continue
+		}
+		ref := prevBlocks.Values()[i]
+		if ton.Bits256(ref.BlkRef.RootHash) != to.RootHash || ton.Bits256(ref.BlkRef.FileHash) != to.FileHash {
+			return fmt.Errorf("previous block hash mismatch")
+		}
+		if toKeyBlock && !ref.Key {
+			return fmt.Errorf("previous block is not a key block")
+		}
+		found = true
+		break
+	}
+	if !found {
+		return fmt.Errorf("previous block not found in state proof")
+	}
+	if len(destProof) > 0 {
+		info, err := checkBlockHeaderProof(destProof, to)
+		if err != nil {
+			return err
+		}
+		if toKeyBlock && !info.KeyBlock {
+			return fmt.Errorf("block is not a key block")
+		}
+	}
+	return nil
+}
+
+// checkForwardLink checks that "to" is signed by validators of the "from" key block.
+func checkForwardLink(from, to ton.BlockIDExt, toKeyBlock bool, configProof, destProof []byte, signatures liteclient.LiteServerSignatureSetC) error {
+	if from.Workchain != -1 || to.Workchain != -1 {
+		return fmt.Errorf("both blocks must be masterchain blocks")
+	}
+	if to.Seqno <= from.Seqno {
+		return fmt.Errorf("forward link must increase seqno")
+	}
+	config, err := checkKeyBlockConfigProof(configProof, from)
+	if err != nil {
+		return err
+	}
+	info, err := checkBlockHeaderProof(destProof, to)
+	if err != nil {
+		return err
+	}
+	if info.KeyBlock != toKeyBlock {
+		return fmt.Errorf("key block flag mismatch")
+	}
+	if info.GenCatchainSeqno != signatures.CatchainSeqno {
+		return fmt.Errorf("catchain seqno mismatch")
+	}
+	shuffle := false
+	if config.ConfigParam28 != nil && config.ConfigParam28.CatchainConfig.SumType == "CatchainConfigNew" {
+		shuffle = config.ConfigParam28.CatchainConfig.CatchainConfigNew.ShuffleMcValidators
+	}
+	validators := masterchainValidators(config.ConfigParam34.CurValidators, info.GenCatchainSeqno, shuffle)
+	hash := validatorSetHash(validators, info.GenCatchainSeqno)
+	if hash != signatures.ValidatorSetHash || hash != info.GenValidatorListHashShort {
+		return fmt.Errorf("validator set hash mismatch")
+	}
+	return checkBlockSignatures(to, validators, signatures.Signatures)
+}
+
+// masterchainValidators returns a subset of validators responsible for masterchain blocks.
+// The algorithm is taken from ton-blockchain/ton:
+// https://github.com/ton-blockchain/ton/blob/v2023.06/crypto/block/mc-config.cpp#L1754
+func masterchainValidators(set tlb.ValidatorSet, catchainSeqno uint32, shuffle bool) []tlb.ValidatorDescr {
+	list := set.List()
+	count := int(set.Main())
+	if count > len(list) {
+		count = len(list)
+	}
+	if !shuffle {
+		return list[:count]
+	}
+	prng := newValidatorSetPRNG(-1, masterchainShard, catchainSeqno)
+	idx := make([]int, count)
+	for i := 0; i < count; i++ {
+		j := int(prng.nextRanged(uint64(i + 1)))
+		idx[i] = idx[j]
+		idx[j] = i
+	}
+	validators := make([]tlb.ValidatorDescr, 0, count)
+	for _, i := range idx {
+		validators = append(validators, list[i])
+	}
+	return validators
+}
+
+// validatorSetPRNG is a pseudo-random generator used to shuffle validators.
+type validatorSetPRNG struct {
+	// data contains seed:bits256 shard:int64 workchain:int32 cc_seqno:uint32 in big-endian.
+	data [48]byte
+	hash [64]byte
+	pos  int
+}
+
+func newValidatorSetPRNG(workchain int32, shard uint64, catchainSeqno uint32) *validatorSetPRNG {
+	prng := validatorSetPRNG{pos: 8}
+	binary.BigEndian.PutUint64(prng.data[32:], shard)
+	binary.BigEndian.PutUint32(prng.data[40:], uint32(workchain))
+	binary.BigEndian.PutUint32(prng.data[44:], catchainSeqno)
+	return &prng
+}
+
+func (p *validatorSetPRNG) nextUint64() uint64 {
+	if p.pos == 8 {
+		p.hash = sha512.Sum512(p.data[:])
+		for i := 31; i >= 0; i-- {
+			p.data[i]++
+			if p.data[i] != 0 {
+				break
+			}
+		}
+		p.pos = 0
+	}
+	value := binary.BigEndian.Uint64(p.hash[p.pos*8:])
+	p.pos++
+	return value
+}
+
+// nextRanged returns a pseudo-random number in [0, n).
+func (p *validatorSetPRNG) nextRanged(n uint64) uint64 {
+	hi, _ := bits.Mul64(n, p.nextUint64())
+	return hi
+}
+
+// validatorSetHash returns a short hash of the masterchain validators which signs blocks.
+func validatorSetHash(validators []tlb.ValidatorDescr, catchainSeqno uint32) uint32 {
+	var buf bytes.Buffer
+	_ = binary.Write(&buf, binary.LittleEndian, uint32(magicValidatorSet))
+	_ = binary.Write(&buf, binary.LittleEndian, catchainSeqno)
+	_ = binary.Write(&buf, binary.LittleEndian, uint32(len(validators)))
+	for _, v := range validators {
+		pubKey := v.PubKey()
+		adnl := v.AdnlAddr()
+		buf.Write(pubKey[:])
+		_ = binary.Write(&buf, binary.LittleEndian, v.Weight())
+		buf.Write(adnl[:])
+	}
+	return crc32.Checksum(buf.Bytes(), crc32.MakeTable(crc32.Castagnoli))
+}
+
+// validatorNodeID returns a short node ID of the validator which is a hash of its TL-serialized public key.
+func validatorNodeID(pubKey tlb.Bits256) ton.Bits256 {
+	b := make([]byte, 4, 36)
+	binary.LittleEndian.PutUint32(b, magicPubKeyEd25519)
+	return sha256.Sum256(append(b, pubKey[:]...))
+}
+
+// checkBlockSignatures checks that validators holding more than 2/3 of the total weight signed the block.
+func checkBlockSignatures(blockID ton.BlockIDExt, validators []tlb.ValidatorDescr, signatures []liteclient.LiteServerSignatureC) error {
+	message := make([]byte, 4, 68)
+	binary.LittleEndian.PutUint32(message, magicTonBlockID)
+	message = append(message, blockID.RootHash[:]...)
+	message = append(message, blockID.FileHash[:]...)
+
+	nodes := make(map[ton.Bits256]int, len(validators))
+	var totalWeight uint64
+	for i, v := range validators {
+		nodes[validatorNodeID(v.PubKey())] = i
+		totalWeight += v.Weight()
+	}
+	signed := make(map[int]struct{}, len(signatures))
+	var signedWeight uint64
+	for _, signature := range signatures {
+		i, ok := nodes[ton.Bits256(signature.NodeIdShort)]
+		if !ok {
+			return fmt.Errorf("signature of unknown validator %x", signature.NodeIdShort)
+		}
+		if _, ok := signed[i]; ok {
+			return fmt.Errorf("duplicated signature of validator %x", signature.NodeIdShort)
+		}
+		pubKey := validators[i].PubKey()
+		if !ed25519.Verify(pubKey[:], message, signature.Signature) {
+			return fmt.Errorf("invalid signature of validator %x", signature.NodeIdShort)
+		}
+		signed[i] = struct{}{}
+		signedWeight += validators[i].Weight()
+	}
+	// signedWeight * 3 > totalWeight * 2
+	signedHi, signedLo := bits.Mul64(signedWeight, 3)
+	totalHi, totalLo := bits.Mul64(totalWeight, 2)
+	if signedHi < totalHi || (signedHi == totalHi && signedLo <= totalLo) {
+		return fmt.Errorf("insufficient total weight of signatures")
+	}
+	return nil
+}
+
+// maxVerifiedBlocks is a maximum number of verified masterchain blocks remembered by masterchainVerifier.
+const maxVerifiedBlocks = 1024
+
+// masterchainVerifier keeps track of trusted masterchain blocks.
+// It starts from a trusted key block and walks block proof chains
+// to check that a masterchain block belongs to the same chain.
+type masterchainVerifier struct {
+	mu sync.Mutex
+	// keyBlock is the latest trusted key block.
+	keyBlock ton.BlockIDExt
+	// last is the latest trusted masterchain block.
+	last ton.BlockIDExt
+	// verified contains recently verified blocks, at most maxVerifiedBlocks.
+	verified map[ton.BlockIDExt]struct{}
+	// order is a ring buffer of verified blocks used to evict the oldest one.
+	order []ton.BlockIDExt
+	next  int
+}
+
+func newMasterchainVerifier(trustedBlock ton.BlockIDExt) *masterchainVerifier {
+	return &masterchainVerifier{
+		keyBlock: trustedBlock,
+		last:     trustedBlock,
+		verified: make(map[ton.BlockIDExt]struct{}, maxVerifiedBlocks),
+		order:    make([]ton.BlockIDExt, 0, maxVerifiedBlocks),
+	}
+}
+
+func (v *masterchainVerifier) isTrusted(blockID ton.BlockIDExt) bool {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if blockID == v.last || blockID == v.keyBlock {
+		return true
+	}
+	_, ok := v.verified[blockID]
+	return ok
+}
+
+func (v *masterchainVerifier) startBlock() ton.BlockIDExt {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	return v.keyBlock
+}
+
+func (v *masterchainVerifier) update(keyBlock *ton.BlockIDExt, last ton.BlockIDExt) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if keyBlock != nil && keyBlock.Seqno > v.keyBlock.Seqno {
+		v.keyBlock = *keyBlock
+	}
+	if last.Seqno > v.last.Seqno {
+		v.last = last
+	}
+	v.remember(last)
+}
+
+// remember adds the block to the set of verified blocks evicting the oldest one if the set is full.
+func (v *masterchainVerifier) remember(blockID ton.BlockIDExt) {
+	if _, ok := v.verified[blockID]; ok {
+		return
+	}
+	if len(v.order) < maxVerifiedBlocks {
+		v.order = append(v.order, blockID)
+	} else {
+		delete(v.verified, v.order[v.next])
+		v.order[v.next] = blockID
+		v.next = (v.next + 1) % maxVerifiedBlocks
+	}
+	v.verified[blockID] = struct{}{}
+}
+
+// verify checks that the given masterchain block belongs to the trusted chain.
+func (v *masterchainVerifier) verify(ctx context.Context, c *Client, blockID ton.BlockIDExt) error {
+	if blockID.Workchain != -1 {
+		return fmt.Errorf("block %v is not a masterchain block", blockID.BlockID)
+	}
+	if v.isTrusted(blockID) {
+		return nil
+	}
+	known := v.startBlock()
+	for {
+		proof, err := c.GetBlockProofRaw(ctx, known, &blockID)
+		if err != nil {
+			return err
+		}
+		last, keyBlock, err := checkBlockProofChain(proof, known)
+		if err != nil {
+			return err
+		}
+		v.update(keyBlock, last)
+		if last == blockID {
+			return nil
+		}
+		if proof.Complete || last == known {
+			return fmt.Errorf("block proof chain doesn't reach block %v", blockID.BlockID)
+		}
+		known = last
+	}
+}
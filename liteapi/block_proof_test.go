@@ -0,0 +1,229 @@
+package liteapi
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"encoding/binary"
+	"os"
+	"testing"
+
+	"github.com/tonkeeper/tongo/liteclient"
+	"github.com/tonkeeper/tongo/tl"
+	"github.com/tonkeeper/tongo/tlb"
+	"github.com/tonkeeper/tongo/ton"
+)
+
+func newTestValidatorSet(weights []uint64, main uint16) (tlb.ValidatorSet, []ed25519.PrivateKey) {
+	keys := make([]tlb.Uint16, 0, len(weights))
+	validators := make([]tlb.ValidatorDescr, 0, len(weights))
+	privateKeys := make([]ed25519.PrivateKey, 0, len(weights))
+	var totalWeight uint64
+	for i, weight := range weights {
+		seed := make([]byte, ed25519.SeedSize)
+		seed[0] = byte(i + 1)
+		privateKey := ed25519.NewKeyFromSeed(seed)
+		var pubKey tlb.Bits256
+		copy(pubKey[:], privateKey.Public().(ed25519.PublicKey))
+		v := tlb.ValidatorDescr{SumType: "Validator"}
+		v.Validator = &struct {
+			PublicKey tlb.SigPubKey
+			Weight    uint64
+		}{PublicKey: tlb.SigPubKey{PubKey: pubKey}, Weight: weight}
+		keys = append(keys, tlb.Uint16(i))
+		validators = append(validators, v)
+		privateKeys = append(privateKeys, privateKey)
+		totalWeight += weight
+	}
+	set := tlb.ValidatorSet{SumType: "ValidatorsExt"}
+	set.ValidatorsExt.Total = uint16(len(weights))
+	set.ValidatorsExt.Main = main
+	set.ValidatorsExt.TotalWeight = totalWeight
+	set.ValidatorsExt.List = tlb.NewHashmapE(keys, validators)
+	return set, privateKeys
+}
+
+func signBlock(blockID ton.BlockIDExt, validator tlb.ValidatorDescr, privateKey ed25519.PrivateKey) liteclient.LiteServerSignatureC {
+	message := make([]byte, 4, 68)
+	binary.LittleEndian.PutUint32(message, magicTonBlockID)
+	message = append(message, blockID.RootHash[:]...)
+	message = append(message, blockID.FileHash[:]...)
+	return liteclient.LiteServerSignatureC{
+		NodeIdShort: tl.Int256(validatorNodeID(validator.PubKey())),
+		Signature:   ed25519.Sign(privateKey, message),
+	}
+}
+
+func Test_checkBlockSignatures(t *testing.T) {
+	set, privateKeys := newTestValidatorSet([]uint64{10, 20, 30, 60}, 4)
+	validators := set.List()
+	blockID := ton.BlockIDExt{
+		BlockID:  ton.BlockID{Workchain: -1, Shard: masterchainShard, Seqno: 100},
+		RootHash: ton.Bits256{1, 2, 3},
+		FileHash: ton.Bits256{4, 5, 6},
+	}
+	sign := func(indexes ...int) []liteclient.LiteServerSignatureC {
+		var signatures []liteclient.LiteServerSignatureC
+		for _, i := range indexes {
+			signatures = append(signatures, signBlock(blockID, validators[i], privateKeys[i]))
+		}
+		return signatures
+	}
+	forged := sign(3, 2)
+	forged[1].Signature = sign(1)[0].Signature
+
+	tests := []struct {
+		name       string
+		signatures []liteclient.LiteServerSignatureC
+		wantErr    bool
+	}{
+		{
+			name:       "all validators",
+			signatures: sign(0, 1, 2, 3),
+		},
+		{
+			name:       "more than 2/3",
+			signatures: sign(3, 2),
+		},
+		{
+			name:       "exactly 2/3 is not enough",
+			signatures: sign(3, 1),
+			wantErr:    true,
+		},
+		{
+			name:       "less than 2/3",
+			signatures: sign(0, 1, 2),
+			wantErr:    true,
+		},
+		{
+			name:       "duplicated signatures",
+			signatures: sign(3, 3, 2),
+			wantErr:    true,
+		},
+		{
+			name:       "forged signature",
+			signatures: forged,
+			wantErr:    true,
+		},
+		{
+			name: "unknown validator",
+			signatures: append(sign(3, 2), liteclient.LiteServerSignatureC{
+				NodeIdShort: tl.Int256{1},
+			}),
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkBlockSignatures(blockID, validators, tt.signatures)
+			if tt.wantErr != (err != nil) {
+				t.Fatalf("want error: %v, got: %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func Test_masterchainValidators(t *testing.T) {
+	set, _ := newTestValidatorSet([]uint64{1, 2, 3, 4, 5, 6, 7, 8}, 5)
+	list := set.List()
+
+	validators := masterchainValidators(set, 10, false)
+	if len(validators) != 5 {
+		t.Fatalf("want 5 validators, got %v", len(validators))
+	}
+	for i, v := range validators {
+		if v.PubKey() != list[i].PubKey() {
+			t.Fatalf("validator %v mismatch", i)
+		}
+	}
+
+	shuffled := masterchainValidators(set, 10, true)
+	if len(shuffled) != 5 {
+		t.Fatalf("want 5 validators, got %v", len(shuffled))
+	}
+	seen := map[tlb.Bits256]struct{}{}
+	for _, v := range shuffled {
+		seen[v.PubKey()] = struct{}{}
+	}
+	for _, v := range validators {
+		if _, ok := seen[v.PubKey()]; !ok {
+			t.Fatalf("shuffled validators must be a permutation of main validators")
+		}
+	}
+	again := masterchainValidators(set, 10, true)
+	for i := range shuffled {
+		if shuffled[i].PubKey() != again[i].PubKey() {
+			t.Fatalf("shuffle must be deterministic")
+		}
+	}
+	if validatorSetHash(shuffled, 10) != validatorSetHash(again, 10) {
+		t.Fatalf("validator set hash must be deterministic")
+	}
+	if validatorSetHash(shuffled, 10) == validatorSetHash(shuffled, 11) {
+		t.Fatalf("validator set hash must depend on catchain seqno")
+	}
+}
+
+func Test_masterchainValidators_mainnet(t *testing.T) {
+	// liteServer.configInfo of mainnet block 26309435 with a state proof and all config params.
+	data, err := os.ReadFile("../ton/testdata/get-last-config-all-2.bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var res liteclient.LiteServerConfigInfoC
+	if err := tl.Unmarshal(bytes.NewReader(data[4:]), &res); err != nil {
+		t.Fatal(err)
+	}
+	info, err := checkBlockHeaderProof(res.StateProof, res.Id.ToBlockIdExt())
+	if err != nil {
+		t.Fatalf("checkBlockHeaderProof() failed: %v", err)
+	}
+	params, err := ton.DecodeConfigParams(res.ConfigProof)
+	if err != nil {
+		t.Fatal(err)
+	}
+	config, _, err := ton.ConvertBlockchainConfig(params, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !config.ConfigParam28.CatchainConfig.CatchainConfigNew.ShuffleMcValidators {
+		t.Fatalf("mainnet shuffles masterchain validators")
+	}
+	validators := masterchainValidators(config.ConfigParam34.CurValidators, info.GenCatchainSeqno, true)
+	if len(validators) != 100 {
+		t.Fatalf("want 100 validators, got %v", len(validators))
+	}
+	if hash := validatorSetHash(validators, info.GenCatchainSeqno); hash != info.GenValidatorListHashShort {
+		t.Fatalf("want validator set hash %v, got %v", info.GenValidatorListHashShort, hash)
+	}
+	unshuffled := masterchainValidators(config.ConfigParam34.CurValidators, info.GenCatchainSeqno, false)
+	if validatorSetHash(unshuffled, info.GenCatchainSeqno) == info.GenValidatorListHashShort {
+		t.Fatalf("validator set hash must depend on the order of validators")
+	}
+}
+
+func Test_masterchainVerifier_isTrusted(t *testing.T) {
+	block := func(seqno uint32) ton.BlockIDExt {
+		return ton.BlockIDExt{BlockID: ton.BlockID{Workchain: -1, Shard: masterchainShard, Seqno: seqno}}
+	}
+	v := newMasterchainVerifier(block(1))
+	for i := uint32(2); i < maxVerifiedBlocks+10; i++ {
+		v.update(nil, block(i))
+	}
+	if len(v.verified) != maxVerifiedBlocks {
+		t.Fatalf("want %v verified blocks, got %v", maxVerifiedBlocks, len(v.verified))
+	}
+	if !v.isTrusted(block(1)) {
+		t.Fatalf("trusted key block must stay trusted")
+	}
+	if v.isTrusted(block(5)) {
+		t.Fatalf("the oldest verified blocks must be evicted")
+	}
+	for _, seqno := range []uint32{20, maxVerifiedBlocks, maxVerifiedBlocks + 9} {
+		if !v.isTrusted(block(seqno)) {
+			t.Fatalf("block %v must be trusted", seqno)
+		}
+	}
+	if v.isTrusted(block(maxVerifiedBlocks + 10)) {
+		t.Fatalf("unknown block must not be trusted")
+	}
+}
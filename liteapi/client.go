@@ -44,6 +44,12 @@ const (
 	// ProofPolicyUnsafe disables proof checks.
 	ProofPolicyUnsafe ProofPolicy = iota
 	ProofPolicyFast
+	// ProofPolicySecure works as ProofPolicyFast and additionally checks that masterchain blocks
+	// belong to the chain started from a trusted key block.
+	// The client walks block proof links and checks validator signatures
+	// before it trusts a masterchain block returned by a lite server.
+	// A trusted block set with WithTrustedBlock takes precedence over the init block of a global config.
+	ProofPolicySecure
 )
 
 // Client provides a convenient way to interact with TON blockchain.
@@ -60,7 +66,8 @@ const (
 // 1. you obtain a master head with GetMasterchainInfo,
 // 2. you get an account state with GetAccountState,
 // the account state can be obtained from a block that is earlier in the blockchain than the master head you obtained at step 1.
-// To avoid this, you can use WithBlock() method to specify a target block for all requests.
+// To avoid this, you can use WithBlock() method to specify a target block for all requests
+// or Snapshot() to pin the current masterchain block.
 type Client struct {
 	pool *pool.ConnPool
 	// proofPolicy specifies a policy for proof checks.
@@ -70,6 +77,17 @@ type Client struct {
 	// the underlying connections pool maintains information about which nodes are archive nodes.
 	archiveDetectionEnabled bool
 
+	// verifier checks masterchain blocks if proofPolicy is ProofPolicySecure.
+	verifier *masterchainVerifier
+
+	// quorum is set by WithQuorum.
+	quorum *quorum
+
+	// cancel stops background goroutines started by NewClient.
+	cancel context.CancelFunc
+	// refreshDone is closed when the configuration refresh goroutine exits, it is nil if refresh is disabled.
+	refreshDone chan struct{}
+
 	// mu protects targetBlockID and networkGlobalID.
 	mu              sync.RWMutex
 	targetBlockID   *ton.BlockIDExt
@@ -87,12 +105,26 @@ type Options struct {
 	InitCtx context.Context
 	// ProofPolicy specifies a policy for proof checks.
 	ProofPolicy ProofPolicy
+	// TrustedBlock is a masterchain key block used as a starting point by ProofPolicySecure.
+	// If it is nil, it is filled with the init block of a global config.
+	TrustedBlock *ton.BlockIDExt
 	// DetectArchiveNodes specifies if a liteapi connection to a node
 	// should detect if its node is an archive node.
 	DetectArchiveNodes bool
 
 	SyncConnectionsInitialization bool
 	PoolStrategy                  pool.Strategy
+	// PoolPolicy specifies how the connections pool retries requests and ejects failing lite servers.
+	PoolPolicy pool.Policy
+	// Observer receives events of lite server connections and requests.
+	Observer pool.Observer
+	// ConfigurationUrl is a URL of the global configuration the lite servers are obtained from.
+	ConfigurationUrl string
+	// ConfigurationRefreshInterval specifies how often the configuration is downloaded again
+	// to update the lite servers. Zero disables refreshing.
+	ConfigurationRefreshInterval time.Duration
+	// liteServersFromEnv is true if the lite servers are taken from the LITE_SERVERS env variable.
+	liteServersFromEnv bool
 }
 
 type Option func(o *Options) error
@@ -136,6 +168,25 @@ func WithPoolStrategy(strategy pool.Strategy) Option {
 	}
 }
 
+// WithPoolPolicy specifies how the connections pool retries requests and ejects failing lite servers.
+// Only read-only requests are retried, SendMessage is sent once.
+func WithPoolPolicy(policy pool.Policy) Option {
+	return func(o *Options) error {
+		o.PoolPolicy = policy
+		return nil
+	}
+}
+
+// WithObserver specifies an observer to receive events of lite server connections and requests,
+// it can be used to wire the client to a logger or metrics.
+// Take a look at pool.NewSlogObserver.
+func WithObserver(observer pool.Observer) Option {
+	return func(o *Options) error {
+		o.Observer = observer
+		return nil
+	}
+}
+
 func WithTimeout(timeout time.Duration) Option {
 	return func(o *Options) error {
 		o.Timeout = timeout
@@ -150,6 +201,16 @@ func WithProofPolicy(policy ProofPolicy) Option {
 	}
 }
 
+// WithTrustedBlock specifies a masterchain key block used as a starting point by ProofPolicySecure.
+// The block is kept regardless of the order of options,
+// a global config's init block is used only if no trusted block is specified.
+func WithTrustedBlock(block ton.BlockIDExt) Option {
+	return func(o *Options) error {
+		o.TrustedBlock = &block
+		return nil
+	}
+}
+
 func WithDetectArchiveNodes() Option {
 	return func(o *Options) error {
 		o.DetectArchiveNodes = true
@@ -171,20 +232,9 @@ func WithInitializationContext(ctx context.Context) Option {
 func FromEnvsOrMainnet() Option {
 	return func(o *Options) error {
 		if value, ok := os.LookupEnv(LiteServerEnvName); ok {
-			servers, err := config.ParseLiteServersEnvVar(value)
-			if err != nil {
-				return err
-			}
-			o.LiteServers = servers
-			o.MaxConnections = len(servers)
-			return nil
-		}
-		file, err := downloadConfig("https://ton.org/global.config.json")
-		if err != nil {
-			return err
+			return setLiteServersFromEnv(value, o)
 		}
-		o.LiteServers = file.LiteServers
-		return nil
+		return setLiteServersFromURL("https://ton.org/global.config.json", o)
 	}
 }
 
@@ -193,20 +243,9 @@ func FromEnvsOrMainnet() Option {
 func FromEnvsOrTestnet() Option {
 	return func(o *Options) error {
 		if value, ok := os.LookupEnv(LiteServerEnvName); ok {
-			servers, err := config.ParseLiteServersEnvVar(value)
-			if err != nil {
-				return err
-			}
-			o.LiteServers = servers
-			o.MaxConnections = len(servers)
-			return nil
-		}
-		file, err := downloadConfig("https://ton.org/testnet-global.config.json")
-		if err != nil {
-			return err
+			return setLiteServersFromEnv(value, o)
 		}
-		o.LiteServers = file.LiteServers
-		return nil
+		return setLiteServersFromURL("https://ton.org/testnet-global.config.json", o)
 	}
 }
 
@@ -216,17 +255,24 @@ func FromEnvsOrTestnet() Option {
 func FromEnvs() Option {
 	return func(o *Options) error {
 		if value, ok := os.LookupEnv(LiteServerEnvName); ok {
-			servers, err := config.ParseLiteServersEnvVar(value)
-			if err != nil {
-				return err
-			}
-			o.LiteServers = servers
-			o.MaxConnections = len(servers)
+			return setLiteServersFromEnv(value, o)
 		}
 		return nil
 	}
 }
 
+func setLiteServersFromEnv(value string, o *Options) error {
+	servers, err := config.ParseLiteServersEnvVar(value)
+	if err != nil {
+		return err
+	}
+	o.LiteServers = servers
+	o.MaxConnections = len(servers)
+	o.ConfigurationUrl = ""
+	o.liteServersFromEnv = true
+	return nil
+}
+
 // Mainnet configures a client to use lite servers from the mainnet.
 func Mainnet() Option {
 	return func(o *Options) error {
@@ -248,9 +294,23 @@ func WithConfigurationUrl(url string) Option {
 	}
 }
 
+// WithConfigurationRefresh makes a client download the configuration again every interval
+// and replace its lite servers without restarting.
+// It works with a configuration set by WithConfigurationUrl, Mainnet, Testnet or FromEnvsOr* options.
+// Lite servers taken from the LITE_SERVERS env variable by FromEnvs or FromEnvsOr* options are fixed,
+// so they are kept as is and the configuration is not refreshed.
+// If the configuration can't be downloaded or none of its lite servers is available,
+// the client keeps using its current lite servers.
+func WithConfigurationRefresh(interval time.Duration) Option {
+	return func(o *Options) error {
+		o.ConfigurationRefreshInterval = interval
+		return nil
+	}
+}
+
 func WithConfigurationFile(file config.GlobalConfigurationFile) Option {
 	return func(o *Options) error {
-		o.LiteServers = file.LiteServers
+		setConfigurationFile(file, o)
 		return nil
 	}
 }
@@ -274,6 +334,8 @@ func NewClient(options ...Option) (*Client, error) {
 		DetectArchiveNodes:            false,
 		SyncConnectionsInitialization: true,
 		PoolStrategy:                  pool.BestPingStrategy,
+		PoolPolicy:                    pool.DefaultPolicy(),
+		Observer:                      pool.NopObserver{},
 		WorkersPerConnection:          1,
 	}
 	for _, o := range options {
@@ -284,7 +346,13 @@ func NewClient(options ...Option) (*Client, error) {
 	if len(opts.LiteServers) == 0 {
 		return nil, fmt.Errorf("server list empty")
 	}
-	connPool := pool.New(opts.PoolStrategy)
+	if opts.ProofPolicy == ProofPolicySecure && opts.TrustedBlock == nil {
+		return nil, fmt.Errorf("trusted block is required for secure proof policy")
+	}
+	if opts.ConfigurationRefreshInterval > 0 && opts.ConfigurationUrl == "" && !opts.liteServersFromEnv {
+		return nil, fmt.Errorf("configuration refresh requires a configuration url")
+	}
+	connPool := pool.New(opts.PoolStrategy, pool.WithPolicy(opts.PoolPolicy), pool.WithObserver(opts.Observer))
 	initCh := connPool.InitializeConnections(opts.InitCtx, opts.Timeout, opts.MaxConnections, opts.WorkersPerConnection, opts.DetectArchiveNodes, opts.LiteServers)
 	if opts.SyncConnectionsInitialization {
 		if err := <-initCh; err != nil {
@@ -296,10 +364,54 @@ func NewClient(options ...Option) (*Client, error) {
 		proofPolicy:             opts.ProofPolicy,
 		archiveDetectionEnabled: opts.DetectArchiveNodes,
 	}
-	go client.pool.Run(context.TODO())
+	if opts.ProofPolicy == ProofPolicySecure {
+		client.verifier = newMasterchainVerifier(*opts.TrustedBlock)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	client.cancel = cancel
+	go client.pool.Run(ctx)
+	if opts.ConfigurationRefreshInterval > 0 && opts.ConfigurationUrl != "" {
+		client.refreshDone = make(chan struct{})
+		go func() {
+			defer close(client.refreshDone)
+			client.refreshConfiguration(ctx, opts.ConfigurationUrl, opts.ConfigurationRefreshInterval, opts.Timeout)
+		}()
+	}
 	return &client, nil
 }
 
+// Close stops background work of the client and closes its connections to lite servers.
+// Clients derived from this one with WithBlock, WithQuorum or Snapshot share the connections and stop working too.
+func (c *Client) Close() {
+	if c.cancel != nil {
+		c.cancel()
+	}
+	if c.refreshDone != nil {
+		<-c.refreshDone
+	}
+	c.pool.Close()
+}
+
+// query calls f with a liteclient of the best connection and its known masterchain head.
+// f must be read-only because it is repeated on another connection
+// if the first one fails, see pool.Policy.
+// If the client is pinned to a block, only lite servers which know the block are used.
+func query[T any](ctx context.Context, c *Client, f func(client *liteclient.Client, masterHead ton.BlockIDExt) (T, error)) (T, error) {
+	return queryAt(ctx, c, c.targetBlockOr(ton.BlockIDExt{}).Seqno, f)
+}
+
+// queryAt works the same way as query but uses only lite servers
+// which know the masterchain block with the given seqno.
+func queryAt[T any](ctx context.Context, c *Client, seqno uint32, f func(client *liteclient.Client, masterHead ton.BlockIDExt) (T, error)) (T, error) {
+	var res T
+	err := c.pool.DoAt(ctx, pool.ReadRequest, seqno, func(client *liteclient.Client, masterHead ton.BlockIDExt) error {
+		var err error
+		res, err = f(client, masterHead)
+		return err
+	})
+	return res, err
+}
+
 func (c *Client) targetBlockOr(blockID ton.BlockIDExt) ton.BlockIDExt {
 	c.mu.RLock()
 	defer c.mu.RUnlock()
@@ -310,10 +422,60 @@ func (c *Client) targetBlockOr(blockID ton.BlockIDExt) ton.BlockIDExt {
 }
 
 func (c *Client) WithBlock(block ton.BlockIDExt) *Client {
+	cli := c.clone()
+	cli.targetBlockID = &block
+	return cli
+}
+
+// clone returns a copy of the client sharing its connections pool.
+func (c *Client) clone() *Client {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
 	return &Client{
-		pool:          c.pool,
-		targetBlockID: &block,
+		pool:                    c.pool,
+		proofPolicy:             c.proofPolicy,
+		archiveDetectionEnabled: c.archiveDetectionEnabled,
+		verifier:                c.verifier,
+		quorum:                  c.quorum,
+		cancel:                  c.cancel,
+		refreshDone:             c.refreshDone,
+		targetBlockID:           c.targetBlockID,
+		networkGlobalID:         c.networkGlobalID,
+	}
+}
+
+// VerifyMasterchainBlock checks that the given block belongs to the masterchain started from the trusted block.
+// It works only with ProofPolicySecure.
+func (c *Client) VerifyMasterchainBlock(ctx context.Context, blockID ton.BlockIDExt) error {
+	if c.verifier == nil {
+		return fmt.Errorf("masterchain verification requires secure proof policy")
+	}
+	return c.verifier.verify(ctx, c, blockID)
+}
+
+// verifyBlock checks that the given block belongs to the chain started from the trusted block
+// if the client uses ProofPolicySecure.
+// A shard block is checked through a chain of shard block links from a masterchain block.
+func (c *Client) verifyBlock(ctx context.Context, blockID ton.BlockIDExt) error {
+	if c.verifier == nil {
+		return nil
+	}
+	if blockID.Workchain == -1 {
+		return c.verifier.verify(ctx, c, blockID)
+	}
+	res, err := query(ctx, c, func(client *liteclient.Client, _ ton.BlockIDExt) (liteclient.LiteServerShardBlockProofC, error) {
+		return client.LiteServerGetShardBlockProof(ctx, liteclient.LiteServerGetShardBlockProofRequest{
+			Id: liteclient.BlockIDExt(blockID),
+		})
+	})
+	if err != nil {
+		return err
+	}
+	masterchainBlock, err := checkShardBlockProof(res, blockID)
+	if err != nil {
+		return err
 	}
+	return c.verifier.verify(ctx, c, masterchainBlock)
 }
 
 func (c *Client) GetMasterchainInfo(ctx context.Context) (liteclient.LiteServerMasterchainInfoC, error) {
@@ -321,7 +483,16 @@ func (c *Client) GetMasterchainInfo(ctx context.Context) (liteclient.LiteServerM
 	if conn == nil {
 		return liteclient.LiteServerMasterchainInfoC{}, pool.ErrNoConnections
 	}
-	return conn.LiteServerGetMasterchainInfo(ctx)
+	res, err := conn.LiteServerGetMasterchainInfo(ctx)
+	if err != nil {
+		return liteclient.LiteServerMasterchainInfoC{}, err
+	}
+	if c.verifier != nil {
+		if err := c.verifier.verify(ctx, c, res.Last.ToBlockIdExt()); err != nil {
+			return liteclient.LiteServerMasterchainInfoC{}, err
+		}
+	}
+	return res, nil
 }
 
 func (c *Client) GetMasterchainInfoExt(ctx context.Context, mode uint32) (liteclient.LiteServerMasterchainInfoExtC, error) {
@@ -329,26 +500,34 @@ func (c *Client) GetMasterchainInfoExt(ctx context.Context, mode uint32) (litecl
 	if conn == nil {
 		return liteclient.LiteServerMasterchainInfoExtC{}, pool.ErrNoConnections
 	}
-	return conn.LiteServerGetMasterchainInfoExt(ctx, liteclient.LiteServerGetMasterchainInfoExtRequest{Mode: mode})
+	res, err := conn.LiteServerGetMasterchainInfoExt(ctx, liteclient.LiteServerGetMasterchainInfoExtRequest{Mode: mode})
+	if err != nil {
+		return liteclient.LiteServerMasterchainInfoExtC{}, err
+	}
+	if c.verifier != nil {
+		if err := c.verifier.verify(ctx, c, res.Last.ToBlockIdExt()); err != nil {
+			return liteclient.LiteServerMasterchainInfoExtC{}, err
+		}
+	}
+	return res, nil
 }
 
 func (c *Client) GetTime(ctx context.Context) (uint32, error) {
-	client, _, err := c.pool.BestMasterchainClient(ctx)
-	if err != nil {
-		return 0, err
-	}
-	res, err := client.LiteServerGetTime(ctx)
+	res, err := query(ctx, c, func(client *liteclient.Client, _ ton.BlockIDExt) (liteclient.LiteServerCurrentTimeC, error) {
+		return client.LiteServerGetTime(ctx)
+	})
 	return res.Now, err
 }
 
 func (c *Client) GetVersion(ctx context.Context) (liteclient.LiteServerVersionC, error) {
-	client, _, err := c.pool.BestMasterchainClient(ctx)
-	if err != nil {
-		return liteclient.LiteServerVersionC{}, err
-	}
-	return client.LiteServerGetVersion(ctx)
+	return query(ctx, c, func(client *liteclient.Client, _ ton.BlockIDExt) (liteclient.LiteServerVersionC, error) {
+		return client.LiteServerGetVersion(ctx)
+	})
 }
 
+// GetBlock returns a block with the given id.
+// The block hash is checked unless the client uses ProofPolicyUnsafe.
+// With ProofPolicySecure the block is checked to belong to the trusted chain.
 func (c *Client) GetBlock(ctx context.Context, blockID ton.BlockIDExt) (tlb.Block, error) {
 	res, err := c.GetBlockRaw(ctx, blockID)
 	if err != nil {
@@ -379,40 +558,75 @@ func (c *Client) GetBlock(ctx context.Context, blockID ton.BlockIDExt) (tlb.Bloc
 	if !bytes.Equal(hash[:], blockID.RootHash[:]) {
 		return tlb.Block{}, fmt.Errorf("block hash mismatch")
 	}
+	if err := c.verifyBlock(ctx, blockID); err != nil {
+		return tlb.Block{}, err
+	}
 	return block, nil
 }
 
 func (c *Client) GetBlockRaw(ctx context.Context, blockID ton.BlockIDExt) (liteclient.LiteServerBlockDataC, error) {
-	client, err := c.pool.BestClientByBlockID(ctx, blockID.BlockID)
-	if err != nil {
-		return liteclient.LiteServerBlockDataC{}, err
-	}
-	res, err := client.LiteServerGetBlock(ctx, liteclient.LiteServerGetBlockRequest{liteclient.BlockIDExt(blockID)})
-	if err != nil {
-		return liteclient.LiteServerBlockDataC{}, err
-	}
-	return res, err
+	return query(ctx, c, func(client *liteclient.Client, _ ton.BlockIDExt) (liteclient.LiteServerBlockDataC, error) {
+		return client.LiteServerGetBlock(ctx, liteclient.LiteServerGetBlockRequest{liteclient.BlockIDExt(blockID)})
+	})
 }
 
+// GetState returns a raw shard state at the given block.
+// Use GetShardState to get a decoded one.
 func (c *Client) GetState(ctx context.Context, blockID ton.BlockIDExt) ([]byte, ton.Bits256, ton.Bits256, error) {
 	res, err := c.GetStateRaw(ctx, blockID)
 	if err != nil {
 		return nil, ton.Bits256{}, ton.Bits256{}, err
 	}
-	// TODO: implement state tlb decoding
 	return res.Data, ton.Bits256(res.RootHash), ton.Bits256(res.FileHash), nil
 }
 
-func (c *Client) GetStateRaw(ctx context.Context, blockID ton.BlockIDExt) (liteclient.LiteServerBlockStateC, error) {
-	client, err := c.pool.BestClientByBlockID(ctx, blockID.BlockID)
+// GetShardState returns a shard state at the given block.
+// Accounts of the state are decoded on demand with tlb.ShardAccounts,
+// so it is possible to walk through a huge state without decoding all accounts at once.
+// Unless the client uses ProofPolicyUnsafe, the state is checked against the state update of the block's header.
+func (c *Client) GetShardState(ctx context.Context, blockID ton.BlockIDExt) (tlb.LazyShardState, error) {
+	res, err := c.GetStateRaw(ctx, blockID)
 	if err != nil {
-		return liteclient.LiteServerBlockStateC{}, err
+		return tlb.LazyShardState{}, err
 	}
-	res, err := client.LiteServerGetState(ctx, liteclient.LiteServerGetStateRequest{Id: liteclient.BlockIDExt(blockID)})
+	cells, err := boc.DeserializeBoc(res.Data)
 	if err != nil {
-		return liteclient.LiteServerBlockStateC{}, err
+		return tlb.LazyShardState{}, err
 	}
-	return res, nil
+	if len(cells) != 1 {
+		return tlb.LazyShardState{}, boc.ErrNotSingleRoot
+	}
+	if c.proofPolicy != ProofPolicyUnsafe {
+		if res.Id.ToBlockIdExt() != blockID {
+			return tlb.LazyShardState{}, fmt.Errorf("state is for another block %v", res.Id.ToBlockIdExt().BlockID)
+		}
+		hash, err := cells[0].Hash256()
+		if err != nil {
+			return tlb.LazyShardState{}, fmt.Errorf("failed to calculate state hash: %w", err)
+		}
+		// the state update is a part of the header proof with mode 1.
+		header, err := c.GetBlockHeaderRaw(ctx, blockID, 1)
+		if err != nil {
+			return tlb.LazyShardState{}, err
+		}
+		if err := checkBlockStateHash(blockID, header.HeaderProof, ton.Bits256(hash)); err != nil {
+			return tlb.LazyShardState{}, err
+		}
+		if err := c.verifyBlock(ctx, blockID); err != nil {
+			return tlb.LazyShardState{}, err
+		}
+	}
+	var state tlb.LazyShardState
+	if err := tlb.NewDecoder().Unmarshal(cells[0], &state); err != nil {
+		return tlb.LazyShardState{}, err
+	}
+	return state, nil
+}
+
+func (c *Client) GetStateRaw(ctx context.Context, blockID ton.BlockIDExt) (liteclient.LiteServerBlockStateC, error) {
+	return query(ctx, c, func(client *liteclient.Client, _ ton.BlockIDExt) (liteclient.LiteServerBlockStateC, error) {
+		return client.LiteServerGetState(ctx, liteclient.LiteServerGetStateRequest{Id: liteclient.BlockIDExt(blockID)})
+	})
 }
 
 func (c *Client) GetBlockHeader(ctx context.Context, blockID ton.BlockIDExt, mode uint32) (tlb.BlockInfo, error) {
@@ -424,35 +638,32 @@ func (c *Client) GetBlockHeader(ctx context.Context, blockID ton.BlockIDExt, mod
 	return info, err
 }
 
+// getBlockInfo works the same way as GetBlockHeader and can be used as ton.BlockInfoGetter.
+func (c *Client) getBlockInfo(ctx context.Context, blockID ton.BlockIDExt) (tlb.BlockInfo, error) {
+	return c.GetBlockHeader(ctx, blockID, 0)
+}
+
 func (c *Client) GetBlockHeaderRaw(ctx context.Context, blockID ton.BlockIDExt, mode uint32) (liteclient.LiteServerBlockHeaderC, error) {
-	client, err := c.pool.BestClientByBlockID(ctx, blockID.BlockID)
-	if err != nil {
-		return liteclient.LiteServerBlockHeaderC{}, err
-	}
-	res, err := client.LiteServerGetBlockHeader(ctx, liteclient.LiteServerGetBlockHeaderRequest{
-		Id:   liteclient.BlockIDExt(blockID),
-		Mode: mode,
+	return query(ctx, c, func(client *liteclient.Client, _ ton.BlockIDExt) (liteclient.LiteServerBlockHeaderC, error) {
+		return client.LiteServerGetBlockHeader(ctx, liteclient.LiteServerGetBlockHeaderRequest{
+			Id:   liteclient.BlockIDExt(blockID),
+			Mode: mode,
+		})
 	})
-	if err != nil {
-		return liteclient.LiteServerBlockHeaderC{}, err
-	}
-	return res, nil
 }
 
 func (c *Client) LookupBlock(ctx context.Context, blockID ton.BlockID, mode uint32, lt *uint64, utime *uint32) (ton.BlockIDExt, tlb.BlockInfo, error) {
-	client, err := c.pool.BestClientByBlockID(ctx, blockID)
-	if err != nil {
-		return ton.BlockIDExt{}, tlb.BlockInfo{}, err
-	}
-	res, err := client.LiteServerLookupBlock(ctx, liteclient.LiteServerLookupBlockRequest{
-		Mode: mode,
-		Id: liteclient.TonNodeBlockIdC{
-			Workchain: uint32(blockID.Workchain),
-			Shard:     blockID.Shard,
-			Seqno:     blockID.Seqno,
-		},
-		Lt:    lt,
-		Utime: utime,
+	res, err := query(ctx, c, func(client *liteclient.Client, _ ton.BlockIDExt) (liteclient.LiteServerBlockHeaderC, error) {
+		return client.LiteServerLookupBlock(ctx, liteclient.LiteServerLookupBlockRequest{
+			Mode: mode,
+			Id: liteclient.TonNodeBlockIdC{
+				Workchain: uint32(blockID.Workchain),
+				Shard:     blockID.Shard,
+				Seqno:     blockID.Seqno,
+			},
+			Lt:    lt,
+			Utime: utime,
+		})
 	})
 	if err != nil {
 		return ton.BlockIDExt{}, tlb.BlockInfo{}, err
@@ -483,11 +694,12 @@ func (c *Client) SendMessage(ctx context.Context, payload []byte) (uint32, error
 	if err := VerifySendMessagePayload(payload); err != nil {
 		return 0, err
 	}
-	client, _, err := c.pool.BestMasterchainClient(ctx)
-	if err != nil {
-		return 0, err
-	}
-	res, err := client.LiteServerSendMessage(ctx, liteclient.LiteServerSendMessageRequest{Body: payload})
+	var res liteclient.LiteServerSendMsgStatusC
+	err := c.pool.Do(ctx, pool.SendRequest, false, func(client *liteclient.Client, _ ton.BlockIDExt) error {
+		var err error
+		res, err = client.LiteServerSendMessage(ctx, liteclient.LiteServerSendMessageRequest{Body: payload})
+		return err
+	})
 	return res.Status, err
 }
 
@@ -501,19 +713,19 @@ func (c *Client) RunSmcMethodByID(ctx context.Context, accountID ton.AccountID,
 	if err != nil {
 		return 0, tlb.VmStack{}, err
 	}
-	client, masterHead, err := c.pool.BestClientByAccountID(ctx, accountID, false)
+	res, err := query(ctx, c, func(client *liteclient.Client, masterHead ton.BlockIDExt) (liteclient.LiteServerRunMethodResultC, error) {
+		return client.LiteServerRunSmcMethod(ctx, liteclient.LiteServerRunSmcMethodRequest{
+			Mode:     4,
+			Id:       liteclient.BlockIDExt(c.targetBlockOr(masterHead)),
+			Account:  liteclient.AccountID(accountID),
+			MethodId: uint64(methodID),
+			Params:   b,
+		})
+	})
 	if err != nil {
 		return 0, tlb.VmStack{}, err
 	}
-	req := liteclient.LiteServerRunSmcMethodRequest{
-		Mode:     4,
-		Id:       liteclient.BlockIDExt(c.targetBlockOr(masterHead)),
-		Account:  liteclient.AccountID(accountID),
-		MethodId: uint64(methodID),
-		Params:   b,
-	}
-	res, err := client.LiteServerRunSmcMethod(ctx, req)
-	if err != nil {
+	if err := c.checkTargetBlock(res.Id.ToBlockIdExt()); err != nil {
 		return 0, tlb.VmStack{}, err
 	}
 	var result tlb.VmStack
@@ -545,6 +757,13 @@ func (c *Client) GetAccountState(ctx context.Context, accountID ton.AccountID) (
 	if err != nil {
 		return tlb.ShardAccount{}, err
 	}
+	var proof shardAccountProof
+	if c.proofPolicy != ProofPolicyUnsafe {
+		proof, err = checkAccountStateProof(accountID, res)
+		if err != nil {
+			return tlb.ShardAccount{}, err
+		}
+	}
 	if len(res.State) == 0 {
 		return tlb.ShardAccount{Account: tlb.Account{SumType: "AccountNone"}}, nil
 	}
@@ -560,23 +779,40 @@ func (c *Client) GetAccountState(ctx context.Context, accountID ton.AccountID) (
 	if err != nil {
 		return tlb.ShardAccount{}, err
 	}
+	if c.proofPolicy != ProofPolicyUnsafe {
+		return tlb.ShardAccount{Account: acc, LastTransHash: proof.LastTransHash, LastTransLt: proof.LastTransLt}, nil
+	}
 	lt, hash, err := decodeAccountDataFromProof(res.Proof, accountID)
 	return tlb.ShardAccount{Account: acc, LastTransHash: hash, LastTransLt: lt}, err
 }
 
 func (c *Client) GetAccountStateRaw(ctx context.Context, accountID ton.AccountID) (liteclient.LiteServerAccountStateC, error) {
-	client, masterHead, err := c.pool.BestClientByAccountID(ctx, accountID, false)
+	request := func(client *liteclient.Client, masterHead ton.BlockIDExt) (liteclient.LiteServerAccountStateC, error) {
+		return client.LiteServerGetAccountState(ctx, liteclient.LiteServerGetAccountStateRequest{
+			Account: liteclient.AccountID(accountID),
+			Id:      liteclient.BlockIDExt(c.targetBlockOr(masterHead)),
+		})
+	}
+	var (
+		res liteclient.LiteServerAccountStateC
+		err error
+	)
+	if c.quorum != nil {
+		res, err = quorumQuery(ctx, c, request, accountStateHash)
+	} else {
+		res, err = query(ctx, c, request)
+	}
 	if err != nil {
 		return liteclient.LiteServerAccountStateC{}, err
 	}
-	blockID := c.targetBlockOr(masterHead)
-	res, err := client.LiteServerGetAccountState(ctx, liteclient.LiteServerGetAccountStateRequest{
-		Account: liteclient.AccountID(accountID),
-		Id:      liteclient.BlockIDExt(blockID),
-	})
-	if err != nil {
+	if err := c.checkTargetBlock(res.Id.ToBlockIdExt()); err != nil {
 		return liteclient.LiteServerAccountStateC{}, err
 	}
+	if c.verifier != nil {
+		if err := c.verifier.verify(ctx, c, res.Id.ToBlockIdExt()); err != nil {
+			return liteclient.LiteServerAccountStateC{}, err
+		}
+	}
 	return res, nil
 }
 
@@ -620,20 +856,14 @@ func (c *Client) GetShardInfo(
 }
 
 func (c *Client) GetShardInfoRaw(ctx context.Context, blockID ton.BlockIDExt, workchain uint32, shard uint64, exact bool) (liteclient.LiteServerShardInfoC, error) {
-	client, _, err := c.pool.BestMasterchainClient(ctx)
-	if err != nil {
-		return liteclient.LiteServerShardInfoC{}, err
-	}
-	res, err := client.LiteServerGetShardInfo(ctx, liteclient.LiteServerGetShardInfoRequest{
-		Id:        liteclient.BlockIDExt(blockID),
-		Workchain: workchain,
-		Shard:     shard,
-		Exact:     exact,
+	return queryAt(ctx, c, c.requiredSeqno(blockID), func(client *liteclient.Client, _ ton.BlockIDExt) (liteclient.LiteServerShardInfoC, error) {
+		return client.LiteServerGetShardInfo(ctx, liteclient.LiteServerGetShardInfoRequest{
+			Id:        liteclient.BlockIDExt(blockID),
+			Workchain: workchain,
+			Shard:     shard,
+			Exact:     exact,
+		})
 	})
-	if err != nil {
-		return liteclient.LiteServerShardInfoC{}, err
-	}
-	return res, nil
 }
 
 func (c *Client) GetAllShardsInfo(ctx context.Context, blockID ton.BlockIDExt) ([]ton.BlockIDExt, error) {
@@ -663,33 +893,42 @@ func (c *Client) GetAllShardsInfo(ctx context.Context, blockID ton.BlockIDExt) (
 	return shards, nil
 }
 
-func (c *Client) GetAllShardsInfoRaw(ctx context.Context, blockID ton.BlockIDExt) (liteclient.LiteServerAllShardsInfoC, error) {
-	client, _, err := c.pool.BestMasterchainClient(ctx)
+// WalkShardBlocks returns shard blocks created after the masterchain block "from"
+// up to the masterchain block "to" including blocks created by splits and merges.
+// Each block goes after its parents.
+func (c *Client) WalkShardBlocks(ctx context.Context, from, to ton.BlockIDExt) ([]ton.BlockIDExt, error) {
+	fromShards, err := c.GetAllShardsInfo(ctx, from)
 	if err != nil {
-		return liteclient.LiteServerAllShardsInfoC{}, err
+		return nil, err
 	}
-	res, err := client.LiteServerGetAllShardsInfo(ctx, liteclient.LiteServerGetAllShardsInfoRequest{
-		Id: liteclient.BlockIDExt(blockID)})
+	toShards, err := c.GetAllShardsInfo(ctx, to)
 	if err != nil {
-		return liteclient.LiteServerAllShardsInfoC{}, err
+		return nil, err
 	}
-	return res, nil
+	return ton.WalkShardBlocks(ctx, fromShards, toShards, c.getBlockInfo)
+}
+
+func (c *Client) GetAllShardsInfoRaw(ctx context.Context, blockID ton.BlockIDExt) (liteclient.LiteServerAllShardsInfoC, error) {
+	return queryAt(ctx, c, c.requiredSeqno(blockID), func(client *liteclient.Client, _ ton.BlockIDExt) (liteclient.LiteServerAllShardsInfoC, error) {
+		return client.LiteServerGetAllShardsInfo(ctx, liteclient.LiteServerGetAllShardsInfoRequest{
+			Id: liteclient.BlockIDExt(blockID)})
+	})
 }
 
+// GetOneTransactionFromBlock returns a transaction of the given account with the given lt from the given block.
+// The transaction is checked against a proof of the block unless the client uses ProofPolicyUnsafe.
 func (c *Client) GetOneTransactionFromBlock(
 	ctx context.Context,
 	accountID ton.AccountID,
 	blockId ton.BlockIDExt,
 	lt uint64,
 ) (ton.Transaction, error) {
-	client, _, err := c.pool.BestClientByAccountID(ctx, accountID, false)
-	if err != nil {
-		return ton.Transaction{}, err
-	}
-	r, err := client.LiteServerGetOneTransaction(ctx, liteclient.LiteServerGetOneTransactionRequest{
-		Id:      liteclient.BlockIDExt(blockId),
-		Account: liteclient.AccountID(accountID),
-		Lt:      lt,
+	r, err := query(ctx, c, func(client *liteclient.Client, _ ton.BlockIDExt) (liteclient.LiteServerTransactionInfoC, error) {
+		return client.LiteServerGetOneTransaction(ctx, liteclient.LiteServerGetOneTransactionRequest{
+			Id:      liteclient.BlockIDExt(blockId),
+			Account: liteclient.AccountID(accountID),
+			Lt:      lt,
+		})
 	})
 	if err != nil {
 		return ton.Transaction{}, err
@@ -705,8 +944,26 @@ func (c *Client) GetOneTransactionFromBlock(
 		return ton.Transaction{}, boc.ErrNotSingleRoot
 	}
 	var t tlb.Transaction
-	err = tlb.Unmarshal(cells[0], &t)
-	return ton.Transaction{Transaction: t, BlockID: r.Id.ToBlockIdExt()}, err
+	if err := tlb.Unmarshal(cells[0], &t); err != nil {
+		return ton.Transaction{}, err
+	}
+	tx := ton.Transaction{Transaction: t, BlockID: r.Id.ToBlockIdExt()}
+	if c.proofPolicy == ProofPolicyUnsafe {
+		return tx, nil
+	}
+	if tx.BlockID != blockId {
+		return ton.Transaction{}, newProofError(ErrTransactionMismatch, "transaction is returned from another block %v", tx.BlockID.BlockID)
+	}
+	if t.AccountAddr != tlb.Bits256(accountID.Address) || t.Lt != lt {
+		return ton.Transaction{}, newProofError(ErrTransactionMismatch, "want transaction %v of %v, got %v of %x", lt, accountID, t.Lt, t.AccountAddr)
+	}
+	if err := checkBlockTransactionsProof(blockId, r.Proof, cells, []ton.Transaction{tx}); err != nil {
+		return ton.Transaction{}, err
+	}
+	if err := c.verifyBlock(ctx, blockId); err != nil {
+		return ton.Transaction{}, err
+	}
+	return tx, nil
 }
 
 func (c *Client) GetTransactions(
@@ -720,6 +977,12 @@ func (c *Client) GetTransactions(
 	if err != nil {
 		return nil, err
 	}
+	return c.decodeTransactions(r, accountID, lt, hash)
+}
+
+// decodeTransactions decodes a lite server's answer to a request of transactions
+// started from the transaction with the given lt and hash.
+func (c *Client) decodeTransactions(r liteclient.LiteServerTransactionListC, accountID ton.AccountID, lt uint64, hash ton.Bits256) ([]ton.Transaction, error) {
 	if len(r.Transactions) == 0 {
 		return []ton.Transaction{}, nil
 	}
@@ -727,7 +990,11 @@ func (c *Client) GetTransactions(
 	if err != nil {
 		return nil, err
 	}
+	if len(cells) != len(r.Ids) {
+		return nil, fmt.Errorf("got %v transactions but %v block ids", len(cells), len(r.Ids))
+	}
 	var res []ton.Transaction
+	txs := make([]tlb.Transaction, 0, len(cells))
 	for i, cell := range cells {
 		var t tlb.Transaction
 		cell.ResetCounters()
@@ -735,26 +1002,43 @@ func (c *Client) GetTransactions(
 		if err != nil {
 			return nil, err
 		}
+		txs = append(txs, t)
 		res = append(res, ton.Transaction{
 			Transaction: t,
 			BlockID:     r.Ids[i].ToBlockIdExt(),
 		})
 	}
+	if c.proofPolicy != ProofPolicyUnsafe {
+		if err := checkTransactionChain(accountID, lt, hash, cells, txs); err != nil {
+			return nil, err
+		}
+	}
 	return res, nil
 }
 
 func (c *Client) GetTransactionsRaw(ctx context.Context, count uint32, accountID ton.AccountID, lt uint64, hash ton.Bits256) (liteclient.LiteServerTransactionListC, error) {
+	if c.quorum != nil {
+		return quorumQuery(ctx, c, func(client *liteclient.Client, _ ton.BlockIDExt) (liteclient.LiteServerTransactionListC, error) {
+			return client.LiteServerGetTransactions(ctx, liteclient.LiteServerGetTransactionsRequest{
+				Count:   count,
+				Account: liteclient.AccountID(accountID),
+				Lt:      lt,
+				Hash:    tl.Int256(hash),
+			})
+		}, transactionListHash)
+	}
 	archiveRequired := false
 	for {
-		client, _, err := c.pool.BestClientByAccountID(ctx, accountID, archiveRequired)
-		if err != nil {
-			return liteclient.LiteServerTransactionListC{}, err
-		}
-		res, err := client.LiteServerGetTransactions(ctx, liteclient.LiteServerGetTransactionsRequest{
-			Count:   count,
-			Account: liteclient.AccountID(accountID),
-			Lt:      lt,
-			Hash:    tl.Int256(hash),
+		var res liteclient.LiteServerTransactionListC
+		err := c.pool.Do(ctx, pool.ReadRequest, archiveRequired, func(client *liteclient.Client, _ ton.BlockIDExt) error {
+			var err error
+			res, err = client.LiteServerGetTransactions(ctx, liteclient.LiteServerGetTransactionsRequest{
+				Count:   count,
+				Account: liteclient.AccountID(accountID),
+				Lt:      lt,
+				Hash:    tl.Int256(hash),
+			})
+			return err
 		})
 		if truncatedHistory(err) {
 			if !c.archiveDetectionEnabled {
@@ -817,35 +1101,99 @@ func (c *Client) GetLastTransactions(ctx context.Context, a ton.AccountID, limit
 	return res, nil
 }
 
+// ListBlockTransactions returns ids of transactions of the given block.
+// Unless the client uses ProofPolicyUnsafe, the ids are checked against a proof of the block,
+// so the mode is extended to request accounts, lts, hashes and the proof.
 func (c *Client) ListBlockTransactions(
 	ctx context.Context,
 	blockID ton.BlockIDExt,
 	mode, count uint32,
 	after *liteclient.LiteServerTransactionId3C,
 ) ([]liteclient.LiteServerTransactionIdC, bool, error) {
+	if c.proofPolicy != ProofPolicyUnsafe {
+		mode |= 1 | 2 | 4 | 32
+	}
 	// TODO: replace with tongo types
 	res, err := c.ListBlockTransactionsRaw(ctx, blockID, mode, count, after)
 	if err != nil {
 		return nil, false, err
 	}
+	if c.proofPolicy != ProofPolicyUnsafe {
+		if err := checkBlockTransactionIdsProof(blockID, res.Proof, res.Ids); err != nil {
+			return nil, false, err
+		}
+		if err := c.verifyBlock(ctx, blockID); err != nil {
+			return nil, false, err
+		}
+	}
 	return res.Ids, res.Incomplete, nil
 }
 
 func (c *Client) ListBlockTransactionsRaw(ctx context.Context, blockID ton.BlockIDExt, mode, count uint32, after *liteclient.LiteServerTransactionId3C) (liteclient.LiteServerBlockTransactionsC, error) {
-	client, err := c.pool.BestClientByBlockID(ctx, blockID.BlockID)
+	return query(ctx, c, func(client *liteclient.Client, _ ton.BlockIDExt) (liteclient.LiteServerBlockTransactionsC, error) {
+		return client.LiteServerListBlockTransactions(ctx, liteclient.LiteServerListBlockTransactionsRequest{
+			Id:    liteclient.BlockIDExt(blockID),
+			Mode:  mode,
+			Count: count,
+			After: after,
+		})
+	})
+}
+
+// ListBlockTransactionsExt returns transactions of the given block.
+// Unlike ListBlockTransactions, it returns full transactions instead of their ids.
+// The transactions are checked against a proof of the block unless the client uses ProofPolicyUnsafe.
+// With ProofPolicySecure the block itself is checked to belong to the trusted chain.
+// The returned bool is true if there are more transactions in the block.
+func (c *Client) ListBlockTransactionsExt(
+	ctx context.Context,
+	blockID ton.BlockIDExt,
+	mode, count uint32,
+	after *liteclient.LiteServerTransactionId3C,
+) ([]ton.Transaction, bool, error) {
+	if c.proofPolicy != ProofPolicyUnsafe {
+		mode |= 32
+	}
+	res, err := c.ListBlockTransactionsExtRaw(ctx, blockID, mode, count, after)
 	if err != nil {
-		return liteclient.LiteServerBlockTransactionsC{}, err
+		return nil, false, err
 	}
-	res, err := client.LiteServerListBlockTransactions(ctx, liteclient.LiteServerListBlockTransactionsRequest{
-		Id:    liteclient.BlockIDExt(blockID),
-		Mode:  mode,
-		Count: count,
-		After: after,
-	})
+	if len(res.Transactions) == 0 {
+		return []ton.Transaction{}, res.Incomplete, nil
+	}
+	cells, err := boc.DeserializeBoc(res.Transactions)
 	if err != nil {
-		return liteclient.LiteServerBlockTransactionsC{}, err
+		return nil, false, err
 	}
-	return res, nil
+	txs := make([]ton.Transaction, 0, len(cells))
+	for _, cell := range cells {
+		var t tlb.Transaction
+		cell.ResetCounters()
+		if err := tlb.Unmarshal(cell, &t); err != nil {
+			return nil, false, err
+		}
+		txs = append(txs, ton.Transaction{Transaction: t, BlockID: blockID})
+	}
+	if c.proofPolicy != ProofPolicyUnsafe {
+		if err := checkBlockTransactionsProof(blockID, res.Proof, cells, txs); err != nil {
+			return nil, false, err
+		}
+		if err := c.verifyBlock(ctx, blockID); err != nil {
+			return nil, false, err
+		}
+	}
+	return txs, res.Incomplete, nil
+}
+
+func (c *Client) ListBlockTransactionsExtRaw(ctx context.Context, blockID ton.BlockIDExt, mode, count uint32, after *liteclient.LiteServerTransactionId3C) (liteclient.LiteServerBlockTransactionsExtC, error) {
+	return query(ctx, c, func(client *liteclient.Client, _ ton.BlockIDExt) (liteclient.LiteServerBlockTransactionsExtC, error) {
+		return client.LiteServerListBlockTransactionsExt(ctx, liteclient.LiteServerListBlockTransactionsExtRequest{
+			Id:    liteclient.BlockIDExt(blockID),
+			Mode:  mode,
+			Count: count,
+			After: after,
+		})
+	})
 }
 
 func (c *Client) GetBlockProof(
@@ -857,39 +1205,32 @@ func (c *Client) GetBlockProof(
 	if err != nil {
 		return liteclient.LiteServerPartialBlockProofC{}, err
 	}
-	// TODO: decode block proof
+	if c.proofPolicy == ProofPolicyUnsafe {
+		return res, nil
+	}
+	if _, _, err := checkBlockProofChain(res, knownBlock); err != nil {
+		return liteclient.LiteServerPartialBlockProofC{}, err
+	}
 	return res, nil
 }
 
 func (c *Client) GetBlockProofRaw(ctx context.Context, knownBlock ton.BlockIDExt, targetBlock *ton.BlockIDExt) (liteclient.LiteServerPartialBlockProofC, error) {
 	var (
-		err    error
-		client *liteclient.Client
-		mode   uint32 = 0
+		mode uint32 = 0
+		tb   *liteclient.TonNodeBlockIdExtC
 	)
-	if targetBlock != nil {
-		client, err = c.pool.BestClientByBlockID(ctx, targetBlock.BlockID)
-		mode = 1
-	} else {
-		client, err = c.pool.BestClientByBlockID(ctx, knownBlock.BlockID)
-	}
-	if err != nil {
-		return liteclient.LiteServerPartialBlockProofC{}, err
-	}
-	var tb *liteclient.TonNodeBlockIdExtC
 	if targetBlock != nil {
 		b := liteclient.BlockIDExt(*targetBlock)
 		tb = &b
+		mode = 1
 	}
-	res, err := client.LiteServerGetBlockProof(ctx, liteclient.LiteServerGetBlockProofRequest{
-		Mode:        mode,
-		KnownBlock:  liteclient.BlockIDExt(knownBlock),
-		TargetBlock: tb,
+	return query(ctx, c, func(client *liteclient.Client, _ ton.BlockIDExt) (liteclient.LiteServerPartialBlockProofC, error) {
+		return client.LiteServerGetBlockProof(ctx, liteclient.LiteServerGetBlockProofRequest{
+			Mode:        mode,
+			KnownBlock:  liteclient.BlockIDExt(knownBlock),
+			TargetBlock: tb,
+		})
 	})
-	if err != nil {
-		return liteclient.LiteServerPartialBlockProofC{}, err
-	}
-	return res, nil
 }
 
 // GetConfigAll returns a current configuration of the blockchain.
@@ -902,29 +1243,21 @@ func (c *Client) GetConfigAll(ctx context.Context, mode ConfigMode) (tlb.ConfigP
 }
 
 func (c *Client) GetConfigAllRaw(ctx context.Context, mode ConfigMode) (liteclient.LiteServerConfigInfoC, error) {
-	client, masterHead, err := c.pool.BestMasterchainClient(ctx)
-	if err != nil {
-		return liteclient.LiteServerConfigInfoC{}, err
-	}
-	res, err := client.LiteServerGetConfigAll(ctx, liteclient.LiteServerGetConfigAllRequest{
-		Mode: uint32(mode),
-		Id:   liteclient.BlockIDExt(c.targetBlockOr(masterHead)),
+	return query(ctx, c, func(client *liteclient.Client, masterHead ton.BlockIDExt) (liteclient.LiteServerConfigInfoC, error) {
+		return client.LiteServerGetConfigAll(ctx, liteclient.LiteServerGetConfigAllRequest{
+			Mode: uint32(mode),
+			Id:   liteclient.BlockIDExt(c.targetBlockOr(masterHead)),
+		})
 	})
-	if err != nil {
-		return liteclient.LiteServerConfigInfoC{}, err
-	}
-	return res, nil
 }
 
 func (c *Client) GetConfigParams(ctx context.Context, mode ConfigMode, paramList []uint32) (tlb.ConfigParams, error) {
-	client, masterHead, err := c.pool.BestMasterchainClient(ctx)
-	if err != nil {
-		return tlb.ConfigParams{}, err
-	}
-	r, err := client.LiteServerGetConfigParams(ctx, liteclient.LiteServerGetConfigParamsRequest{
-		Mode:      uint32(mode),
-		Id:        liteclient.BlockIDExt(c.targetBlockOr(masterHead)),
-		ParamList: paramList,
+	r, err := query(ctx, c, func(client *liteclient.Client, masterHead ton.BlockIDExt) (liteclient.LiteServerConfigInfoC, error) {
+		return client.LiteServerGetConfigParams(ctx, liteclient.LiteServerGetConfigParamsRequest{
+			Mode:      uint32(mode),
+			Id:        liteclient.BlockIDExt(c.targetBlockOr(masterHead)),
+			ParamList: paramList,
+		})
 	})
 	if err != nil {
 		return tlb.ConfigParams{}, err
@@ -932,75 +1265,179 @@ func (c *Client) GetConfigParams(ctx context.Context, mode ConfigMode, paramList
 	return ton.DecodeConfigParams(r.ConfigProof)
 }
 
+// ValidatorStats contains block creation statistics of validators.
+type ValidatorStats struct {
+	// Items contains statistics ordered by validators' public keys.
+	Items []ValidatorStatsItem
+	// Complete is true if there are no more validators after the last one in Items.
+	Complete bool
+}
+
+// ValidatorStatsItem contains block creation statistics of a single validator.
+type ValidatorStatsItem struct {
+	PublicKey ton.Bits256
+	Stats     tlb.CreatorStats
+}
+
+// ValidatorStatsOptions specifies validators returned by GetValidatorStatsWithOptions.
+type ValidatorStatsOptions struct {
+	// Limit is a maximum number of validators to return.
+	Limit uint32
+	// StartAfter skips validators with a public key less than or equal to the given one.
+	StartAfter *ton.Bits256
+	// ModifiedAfter skips validators whose counters weren't updated since the given unix time.
+	ModifiedAfter *uint32
+}
+
+// GetValidatorStats returns the masterchain state extra with block creation statistics of validators.
+// startAfter is sent only if mode&1 is set and modifiedAfter is sent only if mode&4 is set.
+// Use GetValidatorStatsWithOptions to get decoded statistics.
 func (c *Client) GetValidatorStats(
 	ctx context.Context,
 	mode, limit uint32,
 	startAfter *ton.Bits256,
 	modifiedAfter *uint32,
 ) (*tlb.McStateExtra, error) {
-	client, masterHead, err := c.pool.BestMasterchainClient(ctx)
+	_, state, err := c.getValidatorStats(ctx, mode, limit, startAfter, modifiedAfter)
 	if err != nil {
 		return nil, err
 	}
+	if !state.ShardStateUnsplit.Custom.Exists {
+		return nil, fmt.Errorf("not a masterchain state")
+	}
+	return &state.ShardStateUnsplit.Custom.Value.Value, nil
+}
+
+// GetValidatorStatsWithOptions returns block creation statistics of validators stored in the masterchain state.
+// Up to opts.Limit validators with a public key greater than opts.StartAfter are returned.
+// If opts.ModifiedAfter is set, only validators whose counters were updated since then are returned.
+func (c *Client) GetValidatorStatsWithOptions(ctx context.Context, opts ValidatorStatsOptions) (ValidatorStats, error) {
+	var mode uint32
+	if opts.StartAfter != nil {
+		mode |= 1
+	}
+	if opts.ModifiedAfter != nil {
+		mode |= 4
+	}
+	r, state, err := c.getValidatorStats(ctx, mode, opts.Limit, opts.StartAfter, opts.ModifiedAfter)
+	if err != nil {
+		return ValidatorStats{}, err
+	}
+	return decodeValidatorStats(state, r.Count, r.Complete, opts.StartAfter, opts.ModifiedAfter)
+}
+
+func (c *Client) getValidatorStats(
+	ctx context.Context,
+	mode, limit uint32,
+	startAfter *ton.Bits256,
+	modifiedAfter *uint32,
+) (liteclient.LiteServerValidatorStatsC, tlb.ShardStateUnsplit, error) {
 	var sa *tl.Int256
-	if startAfter != nil {
+	if mode&1 == 1 {
+		if startAfter == nil {
+			return liteclient.LiteServerValidatorStatsC{}, tlb.ShardStateUnsplit{}, fmt.Errorf("mode&1 requires startAfter")
+		}
 		b := tl.Int256(*startAfter)
 		sa = &b
 	}
-	r, err := client.LiteServerGetValidatorStats(ctx, liteclient.LiteServerGetValidatorStatsRequest{
-		Mode:          mode,
-		Id:            liteclient.BlockIDExt(c.targetBlockOr(masterHead)),
-		Limit:         limit,
-		StartAfter:    sa,
-		ModifiedAfter: modifiedAfter,
+	var ma *uint32
+	if mode&4 == 4 {
+		if modifiedAfter == nil {
+			return liteclient.LiteServerValidatorStatsC{}, tlb.ShardStateUnsplit{}, fmt.Errorf("mode&4 requires modifiedAfter")
+		}
+		ma = modifiedAfter
+	}
+	var blockID ton.BlockIDExt
+	r, err := query(ctx, c, func(client *liteclient.Client, masterHead ton.BlockIDExt) (liteclient.LiteServerValidatorStatsC, error) {
+		blockID = c.targetBlockOr(masterHead)
+		return client.LiteServerGetValidatorStats(ctx, liteclient.LiteServerGetValidatorStatsRequest{
+			Mode:          mode,
+			Id:            liteclient.BlockIDExt(blockID),
+			Limit:         limit,
+			StartAfter:    sa,
+			ModifiedAfter: ma,
+		})
 	})
 	if err != nil {
-		return nil, err
+		return liteclient.LiteServerValidatorStatsC{}, tlb.ShardStateUnsplit{}, err
+	}
+	// the stats are a part of the data proof which is found by the state hash from the state proof,
+	// so the proofs are always decoded, ProofPolicyUnsafe only skips the block check.
+	state, err := checkStateProof(r.Id.ToBlockIdExt(), r.StateProof, r.DataProof)
+	if err == nil && c.proofPolicy != ProofPolicyUnsafe && r.Id.ToBlockIdExt() != blockID {
+		err = fmt.Errorf("validator stats are for block %v", r.Id.ToBlockIdExt().BlockID)
 	}
-	cells, err := boc.DeserializeBoc(r.DataProof)
 	if err != nil {
-		return nil, err
+		return liteclient.LiteServerValidatorStatsC{}, tlb.ShardStateUnsplit{}, err
 	}
-	if len(cells) != 1 {
-		return nil, boc.ErrNotSingleRoot
+	return r, state, nil
+}
+
+func decodeValidatorStats(state tlb.ShardStateUnsplit, count uint32, complete bool, startAfter *ton.Bits256, modifiedAfter *uint32) (ValidatorStats, error) {
+	if !state.ShardStateUnsplit.Custom.Exists {
+		return ValidatorStats{}, fmt.Errorf("not a masterchain state")
 	}
-	var proof struct {
-		Proof tlb.MerkleProof[tlb.ShardState] // TODO: or ton.ShardStateUnsplit
+	other := state.ShardStateUnsplit.Custom.Value.Value.Other
+	if other.Flags&1 == 0 {
+		return ValidatorStats{}, fmt.Errorf("masterchain state has no block create stats")
 	}
-	err = tlb.Unmarshal(cells[0], &proof)
-	if err != nil {
-		return nil, err
+	stats := ValidatorStats{Complete: complete}
+	for _, item := range other.BlockCreateStats.Items() {
+		if uint32(len(stats.Items)) == count {
+			break
+		}
+		if startAfter != nil && bytes.Compare(item.Key[:], startAfter[:]) <= 0 {
+			continue
+		}
+		if modifiedAfter != nil && !item.Value.McBlocks.ModifiedSince(*modifiedAfter) && !item.Value.ShardBlocks.ModifiedSince(*modifiedAfter) {
+			continue
+		}
+		stats.Items = append(stats.Items, ValidatorStatsItem{
+			PublicKey: ton.Bits256(item.Key),
+			Stats:     item.Value,
+		})
+	}
+	if uint32(len(stats.Items)) != count {
+		return ValidatorStats{}, fmt.Errorf("expected %v validators in the proof, got %v", count, len(stats.Items))
 	}
-	// TODO: extract validator stats params from ShardState
-	// return &proof.Proof.VirtualRoot, nil //shards, nil
-	return nil, fmt.Errorf("not implemented")
+	return stats, nil
 }
 
 func (c *Client) GetLibraries(ctx context.Context, libraryList []ton.Bits256) (map[ton.Bits256]*boc.Cell, error) {
-	client, _, err := c.pool.BestMasterchainClient(ctx)
-	if err != nil {
-		return nil, err
-	}
 	var ll []tl.Int256
 	for _, l := range libraryList {
 		ll = append(ll, tl.Int256(l))
 	}
-	r, err := client.LiteServerGetLibraries(ctx, liteclient.LiteServerGetLibrariesRequest{
-		LibraryList: ll,
+	r, err := query(ctx, c, func(client *liteclient.Client, _ ton.BlockIDExt) (liteclient.LiteServerLibraryResultC, error) {
+		return client.LiteServerGetLibraries(ctx, liteclient.LiteServerGetLibrariesRequest{
+			LibraryList: ll,
+		})
 	})
 	if err != nil {
 		return nil, err
 	}
 	libs := make(map[ton.Bits256]*boc.Cell, len(r.Result))
 	for _, lib := range r.Result {
-		data, err := boc.DeserializeBoc(lib.Data)
+		roots, err := boc.DeserializeBoc(lib.Data)
 		if err != nil {
 			return nil, err
 		}
-		if len(data) != 1 {
-			return nil, fmt.Errorf("multiroot lib is not supported")
+		// a bag of cells can contain several libraries, so the library is chosen by its hash.
+		var cell *boc.Cell
+		for _, root := range roots {
+			hash, err := root.Hash256()
+			if err != nil {
+				return nil, err
+			}
+			if hash == lib.Hash {
+				cell = root
+				break
+			}
+		}
+		if cell == nil {
+			return nil, fmt.Errorf("library %x not found in bag of cells", lib.Hash)
 		}
-		libs[ton.Bits256(lib.Hash)] = data[0]
+		libs[ton.Bits256(lib.Hash)] = cell
 	}
 	return libs, nil
 }
@@ -1014,12 +1451,10 @@ func (c *Client) GetShardBlockProof(ctx context.Context) (liteclient.LiteServerS
 }
 
 func (c *Client) GetShardBlockProofRaw(ctx context.Context) (liteclient.LiteServerShardBlockProofC, error) {
-	client, masterHead, err := c.pool.BestMasterchainClient(ctx)
-	if err != nil {
-		return liteclient.LiteServerShardBlockProofC{}, err
-	}
-	return client.LiteServerGetShardBlockProof(ctx, liteclient.LiteServerGetShardBlockProofRequest{
-		Id: liteclient.BlockIDExt(c.targetBlockOr(masterHead)),
+	return query(ctx, c, func(client *liteclient.Client, masterHead ton.BlockIDExt) (liteclient.LiteServerShardBlockProofC, error) {
+		return client.LiteServerGetShardBlockProof(ctx, liteclient.LiteServerGetShardBlockProofRequest{
+			Id: liteclient.BlockIDExt(c.targetBlockOr(masterHead)),
+		})
 	})
 }
 
@@ -1031,15 +1466,71 @@ func (c *Client) WaitMasterchainSeqno(ctx context.Context, seqno uint32, timeout
 }
 
 func (c *Client) GetOutMsgQueueSizes(ctx context.Context) (liteclient.LiteServerOutMsgQueueSizesC, error) {
-	client, _, err := c.pool.BestMasterchainClient(ctx)
+	return query(ctx, c, func(client *liteclient.Client, _ ton.BlockIDExt) (liteclient.LiteServerOutMsgQueueSizesC, error) {
+		return client.LiteServerGetOutMsgQueueSizes(ctx, liteclient.LiteServerGetOutMsgQueueSizesRequest{})
+	})
+}
+
+// AccountDispatchQueue describes a dispatch queue of an account.
+type AccountDispatchQueue struct {
+	AccountID ton.AccountID
+	// Size is a number of messages in the queue.
+	Size  uint64
+	MinLt uint64
+	MaxLt uint64
+}
+
+// GetDispatchQueueInfo returns dispatch queues of accounts of the given block
+// starting after afterAddr if it is not nil.
+// The returned bool is true if there are no more accounts with non-empty dispatch queues.
+//
+// Unless the client uses ProofPolicyUnsafe, the queues are decoded from a proof of the block's state,
+// so a lite server can't skip or forge them. With ProofPolicySecure the block itself is checked to belong to the trusted chain.
+func (c *Client) GetDispatchQueueInfo(ctx context.Context, blockID ton.BlockIDExt, afterAddr *ton.Bits256, maxAccounts uint32) ([]AccountDispatchQueue, bool, error) {
+	res, err := c.GetDispatchQueueInfoRaw(ctx, blockID, afterAddr, maxAccounts)
 	if err != nil {
-		return liteclient.LiteServerOutMsgQueueSizesC{}, err
+		return nil, false, err
 	}
-	res, err := client.LiteServerGetOutMsgQueueSizes(ctx, liteclient.LiteServerGetOutMsgQueueSizesRequest{})
-	if err != nil {
-		return liteclient.LiteServerOutMsgQueueSizesC{}, err
+	if c.proofPolicy != ProofPolicyUnsafe {
+		queues, err := checkDispatchQueueProof(blockID, afterAddr, res)
+		if err != nil {
+			return nil, false, err
+		}
+		if err := c.verifyBlock(ctx, blockID); err != nil {
+			return nil, false, err
+		}
+		return queues, res.Complete, nil
+	}
+	queues := make([]AccountDispatchQueue, 0, len(res.AccountDispatchQueues))
+	for _, q := range res.AccountDispatchQueues {
+		queues = append(queues, AccountDispatchQueue{
+			AccountID: ton.AccountID{Workchain: blockID.Workchain, Address: q.Addr},
+			Size:      q.Size,
+			MinLt:     q.MinLt,
+			MaxLt:     q.MaxLt,
+		})
 	}
-	return res, nil
+	return queues, res.Complete, nil
+}
+
+// GetDispatchQueueInfoRaw requests a proof of the returned queues unless the client uses ProofPolicyUnsafe,
+// but doesn't check it.
+func (c *Client) GetDispatchQueueInfoRaw(ctx context.Context, blockID ton.BlockIDExt, afterAddr *ton.Bits256, maxAccounts uint32) (liteclient.LiteServerDispatchQueueInfoC, error) {
+	request := liteclient.LiteServerGetDispatchQueueInfoRequest{
+		Id:          liteclient.BlockIDExt(blockID),
+		MaxAccounts: maxAccounts,
+	}
+	if c.proofPolicy != ProofPolicyUnsafe {
+		request.Mode |= 1
+	}
+	if afterAddr != nil {
+		addr := tl.Int256(*afterAddr)
+		request.Mode |= 2
+		request.AfterAddr = &addr
+	}
+	return query(ctx, c, func(client *liteclient.Client, _ ton.BlockIDExt) (liteclient.LiteServerDispatchQueueInfoC, error) {
+		return client.LiteServerGetDispatchQueueInfo(ctx, request)
+	})
 }
 
 var configCache = make(map[string]*config.GlobalConfigurationFile)
@@ -1052,35 +1543,96 @@ func downloadConfig(path string) (*config.GlobalConfigurationFile, error) {
 	if prs {
 		return o, nil
 	}
-	resp, err := http.Get(path)
+	o, err := fetchConfig(context.Background(), path)
+	if err != nil {
+		return nil, err
+	}
+	rand.Shuffle(len(o.LiteServers), func(i, j int) {
+		o.LiteServers[i], o.LiteServers[j] = o.LiteServers[j], o.LiteServers[i]
+	})
+	return o, nil
+}
+
+// fetchConfig downloads the configuration bypassing the cache and puts it to the cache.
+// configHTTPClient downloads configuration files.
+// Its timeout keeps a stalled download from blocking the client forever.
+var configHTTPClient = &http.Client{Timeout: 30 * time.Second}
+
+func fetchConfig(ctx context.Context, path string) (*config.GlobalConfigurationFile, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, path, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := configHTTPClient.Do(req)
 	if err != nil {
 		return nil, err
 	}
 	defer resp.Body.Close()
-	o, err = config.ParseConfig(resp.Body)
-	if err == nil {
-		configCacheMutex.Lock()
-		configCache[path] = o
-		configCacheMutex.Unlock()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to download configuration: %v", resp.Status)
 	}
+	o, err := config.ParseConfig(resp.Body)
 	if err != nil {
 		return nil, err
 	}
-	rand.Shuffle(len(o.LiteServers), func(i, j int) {
-		o.LiteServers[i], o.LiteServers[j] = o.LiteServers[j], o.LiteServers[i]
-	})
+	configCacheMutex.Lock()
+	configCache[path] = o
+	configCacheMutex.Unlock()
 	return o, nil
 }
 
+// refreshConfiguration downloads the configuration every interval and replaces lite servers of the pool
+// until ctx is done.
+func (c *Client) refreshConfiguration(ctx context.Context, url string, interval time.Duration, timeout time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		// the download is canceled by ctx, so Close doesn't wait for it.
+		file, err := fetchConfig(ctx, url)
+		if ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			// the current lite servers are kept until the next attempt.
+			continue
+		}
+		servers := make([]config.LiteServer, len(file.LiteServers))
+		copy(servers, file.LiteServers)
+		rand.Shuffle(len(servers), func(i, j int) {
+			servers[i], servers[j] = servers[j], servers[i]
+		})
+		updateCtx, cancel := context.WithTimeout(ctx, timeout)
+		_ = c.pool.UpdateServers(updateCtx, servers)
+		cancel()
+	}
+}
+
 func setLiteServersFromURL(url string, o *Options) error {
 	file, err := downloadConfig(url)
 	if err != nil {
 		return err
 	}
-	o.LiteServers = file.LiteServers
+	setConfigurationFile(*file, o)
+	o.ConfigurationUrl = url
+	o.liteServersFromEnv = false
 	return nil
 }
 
+// setConfigurationFile sets lite servers of the given configuration file.
+// Its init block becomes a trusted block only if the caller hasn't specified one.
+func setConfigurationFile(file config.GlobalConfigurationFile, o *Options) {
+	o.LiteServers = file.LiteServers
+	if o.TrustedBlock == nil && file.Validator.InitBlock.RootHash != (ton.Bits256{}) {
+		initBlock := file.Validator.InitBlock
+		o.TrustedBlock = &initBlock
+	}
+}
+
 func (c *Client) getNetworkGlobalID() *int32 {
 	c.mu.RLock()
 	defer c.mu.RUnlock()
@@ -1111,11 +1663,12 @@ func (c *Client) GetPoolStatus() pool.Status {
 
 func (c *Client) WaitMasterchainBlock(ctx context.Context, seqno uint32, timeout time.Duration) (ton.BlockIDExt, error) {
 	t := uint32(timeout.Milliseconds())
-	client, _, err := c.pool.BestMasterchainClient(ctx)
-	if err != nil {
-		return ton.BlockIDExt{}, err
-	}
-	res, err := client.WaitMasterchainBlock(ctx, seqno, t)
+	var res liteclient.LiteServerBlockHeaderC
+	err := c.pool.Do(ctx, pool.WaitRequest, false, func(client *liteclient.Client, _ ton.BlockIDExt) error {
+		var err error
+		res, err = client.WaitMasterchainBlock(ctx, seqno, t)
+		return err
+	})
 	if err != nil {
 		return ton.BlockIDExt{}, err
 	}
@@ -10,12 +10,19 @@ import (
 	"log"
 	"math/big"
 	"math/rand"
+	"net/http"
+	"net/http/httptest"
 	"os"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/tonkeeper/tongo/config"
 	"github.com/tonkeeper/tongo/liteapi/pool"
+	"github.com/tonkeeper/tongo/liteclient"
 	"github.com/tonkeeper/tongo/tlb"
 	"github.com/tonkeeper/tongo/ton"
 	"golang.org/x/exp/maps"
@@ -396,6 +403,91 @@ func TestLookupBlock(t *testing.T) {
 	fmt.Printf("Prev block seqno    : %v\n", bl.SeqNo)
 }
 
+func TestGetValidatorStats(t *testing.T) {
+	api, err := NewClient(Mainnet(), FromEnvs(), WithProofPolicy(ProofPolicyFast))
+	if err != nil {
+		t.Fatal(err)
+	}
+	extra, err := api.GetValidatorStats(context.Background(), 0, 10, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if extra.Other.Flags&1 == 0 {
+		t.Fatalf("no block create stats")
+	}
+	stats, err := api.GetValidatorStatsWithOptions(context.Background(), ValidatorStatsOptions{Limit: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stats.Items) == 0 {
+		t.Fatalf("no validator stats")
+	}
+	last := stats.Items[len(stats.Items)-1].PublicKey
+	next, err := api.GetValidatorStatsWithOptions(context.Background(), ValidatorStatsOptions{Limit: 10, StartAfter: &last})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, item := range next.Items {
+		if bytes.Compare(item.PublicKey[:], last[:]) <= 0 {
+			t.Fatalf("validator %x is not after %x", item.PublicKey, last)
+		}
+	}
+}
+
+func Test_decodeValidatorStats(t *testing.T) {
+	keys := []tlb.Bits256{{1}, {2}, {3}, {4}}
+	values := []tlb.CreatorStats{
+		{McBlocks: tlb.Counters{LastUpdated: 100, Total: 1}},
+		{McBlocks: tlb.Counters{LastUpdated: 200, Total: 2}},
+		{ShardBlocks: tlb.Counters{LastUpdated: 300, Total: 3}},
+		{ShardBlocks: tlb.Counters{LastUpdated: 400, Total: 4}},
+	}
+	var state tlb.ShardStateUnsplit
+	state.ShardStateUnsplit.Custom.Exists = true
+	other := &state.ShardStateUnsplit.Custom.Value.Value.Other
+	other.Flags = 1
+	other.BlockCreateStats.SumType = "BlockCreateStats"
+	other.BlockCreateStats.BlockCreateStats.Counters = tlb.NewHashmapE(keys, values)
+
+	modifiedAfter := uint32(250)
+	startAfter := ton.Bits256{1}
+	tests := []struct {
+		name          string
+		count         uint32
+		startAfter    *ton.Bits256
+		modifiedAfter *uint32
+		want          []uint64
+		wantErr       bool
+	}{
+		{name: "all", count: 4, want: []uint64{1, 2, 3, 4}},
+		{name: "limit", count: 2, want: []uint64{1, 2}},
+		{name: "start after", count: 3, startAfter: &startAfter, want: []uint64{2, 3, 4}},
+		{name: "modified after", count: 2, modifiedAfter: &modifiedAfter, want: []uint64{3, 4}},
+		{name: "incomplete proof", count: 5, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stats, err := decodeValidatorStats(state, tt.count, true, tt.startAfter, tt.modifiedAfter)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("want error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("decodeValidatorStats() failed: %v", err)
+			}
+			var totals []uint64
+			for _, item := range stats.Items {
+				totals = append(totals, item.Stats.McBlocks.Total+item.Stats.ShardBlocks.Total)
+			}
+			if fmt.Sprint(totals) != fmt.Sprint(tt.want) {
+				t.Fatalf("want: %v, got: %v", tt.want, totals)
+			}
+		})
+	}
+}
+
 func TestGetOneTransaction(t *testing.T) {
 	tongoClient, err := NewClient(Mainnet(), FromEnvs())
 	if err != nil {
@@ -642,3 +734,229 @@ func TestWaitMasterchainBlock(t *testing.T) {
 	}
 	fmt.Printf("Next block seqno    : %v\n", bl.Seqno)
 }
+
+func TestWithConfigurationFile_trustedBlock(t *testing.T) {
+	initBlock := ton.BlockIDExt{BlockID: ton.BlockID{Workchain: -1, Shard: masterchainShard, Seqno: 10}, RootHash: ton.Bits256{1}}
+	trusted := ton.BlockIDExt{BlockID: ton.BlockID{Workchain: -1, Shard: masterchainShard, Seqno: 20}, RootHash: ton.Bits256{2}}
+	file := config.GlobalConfigurationFile{Validator: config.ValidatorConfig{InitBlock: initBlock}}
+	tests := []struct {
+		name    string
+		options []Option
+		want    ton.BlockIDExt
+	}{
+		{name: "init block", options: []Option{WithConfigurationFile(file)}, want: initBlock},
+		{name: "trusted block before config", options: []Option{WithTrustedBlock(trusted), WithConfigurationFile(file)}, want: trusted},
+		{name: "trusted block after config", options: []Option{WithConfigurationFile(file), WithTrustedBlock(trusted)}, want: trusted},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var opts Options
+			for _, o := range tt.options {
+				if err := o(&opts); err != nil {
+					t.Fatalf("option failed: %v", err)
+				}
+			}
+			if opts.TrustedBlock == nil || *opts.TrustedBlock != tt.want {
+				t.Fatalf("want trusted block: %v, got: %v", tt.want, opts.TrustedBlock)
+			}
+		})
+	}
+}
+
+func TestClient_configurationRefresh(t *testing.T) {
+	first := startTestLiteServer(t, &snapshotHandler{head: 100})
+	second := startTestLiteServer(t, &snapshotHandler{head: 100})
+	configJSON := func(server config.LiteServer) string {
+		host, port, _ := strings.Cut(server.Host, ":")
+		return fmt.Sprintf(`{"liteservers": [{"ip": %q, "port": %v, "id": {"@type": "pub.ed25519", "key": %q}}]}`, host, port, server.Key)
+	}
+	var mu sync.Mutex
+	served := configJSON(first)
+	httpServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		fmt.Fprint(w, served)
+	}))
+	defer httpServer.Close()
+
+	cli, err := NewClient(WithConfigurationUrl(httpServer.URL), WithConfigurationRefresh(50*time.Millisecond))
+	if err != nil {
+		t.Fatalf("NewClient() failed: %v", err)
+	}
+	hosts := func() []string {
+		var hosts []string
+		for _, status := range cli.GetPoolStatus().Connections {
+			hosts = append(hosts, status.ServerHost)
+		}
+		return hosts
+	}
+	if got := hosts(); !reflect.DeepEqual(got, []string{first.Host}) {
+		t.Fatalf("want connection to %v, got: %v", first.Host, got)
+	}
+
+	mu.Lock()
+	served = configJSON(second)
+	mu.Unlock()
+	deadline := time.Now().Add(10 * time.Second)
+	for !reflect.DeepEqual(hosts(), []string{second.Host}) {
+		if time.Now().After(deadline) {
+			t.Fatalf("want connection to %v, got: %v", second.Host, hosts())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if _, err := cli.GetMasterchainInfo(context.Background()); err != nil {
+		t.Fatalf("GetMasterchainInfo() failed: %v", err)
+	}
+
+	// an invalid configuration doesn't break the client.
+	mu.Lock()
+	served = "{}"
+	mu.Unlock()
+	time.Sleep(200 * time.Millisecond)
+	if got := hosts(); !reflect.DeepEqual(got, []string{second.Host}) {
+		t.Fatalf("want connection to %v, got: %v", second.Host, got)
+	}
+
+	// a closed client doesn't refresh its configuration anymore.
+	cli.Close()
+	mu.Lock()
+	served = configJSON(first)
+	mu.Unlock()
+	time.Sleep(200 * time.Millisecond)
+	if got := hosts(); len(got) != 0 {
+		t.Fatalf("want no connections after Close, got: %v", got)
+	}
+	cli.Close()
+}
+
+func TestClient_configurationRefresh_liteServersFromEnv(t *testing.T) {
+	server := startTestLiteServer(t, &snapshotHandler{head: 100})
+	t.Setenv(LiteServerEnvName, server.Host+":"+server.Key)
+
+	cli, err := NewClient(FromEnvsOrMainnet(), WithConfigurationRefresh(10*time.Millisecond))
+	if err != nil {
+		t.Fatalf("NewClient() failed: %v", err)
+	}
+	defer cli.Close()
+	if cli.refreshDone != nil {
+		t.Fatalf("lite servers from env must not be refreshed")
+	}
+	if _, err := cli.GetMasterchainInfo(context.Background()); err != nil {
+		t.Fatalf("GetMasterchainInfo() failed: %v", err)
+	}
+
+	if _, err := NewClient(WithLiteServers([]config.LiteServer{server}), WithConfigurationRefresh(10*time.Millisecond)); err == nil {
+		t.Fatalf("configuration refresh without a configuration url must be rejected")
+	}
+}
+
+func TestClient_Close_stalledConfigurationDownload(t *testing.T) {
+	server := startTestLiteServer(t, &snapshotHandler{head: 100})
+	host, port, _ := strings.Cut(server.Host, ":")
+	configJSON := fmt.Sprintf(`{"liteservers": [{"ip": %q, "port": %v, "id": {"@type": "pub.ed25519", "key": %q}}]}`, host, port, server.Key)
+	var requests atomic.Int32
+	stalled := make(chan struct{})
+	httpServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if requests.Add(1) == 1 {
+			fmt.Fprint(w, configJSON)
+			return
+		}
+		// refresh downloads never finish until they are canceled.
+		select {
+		case stalled <- struct{}{}:
+		default:
+		}
+		<-r.Context().Done()
+	}))
+	defer httpServer.Close()
+
+	cli, err := NewClient(WithConfigurationUrl(httpServer.URL), WithConfigurationRefresh(10*time.Millisecond))
+	if err != nil {
+		t.Fatalf("NewClient() failed: %v", err)
+	}
+	select {
+	case <-stalled:
+	case <-time.After(10 * time.Second):
+		t.Fatalf("configuration refresh hasn't started")
+	}
+	closed := make(chan struct{})
+	go func() {
+		cli.Close()
+		close(closed)
+	}()
+	select {
+	case <-closed:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Close() waits for a stalled configuration download")
+	}
+}
+
+// dispatchQueueHandler answers dispatch queue requests with the given queues
+// and a proof if it is requested.
+type dispatchQueueHandler struct {
+	*snapshotHandler
+	queues []liteclient.LiteServerAccountDispatchQueueInfoC
+	proof  []byte
+}
+
+func (h dispatchQueueHandler) LiteServerGetDispatchQueueInfo(ctx context.Context, request liteclient.LiteServerGetDispatchQueueInfoRequest) (liteclient.LiteServerDispatchQueueInfoC, error) {
+	res := liteclient.LiteServerDispatchQueueInfoC{
+		Mode:                  request.Mode,
+		Id:                    request.Id,
+		AccountDispatchQueues: h.queues,
+		Complete:              true,
+	}
+	if request.Mode&1 != 0 {
+		res.Proof = h.proof
+	}
+	return res, nil
+}
+
+func TestClient_GetDispatchQueueInfo(t *testing.T) {
+	block, proof, queues := newTestDispatchQueueProof(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	honest := startTestLiteServer(t, dispatchQueueHandler{snapshotHandler: &snapshotHandler{head: 100}, queues: queues, proof: proof})
+	for _, policy := range []ProofPolicy{ProofPolicyFast, ProofPolicySecure} {
+		cli, err := NewClient(WithLiteServers([]config.LiteServer{honest}), WithProofPolicy(policy), WithTrustedBlock(block))
+		if err != nil {
+			t.Fatalf("NewClient() failed: %v", err)
+		}
+		defer cli.Close()
+		got, complete, err := cli.GetDispatchQueueInfo(ctx, block, nil, 10)
+		if err != nil {
+			t.Fatalf("GetDispatchQueueInfo() failed: %v", err)
+		}
+		if len(got) != len(queues) || !complete {
+			t.Fatalf("want %v queues, got: %v, complete: %v", len(queues), got, complete)
+		}
+	}
+
+	// the lite server hides the queue of the second account.
+	forged := startTestLiteServer(t, dispatchQueueHandler{
+		snapshotHandler: &snapshotHandler{head: 100},
+		queues:          []liteclient.LiteServerAccountDispatchQueueInfoC{queues[0], queues[2]},
+		proof:           proof,
+	})
+	unsafe, err := NewClient(WithLiteServers([]config.LiteServer{forged}), WithProofPolicy(ProofPolicyUnsafe))
+	if err != nil {
+		t.Fatalf("NewClient() failed: %v", err)
+	}
+	defer unsafe.Close()
+	got, _, err := unsafe.GetDispatchQueueInfo(ctx, block, nil, 10)
+	if err != nil {
+		t.Fatalf("GetDispatchQueueInfo() failed: %v", err)
+	}
+	if len(got) != 2 {
+		t.Fatalf("want 2 unverified queues, got: %v", got)
+	}
+	fast, err := NewClient(WithLiteServers([]config.LiteServer{forged}), WithProofPolicy(ProofPolicyFast))
+	if err != nil {
+		t.Fatalf("NewClient() failed: %v", err)
+	}
+	defer fast.Close()
+	if _, _, err := fast.GetDispatchQueueInfo(ctx, block, nil, 10); !errors.Is(err, ErrInvalidStateProof) {
+		t.Fatalf("want ErrInvalidStateProof, got: %v", err)
+	}
+}
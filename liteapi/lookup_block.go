@@ -0,0 +1,270 @@
+package liteapi
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/tonkeeper/tongo/boc"
+	"github.com/tonkeeper/tongo/liteclient"
+	"github.com/tonkeeper/tongo/tlb"
+	"github.com/tonkeeper/tongo/ton"
+)
+
+const (
+	// LookupBlockBySeqno looks up a block by its seqno.
+	LookupBlockBySeqno uint32 = 1
+	// LookupBlockByLt looks up the first block of a shard ending after the given logical time.
+	LookupBlockByLt uint32 = 2
+	// LookupBlockByUtime looks up the first block of a shard generated at or after the given unix time.
+	LookupBlockByUtime uint32 = 4
+)
+
+// LookupBlockResult is a block found by LookupBlockWithProof.
+type LookupBlockResult struct {
+	ID   ton.BlockIDExt
+	Info tlb.BlockInfo
+	// MasterchainBlockID is a masterchain block which references the found block.
+	MasterchainBlockID ton.BlockIDExt
+	// ShardLinks is a chain of blocks starting from the top shard block of MasterchainBlockID,
+	// each block is a parent of the previous one, and the last one is ID.
+	// It is empty for a masterchain block.
+	ShardLinks []ton.BlockIDExt
+}
+
+// LookupBlockWithProof works the same way as LookupBlock,
+// but additionally asks a lite server to prove that the found block is referenced
+// by the current masterchain block of the client.
+// The proof is checked unless the client uses ProofPolicyUnsafe.
+// With ProofPolicySecure the masterchain block itself is checked against the trusted chain first.
+// mode is a combination of LookupBlockBySeqno, LookupBlockByLt and LookupBlockByUtime.
+func (c *Client) LookupBlockWithProof(ctx context.Context, blockID ton.BlockID, mode uint32, lt *uint64, utime *uint32) (LookupBlockResult, error) {
+	res, masterchainBlock, err := c.LookupBlockWithProofRaw(ctx, blockID, mode, lt, utime)
+	if err != nil {
+		return LookupBlockResult{}, err
+	}
+	if c.proofPolicy == ProofPolicyUnsafe {
+		header, err := decodeMerkleProofOf[tlb.BlockHeader](res.Header, ton.Bits256(res.Id.RootHash))
+		if err != nil {
+			return LookupBlockResult{}, err
+		}
+		result := LookupBlockResult{
+			ID:                 res.Id.ToBlockIdExt(),
+			Info:               header.Info,
+			MasterchainBlockID: res.McBlockId.ToBlockIdExt(),
+		}
+		for _, link := range res.ShardLinks {
+			result.ShardLinks = append(result.ShardLinks, link.Id.ToBlockIdExt())
+		}
+		return result, nil
+	}
+	if c.verifier != nil {
+		if err := c.verifyBlock(ctx, masterchainBlock); err != nil {
+			return LookupBlockResult{}, err
+		}
+	}
+	return checkLookupBlockProof(res, masterchainBlock, blockID, mode, lt, utime)
+}
+
+// LookupBlockWithProofRaw returns a lite server's answer along with the masterchain block used for the request.
+func (c *Client) LookupBlockWithProofRaw(ctx context.Context, blockID ton.BlockID, mode uint32, lt *uint64, utime *uint32) (liteclient.LiteServerLookupBlockResultC, ton.BlockIDExt, error) {
+	var masterchainBlock ton.BlockIDExt
+	res, err := query(ctx, c, func(client *liteclient.Client, masterHead ton.BlockIDExt) (liteclient.LiteServerLookupBlockResultC, error) {
+		masterchainBlock = c.targetBlockOr(masterHead)
+		return client.LiteServerLookupBlockWithProof(ctx, liteclient.LiteServerLookupBlockWithProofRequest{
+			Mode: mode,
+			Id: liteclient.TonNodeBlockIdC{
+				Workchain: uint32(blockID.Workchain),
+				Shard:     blockID.Shard,
+				Seqno:     blockID.Seqno,
+			},
+			McBlockId: liteclient.BlockIDExt(masterchainBlock),
+			Lt:        lt,
+			Utime:     utime,
+		})
+	})
+	if err != nil {
+		return liteclient.LiteServerLookupBlockResultC{}, ton.BlockIDExt{}, err
+	}
+	return res, masterchainBlock, nil
+}
+
+// checkLookupBlockProof checks that the found block matches the request
+// and is referenced by clientBlock through res.McBlockId and the chain of shard links.
+func checkLookupBlockProof(res liteclient.LiteServerLookupBlockResultC, clientBlock ton.BlockIDExt, blockID ton.BlockID, mode uint32, lt *uint64, utime *uint32) (LookupBlockResult, error) {
+	result := LookupBlockResult{
+		ID:                 res.Id.ToBlockIdExt(),
+		MasterchainBlockID: res.McBlockId.ToBlockIdExt(),
+	}
+	header, err := decodeMerkleProofOf[tlb.BlockHeader](res.Header, result.ID.RootHash)
+	if err != nil {
+		return LookupBlockResult{}, newProofError(ErrInvalidShardProof, "block header proof is not for block %v: %w", result.ID.BlockID, err)
+	}
+	result.Info = header.Info
+	if err := checkLookupBlockHeader(result.ID, header.Info, blockID, mode, lt, utime); err != nil {
+		return LookupBlockResult{}, newProofError(ErrInvalidShardProof, "%w", err)
+	}
+	if err := checkLookupBlockPrevHeader(result.ID, header.Info, res.PrevHeader, mode, lt, utime); err != nil {
+		return LookupBlockResult{}, newProofError(ErrInvalidShardProof, "%w", err)
+	}
+	if result.MasterchainBlockID.Workchain != -1 {
+		return LookupBlockResult{}, newProofError(ErrInvalidShardProof, "block %v is not a masterchain block", result.MasterchainBlockID.BlockID)
+	}
+	if result.MasterchainBlockID != clientBlock {
+		blockProof, stateProof, err := splitBlockStateProof(clientBlock, res.ClientMcStateProof)
+		if err != nil {
+			return LookupBlockResult{}, newProofError(ErrInvalidShardProof, "%w", err)
+		}
+		if err := checkBackwardLink(clientBlock, result.MasterchainBlockID, false, blockProof, stateProof, nil); err != nil {
+			return LookupBlockResult{}, newProofError(ErrInvalidShardProof, "%w", err)
+		}
+	}
+	current := result.MasterchainBlockID
+	for i, link := range res.ShardLinks {
+		next := link.Id.ToBlockIdExt()
+		if i == 0 {
+			err = checkTopShardBlock(current, next, res.McBlockProof, link.Proof)
+		} else {
+			err = checkParentBlock(current, next, link.Proof)
+		}
+		if err != nil {
+			return LookupBlockResult{}, newProofError(ErrInvalidShardProof, "invalid link from %v to %v: %w", current.BlockID, next.BlockID, err)
+		}
+		result.ShardLinks = append(result.ShardLinks, next)
+		current = next
+	}
+	if current != result.ID {
+		return LookupBlockResult{}, newProofError(ErrInvalidShardProof, "shard links end at unexpected block %v", current.BlockID)
+	}
+	return result, nil
+}
+
+// splitBlockStateProof splits a bag with both a proof of the given block and a proof of its state,
+// like client_mc_state_proof of liteServer.lookupBlockResult, into separate bags.
+func splitBlockStateProof(blockID ton.BlockIDExt, proof []byte) ([]byte, []byte, error) {
+	cells, err := boc.DeserializeBoc(proof)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to decode client masterchain state proof: %w", err)
+	}
+	blockRoot, stateRoot, err := findBlockStateProof(blockID, cells, cells)
+	if err != nil {
+		return nil, nil, err
+	}
+	blockProof, err := blockRoot.ToBoc()
+	if err != nil {
+		return nil, nil, err
+	}
+	stateProof, err := stateRoot.ToBoc()
+	if err != nil {
+		return nil, nil, err
+	}
+	return blockProof, stateProof, nil
+}
+
+// checkLookupBlockHeader checks that the found block satisfies the lookup request.
+func checkLookupBlockHeader(found ton.BlockIDExt, info tlb.BlockInfo, blockID ton.BlockID, mode uint32, lt *uint64, utime *uint32) error {
+	if info.SeqNo != found.Seqno || info.Shard.WorkchainID != found.Workchain {
+		return fmt.Errorf("block header doesn't match block %v", found.BlockID)
+	}
+	if found.Workchain != blockID.Workchain {
+		return fmt.Errorf("block %v is not in workchain %v", found.BlockID, blockID.Workchain)
+	}
+	shard, err := ton.ParseShardID(int64(blockID.Shard))
+	if err != nil {
+		return err
+	}
+	if !shard.MatchBlockID(found.BlockID) {
+		return fmt.Errorf("block %v doesn't match shard %016x", found.BlockID, blockID.Shard)
+	}
+	switch {
+	case mode&LookupBlockBySeqno != 0:
+		if found.Seqno != blockID.Seqno {
+			return fmt.Errorf("want seqno %v, got block %v", blockID.Seqno, found.BlockID)
+		}
+	case mode&LookupBlockByLt != 0:
+		if lt == nil || *lt >= info.EndLt {
+			return fmt.Errorf("block %v ends before the requested lt", found.BlockID)
+		}
+	case mode&LookupBlockByUtime != 0:
+		if utime == nil || info.GenUtime < *utime {
+			return fmt.Errorf("block %v is generated before the requested utime", found.BlockID)
+		}
+	}
+	return nil
+}
+
+// checkLookupBlockPrevHeader checks that the parent of the found block proven by prevHeader
+// doesn't satisfy a lookup by lt or utime, so the found block is the first one that does.
+func checkLookupBlockPrevHeader(found ton.BlockIDExt, info tlb.BlockInfo, prevHeader []byte, mode uint32, lt *uint64, utime *uint32) error {
+	if mode&LookupBlockBySeqno != 0 || mode&(LookupBlockByLt|LookupBlockByUtime) == 0 || found.Seqno == 0 {
+		return nil
+	}
+	parents, err := ton.GetParents(info)
+	if err != nil {
+		return err
+	}
+	var (
+		prev tlb.BlockHeader
+		ok   bool
+	)
+	for _, parent := range parents {
+		if prev, err = decodeMerkleProofOf[tlb.BlockHeader](prevHeader, parent.RootHash); err == nil && prev.Info.SeqNo == parent.Seqno {
+			ok = true
+			break
+		}
+	}
+	if !ok {
+		return fmt.Errorf("previous block header proof is not for a parent of block %v", found.BlockID)
+	}
+	switch {
+	case mode&LookupBlockByLt != 0:
+		if prev.Info.EndLt > *lt {
+			return fmt.Errorf("parent of block %v ends after the requested lt", found.BlockID)
+		}
+	case mode&LookupBlockByUtime != 0:
+		if prev.Info.GenUtime >= *utime {
+			return fmt.Errorf("parent of block %v is generated at or after the requested utime", found.BlockID)
+		}
+	}
+	return nil
+}
+
+// checkTopShardBlock checks that shardBlock is the top block of its shard in the masterchain block.
+func checkTopShardBlock(masterchainBlock, shardBlock ton.BlockIDExt, blockProof, stateProof []byte) error {
+	state, err := checkStateProof(masterchainBlock, blockProof, stateProof)
+	if err != nil {
+		return err
+	}
+	if !state.ShardStateUnsplit.Custom.Exists {
+		return fmt.Errorf("no shard hashes in masterchain state")
+	}
+	for _, item := range state.ShardStateUnsplit.Custom.Value.Value.ShardHashes.Items() {
+		if int32(item.Key) != shardBlock.Workchain {
+			continue
+		}
+		for _, desc := range item.Value.Value.BinTree.Values {
+			if ton.ToBlockId(desc, shardBlock.Workchain) == shardBlock {
+				return nil
+			}
+		}
+	}
+	return fmt.Errorf("block %v is not a top shard block of %v", shardBlock.BlockID, masterchainBlock.BlockID)
+}
+
+// checkParentBlock checks that headerProof is a header proof of the given block
+// and parent is one of the block's previous blocks.
+func checkParentBlock(block, parent ton.BlockIDExt, headerProof []byte) error {
+	header, err := decodeMerkleProofOf[tlb.BlockHeader](headerProof, block.RootHash)
+	if err != nil {
+		return fmt.Errorf("block header proof is not for block %v: %w", block.BlockID, err)
+	}
+	parents, err := ton.GetParents(header.Info)
+	if err != nil {
+		return err
+	}
+	for _, p := range parents {
+		if p == parent {
+			return nil
+		}
+	}
+	return fmt.Errorf("block %v is not a parent of %v", parent.BlockID, block.BlockID)
+}
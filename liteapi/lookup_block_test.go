@@ -0,0 +1,304 @@
+package liteapi
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/tonkeeper/tongo/boc"
+	"github.com/tonkeeper/tongo/config"
+	"github.com/tonkeeper/tongo/liteclient"
+	"github.com/tonkeeper/tongo/tlb"
+	"github.com/tonkeeper/tongo/ton"
+)
+
+func Test_checkLookupBlockProof(t *testing.T) {
+	blockCell, block := readTestBlock(t, "../tlb/testdata/block-5/block.bin")
+	blockHash, err := blockCell.Hash256()
+	if err != nil {
+		t.Fatalf("Hash256() failed: %v", err)
+	}
+	info := block.Info
+	blockID := ton.BlockIDExt{
+		BlockID: ton.BlockID{
+			Workchain: info.Shard.WorkchainID,
+			Shard:     masterchainShard,
+			Seqno:     info.SeqNo,
+		},
+		RootHash: blockHash,
+	}
+	blockCell.ResetCounters()
+	headerProof := createTestProof(t, blockCell, []int{1}, []int{2}, []int{3})
+	header, err := boc.SerializeBocMulti([]*boc.Cell{headerProof}, false, false, false, 0)
+	if err != nil {
+		t.Fatalf("SerializeBocMulti() failed: %v", err)
+	}
+	res := liteclient.LiteServerLookupBlockResultC{
+		Id:        liteclient.BlockIDExt(blockID),
+		McBlockId: liteclient.BlockIDExt(blockID),
+		Header:    header,
+	}
+	lt := info.StartLt
+	utime := info.GenUtime
+
+	result, err := checkLookupBlockProof(res, blockID, blockID.BlockID, LookupBlockBySeqno, nil, nil)
+	if err != nil {
+		t.Fatalf("checkLookupBlockProof() failed: %v", err)
+	}
+	if result.ID != blockID || result.Info.SeqNo != info.SeqNo || len(result.ShardLinks) != 0 {
+		t.Fatalf("unexpected result: %v", result)
+	}
+	// lookups by lt and utime require a proof of the previous block.
+	if _, err := checkLookupBlockProof(res, blockID, blockID.BlockID, LookupBlockByLt, &lt, nil); !errors.Is(err, ErrInvalidShardProof) {
+		t.Fatalf("want ErrInvalidShardProof, got: %v", err)
+	}
+	if _, err := checkLookupBlockProof(res, blockID, blockID.BlockID, LookupBlockByUtime, nil, &utime); !errors.Is(err, ErrInvalidShardProof) {
+		t.Fatalf("want ErrInvalidShardProof, got: %v", err)
+	}
+	another := blockID.BlockID
+	another.Seqno += 1
+	if _, err := checkLookupBlockProof(res, blockID, another, LookupBlockBySeqno, nil, nil); !errors.Is(err, ErrInvalidShardProof) {
+		t.Fatalf("want ErrInvalidShardProof, got: %v", err)
+	}
+	forged := res
+	forged.Id.RootHash = [32]byte{1}
+	if _, err := checkLookupBlockProof(forged, blockID, blockID.BlockID, LookupBlockBySeqno, nil, nil); !errors.Is(err, ErrInvalidShardProof) {
+		t.Fatalf("want ErrInvalidShardProof, got: %v", err)
+	}
+	// a newer client block requires a proof that the found block is its ancestor.
+	newer := blockID
+	newer.Seqno += 1
+	if _, err := checkLookupBlockProof(res, newer, blockID.BlockID, LookupBlockBySeqno, nil, nil); !errors.Is(err, ErrInvalidShardProof) {
+		t.Fatalf("want ErrInvalidShardProof, got: %v", err)
+	}
+}
+
+// newTestHeaderProof returns a block header with the given info and a proof of the header.
+func newTestHeaderProof(t *testing.T, info tlb.BlockInfo) (ton.BlockIDExt, []byte) {
+	cell := boc.NewCell()
+	if err := tlb.Marshal(cell, tlb.BlockHeader{GlobalId: -239, Info: info}); err != nil {
+		t.Fatalf("Marshal() failed: %v", err)
+	}
+	hash, err := cell.Hash256()
+	if err != nil {
+		t.Fatalf("Hash256() failed: %v", err)
+	}
+	cell.ResetCounters()
+	proof, err := boc.SerializeBoc(createTestProof(t, cell), false, false, false, 0)
+	if err != nil {
+		t.Fatalf("SerializeBoc() failed: %v", err)
+	}
+	blockID := ton.BlockIDExt{
+		BlockID:  ton.BlockID{Workchain: -1, Shard: masterchainShard, Seqno: info.SeqNo},
+		RootHash: hash,
+	}
+	return blockID, proof
+}
+
+func Test_checkLookupBlockProof_prevHeader(t *testing.T) {
+	_, block := readTestBlock(t, "../tlb/testdata/block-5/block.bin")
+	// a chain of three blocks generated 5 seconds apart.
+	var (
+		ids     []ton.BlockIDExt
+		headers [][]byte
+		infos   []tlb.BlockInfo
+	)
+	for i := 0; i < 3; i++ {
+		info := block.Info
+		info.SeqNo = block.Info.SeqNo + uint32(i)
+		info.GenUtime = 1000 + uint32(i)*5
+		info.StartLt = 100 + uint64(i)*100
+		info.EndLt = info.StartLt + 10
+		if i > 0 {
+			prev := infos[i-1]
+			info.PrevRef = tlb.BlkPrevInfo{SumType: "PrevBlkInfo", PrevBlkInfo: &struct{ Prev tlb.ExtBlkRef }{
+				Prev: tlb.ExtBlkRef{EndLt: prev.EndLt, SeqNo: prev.SeqNo, RootHash: tlb.Bits256(ids[i-1].RootHash)},
+			}}
+		}
+		id, header := newTestHeaderProof(t, info)
+		ids = append(ids, id)
+		headers = append(headers, header)
+		infos = append(infos, info)
+	}
+	lookup := func(found int, mode uint32, lt *uint64, utime *uint32) error {
+		res := liteclient.LiteServerLookupBlockResultC{
+			Id:         liteclient.BlockIDExt(ids[found]),
+			McBlockId:  liteclient.BlockIDExt(ids[found]),
+			Header:     headers[found],
+			PrevHeader: headers[found-1],
+		}
+		_, err := checkLookupBlockProof(res, ids[found], ton.BlockID{Workchain: -1, Shard: masterchainShard}, mode, lt, utime)
+		return err
+	}
+	for _, tt := range []struct {
+		name    string
+		found   int
+		utime   uint32
+		wantErr bool
+	}{
+		{name: "block generated at utime", found: 2, utime: 1010},
+		{name: "first block after utime", found: 2, utime: 1007},
+		{name: "older block", found: 1, utime: 1007, wantErr: true},
+		{name: "newer block", found: 2, utime: 1003, wantErr: true},
+	} {
+		t.Run("utime/"+tt.name, func(t *testing.T) {
+			if err := lookup(tt.found, LookupBlockByUtime, nil, &tt.utime); (err != nil) != tt.wantErr {
+				t.Fatalf("want error %v, got: %v", tt.wantErr, err)
+			}
+		})
+	}
+	for _, tt := range []struct {
+		name    string
+		found   int
+		lt      uint64
+		wantErr bool
+	}{
+		{name: "block containing lt", found: 2, lt: 305},
+		{name: "first block after lt", found: 2, lt: 250},
+		{name: "older block", found: 1, lt: 250, wantErr: true},
+		{name: "newer block", found: 2, lt: 205, wantErr: true},
+	} {
+		t.Run("lt/"+tt.name, func(t *testing.T) {
+			if err := lookup(tt.found, LookupBlockByLt, &tt.lt, nil); (err != nil) != tt.wantErr {
+				t.Fatalf("want error %v, got: %v", tt.wantErr, err)
+			}
+		})
+	}
+	// the previous header must be a header of the found block's parent.
+	utime := uint32(1007)
+	res := liteclient.LiteServerLookupBlockResultC{
+		Id:         liteclient.BlockIDExt(ids[2]),
+		McBlockId:  liteclient.BlockIDExt(ids[2]),
+		Header:     headers[2],
+		PrevHeader: headers[0],
+	}
+	if _, err := checkLookupBlockProof(res, ids[2], ton.BlockID{Workchain: -1, Shard: masterchainShard}, LookupBlockByUtime, nil, &utime); !errors.Is(err, ErrInvalidShardProof) {
+		t.Fatalf("want ErrInvalidShardProof, got: %v", err)
+	}
+}
+
+func Test_splitBlockStateProof(t *testing.T) {
+	blockCell, _ := readTestBlock(t, "../tlb/testdata/block-5/block.bin")
+	stateCell := blockCell.Refs()[2].Refs()[1]
+	stateCell.ResetCounters()
+	var state tlb.ShardStateUnsplit
+	if err := tlb.Unmarshal(stateCell, &state); err != nil {
+		t.Fatalf("Unmarshal() failed: %v", err)
+	}
+	stateCell.ResetCounters()
+	prevBlocks := state.ShardStateUnsplit.Custom.Value.Value.Other.PrevBlocks
+	if len(prevBlocks.Keys()) == 0 {
+		t.Fatalf("want previous blocks in the masterchain state")
+	}
+	ref := prevBlocks.Values()[0].BlkRef
+	prevBlock := ton.BlockIDExt{
+		BlockID:  ton.BlockID{Workchain: -1, Shard: masterchainShard, Seqno: uint32(prevBlocks.Keys()[0])},
+		RootHash: ton.Bits256(ref.RootHash),
+		FileHash: ton.Bits256(ref.FileHash),
+	}
+	block := newTestBlock(t, stateCell)
+	blockHash, err := block.Hash256()
+	if err != nil {
+		t.Fatalf("Hash256() failed: %v", err)
+	}
+	clientBlock := ton.BlockIDExt{
+		BlockID:  ton.BlockID{Workchain: -1, Shard: masterchainShard, Seqno: state.ShardStateUnsplit.SeqNo},
+		RootHash: blockHash,
+	}
+	blockProof := createTestProof(t, block, []int{3})
+	stateProof := createTestProof(t, stateCell, []int{0}, []int{1}, []int{2})
+
+	for name, roots := range map[string][]*boc.Cell{
+		"block proof first": {blockProof, stateProof},
+		"state proof first": {stateProof, blockProof},
+	} {
+		t.Run(name, func(t *testing.T) {
+			proof, err := boc.SerializeBocMulti(roots, false, false, false, 0)
+			if err != nil {
+				t.Fatalf("SerializeBocMulti() failed: %v", err)
+			}
+			gotBlockProof, gotStateProof, err := splitBlockStateProof(clientBlock, proof)
+			if err != nil {
+				t.Fatalf("splitBlockStateProof() failed: %v", err)
+			}
+			if bytes.Equal(gotBlockProof, gotStateProof) {
+				t.Fatalf("want different block and state proofs")
+			}
+			if err := checkBackwardLink(clientBlock, prevBlock, false, gotBlockProof, gotStateProof, nil); err != nil {
+				t.Fatalf("checkBackwardLink() failed: %v", err)
+			}
+			// the block proof doesn't contain the state, and the state proof isn't for the block.
+			if err := checkBackwardLink(clientBlock, prevBlock, false, gotBlockProof, gotBlockProof, nil); err == nil {
+				t.Fatalf("want error for a block proof used as a state proof")
+			}
+			if err := checkBackwardLink(clientBlock, prevBlock, false, gotStateProof, gotStateProof, nil); err == nil {
+				t.Fatalf("want error for a state proof used as a block proof")
+			}
+		})
+	}
+	onlyBlock, err := boc.SerializeBocMulti([]*boc.Cell{blockProof}, false, false, false, 0)
+	if err != nil {
+		t.Fatalf("SerializeBocMulti() failed: %v", err)
+	}
+	if _, _, err := splitBlockStateProof(clientBlock, onlyBlock); err == nil {
+		t.Fatalf("want error for a bag without a state proof")
+	}
+}
+
+// unprovableHeadHandler is a lite server which reports a masterchain head
+// but can't prove that the head belongs to the trusted chain.
+type unprovableHeadHandler struct {
+	liteclient.UnimplementedLiteServerHandler
+
+	mu                 sync.Mutex
+	blockProofRequests int
+}
+
+func (h *unprovableHeadHandler) LiteServerGetMasterchainInfo(ctx context.Context) (liteclient.LiteServerMasterchainInfoC, error) {
+	return liteclient.LiteServerMasterchainInfoC{
+		Last: liteclient.TonNodeBlockIdExtC{Workchain: uint32(0xffffffff), Shard: masterchainShard, Seqno: 100},
+	}, nil
+}
+
+func (h *unprovableHeadHandler) LiteServerLookupBlockWithProof(ctx context.Context, request liteclient.LiteServerLookupBlockWithProofRequest) (liteclient.LiteServerLookupBlockResultC, error) {
+	return liteclient.LiteServerLookupBlockResultC{McBlockId: request.McBlockId}, nil
+}
+
+func (h *unprovableHeadHandler) LiteServerGetBlockProof(ctx context.Context, request liteclient.LiteServerGetBlockProofRequest) (liteclient.LiteServerPartialBlockProofC, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.blockProofRequests += 1
+	return liteclient.LiteServerPartialBlockProofC{}, liteclient.LiteServerErrorC{Code: 651, Message: "block not found"}
+}
+
+func (h *unprovableHeadHandler) blockProofRequestsNumber() int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.blockProofRequests
+}
+
+func TestClient_LookupBlockWithProof_unverifiedMasterchainBlock(t *testing.T) {
+	handler := &unprovableHeadHandler{}
+	trusted := ton.BlockIDExt{BlockID: ton.BlockID{Workchain: -1, Shard: masterchainShard, Seqno: 1}}
+	cli, err := NewClient(
+		WithLiteServers([]config.LiteServer{startTestLiteServer(t, handler)}),
+		WithProofPolicy(ProofPolicySecure),
+		WithTrustedBlock(trusted))
+	if err != nil {
+		t.Fatalf("NewClient() failed: %v", err)
+	}
+	defer cli.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	blockID := ton.BlockID{Workchain: -1, Shard: masterchainShard, Seqno: 50}
+	_, err = cli.LookupBlockWithProof(ctx, blockID, LookupBlockBySeqno, nil, nil)
+	if err == nil {
+		t.Fatalf("a lookup anchored at an unverified masterchain block must be rejected")
+	}
+	if errors.Is(err, ErrInvalidShardProof) || handler.blockProofRequestsNumber() == 0 {
+		t.Fatalf("the masterchain block must be verified before the lookup proof, got: %v", err)
+	}
+}
@@ -0,0 +1,148 @@
+package liteapi
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+
+	"github.com/tonkeeper/tongo/boc"
+	"github.com/tonkeeper/tongo/liteclient"
+	"github.com/tonkeeper/tongo/tl"
+	"github.com/tonkeeper/tongo/tlb"
+	"github.com/tonkeeper/tongo/ton"
+)
+
+// NonfinalCandidateID identifies a block candidate which is not finalized by validators yet.
+type NonfinalCandidateID struct {
+	BlockID          ton.BlockIDExt
+	Creator          ton.Bits256
+	CollatedDataHash ton.Bits256
+}
+
+// NonfinalCandidateInfo describes a block candidate and its approval progress.
+type NonfinalCandidateInfo struct {
+	ID NonfinalCandidateID
+	// Available is true if the candidate's data can be downloaded with GetNonfinalCandidate.
+	Available      bool
+	ApprovedWeight uint64
+	SignedWeight   uint64
+	TotalWeight    uint64
+}
+
+// NonfinalValidatorGroup describes a validator group collating the next block of a shard.
+type NonfinalValidatorGroup struct {
+	NextBlockID   ton.BlockID
+	CatchainSeqno uint32
+	Prev          []ton.BlockIDExt
+	Candidates    []NonfinalCandidateInfo
+}
+
+// NonfinalCandidate is a block candidate along with its collated data.
+type NonfinalCandidate struct {
+	ID           NonfinalCandidateID
+	Block        tlb.Block
+	CollatedData []byte
+}
+
+func nonfinalCandidateID(id liteclient.LiteServerNonfinalCandidateIdC) NonfinalCandidateID {
+	return NonfinalCandidateID{
+		BlockID:          id.BlockId.ToBlockIdExt(),
+		Creator:          ton.Bits256(id.Creator),
+		CollatedDataHash: ton.Bits256(id.CollatedDataHash),
+	}
+}
+
+// GetNonfinalValidatorGroups returns validator groups working on blocks which are not finalized yet.
+// workchain and shard narrow the result down if they are not nil.
+func (c *Client) GetNonfinalValidatorGroups(ctx context.Context, workchain *int32, shard *uint64) ([]NonfinalValidatorGroup, error) {
+	res, err := c.GetNonfinalValidatorGroupsRaw(ctx, workchain, shard)
+	if err != nil {
+		return nil, err
+	}
+	groups := make([]NonfinalValidatorGroup, 0, len(res.Groups))
+	for _, g := range res.Groups {
+		group := NonfinalValidatorGroup{
+			NextBlockID:   g.NextBlockId.ToBlockId(),
+			CatchainSeqno: g.CcSeqno,
+		}
+		for _, prev := range g.Prev {
+			group.Prev = append(group.Prev, prev.ToBlockIdExt())
+		}
+		for _, candidate := range g.Candidates {
+			group.Candidates = append(group.Candidates, NonfinalCandidateInfo{
+				ID:             nonfinalCandidateID(candidate.Id),
+				Available:      candidate.Available,
+				ApprovedWeight: candidate.ApprovedWeight,
+				SignedWeight:   candidate.SignedWeight,
+				TotalWeight:    candidate.TotalWeight,
+			})
+		}
+		groups = append(groups, group)
+	}
+	return groups, nil
+}
+
+func (c *Client) GetNonfinalValidatorGroupsRaw(ctx context.Context, workchain *int32, shard *uint64) (liteclient.LiteServerNonfinalValidatorGroupsC, error) {
+	var request liteclient.LiteServerNonfinalGetValidatorGroupsRequest
+	if workchain != nil {
+		wc := uint32(*workchain)
+		request.Mode |= 1
+		request.Wc = &wc
+	}
+	if shard != nil {
+		request.Mode |= 2
+		request.Shard = shard
+	}
+	return query(ctx, c, func(client *liteclient.Client, _ ton.BlockIDExt) (liteclient.LiteServerNonfinalValidatorGroupsC, error) {
+		return client.LiteServerNonfinalGetValidatorGroups(ctx, request)
+	})
+}
+
+// GetNonfinalCandidate downloads a block candidate which is not finalized yet.
+// The candidate's data is checked against its id.
+func (c *Client) GetNonfinalCandidate(ctx context.Context, id NonfinalCandidateID) (NonfinalCandidate, error) {
+	res, err := c.GetNonfinalCandidateRaw(ctx, id)
+	if err != nil {
+		return NonfinalCandidate{}, err
+	}
+	if nonfinalCandidateID(res.Id) != id {
+		return NonfinalCandidate{}, fmt.Errorf("got candidate %v instead of %v", res.Id.BlockId.ToBlockIdExt().BlockID, id.BlockID.BlockID)
+	}
+	if ton.Bits256(sha256.Sum256(res.Data)) != id.BlockID.FileHash {
+		return NonfinalCandidate{}, fmt.Errorf("candidate data doesn't match file hash")
+	}
+	if ton.Bits256(sha256.Sum256(res.CollatedData)) != id.CollatedDataHash {
+		return NonfinalCandidate{}, fmt.Errorf("candidate collated data doesn't match its hash")
+	}
+	cells, err := boc.DeserializeBoc(res.Data)
+	if err != nil {
+		return NonfinalCandidate{}, err
+	}
+	if len(cells) != 1 {
+		return NonfinalCandidate{}, boc.ErrNotSingleRoot
+	}
+	hash, err := cells[0].Hash256()
+	if err != nil {
+		return NonfinalCandidate{}, err
+	}
+	if hash != id.BlockID.RootHash {
+		return NonfinalCandidate{}, fmt.Errorf("candidate data doesn't match root hash")
+	}
+	var block tlb.Block
+	if err := tlb.Unmarshal(cells[0], &block); err != nil {
+		return NonfinalCandidate{}, err
+	}
+	return NonfinalCandidate{ID: id, Block: block, CollatedData: res.CollatedData}, nil
+}
+
+func (c *Client) GetNonfinalCandidateRaw(ctx context.Context, id NonfinalCandidateID) (liteclient.LiteServerNonfinalCandidateC, error) {
+	return query(ctx, c, func(client *liteclient.Client, _ ton.BlockIDExt) (liteclient.LiteServerNonfinalCandidateC, error) {
+		return client.LiteServerNonfinalGetCandidate(ctx, liteclient.LiteServerNonfinalGetCandidateRequest{
+			Id: liteclient.LiteServerNonfinalCandidateIdC{
+				BlockId:          liteclient.BlockIDExt(id.BlockID),
+				Creator:          tl.Int256(id.Creator),
+				CollatedDataHash: tl.Int256(id.CollatedDataHash),
+			},
+		})
+	})
+}
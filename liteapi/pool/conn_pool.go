@@ -34,13 +34,24 @@ const (
 //  2. FirstWorkingConnection - it'll switch to the first working connection.
 //
 // For both strategies, a connection has to be not more than 1 block behind the head of masterchain to be considered as working.
+// Connections ejected by their circuit breakers are skipped, see Policy.
 type ConnPool struct {
 	strategy           Strategy
 	updateBestInterval time.Duration
+	policy             Policy
+	observer           Observer
+
+	breakersMu sync.Mutex
+	breakers   map[conn]*circuitBreaker
 
 	masterHeadUpdatedCh chan masterHeadUpdated
 
+	// updateMu serializes UpdateServers calls.
+	updateMu sync.Mutex
+
 	mu         sync.RWMutex
+	settings   connSettings
+	nextConnID int
 	conns      []conn
 	bestConn   conn
 	waitListID uint64
@@ -51,6 +62,8 @@ type ConnPool struct {
 // used to implement tests.
 type conn interface {
 	ID() int
+	ServerHost() string
+	ServerKey() string
 	MasterHead() ton.BlockIDExt
 	SetMasterHead(ton.BlockIDExt)
 	IsOK() bool
@@ -59,57 +72,65 @@ type conn interface {
 	IsArchiveNode() bool
 	AverageRoundTrip() time.Duration
 	Status() ConnStatus
+	Close()
+}
+
+// Options holds parameters to configure a connections pool.
+type Options struct {
+	Policy   Policy
+	Observer Observer
+}
+
+type Option func(o *Options)
+
+// WithPolicy sets a policy for retries and circuit breakers.
+func WithPolicy(policy Policy) Option {
+	return func(o *Options) {
+		o.Policy = policy
+	}
+}
+
+// WithObserver sets an observer to receive events of the pool and its lite server clients.
+func WithObserver(observer Observer) Option {
+	return func(o *Options) {
+		o.Observer = observer
+	}
 }
 
 // New returns a new instance of a connections pool.
-func New(strategy Strategy) *ConnPool {
+func New(strategy Strategy, opts ...Option) *ConnPool {
+	options := &Options{
+		Policy:   DefaultPolicy(),
+		Observer: NopObserver{},
+	}
+	for _, o := range opts {
+		o(options)
+	}
 	return &ConnPool{
 		strategy:            strategy,
 		updateBestInterval:  updateBestConnectionInterval,
+		policy:              options.Policy,
+		observer:            options.Observer,
+		breakers:            map[conn]*circuitBreaker{},
 		waitList:            map[uint64]chan ton.BlockIDExt{},
 		masterHeadUpdatedCh: make(chan masterHeadUpdated, 10),
 	}
 }
 
 func (p *ConnPool) InitializeConnections(ctx context.Context, timeout time.Duration, maxConnections int, workersPerConnection int, detectArchiveNodes bool, servers []config.LiteServer) chan error {
+	p.mu.Lock()
+	p.settings = connSettings{
+		timeout:              timeout,
+		maxConnections:       maxConnections,
+		workersPerConnection: workersPerConnection,
+		detectArchiveNodes:   detectArchiveNodes,
+	}
+	p.nextConnID = len(servers)
+	p.mu.Unlock()
+
 	ch := make(chan error, 1)
 	go func() {
-		clientsCh := make(chan clientWrapper, len(servers))
-		for connID, server := range servers {
-			go func(connID int, server config.LiteServer) {
-				cli, _ := connect(ctx, timeout, server, workersPerConnection)
-				// TODO: log error
-				clientsCh <- clientWrapper{
-					connID:     connID,
-					cli:        cli,
-					serverHost: server.Host,
-				}
-			}(connID, server)
-		}
-
-		processedConnections := 0
-		for {
-			if processedConnections == len(servers) {
-				break
-			}
-			select {
-			case <-ctx.Done():
-				break
-			case wrapper := <-clientsCh:
-				processedConnections += 1
-				if wrapper.cli == nil {
-					continue
-				}
-				if p.ConnectionsNumber() < maxConnections {
-					c := p.addConnection(wrapper.connID, wrapper.cli, wrapper.serverHost)
-					go c.Run(context.TODO(), detectArchiveNodes)
-				}
-				if p.ConnectionsNumber() == maxConnections {
-					processedConnections = len(servers)
-					break
-				}
-			}
-		}
+		p.connectServers(ctx, servers, 0, maxConnections)
 		if p.ConnectionsNumber() == 0 {
 			ch <- fmt.Errorf("all liteservers are unavailable")
 			return
@@ -119,37 +140,196 @@ func (p *ConnPool) InitializeConnections(ctx context.Context, timeout time.Durat
 	return ch
 }
 
-func connect(ctx context.Context, timeout time.Duration, server config.LiteServer, n int) (*liteclient.Client, error) {
+// connectServers connects to the given servers concurrently and adds up to limit connections to the pool.
+// Connections get IDs starting from firstID in the order of servers.
+// It returns a number of added connections.
+func (p *ConnPool) connectServers(ctx context.Context, servers []config.LiteServer, firstID int, limit int) int {
+	p.mu.RLock()
+	settings := p.settings
+	p.mu.RUnlock()
+
+	clientsCh := make(chan clientWrapper, len(servers))
+	for i, server := range servers {
+		go func(connID int, server config.LiteServer) {
+			cli, _ := connect(ctx, settings.timeout, server, settings.workersPerConnection, p.observer)
+			clientsCh <- clientWrapper{
+				connID: connID,
+				cli:    cli,
+				server: server,
+			}
+		}(firstID+i, server)
+	}
+	added := 0
+	for processed := 0; processed < len(servers); processed++ {
+		var wrapper clientWrapper
+		select {
+		case <-ctx.Done():
+			go closeClients(clientsCh, len(servers)-processed)
+			return added
+		case wrapper = <-clientsCh:
+		}
+		if wrapper.cli == nil {
+			continue
+		}
+		if added >= limit {
+			wrapper.cli.Close()
+			continue
+		}
+		runCtx, cancel := context.WithCancel(context.Background())
+		c := p.addConnection(wrapper.connID, wrapper.cli, wrapper.server, cancel)
+		go c.Run(runCtx, settings.detectArchiveNodes)
+		added += 1
+	}
+	return added
+}
+
+// closeClients waits for n more results of connect and closes the connected clients.
+func closeClients(clientsCh chan clientWrapper, n int) {
+	for i := 0; i < n; i++ {
+		if wrapper := <-clientsCh; wrapper.cli != nil {
+			wrapper.cli.Close()
+		}
+	}
+}
+
+// UpdateServers replaces lite servers of the pool without interrupting requests.
+// Connections to lite servers which are not in the given list are closed,
+// and new lite servers are connected to keep the number of connections given to InitializeConnections.
+// If none of the given lite servers is available, the pool keeps its connections and returns an error.
+func (p *ConnPool) UpdateServers(ctx context.Context, servers []config.LiteServer) error {
+	p.updateMu.Lock()
+	defer p.updateMu.Unlock()
+
+	wanted := make(map[config.LiteServer]struct{}, len(servers))
+	for _, server := range servers {
+		wanted[server] = struct{}{}
+	}
+	p.mu.Lock()
+	maxConnections := p.settings.maxConnections
+	if maxConnections == 0 {
+		maxConnections = len(servers)
+	}
+	current := make(map[config.LiteServer]struct{}, len(p.conns))
+	kept := 0
+	for _, c := range p.conns {
+		server := config.LiteServer{Host: c.ServerHost(), Key: c.ServerKey()}
+		current[server] = struct{}{}
+		if _, ok := wanted[server]; ok {
+			kept += 1
+		}
+	}
+	var newServers []config.LiteServer
+	for _, server := range servers {
+		if _, ok := current[server]; !ok {
+			newServers = append(newServers, server)
+		}
+	}
+	firstID := p.nextConnID
+	p.nextConnID += len(newServers)
+	p.mu.Unlock()
+
+	added := p.connectServers(ctx, newServers, firstID, maxConnections-kept)
+	if kept+added == 0 {
+		return fmt.Errorf("all liteservers are unavailable")
+	}
+	p.removeConnections(wanted)
+	return nil
+}
+
+// Close closes all connections of the pool.
+func (p *ConnPool) Close() {
+	p.removeConnections(nil)
+}
+
+// removeConnections closes connections to lite servers which are not in the given set.
+func (p *ConnPool) removeConnections(servers map[config.LiteServer]struct{}) {
+	p.mu.Lock()
+	var removed []conn
+	conns := p.conns[:0:0]
+	for _, c := range p.conns {
+		if _, ok := servers[config.LiteServer{Host: c.ServerHost(), Key: c.ServerKey()}]; ok {
+			conns = append(conns, c)
+			continue
+		}
+		removed = append(removed, c)
+	}
+	p.conns = conns
+	for _, c := range removed {
+		if c == p.bestConn && len(conns) > 0 {
+			p.bestConn = conns[0]
+		}
+	}
+	if len(conns) == 0 {
+		p.bestConn = nil
+	}
+	p.mu.Unlock()
+
+	p.breakersMu.Lock()
+	for _, c := range removed {
+		delete(p.breakers, c)
+	}
+	p.breakersMu.Unlock()
+	for _, c := range removed {
+		c.Close()
+	}
+	if len(removed) > 0 {
+		p.updateBest()
+	}
+}
+
+func connect(ctx context.Context, timeout time.Duration, server config.LiteServer, n int, observer Observer) (*liteclient.Client, error) {
+	if observer == nil {
+		observer = NopObserver{}
+	}
 	serverPubkey, err := base64.StdEncoding.DecodeString(server.Key)
 	if err != nil {
+		observer.ConnectionFailed(server.Host, err)
 		return nil, err
 	}
 	c, err := liteclient.NewConnection(ctx, serverPubkey, server.Host)
 	if err != nil {
+		observer.ConnectionFailed(server.Host, err)
 		return nil, err
 	}
-	cli := liteclient.NewClient(c, liteclient.OptionTimeout(timeout), liteclient.OptionWorkersPerConnection(n))
+	cli := liteclient.NewClient(c, liteclient.OptionTimeout(timeout), liteclient.OptionWorkersPerConnection(n), liteclient.OptionObserver(observer))
 	if _, err := cli.LiteServerGetMasterchainInfo(ctx); err != nil {
+		cli.Close()
+		observer.ConnectionFailed(server.Host, err)
 		return nil, err
 	}
+	observer.Connected(server.Host, false)
 	return cli, nil
 }
 
 type clientWrapper struct {
-	connID     int
-	cli        *liteclient.Client
-	serverHost string
+	connID int
+	cli    *liteclient.Client
+	server config.LiteServer
 }
 
-func (p *ConnPool) addConnection(connID int, cli *liteclient.Client, serverHost string) *connection {
+// connSettings holds parameters of connections given to InitializeConnections.
+type connSettings struct {
+	timeout              time.Duration
+	maxConnections       int
+	workersPerConnection int
+	detectArchiveNodes   bool
+}
+
+func (p *ConnPool) addConnection(connID int, cli *liteclient.Client, server config.LiteServer, cancel context.CancelFunc) *connection {
 	p.mu.Lock()
 	defer p.mu.Unlock()
+	breaker := &circuitBreaker{}
 	c := &connection{
 		id:                  connID,
-		serverHost:          serverHost,
+		serverHost:          server.Host,
+		serverKey:           server.Key,
 		client:              cli,
+		cancel:              cancel,
 		masterHeadUpdatedCh: p.masterHeadUpdatedCh,
+		policy:              p.policy,
+		breaker:             breaker,
 	}
+	p.setBreaker(c, breaker)
 	p.conns = append(p.conns, c)
 	sort.Slice(p.conns, func(i, j int) bool {
 		return p.conns[i].ID() < p.conns[j].ID()
@@ -185,29 +365,51 @@ func (p *ConnPool) updateBest() {
 		return
 	}
 
-	var maxSeqno uint32
-	for _, c := range p.conns {
-		masterSeqno := c.MasterHead().Seqno
-		if maxSeqno < masterSeqno {
-			maxSeqno = masterSeqno
+	now := time.Now()
+	maxSeqno := p.maxSeqno()
+	if p.observer != nil {
+		for _, c := range p.conns {
+			if head := c.MasterHead().Seqno; head <= maxSeqno {
+				p.observer.MasterHeadLag(c.ServerHost(), maxSeqno-head)
+			}
+		}
+	}
+	if p.policy.MaxHeadLag > 0 {
+		for _, c := range p.conns {
+			if c.MasterHead().Seqno+p.policy.MaxHeadLag < maxSeqno {
+				p.breaker(c).eject(p.policy, now)
+			}
 		}
 	}
 
 	switch p.strategy {
 	case BestPingStrategy:
-		if bestConn := p.findBestPingConnection(maxSeqno); bestConn != nil {
+		if bestConn := p.findBestPingConnection(maxSeqno, now); bestConn != nil {
 			p.bestConn = bestConn
 		}
 	case FirstWorkingConnection:
-		if bestConn := p.findFirstWorkingConnection(maxSeqno); bestConn != nil {
+		if bestConn := p.findFirstWorkingConnection(maxSeqno, now); bestConn != nil {
 			p.bestConn = bestConn
 		}
 	}
 }
 
-func (p *ConnPool) findFirstWorkingConnection(maxSeqno uint32) conn {
+// maxSeqno returns the latest masterchain seqno known to the pool.
+// The caller must hold p.mu.
+func (p *ConnPool) maxSeqno() uint32 {
+	var maxSeqno uint32
+	for _, c := range p.conns {
+		masterSeqno := c.MasterHead().Seqno
+		if maxSeqno < masterSeqno {
+			maxSeqno = masterSeqno
+		}
+	}
+	return maxSeqno
+}
+
+func (p *ConnPool) findFirstWorkingConnection(maxSeqno uint32, now time.Time) conn {
 	for _, c := range p.conns {
-		if !c.IsOK() {
+		if !c.IsOK() || !p.breaker(c).available(now) {
 			continue
 		}
 		if c.MasterHead().Seqno+1 >= maxSeqno {
@@ -217,10 +419,10 @@ func (p *ConnPool) findFirstWorkingConnection(maxSeqno uint32) conn {
 	return nil
 }
 
-func (p *ConnPool) findBestPingConnection(maxSeqno uint32) conn {
+func (p *ConnPool) findBestPingConnection(maxSeqno uint32, now time.Time) conn {
 	var bestConn conn
 	for _, c := range p.conns {
-		if !c.IsOK() {
+		if !c.IsOK() || !p.breaker(c).available(now) {
 			continue
 		}
 		if c.MasterHead().Seqno+1 < maxSeqno {
@@ -239,11 +441,35 @@ func (p *ConnPool) bestConnection() conn {
 	return p.bestConn
 }
 
+// breaker returns a circuit breaker of the given connection.
+func (p *ConnPool) breaker(c conn) *circuitBreaker {
+	p.breakersMu.Lock()
+	defer p.breakersMu.Unlock()
+	if p.breakers == nil {
+		p.breakers = map[conn]*circuitBreaker{}
+	}
+	b, ok := p.breakers[c]
+	if !ok {
+		b = &circuitBreaker{}
+		p.breakers[c] = b
+	}
+	return b
+}
+
+func (p *ConnPool) setBreaker(c conn, b *circuitBreaker) {
+	p.breakersMu.Lock()
+	defer p.breakersMu.Unlock()
+	p.breakers[c] = b
+}
+
 type MasterchainInfoClient struct {
 	conn conn
 }
 
 func (s *MasterchainInfoClient) LiteServerGetMasterchainInfoExt(ctx context.Context, request liteclient.LiteServerGetMasterchainInfoExtRequest) (res liteclient.LiteServerMasterchainInfoExtC, err error) {
+	if s.conn == nil {
+		return liteclient.LiteServerMasterchainInfoExtC{}, ErrNoConnections
+	}
 	info, err := s.conn.Client().LiteServerGetMasterchainInfoExt(ctx, request)
 	if err != nil {
 		return liteclient.LiteServerMasterchainInfoExtC{}, err
@@ -253,6 +479,9 @@ func (s *MasterchainInfoClient) LiteServerGetMasterchainInfoExt(ctx context.Cont
 }
 
 func (s *MasterchainInfoClient) LiteServerGetMasterchainInfo(ctx context.Context) (liteclient.LiteServerMasterchainInfoC, error) {
+	if s.conn == nil {
+		return liteclient.LiteServerMasterchainInfoC{}, ErrNoConnections
+	}
 	info, err := s.conn.Client().LiteServerGetMasterchainInfo(ctx)
 	if err != nil {
 		return liteclient.LiteServerMasterchainInfoC{}, err
@@ -269,13 +498,21 @@ func (p *ConnPool) BestMasterchainInfoClient() *MasterchainInfoClient {
 
 // BestMasterchainClient returns a liteclient and its known masterchain head.
 func (p *ConnPool) BestMasterchainClient(ctx context.Context) (*liteclient.Client, ton.BlockIDExt, error) {
+	c, head, err := p.bestMasterchainConnection(ctx)
+	if err != nil {
+		return nil, ton.BlockIDExt{}, err
+	}
+	return c.Client(), head, nil
+}
+
+func (p *ConnPool) bestMasterchainConnection(ctx context.Context) (conn, ton.BlockIDExt, error) {
 	bestConnection := p.bestConnection()
 	if bestConnection == nil {
 		return nil, ton.BlockIDExt{}, ErrNoConnections
 	}
 	masterHead := bestConnection.MasterHead()
 	if masterHead.Seqno > 0 {
-		return bestConnection.Client(), bestConnection.MasterHead(), nil
+		return bestConnection, masterHead, nil
 	}
 	// so this client is not initialized yet,
 	// let's wait for it to be initialized.
@@ -286,16 +523,28 @@ func (p *ConnPool) BestMasterchainClient(ctx context.Context) (*liteclient.Clien
 	case <-ctx.Done():
 		return nil, ton.BlockIDExt{}, ctx.Err()
 	case head := <-ch:
-		return bestConnection.Client(), head, nil
+		return bestConnection, head, nil
 	}
 }
+
 func (p *ConnPool) BestArchiveClient(ctx context.Context) (*liteclient.Client, ton.BlockIDExt, error) {
+	c, err := p.bestArchiveConnection()
+	if err != nil {
+		return nil, ton.BlockIDExt{}, err
+	}
+	return c.Client(), c.MasterHead(), nil
+}
+
+func (p *ConnPool) bestArchiveConnection() (conn, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	now := time.Now()
 	for _, c := range p.conns {
-		if c.IsOK() && c.IsArchiveNode() {
-			return c.Client(), c.MasterHead(), nil
+		if c.IsOK() && c.IsArchiveNode() && p.breaker(c).available(now) {
+			return c, nil
 		}
 	}
-	return nil, ton.BlockIDExt{}, fmt.Errorf("no archive nodes available")
+	return nil, fmt.Errorf("no archive nodes available")
 }
 
 // BestClientByAccountID returns a liteclient and its known masterchain head.
@@ -312,6 +561,112 @@ func (p *ConnPool) BestClientByBlockID(ctx context.Context, blockID ton.BlockID)
 	return server, err
 }
 
+// Do calls f with the best liteclient and its known masterchain head.
+// If f fails because of a connection problem,
+// the call is repeated on another healthy connection as many times as the pool's policy allows for the given kind of request.
+// An error returned by a lite server is considered as an answer and is never retried.
+// If archiveRequired is true, only archive nodes are used.
+func (p *ConnPool) Do(ctx context.Context, kind RequestKind, archiveRequired bool, f func(client *liteclient.Client, masterHead ton.BlockIDExt) error) error {
+	return p.do(ctx, kind, archiveRequired, 0, f)
+}
+
+// DoAt works the same way as Do but uses only connections
+// which know the masterchain block with the given seqno.
+func (p *ConnPool) DoAt(ctx context.Context, kind RequestKind, seqno uint32, f func(client *liteclient.Client, masterHead ton.BlockIDExt) error) error {
+	return p.do(ctx, kind, false, seqno, f)
+}
+
+func (p *ConnPool) do(ctx context.Context, kind RequestKind, archiveRequired bool, minSeqno uint32, f func(client *liteclient.Client, masterHead ton.BlockIDExt) error) error {
+	var (
+		c    conn
+		head ton.BlockIDExt
+		err  error
+	)
+	if archiveRequired {
+		if c, err = p.bestArchiveConnection(); err != nil {
+			return err
+		}
+		head = c.MasterHead()
+	} else if c, head, err = p.bestMasterchainConnection(ctx); err != nil {
+		return err
+	}
+	tried := map[conn]struct{}{}
+	if head.Seqno < minSeqno {
+		if c = p.nextConnection(tried, archiveRequired, minSeqno); c == nil {
+			return fmt.Errorf("no connections with masterchain block %v", minSeqno)
+		}
+		head = c.MasterHead()
+	}
+	for attempt := 0; ; attempt++ {
+		tried[c] = struct{}{}
+		p.breaker(c).admit(time.Now())
+		err = f(c.Client(), head)
+		if !isConnectionFailure(err) {
+			p.breaker(c).success()
+			return err
+		}
+		if ctx.Err() != nil {
+			return err
+		}
+		p.reportFailure(c)
+		if attempt >= p.policy.Retries[kind] {
+			return err
+		}
+		next := p.nextConnection(tried, archiveRequired, minSeqno)
+		if next == nil {
+			return err
+		}
+		if sleep(ctx, p.policy.Backoff.Delay(attempt)) != nil {
+			return err
+		}
+		c, head = next, next.MasterHead()
+	}
+}
+
+// reportFailure records a failed request and switches to another connection if the failed one gets ejected.
+func (p *ConnPool) reportFailure(c conn) {
+	if !p.breaker(c).failure(p.policy, time.Now()) {
+		return
+	}
+	if c == p.bestConnection() {
+		p.updateBest()
+	}
+}
+
+// nextConnection returns a healthy connection with the best ping which has not been tried yet.
+// If minSeqno is not zero, the connection has to know the masterchain block with this seqno,
+// otherwise, it has to be not more than 1 block behind the head of masterchain.
+func (p *ConnPool) nextConnection(tried map[conn]struct{}, archiveRequired bool, minSeqno uint32) conn {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	now := time.Now()
+	maxSeqno := p.maxSeqno()
+	var next conn
+	for _, c := range p.conns {
+		if _, ok := tried[c]; ok {
+			continue
+		}
+		if !c.IsOK() || !p.breaker(c).available(now) {
+			continue
+		}
+		if archiveRequired && !c.IsArchiveNode() {
+			continue
+		}
+		head := c.MasterHead().Seqno
+		if minSeqno > 0 && head < minSeqno {
+			continue
+		}
+		if minSeqno == 0 && (head == 0 || head+1 < maxSeqno) {
+			continue
+		}
+		if next == nil || c.AverageRoundTrip() < next.AverageRoundTrip() {
+			next = c
+		}
+	}
+	return next
+}
+
 // ConnectionsNumber returns a number of connections in this pool.
 func (p *ConnPool) ConnectionsNumber() int {
 	p.mu.RLock()
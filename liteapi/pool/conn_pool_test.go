@@ -2,6 +2,9 @@ package pool
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"reflect"
 	"testing"
 	"time"
 
@@ -14,6 +17,7 @@ type mockConn struct {
 	seqno        uint32
 	isOK         bool
 	avgRoundTrip time.Duration
+	client       *liteclient.Client
 }
 
 func (m *mockConn) AverageRoundTrip() time.Duration {
@@ -28,6 +32,17 @@ func (m *mockConn) ID() int {
 	return 0
 }
 
+func (m *mockConn) ServerHost() string {
+	return fmt.Sprintf("server-%d", m.id)
+}
+
+func (m *mockConn) ServerKey() string {
+	return ""
+}
+
+func (m *mockConn) Close() {
+}
+
 func (m *mockConn) MasterHead() ton.BlockIDExt {
 	return ton.BlockIDExt{BlockID: ton.BlockID{Seqno: m.seqno}}
 }
@@ -44,7 +59,7 @@ func (m *mockConn) IsOK() bool {
 }
 
 func (m *mockConn) Client() *liteclient.Client {
-	panic("implement me")
+	return m.client
 }
 
 func (m *mockConn) Status() ConnStatus {
@@ -161,3 +176,96 @@ func TestConnPool_updateBest(t *testing.T) {
 		})
 	}
 }
+
+type lagObserver struct {
+	NopObserver
+	lags map[string]uint32
+}
+
+func (o *lagObserver) MasterHeadLag(host string, lag uint32) {
+	o.lags[host] = lag
+}
+
+func TestConnPool_updateBest_observer(t *testing.T) {
+	observer := &lagObserver{lags: map[string]uint32{}}
+	p := &ConnPool{
+		conns: []conn{
+			&mockConn{seqno: 100, isOK: true, id: 0},
+			&mockConn{seqno: 97, isOK: true, id: 1},
+			&mockConn{seqno: 0, isOK: false, id: 2},
+		},
+		strategy: BestPingStrategy,
+		observer: observer,
+	}
+	p.updateBest()
+	want := map[string]uint32{"server-0": 0, "server-1": 3, "server-2": 100}
+	if !reflect.DeepEqual(observer.lags, want) {
+		t.Fatalf("want lags: %v, got: %v", want, observer.lags)
+	}
+}
+
+func TestConnPool_Quorum(t *testing.T) {
+	p := &ConnPool{
+		conns: []conn{
+			&mockConn{seqno: 100, isOK: true, id: 0, avgRoundTrip: 30 * time.Millisecond},
+			&mockConn{seqno: 98, isOK: true, id: 1, avgRoundTrip: 10 * time.Millisecond},
+			&mockConn{seqno: 100, isOK: false, id: 2},
+			&mockConn{seqno: 99, isOK: true, id: 3, avgRoundTrip: 20 * time.Millisecond},
+			&mockConn{seqno: 100, isOK: true, id: 4, avgRoundTrip: 40 * time.Millisecond},
+		},
+	}
+	hosts := make([]string, 2)
+	heads := make([]uint32, 2)
+	errs, err := p.Quorum(context.Background(), 2, 0, func(i int, serverHost string, _ *liteclient.Client, masterHead ton.BlockIDExt) error {
+		hosts[i], heads[i] = serverHost, masterHead.Seqno
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Quorum() failed: %v", err)
+	}
+	if len(errs) != 2 {
+		t.Fatalf("want 2 errors, got: %v", errs)
+	}
+	if want := []string{"server-1", "server-3"}; !reflect.DeepEqual(hosts, want) {
+		t.Fatalf("want connections with the best ping: %v, got: %v", want, hosts)
+	}
+	if want := []uint32{98, 98}; !reflect.DeepEqual(heads, want) {
+		t.Fatalf("want the oldest head: %v, got: %v", want, heads)
+	}
+
+	hosts = make([]string, 2)
+	if _, err := p.Quorum(context.Background(), 2, 100, func(i int, serverHost string, _ *liteclient.Client, _ ton.BlockIDExt) error {
+		hosts[i] = serverHost
+		return nil
+	}); err != nil {
+		t.Fatalf("Quorum() failed: %v", err)
+	}
+	if want := []string{"server-0", "server-4"}; !reflect.DeepEqual(hosts, want) {
+		t.Fatalf("want connections which know the block: %v, got: %v", want, hosts)
+	}
+	if _, err := p.Quorum(context.Background(), 3, 100, func(int, string, *liteclient.Client, ton.BlockIDExt) error { return nil }); err == nil {
+		t.Fatalf("want error when there are not enough connections")
+	}
+}
+
+func TestConnPool_Close(t *testing.T) {
+	conns := []conn{
+		&mockConn{seqno: 100, isOK: true, id: 0, client: &liteclient.Client{}},
+		&mockConn{seqno: 100, isOK: true, id: 1, client: &liteclient.Client{}},
+	}
+	p := &ConnPool{
+		conns:    conns,
+		bestConn: conns[0],
+		strategy: BestPingStrategy,
+	}
+	p.Close()
+	if p.bestConnection() != nil {
+		t.Fatalf("closed connection must not be the best one")
+	}
+	if _, _, err := p.BestMasterchainClient(context.Background()); !errors.Is(err, ErrNoConnections) {
+		t.Fatalf("want ErrNoConnections, got: %v", err)
+	}
+	if _, err := p.BestMasterchainInfoClient().LiteServerGetMasterchainInfo(context.Background()); !errors.Is(err, ErrNoConnections) {
+		t.Fatalf("want ErrNoConnections, got: %v", err)
+	}
+}
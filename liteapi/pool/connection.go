@@ -12,11 +12,18 @@ import (
 type connection struct {
 	id         int
 	serverHost string
+	serverKey  string
 	client     *liteclient.Client
+	// cancel stops Run, it is nil if the connection is not a part of a pool.
+	cancel context.CancelFunc
 
 	// masterHeadUpdatedCh is used to send a notification when a known master head is changed.
 	masterHeadUpdatedCh chan masterHeadUpdated
 
+	policy Policy
+	// breaker is shared with the pool, it can be nil if the connection is not a part of a pool.
+	breaker *circuitBreaker
+
 	mu sync.RWMutex
 	// masterHead is the latest known masterchain head.
 	masterHead ton.BlockIDExt
@@ -33,34 +40,49 @@ func (c *connection) Run(ctx context.Context, detectArchive bool) {
 		go func() {
 			ctx, cancel := context.WithTimeout(ctx, 10*time.Minute)
 			defer cancel()
-			// TODO: retry several times on error
-			seqno, err := c.FindMinAvailableMasterchainSeqno(ctx)
-			if err != nil {
+			for attempt := 0; attempt <= c.policy.Retries[ReadRequest]; attempt++ {
+				seqno, err := c.FindMinAvailableMasterchainSeqno(ctx)
+				if err != nil {
+					if sleep(ctx, c.retryDelay(attempt)) != nil {
+						return
+					}
+					continue
+				}
+				if seqno == 2 {
+					c.setArchive(true)
+				}
 				return
 			}
-			if seqno == 2 {
-				c.setArchive(true)
-			}
 		}()
 	}
+	attempt := 0
 	for {
 		var head ton.BlockIDExt
 		for {
+			c.admit()
 			res, err := c.client.LiteServerGetMasterchainInfo(ctx)
 			if err != nil {
-				// TODO: log error
-				time.Sleep(1000 * time.Millisecond)
+				c.failure()
+				if sleep(ctx, c.retryDelay(attempt)) != nil {
+					return
+				}
+				attempt++
 				continue
 			}
+			c.success()
 			head = res.Last.ToBlockIdExt()
 			break
 		}
 		c.SetMasterHead(head)
 		for {
+			c.admit()
 			res, err := c.client.WaitMasterchainBlock(ctx, head.Seqno+1, 15_000)
 			if err != nil {
-				// TODO: log error
-				time.Sleep(1000 * time.Millisecond)
+				c.failure()
+				if sleep(ctx, c.retryDelay(attempt)) != nil {
+					return
+				}
+				attempt++
 				// we want to request seqno again with LiteServerGetMasterchainInfo
 				// to avoid situation when this server has been offline for too long,
 				// and it doesn't contain a block with the latest known seqno anymore.
@@ -69,12 +91,41 @@ func (c *connection) Run(ctx context.Context, detectArchive bool) {
 			if ctx.Err() != nil {
 				return
 			}
+			c.success()
+			attempt = 0
 			head = res.Id.ToBlockIdExt()
 			c.SetMasterHead(head)
 		}
 	}
 }
 
+// retryDelay returns a delay before the next attempt to reach a lite server.
+func (c *connection) retryDelay(attempt int) time.Duration {
+	if delay := c.policy.Backoff.Delay(attempt); delay > 0 {
+		return delay
+	}
+	return time.Second
+}
+
+// admit lets the breaker know that a request is sent through the connection.
+func (c *connection) admit() {
+	if c.breaker != nil {
+		c.breaker.admit(time.Now())
+	}
+}
+
+func (c *connection) success() {
+	if c.breaker != nil {
+		c.breaker.success()
+	}
+}
+
+func (c *connection) failure() {
+	if c.breaker != nil {
+		c.breaker.failure(c.policy, time.Now())
+	}
+}
+
 // IsOK returns true if there is no problems with the underlying liteclient and its connection to a lite server.
 func (c *connection) IsOK() bool {
 	return c.client.IsOK()
@@ -84,6 +135,22 @@ func (c *connection) ID() int {
 	return c.id
 }
 
+func (c *connection) ServerHost() string {
+	return c.serverHost
+}
+
+func (c *connection) ServerKey() string {
+	return c.serverKey
+}
+
+// Close stops the connection and closes its client.
+func (c *connection) Close() {
+	if c.cancel != nil {
+		c.cancel()
+	}
+	c.client.Close()
+}
+
 func (c *connection) Client() *liteclient.Client {
 	return c.client
 }
@@ -160,6 +227,8 @@ type ConnStatus struct {
 	ServerHost string
 	Connected  bool
 	Archive    bool
+	// Ejected is true if the connection is ejected from the pool by its circuit breaker.
+	Ejected bool
 }
 
 func (c *connection) Status() ConnStatus {
@@ -167,5 +236,6 @@ func (c *connection) Status() ConnStatus {
 		ServerHost: c.serverHost,
 		Connected:  c.IsOK(),
 		Archive:    c.IsArchiveNode(),
+		Ejected:    c.breaker != nil && c.breaker.ejected(time.Now()),
 	}
 }
@@ -0,0 +1,43 @@
+package pool
+
+import (
+	"log/slog"
+
+	"github.com/tonkeeper/tongo/liteclient"
+)
+
+// Observer receives events of a connections pool and its lite server clients.
+// Methods are called synchronously, so they must not block.
+// Embed NopObserver to handle only some of the events.
+type Observer interface {
+	liteclient.Observer
+	// MasterHeadLag is called periodically for every connection of the pool
+	// with a number of masterchain blocks the connection's head is behind the latest head known to the pool.
+	MasterHeadLag(host string, lag uint32)
+}
+
+// NopObserver ignores all events.
+type NopObserver struct {
+	liteclient.NopObserver
+}
+
+var _ Observer = NopObserver{}
+
+func (NopObserver) MasterHeadLag(host string, lag uint32) {}
+
+// SlogObserver writes events to a structured logger.
+type SlogObserver struct {
+	liteclient.SlogObserver
+}
+
+var _ Observer = SlogObserver{}
+
+// NewSlogObserver returns an observer writing events to the given logger.
+// A nil logger means slog.Default().
+func NewSlogObserver(logger *slog.Logger) SlogObserver {
+	return SlogObserver{SlogObserver: liteclient.NewSlogObserver(logger)}
+}
+
+func (o SlogObserver) MasterHeadLag(host string, lag uint32) {
+	o.Logger.Debug("liteclient masterchain head lag", "host", host, "lag", lag)
+}
@@ -0,0 +1,210 @@
+package pool
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/tonkeeper/tongo/liteclient"
+)
+
+// RequestKind classifies requests to lite servers,
+// a policy decides how many times a request is retried based on its kind.
+type RequestKind int
+
+const (
+	// ReadRequest is a read-only query, it is safe to repeat it on another lite server.
+	ReadRequest RequestKind = iota
+	// WaitRequest waits for a masterchain block to appear.
+	WaitRequest
+	// SendRequest sends an external message to the blockchain.
+	// Repeating it on another lite server can broadcast the message twice.
+	SendRequest
+)
+
+// Backoff describes an exponential backoff.
+type Backoff struct {
+	// Initial is a delay before the first retry.
+	Initial time.Duration
+	// Max limits a delay between two attempts.
+	Max time.Duration
+	// Multiplier is a factor a delay grows by after each attempt.
+	Multiplier float64
+}
+
+// Delay returns a delay before the given attempt, attempts are counted from 0.
+func (b Backoff) Delay(attempt int) time.Duration {
+	delay := b.Initial
+	for i := 0; i < attempt && delay < b.Max; i++ {
+		delay = time.Duration(float64(delay) * b.Multiplier)
+	}
+	if b.Max > 0 && delay > b.Max {
+		return b.Max
+	}
+	return delay
+}
+
+// Policy specifies how a pool deals with failing lite servers.
+//
+// Every connection has a circuit breaker.
+// A breaker ejects its connection from the pool after FailureThreshold consecutive errors
+// or when the connection's masterchain head falls behind the pool's head by more than MaxHeadLag blocks.
+// An ejected connection is not used for requests until EjectionTimeout passes,
+// then it is re-admitted on probation: the first error ejects it again for twice as long, up to MaxEjectionTimeout,
+// and the first success closes the breaker.
+//
+// A zero Policy disables retries and circuit breakers.
+type Policy struct {
+	// Retries is a number of times a request of the given kind is repeated on another connection after an error.
+	// A request is never repeated if its context is done or a lite server answers with an error.
+	Retries map[RequestKind]int
+	// Backoff is used between retries of a request and between attempts of a connection to recover.
+	Backoff Backoff
+	// FailureThreshold is a number of consecutive errors which ejects a connection. Zero disables ejection on errors.
+	FailureThreshold int
+	// MaxHeadLag is a number of masterchain blocks a connection can fall behind before it is ejected.
+	// Zero disables ejection of stale connections.
+	MaxHeadLag uint32
+	// EjectionTimeout is a period an ejected connection is not used for.
+	EjectionTimeout time.Duration
+	// MaxEjectionTimeout limits EjectionTimeout growth for connections failing repeatedly.
+	MaxEjectionTimeout time.Duration
+}
+
+// DefaultPolicy returns a policy used by New.
+func DefaultPolicy() Policy {
+	return Policy{
+		Retries: map[RequestKind]int{
+			ReadRequest: 2,
+		},
+		Backoff: Backoff{
+			Initial:    100 * time.Millisecond,
+			Max:        5 * time.Second,
+			Multiplier: 2,
+		},
+		FailureThreshold:   3,
+		MaxHeadLag:         5,
+		EjectionTimeout:    10 * time.Second,
+		MaxEjectionTimeout: 5 * time.Minute,
+	}
+}
+
+// isConnectionFailure reports if err says nothing about a request itself
+// and the request can succeed on another lite server.
+func isConnectionFailure(err error) bool {
+	if err == nil {
+		return false
+	}
+	var liteServerErr liteclient.LiteServerErrorC
+	if errors.As(err, &liteServerErr) {
+		return false
+	}
+	return !errors.Is(err, context.Canceled)
+}
+
+// sleep waits for the given duration or until ctx is done.
+func sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+type breakerState int
+
+const (
+	breakerClosed breakerState = iota
+	breakerOpen
+	breakerHalfOpen
+)
+
+// circuitBreaker tracks health of a single connection.
+type circuitBreaker struct {
+	mu        sync.Mutex
+	state     breakerState
+	failures  int
+	ejections int
+	openUntil time.Time
+}
+
+// available reports if a connection can be used for requests.
+// An open breaker becomes available once its ejection timeout passes.
+func (b *circuitBreaker) available(now time.Time) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.state != breakerOpen || !now.Before(b.openUntil)
+}
+
+// ejected reports if the breaker is open and its ejection timeout hasn't passed yet.
+func (b *circuitBreaker) ejected(now time.Time) bool {
+	return !b.available(now)
+}
+
+// admit is called when a request is sent through a connection.
+// It moves an open breaker to the half-open state once its ejection timeout passes,
+// so the result of the request decides if the connection is re-admitted.
+func (b *circuitBreaker) admit(now time.Time) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.state == breakerOpen && !now.Before(b.openUntil) {
+		b.state = breakerHalfOpen
+	}
+}
+
+func (b *circuitBreaker) success() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.state == breakerOpen {
+		return
+	}
+	b.state = breakerClosed
+	b.failures = 0
+	b.ejections = 0
+}
+
+// failure records an error and returns true if it ejects the connection.
+func (b *circuitBreaker) failure(policy Policy, now time.Time) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.state == breakerOpen || policy.FailureThreshold == 0 {
+		return false
+	}
+	b.failures++
+	if b.state == breakerHalfOpen || b.failures >= policy.FailureThreshold {
+		b.open(policy, now)
+		return true
+	}
+	return false
+}
+
+// eject opens the breaker regardless of the number of errors.
+func (b *circuitBreaker) eject(policy Policy, now time.Time) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.state == breakerOpen {
+		return
+	}
+	b.open(policy, now)
+}
+
+func (b *circuitBreaker) open(policy Policy, now time.Time) {
+	timeout := policy.EjectionTimeout
+	for i := 0; i < b.ejections && (policy.MaxEjectionTimeout == 0 || timeout < policy.MaxEjectionTimeout); i++ {
+		timeout *= 2
+	}
+	if policy.MaxEjectionTimeout > 0 && timeout > policy.MaxEjectionTimeout {
+		timeout = policy.MaxEjectionTimeout
+	}
+	b.state = breakerOpen
+	b.failures = 0
+	b.ejections++
+	b.openUntil = now.Add(timeout)
+}
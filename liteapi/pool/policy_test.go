@@ -0,0 +1,189 @@
+package pool
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/tonkeeper/tongo/liteclient"
+	"github.com/tonkeeper/tongo/ton"
+)
+
+func TestBackoff_Delay(t *testing.T) {
+	backoff := Backoff{Initial: 100 * time.Millisecond, Max: time.Second, Multiplier: 2}
+	tests := []struct {
+		attempt int
+		want    time.Duration
+	}{
+		{attempt: 0, want: 100 * time.Millisecond},
+		{attempt: 1, want: 200 * time.Millisecond},
+		{attempt: 3, want: 800 * time.Millisecond},
+		{attempt: 4, want: time.Second},
+		{attempt: 100, want: time.Second},
+	}
+	for _, tt := range tests {
+		if got := backoff.Delay(tt.attempt); got != tt.want {
+			t.Errorf("Delay(%v) = %v, want %v", tt.attempt, got, tt.want)
+		}
+	}
+}
+
+func Test_circuitBreaker(t *testing.T) {
+	policy := Policy{
+		FailureThreshold:   2,
+		EjectionTimeout:    10 * time.Second,
+		MaxEjectionTimeout: 15 * time.Second,
+	}
+	now := time.Now()
+	b := &circuitBreaker{}
+	if b.failure(policy, now) {
+		t.Fatalf("the first failure must not eject a connection")
+	}
+	b.success()
+	if b.failure(policy, now) {
+		t.Fatalf("success must reset failures")
+	}
+	if !b.failure(policy, now) {
+		t.Fatalf("want ejection after %v failures", policy.FailureThreshold)
+	}
+	if b.available(now.Add(9 * time.Second)) {
+		t.Fatalf("ejected connection must not be available")
+	}
+	if !b.available(now.Add(10*time.Second)) || b.ejected(now.Add(10*time.Second)) {
+		t.Fatalf("connection must be re-admitted after ejection timeout")
+	}
+	// checking a breaker doesn't change its state, only a sent request does.
+	if b.state != breakerOpen {
+		t.Fatalf("want open breaker until a request is sent, got: %v", b.state)
+	}
+	now = now.Add(10 * time.Second)
+	b.admit(now)
+	if b.state != breakerHalfOpen {
+		t.Fatalf("want half-open breaker after a request is sent, got: %v", b.state)
+	}
+	// a failure on probation ejects the connection again for a longer period.
+	if !b.failure(policy, now) {
+		t.Fatalf("want ejection after a failure on probation")
+	}
+	if b.available(now.Add(14 * time.Second)) {
+		t.Fatalf("ejection timeout must grow")
+	}
+	if !b.available(now.Add(15 * time.Second)) {
+		t.Fatalf("ejection timeout must be limited by MaxEjectionTimeout")
+	}
+	b.admit(now.Add(15 * time.Second))
+	b.success()
+	if b.failure(policy, now) {
+		t.Fatalf("success on probation must close the breaker")
+	}
+}
+
+func TestConnPool_Do(t *testing.T) {
+	errConn := errors.New("connection closed")
+	tests := []struct {
+		name      string
+		kind      RequestKind
+		errs      []error
+		wantErr   error
+		wantCalls int
+	}{
+		{
+			name:      "read request is retried on another connection",
+			kind:      ReadRequest,
+			errs:      []error{errConn, nil},
+			wantCalls: 2,
+		},
+		{
+			name:      "read request is retried until retries are exhausted",
+			kind:      ReadRequest,
+			errs:      []error{errConn, errConn, errConn},
+			wantErr:   errConn,
+			wantCalls: 2,
+		},
+		{
+			name:      "send request is not retried",
+			kind:      SendRequest,
+			errs:      []error{errConn, nil},
+			wantErr:   errConn,
+			wantCalls: 1,
+		},
+		{
+			name:      "lite server error is not retried",
+			kind:      ReadRequest,
+			errs:      []error{liteclient.LiteServerErrorC{Code: 651}, nil},
+			wantErr:   liteclient.LiteServerErrorC{Code: 651},
+			wantCalls: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conns := []conn{
+				&mockConn{id: 0, seqno: 100, isOK: true, avgRoundTrip: 10 * time.Millisecond, client: &liteclient.Client{}},
+				&mockConn{id: 1, seqno: 100, isOK: true, avgRoundTrip: 30 * time.Millisecond, client: &liteclient.Client{}},
+				&mockConn{id: 2, seqno: 100, isOK: true, avgRoundTrip: 20 * time.Millisecond, client: &liteclient.Client{}},
+			}
+			p := &ConnPool{
+				conns:    conns,
+				bestConn: conns[0],
+				strategy: BestPingStrategy,
+				policy:   Policy{Retries: map[RequestKind]int{ReadRequest: 1}},
+			}
+			var clients []*liteclient.Client
+			err := p.Do(context.Background(), tt.kind, false, func(client *liteclient.Client, masterHead ton.BlockIDExt) error {
+				err := tt.errs[len(clients)]
+				clients = append(clients, client)
+				return err
+			})
+			if err != tt.wantErr {
+				t.Fatalf("want error: %v, got: %v", tt.wantErr, err)
+			}
+			if len(clients) != tt.wantCalls {
+				t.Fatalf("want %v calls, got: %v", tt.wantCalls, len(clients))
+			}
+			// the next attempt goes to the healthy connection with the best ping.
+			if len(clients) > 1 && clients[1] != conns[2].Client() {
+				t.Fatalf("request is retried on unexpected connection")
+			}
+		})
+	}
+}
+
+func TestConnPool_ejection(t *testing.T) {
+	conns := []conn{
+		&mockConn{id: 0, seqno: 100, isOK: true, avgRoundTrip: 10 * time.Millisecond, client: &liteclient.Client{}},
+		&mockConn{id: 1, seqno: 100, isOK: true, avgRoundTrip: 20 * time.Millisecond, client: &liteclient.Client{}},
+		&mockConn{id: 2, seqno: 90, isOK: true, avgRoundTrip: 5 * time.Millisecond, client: &liteclient.Client{}},
+	}
+	p := &ConnPool{
+		conns:    conns,
+		bestConn: conns[0],
+		strategy: BestPingStrategy,
+		policy: Policy{
+			FailureThreshold: 2,
+			MaxHeadLag:       5,
+			EjectionTimeout:  time.Minute,
+		},
+	}
+	p.updateBest()
+	if !p.breaker(conns[2]).ejected(time.Now()) {
+		t.Fatalf("stale connection must be ejected")
+	}
+	if p.bestConnection() != conns[0] {
+		t.Fatalf("want the first connection to be the best one")
+	}
+	for i := 0; i < 2; i++ {
+		_ = p.Do(context.Background(), ReadRequest, false, func(client *liteclient.Client, masterHead ton.BlockIDExt) error {
+			return errors.New("connection closed")
+		})
+	}
+	if p.bestConnection() != conns[1] {
+		t.Fatalf("failing connection must be replaced with a healthy one")
+	}
+	// the stale connection catches up but is still ejected.
+	conns[2].(*mockConn).seqno = 100
+	p.updateBest()
+	if p.bestConnection() != conns[1] {
+		t.Fatalf("ejected connection must not be used before ejection timeout")
+	}
+}
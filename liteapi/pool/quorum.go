@@ -0,0 +1,75 @@
+package pool
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/tonkeeper/tongo/liteclient"
+	"github.com/tonkeeper/tongo/ton"
+)
+
+// Quorum calls f concurrently on n different healthy connections with the best ping.
+// i is an index of a call in [0, n), so f can store its result without synchronization.
+// masterHead is the oldest masterchain head among the chosen connections,
+// so a block with this seqno is known to all of them.
+// If seqno is not zero, only connections which know the masterchain block with this seqno are used.
+//
+// Quorum returns an error of every call, or an error if there are less than n suitable connections.
+// Calls are not retried, but their errors are counted by circuit breakers the same way as Do does.
+func (p *ConnPool) Quorum(ctx context.Context, n int, seqno uint32, f func(i int, serverHost string, client *liteclient.Client, masterHead ton.BlockIDExt) error) ([]error, error) {
+	conns := p.quorumConnections(n, seqno)
+	if len(conns) < n {
+		return nil, fmt.Errorf("quorum requires %v connections but only %v are available", n, len(conns))
+	}
+	head := conns[0].MasterHead()
+	for _, c := range conns[1:] {
+		if h := c.MasterHead(); h.Seqno < head.Seqno {
+			head = h
+		}
+	}
+	errs := make([]error, n)
+	var wg sync.WaitGroup
+	for i, c := range conns {
+		wg.Add(1)
+		go func(i int, c conn) {
+			defer wg.Done()
+			errs[i] = f(i, c.ServerHost(), c.Client(), head)
+			if isConnectionFailure(errs[i]) && ctx.Err() == nil {
+				p.reportFailure(c)
+				return
+			}
+			p.breaker(c).success()
+		}(i, c)
+	}
+	wg.Wait()
+	return errs, nil
+}
+
+// quorumConnections returns up to n healthy connections with the best ping.
+func (p *ConnPool) quorumConnections(n int, seqno uint32) []conn {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	now := time.Now()
+	var conns []conn
+	for _, c := range p.conns {
+		if !c.IsOK() || !p.breaker(c).available(now) {
+			continue
+		}
+		head := c.MasterHead().Seqno
+		if head == 0 || head < seqno {
+			continue
+		}
+		conns = append(conns, c)
+	}
+	sort.SliceStable(conns, func(i, j int) bool {
+		return conns[i].AverageRoundTrip() < conns[j].AverageRoundTrip()
+	})
+	if len(conns) > n {
+		conns = conns[:n]
+	}
+	return conns
+}
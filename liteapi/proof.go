@@ -0,0 +1,613 @@
+package liteapi
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/tonkeeper/tongo/boc"
+	"github.com/tonkeeper/tongo/liteclient"
+	"github.com/tonkeeper/tongo/tlb"
+	"github.com/tonkeeper/tongo/ton"
+)
+
+var (
+	// ErrInvalidShardProof means that a shard block isn't proven to be a part of a masterchain block.
+	ErrInvalidShardProof = errors.New("invalid shard proof")
+	// ErrInvalidStateProof means that a state proof doesn't match a block.
+	ErrInvalidStateProof = errors.New("invalid state proof")
+	// ErrAccountStateMismatch means that an account state doesn't match its proof.
+	ErrAccountStateMismatch = errors.New("account state doesn't match proof")
+	// ErrTransactionMismatch means that transactions don't form the requested chain.
+	ErrTransactionMismatch = errors.New("transaction doesn't match requested chain")
+)
+
+// ProofError is returned when data returned by a lite server doesn't match its proof,
+// which means the lite server returned forged or corrupted data.
+// Use errors.Is with ErrInvalidShardProof, ErrInvalidStateProof, ErrAccountStateMismatch
+// or ErrTransactionMismatch to find out what check failed.
+type ProofError struct {
+	// Kind is one of ErrInvalidShardProof, ErrInvalidStateProof, ErrAccountStateMismatch, ErrTransactionMismatch.
+	Kind error
+	// Err describes what exactly is wrong.
+	Err error
+}
+
+func (e *ProofError) Error() string {
+	return fmt.Sprintf("%v: %v", e.Kind, e.Err)
+}
+
+func (e *ProofError) Is(target error) bool {
+	return e.Kind == target
+}
+
+func (e *ProofError) Unwrap() error {
+	return e.Err
+}
+
+func newProofError(kind error, format string, args ...any) error {
+	return &ProofError{Kind: kind, Err: fmt.Errorf(format, args...)}
+}
+
+// decodeMerkleProofOf decodes a merkle proof of a cell tree with the given hash
+// looking for it among all roots of the given boc.
+func decodeMerkleProofOf[T any](proofBoc []byte, hash ton.Bits256) (T, error) {
+	var root T
+	cells, err := boc.DeserializeBoc(proofBoc)
+	if err != nil {
+		return root, err
+	}
+	cell, err := boc.FindMerkleProof(cells, hash)
+	if err != nil {
+		return root, err
+	}
+	root, _, err = decodeMerkleProofCell[T](cell)
+	return root, err
+}
+
+// decodeMerkleProofCell decodes the given merkle proof cell
+// and checks that the proof's virtual hash is a hash of the original cell tree.
+// It returns the virtual root of the proof along with its hash.
+func decodeMerkleProofCell[T any](cell *boc.Cell) (T, ton.Bits256, error) {
+	var root T
+	if cell.CellType() != boc.MerkleProofCell || cell.RefsSize() != 1 {
+		return root, ton.Bits256{}, fmt.Errorf("not a merkle proof cell")
+	}
+	hash, err := cell.Refs()[0].Hash256WithLevel(0)
+	if err != nil {
+		return root, ton.Bits256{}, err
+	}
+	cell.ResetCounters()
+	var proof tlb.MerkleProof[T]
+	if err := tlb.Unmarshal(cell, &proof); err != nil {
+		return root, ton.Bits256{}, err
+	}
+	if proof.VirtualHash != hash {
+		return root, ton.Bits256{}, fmt.Errorf("merkle proof virtual hash mismatch")
+	}
+	return proof.VirtualRoot, ton.Bits256(hash), nil
+}
+
+// blockStateUpdate is a part of a block which is left unpruned in a state proof.
+type blockStateUpdate struct {
+	Magic       tlb.Magic `tlb:"block#11ef55aa"`
+	GlobalId    int32
+	Info        boc.Cell                   `tlb:"^"`
+	ValueFlow   boc.Cell                   `tlb:"^"`
+	StateUpdate tlb.MerkleUpdate[boc.Cell] `tlb:"^"`
+}
+
+// checkBlockStateHash checks that headerProof is a proof of the given block
+// and the block's state update leads to a state with the given hash.
+func checkBlockStateHash(blockID ton.BlockIDExt, headerProof []byte, stateHash ton.Bits256) error {
+	block, err := decodeMerkleProofOf[blockStateUpdate](headerProof, blockID.RootHash)
+	if err != nil {
+		return newProofError(ErrInvalidStateProof, "block header proof is not for block %v: %w", blockID.BlockID, err)
+	}
+	if ton.Bits256(block.StateUpdate.ToHash) != stateHash {
+		return newProofError(ErrInvalidStateProof, "state is not a state of block %v", blockID.BlockID)
+	}
+	return nil
+}
+
+// checkStateProof checks that stateProof is a proof of the given block,
+// dataProof is a proof of the block's state, and returns the virtual root of dataProof.
+// Both proofs can be multi-root bags, the required proofs are chosen by their hashes.
+func checkStateProof(blockID ton.BlockIDExt, stateProof, dataProof []byte) (tlb.ShardStateUnsplit, error) {
+	stateCells, err := boc.DeserializeBoc(stateProof)
+	if err != nil {
+		return tlb.ShardStateUnsplit{}, fmt.Errorf("failed to decode state proof: %w", err)
+	}
+	dataCells, err := boc.DeserializeBoc(dataProof)
+	if err != nil {
+		return tlb.ShardStateUnsplit{}, fmt.Errorf("failed to decode data proof: %w", err)
+	}
+	blockProof, stateRoot, err := findBlockStateProof(blockID, stateCells, dataCells)
+	if err != nil {
+		return tlb.ShardStateUnsplit{}, err
+	}
+	return checkBlockStateProof[tlb.ShardStateUnsplit](blockID, blockProof, stateRoot)
+}
+
+// findBlockStateProof looks for a proof of the given block among blockCells
+// and for a proof of the block's state among stateCells.
+// Both proofs are chosen by their hashes, so the order of roots doesn't matter.
+func findBlockStateProof(blockID ton.BlockIDExt, blockCells, stateCells []*boc.Cell) (*boc.Cell, *boc.Cell, error) {
+	blockProof, err := boc.FindMerkleProof(blockCells, blockID.RootHash)
+	if err != nil {
+		return nil, nil, fmt.Errorf("state proof is not for block %v: %w", blockID.BlockID, err)
+	}
+	block, _, err := decodeMerkleProofCell[blockStateUpdate](blockProof)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to decode state proof: %w", err)
+	}
+	stateRoot, err := boc.FindMerkleProof(stateCells, block.StateUpdate.ToHash)
+	if err != nil {
+		return nil, nil, fmt.Errorf("data proof is not for state of block %v: %w", blockID.BlockID, err)
+	}
+	return blockProof, stateRoot, nil
+}
+
+// checkBlockStateProof checks that blockProof is a proof of the given block,
+// stateProof is a proof of the block's state, and returns the virtual root of stateProof decoded as T.
+func checkBlockStateProof[T any](blockID ton.BlockIDExt, blockProof, stateProof *boc.Cell) (T, error) {
+	var state T
+	block, blockHash, err := decodeMerkleProofCell[blockStateUpdate](blockProof)
+	if err != nil {
+		return state, fmt.Errorf("failed to decode state proof: %w", err)
+	}
+	if blockHash != blockID.RootHash {
+		return state, fmt.Errorf("state proof is not for block %v", blockID.BlockID)
+	}
+	state, stateHash, err := decodeMerkleProofCell[T](stateProof)
+	if err != nil {
+		return state, fmt.Errorf("failed to decode data proof: %w", err)
+	}
+	if stateHash != ton.Bits256(block.StateUpdate.ToHash) {
+		return state, fmt.Errorf("state hash mismatch")
+	}
+	return state, nil
+}
+
+// shardStateAccounts is a part of ShardStateUnsplit required to look up an account in a state proof.
+type shardStateAccounts struct {
+	Magic           tlb.Magic `tlb:"shard_state#9023afe2"`
+	GlobalID        int32
+	ShardID         tlb.ShardIdent
+	SeqNo           uint32
+	VertSeqNo       uint32
+	GenUtime        uint32
+	GenLt           uint64
+	MinRefMcSeqno   uint32
+	OutMsgQueueInfo boc.Cell `tlb:"^"`
+	BeforeSplit     bool
+	Accounts        tlb.ShardAccounts `tlb:"^"`
+}
+
+// shardAccountProof is a ShardAccount whose account is replaced with its hash,
+// because the account cell is usually pruned in a state proof.
+type shardAccountProof struct {
+	AccountHash   ton.Bits256
+	LastTransHash tlb.Bits256
+	LastTransLt   uint64
+}
+
+func (a *shardAccountProof) UnmarshalTLB(c *boc.Cell, decoder *tlb.Decoder) error {
+	// account_descr$_ account:^Account last_trans_hash:bits256 last_trans_lt:uint64 = ShardAccount;
+	ref, err := c.NextRef()
+	if err != nil {
+		return err
+	}
+	hash, err := ref.Hash256WithLevel(0)
+	if err != nil {
+		return err
+	}
+	a.AccountHash = hash
+	var data struct {
+		LastTransHash tlb.Bits256
+		LastTransLt   uint64
+	}
+	if err := decoder.Unmarshal(c, &data); err != nil {
+		return err
+	}
+	a.LastTransHash = data.LastTransHash
+	a.LastTransLt = data.LastTransLt
+	return nil
+}
+
+// checkShardProof checks that shardProof proves that shardBlock is a shard block referenced by masterchainBlock.
+func checkShardProof(masterchainBlock, shardBlock ton.BlockIDExt, shardProof []byte) error {
+	if masterchainBlock.Workchain != -1 {
+		return newProofError(ErrInvalidShardProof, "block %v is not a masterchain block", masterchainBlock.BlockID)
+	}
+	if masterchainBlock == shardBlock {
+		return nil
+	}
+	cells, err := boc.DeserializeBoc(shardProof)
+	if err != nil {
+		return newProofError(ErrInvalidShardProof, "%w", err)
+	}
+	blockProof, stateProof, err := findBlockStateProof(masterchainBlock, cells, cells)
+	if err != nil {
+		return newProofError(ErrInvalidShardProof, "%w", err)
+	}
+	state, err := checkBlockStateProof[tlb.ShardStateUnsplit](masterchainBlock, blockProof, stateProof)
+	if err != nil {
+		return newProofError(ErrInvalidShardProof, "%w", err)
+	}
+	if !state.ShardStateUnsplit.Custom.Exists {
+		return newProofError(ErrInvalidShardProof, "no shard hashes in masterchain state")
+	}
+	shardHashes := state.ShardStateUnsplit.Custom.Value.Value.ShardHashes
+	for _, item := range shardHashes.Items() {
+		if int32(item.Key) != shardBlock.Workchain {
+			continue
+		}
+		for _, desc := range item.Value.Value.BinTree.Values {
+			if ton.ToBlockId(desc, shardBlock.Workchain) == shardBlock {
+				return nil
+			}
+		}
+	}
+	return newProofError(ErrInvalidShardProof, "block %v is not found in masterchain block %v", shardBlock.BlockID, masterchainBlock.BlockID)
+}
+
+// checkAccountStateProof checks that the account state returned by a lite server
+// is a part of the state of the masterchain block res.Id.
+// It walks the proof chain: masterchain block -> shard block -> shard state -> ShardAccounts -> account.
+// If the account doesn't exist, the returned shardAccountProof is empty.
+func checkAccountStateProof(accountID ton.AccountID, res liteclient.LiteServerAccountStateC) (shardAccountProof, error) {
+	masterchainBlock := res.Id.ToBlockIdExt()
+	shardBlock := res.Shardblk.ToBlockIdExt()
+	if err := checkShardProof(masterchainBlock, shardBlock, res.ShardProof); err != nil {
+		return shardAccountProof{}, err
+	}
+	cells, err := boc.DeserializeBoc(res.Proof)
+	if err != nil {
+		return shardAccountProof{}, newProofError(ErrInvalidStateProof, "%w", err)
+	}
+	blockProof, stateProof, err := findBlockStateProof(shardBlock, cells, cells)
+	if err != nil {
+		return shardAccountProof{}, newProofError(ErrInvalidStateProof, "%w", err)
+	}
+	return checkAccountProof(accountID, shardBlock, blockProof, stateProof, res.State)
+}
+
+// checkAccountProof checks that blockProof and stateProof prove the account state of the given shard block.
+// If the account doesn't exist, the returned shardAccountProof is empty.
+func checkAccountProof(accountID ton.AccountID, shardBlock ton.BlockIDExt, blockProof, stateProof *boc.Cell, accountState []byte) (shardAccountProof, error) {
+	shard, err := ton.ParseShardID(int64(shardBlock.Shard))
+	if err != nil {
+		return shardAccountProof{}, newProofError(ErrInvalidShardProof, "%w", err)
+	}
+	if shardBlock.Workchain != accountID.Workchain || !shard.MatchAccountID(accountID) {
+		return shardAccountProof{}, newProofError(ErrInvalidShardProof, "block %v doesn't contain account %v", shardBlock.BlockID, accountID)
+	}
+	state, err := checkBlockStateProof[shardStateAccounts](shardBlock, blockProof, stateProof)
+	if err != nil {
+		return shardAccountProof{}, newProofError(ErrInvalidStateProof, "%w", err)
+	}
+	leaf, err := state.Accounts.Lookup(tlb.Bits256(accountID.Address))
+	if err != nil {
+		return shardAccountProof{}, newProofError(ErrInvalidStateProof, "%w", err)
+	}
+	if leaf == nil {
+		if len(accountState) > 0 {
+			return shardAccountProof{}, newProofError(ErrAccountStateMismatch, "account %v doesn't exist in block %v", accountID, shardBlock.BlockID)
+		}
+		return shardAccountProof{}, nil
+	}
+	var account shardAccountProof
+	if err := tlb.Unmarshal(leaf, &account); err != nil {
+		return shardAccountProof{}, newProofError(ErrInvalidStateProof, "%w", err)
+	}
+	if len(accountState) == 0 {
+		return shardAccountProof{}, newProofError(ErrAccountStateMismatch, "account %v exists in block %v", accountID, shardBlock.BlockID)
+	}
+	cells, err := boc.DeserializeBoc(accountState)
+	if err != nil {
+		return shardAccountProof{}, newProofError(ErrAccountStateMismatch, "%w", err)
+	}
+	// the account state is looked up by its hash, so a multi-root bag is accepted as well.
+	for _, cell := range cells {
+		hash, err := cell.Hash256()
+		if err != nil {
+			return shardAccountProof{}, newProofError(ErrAccountStateMismatch, "%w", err)
+		}
+		if hash == account.AccountHash {
+			return account, nil
+		}
+	}
+	return shardAccountProof{}, newProofError(ErrAccountStateMismatch, "account hash mismatch")
+}
+
+// shardStateDispatchQueue is a part of ShardStateUnsplit required to read dispatch queues from a state proof.
+type shardStateDispatchQueue struct {
+	Magic         tlb.Magic `tlb:"shard_state#9023afe2"`
+	GlobalID      int32
+	ShardID       tlb.ShardIdent
+	SeqNo         uint32
+	VertSeqNo     uint32
+	GenUtime      uint32
+	GenLt         uint64
+	MinRefMcSeqno uint32
+	DispatchQueue dispatchQueueProof
+}
+
+// dispatchQueueProof keeps the root of a dispatch queue, which is nil if the queue is empty.
+type dispatchQueueProof struct {
+	root *boc.Cell
+}
+
+func (q *dispatchQueueProof) UnmarshalTLB(c *boc.Cell, decoder *tlb.Decoder) error {
+	info, err := c.NextRef()
+	if err != nil {
+		return err
+	}
+	if info.CellType() == boc.PrunedBranchCell {
+		return fmt.Errorf("out msg queue info is pruned")
+	}
+	// _ out_queue:OutMsgQueue proc_info:ProcessedInfo extra:(Maybe OutMsgQueueExtra) = OutMsgQueueInfo;
+	// both out_queue and proc_info are HashmapE, so they are skipped as a bit and an optional ref.
+	for i := 0; i < 2; i++ {
+		exist, err := info.ReadBit()
+		if err != nil {
+			return err
+		}
+		if exist {
+			if _, err := info.NextRef(); err != nil {
+				return err
+			}
+		}
+	}
+	exist, err := info.ReadBit()
+	if err != nil || !exist {
+		return err
+	}
+	// out_msg_queue_extra#0 dispatch_queue:DispatchQueue out_queue_size:(Maybe uint48) = OutMsgQueueExtra;
+	tag, err := info.ReadUint(4)
+	if err != nil {
+		return err
+	}
+	if tag != 0 {
+		return fmt.Errorf("invalid out msg queue extra tag: %v", tag)
+	}
+	exist, err = info.ReadBit()
+	if err != nil || !exist {
+		return err
+	}
+	q.root, err = info.NextRef()
+	return err
+}
+
+// checkDispatchQueueProof checks that the dispatch queues returned by a lite server are consecutive queues
+// of the given block's state starting after afterAddr, and returns the queues decoded from the proof.
+// If the lite server reports the list as complete, the state must contain no queues after the returned ones.
+func checkDispatchQueueProof(blockID ton.BlockIDExt, afterAddr *ton.Bits256, res liteclient.LiteServerDispatchQueueInfoC) ([]AccountDispatchQueue, error) {
+	if res.Id.ToBlockIdExt() != blockID {
+		return nil, newProofError(ErrInvalidStateProof, "dispatch queues are for block %v instead of %v", res.Id.ToBlockIdExt().BlockID, blockID.BlockID)
+	}
+	cells, err := boc.DeserializeBoc(res.Proof)
+	if err != nil {
+		return nil, newProofError(ErrInvalidStateProof, "%w", err)
+	}
+	blockProof, stateProof, err := findBlockStateProof(blockID, cells, cells)
+	if err != nil {
+		return nil, newProofError(ErrInvalidStateProof, "%w", err)
+	}
+	state, err := checkBlockStateProof[shardStateDispatchQueue](blockID, blockProof, stateProof)
+	if err != nil {
+		return nil, newProofError(ErrInvalidStateProof, "%w", err)
+	}
+	queues := make([]AccountDispatchQueue, 0, len(res.AccountDispatchQueues))
+	// an incomplete empty list is trivially a prefix of the queues.
+	if state.DispatchQueue.root != nil && (res.Complete || len(res.AccountDispatchQueues) > 0) {
+		var after *boc.BitString
+		if afterAddr != nil {
+			key := boc.NewBitString(256)
+			if err := key.WriteBytes(afterAddr[:]); err != nil {
+				return nil, err
+			}
+			after = &key
+		}
+		// _ messages:(HashmapE 64 EnqueuedMsg) count:uint48 = AccountDispatchQueue;
+		// the queues are augmented with min created_lt of their messages.
+		err = tlb.WalkHashmap(256, state.DispatchQueue.root, after, false, func(key boc.BitString, leaf *boc.Cell) (bool, error) {
+			if len(queues) == len(res.AccountDispatchQueues) {
+				return false, fmt.Errorf("dispatch queue of %x is missing", key.Buffer())
+			}
+			queue := AccountDispatchQueue{AccountID: ton.AccountID{Workchain: blockID.Workchain}}
+			copy(queue.AccountID.Address[:], key.Buffer())
+			if _, err := leaf.ReadUint(64); err != nil {
+				return false, err
+			}
+			exist, err := leaf.ReadBit()
+			if err != nil {
+				return false, err
+			}
+			if exist {
+				messages, err := leaf.NextRef()
+				if err != nil {
+					return false, err
+				}
+				if queue.MinLt, err = dispatchQueueLt(messages, false); err != nil {
+					return false, err
+				}
+				if queue.MaxLt, err = dispatchQueueLt(messages, true); err != nil {
+					return false, err
+				}
+			}
+			if queue.Size, err = leaf.ReadUint(48); err != nil {
+				return false, err
+			}
+			queues = append(queues, queue)
+			// a complete list must be followed by no more queues.
+			return res.Complete || len(queues) < len(res.AccountDispatchQueues), nil
+		})
+		if err != nil {
+			return nil, newProofError(ErrInvalidStateProof, "%w", err)
+		}
+	}
+	if len(queues) != len(res.AccountDispatchQueues) {
+		return nil, newProofError(ErrInvalidStateProof, "state contains %v dispatch queues instead of %v", len(queues), len(res.AccountDispatchQueues))
+	}
+	for i, q := range res.AccountDispatchQueues {
+		proven := queues[i]
+		if proven.AccountID.Address != q.Addr || proven.Size != q.Size || proven.MinLt != q.MinLt || proven.MaxLt != q.MaxLt {
+			return nil, newProofError(ErrInvalidStateProof, "dispatch queue of %x doesn't match proof", q.Addr)
+		}
+	}
+	return queues, nil
+}
+
+// dispatchQueueLt returns the first or the last lt of messages of a dispatch queue.
+func dispatchQueueLt(messages *boc.Cell, last bool) (uint64, error) {
+	var lt uint64
+	err := tlb.WalkHashmap(64, messages, nil, last, func(key boc.BitString, _ *boc.Cell) (bool, error) {
+		var err error
+		lt, err = key.ReadUint(64)
+		return false, err
+	})
+	return lt, err
+}
+
+// checkTransactionChain checks that the given transactions belong to the account and form a chain
+// started from the transaction with the given lt and hash and linked via prev_trans_lt and prev_trans_hash.
+func checkTransactionChain(accountID ton.AccountID, lt uint64, hash ton.Bits256, cells []*boc.Cell, txs []tlb.Transaction) error {
+	for i, tx := range txs {
+		txHash, err := cells[i].Hash256()
+		if err != nil {
+			return newProofError(ErrTransactionMismatch, "%w", err)
+		}
+		if txHash != hash || tx.Lt != lt {
+			return newProofError(ErrTransactionMismatch, "want transaction %v:%x, got %v:%x", lt, hash, tx.Lt, txHash)
+		}
+		if tx.AccountAddr != tlb.Bits256(accountID.Address) {
+			return newProofError(ErrTransactionMismatch, "transaction %x belongs to another account", txHash)
+		}
+		lt, hash = tx.PrevTransLt, ton.Bits256(tx.PrevTransHash)
+	}
+	return nil
+}
+
+// blockTransactionsProof is a part of a block which is left unpruned in a proof of block transactions.
+type blockTransactionsProof struct {
+	Magic       tlb.Magic `tlb:"block#11ef55aa"`
+	GlobalId    int32
+	Info        boc.Cell `tlb:"^"`
+	ValueFlow   boc.Cell `tlb:"^"`
+	StateUpdate boc.Cell `tlb:"^"`
+	Extra       struct {
+		Magic         tlb.Magic                                                               `tlb:"block_extra#4a33f6fd"`
+		InMsgDescr    boc.Cell                                                                `tlb:"^"`
+		OutMsgDescr   boc.Cell                                                                `tlb:"^"`
+		AccountBlocks tlb.HashmapAugE[tlb.Bits256, accountBlockProof, tlb.CurrencyCollection] `tlb:"^"`
+	} `tlb:"^"`
+}
+
+// accountBlockProof is an AccountBlock whose transactions are replaced with their hashes,
+// because transaction cells are usually pruned in a block proof.
+type accountBlockProof struct {
+	Magic        tlb.Magic `tlb:"acc_trans#5"`
+	AccountAddr  tlb.Bits256
+	Transactions tlb.HashmapAug[tlb.Uint64, transactionHash, tlb.CurrencyCollection]
+}
+
+// transactionHash is a hash of a transaction referenced by an AccountBlock.
+type transactionHash ton.Bits256
+
+func (h *transactionHash) UnmarshalTLB(c *boc.Cell, decoder *tlb.Decoder) error {
+	ref, err := c.NextRef()
+	if err != nil {
+		return err
+	}
+	hash, err := ref.Hash256WithLevel(0)
+	if err != nil {
+		return err
+	}
+	*h = transactionHash(hash)
+	return nil
+}
+
+// blockTransactionKey identifies a transaction of a block.
+type blockTransactionKey struct {
+	account tlb.Bits256
+	lt      uint64
+}
+
+// decodeBlockTransactionsProof returns hashes of transactions proven to be a part of the given block.
+func decodeBlockTransactionsProof(blockID ton.BlockIDExt, proof []byte) (map[blockTransactionKey]ton.Bits256, error) {
+	block, err := decodeMerkleProofOf[blockTransactionsProof](proof, blockID.RootHash)
+	if err != nil {
+		return nil, newProofError(ErrTransactionMismatch, "block proof is not for block %v: %w", blockID.BlockID, err)
+	}
+	proven := map[blockTransactionKey]ton.Bits256{}
+	for _, accountBlock := range block.Extra.AccountBlocks.Values() {
+		for i, lt := range accountBlock.Transactions.Keys() {
+			proven[blockTransactionKey{account: accountBlock.AccountAddr, lt: uint64(lt)}] = ton.Bits256(accountBlock.Transactions.Values()[i])
+		}
+	}
+	return proven, nil
+}
+
+// checkBlockTransactionsProof checks that each of the given transactions is a part of the given block.
+func checkBlockTransactionsProof(blockID ton.BlockIDExt, proof []byte, cells []*boc.Cell, txs []ton.Transaction) error {
+	proven, err := decodeBlockTransactionsProof(blockID, proof)
+	if err != nil {
+		return err
+	}
+	for i, tx := range txs {
+		hash, err := cells[i].Hash256()
+		if err != nil {
+			return newProofError(ErrTransactionMismatch, "%w", err)
+		}
+		if proven[blockTransactionKey{account: tx.AccountAddr, lt: tx.Lt}] != hash {
+			return newProofError(ErrTransactionMismatch, "transaction %v:%x is not found in block %v", tx.Lt, hash, blockID.BlockID)
+		}
+	}
+	return nil
+}
+
+// checkBlockTransactionIdsProof checks that each of the given transaction ids is a part of the given block.
+// The ids must contain an account, lt and hash.
+func checkBlockTransactionIdsProof(blockID ton.BlockIDExt, proof []byte, ids []liteclient.LiteServerTransactionIdC) error {
+	proven, err := decodeBlockTransactionsProof(blockID, proof)
+	if err != nil {
+		return err
+	}
+	for _, id := range ids {
+		if id.Account == nil || id.Lt == nil || id.Hash == nil {
+			return newProofError(ErrTransactionMismatch, "transaction id without account, lt or hash")
+		}
+		hash, ok := proven[blockTransactionKey{account: tlb.Bits256(*id.Account), lt: *id.Lt}]
+		if !ok || hash != ton.Bits256(*id.Hash) {
+			return newProofError(ErrTransactionMismatch, "transaction %v:%x is not found in block %v", *id.Lt, *id.Hash, blockID.BlockID)
+		}
+	}
+	return nil
+}
+
+// checkShardBlockProof checks that the chain of shard block links leads from res.MasterchainId to shardBlock
+// and returns the masterchain block which references shardBlock.
+// The first link proves a top shard block of the masterchain block,
+// each next link proves a parent of the previous block.
+func checkShardBlockProof(res liteclient.LiteServerShardBlockProofC, shardBlock ton.BlockIDExt) (ton.BlockIDExt, error) {
+	masterchainBlock := res.MasterchainId.ToBlockIdExt()
+	current := masterchainBlock
+	for i, link := range res.Links {
+		next := link.Id.ToBlockIdExt()
+		var err error
+		if i == 0 {
+			err = checkShardProof(current, next, link.Proof)
+		} else {
+			err = checkParentBlock(current, next, link.Proof)
+		}
+		if err != nil {
+			return ton.BlockIDExt{}, newProofError(ErrInvalidShardProof, "invalid link from %v to %v: %w", current.BlockID, next.BlockID, err)
+		}
+		current = next
+	}
+	if current != shardBlock {
+		return ton.BlockIDExt{}, newProofError(ErrInvalidShardProof, "shard links end at unexpected block %v", current.BlockID)
+	}
+	return masterchainBlock, nil
+}
@@ -0,0 +1,752 @@
+package liteapi
+
+import (
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/tonkeeper/tongo/boc"
+	"github.com/tonkeeper/tongo/liteclient"
+	"github.com/tonkeeper/tongo/tl"
+	"github.com/tonkeeper/tongo/tlb"
+	"github.com/tonkeeper/tongo/ton"
+)
+
+func readTestBlock(t *testing.T, filename string) (*boc.Cell, tlb.Block) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		t.Fatalf("ReadFile() failed: %v", err)
+	}
+	cells, err := boc.DeserializeBoc(data)
+	if err != nil {
+		t.Fatalf("DeserializeBoc() failed: %v", err)
+	}
+	var block tlb.Block
+	if err := tlb.Unmarshal(cells[0], &block); err != nil {
+		t.Fatalf("Unmarshal() failed: %v", err)
+	}
+	cells[0].ResetCounters()
+	return cells[0], block
+}
+
+func createTestProof(t *testing.T, root *boc.Cell, prune ...[]int) *boc.Cell {
+	prover, err := boc.NewMerkleProver(root)
+	if err != nil {
+		t.Fatalf("NewMerkleProver() failed: %v", err)
+	}
+	cursor := prover.Cursor()
+	for _, path := range prune {
+		c := cursor
+		for _, ref := range path {
+			c = c.Ref(ref)
+		}
+		c.Prune()
+	}
+	proof, err := prover.CreateProof(cursor)
+	if err != nil {
+		t.Fatalf("CreateProof() failed: %v", err)
+	}
+	cells, err := boc.DeserializeBoc(proof)
+	if err != nil {
+		t.Fatalf("DeserializeBoc() failed: %v", err)
+	}
+	return cells[0]
+}
+
+// newTestBlock returns a block whose state update points to the given state.
+// Only fields checked by a state proof are filled.
+func newTestBlock(t *testing.T, state *boc.Cell) *boc.Cell {
+	stateHash, err := state.Hash256WithLevel(0)
+	if err != nil {
+		t.Fatalf("Hash256() failed: %v", err)
+	}
+	update := boc.NewCell()
+	update.WriteUint(0x04, 8)
+	update.WriteBytes(make([]byte, 32))
+	update.WriteBytes(stateHash[:])
+	update.WriteUint(0, 16)
+	update.WriteUint(0, 16)
+	update.AddRef(boc.NewCell())
+	update.AddRef(boc.NewCell())
+
+	block := boc.NewCell()
+	block.WriteUint(0x11ef55aa, 32)
+	block.WriteInt(-239, 32)
+	block.AddRef(boc.NewCell())
+	block.AddRef(boc.NewCell())
+	block.AddRef(update)
+	block.AddRef(boc.NewCell())
+	return block
+}
+
+func Test_checkBlockStateHash(t *testing.T) {
+	blockCell, _ := readTestBlock(t, "../tlb/testdata/block-5/block.bin")
+	stateCell := blockCell.Refs()[2].Refs()[1]
+	stateHash, err := stateCell.Hash256WithLevel(0)
+	if err != nil {
+		t.Fatalf("Hash256() failed: %v", err)
+	}
+	block := newTestBlock(t, stateCell)
+	blockHash, err := block.Hash256()
+	if err != nil {
+		t.Fatalf("Hash256() failed: %v", err)
+	}
+	blockID := ton.BlockIDExt{
+		BlockID:  ton.BlockID{Workchain: -1, Shard: masterchainShard, Seqno: 1},
+		RootHash: blockHash,
+	}
+	block.ResetCounters()
+	headerProof, err := boc.SerializeBoc(createTestProof(t, block, []int{3}), false, false, false, 0)
+	if err != nil {
+		t.Fatalf("SerializeBoc() failed: %v", err)
+	}
+	if err := checkBlockStateHash(blockID, headerProof, stateHash); err != nil {
+		t.Fatalf("checkBlockStateHash() failed: %v", err)
+	}
+	if err := checkBlockStateHash(blockID, headerProof, ton.Bits256{1}); !errors.Is(err, ErrInvalidStateProof) {
+		t.Fatalf("want ErrInvalidStateProof for another state, got: %v", err)
+	}
+	another := blockID
+	another.RootHash = ton.Bits256{1}
+	if err := checkBlockStateHash(another, headerProof, stateHash); !errors.Is(err, ErrInvalidStateProof) {
+		t.Fatalf("want ErrInvalidStateProof for another block, got: %v", err)
+	}
+}
+
+func Test_checkAccountProof(t *testing.T) {
+	// a masterchain block containing its state.
+	blockCell, _ := readTestBlock(t, "../tlb/testdata/block-5/block.bin")
+	// block#11ef55aa global_id:int32 info:^BlockInfo value_flow:^ValueFlow state_update:^(MERKLE_UPDATE ShardState) extra:^BlockExtra
+	stateCell := blockCell.Refs()[2].Refs()[1]
+	stateCell.ResetCounters()
+	var state tlb.LazyShardState
+	if err := tlb.Unmarshal(stateCell, &state); err != nil {
+		t.Fatalf("Unmarshal() failed: %v", err)
+	}
+	block := newTestBlock(t, stateCell)
+	blockHash, err := block.Hash256()
+	if err != nil {
+		t.Fatalf("Hash256() failed: %v", err)
+	}
+	blockID := ton.BlockIDExt{
+		BlockID: ton.BlockID{
+			Workchain: -1,
+			Shard:     masterchainShard,
+			Seqno:     state.UnsplitState.Value.ShardStateUnsplit.SeqNo,
+		},
+		RootHash: blockHash,
+	}
+	accountCell := func(address tlb.Bits256) *boc.Cell {
+		leaf, err := state.ShardAccounts[0].Lookup(address)
+		if err != nil {
+			t.Fatalf("Lookup() failed: %v", err)
+		}
+		// account_descr$_ account:^Account last_trans_hash:bits256 last_trans_lt:uint64 = ShardAccount;
+		return leaf.Refs()[0]
+	}
+	// the state is a part of a merkle update, so accounts untouched by the block are pruned.
+	var all, addresses []tlb.Bits256
+	err = state.ShardAccounts[0].Iterate(func(address tlb.Bits256, account tlb.ShardAccount, info tlb.DepthBalanceInfo) error {
+		all = append(all, address)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Iterate() failed: %v", err)
+	}
+	for _, address := range all {
+		if accountCell(address).Level() == 0 {
+			addresses = append(addresses, address)
+		}
+	}
+	if len(addresses) == 0 {
+		t.Fatalf("want at least one unpruned account")
+	}
+	forged := boc.NewCell()
+	forged.WriteUint(0, 1) // account_none$0 = Account;
+	forgedState, err := boc.SerializeBoc(forged, false, false, false, 0)
+	if err != nil {
+		t.Fatalf("SerializeBoc() failed: %v", err)
+	}
+	accountState := func(address tlb.Bits256) []byte {
+		data, err := boc.SerializeBoc(accountCell(address), false, false, false, 0)
+		if err != nil {
+			t.Fatalf("SerializeBoc() failed: %v", err)
+		}
+		return data
+	}
+	existing := ton.AccountID{Workchain: -1, Address: addresses[0]}
+	missing := ton.AccountID{Workchain: -1, Address: addresses[0]}
+	missing.Address[31] ^= 1
+
+	// shard_state#9023afe2 ... out_msg_queue_info:^OutMsgQueueInfo before_split:(## 1) accounts:^ShardAccounts ^[...] custom:(Maybe ^McStateExtra)
+	blockProof := createTestProof(t, block, []int{3})
+	stateProof := createTestProof(t, stateCell, []int{0}, []int{2}, []int{3})
+	prunedStateProof := createTestProof(t, stateCell, []int{1})
+
+	tests := []struct {
+		name         string
+		accountID    ton.AccountID
+		blockID      ton.BlockIDExt
+		stateProof   *boc.Cell
+		accountState []byte
+		wantErr      error
+		wantExists   bool
+	}{
+		{
+			name:         "existing account",
+			accountID:    existing,
+			blockID:      blockID,
+			stateProof:   stateProof,
+			accountState: accountState(existing.Address),
+			wantExists:   true,
+		},
+		{
+			name:       "missing account",
+			accountID:  missing,
+			blockID:    blockID,
+			stateProof: stateProof,
+		},
+		{
+			name:         "forged account state",
+			accountID:    existing,
+			blockID:      blockID,
+			stateProof:   stateProof,
+			accountState: forgedState,
+			wantErr:      ErrAccountStateMismatch,
+		},
+		{
+			name:       "hidden account state",
+			accountID:  existing,
+			blockID:    blockID,
+			stateProof: stateProof,
+			wantErr:    ErrAccountStateMismatch,
+		},
+		{
+			name:         "forged missing account",
+			accountID:    missing,
+			blockID:      blockID,
+			stateProof:   stateProof,
+			accountState: accountState(existing.Address),
+			wantErr:      ErrAccountStateMismatch,
+		},
+		{
+			name:       "pruned accounts",
+			accountID:  missing,
+			blockID:    blockID,
+			stateProof: prunedStateProof,
+			wantErr:    ErrInvalidStateProof,
+		},
+		{
+			name:      "another block",
+			accountID: existing,
+			blockID: ton.BlockIDExt{
+				BlockID:  blockID.BlockID,
+				RootHash: ton.Bits256{1},
+			},
+			stateProof:   stateProof,
+			accountState: accountState(existing.Address),
+			wantErr:      ErrInvalidStateProof,
+		},
+		{
+			name:         "another workchain",
+			accountID:    ton.AccountID{Workchain: 0, Address: existing.Address},
+			blockID:      blockID,
+			stateProof:   stateProof,
+			accountState: accountState(existing.Address),
+			wantErr:      ErrInvalidShardProof,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			account, err := checkAccountProof(tt.accountID, tt.blockID, blockProof, tt.stateProof, tt.accountState)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("want error: %v, got: %v", tt.wantErr, err)
+				}
+				var proofErr *ProofError
+				if !errors.As(err, &proofErr) {
+					t.Fatalf("want ProofError, got: %T", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("checkAccountProof() failed: %v", err)
+			}
+			exists := account.AccountHash != (ton.Bits256{})
+			if exists != tt.wantExists {
+				t.Fatalf("want exists: %v, got: %v", tt.wantExists, exists)
+			}
+		})
+	}
+}
+
+func Test_checkTransactionChain(t *testing.T) {
+	_, block := readTestBlock(t, "../tlb/testdata/block-1/block.bin")
+	var txs []tlb.Transaction
+	for _, accountBlock := range block.Extra.AccountBlocks.Values() {
+		values := accountBlock.Transactions.Values()
+		if len(values) < 2 {
+			continue
+		}
+		// newest first, as a lite server returns them.
+		for i := len(values) - 1; i >= 0; i-- {
+			txs = append(txs, values[i].Value)
+		}
+		break
+	}
+	if len(txs) < 2 {
+		t.Fatalf("want an account with at least two transactions")
+	}
+	cells := make([]*boc.Cell, 0, len(txs))
+	for _, tx := range txs {
+		cell := boc.NewCell()
+		if err := tlb.Marshal(cell, tx); err != nil {
+			t.Fatalf("Marshal() failed: %v", err)
+		}
+		cells = append(cells, cell)
+	}
+	hash, err := cells[0].Hash256()
+	if err != nil {
+		t.Fatalf("Hash256() failed: %v", err)
+	}
+	accountID := ton.AccountID{Workchain: 0, Address: txs[0].AccountAddr}
+	lt := txs[0].Lt
+
+	if err := checkTransactionChain(accountID, lt, hash, cells, txs); err != nil {
+		t.Fatalf("checkTransactionChain() failed: %v", err)
+	}
+	if err := checkTransactionChain(accountID, lt, ton.Bits256{1}, cells, txs); !errors.Is(err, ErrTransactionMismatch) {
+		t.Fatalf("want ErrTransactionMismatch, got: %v", err)
+	}
+	if err := checkTransactionChain(accountID, lt, hash, cells[:1], []tlb.Transaction{txs[1]}); !errors.Is(err, ErrTransactionMismatch) {
+		t.Fatalf("want ErrTransactionMismatch, got: %v", err)
+	}
+	skipped := []*boc.Cell{cells[0], cells[0]}
+	if err := checkTransactionChain(accountID, lt, hash, skipped, []tlb.Transaction{txs[0], txs[0]}); !errors.Is(err, ErrTransactionMismatch) {
+		t.Fatalf("want ErrTransactionMismatch, got: %v", err)
+	}
+	another := ton.AccountID{Workchain: 0, Address: ton.Bits256{1}}
+	if err := checkTransactionChain(another, lt, hash, cells, txs); !errors.Is(err, ErrTransactionMismatch) {
+		t.Fatalf("want ErrTransactionMismatch, got: %v", err)
+	}
+}
+
+func Test_checkStateProof_multiRoot(t *testing.T) {
+	blockCell, _ := readTestBlock(t, "../tlb/testdata/block-5/block.bin")
+	stateCell := blockCell.Refs()[2].Refs()[1]
+	block := newTestBlock(t, stateCell)
+	blockHash, err := block.Hash256()
+	if err != nil {
+		t.Fatalf("Hash256() failed: %v", err)
+	}
+	blockID := ton.BlockIDExt{
+		BlockID:  ton.BlockID{Workchain: -1, Shard: masterchainShard},
+		RootHash: blockHash,
+	}
+	blockProof := createTestProof(t, block, []int{3})
+	stateProof := createTestProof(t, stateCell, []int{0}, []int{1})
+	// a lite server can put both proofs into one bag of cells in any order.
+	proofs, err := boc.SerializeBocMulti([]*boc.Cell{stateProof, blockProof}, false, false, false, 0)
+	if err != nil {
+		t.Fatalf("SerializeBocMulti() failed: %v", err)
+	}
+	state, err := checkStateProof(blockID, proofs, proofs)
+	if err != nil {
+		t.Fatalf("checkStateProof() failed: %v", err)
+	}
+	if !state.ShardStateUnsplit.Custom.Exists {
+		t.Fatalf("want masterchain state")
+	}
+	anotherBlock := blockID
+	anotherBlock.RootHash = ton.Bits256{1}
+	if _, err := checkStateProof(anotherBlock, proofs, proofs); !errors.Is(err, boc.ErrMerkleProofNotFound) {
+		t.Fatalf("want ErrMerkleProofNotFound, got: %v", err)
+	}
+}
+
+func Test_checkBlockTransactionsProof(t *testing.T) {
+	blockCell, block := readTestBlock(t, "../tlb/testdata/block-1/block.bin")
+	blockHash, err := blockCell.Hash256()
+	if err != nil {
+		t.Fatalf("Hash256() failed: %v", err)
+	}
+	blockID := ton.BlockIDExt{RootHash: blockHash}
+	var cells []*boc.Cell
+	var txs []ton.Transaction
+	for _, accountBlock := range block.Extra.AccountBlocks.Values() {
+		for _, tx := range accountBlock.Transactions.Values() {
+			cell := boc.NewCell()
+			if err := tlb.Marshal(cell, tx.Value); err != nil {
+				t.Fatalf("Marshal() failed: %v", err)
+			}
+			cells = append(cells, cell)
+			txs = append(txs, ton.Transaction{Transaction: tx.Value, BlockID: blockID})
+		}
+	}
+	if len(txs) == 0 {
+		t.Fatalf("want a block with transactions")
+	}
+	blockCell.ResetCounters()
+	proofCell := createTestProof(t, blockCell, []int{0}, []int{1}, []int{2}, []int{3, 0}, []int{3, 1})
+	proof, err := boc.SerializeBocMulti([]*boc.Cell{proofCell}, false, false, false, 0)
+	if err != nil {
+		t.Fatalf("SerializeBocMulti() failed: %v", err)
+	}
+
+	if err := checkBlockTransactionsProof(blockID, proof, cells, txs); err != nil {
+		t.Fatalf("checkBlockTransactionsProof() failed: %v", err)
+	}
+	forged := txs[0]
+	forged.Lt += 1
+	if err := checkBlockTransactionsProof(blockID, proof, cells[:1], []ton.Transaction{forged}); !errors.Is(err, ErrTransactionMismatch) {
+		t.Fatalf("want ErrTransactionMismatch, got: %v", err)
+	}
+	anotherBlock := blockID
+	anotherBlock.RootHash = ton.Bits256{1}
+	if err := checkBlockTransactionsProof(anotherBlock, proof, cells, txs); !errors.Is(err, ErrTransactionMismatch) {
+		t.Fatalf("want ErrTransactionMismatch, got: %v", err)
+	}
+
+	var ids []liteclient.LiteServerTransactionIdC
+	for i, tx := range txs {
+		hash, err := cells[i].Hash256()
+		if err != nil {
+			t.Fatalf("Hash256() failed: %v", err)
+		}
+		account, lt, txHash := tl.Int256(tx.AccountAddr), tx.Lt, tl.Int256(hash)
+		ids = append(ids, liteclient.LiteServerTransactionIdC{Mode: 7, Account: &account, Lt: &lt, Hash: &txHash})
+	}
+	if err := checkBlockTransactionIdsProof(blockID, proof, ids); err != nil {
+		t.Fatalf("checkBlockTransactionIdsProof() failed: %v", err)
+	}
+	forgedHash := tl.Int256{1}
+	forgedID := ids[0]
+	forgedID.Hash = &forgedHash
+	if err := checkBlockTransactionIdsProof(blockID, proof, []liteclient.LiteServerTransactionIdC{forgedID}); !errors.Is(err, ErrTransactionMismatch) {
+		t.Fatalf("want ErrTransactionMismatch, got: %v", err)
+	}
+	noHashID := ids[0]
+	noHashID.Hash = nil
+	if err := checkBlockTransactionIdsProof(blockID, proof, []liteclient.LiteServerTransactionIdC{noHashID}); !errors.Is(err, ErrTransactionMismatch) {
+		t.Fatalf("want ErrTransactionMismatch, got: %v", err)
+	}
+}
+
+func Test_checkShardBlockProof(t *testing.T) {
+	blockCell, _ := readTestBlock(t, "../tlb/testdata/block-5/block.bin")
+	stateCell := blockCell.Refs()[2].Refs()[1]
+	stateCell.ResetCounters()
+	var state tlb.ShardStateUnsplit
+	if err := tlb.Unmarshal(stateCell, &state); err != nil {
+		t.Fatalf("Unmarshal() failed: %v", err)
+	}
+	stateCell.ResetCounters()
+	block := newTestBlock(t, stateCell)
+	blockHash, err := block.Hash256()
+	if err != nil {
+		t.Fatalf("Hash256() failed: %v", err)
+	}
+	masterchainBlock := ton.BlockIDExt{
+		BlockID:  ton.BlockID{Workchain: -1, Shard: masterchainShard, Seqno: state.ShardStateUnsplit.SeqNo},
+		RootHash: blockHash,
+	}
+	var shardBlock ton.BlockIDExt
+	for _, item := range state.ShardStateUnsplit.Custom.Value.Value.ShardHashes.Items() {
+		shardBlock = ton.ToBlockId(item.Value.Value.BinTree.Values[0], int32(item.Key))
+		break
+	}
+	blockProof := createTestProof(t, block, []int{3})
+	stateProof := createTestProof(t, stateCell, []int{0}, []int{1}, []int{2})
+	proof, err := boc.SerializeBocMulti([]*boc.Cell{blockProof, stateProof}, false, false, false, 0)
+	if err != nil {
+		t.Fatalf("SerializeBocMulti() failed: %v", err)
+	}
+	res := liteclient.LiteServerShardBlockProofC{
+		MasterchainId: liteclient.BlockIDExt(masterchainBlock),
+		Links: []liteclient.LiteServerShardBlockLinkC{
+			{Id: liteclient.BlockIDExt(shardBlock), Proof: proof},
+		},
+	}
+	got, err := checkShardBlockProof(res, shardBlock)
+	if err != nil {
+		t.Fatalf("checkShardBlockProof() failed: %v", err)
+	}
+	if got != masterchainBlock {
+		t.Fatalf("want masterchain block: %v, got: %v", masterchainBlock.BlockID, got.BlockID)
+	}
+	anotherShardBlock := shardBlock
+	anotherShardBlock.RootHash = ton.Bits256{1}
+	if _, err := checkShardBlockProof(res, anotherShardBlock); !errors.Is(err, ErrInvalidShardProof) {
+		t.Fatalf("want ErrInvalidShardProof, got: %v", err)
+	}
+	res.Links[0].Id = liteclient.BlockIDExt(anotherShardBlock)
+	if _, err := checkShardBlockProof(res, anotherShardBlock); !errors.Is(err, ErrInvalidShardProof) {
+		t.Fatalf("want ErrInvalidShardProof, got: %v", err)
+	}
+}
+
+func Test_checkShardProof_rootsOrder(t *testing.T) {
+	blockCell, _ := readTestBlock(t, "../tlb/testdata/block-5/block.bin")
+	stateCell := blockCell.Refs()[2].Refs()[1]
+	stateCell.ResetCounters()
+	var state tlb.ShardStateUnsplit
+	if err := tlb.Unmarshal(stateCell, &state); err != nil {
+		t.Fatalf("Unmarshal() failed: %v", err)
+	}
+	stateCell.ResetCounters()
+	block := newTestBlock(t, stateCell)
+	blockHash, err := block.Hash256()
+	if err != nil {
+		t.Fatalf("Hash256() failed: %v", err)
+	}
+	masterchainBlock := ton.BlockIDExt{
+		BlockID:  ton.BlockID{Workchain: -1, Shard: masterchainShard, Seqno: state.ShardStateUnsplit.SeqNo},
+		RootHash: blockHash,
+	}
+	var shardBlock ton.BlockIDExt
+	for _, item := range state.ShardStateUnsplit.Custom.Value.Value.ShardHashes.Items() {
+		shardBlock = ton.ToBlockId(item.Value.Value.BinTree.Values[0], int32(item.Key))
+		break
+	}
+	if shardBlock.Seqno == 0 {
+		t.Fatalf("want a shard block in the masterchain state")
+	}
+	blockProof := createTestProof(t, block, []int{3})
+	stateProof := createTestProof(t, stateCell, []int{0}, []int{1}, []int{2})
+
+	for name, roots := range map[string][]*boc.Cell{
+		"block proof first": {blockProof, stateProof},
+		"state proof first": {stateProof, blockProof},
+	} {
+		t.Run(name, func(t *testing.T) {
+			proof, err := boc.SerializeBocMulti(roots, false, false, false, 0)
+			if err != nil {
+				t.Fatalf("SerializeBocMulti() failed: %v", err)
+			}
+			if err := checkShardProof(masterchainBlock, shardBlock, proof); err != nil {
+				t.Fatalf("checkShardProof() failed: %v", err)
+			}
+			anotherShardBlock := shardBlock
+			anotherShardBlock.RootHash = ton.Bits256{1}
+			if err := checkShardProof(masterchainBlock, anotherShardBlock, proof); !errors.Is(err, ErrInvalidShardProof) {
+				t.Fatalf("want ErrInvalidShardProof, got: %v", err)
+			}
+		})
+	}
+}
+
+func Test_checkAccountStateProof_rootsOrder(t *testing.T) {
+	blockCell, _ := readTestBlock(t, "../tlb/testdata/block-5/block.bin")
+	stateCell := blockCell.Refs()[2].Refs()[1]
+	stateCell.ResetCounters()
+	var state tlb.LazyShardState
+	if err := tlb.Unmarshal(stateCell, &state); err != nil {
+		t.Fatalf("Unmarshal() failed: %v", err)
+	}
+	stateCell.ResetCounters()
+	block := newTestBlock(t, stateCell)
+	blockHash, err := block.Hash256()
+	if err != nil {
+		t.Fatalf("Hash256() failed: %v", err)
+	}
+	blockID := ton.BlockIDExt{
+		BlockID:  ton.BlockID{Workchain: -1, Shard: masterchainShard, Seqno: state.UnsplitState.Value.ShardStateUnsplit.SeqNo},
+		RootHash: blockHash,
+	}
+	var accountID ton.AccountID
+	var accountCell *boc.Cell
+	err = state.ShardAccounts[0].Iterate(func(address tlb.Bits256, account tlb.ShardAccount, info tlb.DepthBalanceInfo) error {
+		if accountCell != nil {
+			return nil
+		}
+		leaf, err := state.ShardAccounts[0].Lookup(address)
+		if err != nil {
+			return err
+		}
+		if leaf.Refs()[0].Level() == 0 {
+			accountID = ton.AccountID{Workchain: -1, Address: address}
+			accountCell = leaf.Refs()[0]
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Iterate() failed: %v", err)
+	}
+	if accountCell == nil {
+		t.Fatalf("want at least one unpruned account")
+	}
+	// a multi-root bag with the account state among other roots.
+	accountState, err := boc.SerializeBocMulti([]*boc.Cell{boc.NewCell(), accountCell}, false, false, false, 0)
+	if err != nil {
+		t.Fatalf("SerializeBocMulti() failed: %v", err)
+	}
+	blockProof := createTestProof(t, block, []int{3})
+	stateProof := createTestProof(t, stateCell, []int{0}, []int{2}, []int{3})
+	proof, err := boc.SerializeBocMulti([]*boc.Cell{stateProof, blockProof}, false, false, false, 0)
+	if err != nil {
+		t.Fatalf("SerializeBocMulti() failed: %v", err)
+	}
+	res := liteclient.LiteServerAccountStateC{
+		Id:       liteclient.BlockIDExt(blockID),
+		Shardblk: liteclient.BlockIDExt(blockID),
+		Proof:    proof,
+		State:    accountState,
+	}
+	account, err := checkAccountStateProof(accountID, res)
+	if err != nil {
+		t.Fatalf("checkAccountStateProof() failed: %v", err)
+	}
+	if account.AccountHash == (ton.Bits256{}) {
+		t.Fatalf("want existing account")
+	}
+}
+
+// testDispatchQueue mirrors AccountDispatchQueue, message values are replaced with their lts.
+type testDispatchQueue struct {
+	Messages tlb.HashmapE[tlb.Uint64, tlb.Uint64]
+	Count    tlb.Uint48
+}
+
+// newTestDispatchQueueProof returns a block with a state containing dispatch queues of three accounts
+// and a proof of the queues with the given paths of the state pruned.
+// The queues are returned as a lite server reports them.
+func newTestDispatchQueueProof(t *testing.T, prune ...[]int) (ton.BlockIDExt, []byte, []liteclient.LiteServerAccountDispatchQueueInfoC) {
+	queues := []liteclient.LiteServerAccountDispatchQueueInfoC{
+		{Addr: tl.Int256{0x10}, Size: 2, MinLt: 5, MaxLt: 7},
+		{Addr: tl.Int256{0x80}, Size: 1, MinLt: 20, MaxLt: 20},
+		{Addr: tl.Int256{0xc0}, Size: 3, MinLt: 30, MaxLt: 40},
+	}
+	lts := [][]tlb.Uint64{{5, 7}, {20}, {30, 31, 40}}
+	var keys []tlb.Bits256
+	var values []testDispatchQueue
+	var extras []uint64
+	for i, q := range queues {
+		keys = append(keys, tlb.Bits256(q.Addr))
+		values = append(values, testDispatchQueue{
+			Messages: tlb.NewHashmapE(lts[i], lts[i]),
+			Count:    tlb.Uint48(len(lts[i])),
+		})
+		extras = append(extras, q.MinLt)
+	}
+	type outMsgQueueExtra struct {
+		Magic         tlb.Magic `tlb:"out_msg_queue_extra#0"`
+		DispatchQueue tlb.HashmapAugE[tlb.Bits256, testDispatchQueue, uint64]
+		OutQueueSize  tlb.Maybe[tlb.Uint48]
+	}
+	type outMsgQueueInfo struct {
+		OutQueue tlb.HashmapE[tlb.Uint64, tlb.Uint64]
+		ProcInfo tlb.HashmapE[tlb.Uint64, tlb.Uint64]
+		Extra    tlb.Maybe[outMsgQueueExtra]
+	}
+	state := struct {
+		Magic           tlb.Magic `tlb:"shard_state#9023afe2"`
+		GlobalID        int32
+		ShardID         tlb.ShardIdent
+		SeqNo           uint32
+		VertSeqNo       uint32
+		GenUtime        uint32
+		GenLt           uint64
+		MinRefMcSeqno   uint32
+		OutMsgQueueInfo outMsgQueueInfo `tlb:"^"`
+	}{
+		GlobalID: -239,
+		ShardID:  tlb.ShardIdent{WorkchainID: -1, ShardPrefix: 1 << 63},
+		SeqNo:    100,
+	}
+	state.OutMsgQueueInfo.Extra.Exists = true
+	state.OutMsgQueueInfo.Extra.Value.DispatchQueue = tlb.NewHashmapAugE(keys, values, extras, func(left, right uint64) (uint64, error) {
+		if right < left {
+			return right, nil
+		}
+		return left, nil
+	})
+	stateCell := boc.NewCell()
+	if err := tlb.Marshal(stateCell, state); err != nil {
+		t.Fatalf("Marshal() failed: %v", err)
+	}
+	block := newTestBlock(t, stateCell)
+	blockHash, err := block.Hash256()
+	if err != nil {
+		t.Fatalf("Hash256() failed: %v", err)
+	}
+	blockID := ton.BlockIDExt{
+		BlockID:  ton.BlockID{Workchain: -1, Shard: masterchainShard, Seqno: 100},
+		RootHash: blockHash,
+	}
+	proof, err := boc.SerializeBocMulti([]*boc.Cell{
+		createTestProof(t, block, []int{3}),
+		createTestProof(t, stateCell, prune...),
+	}, false, false, false, 0)
+	if err != nil {
+		t.Fatalf("SerializeBocMulti() failed: %v", err)
+	}
+	return blockID, proof, queues
+}
+
+func Test_checkDispatchQueueProof(t *testing.T) {
+	blockID, proof, queues := newTestDispatchQueueProof(t)
+	// out_msg_queue_info -> dispatch queue -> the branch with the queues of 0x80.. and 0xc0..
+	_, prunedProof, _ := newTestDispatchQueueProof(t, []int{0, 0, 1})
+	after := ton.Bits256{0x10}
+	anotherBlock := blockID
+	anotherBlock.Seqno++
+
+	tests := []struct {
+		name      string
+		block     ton.BlockIDExt
+		proof     []byte
+		afterAddr *ton.Bits256
+		queues    []liteclient.LiteServerAccountDispatchQueueInfoC
+		complete  bool
+		wantErr   bool
+	}{
+		{name: "all queues", proof: proof, queues: queues, complete: true},
+		{name: "first queue", proof: proof, queues: queues[:1]},
+		{name: "after an address", proof: proof, afterAddr: &after, queues: queues[1:], complete: true},
+		{name: "first queue of a pruned proof", proof: prunedProof, queues: queues[:1]},
+		{name: "queue hidden by a pruned branch", proof: prunedProof, queues: queues[:2], wantErr: true},
+		{name: "skipped queue", proof: proof, queues: []liteclient.LiteServerAccountDispatchQueueInfoC{queues[0], queues[2]}, wantErr: true},
+		{name: "incomplete list reported as complete", proof: proof, queues: queues[:2], complete: true, wantErr: true},
+		{name: "forged size", proof: proof, queues: []liteclient.LiteServerAccountDispatchQueueInfoC{{Addr: queues[0].Addr, Size: 1, MinLt: 5, MaxLt: 7}}, wantErr: true},
+		{name: "forged max lt", proof: proof, queues: []liteclient.LiteServerAccountDispatchQueueInfoC{{Addr: queues[0].Addr, Size: 2, MinLt: 5, MaxLt: 6}}, wantErr: true},
+		{name: "queue of another block", block: anotherBlock, proof: proof, queues: queues, complete: true, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			block := blockID
+			if tt.block != (ton.BlockIDExt{}) {
+				block = tt.block
+			}
+			res := liteclient.LiteServerDispatchQueueInfoC{
+				Id:                    liteclient.BlockIDExt(blockID),
+				AccountDispatchQueues: tt.queues,
+				Complete:              tt.complete,
+				Proof:                 tt.proof,
+			}
+			got, err := checkDispatchQueueProof(block, tt.afterAddr, res)
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidStateProof) {
+					t.Fatalf("want ErrInvalidStateProof, got: %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("checkDispatchQueueProof() failed: %v", err)
+			}
+			if len(got) != len(tt.queues) {
+				t.Fatalf("want %v queues, got: %v", len(tt.queues), len(got))
+			}
+			for i, q := range tt.queues {
+				want := AccountDispatchQueue{
+					AccountID: ton.AccountID{Workchain: -1, Address: ton.Bits256(q.Addr)},
+					Size:      q.Size,
+					MinLt:     q.MinLt,
+					MaxLt:     q.MaxLt,
+				}
+				if got[i] != want {
+					t.Fatalf("want queue: %v, got: %v", want, got[i])
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,164 @@
+package proxy
+
+import (
+	"container/list"
+	"context"
+	"sync"
+)
+
+type cacheEntry struct {
+	key   string
+	value any
+	// size is a number of bytes the entry takes into account.
+	size int
+}
+
+// cache keeps immutable answers in an LRU list limited by their total size in bytes
+// and answers valid for the current masterchain head in a map dropped when the head changes.
+type cache struct {
+	mu sync.Mutex
+	// maxBytes is a maximum total size of entries in the LRU list.
+	maxBytes int
+	// bytes is a current total size of entries in the LRU list.
+	bytes     int
+	lru       *list.List
+	items     map[string]*list.Element
+	headSeqno uint32
+	headItems map[string]any
+}
+
+func newCache(maxBytes int) *cache {
+	return &cache{
+		maxBytes:  maxBytes,
+		lru:       list.New(),
+		items:     map[string]*list.Element{},
+		headItems: map[string]any{},
+	}
+}
+
+func (c *cache) get(key string) (any, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.items[key]
+	if !ok {
+		return nil, false
+	}
+	c.lru.MoveToFront(e)
+	return e.Value.(*cacheEntry).value, true
+}
+
+// add caches an immutable answer of the given size in bytes.
+// An answer larger than the whole cache isn't cached.
+func (c *cache) add(key string, value any, size int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	size += len(key)
+	if size > c.maxBytes {
+		return
+	}
+	if e, ok := c.items[key]; ok {
+		c.lru.MoveToFront(e)
+		entry := e.Value.(*cacheEntry)
+		c.bytes += size - entry.size
+		entry.value = value
+		entry.size = size
+	} else {
+		c.items[key] = c.lru.PushFront(&cacheEntry{key: key, value: value, size: size})
+		c.bytes += size
+	}
+	for c.bytes > c.maxBytes {
+		e := c.lru.Back()
+		c.lru.Remove(e)
+		entry := e.Value.(*cacheEntry)
+		delete(c.items, entry.key)
+		c.bytes -= entry.size
+	}
+}
+
+// getHead returns an answer cached for the given masterchain head.
+func (c *cache) getHead(seqno uint32, key string) (any, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if seqno != c.headSeqno {
+		return nil, false
+	}
+	value, ok := c.headItems[key]
+	return value, ok
+}
+
+// addHead caches an answer for the given masterchain head.
+// Answers for older heads are dropped.
+func (c *cache) addHead(seqno uint32, key string, value any) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if seqno < c.headSeqno {
+		return
+	}
+	if seqno > c.headSeqno {
+		c.headSeqno = seqno
+		c.headItems = map[string]any{}
+	}
+	c.headItems[key] = value
+}
+
+// call is an in-flight upstream request shared by identical requests.
+type call struct {
+	done  chan struct{}
+	value any
+	err   error
+	// waiters is a number of callers waiting for the result.
+	waiters int
+	cancel  context.CancelFunc
+}
+
+// flightGroup coalesces concurrent identical requests into one upstream request.
+type flightGroup struct {
+	mu    sync.Mutex
+	calls map[string]*call
+}
+
+// do runs f once for all concurrent callers with the same key.
+// shared reports if the result was obtained by another caller.
+// f runs with a context detached from the callers,
+// a caller stops waiting when its context is done and f is cancelled once no callers are left.
+func (g *flightGroup) do(ctx context.Context, key string, f func(ctx context.Context) (any, error)) (value any, shared bool, err error) {
+	g.mu.Lock()
+	if g.calls == nil {
+		g.calls = map[string]*call{}
+	}
+	c, shared := g.calls[key]
+	if !shared {
+		callCtx, cancel := context.WithCancel(context.Background())
+		c = &call{done: make(chan struct{}), cancel: cancel}
+		g.calls[key] = c
+		go func() {
+			c.value, c.err = f(callCtx)
+			g.mu.Lock()
+			if g.calls[key] == c {
+				delete(g.calls, key)
+			}
+			g.mu.Unlock()
+			close(c.done)
+			cancel()
+		}()
+	}
+	c.waiters += 1
+	g.mu.Unlock()
+
+	select {
+	case <-c.done:
+		return c.value, shared, c.err
+	case <-ctx.Done():
+		g.mu.Lock()
+		c.waiters -= 1
+		if c.waiters == 0 {
+			// nobody needs the result anymore, later callers start a new request.
+			if g.calls[key] == c {
+				delete(g.calls, key)
+			}
+			c.cancel()
+		}
+		g.mu.Unlock()
+		return nil, shared, ctx.Err()
+	}
+}
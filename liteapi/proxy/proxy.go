@@ -0,0 +1,332 @@
+// Package proxy implements a lite server handler forwarding queries to upstream lite servers.
+// Concurrent identical queries are coalesced into one upstream query,
+// and answers that can't change are cached.
+package proxy
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/tonkeeper/tongo/liteclient"
+	"github.com/tonkeeper/tongo/tl"
+	"github.com/tonkeeper/tongo/ton"
+)
+
+const (
+	// defaultCacheSize is a default maximum total size in bytes of cached immutable answers.
+	defaultCacheSize = 64 << 20
+)
+
+// Upstream provides lite server clients to forward queries to.
+// It is implemented by pool.ConnPool.
+type Upstream interface {
+	// BestMasterchainClient returns a lite server client and its known masterchain head.
+	BestMasterchainClient(ctx context.Context) (*liteclient.Client, ton.BlockIDExt, error)
+	WaitMasterchainSeqno(ctx context.Context, seqno uint32, timeout time.Duration) error
+}
+
+// policy specifies how answers to a query are reused.
+type policy int
+
+const (
+	// forward sends every query upstream.
+	forward policy = iota
+	// coalesce shares an answer among concurrent identical queries.
+	coalesce
+	// cacheHead works as coalesce and additionally caches an answer until the masterchain head changes.
+	// It is used by queries without a block id.
+	cacheHead
+	// cacheForever works as coalesce and additionally caches an answer for good.
+	// It is used by queries for a particular block, their answers can't change.
+	cacheForever
+)
+
+// Stats contains counters of a proxy.
+type Stats struct {
+	// Hits is a number of queries answered from the cache.
+	Hits uint64
+	// Misses is a number of queries sent upstream.
+	Misses uint64
+	// Coalesced is a number of queries answered with a result of a concurrent identical query.
+	Coalesced uint64
+	// Errors is a number of queries failed upstream.
+	Errors uint64
+}
+
+// Proxy is a liteclient.LiteServerHandler answering queries with the help of upstream lite servers.
+type Proxy struct {
+	upstream Upstream
+	cache    *cache
+	group    flightGroup
+
+	hits      atomic.Uint64
+	misses    atomic.Uint64
+	coalesced atomic.Uint64
+	errors    atomic.Uint64
+}
+
+var _ liteclient.LiteServerHandler = (*Proxy)(nil)
+var _ liteclient.MasterchainSeqnoWaiter = (*Proxy)(nil)
+
+// Options holds parameters to configure a proxy.
+type Options struct {
+	// CacheSize is a maximum total size in bytes of cached immutable answers.
+	CacheSize int
+}
+
+type Option func(o *Options)
+
+// WithCacheSize sets a maximum total size in bytes of cached immutable answers.
+// Zero disables caching, concurrent identical queries are still coalesced.
+func WithCacheSize(size int) Option {
+	return func(o *Options) {
+		o.CacheSize = size
+	}
+}
+
+// New returns a proxy forwarding queries to the given upstream.
+func New(upstream Upstream, opts ...Option) *Proxy {
+	options := &Options{
+		CacheSize: defaultCacheSize,
+	}
+	for _, o := range opts {
+		o(options)
+	}
+	return &Proxy{
+		upstream: upstream,
+		cache:    newCache(options.CacheSize),
+	}
+}
+
+// Stats returns current values of the proxy's counters.
+func (p *Proxy) Stats() Stats {
+	return Stats{
+		Hits:      p.hits.Load(),
+		Misses:    p.misses.Load(),
+		Coalesced: p.coalesced.Load(),
+		Errors:    p.errors.Load(),
+	}
+}
+
+// query answers a request with the given policy.
+// A request is identified by its name and serialized value.
+// Immutable answers are looked up in the cache before choosing an upstream client,
+// answers for a masterchain head need the upstream's head to be looked up.
+func query[Req, Res any](ctx context.Context, p *Proxy, pol policy, name string, request Req, f func(*liteclient.Client, context.Context, Req) (Res, error)) (res Res, err error) {
+	var key string
+	if pol != forward {
+		payload, err := tl.Marshal(request)
+		if err != nil {
+			return res, err
+		}
+		key = name + "/" + string(payload)
+	}
+	if pol == cacheForever {
+		if value, ok := p.cache.get(key); ok {
+			p.hits.Add(1)
+			return value.(Res), nil
+		}
+	}
+	client, head, err := p.upstream.BestMasterchainClient(ctx)
+	if err != nil {
+		return res, err
+	}
+	send := func(ctx context.Context) (Res, error) {
+		p.misses.Add(1)
+		res, err := f(client, ctx, request)
+		if err != nil {
+			p.errors.Add(1)
+		}
+		return res, err
+	}
+	if pol == forward {
+		return send(ctx)
+	}
+	flightKey := key
+	if pol == cacheHead {
+		if value, ok := p.cache.getHead(head.Seqno, key); ok {
+			p.hits.Add(1)
+			return value.(Res), nil
+		}
+		// identical queries for different heads must not be coalesced.
+		flightKey = fmt.Sprintf("%v/%v", head.Seqno, key)
+	}
+	value, shared, err := p.group.do(ctx, flightKey, func(ctx context.Context) (any, error) {
+		res, err := send(ctx)
+		if err != nil {
+			return nil, err
+		}
+		switch pol {
+		case cacheHead:
+			p.cache.addHead(head.Seqno, key, res)
+		case cacheForever:
+			if p.cache.maxBytes > 0 {
+				answer, err := tl.Marshal(res)
+				if err != nil {
+					return nil, err
+				}
+				p.cache.add(key, res, len(answer))
+			}
+		}
+		return res, nil
+	})
+	if shared {
+		p.coalesced.Add(1)
+	}
+	if err != nil {
+		return res, err
+	}
+	return value.(Res), nil
+}
+
+// noRequest adapts a client method without parameters to query.
+func noRequest[Res any](f func(*liteclient.Client, context.Context) (Res, error)) func(*liteclient.Client, context.Context, struct{}) (Res, error) {
+	return func(c *liteclient.Client, ctx context.Context, _ struct{}) (Res, error) {
+		return f(c, ctx)
+	}
+}
+
+// WaitMasterchainSeqno waits for the upstream to reach the given masterchain block.
+func (p *Proxy) WaitMasterchainSeqno(ctx context.Context, seqno uint32, timeout uint32) error {
+	return p.upstream.WaitMasterchainSeqno(ctx, seqno, time.Duration(timeout)*time.Millisecond)
+}
+
+func (p *Proxy) LiteServerGetMasterchainInfo(ctx context.Context) (liteclient.LiteServerMasterchainInfoC, error) {
+	return query(ctx, p, cacheHead, "getMasterchainInfo", struct{}{}, noRequest((*liteclient.Client).LiteServerGetMasterchainInfo))
+}
+
+func (p *Proxy) LiteServerGetMasterchainInfoExt(ctx context.Context, request liteclient.LiteServerGetMasterchainInfoExtRequest) (liteclient.LiteServerMasterchainInfoExtC, error) {
+	return query(ctx, p, cacheHead, "getMasterchainInfoExt", request, (*liteclient.Client).LiteServerGetMasterchainInfoExt)
+}
+
+func (p *Proxy) LiteServerGetTime(ctx context.Context) (liteclient.LiteServerCurrentTimeC, error) {
+	return query(ctx, p, coalesce, "getTime", struct{}{}, noRequest((*liteclient.Client).LiteServerGetTime))
+}
+
+func (p *Proxy) LiteServerGetVersion(ctx context.Context) (liteclient.LiteServerVersionC, error) {
+	return query(ctx, p, coalesce, "getVersion", struct{}{}, noRequest((*liteclient.Client).LiteServerGetVersion))
+}
+
+func (p *Proxy) LiteServerGetBlock(ctx context.Context, request liteclient.LiteServerGetBlockRequest) (liteclient.LiteServerBlockDataC, error) {
+	return query(ctx, p, cacheForever, "getBlock", request, (*liteclient.Client).LiteServerGetBlock)
+}
+
+// LiteServerGetState isn't cached because a state can be huge.
+func (p *Proxy) LiteServerGetState(ctx context.Context, request liteclient.LiteServerGetStateRequest) (liteclient.LiteServerBlockStateC, error) {
+	return query(ctx, p, coalesce, "getState", request, (*liteclient.Client).LiteServerGetState)
+}
+
+func (p *Proxy) LiteServerGetBlockHeader(ctx context.Context, request liteclient.LiteServerGetBlockHeaderRequest) (liteclient.LiteServerBlockHeaderC, error) {
+	return query(ctx, p, cacheForever, "getBlockHeader", request, (*liteclient.Client).LiteServerGetBlockHeader)
+}
+
+func (p *Proxy) LiteServerSendMessage(ctx context.Context, request liteclient.LiteServerSendMessageRequest) (liteclient.LiteServerSendMsgStatusC, error) {
+	return query(ctx, p, forward, "sendMessage", request, (*liteclient.Client).LiteServerSendMessage)
+}
+
+func (p *Proxy) LiteServerGetAccountState(ctx context.Context, request liteclient.LiteServerGetAccountStateRequest) (liteclient.LiteServerAccountStateC, error) {
+	return query(ctx, p, cacheForever, "getAccountState", request, (*liteclient.Client).LiteServerGetAccountState)
+}
+
+func (p *Proxy) LiteServerGetAccountStatePrunned(ctx context.Context, request liteclient.LiteServerGetAccountStatePrunnedRequest) (liteclient.LiteServerAccountStateC, error) {
+	return query(ctx, p, cacheForever, "getAccountStatePrunned", request, (*liteclient.Client).LiteServerGetAccountStatePrunned)
+}
+
+func (p *Proxy) LiteServerRunSmcMethod(ctx context.Context, request liteclient.LiteServerRunSmcMethodRequest) (liteclient.LiteServerRunMethodResultC, error) {
+	return query(ctx, p, cacheForever, "runSmcMethod", request, (*liteclient.Client).LiteServerRunSmcMethod)
+}
+
+func (p *Proxy) LiteServerGetShardInfo(ctx context.Context, request liteclient.LiteServerGetShardInfoRequest) (liteclient.LiteServerShardInfoC, error) {
+	return query(ctx, p, cacheForever, "getShardInfo", request, (*liteclient.Client).LiteServerGetShardInfo)
+}
+
+func (p *Proxy) LiteServerGetAllShardsInfo(ctx context.Context, request liteclient.LiteServerGetAllShardsInfoRequest) (liteclient.LiteServerAllShardsInfoC, error) {
+	return query(ctx, p, cacheForever, "getAllShardsInfo", request, (*liteclient.Client).LiteServerGetAllShardsInfo)
+}
+
+func (p *Proxy) LiteServerGetOneTransaction(ctx context.Context, request liteclient.LiteServerGetOneTransactionRequest) (liteclient.LiteServerTransactionInfoC, error) {
+	return query(ctx, p, cacheForever, "getOneTransaction", request, (*liteclient.Client).LiteServerGetOneTransaction)
+}
+
+func (p *Proxy) LiteServerGetTransactions(ctx context.Context, request liteclient.LiteServerGetTransactionsRequest) (liteclient.LiteServerTransactionListC, error) {
+	return query(ctx, p, cacheForever, "getTransactions", request, (*liteclient.Client).LiteServerGetTransactions)
+}
+
+// LiteServerLookupBlock caches only lookups by seqno,
+// a block found by lt or utime near the head depends on which blocks exist yet.
+func (p *Proxy) LiteServerLookupBlock(ctx context.Context, request liteclient.LiteServerLookupBlockRequest) (liteclient.LiteServerBlockHeaderC, error) {
+	pol := coalesce
+	if request.Mode&1 != 0 {
+		pol = cacheForever
+	}
+	return query(ctx, p, pol, "lookupBlock", request, (*liteclient.Client).LiteServerLookupBlock)
+}
+
+func (p *Proxy) LiteServerLookupBlockWithProof(ctx context.Context, request liteclient.LiteServerLookupBlockWithProofRequest) (liteclient.LiteServerLookupBlockResultC, error) {
+	return query(ctx, p, cacheForever, "lookupBlockWithProof", request, (*liteclient.Client).LiteServerLookupBlockWithProof)
+}
+
+func (p *Proxy) LiteServerListBlockTransactions(ctx context.Context, request liteclient.LiteServerListBlockTransactionsRequest) (liteclient.LiteServerBlockTransactionsC, error) {
+	return query(ctx, p, cacheForever, "listBlockTransactions", request, (*liteclient.Client).LiteServerListBlockTransactions)
+}
+
+func (p *Proxy) LiteServerListBlockTransactionsExt(ctx context.Context, request liteclient.LiteServerListBlockTransactionsExtRequest) (liteclient.LiteServerBlockTransactionsExtC, error) {
+	return query(ctx, p, cacheForever, "listBlockTransactionsExt", request, (*liteclient.Client).LiteServerListBlockTransactionsExt)
+}
+
+// LiteServerGetBlockProof caches a proof without a target block only until the masterchain head changes,
+// because such a proof leads to the last known block.
+func (p *Proxy) LiteServerGetBlockProof(ctx context.Context, request liteclient.LiteServerGetBlockProofRequest) (liteclient.LiteServerPartialBlockProofC, error) {
+	pol := cacheForever
+	if request.Mode&1 == 0 {
+		pol = cacheHead
+	}
+	return query(ctx, p, pol, "getBlockProof", request, (*liteclient.Client).LiteServerGetBlockProof)
+}
+
+func (p *Proxy) LiteServerGetConfigAll(ctx context.Context, request liteclient.LiteServerGetConfigAllRequest) (liteclient.LiteServerConfigInfoC, error) {
+	return query(ctx, p, cacheForever, "getConfigAll", request, (*liteclient.Client).LiteServerGetConfigAll)
+}
+
+func (p *Proxy) LiteServerGetConfigParams(ctx context.Context, request liteclient.LiteServerGetConfigParamsRequest) (liteclient.LiteServerConfigInfoC, error) {
+	return query(ctx, p, cacheForever, "getConfigParams", request, (*liteclient.Client).LiteServerGetConfigParams)
+}
+
+func (p *Proxy) LiteServerGetValidatorStats(ctx context.Context, request liteclient.LiteServerGetValidatorStatsRequest) (liteclient.LiteServerValidatorStatsC, error) {
+	return query(ctx, p, cacheForever, "getValidatorStats", request, (*liteclient.Client).LiteServerGetValidatorStats)
+}
+
+// LiteServerGetLibraries caches libraries by their hashes.
+func (p *Proxy) LiteServerGetLibraries(ctx context.Context, request liteclient.LiteServerGetLibrariesRequest) (liteclient.LiteServerLibraryResultC, error) {
+	return query(ctx, p, cacheForever, "getLibraries", request, (*liteclient.Client).LiteServerGetLibraries)
+}
+
+func (p *Proxy) LiteServerGetLibrariesWithProof(ctx context.Context, request liteclient.LiteServerGetLibrariesWithProofRequest) (liteclient.LiteServerLibraryResultWithProofC, error) {
+	return query(ctx, p, cacheForever, "getLibrariesWithProof", request, (*liteclient.Client).LiteServerGetLibrariesWithProof)
+}
+
+func (p *Proxy) LiteServerGetShardBlockProof(ctx context.Context, request liteclient.LiteServerGetShardBlockProofRequest) (liteclient.LiteServerShardBlockProofC, error) {
+	return query(ctx, p, cacheForever, "getShardBlockProof", request, (*liteclient.Client).LiteServerGetShardBlockProof)
+}
+
+func (p *Proxy) LiteServerGetOutMsgQueueSizes(ctx context.Context, request liteclient.LiteServerGetOutMsgQueueSizesRequest) (liteclient.LiteServerOutMsgQueueSizesC, error) {
+	return query(ctx, p, cacheHead, "getOutMsgQueueSizes", request, (*liteclient.Client).LiteServerGetOutMsgQueueSizes)
+}
+
+func (p *Proxy) LiteServerGetDispatchQueueInfo(ctx context.Context, request liteclient.LiteServerGetDispatchQueueInfoRequest) (liteclient.LiteServerDispatchQueueInfoC, error) {
+	return query(ctx, p, cacheForever, "getDispatchQueueInfo", request, (*liteclient.Client).LiteServerGetDispatchQueueInfo)
+}
+
+func (p *Proxy) LiteServerNonfinalGetValidatorGroups(ctx context.Context, request liteclient.LiteServerNonfinalGetValidatorGroupsRequest) (liteclient.LiteServerNonfinalValidatorGroupsC, error) {
+	return query(ctx, p, coalesce, "nonfinal.getValidatorGroups", request, (*liteclient.Client).LiteServerNonfinalGetValidatorGroups)
+}
+
+func (p *Proxy) LiteServerNonfinalGetCandidate(ctx context.Context, request liteclient.LiteServerNonfinalGetCandidateRequest) (liteclient.LiteServerNonfinalCandidateC, error) {
+	return query(ctx, p, cacheForever, "nonfinal.getCandidate", request, (*liteclient.Client).LiteServerNonfinalGetCandidate)
+}
+
+func (p *Proxy) LiteProxyGetRequestRateLimit(ctx context.Context) (liteclient.LiteProxyRequestRateLimitC, error) {
+	return query(ctx, p, forward, "getRequestRateLimit", struct{}{}, noRequest((*liteclient.Client).LiteProxyGetRequestRateLimit))
+}
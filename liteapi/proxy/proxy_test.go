@@ -0,0 +1,365 @@
+package proxy
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"errors"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/tonkeeper/tongo/liteclient"
+	"github.com/tonkeeper/tongo/ton"
+)
+
+type testHandler struct {
+	liteclient.UnimplementedLiteServerHandler
+
+	mu           sync.Mutex
+	calls        map[string]int
+	headerCalled chan struct{}
+	headerReady  chan struct{}
+}
+
+func (h *testHandler) called(name string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.calls[name] += 1
+}
+
+func (h *testHandler) callsNumber(name string) int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.calls[name]
+}
+
+func (h *testHandler) LiteServerGetMasterchainInfo(ctx context.Context) (liteclient.LiteServerMasterchainInfoC, error) {
+	h.called("getMasterchainInfo")
+	return liteclient.LiteServerMasterchainInfoC{}, nil
+}
+
+func (h *testHandler) LiteServerGetBlock(ctx context.Context, request liteclient.LiteServerGetBlockRequest) (liteclient.LiteServerBlockDataC, error) {
+	h.called("getBlock")
+	return liteclient.LiteServerBlockDataC{Id: request.Id, Data: []byte{byte(request.Id.Seqno)}}, nil
+}
+
+func (h *testHandler) LiteServerGetBlockHeader(ctx context.Context, request liteclient.LiteServerGetBlockHeaderRequest) (liteclient.LiteServerBlockHeaderC, error) {
+	h.called("getBlockHeader")
+	h.headerCalled <- struct{}{}
+	<-h.headerReady
+	return liteclient.LiteServerBlockHeaderC{Id: request.Id}, nil
+}
+
+func (h *testHandler) LiteServerGetAccountState(ctx context.Context, request liteclient.LiteServerGetAccountStateRequest) (liteclient.LiteServerAccountStateC, error) {
+	h.called("getAccountState")
+	return liteclient.LiteServerAccountStateC{}, errors.New("block is not ready")
+}
+
+func (h *testHandler) LiteServerLookupBlock(ctx context.Context, request liteclient.LiteServerLookupBlockRequest) (liteclient.LiteServerBlockHeaderC, error) {
+	h.called("lookupBlock")
+	return liteclient.LiteServerBlockHeaderC{Id: liteclient.TonNodeBlockIdExtC{Seqno: request.Id.Seqno}}, nil
+}
+
+func (h *testHandler) LiteServerSendMessage(ctx context.Context, request liteclient.LiteServerSendMessageRequest) (liteclient.LiteServerSendMsgStatusC, error) {
+	h.called("sendMessage")
+	return liteclient.LiteServerSendMsgStatusC{Status: 1}, nil
+}
+
+type testUpstream struct {
+	client *liteclient.Client
+
+	mu          sync.Mutex
+	head        ton.BlockIDExt
+	clientCalls int
+}
+
+func (u *testUpstream) BestMasterchainClient(ctx context.Context) (*liteclient.Client, ton.BlockIDExt, error) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.clientCalls += 1
+	return u.client, u.head, nil
+}
+
+func (u *testUpstream) clientCallsNumber() int {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	return u.clientCalls
+}
+
+func (u *testUpstream) WaitMasterchainSeqno(ctx context.Context, seqno uint32, timeout time.Duration) error {
+	return nil
+}
+
+func (u *testUpstream) setHead(seqno uint32) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.head.Seqno = seqno
+}
+
+func newTestUpstream(t *testing.T, handler liteclient.LiteServerHandler) *testUpstream {
+	public, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey() failed: %v", err)
+	}
+	server, err := liteclient.NewServer(private, handler)
+	if err != nil {
+		t.Fatalf("NewServer() failed: %v", err)
+	}
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen() failed: %v", err)
+	}
+	go server.Serve(l)
+	t.Cleanup(func() { server.Close() })
+	conn, err := liteclient.NewConnection(context.Background(), public, l.Addr().String())
+	if err != nil {
+		t.Fatalf("NewConnection() failed: %v", err)
+	}
+	return &testUpstream{client: liteclient.NewClient(conn), head: ton.BlockIDExt{BlockID: ton.BlockID{Seqno: 10}}}
+}
+
+func TestProxy(t *testing.T) {
+	handler := &testHandler{
+		calls:        map[string]int{},
+		headerCalled: make(chan struct{}, 1),
+		headerReady:  make(chan struct{}),
+	}
+	upstream := newTestUpstream(t, handler)
+	p := New(upstream)
+	ctx := context.Background()
+
+	// immutable answers are cached.
+	for _, seqno := range []uint32{1, 2, 1, 2} {
+		block, err := p.LiteServerGetBlock(ctx, liteclient.LiteServerGetBlockRequest{Id: liteclient.TonNodeBlockIdExtC{Seqno: seqno}})
+		if err != nil {
+			t.Fatalf("LiteServerGetBlock() failed: %v", err)
+		}
+		if block.Id.Seqno != seqno || block.Data[0] != byte(seqno) {
+			t.Fatalf("want block %v, got: %v", seqno, block.Id.Seqno)
+		}
+	}
+	if n := handler.callsNumber("getBlock"); n != 2 {
+		t.Fatalf("want 2 upstream getBlock calls, got: %v", n)
+	}
+	if stats := p.Stats(); stats.Hits != 2 || stats.Misses != 2 {
+		t.Fatalf("unexpected stats: %+v", stats)
+	}
+	if n := upstream.clientCallsNumber(); n != 2 {
+		t.Fatalf("want cache hits to skip upstream, got %v client calls", n)
+	}
+
+	// a masterchain head is cached until the head changes.
+	for _, seqno := range []uint32{10, 10, 11, 11} {
+		upstream.setHead(seqno)
+		if _, err := p.LiteServerGetMasterchainInfo(ctx); err != nil {
+			t.Fatalf("LiteServerGetMasterchainInfo() failed: %v", err)
+		}
+	}
+	if n := handler.callsNumber("getMasterchainInfo"); n != 2 {
+		t.Fatalf("want 2 upstream getMasterchainInfo calls, got: %v", n)
+	}
+
+	// errors are not cached, messages are always forwarded.
+	for i := 0; i < 2; i++ {
+		if _, err := p.LiteServerGetAccountState(ctx, liteclient.LiteServerGetAccountStateRequest{}); err == nil {
+			t.Fatalf("want error")
+		}
+		if _, err := p.LiteServerSendMessage(ctx, liteclient.LiteServerSendMessageRequest{Body: []byte{1}}); err != nil {
+			t.Fatalf("LiteServerSendMessage() failed: %v", err)
+		}
+	}
+	if n := handler.callsNumber("getAccountState"); n != 2 {
+		t.Fatalf("want 2 upstream getAccountState calls, got: %v", n)
+	}
+	if n := handler.callsNumber("sendMessage"); n != 2 {
+		t.Fatalf("want 2 upstream sendMessage calls, got: %v", n)
+	}
+}
+
+func TestProxy_LiteServerLookupBlock(t *testing.T) {
+	lt := uint64(1000)
+	utime := uint32(1700000000)
+	tests := []struct {
+		name      string
+		request   liteclient.LiteServerLookupBlockRequest
+		wantCalls int
+	}{
+		{
+			name:      "by seqno is cached",
+			request:   liteclient.LiteServerLookupBlockRequest{Mode: 1, Id: liteclient.TonNodeBlockIdC{Seqno: 5}},
+			wantCalls: 1,
+		},
+		{
+			name:      "by lt is not cached",
+			request:   liteclient.LiteServerLookupBlockRequest{Mode: 2, Lt: &lt},
+			wantCalls: 2,
+		},
+		{
+			name:      "by utime is not cached",
+			request:   liteclient.LiteServerLookupBlockRequest{Mode: 4, Utime: &utime},
+			wantCalls: 2,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := &testHandler{calls: map[string]int{}}
+			p := New(newTestUpstream(t, handler))
+			for i := 0; i < 2; i++ {
+				if _, err := p.LiteServerLookupBlock(context.Background(), tt.request); err != nil {
+					t.Fatalf("LiteServerLookupBlock() failed: %v", err)
+				}
+			}
+			if n := handler.callsNumber("lookupBlock"); n != tt.wantCalls {
+				t.Fatalf("want %v upstream lookupBlock calls, got: %v", tt.wantCalls, n)
+			}
+		})
+	}
+}
+
+func TestProxy_coalescing(t *testing.T) {
+	handler := &testHandler{
+		calls:        map[string]int{},
+		headerCalled: make(chan struct{}, 1),
+		headerReady:  make(chan struct{}),
+	}
+	p := New(newTestUpstream(t, handler), WithCacheSize(0))
+	request := liteclient.LiteServerGetBlockHeaderRequest{Id: liteclient.TonNodeBlockIdExtC{Seqno: 5}}
+
+	var wg sync.WaitGroup
+	results := make([]liteclient.LiteServerBlockHeaderC, 5)
+	errs := make([]error, 5)
+	query := func(i int) {
+		defer wg.Done()
+		results[i], errs[i] = p.LiteServerGetBlockHeader(context.Background(), request)
+	}
+	wg.Add(1)
+	go query(0)
+	<-handler.headerCalled
+	for i := 1; i < len(results); i++ {
+		wg.Add(1)
+		go query(i)
+	}
+	// give the followers some time to join the in-flight request.
+	time.Sleep(100 * time.Millisecond)
+	close(handler.headerReady)
+	wg.Wait()
+
+	for i := range results {
+		if errs[i] != nil {
+			t.Fatalf("LiteServerGetBlockHeader() failed: %v", errs[i])
+		}
+		if results[i].Id.Seqno != 5 {
+			t.Fatalf("want block 5, got: %v", results[i].Id.Seqno)
+		}
+	}
+	if n := handler.callsNumber("getBlockHeader"); n != 1 {
+		t.Fatalf("want 1 upstream getBlockHeader call, got: %v", n)
+	}
+	if stats := p.Stats(); stats.Coalesced != 4 || stats.Hits != 0 {
+		t.Fatalf("unexpected stats: %+v", stats)
+	}
+}
+
+func Test_cache_maxBytes(t *testing.T) {
+	c := newCache(100)
+	c.add("a", 1, 40)
+	c.add("b", 2, 40)
+	if _, ok := c.get("a"); !ok {
+		t.Fatalf("want a cached")
+	}
+	// "b" is the least recently used entry now.
+	c.add("c", 3, 40)
+	if _, ok := c.get("b"); ok {
+		t.Fatalf("want b evicted")
+	}
+	if _, ok := c.get("a"); !ok {
+		t.Fatalf("want a cached")
+	}
+	if c.bytes != 82 {
+		t.Fatalf("want 82 bytes, got: %v", c.bytes)
+	}
+	c.add("d", 4, 200)
+	if _, ok := c.get("d"); ok {
+		t.Fatalf("want too large answer not cached")
+	}
+	if len(c.items) != 2 || c.lru.Len() != 2 {
+		t.Fatalf("want 2 entries, got: %v", len(c.items))
+	}
+}
+
+func Test_flightGroup_detachedContext(t *testing.T) {
+	var g flightGroup
+	started := make(chan struct{})
+	release := make(chan struct{})
+	cancelled := make(chan struct{})
+	f := func(ctx context.Context) (any, error) {
+		close(started)
+		select {
+		case <-release:
+			return 1, nil
+		case <-ctx.Done():
+			close(cancelled)
+			return nil, ctx.Err()
+		}
+	}
+
+	// the first caller leaves, the call keeps running for the second one.
+	ctx1, cancel1 := context.WithCancel(context.Background())
+	errs1 := make(chan error, 1)
+	go func() {
+		_, _, err := g.do(ctx1, "key", f)
+		errs1 <- err
+	}()
+	<-started
+	results := make(chan any, 1)
+	go func() {
+		value, shared, err := g.do(context.Background(), "key", f)
+		if err != nil || !shared {
+			t.Errorf("want shared result, got: %v, %v", shared, err)
+		}
+		results <- value
+	}()
+	for {
+		g.mu.Lock()
+		waiters := g.calls["key"].waiters
+		g.mu.Unlock()
+		if waiters == 2 {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	cancel1()
+	if err := <-errs1; !errors.Is(err, context.Canceled) {
+		t.Fatalf("want canceled, got: %v", err)
+	}
+	close(release)
+	if value := <-results; value != 1 {
+		t.Fatalf("want 1, got: %v", value)
+	}
+
+	// the call is cancelled once all callers leave.
+	started = make(chan struct{})
+	ctx2, cancel2 := context.WithCancel(context.Background())
+	errs2 := make(chan error, 1)
+	go func() {
+		_, _, err := g.do(ctx2, "key", func(ctx context.Context) (any, error) {
+			close(started)
+			<-ctx.Done()
+			close(cancelled)
+			return nil, ctx.Err()
+		})
+		errs2 <- err
+	}()
+	<-started
+	cancel2()
+	if err := <-errs2; !errors.Is(err, context.Canceled) {
+		t.Fatalf("want canceled, got: %v", err)
+	}
+	select {
+	case <-cancelled:
+	case <-time.After(time.Second):
+		t.Fatalf("want the shared call cancelled")
+	}
+}
@@ -0,0 +1,147 @@
+package liteapi
+
+import (
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/tonkeeper/tongo/boc"
+	"github.com/tonkeeper/tongo/liteclient"
+	"github.com/tonkeeper/tongo/ton"
+)
+
+// ErrNoQuorum means that not enough lite servers returned the same result.
+var ErrNoQuorum = errors.New("lite servers don't agree on result")
+
+// QuorumError is returned by a client created with WithQuorum
+// when less than the required number of lite servers agree on a result.
+type QuorumError struct {
+	// Required is a number of lite servers which have to agree on a result.
+	Required int
+	// Results contains an answer of every queried lite server.
+	Results []QuorumResult
+}
+
+// QuorumResult describes an answer of a single lite server.
+type QuorumResult struct {
+	Server string
+	// Hash identifies a result, lite servers agree if their results have equal hashes.
+	// For account states, it is a hash of the account's state,
+	// for transactions, it is a hash of the list of transaction hashes.
+	Hash ton.Bits256
+	// Err is an error returned by the lite server, Hash is meaningless if it is not nil.
+	Err error
+}
+
+func (e *QuorumError) Error() string {
+	results := make([]string, 0, len(e.Results))
+	for _, r := range e.Results {
+		if r.Err != nil {
+			results = append(results, fmt.Sprintf("%v: %v", r.Server, r.Err))
+			continue
+		}
+		results = append(results, fmt.Sprintf("%v: %v", r.Server, r.Hash.Hex()))
+	}
+	return fmt.Sprintf("%v, %v required: %v", ErrNoQuorum, e.Required, strings.Join(results, ", "))
+}
+
+func (e *QuorumError) Is(target error) bool {
+	return target == ErrNoQuorum
+}
+
+type quorum struct {
+	n, m int
+}
+
+// WithQuorum returns a client which sends account state and transaction requests
+// to n different lite servers at once and returns a result only if at least m of them agree on it.
+// Otherwise, a request fails with *QuorumError containing results of all lite servers.
+// This is useful for critical reads like checking a balance before a large payout.
+//
+// The connections pool has to have at least n connections, take a look at WithMaxConnectionsNumber() option.
+// Account states are requested at the oldest masterchain head of the chosen lite servers
+// unless the client is pinned to a block with WithBlock or Snapshot.
+// Transactions are never requested from archive nodes in this mode.
+// It returns an error if n is not positive or m is not in the range from 1 to n.
+func (c *Client) WithQuorum(n, m int) (*Client, error) {
+	if n <= 0 || m < 1 || m > n {
+		return nil, fmt.Errorf("invalid quorum: %v of %v", m, n)
+	}
+	cli := c.clone()
+	cli.quorum = &quorum{n: n, m: m}
+	return cli, nil
+}
+
+// quorumQuery calls f on c.quorum.n lite servers and returns a result returned by at least c.quorum.m of them.
+// hash identifies a result.
+func quorumQuery[T any](ctx context.Context, c *Client, f func(client *liteclient.Client, masterHead ton.BlockIDExt) (T, error), hash func(T) (ton.Bits256, error)) (T, error) {
+	var empty T
+	n, m := c.quorum.n, c.quorum.m
+	values := make([]T, n)
+	results := make([]QuorumResult, n)
+	errs, err := c.pool.Quorum(ctx, n, c.targetBlockOr(ton.BlockIDExt{}).Seqno, func(i int, serverHost string, client *liteclient.Client, masterHead ton.BlockIDExt) error {
+		results[i].Server = serverHost
+		value, err := f(client, masterHead)
+		if err != nil {
+			return err
+		}
+		values[i] = value
+		results[i].Hash, err = hash(value)
+		return err
+	})
+	if err != nil {
+		return empty, err
+	}
+	votes := make(map[ton.Bits256]int, n)
+	for i := range results {
+		results[i].Err = errs[i]
+		if errs[i] != nil {
+			continue
+		}
+		votes[results[i].Hash] += 1
+		if votes[results[i].Hash] >= m {
+			return values[i], nil
+		}
+	}
+	return empty, &QuorumError{Required: m, Results: results}
+}
+
+// accountStateHash returns a hash of an account's state or a zero hash if the account doesn't exist.
+func accountStateHash(res liteclient.LiteServerAccountStateC) (ton.Bits256, error) {
+	if len(res.State) == 0 {
+		return ton.Bits256{}, nil
+	}
+	cells, err := boc.DeserializeBoc(res.State)
+	if err != nil {
+		return ton.Bits256{}, err
+	}
+	if len(cells) != 1 {
+		return ton.Bits256{}, boc.ErrNotSingleRoot
+	}
+	hash, err := cells[0].Hash256()
+	return ton.Bits256(hash), err
+}
+
+// transactionListHash returns a hash of hashes of the given transactions.
+func transactionListHash(res liteclient.LiteServerTransactionListC) (ton.Bits256, error) {
+	if len(res.Transactions) == 0 {
+		return ton.Bits256{}, nil
+	}
+	cells, err := boc.DeserializeBoc(res.Transactions)
+	if err != nil {
+		return ton.Bits256{}, err
+	}
+	h := sha256.New()
+	for _, cell := range cells {
+		hash, err := cell.Hash()
+		if err != nil {
+			return ton.Bits256{}, err
+		}
+		h.Write(hash)
+	}
+	var hash ton.Bits256
+	copy(hash[:], h.Sum(nil))
+	return hash, nil
+}
@@ -0,0 +1,137 @@
+package liteapi
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/tonkeeper/tongo/boc"
+	"github.com/tonkeeper/tongo/config"
+	"github.com/tonkeeper/tongo/liteclient"
+	"github.com/tonkeeper/tongo/ton"
+)
+
+// quorumHandler is a lite server which answers with the given account state.
+type quorumHandler struct {
+	*snapshotHandler
+	state []byte
+}
+
+func (h *quorumHandler) LiteServerGetAccountState(ctx context.Context, request liteclient.LiteServerGetAccountStateRequest) (liteclient.LiteServerAccountStateC, error) {
+	res, err := h.snapshotHandler.LiteServerGetAccountState(ctx, request)
+	res.State = h.state
+	return res, err
+}
+
+func testAccountState(t *testing.T, balance uint64) []byte {
+	cell := boc.NewCell()
+	if err := cell.WriteUint(balance, 64); err != nil {
+		t.Fatalf("WriteUint() failed: %v", err)
+	}
+	state, err := cell.ToBoc()
+	if err != nil {
+		t.Fatalf("ToBoc() failed: %v", err)
+	}
+	return state
+}
+
+func TestClient_WithQuorum(t *testing.T) {
+	honest := testAccountState(t, 100)
+	servers := []config.LiteServer{
+		startTestLiteServer(t, &quorumHandler{snapshotHandler: &snapshotHandler{head: 100}, state: honest}),
+		startTestLiteServer(t, &quorumHandler{snapshotHandler: &snapshotHandler{head: 100}, state: honest}),
+		startTestLiteServer(t, &quorumHandler{snapshotHandler: &snapshotHandler{head: 100}, state: testAccountState(t, 1_000_000)}),
+	}
+	cli, err := NewClient(WithLiteServers(servers), WithMaxConnectionsNumber(3))
+	if err != nil {
+		t.Fatalf("NewClient() failed: %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	for {
+		if _, err := cli.pool.Quorum(ctx, 3, 100, func(int, string, *liteclient.Client, ton.BlockIDExt) error { return nil }); err == nil {
+			break
+		}
+		if ctx.Err() != nil {
+			t.Fatalf("the pool doesn't have 3 connections")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	accountID := ton.AccountID{Workchain: -1}
+
+	quorumCli, err := cli.WithQuorum(3, 2)
+	if err != nil {
+		t.Fatalf("WithQuorum() failed: %v", err)
+	}
+	res, err := quorumCli.GetAccountStateRaw(ctx, accountID)
+	if err != nil {
+		t.Fatalf("GetAccountStateRaw() failed: %v", err)
+	}
+	if string(res.State) != string(honest) {
+		t.Fatalf("want a state returned by the majority")
+	}
+
+	quorumCli, err = cli.WithQuorum(3, 3)
+	if err != nil {
+		t.Fatalf("WithQuorum() failed: %v", err)
+	}
+	_, err = quorumCli.GetAccountStateRaw(ctx, accountID)
+	var quorumErr *QuorumError
+	if !errors.As(err, &quorumErr) || !errors.Is(err, ErrNoQuorum) {
+		t.Fatalf("want QuorumError, got: %v", err)
+	}
+	if quorumErr.Required != 3 || len(quorumErr.Results) != 3 {
+		t.Fatalf("want 3 results with 3 required, got: %v", quorumErr)
+	}
+	hashes := map[ton.Bits256]int{}
+	for _, r := range quorumErr.Results {
+		if r.Err != nil || r.Server == "" {
+			t.Fatalf("unexpected result: %#v", r)
+		}
+		hashes[r.Hash] += 1
+	}
+	if len(hashes) != 2 {
+		t.Fatalf("want 2 different results, got: %v", hashes)
+	}
+
+	quorumCli, err = cli.WithQuorum(4, 2)
+	if err != nil {
+		t.Fatalf("WithQuorum() failed: %v", err)
+	}
+	if _, err := quorumCli.GetAccountStateRaw(ctx, accountID); err == nil {
+		t.Fatalf("quorum of more lite servers than the pool has must fail")
+	}
+
+	for _, q := range [][2]int{{0, 0}, {-1, 1}, {2, 3}, {3, 0}} {
+		if _, err := cli.WithQuorum(q[0], q[1]); err == nil {
+			t.Fatalf("want error for quorum %v of %v", q[1], q[0])
+		}
+	}
+}
+
+func TestClient_clone(t *testing.T) {
+	networkID := int32(-239)
+	block := ton.BlockIDExt{BlockID: ton.BlockID{Workchain: -1, Shard: masterchainShard, Seqno: 5}}
+	cli := &Client{proofPolicy: ProofPolicyFast, networkGlobalID: &networkID}
+
+	quorumCli, err := cli.WithQuorum(3, 2)
+	if err != nil {
+		t.Fatalf("WithQuorum() failed: %v", err)
+	}
+	blockCli := quorumCli.WithBlock(block)
+	for _, c := range []*Client{quorumCli, blockCli} {
+		if id := c.getNetworkGlobalID(); id == nil || *id != networkID {
+			t.Fatalf("want network global id %v, got: %v", networkID, id)
+		}
+		if c.proofPolicy != ProofPolicyFast || c.quorum == nil || *c.quorum != (quorum{n: 3, m: 2}) {
+			t.Fatalf("want client settings to be kept")
+		}
+	}
+	if target, ok := blockCli.TargetBlock(); !ok || target != block {
+		t.Fatalf("want target block %v, got: %v", block, target)
+	}
+	if _, ok := quorumCli.TargetBlock(); ok {
+		t.Fatalf("want no target block")
+	}
+}
@@ -0,0 +1,88 @@
+package liteapi
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/tonkeeper/tongo/ton"
+)
+
+// Snapshot returns a client pinned to the current masterchain block.
+// It works the same way as WithBlock, so account states, get methods and configuration
+// are evaluated at the pinned block no matter which lite server is used for a request.
+// Requests are sent only to lite servers which know the pinned block,
+// and an answer evaluated at another block is rejected.
+// Use TargetBlock to get the pinned block and GetAccountShardBlock to find a shard block of an account in it.
+// If the client is already pinned to a block, the returned client is pinned to the same block.
+func (c *Client) Snapshot(ctx context.Context) (*Client, error) {
+	if block, ok := c.TargetBlock(); ok {
+		return c.WithBlock(block), nil
+	}
+	_, head, err := c.pool.BestMasterchainClient(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if c.verifier != nil {
+		if err := c.verifier.verify(ctx, c, head); err != nil {
+			return nil, err
+		}
+	}
+	return c.WithBlock(head), nil
+}
+
+// TargetBlock returns a masterchain block the client is pinned to with WithBlock or Snapshot.
+func (c *Client) TargetBlock() (ton.BlockIDExt, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if c.targetBlockID == nil {
+		return ton.BlockIDExt{}, false
+	}
+	return *c.targetBlockID, true
+}
+
+// checkTargetBlock returns an error if the client is pinned to a block
+// but a lite server evaluated a request at another one.
+func (c *Client) checkTargetBlock(blockID ton.BlockIDExt) error {
+	if target, ok := c.TargetBlock(); ok && target != blockID {
+		return fmt.Errorf("lite server answered at block %v instead of %v", blockID.BlockID, target.BlockID)
+	}
+	return nil
+}
+
+// requiredSeqno returns a seqno of a masterchain block a lite server has to know to answer a request about the given block.
+func (c *Client) requiredSeqno(blockID ton.BlockIDExt) uint32 {
+	if blockID.Workchain == -1 {
+		return blockID.Seqno
+	}
+	return c.targetBlockOr(ton.BlockIDExt{}).Seqno
+}
+
+// GetAccountShardBlock returns a shard block containing a state of the account
+// at the masterchain block the client is pinned to or at the current masterchain head.
+func (c *Client) GetAccountShardBlock(ctx context.Context, accountID ton.AccountID) (ton.BlockIDExt, error) {
+	masterchainBlock, ok := c.TargetBlock()
+	if !ok {
+		_, head, err := c.pool.BestMasterchainClient(ctx)
+		if err != nil {
+			return ton.BlockIDExt{}, err
+		}
+		masterchainBlock = head
+	}
+	if accountID.Workchain == -1 {
+		return masterchainBlock, nil
+	}
+	shards, err := c.GetAllShardsInfo(ctx, masterchainBlock)
+	if err != nil {
+		return ton.BlockIDExt{}, err
+	}
+	for _, block := range shards {
+		shard, err := ton.ParseShardID(int64(block.Shard))
+		if err != nil {
+			return ton.BlockIDExt{}, err
+		}
+		if block.Workchain == accountID.Workchain && shard.MatchAccountID(accountID) {
+			return block, nil
+		}
+	}
+	return ton.BlockIDExt{}, fmt.Errorf("no shard of workchain %v in block %v", accountID.Workchain, masterchainBlock.BlockID)
+}
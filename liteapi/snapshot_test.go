@@ -0,0 +1,113 @@
+package liteapi
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/tonkeeper/tongo/config"
+	"github.com/tonkeeper/tongo/liteapi/pool"
+	"github.com/tonkeeper/tongo/liteclient"
+	"github.com/tonkeeper/tongo/ton"
+)
+
+// snapshotHandler is a lite server with the given masterchain head
+// which answers account state requests at the requested block.
+type snapshotHandler struct {
+	liteclient.UnimplementedLiteServerHandler
+
+	head uint32
+	// answerAt makes the server answer at another block if it's not zero.
+	answerAt uint32
+
+	mu       sync.Mutex
+	requests int
+}
+
+func (h *snapshotHandler) LiteServerGetMasterchainInfo(ctx context.Context) (liteclient.LiteServerMasterchainInfoC, error) {
+	return liteclient.LiteServerMasterchainInfoC{
+		Last: liteclient.TonNodeBlockIdExtC{Workchain: uint32(0xffffffff), Shard: masterchainShard, Seqno: h.head},
+	}, nil
+}
+
+func (h *snapshotHandler) LiteServerGetAccountState(ctx context.Context, request liteclient.LiteServerGetAccountStateRequest) (liteclient.LiteServerAccountStateC, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.requests += 1
+	if request.Id.Seqno > h.head {
+		return liteclient.LiteServerAccountStateC{}, liteclient.LiteServerErrorC{Code: 651, Message: "block not found"}
+	}
+	id := request.Id
+	if h.answerAt > 0 {
+		id.Seqno = h.answerAt
+	}
+	return liteclient.LiteServerAccountStateC{Id: id, Shardblk: id}, nil
+}
+
+// WaitMasterchainSeqno makes connections of a pool wait for new blocks instead of failing.
+func (h *snapshotHandler) WaitMasterchainSeqno(ctx context.Context, seqno uint32, timeout uint32) error {
+	if seqno <= h.head {
+		return nil
+	}
+	select {
+	case <-ctx.Done():
+	case <-time.After(time.Duration(timeout) * time.Millisecond):
+	}
+	return liteclient.LiteServerErrorC{Code: 652, Message: "timeout"}
+}
+
+func (h *snapshotHandler) setAnswerAt(seqno uint32) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.answerAt = seqno
+}
+
+func (h *snapshotHandler) requestsNumber() int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.requests
+}
+
+func TestClient_Snapshot(t *testing.T) {
+	behind := &snapshotHandler{head: 100}
+	ahead := &snapshotHandler{head: 105}
+	cli, err := NewClient(
+		WithLiteServers([]config.LiteServer{startTestLiteServer(t, behind), startTestLiteServer(t, ahead)}),
+		WithMaxConnectionsNumber(2))
+	if err != nil {
+		t.Fatalf("NewClient() failed: %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	// the snapshot block has to be known by the pool before requests are routed to it.
+	for cli.pool.DoAt(ctx, pool.ReadRequest, 105, func(*liteclient.Client, ton.BlockIDExt) error { return nil }) != nil {
+		if ctx.Err() != nil {
+			t.Fatalf("the pool doesn't know block 105")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	block := ton.BlockIDExt{BlockID: ton.BlockID{Workchain: -1, Shard: masterchainShard, Seqno: 105}}
+	snapshot := cli.WithBlock(block)
+	if target, ok := snapshot.TargetBlock(); !ok || target != block {
+		t.Fatalf("want target block %v, got: %v", block, target)
+	}
+	accountID := ton.AccountID{Workchain: -1}
+	for i := 0; i < 5; i++ {
+		res, err := snapshot.GetAccountStateRaw(ctx, accountID)
+		if err != nil {
+			t.Fatalf("GetAccountStateRaw() failed: %v", err)
+		}
+		if res.Id.ToBlockIdExt() != block {
+			t.Fatalf("want state at %v, got: %v", block, res.Id.ToBlockIdExt())
+		}
+	}
+	if behind.requestsNumber() != 0 {
+		t.Fatalf("requests must be routed only to lite servers which know the snapshot block")
+	}
+
+	ahead.setAnswerAt(104)
+	if _, err := snapshot.GetAccountStateRaw(ctx, accountID); err == nil {
+		t.Fatalf("an answer at another block must be rejected")
+	}
+}
@@ -0,0 +1,350 @@
+package liteapi
+
+import (
+	"context"
+	"time"
+
+	"github.com/tonkeeper/tongo/boc"
+	"github.com/tonkeeper/tongo/liteapi/pool"
+	"github.com/tonkeeper/tongo/liteclient"
+	"github.com/tonkeeper/tongo/tl"
+	"github.com/tonkeeper/tongo/tlb"
+	"github.com/tonkeeper/tongo/ton"
+)
+
+const (
+	// subscriptionWaitTimeout limits a single wait for the next masterchain block.
+	subscriptionWaitTimeout = time.Minute
+	// blockTransactionsPageSize is a number of transactions requested at once.
+	blockTransactionsPageSize = 256
+)
+
+// subscriptionBackoff is used between failed waits for the next masterchain block.
+var subscriptionBackoff = pool.Backoff{
+	Initial:    100 * time.Millisecond,
+	Max:        5 * time.Second,
+	Multiplier: 2,
+}
+
+// blockSource provides blocks followed by a subscription.
+// It is implemented by Client.
+type blockSource interface {
+	masterchainHead(ctx context.Context) (ton.BlockIDExt, error)
+	WaitMasterchainSeqno(ctx context.Context, seqno uint32, timeout time.Duration) error
+	LookupBlock(ctx context.Context, blockID ton.BlockID, mode uint32, lt *uint64, utime *uint32) (ton.BlockIDExt, tlb.BlockInfo, error)
+	GetAllShardsInfo(ctx context.Context, blockID ton.BlockIDExt) ([]ton.BlockIDExt, error)
+	getBlockInfo(ctx context.Context, blockID ton.BlockIDExt) (tlb.BlockInfo, error)
+}
+
+// Cursor is a position of an event in a subscription.
+// Pass a cursor of the last processed event to ResumeBlocks or TransactionFilter.After
+// to continue a subscription right after that event.
+type Cursor struct {
+	// MasterchainSeqno is a seqno of a masterchain block which references the block.
+	MasterchainSeqno uint32
+	// BlockIndex is a position of the block among blocks emitted for the masterchain block.
+	BlockIndex int
+	// TransactionIndex is a position of the transaction in the block.
+	// It is always 0 for block events.
+	TransactionIndex int
+}
+
+func (c Cursor) less(other Cursor) bool {
+	if c.MasterchainSeqno != other.MasterchainSeqno {
+		return c.MasterchainSeqno < other.MasterchainSeqno
+	}
+	if c.BlockIndex != other.BlockIndex {
+		return c.BlockIndex < other.BlockIndex
+	}
+	return c.TransactionIndex < other.TransactionIndex
+}
+
+// BlockEvent is a block emitted by a block subscription.
+type BlockEvent struct {
+	Block ton.BlockIDExt
+	// MasterchainBlock is the first masterchain block which references Block.
+	// It is Block itself for masterchain blocks.
+	MasterchainBlock ton.BlockIDExt
+	Cursor           Cursor
+}
+
+// TransactionEvent is a transaction emitted by a transaction subscription.
+type TransactionEvent struct {
+	ton.Transaction
+	// MasterchainBlock is the first masterchain block which references the transaction's block.
+	MasterchainBlock ton.BlockIDExt
+	Cursor           Cursor
+}
+
+// TransactionFilter specifies transactions emitted by SubscribeTransactions.
+type TransactionFilter struct {
+	// FromSeqno is a seqno of the first masterchain block to process.
+	// Zero means the current masterchain head.
+	FromSeqno uint32
+	// After resumes a subscription right after the transaction with this cursor.
+	// FromSeqno is ignored if After is set.
+	After *Cursor
+	// Accounts limits transactions to the given accounts. Empty means all accounts.
+	Accounts []ton.AccountID
+	// Opcodes limits transactions to ones whose inbound message body starts with one of the given opcodes.
+	// Empty means all transactions.
+	Opcodes []uint32
+}
+
+// Subscription delivers events until its context is done or an error occurs.
+type Subscription[T any] struct {
+	events chan T
+	err    error
+}
+
+func newSubscription[T any](ctx context.Context, run func(ctx context.Context, emit func(T) error) error) *Subscription[T] {
+	s := &Subscription[T]{events: make(chan T)}
+	go func() {
+		defer close(s.events)
+		s.err = run(ctx, func(event T) error {
+			select {
+			case s.events <- event:
+				return nil
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		})
+	}()
+	return s
+}
+
+// Events returns a channel with events. The channel is closed when the subscription stops.
+func (s *Subscription[T]) Events() <-chan T {
+	return s.events
+}
+
+// Err returns an error which stopped the subscription.
+// It must be called after the events channel is closed.
+func (s *Subscription[T]) Err() error {
+	return s.err
+}
+
+// SubscribeBlocks follows the masterchain starting from the masterchain block with the given seqno
+// and emits every new block of all workchains.
+// Shard blocks created between two consecutive masterchain blocks are emitted before the later masterchain block,
+// each block is emitted after its parents.
+// Zero fromSeqno means the current masterchain head.
+func (c *Client) SubscribeBlocks(ctx context.Context, fromSeqno uint32) *Subscription[BlockEvent] {
+	return newSubscription(ctx, func(ctx context.Context, emit func(BlockEvent) error) error {
+		return followBlocks(ctx, c, fromSeqno, emit)
+	})
+}
+
+// ResumeBlocks works the same way as SubscribeBlocks but starts right after the block with the given cursor.
+func (c *Client) ResumeBlocks(ctx context.Context, after Cursor) *Subscription[BlockEvent] {
+	return newSubscription(ctx, func(ctx context.Context, emit func(BlockEvent) error) error {
+		return resumeBlocks(ctx, c, after, emit)
+	})
+}
+
+// resumeBlocks works as followBlocks but skips blocks up to the given cursor.
+func resumeBlocks(ctx context.Context, src blockSource, after Cursor, emit func(BlockEvent) error) error {
+	return followBlocks(ctx, src, after.MasterchainSeqno, func(event BlockEvent) error {
+		if !after.less(event.Cursor) {
+			return nil
+		}
+		return emit(event)
+	})
+}
+
+// SubscribeTransactions follows the masterchain the same way as SubscribeBlocks
+// and emits transactions of every new block matching the filter.
+// Transactions of a block are emitted in the order of account addresses and logical time.
+func (c *Client) SubscribeTransactions(ctx context.Context, filter TransactionFilter) *Subscription[TransactionEvent] {
+	fromSeqno := filter.FromSeqno
+	if filter.After != nil {
+		fromSeqno = filter.After.MasterchainSeqno
+	}
+	return newSubscription(ctx, func(ctx context.Context, emit func(TransactionEvent) error) error {
+		return followBlocks(ctx, c, fromSeqno, func(event BlockEvent) error {
+			if filter.After != nil {
+				blockCursor := Cursor{MasterchainSeqno: filter.After.MasterchainSeqno, BlockIndex: filter.After.BlockIndex}
+				if event.Cursor.less(blockCursor) {
+					return nil
+				}
+			}
+			if !filter.matchBlock(event.Block) {
+				return nil
+			}
+			txs, err := c.blockTransactions(ctx, event.Block)
+			if err != nil {
+				return err
+			}
+			for i, tx := range txs {
+				cursor := event.Cursor
+				cursor.TransactionIndex = i
+				if filter.After != nil && !filter.After.less(cursor) {
+					continue
+				}
+				if !filter.matchTransaction(tx) {
+					continue
+				}
+				err := emit(TransactionEvent{Transaction: tx, MasterchainBlock: event.MasterchainBlock, Cursor: cursor})
+				if err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+	})
+}
+
+// matchBlock reports if the block can contain transactions of the filter's accounts.
+func (f TransactionFilter) matchBlock(blockID ton.BlockIDExt) bool {
+	if len(f.Accounts) == 0 {
+		return true
+	}
+	shard, err := ton.ParseShardID(int64(blockID.Shard))
+	if err != nil {
+		return false
+	}
+	for _, account := range f.Accounts {
+		if account.Workchain == blockID.Workchain && shard.MatchAccountID(account) {
+			return true
+		}
+	}
+	return false
+}
+
+func (f TransactionFilter) matchTransaction(tx ton.Transaction) bool {
+	if len(f.Accounts) > 0 {
+		found := false
+		for _, account := range f.Accounts {
+			if account.Workchain == tx.BlockID.Workchain && account.Address == tx.AccountAddr {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if len(f.Opcodes) == 0 {
+		return true
+	}
+	if !tx.Msgs.InMsg.Exists {
+		return false
+	}
+	body := boc.Cell(tx.Msgs.InMsg.Value.Value.Body.Value)
+	body.ResetCounters()
+	if body.BitsAvailableForRead() < 32 {
+		return false
+	}
+	opcode, err := body.ReadUint(32)
+	if err != nil {
+		return false
+	}
+	for _, op := range f.Opcodes {
+		if uint64(op) == opcode {
+			return true
+		}
+	}
+	return false
+}
+
+// followBlocks calls emit for every block referenced by masterchain blocks starting from fromSeqno.
+func followBlocks(ctx context.Context, src blockSource, fromSeqno uint32, emit func(BlockEvent) error) error {
+	seqno := fromSeqno
+	if seqno == 0 {
+		head, err := src.masterchainHead(ctx)
+		if err != nil {
+			return err
+		}
+		seqno = head.Seqno
+	}
+	var prevShards []ton.BlockIDExt
+	if seqno > 0 {
+		prev, err := waitMasterchainBlockID(ctx, src, seqno-1)
+		if err != nil {
+			return err
+		}
+		if prevShards, err = src.GetAllShardsInfo(ctx, prev); err != nil {
+			return err
+		}
+	}
+	for {
+		masterchainBlock, err := waitMasterchainBlockID(ctx, src, seqno)
+		if err != nil {
+			return err
+		}
+		shards, err := src.GetAllShardsInfo(ctx, masterchainBlock)
+		if err != nil {
+			return err
+		}
+		blocks, err := ton.WalkShardBlocks(ctx, prevShards, shards, src.getBlockInfo)
+		if err != nil {
+			return err
+		}
+		blocks = append(blocks, masterchainBlock)
+		for i, block := range blocks {
+			event := BlockEvent{
+				Block:            block,
+				MasterchainBlock: masterchainBlock,
+				Cursor:           Cursor{MasterchainSeqno: seqno, BlockIndex: i},
+			}
+			if err := emit(event); err != nil {
+				return err
+			}
+		}
+		prevShards = shards
+		seqno++
+	}
+}
+
+// waitMasterchainBlockID waits for a masterchain block with the given seqno and returns its id.
+// Failed waits are repeated with subscriptionBackoff until ctx is done.
+func waitMasterchainBlockID(ctx context.Context, src blockSource, seqno uint32) (ton.BlockIDExt, error) {
+	for attempt := 0; ; attempt++ {
+		err := src.WaitMasterchainSeqno(ctx, seqno, subscriptionWaitTimeout)
+		if err == nil {
+			break
+		}
+		if ctx.Err() != nil {
+			return ton.BlockIDExt{}, ctx.Err()
+		}
+		timer := time.NewTimer(subscriptionBackoff.Delay(attempt))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ton.BlockIDExt{}, ctx.Err()
+		case <-timer.C:
+		}
+	}
+	blockID, _, err := src.LookupBlock(ctx, ton.BlockID{Workchain: -1, Shard: masterchainShard, Seqno: seqno}, 1, nil, nil)
+	return blockID, err
+}
+
+// masterchainHead returns the masterchain head of the best lite server.
+func (c *Client) masterchainHead(ctx context.Context) (ton.BlockIDExt, error) {
+	_, head, err := c.pool.BestMasterchainClient(ctx)
+	return head, err
+}
+
+// blockTransactions returns all transactions of the given block.
+func (c *Client) blockTransactions(ctx context.Context, blockID ton.BlockIDExt) ([]ton.Transaction, error) {
+	var (
+		txs   []ton.Transaction
+		after *liteclient.LiteServerTransactionId3C
+	)
+	for {
+		var mode uint32
+		if after != nil {
+			mode |= 128
+		}
+		page, incomplete, err := c.ListBlockTransactionsExt(ctx, blockID, mode, blockTransactionsPageSize, after)
+		if err != nil {
+			return nil, err
+		}
+		txs = append(txs, page...)
+		if !incomplete || len(page) == 0 {
+			return txs, nil
+		}
+		last := page[len(page)-1]
+		after = &liteclient.LiteServerTransactionId3C{Account: tl.Int256(last.AccountAddr), Lt: last.Lt}
+	}
+}
@@ -0,0 +1,291 @@
+package liteapi
+
+import (
+	"context"
+	"errors"
+	"math/bits"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/tonkeeper/tongo/boc"
+	"github.com/tonkeeper/tongo/liteclient"
+	"github.com/tonkeeper/tongo/tlb"
+	"github.com/tonkeeper/tongo/ton"
+)
+
+func newTestTransaction(t *testing.T, account ton.AccountID, opcode *uint32) ton.Transaction {
+	tx := ton.Transaction{BlockID: ton.BlockIDExt{BlockID: ton.BlockID{Workchain: account.Workchain, Shard: masterchainShard}}}
+	tx.AccountAddr = account.Address
+	if opcode != nil {
+		body := boc.NewCell()
+		if err := body.WriteUint(uint64(*opcode), 32); err != nil {
+			t.Fatalf("WriteUint() failed: %v", err)
+		}
+		tx.Msgs.InMsg.Exists = true
+		tx.Msgs.InMsg.Value.Value.Body.Value = tlb.Any(*body)
+	}
+	return tx
+}
+
+func TestTransactionFilter(t *testing.T) {
+	alice := ton.AccountID{Workchain: 0, Address: ton.Bits256{0x10}}
+	bob := ton.AccountID{Workchain: 0, Address: ton.Bits256{0x90}}
+	transfer := uint32(0x0f8a7ea5)
+	notify := uint32(0x7362d09c)
+
+	tests := []struct {
+		name   string
+		filter TransactionFilter
+		tx     ton.Transaction
+		want   bool
+	}{
+		{
+			name: "empty filter",
+			tx:   newTestTransaction(t, alice, nil),
+			want: true,
+		},
+		{
+			name:   "account matches",
+			filter: TransactionFilter{Accounts: []ton.AccountID{bob, alice}},
+			tx:     newTestTransaction(t, alice, nil),
+			want:   true,
+		},
+		{
+			name:   "another account",
+			filter: TransactionFilter{Accounts: []ton.AccountID{bob}},
+			tx:     newTestTransaction(t, alice, nil),
+		},
+		{
+			name:   "opcode matches",
+			filter: TransactionFilter{Opcodes: []uint32{notify, transfer}},
+			tx:     newTestTransaction(t, alice, &transfer),
+			want:   true,
+		},
+		{
+			name:   "another opcode",
+			filter: TransactionFilter{Opcodes: []uint32{notify}},
+			tx:     newTestTransaction(t, alice, &transfer),
+		},
+		{
+			name:   "no inbound message",
+			filter: TransactionFilter{Opcodes: []uint32{transfer}},
+			tx:     newTestTransaction(t, alice, nil),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.filter.matchTransaction(tt.tx); got != tt.want {
+				t.Fatalf("matchTransaction() want: %v, got: %v", tt.want, got)
+			}
+		})
+	}
+
+	filter := TransactionFilter{Accounts: []ton.AccountID{alice}}
+	left := ton.BlockIDExt{BlockID: ton.BlockID{Workchain: 0, Shard: 0x4000000000000000}}
+	right := ton.BlockIDExt{BlockID: ton.BlockID{Workchain: 0, Shard: 0xc000000000000000}}
+	masterchain := ton.BlockIDExt{BlockID: ton.BlockID{Workchain: -1, Shard: masterchainShard}}
+	if !filter.matchBlock(left) || filter.matchBlock(right) || filter.matchBlock(masterchain) {
+		t.Fatalf("want only the left shard to match")
+	}
+}
+
+func TestCursor_less(t *testing.T) {
+	cursors := []Cursor{
+		{MasterchainSeqno: 1, BlockIndex: 2, TransactionIndex: 5},
+		{MasterchainSeqno: 2},
+		{MasterchainSeqno: 2, TransactionIndex: 1},
+		{MasterchainSeqno: 2, BlockIndex: 1},
+	}
+	for i := 0; i < len(cursors)-1; i++ {
+		if !cursors[i].less(cursors[i+1]) || cursors[i+1].less(cursors[i]) {
+			t.Fatalf("want %v before %v", cursors[i], cursors[i+1])
+		}
+	}
+}
+
+// fakeBlockSource is a blockchain with a split of a workchain 0 shard.
+type fakeBlockSource struct {
+	head        uint32
+	masterchain map[uint32]ton.BlockIDExt
+	shards      map[uint32][]ton.BlockIDExt
+	infos       map[ton.BlockIDExt]tlb.BlockInfo
+	// waitFailures is a number of waits failing before the first successful one.
+	waitFailures int
+	waits        int
+	shardsErr    error
+}
+
+func newTestShardBlockInfo(block, parent ton.BlockIDExt, afterSplit bool) tlb.BlockInfo {
+	var info tlb.BlockInfo
+	info.SeqNo = block.Seqno
+	info.AfterSplit = afterSplit
+	lowBit := block.Shard & (^block.Shard + 1)
+	info.Shard = tlb.ShardIdent{
+		ShardPfxBits: tlb.Uint6(63 - bits.TrailingZeros64(block.Shard)),
+		WorkchainID:  block.Workchain,
+		ShardPrefix:  block.Shard - lowBit,
+	}
+	info.PrevRef.SumType = "PrevBlkInfo"
+	info.PrevRef.PrevBlkInfo = &struct{ Prev tlb.ExtBlkRef }{
+		Prev: tlb.ExtBlkRef{SeqNo: parent.Seqno, RootHash: tlb.Bits256(parent.RootHash), FileHash: tlb.Bits256(parent.FileHash)},
+	}
+	return info
+}
+
+func newFakeBlockSource() *fakeBlockSource {
+	id := func(workchain int32, shard uint64, seqno uint32) ton.BlockIDExt {
+		return ton.BlockIDExt{
+			BlockID:  ton.BlockID{Workchain: workchain, Shard: shard, Seqno: seqno},
+			RootHash: ton.Bits256{byte(seqno), byte(shard >> 56)},
+		}
+	}
+	shard := id(0, 0x8000000000000000, 20)
+	left := id(0, 0x4000000000000000, 21)
+	right := id(0, 0xc000000000000000, 21)
+	left2 := id(0, 0x4000000000000000, 22)
+	return &fakeBlockSource{
+		head: 12,
+		masterchain: map[uint32]ton.BlockIDExt{
+			10: id(-1, masterchainShard, 10),
+			11: id(-1, masterchainShard, 11),
+			12: id(-1, masterchainShard, 12),
+		},
+		shards: map[uint32][]ton.BlockIDExt{
+			10: {shard},
+			11: {left, right},
+			12: {left2, right},
+		},
+		infos: map[ton.BlockIDExt]tlb.BlockInfo{
+			left:  newTestShardBlockInfo(left, shard, true),
+			right: newTestShardBlockInfo(right, shard, true),
+			left2: newTestShardBlockInfo(left2, left, false),
+		},
+	}
+}
+
+func (s *fakeBlockSource) masterchainHead(ctx context.Context) (ton.BlockIDExt, error) {
+	return s.masterchain[s.head], nil
+}
+
+func (s *fakeBlockSource) WaitMasterchainSeqno(ctx context.Context, seqno uint32, timeout time.Duration) error {
+	s.waits += 1
+	if s.waitFailures > 0 {
+		s.waitFailures -= 1
+		return errors.New("connection failure")
+	}
+	if seqno > s.head {
+		return liteclient.LiteServerErrorC{Code: 652, Message: "timeout"}
+	}
+	return nil
+}
+
+func (s *fakeBlockSource) LookupBlock(ctx context.Context, blockID ton.BlockID, mode uint32, lt *uint64, utime *uint32) (ton.BlockIDExt, tlb.BlockInfo, error) {
+	block, ok := s.masterchain[blockID.Seqno]
+	if !ok {
+		return ton.BlockIDExt{}, tlb.BlockInfo{}, errors.New("block not found")
+	}
+	return block, tlb.BlockInfo{}, nil
+}
+
+func (s *fakeBlockSource) GetAllShardsInfo(ctx context.Context, blockID ton.BlockIDExt) ([]ton.BlockIDExt, error) {
+	if s.shardsErr != nil {
+		return nil, s.shardsErr
+	}
+	return s.shards[blockID.Seqno], nil
+}
+
+func (s *fakeBlockSource) getBlockInfo(ctx context.Context, blockID ton.BlockIDExt) (tlb.BlockInfo, error) {
+	info, ok := s.infos[blockID]
+	if !ok {
+		return tlb.BlockInfo{}, errors.New("block not found")
+	}
+	return info, nil
+}
+
+var errStopTest = errors.New("stop")
+
+// collectBlocks returns the given number of events emitted by follow.
+func collectBlocks(t *testing.T, number int, follow func(ctx context.Context, emit func(BlockEvent) error) error) []BlockEvent {
+	var events []BlockEvent
+	err := follow(context.Background(), func(event BlockEvent) error {
+		events = append(events, event)
+		if len(events) == number {
+			return errStopTest
+		}
+		return nil
+	})
+	if !errors.Is(err, errStopTest) {
+		t.Fatalf("want stop error, got: %v", err)
+	}
+	return events
+}
+
+func Test_followBlocks(t *testing.T) {
+	src := newFakeBlockSource()
+	events := collectBlocks(t, 5, func(ctx context.Context, emit func(BlockEvent) error) error {
+		return followBlocks(ctx, src, 11, emit)
+	})
+	want := []BlockEvent{
+		{Block: src.shards[11][0], MasterchainBlock: src.masterchain[11], Cursor: Cursor{MasterchainSeqno: 11}},
+		{Block: src.shards[11][1], MasterchainBlock: src.masterchain[11], Cursor: Cursor{MasterchainSeqno: 11, BlockIndex: 1}},
+		{Block: src.masterchain[11], MasterchainBlock: src.masterchain[11], Cursor: Cursor{MasterchainSeqno: 11, BlockIndex: 2}},
+		{Block: src.shards[12][0], MasterchainBlock: src.masterchain[12], Cursor: Cursor{MasterchainSeqno: 12}},
+		{Block: src.masterchain[12], MasterchainBlock: src.masterchain[12], Cursor: Cursor{MasterchainSeqno: 12, BlockIndex: 1}},
+	}
+	if !reflect.DeepEqual(events, want) {
+		t.Fatalf("want events: %v, got: %v", want, events)
+	}
+
+	// zero seqno starts from the current head.
+	events = collectBlocks(t, 2, func(ctx context.Context, emit func(BlockEvent) error) error {
+		return followBlocks(ctx, src, 0, emit)
+	})
+	if !reflect.DeepEqual(events, want[3:]) {
+		t.Fatalf("want events: %v, got: %v", want[3:], events)
+	}
+
+	// a resumed subscription starts right after the cursor.
+	events = collectBlocks(t, 3, func(ctx context.Context, emit func(BlockEvent) error) error {
+		return resumeBlocks(ctx, src, want[1].Cursor, emit)
+	})
+	if !reflect.DeepEqual(events, want[2:]) {
+		t.Fatalf("want events: %v, got: %v", want[2:], events)
+	}
+}
+
+func Test_followBlocks_errors(t *testing.T) {
+	// failed waits are retried.
+	src := newFakeBlockSource()
+	src.waitFailures = 2
+	events := collectBlocks(t, 1, func(ctx context.Context, emit func(BlockEvent) error) error {
+		return followBlocks(ctx, src, 12, emit)
+	})
+	if events[0].Block != src.shards[12][0] || src.waits != 4 {
+		t.Fatalf("unexpected event %v after %v waits", events[0], src.waits)
+	}
+
+	// a subscription stops when its context is done while waiting for a new block.
+	ctx, cancel := context.WithCancel(context.Background())
+	err := followBlocks(ctx, newFakeBlockSource(), 12, func(event BlockEvent) error {
+		if event.Block.Workchain == -1 {
+			cancel()
+		}
+		return nil
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("want canceled, got: %v", err)
+	}
+
+	// errors of the shard walk stop a subscription.
+	src = newFakeBlockSource()
+	src.shardsErr = errors.New("shards are not available")
+	if err := followBlocks(context.Background(), src, 12, func(BlockEvent) error { return nil }); !errors.Is(err, src.shardsErr) {
+		t.Fatalf("want shards error, got: %v", err)
+	}
+	src = newFakeBlockSource()
+	delete(src.infos, src.shards[12][0])
+	if err := followBlocks(context.Background(), src, 12, func(BlockEvent) error { return nil }); err == nil || err.Error() != "block not found" {
+		t.Fatalf("want block info error, got: %v", err)
+	}
+}
@@ -11,6 +11,13 @@ import (
 	"io"
 )
 
+const (
+	// minPacketSize is a size of a packet with an empty payload: a nonce and a checksum.
+	minPacketSize = 32 + 32
+	// maxPacketSize is a maximum size of a packet accepted by ParsePacket.
+	maxPacketSize = 1 << 24
+)
+
 type params [160]byte
 
 func newParameters() (params, error) {
@@ -85,6 +92,12 @@ func (p Packet) MagicType() uint32 {
 }
 
 func ParsePacket(r io.Reader, decryptor cipher.Stream) (Packet, error) {
+	return parsePacket(r, decryptor, maxPacketSize)
+}
+
+// parsePacket works the same way as ParsePacket,
+// but rejects packets larger than maxSize before reading them.
+func parsePacket(r io.Reader, decryptor cipher.Stream, maxSize int) (Packet, error) {
 	var p Packet
 	size := make([]byte, 4) //todo: reuse via sync.pool
 	n, err := io.ReadFull(r, size)
@@ -96,6 +109,9 @@ func ParsePacket(r io.Reader, decryptor cipher.Stream) (Packet, error) {
 	}
 	decryptor.XORKeyStream(size, size)
 	length := int(binary.LittleEndian.Uint32(size))
+	if length < minPacketSize || length > maxSize {
+		return p, fmt.Errorf("invalid packet length: %v", length)
+	}
 	data := make([]byte, length)
 	n, err = io.ReadFull(r, data)
 	if err != nil {
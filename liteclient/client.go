@@ -36,6 +36,7 @@ type Client struct {
 	connMutex    sync.Mutex
 	queries      map[queryID]chan []byte
 	queriesMutex sync.Mutex
+	observer     Observer
 }
 
 type Options func(connection *Client)
@@ -63,15 +64,26 @@ func OptionWorkersPerConnection(n int) Options {
 	}
 }
 
+// OptionObserver sets an observer to receive events of the client and its connections.
+func OptionObserver(o Observer) Options {
+	return func(c *Client) {
+		c.observer = o
+	}
+}
+
 func NewClient(c *Connection, opts ...Options) *Client {
 	c2 := &Client{
 		timeout:     defaultTimeout,
 		connections: []*Connection{c},
 		queries:     make(map[queryID]chan []byte),
+		observer:    NopObserver{},
 	}
 	for _, f := range opts {
 		f(c2)
 	}
+	for _, conn := range c2.connections {
+		conn.setObserver(c2.observer)
+	}
 
 	for _, conn := range c2.connections {
 		go c2.reader(conn)
@@ -89,10 +101,24 @@ func (c *Client) IsOK() bool {
 	return false
 }
 
+// Close closes all connections of the client.
+// Requests sent after Close fail.
+func (c *Client) Close() {
+	for _, conn := range c.connections {
+		conn.Close()
+	}
+}
+
 // Request sends q as query in adnl.message.query and receives answer from adnl.message.answer
 // adnl.message.query query_id:int256 query:bytes = adnl.Message
 // adnl.message.answer query_id:int256 answer:bytes = adnl.Message
 func (c *Client) Request(ctx context.Context, q []byte) ([]byte, error) {
+	b, _, err := c.request(ctx, q)
+	return b, err
+}
+
+// request works the same way as Request and additionally returns a host of the lite server the query was sent to.
+func (c *Client) request(ctx context.Context, q []byte) ([]byte, string, error) {
 	ctx, cancel := context.WithTimeout(ctx, c.timeout)
 	defer cancel()
 	var id queryID
@@ -105,7 +131,7 @@ func (c *Client) Request(ctx context.Context, q []byte) ([]byte, error) {
 	data = alignBytes(data)
 	p, err := NewPacket(data)
 	if err != nil {
-		return nil, newClientError("NewPacket() failed: %v", err)
+		return nil, "", newClientError("NewPacket() failed: %v", err)
 	}
 	resp := c.registerCallback(id)
 	defer c.unregisterCallback(id)
@@ -117,13 +143,13 @@ func (c *Client) Request(ctx context.Context, q []byte) ([]byte, error) {
 
 	err = conn.Send(p)
 	if err != nil {
-		return nil, err
+		return nil, conn.host, err
 	}
 	select {
 	case <-ctx.Done():
-		return nil, newClientError("request timeout: %v", ctx.Err())
+		return nil, conn.host, newClientError("request timeout: %v", ctx.Err())
 	case b := <-resp:
-		return b, nil
+		return b, conn.host, nil
 	}
 }
 
@@ -175,7 +201,13 @@ func decodeLength(b []byte) (int, []byte, error) {
 }
 
 func (c *Client) reader(conn *Connection) {
-	for p := range conn.Responses() {
+	for {
+		var p Packet
+		select {
+		case p = <-conn.Responses():
+		case <-conn.closed:
+			return
+		}
 		if p.MagicType() != magicADNLAnswer {
 			continue
 		}
@@ -217,7 +249,14 @@ func (c *Client) liteServerRequest(ctx context.Context, q []byte) ([]byte, error
 	data = append(data, tl.EncodeLength(len(q))...)
 	data = append(data, q...)
 	data = alignBytes(data)
-	return c.Request(ctx, data)
+	start := time.Now()
+	resp, host, err := c.request(ctx, data)
+	observedErr := err
+	if err == nil {
+		observedErr = answerError(resp)
+	}
+	c.observer.RequestDone(host, requestName(q), time.Since(start), observedErr)
+	return resp, err
 }
 
 func alignBytes(data []byte) []byte {
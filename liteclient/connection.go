@@ -25,6 +25,8 @@ type ConnectionStatus int
 const (
 	Connecting ConnectionStatus = iota
 	Connected
+	// Closed means that the connection is closed with Close and won't be reconnected.
+	Closed
 )
 const (
 	reconnectTimeout = 10 * time.Second
@@ -41,6 +43,9 @@ type Connection struct {
 	resp             chan Packet
 	authKey          ed25519.PrivateKey
 	authCompleteChan chan error // Closes when auth is complete or error is sent
+	// closed is closed by Close to stop all goroutines of the connection.
+	closed    chan struct{}
+	closeOnce sync.Once
 
 	// mu protects all fields below.
 	mu           sync.Mutex
@@ -49,6 +54,7 @@ type Connection struct {
 	pings        map[uint64]time.Time
 	avgRoundTrip time.Duration
 	nonce        []byte
+	observer     Observer
 }
 
 func NewConnection(ctx context.Context, peerPublicKey []byte, host string, authKeys ...ed25519.PrivateKey) (*Connection, error) {
@@ -61,6 +67,7 @@ func NewConnection(ctx context.Context, peerPublicKey []byte, host string, authK
 		resp:             make(chan Packet),
 		status:           Connecting,
 		authCompleteChan: make(chan error),
+		closed:           make(chan struct{}),
 	}
 	if len(authKeys) == 1 {
 		c.authKey = authKeys[0]
@@ -78,6 +85,11 @@ func (c *Connection) setupEncryptedConnection(ctx context.Context) error {
 		return err
 	}
 	c.mu.Lock()
+	if c.status == Closed {
+		c.mu.Unlock()
+		econn.close()
+		return errConnectionClosed
+	}
 	c.econn = econn
 	c.pings = make(map[uint64]time.Time, 5)
 
@@ -118,7 +130,7 @@ func (c *Connection) setupEncryptedConnection(ctx context.Context) error {
 
 func (c *Connection) reconnect() {
 	c.mu.Lock()
-	if c.status == Connecting {
+	if c.status == Connecting || c.status == Closed {
 		c.mu.Unlock()
 		return
 	}
@@ -126,14 +138,52 @@ func (c *Connection) reconnect() {
 	c.econn.close()
 	c.mu.Unlock()
 
+	c.getObserver().Disconnected(c.host)
+
 	for {
-		if err := c.setupEncryptedConnection(context.Background()); err != nil {
-			fmt.Printf("error reconnecting to %s: %s\n", c.host, err)
-			time.Sleep(1 * time.Second)
-			continue
+		err := c.setupEncryptedConnection(context.Background())
+		if err == nil {
+			break
+		}
+		if err == errConnectionClosed {
+			return
+		}
+		c.getObserver().ConnectionFailed(c.host, err)
+		select {
+		case <-c.closed:
+			return
+		case <-time.After(1 * time.Second):
 		}
-		break
 	}
+	c.getObserver().Connected(c.host, true)
+}
+
+// Close closes the connection and stops reconnecting to the lite server.
+func (c *Connection) Close() {
+	c.closeOnce.Do(func() {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		close(c.closed)
+		if c.status == Connected {
+			c.econn.close()
+		}
+		c.status = Closed
+	})
+}
+
+func (c *Connection) setObserver(o Observer) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.observer = o
+}
+
+func (c *Connection) getObserver() Observer {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.observer == nil {
+		return NopObserver{}
+	}
+	return c.observer
 }
 
 func averageRoundTrip(roundTrips []time.Duration) time.Duration {
@@ -176,8 +226,14 @@ func (c *Connection) reader(packetCh chan Packet) {
 				continue
 			}
 
-			c.resp <- p
+			select {
+			case c.resp <- p:
+			case <-c.closed:
+				return
+			}
 
+		case <-c.closed:
+			return
 		case <-time.After(reconnectTimeout):
 			c.reconnect()
 			// setupEncryptedConnection will run another reader.
@@ -191,6 +247,9 @@ func (c *Connection) Send(p Packet) error {
 
 	c.mu.Lock()
 	defer c.mu.Unlock()
+	if c.status == Closed {
+		return errConnectionClosed
+	}
 	if c.status != Connected {
 		return newClientError("not connected yet")
 	}
@@ -209,7 +268,11 @@ func (c *Connection) ping() {
 	ping := make([]byte, 12)
 	binary.LittleEndian.PutUint32(ping[:4], magicTCPPing)
 	for {
-		time.Sleep(time.Second * 3)
+		select {
+		case <-c.closed:
+			return
+		case <-time.After(time.Second * 3):
+		}
 		if _, err := rand.Read(ping[4:]); err != nil {
 			panic(err) // impossible if source of randomness is correct
 		}
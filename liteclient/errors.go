@@ -4,6 +4,9 @@ import (
 	"fmt"
 )
 
+// errConnectionClosed is returned when a connection is closed with Close.
+var errConnectionClosed = newClientError("connection closed")
+
 type clientError string
 
 func (e clientError) Error() string {
@@ -67,6 +67,14 @@ func (t *LiteServerSignatureSet) UnmarshalTL(r io.Reader) error {
 	return nil
 }
 
+func (t TonNodeBlockIdC) ToBlockId() ton.BlockID {
+	return ton.BlockID{
+		Workchain: int32(t.Workchain),
+		Shard:     t.Shard,
+		Seqno:     t.Seqno,
+	}
+}
+
 func (t TonNodeBlockIdExtC) ToBlockIdExt() ton.BlockIDExt {
 	res := ton.BlockIDExt{
 		RootHash: ton.Bits256(t.RootHash),
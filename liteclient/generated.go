@@ -2926,6 +2926,299 @@ func (t *LiteServerDebugVerbosityC) UnmarshalTL(r io.Reader) error {
 	return nil
 }
 
+type LiteServerNonfinalCandidateIdC struct {
+	BlockId          TonNodeBlockIdExtC
+	Creator          tl.Int256
+	CollatedDataHash tl.Int256
+}
+
+func (t LiteServerNonfinalCandidateIdC) MarshalTL() ([]byte, error) {
+	var (
+		err error
+		b   []byte
+	)
+	buf := new(bytes.Buffer)
+	b, err = tl.Marshal(t.BlockId)
+	if err != nil {
+		return nil, err
+	}
+	_, err = buf.Write(b)
+	if err != nil {
+		return nil, err
+	}
+	b, err = tl.Marshal(t.Creator)
+	if err != nil {
+		return nil, err
+	}
+	_, err = buf.Write(b)
+	if err != nil {
+		return nil, err
+	}
+	b, err = tl.Marshal(t.CollatedDataHash)
+	if err != nil {
+		return nil, err
+	}
+	_, err = buf.Write(b)
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (t *LiteServerNonfinalCandidateIdC) UnmarshalTL(r io.Reader) error {
+	var err error
+	err = tl.Unmarshal(r, &t.BlockId)
+	if err != nil {
+		return err
+	}
+	err = tl.Unmarshal(r, &t.Creator)
+	if err != nil {
+		return err
+	}
+	err = tl.Unmarshal(r, &t.CollatedDataHash)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+type LiteServerNonfinalCandidateC struct {
+	Id           LiteServerNonfinalCandidateIdC
+	Data         []byte
+	CollatedData []byte
+}
+
+func (t LiteServerNonfinalCandidateC) MarshalTL() ([]byte, error) {
+	var (
+		err error
+		b   []byte
+	)
+	buf := new(bytes.Buffer)
+	b, err = tl.Marshal(t.Id)
+	if err != nil {
+		return nil, err
+	}
+	_, err = buf.Write(b)
+	if err != nil {
+		return nil, err
+	}
+	b, err = tl.Marshal(t.Data)
+	if err != nil {
+		return nil, err
+	}
+	_, err = buf.Write(b)
+	if err != nil {
+		return nil, err
+	}
+	b, err = tl.Marshal(t.CollatedData)
+	if err != nil {
+		return nil, err
+	}
+	_, err = buf.Write(b)
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (t *LiteServerNonfinalCandidateC) UnmarshalTL(r io.Reader) error {
+	var err error
+	err = tl.Unmarshal(r, &t.Id)
+	if err != nil {
+		return err
+	}
+	err = tl.Unmarshal(r, &t.Data)
+	if err != nil {
+		return err
+	}
+	err = tl.Unmarshal(r, &t.CollatedData)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+type LiteServerNonfinalCandidateInfoC struct {
+	Id             LiteServerNonfinalCandidateIdC
+	Available      bool
+	ApprovedWeight uint64
+	SignedWeight   uint64
+	TotalWeight    uint64
+}
+
+func (t LiteServerNonfinalCandidateInfoC) MarshalTL() ([]byte, error) {
+	var (
+		err error
+		b   []byte
+	)
+	buf := new(bytes.Buffer)
+	b, err = tl.Marshal(t.Id)
+	if err != nil {
+		return nil, err
+	}
+	_, err = buf.Write(b)
+	if err != nil {
+		return nil, err
+	}
+	b, err = tl.Marshal(t.Available)
+	if err != nil {
+		return nil, err
+	}
+	_, err = buf.Write(b)
+	if err != nil {
+		return nil, err
+	}
+	b, err = tl.Marshal(t.ApprovedWeight)
+	if err != nil {
+		return nil, err
+	}
+	_, err = buf.Write(b)
+	if err != nil {
+		return nil, err
+	}
+	b, err = tl.Marshal(t.SignedWeight)
+	if err != nil {
+		return nil, err
+	}
+	_, err = buf.Write(b)
+	if err != nil {
+		return nil, err
+	}
+	b, err = tl.Marshal(t.TotalWeight)
+	if err != nil {
+		return nil, err
+	}
+	_, err = buf.Write(b)
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (t *LiteServerNonfinalCandidateInfoC) UnmarshalTL(r io.Reader) error {
+	var err error
+	err = tl.Unmarshal(r, &t.Id)
+	if err != nil {
+		return err
+	}
+	err = tl.Unmarshal(r, &t.Available)
+	if err != nil {
+		return err
+	}
+	err = tl.Unmarshal(r, &t.ApprovedWeight)
+	if err != nil {
+		return err
+	}
+	err = tl.Unmarshal(r, &t.SignedWeight)
+	if err != nil {
+		return err
+	}
+	err = tl.Unmarshal(r, &t.TotalWeight)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+type LiteServerNonfinalValidatorGroupInfoC struct {
+	NextBlockId TonNodeBlockIdC
+	CcSeqno     uint32
+	Prev        []TonNodeBlockIdExtC
+	Candidates  []LiteServerNonfinalCandidateInfoC
+}
+
+func (t LiteServerNonfinalValidatorGroupInfoC) MarshalTL() ([]byte, error) {
+	var (
+		err error
+		b   []byte
+	)
+	buf := new(bytes.Buffer)
+	b, err = tl.Marshal(t.NextBlockId)
+	if err != nil {
+		return nil, err
+	}
+	_, err = buf.Write(b)
+	if err != nil {
+		return nil, err
+	}
+	b, err = tl.Marshal(t.CcSeqno)
+	if err != nil {
+		return nil, err
+	}
+	_, err = buf.Write(b)
+	if err != nil {
+		return nil, err
+	}
+	b, err = tl.Marshal(t.Prev)
+	if err != nil {
+		return nil, err
+	}
+	_, err = buf.Write(b)
+	if err != nil {
+		return nil, err
+	}
+	b, err = tl.Marshal(t.Candidates)
+	if err != nil {
+		return nil, err
+	}
+	_, err = buf.Write(b)
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (t *LiteServerNonfinalValidatorGroupInfoC) UnmarshalTL(r io.Reader) error {
+	var err error
+	err = tl.Unmarshal(r, &t.NextBlockId)
+	if err != nil {
+		return err
+	}
+	err = tl.Unmarshal(r, &t.CcSeqno)
+	if err != nil {
+		return err
+	}
+	err = tl.Unmarshal(r, &t.Prev)
+	if err != nil {
+		return err
+	}
+	err = tl.Unmarshal(r, &t.Candidates)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+type LiteServerNonfinalValidatorGroupsC struct {
+	Groups []LiteServerNonfinalValidatorGroupInfoC
+}
+
+func (t LiteServerNonfinalValidatorGroupsC) MarshalTL() ([]byte, error) {
+	var (
+		err error
+		b   []byte
+	)
+	buf := new(bytes.Buffer)
+	b, err = tl.Marshal(t.Groups)
+	if err != nil {
+		return nil, err
+	}
+	_, err = buf.Write(b)
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (t *LiteServerNonfinalValidatorGroupsC) UnmarshalTL(r io.Reader) error {
+	var err error
+	err = tl.Unmarshal(r, &t.Groups)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
 type LiteServerGetMasterchainInfoRequest struct{}
 
 func (t *LiteServerGetMasterchainInfoRequest) UnmarshalTL(r io.Reader) error {
@@ -4258,26 +4551,6 @@ func (t *LiteServerListBlockTransactionsRequest) UnmarshalTL(r io.Reader) error
 		}
 		t.After = &tempAfter
 	}
-	if (t.Mode>>6)&1 == 1 {
-		var tTrue bool
-		err = tl.Unmarshal(r, &tTrue)
-		if err != nil {
-			return err
-		}
-		if tTrue != true {
-			return fmt.Errorf("not a True type")
-		}
-	}
-	if (t.Mode>>5)&1 == 1 {
-		var tTrue bool
-		err = tl.Unmarshal(r, &tTrue)
-		if err != nil {
-			return err
-		}
-		if tTrue != true {
-			return fmt.Errorf("not a True type")
-		}
-	}
 	return nil
 }
 
@@ -4384,26 +4657,6 @@ func (t *LiteServerListBlockTransactionsExtRequest) UnmarshalTL(r io.Reader) err
 		}
 		t.After = &tempAfter
 	}
-	if (t.Mode>>6)&1 == 1 {
-		var tTrue bool
-		err = tl.Unmarshal(r, &tTrue)
-		if err != nil {
-			return err
-		}
-		if tTrue != true {
-			return fmt.Errorf("not a True type")
-		}
-	}
-	if (t.Mode>>5)&1 == 1 {
-		var tTrue bool
-		err = tl.Unmarshal(r, &tTrue)
-		if err != nil {
-			return err
-		}
-		if tTrue != true {
-			return fmt.Errorf("not a True type")
-		}
-	}
 	return nil
 }
 
@@ -5197,16 +5450,6 @@ func (t *LiteServerGetDispatchQueueInfoRequest) UnmarshalTL(r io.Reader) error {
 	if err != nil {
 		return err
 	}
-	if (t.Mode>>0)&1 == 1 {
-		var tTrue bool
-		err = tl.Unmarshal(r, &tTrue)
-		if err != nil {
-			return err
-		}
-		if tTrue != true {
-			return fmt.Errorf("not a True type")
-		}
-	}
 	return nil
 }
 
@@ -5273,6 +5516,166 @@ func (c *Client) LiteProxyGetRequestRateLimit(ctx context.Context) (res LiteProx
 	return res, fmt.Errorf("invalid tag")
 }
 
+type LiteServerNonfinalGetValidatorGroupsRequest struct {
+	Mode  uint32
+	Wc    *uint32
+	Shard *uint64
+}
+
+func (t LiteServerNonfinalGetValidatorGroupsRequest) MarshalTL() ([]byte, error) {
+	var (
+		err error
+		b   []byte
+	)
+	buf := new(bytes.Buffer)
+	b, err = tl.Marshal(t.Mode)
+	if err != nil {
+		return nil, err
+	}
+	_, err = buf.Write(b)
+	if err != nil {
+		return nil, err
+	}
+	if (t.Mode>>0)&1 == 1 {
+		b, err = tl.Marshal(t.Wc)
+		if err != nil {
+			return nil, err
+		}
+		_, err = buf.Write(b)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if (t.Mode>>1)&1 == 1 {
+		b, err = tl.Marshal(t.Shard)
+		if err != nil {
+			return nil, err
+		}
+		_, err = buf.Write(b)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return buf.Bytes(), nil
+}
+
+func (t *LiteServerNonfinalGetValidatorGroupsRequest) UnmarshalTL(r io.Reader) error {
+	var err error
+	err = tl.Unmarshal(r, &t.Mode)
+	if err != nil {
+		return err
+	}
+	if (t.Mode>>0)&1 == 1 {
+		var tempWc uint32
+		err = tl.Unmarshal(r, &tempWc)
+		if err != nil {
+			return err
+		}
+		t.Wc = &tempWc
+	}
+	if (t.Mode>>1)&1 == 1 {
+		var tempShard uint64
+		err = tl.Unmarshal(r, &tempShard)
+		if err != nil {
+			return err
+		}
+		t.Shard = &tempShard
+	}
+	return nil
+}
+
+func (c *Client) LiteServerNonfinalGetValidatorGroups(ctx context.Context, request LiteServerNonfinalGetValidatorGroupsRequest) (res LiteServerNonfinalValidatorGroupsC, err error) {
+	payload, err := tl.Marshal(struct {
+		tl.SumType
+		Req LiteServerNonfinalGetValidatorGroupsRequest `tlSumType:"8fb12d81"`
+	}{SumType: "Req", Req: request})
+	if err != nil {
+		return res, err
+	}
+	resp, err := c.liteServerRequest(ctx, payload)
+	if err != nil {
+		return res, err
+	}
+	if len(resp) < 4 {
+		return res, fmt.Errorf("not enough bytes for tag")
+	}
+	tag := binary.LittleEndian.Uint32(resp[:4])
+	if tag == 0xbba9e148 {
+		var errRes LiteServerErrorC
+		err = tl.Unmarshal(bytes.NewReader(resp[4:]), &errRes)
+		if err != nil {
+			return res, err
+		}
+		return res, errRes
+	}
+	if tag == 0x8d0b9dfe {
+		err = tl.Unmarshal(bytes.NewReader(resp[4:]), &res)
+		return res, err
+	}
+	return res, fmt.Errorf("invalid tag")
+}
+
+type LiteServerNonfinalGetCandidateRequest struct {
+	Id LiteServerNonfinalCandidateIdC
+}
+
+func (t LiteServerNonfinalGetCandidateRequest) MarshalTL() ([]byte, error) {
+	var (
+		err error
+		b   []byte
+	)
+	buf := new(bytes.Buffer)
+	b, err = tl.Marshal(t.Id)
+	if err != nil {
+		return nil, err
+	}
+	_, err = buf.Write(b)
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (t *LiteServerNonfinalGetCandidateRequest) UnmarshalTL(r io.Reader) error {
+	var err error
+	err = tl.Unmarshal(r, &t.Id)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func (c *Client) LiteServerNonfinalGetCandidate(ctx context.Context, request LiteServerNonfinalGetCandidateRequest) (res LiteServerNonfinalCandidateC, err error) {
+	payload, err := tl.Marshal(struct {
+		tl.SumType
+		Req LiteServerNonfinalGetCandidateRequest `tlSumType:"300794de"`
+	}{SumType: "Req", Req: request})
+	if err != nil {
+		return res, err
+	}
+	resp, err := c.liteServerRequest(ctx, payload)
+	if err != nil {
+		return res, err
+	}
+	if len(resp) < 4 {
+		return res, fmt.Errorf("not enough bytes for tag")
+	}
+	tag := binary.LittleEndian.Uint32(resp[:4])
+	if tag == 0xbba9e148 {
+		var errRes LiteServerErrorC
+		err = tl.Unmarshal(bytes.NewReader(resp[4:]), &errRes)
+		if err != nil {
+			return res, err
+		}
+		return res, errRes
+	}
+	if tag == 0x80c3468c {
+		err = tl.Unmarshal(bytes.NewReader(resp[4:]), &res)
+		return res, err
+	}
+	return res, fmt.Errorf("invalid tag")
+}
+
 var (
 	// 0xf0f83e86
 	decodeFuncLiteProxyGetRequestRateLimitRequest = decodeRequest(0xf0f83e86, LiteProxyGetRequestRateLimitRequestName, LiteProxyGetRequestRateLimitRequest{})
@@ -5328,6 +5731,10 @@ var (
 	decodeFuncLiteServerLookupBlockRequest = decodeRequest(0xfac8f71e, LiteServerLookupBlockRequestName, LiteServerLookupBlockRequest{})
 	// 0x9c045ff8
 	decodeFuncLiteServerLookupBlockWithProofRequest = decodeRequest(0x9c045ff8, LiteServerLookupBlockWithProofRequestName, LiteServerLookupBlockWithProofRequest{})
+	// 0x300794de
+	decodeFuncLiteServerNonfinalGetCandidateRequest = decodeRequest(0x300794de, LiteServerNonfinalGetCandidateRequestName, LiteServerNonfinalGetCandidateRequest{})
+	// 0x8fb12d81
+	decodeFuncLiteServerNonfinalGetValidatorGroupsRequest = decodeRequest(0x8fb12d81, LiteServerNonfinalGetValidatorGroupsRequestName, LiteServerNonfinalGetValidatorGroupsRequest{})
 	// 0x5cc65dd2
 	decodeFuncLiteServerRunSmcMethodRequest = decodeRequest(0x5cc65dd2, LiteServerRunSmcMethodRequestName, LiteServerRunSmcMethodRequest{})
 	// 0x690ad482
@@ -5362,38 +5769,659 @@ var taggedRequestDecodeFunctions = map[uint32]reqDecoderFunc{
 	0xadfcc7da: decodeFuncLiteServerListBlockTransactionsRequest,
 	0xfac8f71e: decodeFuncLiteServerLookupBlockRequest,
 	0x9c045ff8: decodeFuncLiteServerLookupBlockWithProofRequest,
+	0x300794de: decodeFuncLiteServerNonfinalGetCandidateRequest,
+	0x8fb12d81: decodeFuncLiteServerNonfinalGetValidatorGroupsRequest,
 	0x5cc65dd2: decodeFuncLiteServerRunSmcMethodRequest,
 	0x690ad482: decodeFuncLiteServerSendMessageRequest,
 }
 
+var taggedRequestNames = map[uint32]RequestName{
+	0xf0f83e86: LiteProxyGetRequestRateLimitRequestName,
+	0x5a698507: LiteServerGetAccountStatePrunnedRequestName,
+	0x6b890e25: LiteServerGetAccountStateRequestName,
+	0x74d3fd6b: LiteServerGetAllShardsInfoRequestName,
+	0x21ec069e: LiteServerGetBlockHeaderRequestName,
+	0x8aea9c44: LiteServerGetBlockProofRequestName,
+	0x6377cf0d: LiteServerGetBlockRequestName,
+	0x911b26b7: LiteServerGetConfigAllRequestName,
+	0x2a111c19: LiteServerGetConfigParamsRequestName,
+	0x01e66bf3: LiteServerGetDispatchQueueInfoRequestName,
+	0xd122b662: LiteServerGetLibrariesRequestName,
+	0x8c026c31: LiteServerGetLibrariesWithProofRequestName,
+	0x70a671df: LiteServerGetMasterchainInfoExtRequestName,
+	0x89b5e62e: LiteServerGetMasterchainInfoRequestName,
+	0xd40f24ea: LiteServerGetOneTransactionRequestName,
+	0x7bc19c36: LiteServerGetOutMsgQueueSizesRequestName,
+	0x4ca60350: LiteServerGetShardBlockProofRequestName,
+	0x46a2f425: LiteServerGetShardInfoRequestName,
+	0xba6e2eb6: LiteServerGetStateRequestName,
+	0x16ad5a34: LiteServerGetTimeRequestName,
+	0x1c40e7a1: LiteServerGetTransactionsRequestName,
+	0x091a58bc: LiteServerGetValidatorStatsRequestName,
+	0x232b940b: LiteServerGetVersionRequestName,
+	0x0079dd5c: LiteServerListBlockTransactionsExtRequestName,
+	0xadfcc7da: LiteServerListBlockTransactionsRequestName,
+	0xfac8f71e: LiteServerLookupBlockRequestName,
+	0x9c045ff8: LiteServerLookupBlockWithProofRequestName,
+	0x300794de: LiteServerNonfinalGetCandidateRequestName,
+	0x8fb12d81: LiteServerNonfinalGetValidatorGroupsRequestName,
+	0x5cc65dd2: LiteServerRunSmcMethodRequestName,
+	0x690ad482: LiteServerSendMessageRequestName,
+}
+
 const (
-	LiteProxyGetRequestRateLimitRequestName       RequestName = "liteProxy.getRequestRateLimit"
-	LiteServerGetAccountStatePrunnedRequestName   RequestName = "liteServer.getAccountStatePrunned"
-	LiteServerGetAccountStateRequestName          RequestName = "liteServer.getAccountState"
-	LiteServerGetAllShardsInfoRequestName         RequestName = "liteServer.getAllShardsInfo"
-	LiteServerGetBlockHeaderRequestName           RequestName = "liteServer.getBlockHeader"
-	LiteServerGetBlockProofRequestName            RequestName = "liteServer.getBlockProof"
-	LiteServerGetBlockRequestName                 RequestName = "liteServer.getBlock"
-	LiteServerGetConfigAllRequestName             RequestName = "liteServer.getConfigAll"
-	LiteServerGetConfigParamsRequestName          RequestName = "liteServer.getConfigParams"
-	LiteServerGetDispatchQueueInfoRequestName     RequestName = "liteServer.getDispatchQueueInfo"
-	LiteServerGetLibrariesRequestName             RequestName = "liteServer.getLibraries"
-	LiteServerGetLibrariesWithProofRequestName    RequestName = "liteServer.getLibrariesWithProof"
-	LiteServerGetMasterchainInfoExtRequestName    RequestName = "liteServer.getMasterchainInfoExt"
-	LiteServerGetMasterchainInfoRequestName       RequestName = "liteServer.getMasterchainInfo"
-	LiteServerGetOneTransactionRequestName        RequestName = "liteServer.getOneTransaction"
-	LiteServerGetOutMsgQueueSizesRequestName      RequestName = "liteServer.getOutMsgQueueSizes"
-	LiteServerGetShardBlockProofRequestName       RequestName = "liteServer.getShardBlockProof"
-	LiteServerGetShardInfoRequestName             RequestName = "liteServer.getShardInfo"
-	LiteServerGetStateRequestName                 RequestName = "liteServer.getState"
-	LiteServerGetTimeRequestName                  RequestName = "liteServer.getTime"
-	LiteServerGetTransactionsRequestName          RequestName = "liteServer.getTransactions"
-	LiteServerGetValidatorStatsRequestName        RequestName = "liteServer.getValidatorStats"
-	LiteServerGetVersionRequestName               RequestName = "liteServer.getVersion"
-	LiteServerListBlockTransactionsExtRequestName RequestName = "liteServer.listBlockTransactionsExt"
-	LiteServerListBlockTransactionsRequestName    RequestName = "liteServer.listBlockTransactions"
-	LiteServerLookupBlockRequestName              RequestName = "liteServer.lookupBlock"
-	LiteServerLookupBlockWithProofRequestName     RequestName = "liteServer.lookupBlockWithProof"
-	LiteServerRunSmcMethodRequestName             RequestName = "liteServer.runSmcMethod"
-	LiteServerSendMessageRequestName              RequestName = "liteServer.sendMessage"
+	LiteProxyGetRequestRateLimitRequestName         RequestName = "liteProxy.getRequestRateLimit"
+	LiteServerGetAccountStatePrunnedRequestName     RequestName = "liteServer.getAccountStatePrunned"
+	LiteServerGetAccountStateRequestName            RequestName = "liteServer.getAccountState"
+	LiteServerGetAllShardsInfoRequestName           RequestName = "liteServer.getAllShardsInfo"
+	LiteServerGetBlockHeaderRequestName             RequestName = "liteServer.getBlockHeader"
+	LiteServerGetBlockProofRequestName              RequestName = "liteServer.getBlockProof"
+	LiteServerGetBlockRequestName                   RequestName = "liteServer.getBlock"
+	LiteServerGetConfigAllRequestName               RequestName = "liteServer.getConfigAll"
+	LiteServerGetConfigParamsRequestName            RequestName = "liteServer.getConfigParams"
+	LiteServerGetDispatchQueueInfoRequestName       RequestName = "liteServer.getDispatchQueueInfo"
+	LiteServerGetLibrariesRequestName               RequestName = "liteServer.getLibraries"
+	LiteServerGetLibrariesWithProofRequestName      RequestName = "liteServer.getLibrariesWithProof"
+	LiteServerGetMasterchainInfoExtRequestName      RequestName = "liteServer.getMasterchainInfoExt"
+	LiteServerGetMasterchainInfoRequestName         RequestName = "liteServer.getMasterchainInfo"
+	LiteServerGetOneTransactionRequestName          RequestName = "liteServer.getOneTransaction"
+	LiteServerGetOutMsgQueueSizesRequestName        RequestName = "liteServer.getOutMsgQueueSizes"
+	LiteServerGetShardBlockProofRequestName         RequestName = "liteServer.getShardBlockProof"
+	LiteServerGetShardInfoRequestName               RequestName = "liteServer.getShardInfo"
+	LiteServerGetStateRequestName                   RequestName = "liteServer.getState"
+	LiteServerGetTimeRequestName                    RequestName = "liteServer.getTime"
+	LiteServerGetTransactionsRequestName            RequestName = "liteServer.getTransactions"
+	LiteServerGetValidatorStatsRequestName          RequestName = "liteServer.getValidatorStats"
+	LiteServerGetVersionRequestName                 RequestName = "liteServer.getVersion"
+	LiteServerListBlockTransactionsExtRequestName   RequestName = "liteServer.listBlockTransactionsExt"
+	LiteServerListBlockTransactionsRequestName      RequestName = "liteServer.listBlockTransactions"
+	LiteServerLookupBlockRequestName                RequestName = "liteServer.lookupBlock"
+	LiteServerLookupBlockWithProofRequestName       RequestName = "liteServer.lookupBlockWithProof"
+	LiteServerNonfinalGetCandidateRequestName       RequestName = "liteServer.nonfinal.getCandidate"
+	LiteServerNonfinalGetValidatorGroupsRequestName RequestName = "liteServer.nonfinal.getValidatorGroups"
+	LiteServerRunSmcMethodRequestName               RequestName = "liteServer.runSmcMethod"
+	LiteServerSendMessageRequestName                RequestName = "liteServer.sendMessage"
 )
+
+type LiteServerHandler interface {
+	LiteServerGetMasterchainInfo(ctx context.Context) (res LiteServerMasterchainInfoC, err error)
+	LiteServerGetMasterchainInfoExt(ctx context.Context, request LiteServerGetMasterchainInfoExtRequest) (res LiteServerMasterchainInfoExtC, err error)
+	LiteServerGetTime(ctx context.Context) (res LiteServerCurrentTimeC, err error)
+	LiteServerGetVersion(ctx context.Context) (res LiteServerVersionC, err error)
+	LiteServerGetBlock(ctx context.Context, request LiteServerGetBlockRequest) (res LiteServerBlockDataC, err error)
+	LiteServerGetState(ctx context.Context, request LiteServerGetStateRequest) (res LiteServerBlockStateC, err error)
+	LiteServerGetBlockHeader(ctx context.Context, request LiteServerGetBlockHeaderRequest) (res LiteServerBlockHeaderC, err error)
+	LiteServerSendMessage(ctx context.Context, request LiteServerSendMessageRequest) (res LiteServerSendMsgStatusC, err error)
+	LiteServerGetAccountState(ctx context.Context, request LiteServerGetAccountStateRequest) (res LiteServerAccountStateC, err error)
+	LiteServerGetAccountStatePrunned(ctx context.Context, request LiteServerGetAccountStatePrunnedRequest) (res LiteServerAccountStateC, err error)
+	LiteServerRunSmcMethod(ctx context.Context, request LiteServerRunSmcMethodRequest) (res LiteServerRunMethodResultC, err error)
+	LiteServerGetShardInfo(ctx context.Context, request LiteServerGetShardInfoRequest) (res LiteServerShardInfoC, err error)
+	LiteServerGetAllShardsInfo(ctx context.Context, request LiteServerGetAllShardsInfoRequest) (res LiteServerAllShardsInfoC, err error)
+	LiteServerGetOneTransaction(ctx context.Context, request LiteServerGetOneTransactionRequest) (res LiteServerTransactionInfoC, err error)
+	LiteServerGetTransactions(ctx context.Context, request LiteServerGetTransactionsRequest) (res LiteServerTransactionListC, err error)
+	LiteServerLookupBlock(ctx context.Context, request LiteServerLookupBlockRequest) (res LiteServerBlockHeaderC, err error)
+	LiteServerLookupBlockWithProof(ctx context.Context, request LiteServerLookupBlockWithProofRequest) (res LiteServerLookupBlockResultC, err error)
+	LiteServerListBlockTransactions(ctx context.Context, request LiteServerListBlockTransactionsRequest) (res LiteServerBlockTransactionsC, err error)
+	LiteServerListBlockTransactionsExt(ctx context.Context, request LiteServerListBlockTransactionsExtRequest) (res LiteServerBlockTransactionsExtC, err error)
+	LiteServerGetBlockProof(ctx context.Context, request LiteServerGetBlockProofRequest) (res LiteServerPartialBlockProofC, err error)
+	LiteServerGetConfigAll(ctx context.Context, request LiteServerGetConfigAllRequest) (res LiteServerConfigInfoC, err error)
+	LiteServerGetConfigParams(ctx context.Context, request LiteServerGetConfigParamsRequest) (res LiteServerConfigInfoC, err error)
+	LiteServerGetValidatorStats(ctx context.Context, request LiteServerGetValidatorStatsRequest) (res LiteServerValidatorStatsC, err error)
+	LiteServerGetLibraries(ctx context.Context, request LiteServerGetLibrariesRequest) (res LiteServerLibraryResultC, err error)
+	LiteServerGetLibrariesWithProof(ctx context.Context, request LiteServerGetLibrariesWithProofRequest) (res LiteServerLibraryResultWithProofC, err error)
+	LiteServerGetShardBlockProof(ctx context.Context, request LiteServerGetShardBlockProofRequest) (res LiteServerShardBlockProofC, err error)
+	LiteServerGetOutMsgQueueSizes(ctx context.Context, request LiteServerGetOutMsgQueueSizesRequest) (res LiteServerOutMsgQueueSizesC, err error)
+	LiteServerGetDispatchQueueInfo(ctx context.Context, request LiteServerGetDispatchQueueInfoRequest) (res LiteServerDispatchQueueInfoC, err error)
+	LiteProxyGetRequestRateLimit(ctx context.Context) (res LiteProxyRequestRateLimitC, err error)
+	LiteServerNonfinalGetValidatorGroups(ctx context.Context, request LiteServerNonfinalGetValidatorGroupsRequest) (res LiteServerNonfinalValidatorGroupsC, err error)
+	LiteServerNonfinalGetCandidate(ctx context.Context, request LiteServerNonfinalGetCandidateRequest) (res LiteServerNonfinalCandidateC, err error)
+}
+
+type UnimplementedLiteServerHandler struct{}
+
+func (UnimplementedLiteServerHandler) LiteServerGetMasterchainInfo(ctx context.Context) (res LiteServerMasterchainInfoC, err error) {
+	return res, LiteServerErrorC{Code: 621, Message: "liteServer.getMasterchainInfo is not implemented"}
+}
+
+func (UnimplementedLiteServerHandler) LiteServerGetMasterchainInfoExt(ctx context.Context, request LiteServerGetMasterchainInfoExtRequest) (res LiteServerMasterchainInfoExtC, err error) {
+	return res, LiteServerErrorC{Code: 621, Message: "liteServer.getMasterchainInfoExt is not implemented"}
+}
+
+func (UnimplementedLiteServerHandler) LiteServerGetTime(ctx context.Context) (res LiteServerCurrentTimeC, err error) {
+	return res, LiteServerErrorC{Code: 621, Message: "liteServer.getTime is not implemented"}
+}
+
+func (UnimplementedLiteServerHandler) LiteServerGetVersion(ctx context.Context) (res LiteServerVersionC, err error) {
+	return res, LiteServerErrorC{Code: 621, Message: "liteServer.getVersion is not implemented"}
+}
+
+func (UnimplementedLiteServerHandler) LiteServerGetBlock(ctx context.Context, request LiteServerGetBlockRequest) (res LiteServerBlockDataC, err error) {
+	return res, LiteServerErrorC{Code: 621, Message: "liteServer.getBlock is not implemented"}
+}
+
+func (UnimplementedLiteServerHandler) LiteServerGetState(ctx context.Context, request LiteServerGetStateRequest) (res LiteServerBlockStateC, err error) {
+	return res, LiteServerErrorC{Code: 621, Message: "liteServer.getState is not implemented"}
+}
+
+func (UnimplementedLiteServerHandler) LiteServerGetBlockHeader(ctx context.Context, request LiteServerGetBlockHeaderRequest) (res LiteServerBlockHeaderC, err error) {
+	return res, LiteServerErrorC{Code: 621, Message: "liteServer.getBlockHeader is not implemented"}
+}
+
+func (UnimplementedLiteServerHandler) LiteServerSendMessage(ctx context.Context, request LiteServerSendMessageRequest) (res LiteServerSendMsgStatusC, err error) {
+	return res, LiteServerErrorC{Code: 621, Message: "liteServer.sendMessage is not implemented"}
+}
+
+func (UnimplementedLiteServerHandler) LiteServerGetAccountState(ctx context.Context, request LiteServerGetAccountStateRequest) (res LiteServerAccountStateC, err error) {
+	return res, LiteServerErrorC{Code: 621, Message: "liteServer.getAccountState is not implemented"}
+}
+
+func (UnimplementedLiteServerHandler) LiteServerGetAccountStatePrunned(ctx context.Context, request LiteServerGetAccountStatePrunnedRequest) (res LiteServerAccountStateC, err error) {
+	return res, LiteServerErrorC{Code: 621, Message: "liteServer.getAccountStatePrunned is not implemented"}
+}
+
+func (UnimplementedLiteServerHandler) LiteServerRunSmcMethod(ctx context.Context, request LiteServerRunSmcMethodRequest) (res LiteServerRunMethodResultC, err error) {
+	return res, LiteServerErrorC{Code: 621, Message: "liteServer.runSmcMethod is not implemented"}
+}
+
+func (UnimplementedLiteServerHandler) LiteServerGetShardInfo(ctx context.Context, request LiteServerGetShardInfoRequest) (res LiteServerShardInfoC, err error) {
+	return res, LiteServerErrorC{Code: 621, Message: "liteServer.getShardInfo is not implemented"}
+}
+
+func (UnimplementedLiteServerHandler) LiteServerGetAllShardsInfo(ctx context.Context, request LiteServerGetAllShardsInfoRequest) (res LiteServerAllShardsInfoC, err error) {
+	return res, LiteServerErrorC{Code: 621, Message: "liteServer.getAllShardsInfo is not implemented"}
+}
+
+func (UnimplementedLiteServerHandler) LiteServerGetOneTransaction(ctx context.Context, request LiteServerGetOneTransactionRequest) (res LiteServerTransactionInfoC, err error) {
+	return res, LiteServerErrorC{Code: 621, Message: "liteServer.getOneTransaction is not implemented"}
+}
+
+func (UnimplementedLiteServerHandler) LiteServerGetTransactions(ctx context.Context, request LiteServerGetTransactionsRequest) (res LiteServerTransactionListC, err error) {
+	return res, LiteServerErrorC{Code: 621, Message: "liteServer.getTransactions is not implemented"}
+}
+
+func (UnimplementedLiteServerHandler) LiteServerLookupBlock(ctx context.Context, request LiteServerLookupBlockRequest) (res LiteServerBlockHeaderC, err error) {
+	return res, LiteServerErrorC{Code: 621, Message: "liteServer.lookupBlock is not implemented"}
+}
+
+func (UnimplementedLiteServerHandler) LiteServerLookupBlockWithProof(ctx context.Context, request LiteServerLookupBlockWithProofRequest) (res LiteServerLookupBlockResultC, err error) {
+	return res, LiteServerErrorC{Code: 621, Message: "liteServer.lookupBlockWithProof is not implemented"}
+}
+
+func (UnimplementedLiteServerHandler) LiteServerListBlockTransactions(ctx context.Context, request LiteServerListBlockTransactionsRequest) (res LiteServerBlockTransactionsC, err error) {
+	return res, LiteServerErrorC{Code: 621, Message: "liteServer.listBlockTransactions is not implemented"}
+}
+
+func (UnimplementedLiteServerHandler) LiteServerListBlockTransactionsExt(ctx context.Context, request LiteServerListBlockTransactionsExtRequest) (res LiteServerBlockTransactionsExtC, err error) {
+	return res, LiteServerErrorC{Code: 621, Message: "liteServer.listBlockTransactionsExt is not implemented"}
+}
+
+func (UnimplementedLiteServerHandler) LiteServerGetBlockProof(ctx context.Context, request LiteServerGetBlockProofRequest) (res LiteServerPartialBlockProofC, err error) {
+	return res, LiteServerErrorC{Code: 621, Message: "liteServer.getBlockProof is not implemented"}
+}
+
+func (UnimplementedLiteServerHandler) LiteServerGetConfigAll(ctx context.Context, request LiteServerGetConfigAllRequest) (res LiteServerConfigInfoC, err error) {
+	return res, LiteServerErrorC{Code: 621, Message: "liteServer.getConfigAll is not implemented"}
+}
+
+func (UnimplementedLiteServerHandler) LiteServerGetConfigParams(ctx context.Context, request LiteServerGetConfigParamsRequest) (res LiteServerConfigInfoC, err error) {
+	return res, LiteServerErrorC{Code: 621, Message: "liteServer.getConfigParams is not implemented"}
+}
+
+func (UnimplementedLiteServerHandler) LiteServerGetValidatorStats(ctx context.Context, request LiteServerGetValidatorStatsRequest) (res LiteServerValidatorStatsC, err error) {
+	return res, LiteServerErrorC{Code: 621, Message: "liteServer.getValidatorStats is not implemented"}
+}
+
+func (UnimplementedLiteServerHandler) LiteServerGetLibraries(ctx context.Context, request LiteServerGetLibrariesRequest) (res LiteServerLibraryResultC, err error) {
+	return res, LiteServerErrorC{Code: 621, Message: "liteServer.getLibraries is not implemented"}
+}
+
+func (UnimplementedLiteServerHandler) LiteServerGetLibrariesWithProof(ctx context.Context, request LiteServerGetLibrariesWithProofRequest) (res LiteServerLibraryResultWithProofC, err error) {
+	return res, LiteServerErrorC{Code: 621, Message: "liteServer.getLibrariesWithProof is not implemented"}
+}
+
+func (UnimplementedLiteServerHandler) LiteServerGetShardBlockProof(ctx context.Context, request LiteServerGetShardBlockProofRequest) (res LiteServerShardBlockProofC, err error) {
+	return res, LiteServerErrorC{Code: 621, Message: "liteServer.getShardBlockProof is not implemented"}
+}
+
+func (UnimplementedLiteServerHandler) LiteServerGetOutMsgQueueSizes(ctx context.Context, request LiteServerGetOutMsgQueueSizesRequest) (res LiteServerOutMsgQueueSizesC, err error) {
+	return res, LiteServerErrorC{Code: 621, Message: "liteServer.getOutMsgQueueSizes is not implemented"}
+}
+
+func (UnimplementedLiteServerHandler) LiteServerGetDispatchQueueInfo(ctx context.Context, request LiteServerGetDispatchQueueInfoRequest) (res LiteServerDispatchQueueInfoC, err error) {
+	return res, LiteServerErrorC{Code: 621, Message: "liteServer.getDispatchQueueInfo is not implemented"}
+}
+
+func (UnimplementedLiteServerHandler) LiteProxyGetRequestRateLimit(ctx context.Context) (res LiteProxyRequestRateLimitC, err error) {
+	return res, LiteServerErrorC{Code: 621, Message: "liteProxy.getRequestRateLimit is not implemented"}
+}
+
+func (UnimplementedLiteServerHandler) LiteServerNonfinalGetValidatorGroups(ctx context.Context, request LiteServerNonfinalGetValidatorGroupsRequest) (res LiteServerNonfinalValidatorGroupsC, err error) {
+	return res, LiteServerErrorC{Code: 621, Message: "liteServer.nonfinal.getValidatorGroups is not implemented"}
+}
+
+func (UnimplementedLiteServerHandler) LiteServerNonfinalGetCandidate(ctx context.Context, request LiteServerNonfinalGetCandidateRequest) (res LiteServerNonfinalCandidateC, err error) {
+	return res, LiteServerErrorC{Code: 621, Message: "liteServer.nonfinal.getCandidate is not implemented"}
+}
+
+func dispatchLiteServerHandler(ctx context.Context, h LiteServerHandler, payload []byte) ([]byte, error) {
+	if len(payload) < 4 {
+		return nil, fmt.Errorf("not enough bytes for tag")
+	}
+	switch binary.LittleEndian.Uint32(payload[:4]) {
+	case 0x89b5e62e:
+		res, err := h.LiteServerGetMasterchainInfo(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return tl.Marshal(struct {
+			tl.SumType
+			Res LiteServerMasterchainInfoC `tlSumType:"85832881"`
+		}{SumType: "Res", Res: res})
+	case 0x70a671df:
+		var request LiteServerGetMasterchainInfoExtRequest
+		err := tl.Unmarshal(bytes.NewReader(payload[4:]), &request)
+		if err != nil {
+			return nil, err
+		}
+		res, err := h.LiteServerGetMasterchainInfoExt(ctx, request)
+		if err != nil {
+			return nil, err
+		}
+		return tl.Marshal(struct {
+			tl.SumType
+			Res LiteServerMasterchainInfoExtC `tlSumType:"a8cce0f5"`
+		}{SumType: "Res", Res: res})
+	case 0x16ad5a34:
+		res, err := h.LiteServerGetTime(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return tl.Marshal(struct {
+			tl.SumType
+			Res LiteServerCurrentTimeC `tlSumType:"e953000d"`
+		}{SumType: "Res", Res: res})
+	case 0x232b940b:
+		res, err := h.LiteServerGetVersion(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return tl.Marshal(struct {
+			tl.SumType
+			Res LiteServerVersionC `tlSumType:"5a0491e5"`
+		}{SumType: "Res", Res: res})
+	case 0x6377cf0d:
+		var request LiteServerGetBlockRequest
+		err := tl.Unmarshal(bytes.NewReader(payload[4:]), &request)
+		if err != nil {
+			return nil, err
+		}
+		res, err := h.LiteServerGetBlock(ctx, request)
+		if err != nil {
+			return nil, err
+		}
+		return tl.Marshal(struct {
+			tl.SumType
+			Res LiteServerBlockDataC `tlSumType:"a574ed6c"`
+		}{SumType: "Res", Res: res})
+	case 0xba6e2eb6:
+		var request LiteServerGetStateRequest
+		err := tl.Unmarshal(bytes.NewReader(payload[4:]), &request)
+		if err != nil {
+			return nil, err
+		}
+		res, err := h.LiteServerGetState(ctx, request)
+		if err != nil {
+			return nil, err
+		}
+		return tl.Marshal(struct {
+			tl.SumType
+			Res LiteServerBlockStateC `tlSumType:"abaddc0c"`
+		}{SumType: "Res", Res: res})
+	case 0x21ec069e:
+		var request LiteServerGetBlockHeaderRequest
+		err := tl.Unmarshal(bytes.NewReader(payload[4:]), &request)
+		if err != nil {
+			return nil, err
+		}
+		res, err := h.LiteServerGetBlockHeader(ctx, request)
+		if err != nil {
+			return nil, err
+		}
+		return tl.Marshal(struct {
+			tl.SumType
+			Res LiteServerBlockHeaderC `tlSumType:"752d8219"`
+		}{SumType: "Res", Res: res})
+	case 0x690ad482:
+		var request LiteServerSendMessageRequest
+		err := tl.Unmarshal(bytes.NewReader(payload[4:]), &request)
+		if err != nil {
+			return nil, err
+		}
+		res, err := h.LiteServerSendMessage(ctx, request)
+		if err != nil {
+			return nil, err
+		}
+		return tl.Marshal(struct {
+			tl.SumType
+			Res LiteServerSendMsgStatusC `tlSumType:"3950e597"`
+		}{SumType: "Res", Res: res})
+	case 0x6b890e25:
+		var request LiteServerGetAccountStateRequest
+		err := tl.Unmarshal(bytes.NewReader(payload[4:]), &request)
+		if err != nil {
+			return nil, err
+		}
+		res, err := h.LiteServerGetAccountState(ctx, request)
+		if err != nil {
+			return nil, err
+		}
+		return tl.Marshal(struct {
+			tl.SumType
+			Res LiteServerAccountStateC `tlSumType:"7079c751"`
+		}{SumType: "Res", Res: res})
+	case 0x5a698507:
+		var request LiteServerGetAccountStatePrunnedRequest
+		err := tl.Unmarshal(bytes.NewReader(payload[4:]), &request)
+		if err != nil {
+			return nil, err
+		}
+		res, err := h.LiteServerGetAccountStatePrunned(ctx, request)
+		if err != nil {
+			return nil, err
+		}
+		return tl.Marshal(struct {
+			tl.SumType
+			Res LiteServerAccountStateC `tlSumType:"7079c751"`
+		}{SumType: "Res", Res: res})
+	case 0x5cc65dd2:
+		var request LiteServerRunSmcMethodRequest
+		err := tl.Unmarshal(bytes.NewReader(payload[4:]), &request)
+		if err != nil {
+			return nil, err
+		}
+		res, err := h.LiteServerRunSmcMethod(ctx, request)
+		if err != nil {
+			return nil, err
+		}
+		return tl.Marshal(struct {
+			tl.SumType
+			Res LiteServerRunMethodResultC `tlSumType:"a39a616b"`
+		}{SumType: "Res", Res: res})
+	case 0x46a2f425:
+		var request LiteServerGetShardInfoRequest
+		err := tl.Unmarshal(bytes.NewReader(payload[4:]), &request)
+		if err != nil {
+			return nil, err
+		}
+		res, err := h.LiteServerGetShardInfo(ctx, request)
+		if err != nil {
+			return nil, err
+		}
+		return tl.Marshal(struct {
+			tl.SumType
+			Res LiteServerShardInfoC `tlSumType:"9fe6cd84"`
+		}{SumType: "Res", Res: res})
+	case 0x74d3fd6b:
+		var request LiteServerGetAllShardsInfoRequest
+		err := tl.Unmarshal(bytes.NewReader(payload[4:]), &request)
+		if err != nil {
+			return nil, err
+		}
+		res, err := h.LiteServerGetAllShardsInfo(ctx, request)
+		if err != nil {
+			return nil, err
+		}
+		return tl.Marshal(struct {
+			tl.SumType
+			Res LiteServerAllShardsInfoC `tlSumType:"098fe72d"`
+		}{SumType: "Res", Res: res})
+	case 0xd40f24ea:
+		var request LiteServerGetOneTransactionRequest
+		err := tl.Unmarshal(bytes.NewReader(payload[4:]), &request)
+		if err != nil {
+			return nil, err
+		}
+		res, err := h.LiteServerGetOneTransaction(ctx, request)
+		if err != nil {
+			return nil, err
+		}
+		return tl.Marshal(struct {
+			tl.SumType
+			Res LiteServerTransactionInfoC `tlSumType:"0edeed47"`
+		}{SumType: "Res", Res: res})
+	case 0x1c40e7a1:
+		var request LiteServerGetTransactionsRequest
+		err := tl.Unmarshal(bytes.NewReader(payload[4:]), &request)
+		if err != nil {
+			return nil, err
+		}
+		res, err := h.LiteServerGetTransactions(ctx, request)
+		if err != nil {
+			return nil, err
+		}
+		return tl.Marshal(struct {
+			tl.SumType
+			Res LiteServerTransactionListC `tlSumType:"6f26c60b"`
+		}{SumType: "Res", Res: res})
+	case 0xfac8f71e:
+		var request LiteServerLookupBlockRequest
+		err := tl.Unmarshal(bytes.NewReader(payload[4:]), &request)
+		if err != nil {
+			return nil, err
+		}
+		res, err := h.LiteServerLookupBlock(ctx, request)
+		if err != nil {
+			return nil, err
+		}
+		return tl.Marshal(struct {
+			tl.SumType
+			Res LiteServerBlockHeaderC `tlSumType:"752d8219"`
+		}{SumType: "Res", Res: res})
+	case 0x9c045ff8:
+		var request LiteServerLookupBlockWithProofRequest
+		err := tl.Unmarshal(bytes.NewReader(payload[4:]), &request)
+		if err != nil {
+			return nil, err
+		}
+		res, err := h.LiteServerLookupBlockWithProof(ctx, request)
+		if err != nil {
+			return nil, err
+		}
+		return tl.Marshal(struct {
+			tl.SumType
+			Res LiteServerLookupBlockResultC `tlSumType:"57c7ccc5"`
+		}{SumType: "Res", Res: res})
+	case 0xadfcc7da:
+		var request LiteServerListBlockTransactionsRequest
+		err := tl.Unmarshal(bytes.NewReader(payload[4:]), &request)
+		if err != nil {
+			return nil, err
+		}
+		res, err := h.LiteServerListBlockTransactions(ctx, request)
+		if err != nil {
+			return nil, err
+		}
+		return tl.Marshal(struct {
+			tl.SumType
+			Res LiteServerBlockTransactionsC `tlSumType:"bd8cad2b"`
+		}{SumType: "Res", Res: res})
+	case 0x79dd5c:
+		var request LiteServerListBlockTransactionsExtRequest
+		err := tl.Unmarshal(bytes.NewReader(payload[4:]), &request)
+		if err != nil {
+			return nil, err
+		}
+		res, err := h.LiteServerListBlockTransactionsExt(ctx, request)
+		if err != nil {
+			return nil, err
+		}
+		return tl.Marshal(struct {
+			tl.SumType
+			Res LiteServerBlockTransactionsExtC `tlSumType:"fb8ffce4"`
+		}{SumType: "Res", Res: res})
+	case 0x8aea9c44:
+		var request LiteServerGetBlockProofRequest
+		err := tl.Unmarshal(bytes.NewReader(payload[4:]), &request)
+		if err != nil {
+			return nil, err
+		}
+		res, err := h.LiteServerGetBlockProof(ctx, request)
+		if err != nil {
+			return nil, err
+		}
+		return tl.Marshal(struct {
+			tl.SumType
+			Res LiteServerPartialBlockProofC `tlSumType:"8ed0d2c1"`
+		}{SumType: "Res", Res: res})
+	case 0x911b26b7:
+		var request LiteServerGetConfigAllRequest
+		err := tl.Unmarshal(bytes.NewReader(payload[4:]), &request)
+		if err != nil {
+			return nil, err
+		}
+		res, err := h.LiteServerGetConfigAll(ctx, request)
+		if err != nil {
+			return nil, err
+		}
+		return tl.Marshal(struct {
+			tl.SumType
+			Res LiteServerConfigInfoC `tlSumType:"ae7b272f"`
+		}{SumType: "Res", Res: res})
+	case 0x2a111c19:
+		var request LiteServerGetConfigParamsRequest
+		err := tl.Unmarshal(bytes.NewReader(payload[4:]), &request)
+		if err != nil {
+			return nil, err
+		}
+		res, err := h.LiteServerGetConfigParams(ctx, request)
+		if err != nil {
+			return nil, err
+		}
+		return tl.Marshal(struct {
+			tl.SumType
+			Res LiteServerConfigInfoC `tlSumType:"ae7b272f"`
+		}{SumType: "Res", Res: res})
+	case 0x91a58bc:
+		var request LiteServerGetValidatorStatsRequest
+		err := tl.Unmarshal(bytes.NewReader(payload[4:]), &request)
+		if err != nil {
+			return nil, err
+		}
+		res, err := h.LiteServerGetValidatorStats(ctx, request)
+		if err != nil {
+			return nil, err
+		}
+		return tl.Marshal(struct {
+			tl.SumType
+			Res LiteServerValidatorStatsC `tlSumType:"b9f796d8"`
+		}{SumType: "Res", Res: res})
+	case 0xd122b662:
+		var request LiteServerGetLibrariesRequest
+		err := tl.Unmarshal(bytes.NewReader(payload[4:]), &request)
+		if err != nil {
+			return nil, err
+		}
+		res, err := h.LiteServerGetLibraries(ctx, request)
+		if err != nil {
+			return nil, err
+		}
+		return tl.Marshal(struct {
+			tl.SumType
+			Res LiteServerLibraryResultC `tlSumType:"117ab96b"`
+		}{SumType: "Res", Res: res})
+	case 0x8c026c31:
+		var request LiteServerGetLibrariesWithProofRequest
+		err := tl.Unmarshal(bytes.NewReader(payload[4:]), &request)
+		if err != nil {
+			return nil, err
+		}
+		res, err := h.LiteServerGetLibrariesWithProof(ctx, request)
+		if err != nil {
+			return nil, err
+		}
+		return tl.Marshal(struct {
+			tl.SumType
+			Res LiteServerLibraryResultWithProofC `tlSumType:"99370a1f"`
+		}{SumType: "Res", Res: res})
+	case 0x4ca60350:
+		var request LiteServerGetShardBlockProofRequest
+		err := tl.Unmarshal(bytes.NewReader(payload[4:]), &request)
+		if err != nil {
+			return nil, err
+		}
+		res, err := h.LiteServerGetShardBlockProof(ctx, request)
+		if err != nil {
+			return nil, err
+		}
+		return tl.Marshal(struct {
+			tl.SumType
+			Res LiteServerShardBlockProofC `tlSumType:"1d62a07a"`
+		}{SumType: "Res", Res: res})
+	case 0x7bc19c36:
+		var request LiteServerGetOutMsgQueueSizesRequest
+		err := tl.Unmarshal(bytes.NewReader(payload[4:]), &request)
+		if err != nil {
+			return nil, err
+		}
+		res, err := h.LiteServerGetOutMsgQueueSizes(ctx, request)
+		if err != nil {
+			return nil, err
+		}
+		return tl.Marshal(struct {
+			tl.SumType
+			Res LiteServerOutMsgQueueSizesC `tlSumType:"f8504a03"`
+		}{SumType: "Res", Res: res})
+	case 0x1e66bf3:
+		var request LiteServerGetDispatchQueueInfoRequest
+		err := tl.Unmarshal(bytes.NewReader(payload[4:]), &request)
+		if err != nil {
+			return nil, err
+		}
+		res, err := h.LiteServerGetDispatchQueueInfo(ctx, request)
+		if err != nil {
+			return nil, err
+		}
+		return tl.Marshal(struct {
+			tl.SumType
+			Res LiteServerDispatchQueueInfoC `tlSumType:"5d1132d0"`
+		}{SumType: "Res", Res: res})
+	case 0xf0f83e86:
+		res, err := h.LiteProxyGetRequestRateLimit(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return tl.Marshal(struct {
+			tl.SumType
+			Res LiteProxyRequestRateLimitC `tlSumType:"14cb3f0c"`
+		}{SumType: "Res", Res: res})
+	case 0x8fb12d81:
+		var request LiteServerNonfinalGetValidatorGroupsRequest
+		err := tl.Unmarshal(bytes.NewReader(payload[4:]), &request)
+		if err != nil {
+			return nil, err
+		}
+		res, err := h.LiteServerNonfinalGetValidatorGroups(ctx, request)
+		if err != nil {
+			return nil, err
+		}
+		return tl.Marshal(struct {
+			tl.SumType
+			Res LiteServerNonfinalValidatorGroupsC `tlSumType:"8d0b9dfe"`
+		}{SumType: "Res", Res: res})
+	case 0x300794de:
+		var request LiteServerNonfinalGetCandidateRequest
+		err := tl.Unmarshal(bytes.NewReader(payload[4:]), &request)
+		if err != nil {
+			return nil, err
+		}
+		res, err := h.LiteServerNonfinalGetCandidate(ctx, request)
+		if err != nil {
+			return nil, err
+		}
+		return tl.Marshal(struct {
+			tl.SumType
+			Res LiteServerNonfinalCandidateC `tlSumType:"80c3468c"`
+		}{SumType: "Res", Res: res})
+	}
+	return nil, LiteServerErrorC{Code: 621, Message: "unknown query"}
+}
@@ -29,13 +29,18 @@ func main() {
 	if err != nil {
 		panic(err)
 	}
+	handler, err := g.LoadServerHandler(parsed.Functions, "LiteServerHandler")
+	if err != nil {
+		panic(err)
+	}
 
 	f, err := os.Create("generated.go")
 	if err != nil {
 		panic(err)
 	}
 	_, err = fmt.Fprint(f, `package liteclient
-// Code autogenerated. DO NOT EDIT. 
+
+// Code autogenerated. DO NOT EDIT.
 
 import (
 	"bytes"
@@ -57,4 +62,8 @@ import (
 	if err != nil {
 		panic(err)
 	}
+	_, err = f.WriteString(handler)
+	if err != nil {
+		panic(err)
+	}
 }
@@ -20,14 +20,16 @@ func newKeys(peerPublicKey ed25519.PublicKey) (x25519Keys, error) {
 		return x25519Keys{}, err
 	}
 
-	shared, err := sharedKey(privateKey, peerPublicKey)
+	shared, err := SharedKey(privateKey, peerPublicKey)
 	if err != nil {
 		return x25519Keys{}, err
 	}
 	return x25519Keys{shared: shared, public: public}, nil
 }
 
-func sharedKey(ourKey ed25519.PrivateKey, serverKey ed25519.PublicKey) ([]byte, error) {
+// SharedKey computes an x25519 shared secret of two ed25519 keys,
+// ADNL uses it to derive keys encrypting traffic between two peers.
+func SharedKey(ourKey ed25519.PrivateKey, serverKey ed25519.PublicKey) ([]byte, error) {
 	comp, err := curve.NewCompressedEdwardsYFromBytes(serverKey)
 	if err != nil {
 		return nil, err
@@ -0,0 +1,120 @@
+package liteclient
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/tonkeeper/tongo/tl"
+)
+
+const waitMasterchainSeqnoRequestName RequestName = "liteServer.waitMasterchainSeqno"
+
+// Observer receives events of a client and its connections to lite servers.
+// It can be used to wire a client to a logger or metrics.
+// Methods are called synchronously, so they must not block.
+// Embed NopObserver to handle only some of the events.
+type Observer interface {
+	// Connected is called when a connection to a lite server is established.
+	// reconnect is true if the connection has been lost before.
+	Connected(host string, reconnect bool)
+	// ConnectionFailed is called when a connection to a lite server can't be established,
+	// including failures of a handshake and authentication.
+	ConnectionFailed(host string, err error)
+	// Disconnected is called when a connection to a lite server is lost and a client starts reconnecting.
+	Disconnected(host string)
+	// RequestDone is called when a request to a lite server is answered or fails.
+	// An error answered by a lite server is passed as LiteServerErrorC, so its code is available.
+	RequestDone(host string, method RequestName, duration time.Duration, err error)
+}
+
+// NopObserver ignores all events.
+type NopObserver struct{}
+
+var _ Observer = NopObserver{}
+
+func (NopObserver) Connected(host string, reconnect bool) {}
+
+func (NopObserver) ConnectionFailed(host string, err error) {}
+
+func (NopObserver) Disconnected(host string) {}
+
+func (NopObserver) RequestDone(host string, method RequestName, duration time.Duration, err error) {}
+
+// SlogObserver writes events to a structured logger.
+// Requests are logged at the debug level, connection problems and failed requests at the warning level.
+type SlogObserver struct {
+	Logger *slog.Logger
+}
+
+var _ Observer = SlogObserver{}
+
+// NewSlogObserver returns an observer writing events to the given logger.
+// A nil logger means slog.Default().
+func NewSlogObserver(logger *slog.Logger) SlogObserver {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return SlogObserver{Logger: logger}
+}
+
+func (o SlogObserver) Connected(host string, reconnect bool) {
+	o.Logger.Info("liteclient connected", "host", host, "reconnect", reconnect)
+}
+
+func (o SlogObserver) ConnectionFailed(host string, err error) {
+	o.Logger.Warn("liteclient connection failed", "host", host, "err", err)
+}
+
+func (o SlogObserver) Disconnected(host string) {
+	o.Logger.Warn("liteclient disconnected", "host", host)
+}
+
+func (o SlogObserver) RequestDone(host string, method RequestName, duration time.Duration, err error) {
+	if err == nil {
+		o.Logger.Debug("liteclient request", "host", host, "method", method, "duration", duration)
+		return
+	}
+	var liteServerErr LiteServerErrorC
+	if errors.As(err, &liteServerErr) {
+		o.Logger.Debug("liteclient request", "host", host, "method", method, "duration", duration, "code", int32(liteServerErr.Code), "err", err)
+		return
+	}
+	o.Logger.Warn("liteclient request failed", "host", host, "method", method, "duration", duration, "err", err)
+}
+
+// requestName returns a TL name of a serialized request.
+func requestName(q []byte) RequestName {
+	if len(q) < 4 {
+		return UnknownRequest
+	}
+	tag := binary.LittleEndian.Uint32(q[:4])
+	if tag == magicLiteServerWaitMasterchainSeqno {
+		if len(q) > 12 {
+			return requestName(q[12:])
+		}
+		return waitMasterchainSeqnoRequestName
+	}
+	if name, ok := taggedRequestNames[tag]; ok {
+		return name
+	}
+	return UnknownRequest
+}
+
+// answerError returns an error answered by a lite server.
+// liteServer.waitMasterchainSeqno is answered with a zero code on success, so it isn't considered as an error.
+func answerError(resp []byte) error {
+	if len(resp) < 4 || binary.LittleEndian.Uint32(resp[:4]) != 0xbba9e148 {
+		return nil
+	}
+	var errRes LiteServerErrorC
+	if err := tl.Unmarshal(bytes.NewReader(resp[4:]), &errRes); err != nil {
+		return err
+	}
+	if errRes.Code == 0 {
+		return nil
+	}
+	return errRes
+}
@@ -0,0 +1,70 @@
+package liteclient
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+type requestEvent struct {
+	host   string
+	method RequestName
+	code   uint32
+	failed bool
+}
+
+type recordingObserver struct {
+	NopObserver
+
+	mu       sync.Mutex
+	requests []requestEvent
+}
+
+func (o *recordingObserver) RequestDone(host string, method RequestName, duration time.Duration, err error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	event := requestEvent{host: host, method: method, failed: err != nil}
+	var liteServerErr LiteServerErrorC
+	if errors.As(err, &liteServerErr) {
+		event.code = liteServerErr.Code
+	}
+	o.requests = append(o.requests, event)
+}
+
+func TestClient_observer(t *testing.T) {
+	public, host := startTestServer(t, &testHandler{})
+	conn, err := NewConnection(context.Background(), public, host)
+	if err != nil {
+		t.Fatalf("NewConnection() failed: %v", err)
+	}
+	observer := &recordingObserver{}
+	client := NewClient(conn, OptionObserver(observer))
+
+	ctx := context.Background()
+	if _, err := client.LiteServerGetMasterchainInfo(ctx); err != nil {
+		t.Fatalf("LiteServerGetMasterchainInfo() failed: %v", err)
+	}
+	if _, err := client.LiteServerGetTime(ctx); err == nil {
+		t.Fatalf("want unimplemented error")
+	}
+	if err := client.WaitMasterchainSeqno(ctx, 100, 1000); err != nil {
+		t.Fatalf("WaitMasterchainSeqno() failed: %v", err)
+	}
+	if _, err := client.WaitMasterchainBlock(ctx, 101, 1000); err == nil {
+		t.Fatalf("want timeout error")
+	}
+	want := []requestEvent{
+		{host: host, method: LiteServerGetMasterchainInfoRequestName},
+		{host: host, method: LiteServerGetTimeRequestName, code: 621, failed: true},
+		{host: host, method: waitMasterchainSeqnoRequestName},
+		{host: host, method: LiteServerLookupBlockRequestName, code: 652, failed: true},
+	}
+	observer.mu.Lock()
+	defer observer.mu.Unlock()
+	if !reflect.DeepEqual(observer.requests, want) {
+		t.Fatalf("want events: %v, got: %v", want, observer.requests)
+	}
+}
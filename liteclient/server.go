@@ -0,0 +1,493 @@
+package liteclient
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/tonkeeper/tongo/tl"
+)
+
+const (
+	handshakeTimeout = 10 * time.Second
+	// defaultMaxConnQueries is a default number of queries processed concurrently on a single connection.
+	defaultMaxConnQueries = 64
+	// maxQueryPacketSize is a maximum size of a packet accepted from a client.
+	// Queries are small, so a larger packet is rejected before it is read.
+	maxQueryPacketSize = 4 << 20
+)
+
+// errInternal is returned to a client if a handler panics while answering a query.
+var errInternal = LiteServerErrorC{Code: 602, Message: "internal error"}
+
+// errTooManyQueries is returned to a client which sends more concurrent queries than allowed per connection.
+var errTooManyQueries = LiteServerErrorC{Code: 651, Message: "too many concurrent queries"}
+
+// Client answers lite server queries itself, so it can be used as a handler of a Server.
+var _ LiteServerHandler = (*Client)(nil)
+
+// MasterchainSeqnoWaiter can be implemented by a LiteServerHandler
+// to support queries prefixed with liteServer.waitMasterchainSeqno.
+// If a handler doesn't implement it, the prefix is ignored.
+type MasterchainSeqnoWaiter interface {
+	WaitMasterchainSeqno(ctx context.Context, seqno uint32, timeout uint32) error
+}
+
+// Server is a lite server answering liteServer.* queries over ADNL TCP with the given handler.
+type Server struct {
+	key     ed25519.PrivateKey
+	address []byte
+	handler LiteServerHandler
+	// maxConnQueries is a maximum number of queries processed concurrently on a single connection.
+	maxConnQueries int
+
+	// mu protects all fields below.
+	mu        sync.Mutex
+	closed    bool
+	listeners map[net.Listener]struct{}
+	conns     map[*serverConn]struct{}
+}
+
+// ServerOption configures a Server.
+type ServerOption func(s *Server)
+
+// OptionMaxConnQueries sets a maximum number of queries processed concurrently on a single connection.
+// Queries beyond the limit are answered with an error right away.
+func OptionMaxConnQueries(n int) ServerOption {
+	return func(s *Server) {
+		if n < 1 {
+			n = 1
+		}
+		s.maxConnQueries = n
+	}
+}
+
+// NewServer returns a lite server identified by the given private key.
+// Clients connect to it using the corresponding public key.
+func NewServer(key ed25519.PrivateKey, handler LiteServerHandler, opts ...ServerOption) (*Server, error) {
+	if len(key) != ed25519.PrivateKeySize {
+		return nil, fmt.Errorf("invalid private key length: %v", len(key))
+	}
+	if handler == nil {
+		return nil, fmt.Errorf("handler is required")
+	}
+	a, err := NewAddress(key.Public().(ed25519.PublicKey))
+	if err != nil {
+		return nil, err
+	}
+	s := &Server{
+		key:            key,
+		address:        a.hash(),
+		handler:        handler,
+		maxConnQueries: defaultMaxConnQueries,
+		listeners:      map[net.Listener]struct{}{},
+		conns:          map[*serverConn]struct{}{},
+	}
+	for _, o := range opts {
+		o(s)
+	}
+	return s, nil
+}
+
+// ListenAndServe listens on the TCP network address and serves incoming connections.
+func (s *Server) ListenAndServe(address string) error {
+	l, err := net.Listen("tcp", address)
+	if err != nil {
+		return err
+	}
+	return s.Serve(l)
+}
+
+// Serve accepts incoming connections on the listener until the server is closed.
+// It always closes the listener and returns nil after Close has been called.
+func (s *Server) Serve(l net.Listener) error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		l.Close()
+		return nil
+	}
+	s.listeners[l] = struct{}{}
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		delete(s.listeners, l)
+		s.mu.Unlock()
+		l.Close()
+	}()
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			if s.isClosed() {
+				return nil
+			}
+			return err
+		}
+		go s.serveConn(conn)
+	}
+}
+
+// Close stops all listeners and closes all connections.
+func (s *Server) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closed = true
+	for l := range s.listeners {
+		l.Close()
+	}
+	for c := range s.conns {
+		c.close()
+	}
+	return nil
+}
+
+func (s *Server) isClosed() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.closed
+}
+
+func (s *Server) serveConn(conn net.Conn) {
+	defer func() {
+		if r := recover(); r != nil {
+			slog.Error("liteclient.Server connection panic", "remote", conn.RemoteAddr().String(), "panic", r)
+			conn.Close()
+		}
+	}()
+	econn, err := s.handshake(conn)
+	if err != nil {
+		conn.Close()
+		return
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	c := &serverConn{
+		server:  s,
+		econn:   econn,
+		cancel:  cancel,
+		queries: make(chan struct{}, s.maxConnQueries),
+	}
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		c.close()
+		return
+	}
+	s.conns[c] = struct{}{}
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		delete(s.conns, c)
+		s.mu.Unlock()
+		c.close()
+	}()
+	if err := c.serve(ctx); err != nil && !errors.Is(err, io.EOF) && !s.isClosed() {
+		slog.Info("liteclient.Server connection error", "remote", conn.RemoteAddr().String(), "err", err)
+	}
+}
+
+// handshake reads a handshake packet encrypted with the server's key
+// and sets up AES-CTR streams with the parameters chosen by a client.
+func (s *Server) handshake(conn net.Conn) (*encryptedConn, error) {
+	if err := conn.SetReadDeadline(time.Now().Add(handshakeTimeout)); err != nil {
+		return nil, err
+	}
+	req := make([]byte, 256)
+	if _, err := io.ReadFull(conn, req); err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(req[:32], s.address) {
+		return nil, fmt.Errorf("unknown destination address %x", req[:32])
+	}
+	shared, err := SharedKey(s.key, req[32:64])
+	if err != nil {
+		return nil, err
+	}
+	paramsHash := req[64:96]
+	key := append([]byte{}, shared[:16]...)
+	key = append(key, paramsHash[16:32]...)
+	nonce := append([]byte{}, paramsHash[0:4]...)
+	nonce = append(nonce, shared[20:32]...)
+	cipherKey, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	var param params
+	cipher.NewCTR(cipherKey, nonce).XORKeyStream(param[:], req[96:])
+	if !bytes.Equal(param.hash(), paramsHash) {
+		return nil, fmt.Errorf("invalid handshake parameters hash")
+	}
+	// a client encrypts with tx parameters and decrypts with rx ones.
+	ci, err := aes.NewCipher(param.rxKey())
+	if err != nil {
+		return nil, err
+	}
+	dci, err := aes.NewCipher(param.txKey())
+	if err != nil {
+		return nil, err
+	}
+	econn := &encryptedConn{
+		cipher:   cipher.NewCTR(ci, param.rxNonce()),
+		decipher: cipher.NewCTR(dci, param.txNonce()),
+		conn:     conn,
+	}
+	if err := conn.SetReadDeadline(time.Time{}); err != nil {
+		return nil, err
+	}
+	// an empty packet confirms the handshake.
+	p, err := NewPacket(nil)
+	if err != nil {
+		return nil, err
+	}
+	if err := econn.send(p.marshal()); err != nil {
+		return nil, err
+	}
+	return econn, nil
+}
+
+type serverConn struct {
+	server *Server
+	cancel context.CancelFunc
+	// queries limits the number of queries processed concurrently.
+	queries chan struct{}
+
+	// mu protects all fields below.
+	mu          sync.Mutex
+	econn       *encryptedConn
+	clientNonce []byte
+	serverNonce []byte
+	authKey     ed25519.PublicKey
+}
+
+func (c *serverConn) serve(ctx context.Context) error {
+	reader := bufio.NewReader(c.econn.conn)
+	for {
+		p, err := parsePacket(reader, c.econn.decipher, maxQueryPacketSize)
+		if err != nil {
+			return err
+		}
+		switch p.MagicType() {
+		case magicTCPPing:
+			if len(p.Payload) != 12 {
+				return fmt.Errorf("invalid ping size: %v", len(p.Payload))
+			}
+			pong := make([]byte, 12)
+			binary.LittleEndian.PutUint32(pong[:4], magicTCPPong)
+			copy(pong[4:], p.Payload[4:])
+			if err := c.send(pong); err != nil {
+				return err
+			}
+		case magicTcpAuthentificate:
+			if err := c.handleAuthRequest(p); err != nil {
+				return err
+			}
+		case magicTcpAuthentificationComplete, magicPubKey:
+			if err := c.handleAuthComplete(p); err != nil {
+				return err
+			}
+		case magicADNLQuery:
+			if len(p.Payload) < 37 {
+				return fmt.Errorf("too short payload")
+			}
+			var id queryID
+			copy(id[:], p.Payload[4:36])
+			length, data, err := decodeLength(p.Payload[36:])
+			if err != nil {
+				return err
+			}
+			if len(data) < length {
+				return fmt.Errorf("payload is smaller than should be according to length")
+			}
+			select {
+			case c.queries <- struct{}{}:
+				go func(query []byte) {
+					defer func() { <-c.queries }()
+					c.processQuery(ctx, id, query)
+				}(data[:length])
+			default:
+				c.sendError(id, errTooManyQueries)
+			}
+		}
+	}
+}
+
+func (c *serverConn) processQuery(ctx context.Context, id queryID, query []byte) {
+	defer func() {
+		if r := recover(); r != nil {
+			slog.Error("liteclient.Server query panic", "panic", r)
+			c.sendError(id, errInternal)
+		}
+	}()
+	c.mu.Lock()
+	authKey := c.authKey
+	c.mu.Unlock()
+	if authKey != nil {
+		ctx = context.WithValue(ctx, authKeyContextKey{}, authKey)
+	}
+	answer, err := c.server.answer(ctx, query)
+	if err != nil {
+		c.sendError(id, err)
+		return
+	}
+	c.sendAnswer(id, answer)
+}
+
+// sendError sends the given error as an answer to the query.
+func (c *serverConn) sendError(id queryID, err error) {
+	answer, err := marshalLiteServerError(err)
+	if err != nil {
+		slog.Info("liteclient.Server failed to marshal error", "err", err)
+		return
+	}
+	c.sendAnswer(id, answer)
+}
+
+// sendAnswer sends adnl.message.answer with the given answer to the query.
+func (c *serverConn) sendAnswer(id queryID, answer []byte) {
+	data := make([]byte, 4, 44+len(answer))
+	binary.LittleEndian.PutUint32(data, magicADNLAnswer)
+	data = append(data, id[:]...)
+	data = append(data, encodeLength(len(answer))...)
+	data = append(data, answer...)
+	if err := c.send(alignBytes(data)); err != nil {
+		c.close()
+	}
+}
+
+// answer processes liteServer.query data:bytes = Object,
+// data can be prefixed with liteServer.waitMasterchainSeqno seqno:int timeout_ms:int.
+func (s *Server) answer(ctx context.Context, query []byte) ([]byte, error) {
+	if len(query) < 4 || binary.LittleEndian.Uint32(query[:4]) != magicLiteServerQuery {
+		return nil, LiteServerErrorC{Code: 621, Message: "unknown query"}
+	}
+	length, data, err := decodeLength(query[4:])
+	if err != nil {
+		return nil, LiteServerErrorC{Code: 621, Message: err.Error()}
+	}
+	if len(data) < length {
+		return nil, LiteServerErrorC{Code: 621, Message: "query is smaller than should be according to length"}
+	}
+	data = data[:length]
+	if len(data) >= 12 && binary.LittleEndian.Uint32(data[:4]) == magicLiteServerWaitMasterchainSeqno {
+		if waiter, ok := s.handler.(MasterchainSeqnoWaiter); ok {
+			seqno := binary.LittleEndian.Uint32(data[4:8])
+			timeout := binary.LittleEndian.Uint32(data[8:12])
+			if err := waiter.WaitMasterchainSeqno(ctx, seqno, timeout); err != nil {
+				return nil, err
+			}
+		}
+		data = data[12:]
+		if len(data) == 0 {
+			// a bare prefix only waits for the block,
+			// Client.WaitMasterchainSeqno treats an error with code 0 as success.
+			return nil, LiteServerErrorC{Code: 0}
+		}
+	}
+	return dispatchLiteServerHandler(ctx, s.handler, data)
+}
+
+func marshalLiteServerError(err error) ([]byte, error) {
+	var liteServerErr LiteServerErrorC
+	if !errors.As(err, &liteServerErr) {
+		liteServerErr = LiteServerErrorC{Code: 601, Message: err.Error()}
+	}
+	return tl.Marshal(struct {
+		tl.SumType
+		Err LiteServerErrorC `tlSumType:"bba9e148"`
+	}{SumType: "Err", Err: liteServerErr})
+}
+
+func (c *serverConn) handleAuthRequest(p Packet) error {
+	length, data, err := decodeLength(p.Payload[4:])
+	if err != nil {
+		return err
+	}
+	if len(data) < length {
+		return fmt.Errorf("payload is smaller than should be according to length")
+	}
+	if length != ClientNonceSize {
+		return fmt.Errorf("invalid client nonce size: %v", length)
+	}
+	nonce := make([]byte, ClientNonceSize)
+	if _, err := rand.Read(nonce); err != nil {
+		return fmt.Errorf("error generating nonce: %w", err)
+	}
+	c.mu.Lock()
+	c.clientNonce = append([]byte{}, data[:length]...)
+	c.serverNonce = nonce
+	c.mu.Unlock()
+
+	payload := make([]byte, 4)
+	binary.LittleEndian.PutUint32(payload, magicTcpAuthentificationNonce)
+	payload = append(payload, tl.EncodeLength(len(nonce))...)
+	payload = append(payload, nonce...)
+	return c.send(alignBytes(payload))
+}
+
+// handleAuthComplete checks tcp.authentificationComplete key:PublicKey signature:bytes.
+// The tag may be omitted, this is how Connection sends it.
+func (c *serverConn) handleAuthComplete(p Packet) error {
+	payload := p.Payload
+	if p.MagicType() == magicTcpAuthentificationComplete {
+		payload = payload[4:]
+	}
+	if len(payload) < 37 || binary.LittleEndian.Uint32(payload[:4]) != magicPubKey {
+		return fmt.Errorf("invalid auth complete message")
+	}
+	key := ed25519.PublicKey(append([]byte{}, payload[4:36]...))
+	length, data, err := decodeLength(payload[36:])
+	if err != nil {
+		return err
+	}
+	if len(data) < length {
+		return fmt.Errorf("payload is smaller than should be according to length")
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.serverNonce == nil {
+		return fmt.Errorf("received unexpected auth complete message")
+	}
+	if !ed25519.Verify(key, append(append([]byte{}, c.clientNonce...), c.serverNonce...), data[:length]) {
+		return fmt.Errorf("invalid auth signature")
+	}
+	c.authKey = key
+	c.serverNonce = nil
+	return nil
+}
+
+// send encrypts and sends a packet with the given payload.
+// It is safe to call it from several goroutines.
+func (c *serverConn) send(payload []byte) error {
+	p, err := NewPacket(payload)
+	if err != nil {
+		return err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.econn.send(p.marshal())
+}
+
+func (c *serverConn) close() {
+	c.cancel()
+	c.econn.conn.Close()
+}
+
+type authKeyContextKey struct{}
+
+// AuthKeyFromContext returns a public key a client has authenticated with on a Server.
+// A handler can use it to distinguish clients.
+func AuthKeyFromContext(ctx context.Context) (ed25519.PublicKey, bool) {
+	key, ok := ctx.Value(authKeyContextKey{}).(ed25519.PublicKey)
+	return key, ok
+}
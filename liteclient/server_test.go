@@ -0,0 +1,301 @@
+package liteclient
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+type testHandler struct {
+	UnimplementedLiteServerHandler
+
+	mu       sync.Mutex
+	authKeys []ed25519.PublicKey
+	waited   []uint32
+}
+
+func (h *testHandler) LiteServerGetMasterchainInfo(ctx context.Context) (LiteServerMasterchainInfoC, error) {
+	if key, ok := AuthKeyFromContext(ctx); ok {
+		h.mu.Lock()
+		h.authKeys = append(h.authKeys, key)
+		h.mu.Unlock()
+	}
+	return LiteServerMasterchainInfoC{
+		Last: TonNodeBlockIdExtC{Workchain: 0xffffffff, Shard: 0x8000000000000000, Seqno: 100},
+	}, nil
+}
+
+func (h *testHandler) LiteServerGetAccountState(ctx context.Context, request LiteServerGetAccountStateRequest) (LiteServerAccountStateC, error) {
+	if request.Account.Workchain != 0 {
+		return LiteServerAccountStateC{}, errors.New("unsupported workchain")
+	}
+	return LiteServerAccountStateC{Id: request.Id, State: request.Account.Id[:]}, nil
+}
+
+func (h *testHandler) WaitMasterchainSeqno(ctx context.Context, seqno uint32, timeout uint32) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.waited = append(h.waited, seqno)
+	if seqno > 100 {
+		return LiteServerErrorC{Code: 652, Message: "timeout"}
+	}
+	return nil
+}
+
+func startTestServer(t *testing.T, handler LiteServerHandler, opts ...ServerOption) (ed25519.PublicKey, string) {
+	public, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey() failed: %v", err)
+	}
+	server, err := NewServer(private, handler, opts...)
+	if err != nil {
+		t.Fatalf("NewServer() failed: %v", err)
+	}
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen() failed: %v", err)
+	}
+	go server.Serve(l)
+	t.Cleanup(func() { server.Close() })
+	return public, l.Addr().String()
+}
+
+func TestServer(t *testing.T) {
+	handler := &testHandler{}
+	public, host := startTestServer(t, handler)
+	conn, err := NewConnection(context.Background(), public, host)
+	if err != nil {
+		t.Fatalf("NewConnection() failed: %v", err)
+	}
+	client := NewClient(conn)
+
+	info, err := client.LiteServerGetMasterchainInfo(context.Background())
+	if err != nil {
+		t.Fatalf("LiteServerGetMasterchainInfo() failed: %v", err)
+	}
+	if info.Last.Seqno != 100 {
+		t.Fatalf("want seqno 100, got: %v", info.Last.Seqno)
+	}
+
+	request := LiteServerGetAccountStateRequest{Account: LiteServerAccountIdC{Id: [32]byte{1, 2, 3}}}
+	request.Id.Seqno = 7
+	state, err := client.LiteServerGetAccountState(context.Background(), request)
+	if err != nil {
+		t.Fatalf("LiteServerGetAccountState() failed: %v", err)
+	}
+	if state.Id.Seqno != 7 || !reflect.DeepEqual(state.State, request.Account.Id[:]) {
+		t.Fatalf("unexpected account state: %v", state)
+	}
+
+	request.Account.Workchain = 1
+	_, err = client.LiteServerGetAccountState(context.Background(), request)
+	var liteServerErr LiteServerErrorC
+	if !errors.As(err, &liteServerErr) || liteServerErr.Message != "unsupported workchain" {
+		t.Fatalf("want handler error, got: %v", err)
+	}
+
+	_, err = client.LiteServerGetTime(context.Background())
+	if !errors.As(err, &liteServerErr) || liteServerErr.Code != 621 {
+		t.Fatalf("want not implemented error, got: %v", err)
+	}
+
+	if err := client.WaitMasterchainSeqno(context.Background(), 100, 1000); err != nil {
+		t.Fatalf("WaitMasterchainSeqno() failed: %v", err)
+	}
+	if err := client.WaitMasterchainSeqno(context.Background(), 101, 1000); !errors.As(err, &liteServerErr) || liteServerErr.Code != 652 {
+		t.Fatalf("want timeout error, got: %v", err)
+	}
+	header, err := client.WaitMasterchainBlock(context.Background(), 100, 1000)
+	if !errors.As(err, &liteServerErr) || liteServerErr.Code != 621 {
+		t.Fatalf("want not implemented error, got: %v, %v", header, err)
+	}
+	if want := []uint32{100, 101, 100}; !reflect.DeepEqual(handler.waited, want) {
+		t.Fatalf("want waited seqnos: %v, got: %v", want, handler.waited)
+	}
+}
+
+func TestClient_Close(t *testing.T) {
+	public, host := startTestServer(t, &testHandler{})
+	conn, err := NewConnection(context.Background(), public, host)
+	if err != nil {
+		t.Fatalf("NewConnection() failed: %v", err)
+	}
+	client := NewClient(conn)
+	if _, err := client.LiteServerGetMasterchainInfo(context.Background()); err != nil {
+		t.Fatalf("LiteServerGetMasterchainInfo() failed: %v", err)
+	}
+	client.Close()
+	client.Close()
+	if client.IsOK() || conn.Status() != Closed {
+		t.Fatalf("want closed connection")
+	}
+	_, err = client.LiteServerGetMasterchainInfo(context.Background())
+	if !errors.Is(err, errConnectionClosed) {
+		t.Fatalf("want connection closed error, got: %v", err)
+	}
+}
+
+func TestServer_authentication(t *testing.T) {
+	handler := &testHandler{}
+	public, host := startTestServer(t, handler)
+	clientPublic, clientPrivate, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey() failed: %v", err)
+	}
+	conn, err := NewConnection(context.Background(), public, host, clientPrivate)
+	if err != nil {
+		t.Fatalf("NewConnection() failed: %v", err)
+	}
+	client := NewClient(conn)
+	if _, err := client.LiteServerGetMasterchainInfo(context.Background()); err != nil {
+		t.Fatalf("LiteServerGetMasterchainInfo() failed: %v", err)
+	}
+	if want := []ed25519.PublicKey{clientPublic}; !reflect.DeepEqual(handler.authKeys, want) {
+		t.Fatalf("want auth keys: %v, got: %v", want, handler.authKeys)
+	}
+}
+
+func TestServer_wrongKey(t *testing.T) {
+	_, host := startTestServer(t, &testHandler{})
+	otherPublic, _, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey() failed: %v", err)
+	}
+	if _, err := newEncryptedConnection(context.Background(), otherPublic, host); err == nil {
+		t.Fatalf("want handshake error")
+	}
+}
+
+func TestServer_bareWaitQuery(t *testing.T) {
+	handler := &testHandler{}
+	public, host := startTestServer(t, handler)
+	conn, err := NewConnection(context.Background(), public, host)
+	if err != nil {
+		t.Fatalf("NewConnection() failed: %v", err)
+	}
+	client := NewClient(conn)
+	data := make([]byte, 0, 12)
+	data = binary.LittleEndian.AppendUint32(data, magicLiteServerWaitMasterchainSeqno)
+	data = binary.LittleEndian.AppendUint32(data, 100)
+	data = binary.LittleEndian.AppendUint32(data, 1000)
+	resp, err := client.liteServerRequest(context.Background(), data)
+	if err != nil {
+		t.Fatalf("liteServerRequest() failed: %v", err)
+	}
+	if len(resp) < 4 {
+		t.Fatalf("want an answer, got: %x", resp)
+	}
+	if err := answerError(resp); err != nil {
+		t.Fatalf("want successful wait, got: %v", err)
+	}
+	if want := []uint32{100}; !reflect.DeepEqual(handler.waited, want) {
+		t.Fatalf("want waited seqnos: %v, got: %v", want, handler.waited)
+	}
+}
+
+type blockingHandler struct {
+	testHandler
+	started chan struct{}
+	release chan struct{}
+}
+
+func (h *blockingHandler) LiteServerGetTime(ctx context.Context) (LiteServerCurrentTimeC, error) {
+	h.started <- struct{}{}
+	<-h.release
+	return LiteServerCurrentTimeC{Now: 1}, nil
+}
+
+func TestServer_maxConnQueries(t *testing.T) {
+	handler := &blockingHandler{started: make(chan struct{}), release: make(chan struct{})}
+	public, host := startTestServer(t, handler, OptionMaxConnQueries(1))
+	conn, err := NewConnection(context.Background(), public, host)
+	if err != nil {
+		t.Fatalf("NewConnection() failed: %v", err)
+	}
+	client := NewClient(conn)
+
+	errs := make(chan error, 1)
+	go func() {
+		_, err := client.LiteServerGetTime(context.Background())
+		errs <- err
+	}()
+	<-handler.started
+
+	_, err = client.LiteServerGetMasterchainInfo(context.Background())
+	var liteServerErr LiteServerErrorC
+	if !errors.As(err, &liteServerErr) || liteServerErr.Code != errTooManyQueries.Code {
+		t.Fatalf("want too many queries error, got: %v", err)
+	}
+
+	close(handler.release)
+	if err := <-errs; err != nil {
+		t.Fatalf("LiteServerGetTime() failed: %v", err)
+	}
+	if _, err := client.LiteServerGetMasterchainInfo(context.Background()); err != nil {
+		t.Fatalf("LiteServerGetMasterchainInfo() failed: %v", err)
+	}
+}
+
+type panickingHandler struct {
+	UnimplementedLiteServerHandler
+}
+
+func (h *panickingHandler) LiteServerGetTime(ctx context.Context) (LiteServerCurrentTimeC, error) {
+	panic("unexpected query")
+}
+
+func TestServer_invalidPacketLength(t *testing.T) {
+	public, host := startTestServer(t, &testHandler{})
+	for name, length := range map[string]uint32{
+		"short":     10,
+		"oversized": 0xfffffff0,
+	} {
+		t.Run(name, func(t *testing.T) {
+			econn, err := newEncryptedConnection(context.Background(), public, host)
+			if err != nil {
+				t.Fatalf("newEncryptedConnection() failed: %v", err)
+			}
+			defer econn.close()
+			frame := make([]byte, 4+10)
+			binary.LittleEndian.PutUint32(frame[:4], length)
+			if err := econn.send(frame); err != nil {
+				t.Fatalf("send() failed: %v", err)
+			}
+			if err := econn.conn.SetReadDeadline(time.Now().Add(5 * time.Second)); err != nil {
+				t.Fatalf("SetReadDeadline() failed: %v", err)
+			}
+			if _, err := econn.conn.Read(make([]byte, 1)); !errors.Is(err, io.EOF) {
+				t.Fatalf("want connection closed by server, got: %v", err)
+			}
+		})
+	}
+	// the server keeps serving other connections.
+	conn, err := NewConnection(context.Background(), public, host)
+	if err != nil {
+		t.Fatalf("NewConnection() failed: %v", err)
+	}
+	if _, err := NewClient(conn).LiteServerGetMasterchainInfo(context.Background()); err != nil {
+		t.Fatalf("LiteServerGetMasterchainInfo() failed: %v", err)
+	}
+}
+
+func TestServer_handlerPanic(t *testing.T) {
+	public, host := startTestServer(t, &panickingHandler{})
+	conn, err := NewConnection(context.Background(), public, host)
+	if err != nil {
+		t.Fatalf("NewConnection() failed: %v", err)
+	}
+	client := NewClient(conn)
+	var liteServerErr LiteServerErrorC
+	if _, err := client.LiteServerGetTime(context.Background()); !errors.As(err, &liteServerErr) || liteServerErr.Code != errInternal.Code {
+		t.Fatalf("want internal error, got: %v", err)
+	}
+}
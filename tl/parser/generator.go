@@ -27,6 +27,7 @@ var (
 	defaultKnownTypes = map[string]DefaultType{
 		"#":      {"uint32", false},
 		"int":    {"uint32", false},
+		"int128": {"tl.Int128", false},
 		"int256": {"tl.Int256", false},
 		"long":   {"uint64", false},
 		"bytes":  {"[]byte", true},
@@ -150,6 +151,104 @@ func (g *Generator) LoadFunctions(functions []CombinatorDeclaration) (string, er
 	return string(b), err
 }
 
+// LoadRequestTypes generates request types of the given functions with their marshalers and unmarshalers,
+// but without client methods, so the requests can be sent over a transport other than a lite client.
+func (g *Generator) LoadRequestTypes(functions []CombinatorDeclaration) (string, error) {
+	s := ""
+	for _, c := range functions {
+		requestType, err := g.generateGolangMethodRequestType(c)
+		if err != nil {
+			return "", err
+		}
+		g.newRequestTypes[c.Constructor] = requestType
+		s += "\n" + requestType.definition + "\n"
+		marshaler, err := g.generateMarshalers([]CombinatorDeclaration{c}, requestType.name)
+		if err != nil {
+			return "", err
+		}
+		s += "\n" + marshaler + "\n"
+		unmarshler, err := g.generateUnmarshalers([]CombinatorDeclaration{c}, requestType.name)
+		if err != nil {
+			return "", err
+		}
+		s += "\n" + unmarshler + "\n"
+	}
+	b, err := format.Source([]byte(s))
+	if err != nil {
+		return s, err
+	}
+	return string(b), err
+}
+
+// LoadServerHandler generates a handler interface with the same methods as the ones generated by LoadFunctions,
+// a default implementation of the interface answering every request with an error,
+// and a function dispatching a serialized request to the handler.
+// It must be called after LoadFunctions.
+func (g *Generator) LoadServerHandler(functions []CombinatorDeclaration, handlerName string) (string, error) {
+	iface := strings.Builder{}
+	unimplemented := strings.Builder{}
+	dispatcher := strings.Builder{}
+	iface.WriteString(fmt.Sprintf("type %s interface {\n", handlerName))
+	unimplemented.WriteString(fmt.Sprintf("type Unimplemented%s struct{}\n", handlerName))
+	dispatcher.WriteString(fmt.Sprintf("func dispatch%s(ctx context.Context, h %s, payload []byte) ([]byte, error) {\n", handlerName, handlerName))
+	dispatcher.WriteString("if len(payload) < 4 {return nil, fmt.Errorf(\"not enough bytes for tag\")}\n")
+	dispatcher.WriteString("switch binary.LittleEndian.Uint32(payload[:4]) {\n")
+	for _, c := range functions {
+		methodName := utils.ToCamelCase(c.Constructor)
+		tag, err := tagToUint32(c.Tag)
+		if err != nil {
+			return "", err
+		}
+		respType, err := g.responseType(c)
+		if err != nil {
+			return "", err
+		}
+		if len(respType.tags) == 0 {
+			return "", fmt.Errorf("invalid response type %s tag", respType.name)
+		}
+		signature := methodName + "(ctx context.Context"
+		call := "h." + methodName + "(ctx"
+		if len(c.FieldDefinitions) > 0 {
+			signature += fmt.Sprintf(", request %sRequest", methodName)
+			call += ", request"
+		}
+		signature += fmt.Sprintf(") (res %s, err error)", respType.name)
+		call += ")"
+
+		iface.WriteString(signature + "\n")
+
+		unimplemented.WriteString(fmt.Sprintf("\nfunc (Unimplemented%s) %s {\n", handlerName, signature))
+		unimplemented.WriteString(fmt.Sprintf("return res, LiteServerErrorC{Code: 621, Message: \"%s is not implemented\"}\n}\n", c.Constructor))
+
+		dispatcher.WriteString(fmt.Sprintf("case %#x:\n", tag))
+		if len(c.FieldDefinitions) > 0 {
+			dispatcher.WriteString(fmt.Sprintf("var request %sRequest\n", methodName))
+			dispatcher.WriteString("err := tl.Unmarshal(bytes.NewReader(payload[4:]), &request)\n")
+			dispatcher.WriteString(marshalerReturnErr)
+		}
+		dispatcher.WriteString(fmt.Sprintf("res, err := %s\n", call))
+		dispatcher.WriteString(marshalerReturnErr)
+		if len(respType.tags) == 1 {
+			// simple type response is serialized without a tag
+			dispatcher.WriteString(fmt.Sprintf("return tl.Marshal(struct{tl.SumType \n Res %s", respType.name))
+			dispatcher.WriteString(fmt.Sprintf(" `tlSumType:\"%08x\"`}{SumType: \"Res\", Res: res})\n", respType.tags[0]))
+		} else {
+			dispatcher.WriteString("return tl.Marshal(res)\n")
+		}
+	}
+	iface.WriteString("}\n")
+	dispatcher.WriteString("}\n")
+	dispatcher.WriteString("return nil, LiteServerErrorC{Code: 621, Message: \"unknown query\"}\n")
+	dispatcher.WriteString("}\n")
+
+	s := iface.String() + "\n" + unimplemented.String() + "\n" + dispatcher.String()
+	b, err := format.Source([]byte(s))
+	if err != nil {
+		return s, err
+	}
+	return string(b), err
+}
+
 func (g *Generator) generateGolangType(declarations []CombinatorDeclaration) (tlType, error) {
 	if len(declarations) == 1 {
 		return g.generateGolangSimpleType(declarations[0])
@@ -221,7 +320,7 @@ func (g *Generator) generateGolangStruct(declaration CombinatorDeclaration) (str
 		}
 
 		optional := false
-		if field.Modificator.Name == "mode" { // mode.0?field
+		if field.Modificator.Name != "" { // mode.0?field
 			optional = true
 		}
 
@@ -350,17 +449,12 @@ func (g *Generator) generateUnmarshalerCode(declaration CombinatorDeclaration, r
 				return "", err
 			}
 			typeString := gt.String()
-			builder.WriteString(fmt.Sprintf("if (t.%s>>%s)&1 == 1{\n",
-				utils.ToCamelCase(field.Modificator.Name), field.Modificator.Bit))
 			if typeString == "True" {
-				// TODO: add field?
-				builder.WriteString("var tTrue bool\n")
-				builder.WriteString("err = tl.Unmarshal(r, &tTrue)\n")
-				builder.WriteString(unmarshalerReturnErr)
-				builder.WriteString("if tTrue != true {return fmt.Errorf(\"not a True type\")}\n")
-				builder.WriteString("}\n")
+				// True has no data, the flag itself is the value, the same way it is skipped by the marshaler.
 				continue
 			}
+			builder.WriteString(fmt.Sprintf("if (t.%s>>%s)&1 == 1{\n",
+				utils.ToCamelCase(field.Modificator.Name), field.Modificator.Bit))
 			builder.WriteString(fmt.Sprintf("var temp%s %s\n", name, typeString))
 			builder.WriteString("err = tl.Unmarshal(r, &temp" + name + ")\n")
 			builder.WriteString(unmarshalerReturnErr)
@@ -410,6 +504,10 @@ func (g *Generator) generateSumTypeUnmarshaler(declarations []CombinatorDeclarat
 func (g *Generator) generateMarshalers(declarations []CombinatorDeclaration, receiverType string) (string, error) {
 	builder := strings.Builder{}
 	builder.WriteString(fmt.Sprintf("func (t %s) MarshalTL() ([]byte, error) {\n", receiverType))
+	if len(declarations) == 1 && len(declarations[0].FieldDefinitions) == 0 {
+		builder.WriteString("return nil, nil\n}")
+		return builder.String(), nil
+	}
 	builder.WriteString("var (err error \n b []byte)\n")
 	builder.WriteString("buf := new(bytes.Buffer)\n")
 	if len(declarations) == 1 {
@@ -526,18 +624,9 @@ func (g *Generator) generateGolangMethod(typeName string, c CombinatorDeclaratio
 		return "", fmt.Errorf("invalid error tag")
 	}
 
-	var respType tlType
-	ok = false
-	for k, v := range g.newTlTypes {
-		// TODO: valid if only one constructor OR type
-		if strings.ToLower(c.Combinator) == strings.ToLower(k) {
-			respType = v
-			ok = true
-			break
-		}
-	}
-	if !ok {
-		return "", fmt.Errorf("response type %s not parsed", responseName)
+	respType, err := g.responseType(c)
+	if err != nil {
+		return "", err
 	}
 
 	builder := strings.Builder{}
@@ -591,6 +680,16 @@ func (g *Generator) generateGolangMethod(typeName string, c CombinatorDeclaratio
 	return builder.String(), nil
 }
 
+func (g *Generator) responseType(c CombinatorDeclaration) (tlType, error) {
+	for k, v := range g.newTlTypes {
+		// TODO: valid if only one constructor OR type
+		if strings.ToLower(c.Combinator) == strings.ToLower(k) {
+			return v, nil
+		}
+	}
+	return tlType{}, fmt.Errorf("response type %s not parsed", utils.ToCamelCase(c.Combinator))
+}
+
 func (g *Generator) generateGolangMethodRequestType(c CombinatorDeclaration) (tlType, error) {
 	name := utils.ToCamelCase(c.Constructor) + "Request"
 	s, err := g.generateGolangStruct(c)
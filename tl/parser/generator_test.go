@@ -2,6 +2,7 @@ package parser
 
 import (
 	"fmt"
+	"strings"
 	"testing"
 )
 
@@ -112,6 +113,37 @@ func TestGenerateGolangMethods(t *testing.T) {
 	fmt.Printf("%s", s)
 }
 
+func TestGenerateServerHandler(t *testing.T) {
+	parsed, err := Parse(SOURCE)
+	if err != nil {
+		panic(err)
+	}
+	g := NewGenerator(nil, "*Client")
+
+	_, err = g.LoadTypes(parsed.Declarations)
+	if err != nil {
+		panic(err)
+	}
+	_, err = g.LoadFunctions(parsed.Functions)
+	if err != nil {
+		panic(err)
+	}
+	s, err := g.LoadServerHandler(parsed.Functions, "LiteServerHandler")
+	if err != nil {
+		panic(err)
+	}
+	for _, want := range []string{
+		"type LiteServerHandler interface",
+		"LiteServerGetMasterchainInfo(ctx context.Context) (res LiteServerMasterchainInfoC, err error)",
+		"func (UnimplementedLiteServerHandler) LiteServerGetMasterchainInfo(",
+		"func dispatchLiteServerHandler(ctx context.Context, h LiteServerHandler, payload []byte) ([]byte, error)",
+	} {
+		if !strings.Contains(s, want) {
+			t.Fatalf("generated code doesn't contain %q:\n%s", want, s)
+		}
+	}
+}
+
 func TestCheckBits(t *testing.T) {
 	mode := 53
 	fmt.Printf("Mode: %b\n", mode)
@@ -119,3 +151,35 @@ func TestCheckBits(t *testing.T) {
 		fmt.Printf("%v bit: %v\n", i, (mode>>i)&1 == 1)
 	}
 }
+
+func TestGenerateRequestTypes(t *testing.T) {
+	parsed, err := Parse(`
+adnl.message.nop#17f8dfda = adnl.Message;
+adnl.packetContents#d142cd89 flags:# seqno:flags.6?long = adnl.PacketContents;
+---functions---
+dht.getSignedAddressList#a97948ed = dht.Node;
+`)
+	if err != nil {
+		t.Fatalf("Parse() failed: %v", err)
+	}
+	g := NewGenerator(nil, "")
+	types, err := g.LoadTypes(parsed.Declarations)
+	if err != nil {
+		t.Fatalf("LoadTypes() failed: %v", err)
+	}
+	requests, err := g.LoadRequestTypes(parsed.Functions)
+	if err != nil {
+		t.Fatalf("LoadRequestTypes() failed: %v", err)
+	}
+	for _, want := range []string{
+		"Seqno *uint64",
+		"func (t AdnlMessageNopC) MarshalTL() ([]byte, error) {\n\treturn nil, nil\n}",
+	} {
+		if !strings.Contains(types, want) {
+			t.Fatalf("generated types don't contain %q:\n%s", want, types)
+		}
+	}
+	if !strings.Contains(requests, "type DhtGetSignedAddressListRequest struct{}") {
+		t.Fatalf("generated requests don't contain request type:\n%s", requests)
+	}
+}
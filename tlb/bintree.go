@@ -6,49 +6,150 @@ import (
 	"github.com/tonkeeper/tongo/boc"
 )
 
+// BinTree
+// bt_leaf$0 {X:Type} leaf:X = BinTree X;
+// bt_fork$1 {X:Type} left:^(BinTree X) right:^(BinTree X) = BinTree X;
 type BinTree[T any] struct {
 	Values []T
+	// prefixes contains a path from the root to each leaf.
+	// A prefix at index "i" corresponds to a value at the same index.
+	prefixes []boc.BitString
 }
 
-func decodeRecursiveBinTree(c *boc.Cell) ([]*boc.Cell, error) {
+// NewBinTree returns a new instance of BinTree.
+// Make sure that a prefix at index "i" is a path from the root to a leaf holding a value at the same index.
+// Prefixes must describe a full binary tree, so each fork has both branches.
+func NewBinTree[T any](values []T, prefixes []boc.BitString) BinTree[T] {
+	return BinTree[T]{
+		Values:   values,
+		prefixes: prefixes,
+	}
+}
+
+// Prefixes returns a path from the root to each leaf of this tree.
+func (b BinTree[T]) Prefixes() []boc.BitString {
+	return b.prefixes
+}
+
+func decodeRecursiveBinTree(c *boc.Cell, prefix boc.BitString) ([]*boc.Cell, []boc.BitString, error) {
 	var cellAr []*boc.Cell
+	var prefixes []boc.BitString
 	isBranch, err := c.ReadBit()
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 	if !isBranch {
-		return append(cellAr, c), nil
+		return append(cellAr, c), append(prefixes, prefix), nil
 	}
 
 	l, err := c.NextRef()
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
-	rec, err := decodeRecursiveBinTree(l)
+	lp := growPrefix(prefix, false)
+	rec, recPrefixes, err := decodeRecursiveBinTree(l, lp)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 	cellAr = append(cellAr, rec...)
+	prefixes = append(prefixes, recPrefixes...)
 	r, err := c.NextRef()
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
-	rec, err = decodeRecursiveBinTree(r)
+	rp := growPrefix(prefix, true)
+	rec, recPrefixes, err = decodeRecursiveBinTree(r, rp)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 	cellAr = append(cellAr, rec...)
+	prefixes = append(prefixes, recPrefixes...)
 
-	return cellAr, nil
+	return cellAr, prefixes, nil
+}
+
+// growPrefix returns a copy of the given prefix with one more bit.
+func growPrefix(prefix boc.BitString, bit bool) boc.BitString {
+	p := boc.NewBitString(prefix.GetWriteCursor() + 1)
+	// both operations can't fail because p is big enough.
+	_ = p.WriteBitString(prefix)
+	_ = p.WriteBit(bit)
+	return p
+}
+
+func bitStringToBits(s boc.BitString) []bool {
+	s.ResetCounter()
+	bits := make([]bool, 0, s.BitsAvailableForRead())
+	for s.BitsAvailableForRead() > 0 {
+		bit, _ := s.ReadBit()
+		bits = append(bits, bit)
+	}
+	return bits
 }
 
 func (b BinTree[T]) MarshalTLB(c *boc.Cell, encoder *Encoder) error {
-	// TODO: implement
-	return fmt.Errorf("BinTree marshaling not implemented")
+	if len(b.Values) == 0 {
+		return fmt.Errorf("BinTree must contain at least one leaf")
+	}
+	prefixes := b.prefixes
+	if len(prefixes) == 0 && len(b.Values) == 1 {
+		// a tree with a single leaf doesn't need a prefix
+		prefixes = []boc.BitString{boc.NewBitString(0)}
+	}
+	if len(prefixes) != len(b.Values) {
+		return fmt.Errorf("BinTree has %v values but %v prefixes", len(b.Values), len(prefixes))
+	}
+	paths := make([][]bool, 0, len(prefixes))
+	indexes := make([]int, 0, len(prefixes))
+	for i, prefix := range prefixes {
+		paths = append(paths, bitStringToBits(prefix))
+		indexes = append(indexes, i)
+	}
+	return encodeBinTree(c, b.Values, paths, indexes, 0, encoder)
+}
+
+func encodeBinTree[T any](c *boc.Cell, values []T, paths [][]bool, indexes []int, depth int, encoder *Encoder) error {
+	if len(indexes) == 1 && len(paths[indexes[0]]) == depth {
+		// bt_leaf$0 {X:Type} leaf:X = BinTree X;
+		if err := c.WriteBit(false); err != nil {
+			return err
+		}
+		return encoder.Marshal(c, values[indexes[0]])
+	}
+	// bt_fork$1 {X:Type} left:^(BinTree X) right:^(BinTree X) = BinTree X;
+	var left, right []int
+	for _, i := range indexes {
+		if len(paths[i]) <= depth {
+			return fmt.Errorf("BinTree prefix of leaf %v is a prefix of another leaf", i)
+		}
+		if paths[i][depth] {
+			right = append(right, i)
+		} else {
+			left = append(left, i)
+		}
+	}
+	if len(left) == 0 || len(right) == 0 {
+		return fmt.Errorf("BinTree has a fork with a single branch at depth %v", depth)
+	}
+	if err := c.WriteBit(true); err != nil {
+		return err
+	}
+	l, err := c.NewRef()
+	if err != nil {
+		return err
+	}
+	if err := encodeBinTree(l, values, paths, left, depth+1, encoder); err != nil {
+		return err
+	}
+	r, err := c.NewRef()
+	if err != nil {
+		return err
+	}
+	return encodeBinTree(r, values, paths, right, depth+1, encoder)
 }
 
 func (b *BinTree[T]) UnmarshalTLB(c *boc.Cell, decoder *Decoder) error {
-	dec, err := decodeRecursiveBinTree(c)
+	dec, prefixes, err := decodeRecursiveBinTree(c, boc.NewBitString(0))
 	if err != nil {
 		return err
 	}
@@ -61,5 +162,6 @@ func (b *BinTree[T]) UnmarshalTLB(c *boc.Cell, decoder *Decoder) error {
 		}
 		b.Values = append(b.Values, t)
 	}
+	b.prefixes = prefixes
 	return nil
 }
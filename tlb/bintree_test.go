@@ -0,0 +1,94 @@
+package tlb
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/tonkeeper/tongo/boc"
+)
+
+func shardDesc(seqno uint32) ShardDesc {
+	desc := ShardDesc{SumType: "New"}
+	desc.New.SeqNo = seqno
+	desc.New.SplitMergeAt.SumType = "FsmNone"
+	return desc
+}
+
+func TestNewShardInfoBinTree(t *testing.T) {
+	tests := []struct {
+		name     string
+		shardIDs []uint64
+		wantErr  bool
+	}{
+		{
+			name:     "single shard",
+			shardIDs: []uint64{0x8000000000000000},
+		},
+		{
+			name:     "after split",
+			shardIDs: []uint64{0x4000000000000000, 0xc000000000000000},
+		},
+		{
+			name:     "overlapping shards",
+			shardIDs: []uint64{0x2000000000000000, 0x6000000000000000, 0xa000000000000000, 0xe000000000000000, 0xc000000000000000},
+			wantErr:  true,
+		},
+		{
+			name:     "several splits",
+			shardIDs: []uint64{0x4000000000000000, 0xa000000000000000, 0xd000000000000000, 0xf000000000000000},
+		},
+		{
+			name:     "not covered",
+			shardIDs: []uint64{0x4000000000000000, 0xa000000000000000},
+			wantErr:  true,
+		},
+		{
+			name:     "gap in the middle",
+			shardIDs: []uint64{0x4000000000000000, 0xa000000000000000, 0xf000000000000000},
+			wantErr:  true,
+		},
+		{
+			name:     "workchain and its half",
+			shardIDs: []uint64{0x8000000000000000, 0xc000000000000000},
+			wantErr:  true,
+		},
+		{
+			name:    "no shards",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			shards := make(map[uint64]ShardDesc, len(tt.shardIDs))
+			for i, shardID := range tt.shardIDs {
+				shards[shardID] = shardDesc(uint32(i + 1))
+			}
+			tree, err := NewShardInfoBinTree(shards)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("NewShardInfoBinTree() must fail")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("NewShardInfoBinTree() failed: %v", err)
+			}
+			cell := boc.NewCell()
+			if err := Marshal(cell, tree); err != nil {
+				t.Fatalf("Marshal() failed: %v", err)
+			}
+			var decoded ShardInfoBinTree
+			if err := Unmarshal(cell, &decoded); err != nil {
+				t.Fatalf("Unmarshal() failed: %v", err)
+			}
+			if !reflect.DeepEqual(decoded.ShardIDs(), tt.shardIDs) {
+				t.Fatalf("want shard IDs: %x, got: %x", tt.shardIDs, decoded.ShardIDs())
+			}
+			for i, desc := range decoded.BinTree.Values {
+				if desc.SeqNo() != uint32(i+1) {
+					t.Fatalf("want seqno: %v, got: %v", i+1, desc.SeqNo())
+				}
+			}
+		})
+	}
+}
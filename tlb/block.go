@@ -116,6 +116,61 @@ func (i *BlockInfo) UnmarshalTLB(c *boc.Cell, decoder *Decoder) error {
 	return nil
 }
 
+func (i BlockInfo) MarshalTLB(c *boc.Cell, encoder *Encoder) error {
+	data := struct {
+		Magic     Magic `tlb:"block_info#9bc7a987"`
+		BlockInfo BlockInfoPart
+	}{BlockInfo: i.BlockInfoPart}
+	err := encoder.Marshal(c, data)
+	if err != nil {
+		return err
+	}
+	if i.Flags&1 == 1 {
+		if i.GenSoftware == nil {
+			return fmt.Errorf("gen_software is required if flags has the first bit set")
+		}
+		err = encoder.Marshal(c, *i.GenSoftware)
+		if err != nil {
+			return err
+		}
+	}
+	if i.NotMaster {
+		if i.MasterRef == nil {
+			return fmt.Errorf("master_ref is required for a non-masterchain block")
+		}
+		c1, err := c.NewRef()
+		if err != nil {
+			return err
+		}
+		err = encoder.Marshal(c1, *i.MasterRef)
+		if err != nil {
+			return err
+		}
+	}
+	c1, err := c.NewRef()
+	if err != nil {
+		return err
+	}
+	err = i.PrevRef.MarshalTLB(c1, i.AfterMerge, encoder)
+	if err != nil {
+		return err
+	}
+	if i.VertSeqnoIncr {
+		if i.PrevVertRef == nil {
+			return fmt.Errorf("prev_vert_ref is required if vert_seqno_incr is set")
+		}
+		c1, err = c.NewRef()
+		if err != nil {
+			return err
+		}
+		err = i.PrevVertRef.MarshalTLB(c1, false, encoder)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // GlobalVersion
 // capabilities#c4 version:uint32 capabilities:uint64 = GlobalVersion;
 type GlobalVersion struct {
@@ -193,6 +248,31 @@ func (i *BlkPrevInfo) UnmarshalTLB(c *boc.Cell, isBlks bool, decoder *Decoder) e
 	return nil
 }
 
+func (i BlkPrevInfo) MarshalTLB(c *boc.Cell, isBlks bool, encoder *Encoder) error { // custom marshaler. Not for automatic encoder.
+	if isBlks {
+		if i.PrevBlksInfo == nil {
+			return fmt.Errorf("prev_blks_info is required after merge")
+		}
+		c1, err := c.NewRef()
+		if err != nil {
+			return err
+		}
+		err = encoder.Marshal(c1, i.PrevBlksInfo.Prev1)
+		if err != nil {
+			return err
+		}
+		c2, err := c.NewRef()
+		if err != nil {
+			return err
+		}
+		return encoder.Marshal(c2, i.PrevBlksInfo.Prev2)
+	}
+	if i.PrevBlkInfo == nil {
+		return fmt.Errorf("prev_blk_info is required")
+	}
+	return encoder.Marshal(c, i.PrevBlkInfo.Prev)
+}
+
 // Block
 // block#11ef55aa global_id:int32
 // info:^BlockInfo value_flow:^ValueFlow
@@ -341,6 +421,58 @@ func (m *ValueFlow) UnmarshalTLB(c *boc.Cell, decoder *Decoder) error {
 	return nil
 }
 
+func (m ValueFlow) MarshalTLB(c *boc.Cell, encoder *Encoder) error {
+	sumType := uint64(m.Magic)
+	if sumType == 0 {
+		sumType = valueFlowV1
+		if m.Burned != nil {
+			sumType = valueFlowV2
+		}
+	}
+	if sumType != valueFlowV1 && sumType != valueFlowV2 {
+		return fmt.Errorf("value flow invalid tag: %v", sumType)
+	}
+	err := c.WriteUint(sumType, 32)
+	if err != nil {
+		return err
+	}
+	firstGroup, err := c.NewRef()
+	if err != nil {
+		return err
+	}
+	for _, cc := range []CurrencyCollection{m.FromPrevBlk, m.ToNextBlk, m.Imported, m.Exported} {
+		err = encoder.Marshal(firstGroup, cc)
+		if err != nil {
+			return err
+		}
+	}
+	err = encoder.Marshal(c, m.FeesCollected)
+	if err != nil {
+		return err
+	}
+	if sumType == valueFlowV2 {
+		var burned CurrencyCollection
+		if m.Burned != nil {
+			burned = *m.Burned
+		}
+		err = encoder.Marshal(c, burned)
+		if err != nil {
+			return err
+		}
+	}
+	secondGroup, err := c.NewRef()
+	if err != nil {
+		return err
+	}
+	for _, cc := range []CurrencyCollection{m.FeesImported, m.Recovered, m.Created, m.Minted} {
+		err = encoder.Marshal(secondGroup, cc)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // BlockExtra
 // block_extra in_msg_descr:^InMsgDescr
 // out_msg_descr:^OutMsgDescr
@@ -436,7 +568,7 @@ func (m *McBlockExtra) UnmarshalTLB(c *boc.Cell, decoder *Decoder) error {
 		return err
 	}
 
-	if c1 != nil {
+	if c1 != nil && c1.CellType() != boc.PrunedBranchCell {
 		err = decoder.Unmarshal(c1, &m.McExtraOther)
 		if err != nil {
 			return err
@@ -451,6 +583,37 @@ func (m *McBlockExtra) UnmarshalTLB(c *boc.Cell, decoder *Decoder) error {
 	return nil
 }
 
+func (m McBlockExtra) MarshalTLB(c *boc.Cell, encoder *Encoder) error {
+	err := c.WriteUint(0xcca5, 16)
+	if err != nil {
+		return err
+	}
+	err = encoder.Marshal(c, m.KeyBlock)
+	if err != nil {
+		return err
+	}
+	err = encoder.Marshal(c, m.ShardHashes)
+	if err != nil {
+		return err
+	}
+	err = encoder.Marshal(c, m.ShardFees)
+	if err != nil {
+		return err
+	}
+	c1, err := c.NewRef()
+	if err != nil {
+		return err
+	}
+	err = encoder.Marshal(c1, m.McExtraOther)
+	if err != nil {
+		return err
+	}
+	if m.KeyBlock {
+		return encoder.Marshal(c, m.Config)
+	}
+	return nil
+}
+
 // TransactionsQuantity returns the number of transactions in this block.
 func (b *Block) TransactionsQuantity() int {
 	quantity := 0
@@ -154,3 +154,44 @@ func libraries(s *ShardState) map[string]map[string]struct{} {
 	}
 	return libs
 }
+
+func TestBlock_MarshalTLB(t *testing.T) {
+	folders := []string{
+		"testdata/block-1",
+		"testdata/block-2",
+		"testdata/block-3",
+		"testdata/block-4",
+		"testdata/block-5",
+	}
+	for _, folder := range folders {
+		t.Run(folder, func(t *testing.T) {
+			data, err := os.ReadFile(path.Join(folder, "block.bin"))
+			if err != nil {
+				t.Fatalf("ReadFile() failed: %v", err)
+			}
+			cell, err := boc.DeserializeBoc(data)
+			if err != nil {
+				t.Fatalf("boc.DeserializeBoc() failed: %v", err)
+			}
+			var block Block
+			if err = Unmarshal(cell[0], &block); err != nil {
+				t.Fatalf("Unmarshal() failed: %v", err)
+			}
+			encoded := boc.NewCell()
+			if err = Marshal(encoded, block); err != nil {
+				t.Fatalf("Marshal() failed: %v", err)
+			}
+			wantHash, err := cell[0].Hash256()
+			if err != nil {
+				t.Fatalf("Hash256() failed: %v", err)
+			}
+			hash, err := encoded.Hash256()
+			if err != nil {
+				t.Fatalf("Hash256() failed: %v", err)
+			}
+			if hash != wantHash {
+				t.Fatalf("want hash: %x, got: %x", wantHash, hash)
+			}
+		})
+	}
+}
@@ -106,6 +106,43 @@ func (t *DNSText) UnmarshalTLB(c *boc.Cell, decoder *Decoder) error {
 	return nil
 }
 
+// textChunkSize is a size of a chunk fitting into a cell together with the chunks counter and the chunk length.
+const textChunkSize = 125
+
+func (t DNSText) MarshalTLB(c *boc.Cell, encoder *Encoder) error {
+	var chunks []string
+	for s := string(t); len(s) > 0; {
+		n := len(s)
+		if n > textChunkSize {
+			n = textChunkSize
+		}
+		chunks = append(chunks, s[:n])
+		s = s[n:]
+	}
+	if len(chunks) > 255 {
+		return fmt.Errorf("text is too long")
+	}
+	if err := c.WriteUint(uint64(len(chunks)), 8); err != nil {
+		return err
+	}
+	for i, chunk := range chunks {
+		if i > 0 {
+			next, err := c.NewRef()
+			if err != nil {
+				return err
+			}
+			c = next
+		}
+		if err := c.WriteUint(uint64(len(chunk)), 8); err != nil {
+			return err
+		}
+		if err := c.WriteBytes([]byte(chunk)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // chunk_ref$_ {n:#} ref:^(TextChunks (n + 1)) = TextChunkRef (n + 1);
 // chunk_ref_empty$_ = TextChunkRef 0;
 // text_chunk$_ {n:#} len:(## 8) data:(bits (len * 8)) next:(TextChunkRef n) = TextChunks (n + 1);
@@ -146,6 +146,10 @@ func encodeStruct(c *boc.Cell, o any, encoder *Encoder) error {
 func encodeBasicStruct(c *boc.Cell, o any, encoder *Encoder) error {
 	val := reflect.ValueOf(o)
 	for i := 0; i < val.NumField(); i++ {
+		if !val.Type().Field(i).IsExported() {
+			// unexported fields keep auxiliary data like a cached hash, they aren't a part of TL-B.
+			continue
+		}
 		tag := val.Type().Field(i).Tag.Get("tlb")
 		if err := encode(c, tag, val.Field(i).Interface(), encoder); err != nil {
 			return err
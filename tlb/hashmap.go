@@ -408,49 +408,106 @@ func encodeLabel(c *boc.Cell, keyFirst, keyLast *boc.BitString, keySize int) (bo
 	}
 	keyFirst.ResetCounter()
 	keyLast.ResetCounter()
-	if label.BitsAvailableForRead() < 8 {
-		//hml_short$0 {m:#} {n:#} len:(Unary ~n) {n <= m} s:(n * Bit) = HmLabel ~n m;
-		err := c.WriteBit(false)
-		if err != nil {
+	// we choose the shortest label encoding as the node does,
+	// otherwise a re-encoded hashmap would have a different hash.
+	ln := label.BitsAvailableForRead()
+	k := bitsRequired(keySize)
+	same, bit := isSameBitsLabel(label)
+	switch {
+	case ln > 1 && same && k < 2*ln-1:
+		// hml_same$11 {m:#} v:Bit n:(#<= m) = HmLabel ~n m;
+		if err := c.WriteUint(0b11, 2); err != nil {
 			return boc.BitString{}, err
 		}
-		// todo pack label
-		err = c.WriteUnary(uint(label.BitsAvailableForRead()))
-		if err != nil {
+		if err := c.WriteBit(bit); err != nil {
 			return boc.BitString{}, err
 		}
-		err = c.WriteBitString(label)
-		if err != nil {
+		if err := c.WriteLimUint(ln, keySize); err != nil {
 			return boc.BitString{}, err
 		}
-
-	} else {
+	case k < ln:
 		// hml_long$10 {m:#} n:(#<= m) s:(n * Bit) = HmLabel ~n m;
-		err := c.WriteBit(true)
-		if err != nil {
+		if err := c.WriteUint(0b10, 2); err != nil {
 			return boc.BitString{}, err
 		}
-		err = c.WriteBit(false)
-		if err != nil {
+		if err := c.WriteLimUint(ln, keySize); err != nil {
 			return boc.BitString{}, err
 		}
-		// todo pack label
-		err = c.WriteLimUint(label.BitsAvailableForRead(), keySize)
-		if err != nil {
+		if err := c.WriteBitString(label); err != nil {
 			return boc.BitString{}, err
 		}
-		err = c.WriteBitString(label)
-		if err != nil {
+	default:
+		//hml_short$0 {m:#} {n:#} len:(Unary ~n) {n <= m} s:(n * Bit) = HmLabel ~n m;
+		if err := c.WriteBit(false); err != nil {
+			return boc.BitString{}, err
+		}
+		if err := c.WriteUnary(uint(ln)); err != nil {
+			return boc.BitString{}, err
+		}
+		if err := c.WriteBitString(label); err != nil {
 			return boc.BitString{}, err
 		}
 	}
 	return label, nil
 }
 
+// bitsRequired returns a number of bits required to store a value in range 0..n.
+func bitsRequired(n int) int {
+	k := 0
+	for ; n > 0; n >>= 1 {
+		k++
+	}
+	return k
+}
+
+// isSameBitsLabel reports whether all bits of the given label are equal and returns that bit.
+func isSameBitsLabel(label boc.BitString) (bool, bool) {
+	if label.BitsAvailableForRead() == 0 {
+		return false, false
+	}
+	first, err := label.ReadBit()
+	if err != nil {
+		return false, false
+	}
+	for label.BitsAvailableForRead() > 0 {
+		bit, err := label.ReadBit()
+		if err != nil || bit != first {
+			return false, false
+		}
+	}
+	return true, first
+}
+
 type HashmapAug[keyT fixedSize, T1, T2 any] struct {
 	keys   []keyT
 	values []T1
-	extra  HashMapAugExtraList[T2]
+	// extras contains an extra value of each leaf,
+	// a leaf extra at index "i" corresponds to a value at the same index.
+	extras []T2
+	// extra contains extra values of all nodes of a decoded hashmap.
+	// It is used to marshal forks of a hashmap that has not been changed since decoding.
+	extra   HashMapAugExtraList[T2]
+	combine func(left, right T2) (T2, error)
+}
+
+// AugExtra is implemented by extra types of augmented hashmaps
+// which know how to compute an extra value of a fork from extra values of its branches.
+type AugExtra[T any] interface {
+	Combine(right T) (T, error)
+}
+
+// NewHashmapAug returns a new instance of HashmapAug.
+// Keys must be sorted in ascending order.
+// Make sure that a key at index "i" corresponds to a value and a leaf extra at the same index.
+// combine is used to compute an extra value of each fork,
+// it can be nil if T2 implements AugExtra.
+func NewHashmapAug[keyT fixedSize, T1, T2 any](keys []keyT, values []T1, extras []T2, combine func(left, right T2) (T2, error)) HashmapAug[keyT, T1, T2] {
+	return HashmapAug[keyT, T1, T2]{
+		keys:    keys,
+		values:  values,
+		extras:  extras,
+		combine: combine,
+	}
 }
 
 type HashMapAugExtraList[T any] struct {
@@ -460,7 +517,118 @@ type HashMapAugExtraList[T any] struct {
 }
 
 func (h HashmapAug[keyT, T1, T2]) MarshalTLB(c *boc.Cell, encoder *Encoder) error {
-	return fmt.Errorf("not implemented")
+	_, err := h.marshal(c, encoder)
+	return err
+}
+
+// marshal encodes the hashmap to the given cell and returns an extra value of its root.
+func (h HashmapAug[keyT, T1, T2]) marshal(c *boc.Cell, encoder *Encoder) (T2, error) {
+	var extra T2
+	if len(h.keys) != len(h.values) || len(h.keys) != len(h.extras) {
+		return extra, fmt.Errorf("hashmap aug has %v keys, %v values and %v extras", len(h.keys), len(h.values), len(h.extras))
+	}
+	var s keyT
+	keys := make([]boc.BitString, 0, len(h.keys))
+	for _, k := range h.keys {
+		cell := boc.NewCell()
+		err := Marshal(cell, k)
+		if err != nil {
+			return extra, err
+		}
+		keys = append(keys, cell.RawBitString())
+	}
+	return h.encodeMap(c, keys, h.values, h.extras, &h.extra, s.FixedSize(), encoder)
+}
+
+func (h HashmapAug[keyT, T1, T2]) encodeMap(c *boc.Cell, keys []boc.BitString, values []T1, extras []T2, node *HashMapAugExtraList[T2], keySize int, encoder *Encoder) (T2, error) {
+	var extra T2
+	if len(keys) == 0 || len(values) == 0 {
+		return extra, fmt.Errorf("keys or values are empty")
+	}
+	label, err := encodeLabel(c, &keys[0], &keys[len(keys)-1], keySize)
+	if err != nil {
+		return extra, err
+	}
+	keySize = keySize - label.BitsAvailableForRead() - 1 // l = n - m - 1 // see tlb
+	if len(keys) == 1 {
+		// ahmn_leaf#_ {X:Type} {Y:Type} extra:Y value:X = HashmapAugNode 0 X Y;
+		if err := encoder.Marshal(c, extras[0]); err != nil {
+			return extra, err
+		}
+		if err := encoder.Marshal(c, values[0]); err != nil {
+			return extra, err
+		}
+		return extras[0], nil
+	}
+	// ahmn_fork#_ {n:#} {X:Type} {Y:Type} left:^(HashmapAug n X Y) right:^(HashmapAug n X Y) extra:Y = HashmapAugNode (n + 1) X Y;
+	var leftKeys, rightKeys []boc.BitString
+	var leftValues, rightValues []T1
+	var leftExtras, rightExtras []T2
+	for i := range keys {
+		_, err := keys[i].ReadBits(label.BitsAvailableForRead()) // skip common label
+		if err != nil {
+			return extra, err
+		}
+		isRight, err := keys[i].ReadBit()
+		if err != nil {
+			return extra, err
+		}
+		if isRight {
+			rightKeys = append(rightKeys, keys[i].ReadRemainingBits())
+			rightValues = append(rightValues, values[i])
+			rightExtras = append(rightExtras, extras[i])
+		} else {
+			leftKeys = append(leftKeys, keys[i].ReadRemainingBits())
+			leftValues = append(leftValues, values[i])
+			leftExtras = append(leftExtras, extras[i])
+		}
+	}
+	// a decoded hashmap keeps extra values of its forks,
+	// so we reuse them to get exactly the same cells.
+	var leftNode, rightNode *HashMapAugExtraList[T2]
+	decoded := node != nil && node.Left != nil && node.Right != nil
+	if decoded {
+		leftNode, rightNode = node.Left, node.Right
+	}
+	l, err := c.NewRef()
+	if err != nil {
+		return extra, err
+	}
+	leftExtra, err := h.encodeMap(l, leftKeys, leftValues, leftExtras, leftNode, keySize, encoder)
+	if err != nil {
+		return extra, err
+	}
+	r, err := c.NewRef()
+	if err != nil {
+		return extra, err
+	}
+	rightExtra, err := h.encodeMap(r, rightKeys, rightValues, rightExtras, rightNode, keySize, encoder)
+	if err != nil {
+		return extra, err
+	}
+	if decoded {
+		extra = node.Data
+	} else {
+		extra, err = h.combineExtra(leftExtra, rightExtra)
+		if err != nil {
+			return extra, err
+		}
+	}
+	if err := encoder.Marshal(c, extra); err != nil {
+		return extra, err
+	}
+	return extra, nil
+}
+
+func (h HashmapAug[keyT, T1, T2]) combineExtra(left, right T2) (T2, error) {
+	if h.combine != nil {
+		return h.combine(left, right)
+	}
+	if aug, ok := any(left).(AugExtra[T2]); ok {
+		return aug.Combine(right)
+	}
+	var extra T2
+	return extra, fmt.Errorf("unable to combine extra values of type %T", extra)
 }
 
 func (h *HashmapAug[keyT, T1, T2]) UnmarshalTLB(c *boc.Cell, decoder *Decoder) error {
@@ -531,6 +699,7 @@ func (h *HashmapAug[keyT, T1, T2]) mapInner(keySize, leftKeySize int, c *boc.Cel
 		return err
 	}
 	extras.Data = extra
+	h.extras = append(h.extras, extra)
 	// add node to map
 	var value T1
 	err = decoder.Unmarshal(c, &value)
@@ -553,6 +722,227 @@ func (h *HashmapAug[keyT, T1, T2]) mapInner(keySize, leftKeySize int, c *boc.Cel
 	return nil
 }
 
+// iterateHashmapAug walks through a HashmapAug and calls fn for each leaf.
+// Unlike mapInner, it doesn't keep decoded values, so it can be used to process huge hashmaps.
+func iterateHashmapAug[keyT fixedSize, T1, T2 any](keySize, leftKeySize int, c *boc.Cell, keyPrefix *boc.BitString, decoder *Decoder, fn func(key keyT, value T1, extra T2) error) error {
+	var err error
+	var size int
+	if c.CellType() == boc.PrunedBranchCell {
+		return nil
+	}
+	size, keyPrefix, err = loadLabel(leftKeySize, c, keyPrefix)
+	if err != nil {
+		return err
+	}
+	if keyPrefix.BitsAvailableForRead() < keySize {
+		for _, bit := range []bool{false, true} {
+			next, err := c.NextRef()
+			if err != nil {
+				return err
+			}
+			prefix := keyPrefix.Copy()
+			if err := prefix.WriteBit(bit); err != nil {
+				return err
+			}
+			err = iterateHashmapAug(keySize, leftKeySize-(1+size), next, &prefix, decoder, fn)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	var extra T2
+	if err := decoder.Unmarshal(c, &extra); err != nil {
+		return err
+	}
+	var value T1
+	if err := decoder.Unmarshal(c, &value); err != nil {
+		return err
+	}
+	key, err := keyPrefix.ReadBits(keySize)
+	if err != nil {
+		return err
+	}
+	var k keyT
+	if err := decoder.Unmarshal(boc.NewCellWithBits(key), &k); err != nil {
+		return err
+	}
+	return fn(k, value, extra)
+}
+
+// ErrPrunedKeyPath is returned when a key can't be looked up
+// because its path in a hashmap is hidden by a pruned branch.
+var ErrPrunedKeyPath = errors.New("key path is pruned")
+
+// lookupHashmapAug walks through a HashmapAug along the given key.
+// It returns the leaf cell positioned right after the leaf's extra, so the caller can decode its value,
+// or nil if the hashmap doesn't contain the key.
+// Unlike mapInner, it distinguishes a missing key from a key hidden by a pruned branch.
+func lookupHashmapAug[T2 any](keySize int, c *boc.Cell, key boc.BitString, decoder *Decoder) (*boc.Cell, error) {
+	leaf, err := lookupHashmap(keySize, c, key)
+	if err != nil || leaf == nil {
+		return nil, err
+	}
+	var extra T2
+	if err := decoder.Unmarshal(leaf, &extra); err != nil {
+		return nil, err
+	}
+	return leaf, nil
+}
+
+// lookupHashmap walks through a Hashmap along the given key.
+// It returns the leaf cell positioned right after the leaf's label,
+// or nil if the hashmap doesn't contain the key.
+// A key hidden by a pruned branch results in ErrPrunedKeyPath.
+func lookupHashmap(keySize int, c *boc.Cell, key boc.BitString) (*boc.Cell, error) {
+	key.ResetCounter()
+	remaining := keySize
+	for {
+		if c.CellType() == boc.PrunedBranchCell {
+			return nil, ErrPrunedKeyPath
+		}
+		label := boc.NewBitString(remaining)
+		size, _, err := loadLabel(remaining, c, &label)
+		if err != nil {
+			return nil, err
+		}
+		for i := 0; i < size; i++ {
+			labelBit, err := label.ReadBit()
+			if err != nil {
+				return nil, err
+			}
+			keyBit, err := key.ReadBit()
+			if err != nil {
+				return nil, err
+			}
+			if labelBit != keyBit {
+				return nil, nil
+			}
+		}
+		if size == remaining {
+			return c, nil
+		}
+		isRight, err := key.ReadBit()
+		if err != nil {
+			return nil, err
+		}
+		next, err := c.NextRef()
+		if err != nil {
+			return nil, err
+		}
+		if isRight {
+			next, err = c.NextRef()
+			if err != nil {
+				return nil, err
+			}
+		}
+		c = next
+		c.ResetCounters()
+		remaining -= size + 1
+	}
+}
+
+// WalkHashmap walks through a non-empty Hashmap or HashmapAug with keys of the given size
+// in the ascending order of keys, or in the descending order if reverse is true,
+// starting right after the given key or from the first key if after is nil.
+// fn is called with each key and the leaf cell positioned right after the leaf's label,
+// so the caller can decode the leaf's extra and value. The walk stops when fn returns false.
+// Unlike iterateHashmapAug, a pruned branch which may hide a walked key results in ErrPrunedKeyPath,
+// so a walk over a merkle proof never skips keys silently.
+func WalkHashmap(keySize int, c *boc.Cell, after *boc.BitString, reverse bool, fn func(key boc.BitString, leaf *boc.Cell) (bool, error)) error {
+	var bound []bool
+	if after != nil {
+		key := after.Copy()
+		bound = make([]bool, keySize)
+		for i := range bound {
+			bit, err := key.ReadBit()
+			if err != nil {
+				return err
+			}
+			bound[i] = bit
+		}
+	}
+	prefix := boc.NewBitString(keySize)
+	c.ResetCounters()
+	_, err := walkHashmap(keySize, c, &prefix, bound, reverse, fn)
+	return err
+}
+
+// walkHashmap returns false if fn has stopped the walk.
+// bound is nil when all keys of the subtree are past the key the walk starts after.
+func walkHashmap(remaining int, c *boc.Cell, prefix *boc.BitString, bound []bool, reverse bool, fn func(key boc.BitString, leaf *boc.Cell) (bool, error)) (bool, error) {
+	if c.CellType() == boc.PrunedBranchCell {
+		return false, ErrPrunedKeyPath
+	}
+	depth := prefix.GetWriteCursor()
+	size, prefix, err := loadLabel(remaining, c, prefix)
+	if err != nil {
+		return false, err
+	}
+	if bound != nil {
+		label := prefix.Copy()
+		if err := label.Skip(depth); err != nil {
+			return false, err
+		}
+		for i := 0; i < size; i++ {
+			bit, err := label.ReadBit()
+			if err != nil {
+				return false, err
+			}
+			if bit == bound[depth+i] {
+				continue
+			}
+			if bit == reverse {
+				// all keys of the subtree are before the bound
+				return true, nil
+			}
+			bound = nil
+			break
+		}
+	}
+	if size == remaining {
+		if bound != nil {
+			// the key is equal to the bound
+			return true, nil
+		}
+		return fn(prefix.Copy(), c)
+	}
+	left, err := c.NextRef()
+	if err != nil {
+		return false, err
+	}
+	right, err := c.NextRef()
+	if err != nil {
+		return false, err
+	}
+	branches := []struct {
+		bit  bool
+		cell *boc.Cell
+	}{{false, left}, {true, right}}
+	if reverse {
+		branches[0], branches[1] = branches[1], branches[0]
+	}
+	for _, branch := range branches {
+		nextBound := bound
+		if bound != nil && branch.bit != bound[depth+size] {
+			if branch.bit == reverse {
+				continue
+			}
+			nextBound = nil
+		}
+		next := prefix.Copy()
+		if err := next.WriteBit(branch.bit); err != nil {
+			return false, err
+		}
+		branch.cell.ResetCounters()
+		ok, err := walkHashmap(remaining-(1+size), branch.cell, &next, nextBound, reverse, fn)
+		if err != nil || !ok {
+			return ok, err
+		}
+	}
+	return true, nil
+}
+
 type HashmapAugE[keyT fixedSize, T1, T2 any] struct {
 	m     HashmapAug[keyT, T1, T2]
 	extra T2
@@ -569,15 +959,47 @@ func (h *HashmapAugE[keyT, T1, T2]) UnmarshalTLB(c *boc.Cell, decoder *Decoder)
 	return err
 }
 
+// NewHashmapAugE returns a new instance of HashmapAugE.
+// See NewHashmapAug for details.
+// An extra value of the root is computed during marshaling,
+// for an empty hashmap it is the zero value of T2.
+func NewHashmapAugE[keyT fixedSize, T1, T2 any](keys []keyT, values []T1, extras []T2, combine func(left, right T2) (T2, error)) HashmapAugE[keyT, T1, T2] {
+	return HashmapAugE[keyT, T1, T2]{
+		m: NewHashmapAug(keys, values, extras, combine),
+	}
+}
+
 func (h HashmapAugE[keyT, T1, T2]) MarshalTLB(c *boc.Cell, encoder *Encoder) error {
-	var temp struct {
-		M     Maybe[Ref[HashmapAug[keyT, T1, T2]]]
-		Extra T2
+	if len(h.m.keys) == 0 {
+		// ahme_empty$0 {n:#} {X:Type} {Y:Type} extra:Y = HashmapAugE n X Y;
+		if err := c.WriteBit(false); err != nil {
+			return err
+		}
+		return encoder.Marshal(c, h.extra)
+	}
+	// ahme_root$1 {n:#} {X:Type} {Y:Type} root:^(HashmapAug n X Y) extra:Y = HashmapAugE n X Y;
+	if err := c.WriteBit(true); err != nil {
+		return err
+	}
+	ref, err := c.NewRef()
+	if err != nil {
+		return err
+	}
+	extra, err := h.m.marshal(ref, encoder)
+	if err != nil {
+		return err
 	}
-	temp.M.Exists = len(h.m.keys) > 0
-	temp.M.Value.Value = h.m
-	temp.Extra = h.extra
-	return Marshal(c, temp)
+	return encoder.Marshal(c, extra)
+}
+
+// Extra returns an extra value of the root of this hashmap.
+func (h HashmapAugE[keyT, T1, T2]) Extra() T2 {
+	return h.extra
+}
+
+// Extras returns a list of leaf extra values of this hashmap.
+func (h HashmapAugE[keyT, T1, T2]) Extras() []T2 {
+	return h.m.extras
 }
 
 func (h HashmapAugE[keyT, T1, T2]) Values() []T1 {
@@ -695,6 +1117,16 @@ func (h HashmapAug[_, T1, _]) Values() []T1 {
 	return h.values
 }
 
+// Keys returns a list of keys of this hashmap.
+func (h HashmapAug[keyT, _, _]) Keys() []keyT {
+	return h.keys
+}
+
+// Extras returns a list of leaf extra values of this hashmap.
+func (h HashmapAug[_, _, T2]) Extras() []T2 {
+	return h.extras
+}
+
 // Items returns key-value pairs of this hashmap.
 func (h HashmapE[keyT, T]) Items() []HashmapItem[keyT, T] {
 	return h.m.Items()
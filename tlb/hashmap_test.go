@@ -1,6 +1,8 @@
 package tlb
 
 import (
+	"errors"
+	"fmt"
 	"os"
 	"reflect"
 	"testing"
@@ -103,3 +105,130 @@ func Benchmark_HashmapAug_Unmarshal(b *testing.B) {
 		}
 	}
 }
+
+func TestHashmapAug_MarshalTLB(t *testing.T) {
+	bs, err := os.ReadFile("testdata/hashmap_aug.hex")
+	if err != nil {
+		t.Fatalf("ReadFile() failed: %v", err)
+	}
+	cell, err := boc.DeserializeBocHex(string(bs))
+	if err != nil {
+		t.Fatalf("DeserializeBocHex() failed: %v", err)
+	}
+	var m HashmapAugE[Bits256, AccountBlock, CurrencyCollection]
+	if err = Unmarshal(cell[0], &m); err != nil {
+		t.Fatalf("Unmarshal() failed: %v", err)
+	}
+	decoded := boc.NewCell()
+	if err = Marshal(decoded, m); err != nil {
+		t.Fatalf("Marshal() failed: %v", err)
+	}
+	wantHash, err := cell[0].Hash256()
+	if err != nil {
+		t.Fatalf("Hash256() failed: %v", err)
+	}
+	hash, err := decoded.Hash256()
+	if err != nil {
+		t.Fatalf("Hash256() failed: %v", err)
+	}
+	if hash != wantHash {
+		t.Fatalf("decoded hashmap: want hash %x, got %x", wantHash, hash)
+	}
+	// extra values of forks are recomputed for a new hashmap.
+	constructed := boc.NewCell()
+	h := NewHashmapAugE(m.Keys(), m.Values(), m.Extras(), nil)
+	if err = Marshal(constructed, h); err != nil {
+		t.Fatalf("Marshal() failed: %v", err)
+	}
+	hash, err = constructed.Hash256()
+	if err != nil {
+		t.Fatalf("Hash256() failed: %v", err)
+	}
+	if hash != wantHash {
+		t.Fatalf("constructed hashmap: want hash %x, got %x", wantHash, hash)
+	}
+}
+
+func TestWalkHashmap(t *testing.T) {
+	keys := []Uint16{0x0001, 0x0002, 0x8001, 0x8002, 0xff00}
+	values := []Uint32{1, 2, 3, 4, 5}
+	valueOf := make(map[Uint16]Uint32)
+	for i, k := range keys {
+		valueOf[k] = values[i]
+	}
+	root := boc.NewCell()
+	if err := Marshal(root, NewHashmap(keys, values)); err != nil {
+		t.Fatalf("Marshal() failed: %v", err)
+	}
+	// keys 0x8001, 0x8002 and 0xff00 are hidden by the pruned right branch of the root.
+	prover, err := boc.NewMerkleProver(root)
+	if err != nil {
+		t.Fatalf("NewMerkleProver() failed: %v", err)
+	}
+	cursor := prover.Cursor()
+	cursor.Ref(1).Prune()
+	proof, err := prover.CreateProof(cursor)
+	if err != nil {
+		t.Fatalf("CreateProof() failed: %v", err)
+	}
+	proofRoot, err := boc.DeserializeSingleRootBoc(proof)
+	if err != nil {
+		t.Fatalf("DeserializeSingleRootBoc() failed: %v", err)
+	}
+	pruned := proofRoot.Refs()[0]
+
+	key := func(k uint16) *boc.BitString {
+		bs := boc.NewBitString(16)
+		if err := bs.WriteUint(uint64(k), 16); err != nil {
+			t.Fatalf("WriteUint() failed: %v", err)
+		}
+		return &bs
+	}
+	tests := []struct {
+		name    string
+		cell    *boc.Cell
+		after   *boc.BitString
+		reverse bool
+		limit   int
+		want    []Uint16
+		wantErr error
+	}{
+		{name: "all keys", cell: root, want: keys},
+		{name: "after a key", cell: root, after: key(0x0002), want: []Uint16{0x8001, 0x8002, 0xff00}},
+		{name: "after a missing key", cell: root, after: key(0x8000), want: []Uint16{0x8001, 0x8002, 0xff00}},
+		{name: "after the last key", cell: root, after: key(0xff00)},
+		{name: "limit", cell: root, limit: 3, want: []Uint16{0x0001, 0x0002, 0x8001}},
+		{name: "reverse", cell: root, reverse: true, want: []Uint16{0xff00, 0x8002, 0x8001, 0x0002, 0x0001}},
+		{name: "reverse after a key", cell: root, after: key(0x8002), reverse: true, want: []Uint16{0x8001, 0x0002, 0x0001}},
+		{name: "pruned", cell: pruned, want: []Uint16{0x0001, 0x0002}, wantErr: ErrPrunedKeyPath},
+		{name: "pruned after a key", cell: pruned, after: key(0x0002), wantErr: ErrPrunedKeyPath},
+		{name: "pruned with limit", cell: pruned, limit: 2, want: []Uint16{0x0001, 0x0002}},
+		{name: "pruned reverse", cell: pruned, after: key(0x7fff), reverse: true, want: []Uint16{0x0002, 0x0001}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []Uint16
+			err := WalkHashmap(16, tt.cell, tt.after, tt.reverse, func(key boc.BitString, leaf *boc.Cell) (bool, error) {
+				k, err := key.ReadUint(16)
+				if err != nil {
+					return false, err
+				}
+				var value Uint32
+				if err := Unmarshal(leaf, &value); err != nil {
+					return false, err
+				}
+				if value != valueOf[Uint16(k)] {
+					return false, fmt.Errorf("key %x: value mismatch", k)
+				}
+				got = append(got, Uint16(k))
+				return tt.limit == 0 || len(got) < tt.limit, nil
+			})
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("want error: %v, got: %v", tt.wantErr, err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("want keys: %x, got: %x", tt.want, got)
+			}
+		})
+	}
+}
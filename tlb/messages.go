@@ -537,6 +537,20 @@ type ImportFees struct {
 	ValueImported CurrencyCollection
 }
 
+// Combine returns a sum of two ImportFees values.
+// It is used as an augmentation of InMsgDescr.
+func (f ImportFees) Combine(right ImportFees) (ImportFees, error) {
+	fees := f.FeesCollected + right.FeesCollected
+	if fees < f.FeesCollected {
+		return ImportFees{}, ErrGramsOverflow
+	}
+	value, err := f.ValueImported.Combine(right.ValueImported)
+	if err != nil {
+		return ImportFees{}, err
+	}
+	return ImportFees{FeesCollected: fees, ValueImported: value}, nil
+}
+
 // msg_export_ext$000 msg:^(Message Any)
 //
 //	transaction:^Transaction = OutMsg;
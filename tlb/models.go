@@ -6,6 +6,8 @@ import (
 	"errors"
 	"fmt"
 	"math/big"
+	"math/bits"
+	"sort"
 	"strconv"
 	"strings"
 	"unicode/utf8"
@@ -148,6 +150,43 @@ func (f ExtraCurrencyCollection) MarshalJSON() ([]byte, error) {
 	return f.Dict.MarshalJSON()
 }
 
+// Combine returns a sum of two currency collections.
+// It is used as an augmentation of HashmapAug.
+func (c CurrencyCollection) Combine(right CurrencyCollection) (CurrencyCollection, error) {
+	grams := c.Grams + right.Grams
+	if grams < c.Grams {
+		return CurrencyCollection{}, ErrGramsOverflow
+	}
+	other, err := c.Other.Combine(right.Other)
+	if err != nil {
+		return CurrencyCollection{}, err
+	}
+	return CurrencyCollection{Grams: grams, Other: other}, nil
+}
+
+// Combine returns a sum of two extra currency collections.
+func (f ExtraCurrencyCollection) Combine(right ExtraCurrencyCollection) (ExtraCurrencyCollection, error) {
+	var res ExtraCurrencyCollection
+	for _, item := range f.Dict.Items() {
+		res.Dict.Put(item.Key, item.Value)
+	}
+	for _, item := range right.Dict.Items() {
+		value, ok := res.Dict.Get(item.Key)
+		if !ok {
+			res.Dict.Put(item.Key, item.Value)
+			continue
+		}
+		a, b := big.Int(value), big.Int(item.Value)
+		var sum big.Int
+		sum.Add(&a, &b)
+		if sum.BitLen() > 248 {
+			return ExtraCurrencyCollection{}, fmt.Errorf("extra currency %v overflow", item.Key)
+		}
+		res.Dict.Put(item.Key, VarUInteger32(sum))
+	}
+	return res, nil
+}
+
 // HashUpdate
 // update_hashes#72 {X:Type} old_hash:bits256 new_hash:bits256
 // = HASH_UPDATE X;
@@ -363,9 +402,9 @@ type ShardDesc struct {
 		NextValidatorShard int64
 		MinRefMcSeqNo      uint32
 		GenUTime           uint32
-		//todo: add   split_merge_at:FutureSplitMerge
-		//  ^[ fees_collected:CurrencyCollection
-		//     funds_created:CurrencyCollection ]
+		SplitMergeAt       FutureSplitMerge
+		FeesCollected      CurrencyCollection
+		FundsCreated       CurrencyCollection
 	} `tlbSumType:"old#b"`
 	New struct {
 		SeqNo              uint32
@@ -384,9 +423,11 @@ type ShardDesc struct {
 		NextValidatorShard int64
 		MinRefMcSeqNo      uint32
 		GenUTime           uint32
-		//todo: add   split_merge_at:FutureSplitMerge
-		//  fees_collected:CurrencyCollection
-		//  funds_created:CurrencyCollection
+		SplitMergeAt       FutureSplitMerge
+		Fees               struct {
+			FeesCollected CurrencyCollection
+			FundsCreated  CurrencyCollection
+		} `tlb:"^"`
 	} `tlbSumType:"new#a"`
 }
 
@@ -397,10 +438,102 @@ func (d *ShardDesc) SeqNo() uint32 {
 	return d.Old.SeqNo
 }
 
+// FutureSplitMerge
+// fsm_none$0 = FutureSplitMerge;
+// fsm_split$10 split_utime:uint32 interval:uint32 = FutureSplitMerge;
+// fsm_merge$11 merge_utime:uint32 interval:uint32 = FutureSplitMerge;
+type FutureSplitMerge struct {
+	SumType
+	FsmNone  struct{} `tlbSumType:"fsm_none$0"`
+	FsmSplit struct {
+		SplitUtime uint32
+		Interval   uint32
+	} `tlbSumType:"fsm_split$10"`
+	FsmMerge struct {
+		MergeUtime uint32
+		Interval   uint32
+	} `tlbSumType:"fsm_merge$11"`
+}
+
 type ShardInfoBinTree struct {
 	BinTree BinTree[ShardDesc]
 }
 
+// NewShardInfoBinTree returns a tree of shards of a workchain.
+// A shard ID is a shard prefix followed by a tag bit, 0x8000000000000000 is a workchain without splits.
+// Shards must cover the whole workchain without overlapping.
+func NewShardInfoBinTree(shards map[uint64]ShardDesc) (ShardInfoBinTree, error) {
+	if len(shards) == 0 {
+		return ShardInfoBinTree{}, fmt.Errorf("at least one shard required")
+	}
+	shardIDs := make([]uint64, 0, len(shards))
+	for shardID := range shards {
+		shardIDs = append(shardIDs, shardID)
+	}
+	// leaves of a bin tree go from left to right which is the ascending order of shard IDs.
+	sort.Slice(shardIDs, func(i, j int) bool { return shardIDs[i] < shardIDs[j] })
+	prefixes := make([]boc.BitString, 0, len(shardIDs))
+	descs := make([]ShardDesc, 0, len(shardIDs))
+	// next is the first account ID prefix which is not covered by the previous shards yet.
+	var next uint64
+	for i, shardID := range shardIDs {
+		prefix, err := shardIDToPrefix(shardID)
+		if err != nil {
+			return ShardInfoBinTree{}, err
+		}
+		tag := shardID & -shardID
+		if shardID-tag != next || (i > 0 && next == 0) {
+			return ShardInfoBinTree{}, fmt.Errorf("shard %x overlaps other shards or leaves a gap before it", shardID)
+		}
+		next = shardID + tag
+		prefixes = append(prefixes, prefix)
+		descs = append(descs, shards[shardID])
+	}
+	if next != 0 {
+		return ShardInfoBinTree{}, fmt.Errorf("shards don't cover the whole workchain")
+	}
+	return ShardInfoBinTree{BinTree: NewBinTree(descs, prefixes)}, nil
+}
+
+// ShardIDs returns IDs of shards of this tree.
+// A shard ID at index "i" corresponds to a shard description at the same index of BinTree.Values.
+func (t ShardInfoBinTree) ShardIDs() []uint64 {
+	prefixes := t.BinTree.Prefixes()
+	if len(prefixes) == 0 && len(t.BinTree.Values) == 1 {
+		return []uint64{1 << 63}
+	}
+	shardIDs := make([]uint64, 0, len(prefixes))
+	for _, prefix := range prefixes {
+		shardIDs = append(shardIDs, prefixToShardID(prefix))
+	}
+	return shardIDs
+}
+
+func shardIDToPrefix(shardID uint64) (boc.BitString, error) {
+	if shardID == 0 {
+		return boc.BitString{}, fmt.Errorf("at least one non-zero bit required in shard id")
+	}
+	ln := 63 - bits.TrailingZeros64(shardID)
+	prefix := boc.NewBitString(ln)
+	if ln == 0 {
+		return prefix, nil
+	}
+	if err := prefix.WriteUint(shardID>>(64-ln), ln); err != nil {
+		return boc.BitString{}, err
+	}
+	return prefix, nil
+}
+
+func prefixToShardID(prefix boc.BitString) uint64 {
+	var shardID uint64
+	for i, bit := range bitStringToBits(prefix) {
+		if bit {
+			shardID |= 1 << (63 - i)
+		}
+	}
+	return shardID | 1<<(63-prefix.GetWriteCursor())
+}
+
 type AllShardsInfo struct {
 	ShardHashes HashmapE[Uint32, Ref[ShardInfoBinTree]]
 }
@@ -279,6 +279,30 @@ func TestHashmapAug(t *testing.T) {
 		if !equal(h.H.Values(), c.values) {
 			t.Fatal("invalid values", c.Name)
 		}
+		cells[0].ResetCounters()
+		wantHash, err := cells[0].Hash256()
+		if err != nil {
+			t.Fatal(c.Name, err)
+		}
+		decoded := boc.NewCell()
+		if err = Marshal(decoded, h); err != nil {
+			t.Fatal(c.Name, err)
+		}
+		if hash, _ := decoded.Hash256(); hash != wantHash {
+			t.Fatal("invalid hash of decoded hashmap", c.Name)
+		}
+		h.H = NewHashmapAugE(h.H.Keys(), h.H.Values(), h.H.Extras(), func(left, right int32) (int32, error) {
+			return left + right, nil
+		})
+		constructed := boc.NewCell()
+		if err = Marshal(constructed, h); err != nil {
+			t.Fatal(c.Name, err)
+		}
+		if len(c.values) > 0 {
+			if hash, _ := constructed.Hash256(); hash != wantHash {
+				t.Fatal("invalid hash of constructed hashmap", c.Name)
+			}
+		}
 	}
 }
 
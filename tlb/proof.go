@@ -15,6 +15,10 @@ type MerkleProof[T any] struct {
 	VirtualRoot T `tlb:"^"`
 }
 
+// MerkleUpdate
+// !merkle_update#04 {X:Type} from_hash:bits256 to_hash:bits256 from_depth:uint16 to_depth:uint16 from_root:^X to_root:^X = MERKLE_UPDATE X;
+//
+// A decoded MerkleUpdate keeps its source cell and is marshaled as that cell, see Cell.
 type MerkleUpdate[T any] struct {
 	Magic     Magic `tlb:"!merkle_update#04"`
 	FromHash  Bits256
@@ -23,6 +27,128 @@ type MerkleUpdate[T any] struct {
 	ToDepth   uint16
 	FromRoot  T `tlb:"^"`
 	ToRoot    T `tlb:"^"`
+
+	// cell is a source cell of a decoded merkle update.
+	cell *boc.Cell
+	// header keeps the decoded fields except for the roots,
+	// so their changes are detected without re-encoding the roots.
+	header merkleUpdateHeader
+}
+
+type merkleUpdateHeader struct {
+	Magic     Magic
+	FromHash  Bits256
+	ToHash    Bits256
+	FromDepth uint16
+	ToDepth   uint16
+}
+
+type merkleUpdateFields[T any] struct {
+	Magic     Magic `tlb:"!merkle_update#04"`
+	FromHash  Bits256
+	ToHash    Bits256
+	FromDepth uint16
+	ToDepth   uint16
+	FromRoot  T `tlb:"^"`
+	ToRoot    T `tlb:"^"`
+}
+
+func (m *MerkleUpdate[T]) getHeader() merkleUpdateHeader {
+	return merkleUpdateHeader{
+		Magic:     m.Magic,
+		FromHash:  m.FromHash,
+		ToHash:    m.ToHash,
+		FromDepth: m.FromDepth,
+		ToDepth:   m.ToDepth,
+	}
+}
+
+func (m *MerkleUpdate[T]) UnmarshalTLB(c *boc.Cell, decoder *Decoder) error {
+	var data merkleUpdateFields[T]
+	if err := decoder.Unmarshal(c, &data); err != nil {
+		return err
+	}
+	*m = MerkleUpdate[T]{
+		Magic:     data.Magic,
+		FromHash:  data.FromHash,
+		ToHash:    data.ToHash,
+		FromDepth: data.FromDepth,
+		ToDepth:   data.ToDepth,
+		FromRoot:  data.FromRoot,
+		ToRoot:    data.ToRoot,
+		cell:      c,
+	}
+	m.header = m.getHeader()
+	return nil
+}
+
+// Cell returns the source cell of a decoded merkle update or nil if the merkle update has been constructed.
+//
+// The roots of a decoded merkle update usually contain pruned branches which are skipped during decoding,
+// so a decoded merkle update is marshaled as its source cell instead of its fields.
+// FromRoot and ToRoot of a decoded merkle update must not be mutated because such changes are not marshaled.
+// To encode modified roots, copy the fields to a new MerkleUpdate, it is marshaled from its fields.
+func (m MerkleUpdate[T]) Cell() *boc.Cell {
+	return m.cell
+}
+
+// MarshalTLB writes the source cell of a decoded merkle update as is, see Cell.
+// It returns an error if the hashes or depths of a decoded merkle update have been changed.
+// A constructed merkle update is encoded from its fields.
+func (m MerkleUpdate[T]) MarshalTLB(c *boc.Cell, encoder *Encoder) error {
+	if m.cell != nil {
+		if m.getHeader() != m.header {
+			return fmt.Errorf("merkle update has been modified after decoding")
+		}
+		*c = *m.cell
+		c.ResetCounters()
+		return nil
+	}
+	cell := boc.NewCellExotic(boc.MerkleUpdateCell)
+	err := cell.WriteUint(0x04, 8)
+	if err != nil {
+		return err
+	}
+	for _, v := range []any{m.FromHash, m.ToHash, m.FromDepth, m.ToDepth} {
+		if err := encoder.Marshal(cell, v); err != nil {
+			return err
+		}
+	}
+	for _, root := range []T{m.FromRoot, m.ToRoot} {
+		ref, err := cell.NewRef()
+		if err != nil {
+			return err
+		}
+		if err := encoder.Marshal(ref, root); err != nil {
+			return err
+		}
+	}
+	*c = *cell
+	return nil
+}
+
+// NewMerkleUpdate returns a MerkleUpdate which transforms the "from" cell tree into the "to" cell tree.
+// See boc.CreateMerkleUpdate for details.
+func NewMerkleUpdate[T any](from, to *boc.Cell) (MerkleUpdate[T], error) {
+	cell, err := boc.CreateMerkleUpdate(from, to)
+	if err != nil {
+		return MerkleUpdate[T]{}, err
+	}
+	var update MerkleUpdate[T]
+	if err := Unmarshal(cell, &update); err != nil {
+		return MerkleUpdate[T]{}, err
+	}
+	return update, nil
+}
+
+// Apply applies this merkle update to the "from" cell tree and returns a new cell tree.
+// Both FromHash and ToHash are checked, see boc.ApplyMerkleUpdate for details.
+func (m MerkleUpdate[T]) Apply(from *boc.Cell) (*boc.Cell, error) {
+	update := boc.NewCell()
+	if err := Marshal(update, m); err != nil {
+		return nil, err
+	}
+	return boc.ApplyMerkleUpdate(from, update)
 }
 
 // ShardStateUnsplit
@@ -122,6 +248,33 @@ func (s *ShardState) UnmarshalTLB(c *boc.Cell, decoder *Decoder) error {
 	return nil
 }
 
+func (s ShardState) MarshalTLB(c *boc.Cell, encoder *Encoder) error {
+	switch s.SumType {
+	case "SplitState":
+		err := c.WriteUint(0x5f327da5, 32)
+		if err != nil {
+			return err
+		}
+		c1, err := c.NewRef()
+		if err != nil {
+			return err
+		}
+		err = encoder.Marshal(c1, s.SplitState.Left)
+		if err != nil {
+			return err
+		}
+		c1, err = c.NewRef()
+		if err != nil {
+			return err
+		}
+		return encoder.Marshal(c1, s.SplitState.Right)
+	case "UnsplitState":
+		return encoder.Marshal(c, s.UnsplitState.Value)
+	default:
+		return fmt.Errorf("invalid sum type: %v", s.SumType)
+	}
+}
+
 func (s *ShardState) AccountBalances() map[Bits256]CurrencyCollection {
 	switch s.SumType {
 	case "UnsplitState":
@@ -227,6 +380,36 @@ func (cr *CryptoSignature) UnmarshalTLB(c *boc.Cell, decoder *Decoder) error {
 	return nil
 }
 
+func (cr CryptoSignature) MarshalTLB(c *boc.Cell, encoder *Encoder) error {
+	switch cr.SumType {
+	case "CryptoSignatureSimple":
+		err := c.WriteUint(0x5, 4)
+		if err != nil {
+			return err
+		}
+		return encoder.Marshal(c, cr.CryptoSignatureSimple)
+	case "CryptoSignature":
+		err := c.WriteUint(0xf, 4)
+		if err != nil {
+			return err
+		}
+		if cr.CryptoSignature.SignedCert == nil {
+			return fmt.Errorf("signed certificate is required")
+		}
+		c1, err := c.NewRef()
+		if err != nil {
+			return err
+		}
+		err = encoder.Marshal(c1, *cr.CryptoSignature.SignedCert)
+		if err != nil {
+			return err
+		}
+		return encoder.Marshal(c, cr.CryptoSignature.TempKeySignature)
+	default:
+		return fmt.Errorf("invalid sum type: %v", cr.SumType)
+	}
+}
+
 // signed_certificate$_ certificate:Certificate certificate_signature:CryptoSignature
 //
 //	= SignedCertificate;  // 356+516 = 872 bits
@@ -250,6 +433,20 @@ type ShardFeeCreated struct {
 	Create CurrencyCollection
 }
 
+// Combine returns a sum of two ShardFeeCreated values.
+// It is used as an augmentation of ShardFees.
+func (s ShardFeeCreated) Combine(right ShardFeeCreated) (ShardFeeCreated, error) {
+	fees, err := s.Fees.Combine(right.Fees)
+	if err != nil {
+		return ShardFeeCreated{}, err
+	}
+	create, err := s.Create.Combine(right.Create)
+	if err != nil {
+		return ShardFeeCreated{}, err
+	}
+	return ShardFeeCreated{Fees: fees, Create: create}, nil
+}
+
 // _ (HashmapAugE 96 ShardFeeCreated ShardFeeCreated) = ShardFees;
 type ShardFees struct {
 	Hashmap HashmapAugE[Bits96, ShardFeeCreated, ShardFeeCreated]
@@ -284,6 +481,16 @@ type DepthBalanceInfo struct {
 	Balance    CurrencyCollection
 }
 
+// Combine returns an augmentation of a ShardAccounts fork:
+// split_depth is always zero and balance is a sum of both branches.
+func (d DepthBalanceInfo) Combine(right DepthBalanceInfo) (DepthBalanceInfo, error) {
+	balance, err := d.Balance.Combine(right.Balance)
+	if err != nil {
+		return DepthBalanceInfo{}, err
+	}
+	return DepthBalanceInfo{Balance: balance}, nil
+}
+
 // ^[ overload_history:uint64 underload_history:uint64
 // total_balance:CurrencyCollection
 // total_validator_fees:CurrencyCollection
@@ -405,12 +612,48 @@ func (m *McStateExtraOther) UnmarshalTLB(c *boc.Cell, decoder *Decoder) error {
 	return nil
 }
 
+func (m McStateExtraOther) MarshalTLB(c *boc.Cell, encoder *Encoder) error {
+	err := c.WriteUint(uint64(m.Flags), 16)
+	if err != nil {
+		return err
+	}
+	err = encoder.Marshal(c, m.ValidatorInfo)
+	if err != nil {
+		return err
+	}
+	err = encoder.Marshal(c, m.PrevBlocks)
+	if err != nil {
+		return err
+	}
+	err = encoder.Marshal(c, m.AfterKeyBlock)
+	if err != nil {
+		return err
+	}
+	err = encoder.Marshal(c, m.LastKeyBlock)
+	if err != nil {
+		return err
+	}
+	if m.Flags == 1 {
+		return encoder.Marshal(c, m.BlockCreateStats)
+	}
+	return nil
+}
+
 // _ key:Bool max_end_lt:uint64 = KeyMaxLt;
 type KeyMaxLt struct {
 	Key      bool
 	MaxEndLt uint64
 }
 
+// Combine returns an augmentation of an OldMcBlocksInfo fork.
+func (k KeyMaxLt) Combine(right KeyMaxLt) (KeyMaxLt, error) {
+	maxEndLt := k.MaxEndLt
+	if right.MaxEndLt > maxEndLt {
+		maxEndLt = right.MaxEndLt
+	}
+	return KeyMaxLt{Key: k.Key || right.Key, MaxEndLt: maxEndLt}, nil
+}
+
 // _ key:Bool blk_ref:ExtBlkRef = KeyExtBlkRef;
 type KeyExtBlkRef struct {
 	Key    bool
@@ -429,6 +672,23 @@ type BlockCreateStats struct {
 	} `tlbSumType:"block_create_stats_ext#34"`
 }
 
+// Items returns statistics of all block creators ordered by their public keys.
+func (s BlockCreateStats) Items() []HashmapItem[Bits256, CreatorStats] {
+	switch s.SumType {
+	case "BlockCreateStats":
+		return s.BlockCreateStats.Counters.Items()
+	case "BlockCreateStatsExt":
+		keys := s.BlockCreateStatsExt.Counters.Keys()
+		values := s.BlockCreateStatsExt.Counters.Values()
+		items := make([]HashmapItem[Bits256, CreatorStats], 0, len(keys))
+		for i, key := range keys {
+			items = append(items, HashmapItem[Bits256, CreatorStats]{Key: key, Value: values[i]})
+		}
+		return items
+	}
+	return nil
+}
+
 // creator_info#4 mc_blocks:Counters shard_blocks:Counters = CreatorStats;
 type CreatorStats struct {
 	Magic       Magic `tlb:"creator_info#4"`
@@ -444,3 +704,8 @@ type Counters struct {
 	Cnt2048     uint64
 	Cnt65536    uint64
 }
+
+// ModifiedSince returns true if the counters were updated at or after the given unix time.
+func (c Counters) ModifiedSince(utime uint32) bool {
+	return c.LastUpdated >= utime
+}
@@ -0,0 +1,212 @@
+package tlb
+
+import (
+	"fmt"
+
+	"github.com/tonkeeper/tongo/boc"
+)
+
+// ProofPath reads a part of a cell tree which has to be proven.
+// Every cell accessed with Cell.NextRef or Cell.RefAt ends up in the proof,
+// use CellUsage.Keep to include a whole subtree.
+type ProofPath func(root *boc.Cell, usage *boc.CellUsage) error
+
+// CreateMerkleProof creates a merkle proof of the given cell tree containing all cells read by the given paths.
+// Other cells are replaced with pruned branch cells,
+// so the proof is decoded with Unmarshal the same way as the original tree.
+func CreateMerkleProof(root *boc.Cell, paths ...ProofPath) ([]byte, error) {
+	usage := boc.NewCellUsage(root)
+	for _, path := range paths {
+		root.ResetCounters()
+		if err := path(root, usage); err != nil {
+			return nil, err
+		}
+		usage.Collect()
+	}
+	return usage.CreateProof()
+}
+
+// DecodePath proves all data of the tree decoded as T.
+func DecodePath[T any]() ProofPath {
+	return func(root *boc.Cell, usage *boc.CellUsage) error {
+		var value T
+		return Unmarshal(root, &value)
+	}
+}
+
+// shardStateRefs contains refs of ShardStateUnsplit read by proof paths.
+type shardStateRefs struct {
+	Accounts *boc.Cell
+	// Custom is nil for a shardchain state.
+	Custom *boc.Cell
+}
+
+// decodeShardStateRefs returns refs of an unsplit state or both halves of a split state.
+// Refs not required by a path are skipped, so they end up pruned in a proof.
+func decodeShardStateRefs(root *boc.Cell, usage *boc.CellUsage) ([]shardStateRefs, error) {
+	tag, err := root.PickUint(32)
+	if err != nil {
+		return nil, err
+	}
+	cells := []*boc.Cell{root}
+	if tag == 0x5f327da5 {
+		// split_state#5f327da5 left:^ShardStateUnsplit right:^ShardStateUnsplit = ShardState;
+		if _, err := root.ReadUint(32); err != nil {
+			return nil, err
+		}
+		cells = cells[:0]
+		for i := 0; i < 2; i++ {
+			c, err := root.NextRef()
+			if err != nil {
+				return nil, err
+			}
+			cells = append(cells, c)
+		}
+	}
+	var res []shardStateRefs
+	for _, c := range cells {
+		if c.CellType() == boc.PrunedBranchCell {
+			continue
+		}
+		// shard_state#9023afe2 ... out_msg_queue_info:^OutMsgQueueInfo before_split:(## 1)
+		// accounts:^ShardAccounts ^[...] custom:(Maybe ^McStateExtra)
+		var header struct {
+			Magic         Magic `tlb:"shard_state#9023afe2"`
+			GlobalID      int32
+			ShardID       ShardIdent
+			SeqNo         uint32
+			VertSeqNo     uint32
+			GenUtime      uint32
+			GenLt         uint64
+			MinRefMcSeqno uint32
+			BeforeSplit   bool
+			CustomExists  bool
+		}
+		if err := Unmarshal(c, &header); err != nil {
+			return nil, err
+		}
+		if c.RefsSize() < 3 {
+			return nil, fmt.Errorf("invalid shard state")
+		}
+		refs := shardStateRefs{Accounts: c.Refs()[1]}
+		usage.Visit(refs.Accounts)
+		if header.CustomExists {
+			if c.RefsSize() != 4 {
+				return nil, fmt.Errorf("invalid shard state")
+			}
+			refs.Custom = c.Refs()[3]
+			usage.Visit(refs.Custom)
+		}
+		res = append(res, refs)
+	}
+	return res, nil
+}
+
+// ShardAccountPath proves the presence or the absence of an account in a ShardState.
+// The account's state is included into the proof.
+func ShardAccountPath(address Bits256) ProofPath {
+	return func(root *boc.Cell, usage *boc.CellUsage) error {
+		states, err := decodeShardStateRefs(root, usage)
+		if err != nil {
+			return err
+		}
+		for _, state := range states {
+			accounts := ShardAccounts{cell: state.Accounts}
+			leaf, err := accounts.Lookup(address)
+			if err != nil {
+				return err
+			}
+			if leaf == nil {
+				continue
+			}
+			// account_descr$_ account:^Account last_trans_hash:bits256 last_trans_lt:uint64 = ShardAccount;
+			account, err := leaf.NextRef()
+			if err != nil {
+				return err
+			}
+			usage.Keep(account)
+		}
+		return nil
+	}
+}
+
+// ConfigParamPath proves the presence or the absence of config params in a masterchain ShardState.
+// Values of present params are included into the proof.
+func ConfigParamPath(params ...uint32) ProofPath {
+	return func(root *boc.Cell, usage *boc.CellUsage) error {
+		states, err := decodeShardStateRefs(root, usage)
+		if err != nil {
+			return err
+		}
+		if len(states) != 1 || states[0].Custom == nil {
+			return fmt.Errorf("not a masterchain state")
+		}
+		extra := states[0].Custom
+		if extra.CellType() == boc.PrunedBranchCell {
+			return ErrPrunedKeyPath
+		}
+		// masterchain_state_extra#cc26 shard_hashes:ShardHashes config:ConfigParams ...
+		// _ config_addr:bits256 config:^(Hashmap 32 ^Cell) = ConfigParams;
+		var header struct {
+			Magic             Magic `tlb:"masterchain_state_extra#cc26"`
+			ShardHashesExists bool
+			ConfigAddr        Bits256
+		}
+		if err := Unmarshal(extra, &header); err != nil {
+			return err
+		}
+		index := 0
+		if header.ShardHashesExists {
+			index = 1
+		}
+		if extra.RefsSize() <= index {
+			return fmt.Errorf("invalid masterchain state extra")
+		}
+		config := extra.Refs()[index]
+		usage.Visit(config)
+		for _, param := range params {
+			config.ResetCounters()
+			key := boc.NewBitString(32)
+			if err := key.WriteUint(uint64(param), 32); err != nil {
+				return err
+			}
+			leaf, err := lookupHashmap(32, config, key)
+			if err != nil {
+				return err
+			}
+			if leaf == nil {
+				continue
+			}
+			value, err := leaf.NextRef()
+			if err != nil {
+				return err
+			}
+			usage.Keep(value)
+		}
+		return nil
+	}
+}
+
+// VerifyMerkleProof checks that the given bag of cells contains a merkle proof of a cell tree with the given hash
+// and decodes its virtual root.
+// The bag of cells can contain several roots, the proof is chosen by its virtual hash.
+// Pruned branches are skipped during decoding, so the corresponding fields are left empty.
+func VerifyMerkleProof[T any](proofBoc []byte, hash Bits256) (T, error) {
+	var root T
+	cells, err := boc.DeserializeBoc(proofBoc)
+	if err != nil {
+		return root, err
+	}
+	cell, err := boc.FindMerkleProof(cells, hash)
+	if err != nil {
+		return root, err
+	}
+	var proof MerkleProof[T]
+	if err := Unmarshal(cell, &proof); err != nil {
+		return root, err
+	}
+	if proof.VirtualHash != hash {
+		return root, fmt.Errorf("merkle proof virtual hash mismatch")
+	}
+	return proof.VirtualRoot, nil
+}
@@ -0,0 +1,204 @@
+package tlb
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/tonkeeper/tongo/boc"
+)
+
+// newTestMasterchainState returns a masterchain state with the given number of accounts and config params 0..params-1.
+// Only fields read by proof paths are filled.
+func newTestMasterchainState(t *testing.T, accounts int, params int) (*boc.Cell, []Bits256) {
+	var addresses []Bits256
+	var values []ShardAccount
+	var extras []DepthBalanceInfo
+	for i := 0; i < accounts; i++ {
+		addresses = append(addresses, Bits256{byte(i * 256 / accounts), 1})
+		values = append(values, ShardAccount{
+			Account:       Account{SumType: "AccountNone"},
+			LastTransHash: Bits256{byte(i)},
+			LastTransLt:   uint64(i),
+		})
+		extras = append(extras, DepthBalanceInfo{})
+	}
+	var keys []Uint32
+	var configValues []Ref[boc.Cell]
+	for i := 0; i < params; i++ {
+		value := boc.NewCell()
+		if err := value.WriteUint(uint64(i), 32); err != nil {
+			t.Fatalf("WriteUint() failed: %v", err)
+		}
+		keys = append(keys, Uint32(i))
+		configValues = append(configValues, Ref[boc.Cell]{Value: *value})
+	}
+	type mcStateExtra struct {
+		Magic         Magic `tlb:"masterchain_state_extra#cc26"`
+		ShardHashes   HashmapE[Uint32, Ref[boc.Cell]]
+		Config        ConfigParams
+		Other         boc.Cell `tlb:"^"`
+		GlobalBalance CurrencyCollection
+	}
+	state := struct {
+		Magic           Magic `tlb:"shard_state#9023afe2"`
+		GlobalID        int32
+		ShardID         ShardIdent
+		SeqNo           uint32
+		VertSeqNo       uint32
+		GenUtime        uint32
+		GenLt           uint64
+		MinRefMcSeqno   uint32
+		OutMsgQueueInfo boc.Cell `tlb:"^"`
+		BeforeSplit     bool
+		Accounts        HashmapAugE[Bits256, ShardAccount, DepthBalanceInfo] `tlb:"^"`
+		Other           boc.Cell                                             `tlb:"^"`
+		Custom          Maybe[Ref[mcStateExtra]]
+	}{
+		GlobalID: -239,
+		ShardID:  ShardIdent{WorkchainID: -1, ShardPrefix: 1 << 63},
+		SeqNo:    100,
+		Accounts: NewHashmapAugE(addresses, values, extras, DepthBalanceInfo.Combine),
+	}
+	state.Custom.Exists = true
+	state.Custom.Value.Value.Config.Config = NewHashmap(keys, configValues)
+	cell := boc.NewCell()
+	if err := Marshal(cell, state); err != nil {
+		t.Fatalf("Marshal() failed: %v", err)
+	}
+	return cell, addresses
+}
+
+func TestCreateMerkleProof(t *testing.T) {
+	state, addresses := newTestMasterchainState(t, 64, 40)
+	hash, err := state.Hash256()
+	if err != nil {
+		t.Fatalf("Hash256() failed: %v", err)
+	}
+	fullState, err := state.ToBoc()
+	if err != nil {
+		t.Fatalf("ToBoc() failed: %v", err)
+	}
+	missing := addresses[5]
+	missing[31] = 0xff
+
+	proof, err := CreateMerkleProof(state,
+		ShardAccountPath(addresses[3]),
+		ShardAccountPath(addresses[40]),
+		ShardAccountPath(missing),
+		ConfigParamPath(34, 100))
+	if err != nil {
+		t.Fatalf("CreateMerkleProof() failed: %v", err)
+	}
+	if len(proof) > len(fullState)/2 {
+		t.Fatalf("proof is too big: %v bytes, full state: %v bytes", len(proof), len(fullState))
+	}
+
+	lazyState, err := VerifyMerkleProof[LazyShardState](proof, hash)
+	if err != nil {
+		t.Fatalf("VerifyMerkleProof() failed: %v", err)
+	}
+	accounts := lazyState.ShardAccounts[0]
+	for _, i := range []int{3, 40} {
+		leaf, err := accounts.Lookup(addresses[i])
+		if err != nil {
+			t.Fatalf("Lookup() failed: %v", err)
+		}
+		if leaf == nil {
+			t.Fatalf("account %v not found", i)
+		}
+		var account ShardAccount
+		if err := Unmarshal(leaf, &account); err != nil {
+			t.Fatalf("Unmarshal() failed: %v", err)
+		}
+		if account.LastTransLt != uint64(i) || account.Account.SumType != "AccountNone" {
+			t.Fatalf("account %v mismatch", i)
+		}
+	}
+	leaf, err := accounts.Lookup(missing)
+	if err != nil || leaf != nil {
+		t.Fatalf("want missing account, got: %v, %v", leaf, err)
+	}
+	if _, err := accounts.Lookup(addresses[20]); !errors.Is(err, ErrPrunedKeyPath) {
+		t.Fatalf("want ErrPrunedKeyPath, got: %v", err)
+	}
+
+	shardState, err := VerifyMerkleProof[ShardState](proof, hash)
+	if err != nil {
+		t.Fatalf("VerifyMerkleProof() failed: %v", err)
+	}
+	config := shardState.UnsplitState.Value.ShardStateUnsplit.Custom.Value.Value.Config.Config
+	if keys := config.Keys(); !reflect.DeepEqual(keys, []Uint32{34}) {
+		t.Fatalf("want config keys: [34], got: %v", keys)
+	}
+	param, ok := config.Get(34)
+	if !ok {
+		t.Fatalf("config param 34 not found")
+	}
+	if value, err := param.Value.ReadUint(32); err != nil || value != 34 {
+		t.Fatalf("want config param value 34, got: %v, %v", value, err)
+	}
+
+	if _, err := VerifyMerkleProof[LazyShardState](proof, Bits256{1}); !errors.Is(err, boc.ErrMerkleProofNotFound) {
+		t.Fatalf("want ErrMerkleProofNotFound, got: %v", err)
+	}
+
+	// the proof can be sent along with other proofs in one bag of cells
+	otherState, _ := newTestMasterchainState(t, 4, 4)
+	otherProof, err := CreateMerkleProof(otherState, ConfigParamPath(1))
+	if err != nil {
+		t.Fatalf("CreateMerkleProof() failed: %v", err)
+	}
+	var roots []*boc.Cell
+	for _, data := range [][]byte{otherProof, proof} {
+		root, err := boc.DeserializeSingleRootBoc(data)
+		if err != nil {
+			t.Fatalf("DeserializeSingleRootBoc() failed: %v", err)
+		}
+		roots = append(roots, root)
+	}
+	multiRoot, err := boc.SerializeBocMulti(roots, false, false, false, 0)
+	if err != nil {
+		t.Fatalf("SerializeBocMulti() failed: %v", err)
+	}
+	lazyState, err = VerifyMerkleProof[LazyShardState](multiRoot, hash)
+	if err != nil {
+		t.Fatalf("VerifyMerkleProof() failed: %v", err)
+	}
+	if leaf, err := lazyState.ShardAccounts[0].Lookup(addresses[3]); err != nil || leaf == nil {
+		t.Fatalf("want account 3 in multi-root proof, got: %v, %v", leaf, err)
+	}
+}
+
+func TestCreateMerkleProof_decodePath(t *testing.T) {
+	state, addresses := newTestMasterchainState(t, 8, 4)
+	hash, err := state.Hash256()
+	if err != nil {
+		t.Fatalf("Hash256() failed: %v", err)
+	}
+	// the test state has no valid out_msg_queue_info, so only accounts are decoded.
+	type accountsOnly struct {
+		Magic           Magic `tlb:"shard_state#9023afe2"`
+		GlobalID        int32
+		ShardID         ShardIdent
+		SeqNo           uint32
+		VertSeqNo       uint32
+		GenUtime        uint32
+		GenLt           uint64
+		MinRefMcSeqno   uint32
+		OutMsgQueueInfo boc.Cell `tlb:"^"`
+		BeforeSplit     bool
+		Accounts        HashmapAugE[Bits256, ShardAccount, DepthBalanceInfo] `tlb:"^"`
+	}
+	proof, err := CreateMerkleProof(state, DecodePath[accountsOnly]())
+	if err != nil {
+		t.Fatalf("CreateMerkleProof() failed: %v", err)
+	}
+	decoded, err := VerifyMerkleProof[accountsOnly](proof, hash)
+	if err != nil {
+		t.Fatalf("VerifyMerkleProof() failed: %v", err)
+	}
+	if !reflect.DeepEqual(decoded.Accounts.Keys(), addresses) {
+		t.Fatalf("accounts mismatch")
+	}
+}
@@ -1,6 +1,7 @@
 package tlb
 
 import (
+	"os"
 	"reflect"
 	"testing"
 
@@ -46,3 +47,143 @@ func TestConfigParams_CloneKeepingSubsetOfKeys(t *testing.T) {
 		})
 	}
 }
+
+func TestMerkleUpdate_Apply(t *testing.T) {
+	data, err := os.ReadFile("testdata/block-1/block.bin")
+	if err != nil {
+		t.Fatalf("ReadFile() failed: %v", err)
+	}
+	cell, err := boc.DeserializeSingleRootBoc(data)
+	if err != nil {
+		t.Fatalf("DeserializeSingleRootBoc() failed: %v", err)
+	}
+	var block Block
+	if err := Unmarshal(cell, &block); err != nil {
+		t.Fatalf("Unmarshal() failed: %v", err)
+	}
+	// the old state in a block is a merkle proof of the state, so it can be used as the old state.
+	from := cell.Refs()[2].Refs()[0]
+	to, err := block.StateUpdate.Apply(from)
+	if err != nil {
+		t.Fatalf("Apply() failed: %v", err)
+	}
+	toHash, err := to.Hash256WithLevel(0)
+	if err != nil {
+		t.Fatalf("Hash256WithLevel() failed: %v", err)
+	}
+	if Bits256(toHash) != block.StateUpdate.ToHash {
+		t.Fatalf("want hash: %x, got: %x", block.StateUpdate.ToHash, toHash)
+	}
+
+	update, err := NewMerkleUpdate[ShardState](from, to)
+	if err != nil {
+		t.Fatalf("NewMerkleUpdate() failed: %v", err)
+	}
+	if update.FromHash != block.StateUpdate.FromHash || update.ToHash != block.StateUpdate.ToHash {
+		t.Fatalf("merkle update hashes mismatch")
+	}
+	if _, err := update.Apply(to); err == nil {
+		t.Fatalf("merkle update must not be applied to another state")
+	}
+	res, err := update.Apply(from)
+	if err != nil {
+		t.Fatalf("Apply() failed: %v", err)
+	}
+	resHash, err := res.Hash256WithLevel(0)
+	if err != nil {
+		t.Fatalf("Hash256WithLevel() failed: %v", err)
+	}
+	if resHash != toHash {
+		t.Fatalf("want hash: %x, got: %x", toHash, resHash)
+	}
+}
+
+func TestMerkleUpdate_MarshalTLB(t *testing.T) {
+	data, err := os.ReadFile("testdata/block-1/block.bin")
+	if err != nil {
+		t.Fatalf("ReadFile() failed: %v", err)
+	}
+	cell, err := boc.DeserializeSingleRootBoc(data)
+	if err != nil {
+		t.Fatalf("DeserializeSingleRootBoc() failed: %v", err)
+	}
+	var block Block
+	if err := Unmarshal(cell, &block); err != nil {
+		t.Fatalf("Unmarshal() failed: %v", err)
+	}
+	update := block.StateUpdate
+	c := boc.NewCell()
+	if err := Marshal(c, update); err != nil {
+		t.Fatalf("Marshal() failed: %v", err)
+	}
+	hash, err := c.Hash256()
+	if err != nil {
+		t.Fatalf("Hash256() failed: %v", err)
+	}
+	wantHash, err := cell.Refs()[2].Hash256()
+	if err != nil {
+		t.Fatalf("Hash256() failed: %v", err)
+	}
+	if hash != wantHash {
+		t.Fatalf("want hash: %x, got: %x", wantHash, hash)
+	}
+
+	update.ToHash = Bits256{1}
+	if err := Marshal(boc.NewCell(), update); err == nil {
+		t.Fatalf("modified merkle update must not be marshaled from the source cell")
+	}
+}
+
+func TestMerkleUpdate_MarshalTLB_ModifiedRoots(t *testing.T) {
+	from := boc.NewCell()
+	if err := Marshal(from, NewHashmap([]Uint32{1, 2}, []Uint32{10, 20})); err != nil {
+		t.Fatalf("Marshal() failed: %v", err)
+	}
+	to := boc.NewCell()
+	if err := Marshal(to, NewHashmap([]Uint32{1, 2}, []Uint32{10, 30})); err != nil {
+		t.Fatalf("Marshal() failed: %v", err)
+	}
+	update, err := NewMerkleUpdate[Hashmap[Uint32, Uint32]](from, to)
+	if err != nil {
+		t.Fatalf("NewMerkleUpdate() failed: %v", err)
+	}
+	if update.Cell() == nil {
+		t.Fatalf("decoded merkle update must keep its source cell")
+	}
+	wantHash, err := update.Cell().Hash256()
+	if err != nil {
+		t.Fatalf("Hash256() failed: %v", err)
+	}
+	// a copy of the fields is encoded from the fields, so modified roots are marshaled.
+	modified := MerkleUpdate[Hashmap[Uint32, Uint32]]{
+		Magic:     update.Magic,
+		FromHash:  update.FromHash,
+		ToHash:    update.ToHash,
+		FromDepth: update.FromDepth,
+		ToDepth:   update.ToDepth,
+		FromRoot:  update.FromRoot,
+		ToRoot:    NewHashmap([]Uint32{1, 2}, []Uint32{10, 40}),
+	}
+	if modified.Cell() != nil {
+		t.Fatalf("constructed merkle update must not have a source cell")
+	}
+	c := boc.NewCell()
+	if err := Marshal(c, modified); err != nil {
+		t.Fatalf("Marshal() failed: %v", err)
+	}
+	hash, err := c.Hash256()
+	if err != nil {
+		t.Fatalf("Hash256() failed: %v", err)
+	}
+	if hash == wantHash {
+		t.Fatalf("modified roots must be marshaled")
+	}
+	c.ResetCounters()
+	var decoded MerkleUpdate[Hashmap[Uint32, Uint32]]
+	if err := Unmarshal(c, &decoded); err != nil {
+		t.Fatalf("Unmarshal() failed: %v", err)
+	}
+	if values := decoded.ToRoot.Values(); len(values) != 2 || values[1] != 40 {
+		t.Fatalf("want modified to root, got: %v", values)
+	}
+}
@@ -0,0 +1,228 @@
+package tlb
+
+import (
+	"fmt"
+
+	"github.com/tonkeeper/tongo/boc"
+)
+
+// ShardAccounts
+// _ (HashmapAugE 256 ShardAccount DepthBalanceInfo) = ShardAccounts;
+// ShardAccounts keeps a cell with accounts and decodes them on demand,
+// so a state with millions of accounts doesn't have to be decoded at once.
+type ShardAccounts struct {
+	cell    *boc.Cell
+	decoder *Decoder
+}
+
+func (a *ShardAccounts) UnmarshalTLB(c *boc.Cell, decoder *Decoder) error {
+	a.cell = c
+	a.decoder = decoder
+	return nil
+}
+
+func (a ShardAccounts) MarshalTLB(c *boc.Cell, encoder *Encoder) error {
+	if a.cell == nil {
+		return fmt.Errorf("ShardAccounts has no cell")
+	}
+	*c = *a.cell
+	c.ResetCounters()
+	return nil
+}
+
+func (a ShardAccounts) getDecoder() *Decoder {
+	if a.decoder == nil {
+		return NewDecoder()
+	}
+	return a.decoder
+}
+
+// Iterate calls fn for each account in the order of their addresses.
+// Iteration stops at the first error returned by fn and the error is returned as is.
+// Accounts hidden by pruned branches are skipped.
+func (a ShardAccounts) Iterate(fn func(address Bits256, account ShardAccount, info DepthBalanceInfo) error) error {
+	if a.cell == nil || a.cell.CellType() == boc.PrunedBranchCell {
+		return nil
+	}
+	a.cell.ResetCounters()
+	exist, err := a.cell.ReadBit()
+	if err != nil {
+		return err
+	}
+	if !exist {
+		return nil
+	}
+	root, err := a.cell.NextRef()
+	if err != nil {
+		return err
+	}
+	keyPrefix := boc.NewBitString(256)
+	return iterateHashmapAug(256, 256, root, &keyPrefix, a.getDecoder(), fn)
+}
+
+// Lookup finds an account with the given address and returns a cell positioned at its ShardAccount.
+// It returns nil if there is no such account.
+// Lookup can be used with merkle proofs: if the account's path is hidden by a pruned branch,
+// ErrPrunedKeyPath is returned, so a missing account is never confused with a pruned one.
+func (a ShardAccounts) Lookup(address Bits256) (*boc.Cell, error) {
+	if a.cell == nil || a.cell.CellType() == boc.PrunedBranchCell {
+		return nil, ErrPrunedKeyPath
+	}
+	a.cell.ResetCounters()
+	exist, err := a.cell.ReadBit()
+	if err != nil {
+		return nil, err
+	}
+	if !exist {
+		return nil, nil
+	}
+	root, err := a.cell.NextRef()
+	if err != nil {
+		return nil, err
+	}
+	root.ResetCounters()
+	key := boc.NewBitString(256)
+	if err := key.WriteBytes(address[:]); err != nil {
+		return nil, err
+	}
+	return lookupHashmapAug[DepthBalanceInfo](256, root, key, a.getDecoder())
+}
+
+// Extra returns an augmentation of the root which contains a total balance of all accounts.
+func (a ShardAccounts) Extra() (DepthBalanceInfo, error) {
+	if a.cell == nil {
+		return DepthBalanceInfo{}, fmt.Errorf("ShardAccounts has no cell")
+	}
+	a.cell.ResetCounters()
+	var accounts struct {
+		Root  Maybe[Ref[boc.Cell]]
+		Extra DepthBalanceInfo
+	}
+	if err := a.getDecoder().Unmarshal(a.cell, &accounts); err != nil {
+		return DepthBalanceInfo{}, err
+	}
+	return accounts.Extra, nil
+}
+
+// Decode decodes all accounts at once.
+func (a ShardAccounts) Decode() (HashmapAugE[Bits256, ShardAccount, DepthBalanceInfo], error) {
+	var accounts HashmapAugE[Bits256, ShardAccount, DepthBalanceInfo]
+	if a.cell == nil {
+		return accounts, nil
+	}
+	a.cell.ResetCounters()
+	err := a.getDecoder().Unmarshal(a.cell, &accounts)
+	return accounts, err
+}
+
+// LazyShardState is a ShardState whose accounts are decoded on demand.
+// It mirrors ShardState, but Accounts fields of the shard states are left empty,
+// use ShardAccounts or DecodeAccountBalances to access accounts.
+type LazyShardState struct {
+	SumType
+	UnsplitState struct {
+		Value ShardStateUnsplit
+	} `tlbSumType:"_"`
+	SplitState struct {
+		Left  ShardStateUnsplit
+		Right ShardStateUnsplit
+	} `tlbSumType:"split_state#5f327da5"`
+	// ShardAccounts contains accounts of an unsplit state
+	// or accounts of the left and right halves of a split state.
+	ShardAccounts []ShardAccounts
+}
+
+func (s *LazyShardState) UnmarshalTLB(c *boc.Cell, decoder *Decoder) error {
+	sumType, err := c.ReadUint(32)
+	if err != nil {
+		return err
+	}
+	switch sumType {
+	case 0x5f327da5:
+		s.SumType = "SplitState"
+		s.ShardAccounts = make([]ShardAccounts, 2)
+		for i, half := range []*ShardStateUnsplit{&s.SplitState.Left, &s.SplitState.Right} {
+			c1, err := c.NextRef()
+			if err != nil {
+				return err
+			}
+			if c1.CellType() == boc.PrunedBranchCell {
+				*half = ShardStateUnsplit{}
+				continue
+			}
+			if _, err := c1.ReadUint(32); err != nil {
+				return err
+			}
+			*half, s.ShardAccounts[i], err = decodeLazyShardStateUnsplit(c1, decoder)
+			if err != nil {
+				return err
+			}
+		}
+	case 0x9023afe2:
+		s.SumType = "UnsplitState"
+		s.ShardAccounts = make([]ShardAccounts, 1)
+		s.UnsplitState.Value, s.ShardAccounts[0], err = decodeLazyShardStateUnsplit(c, decoder)
+		if err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("invalid tag")
+	}
+	return nil
+}
+
+// decodeLazyShardStateUnsplit decodes ShardStateUnsplit without its tag.
+func decodeLazyShardStateUnsplit(c *boc.Cell, decoder *Decoder) (ShardStateUnsplit, ShardAccounts, error) {
+	var data struct {
+		GlobalID        int32
+		ShardID         ShardIdent
+		SeqNo           uint32
+		VertSeqNo       uint32
+		GenUtime        uint32
+		GenLt           uint64
+		MinRefMcSeqno   uint32
+		OutMsgQueueInfo OutMsgQueueInfo `tlb:"^"`
+		BeforeSplit     bool
+		Accounts        ShardAccounts          `tlb:"^"`
+		Other           ShardStateUnsplitOther `tlb:"^"`
+		Custom          Maybe[Ref[McStateExtra]]
+	}
+	if err := decoder.Unmarshal(c, &data); err != nil {
+		return ShardStateUnsplit{}, ShardAccounts{}, err
+	}
+	state := ShardStateUnsplit{
+		ShardStateUnsplit: ShardStateUnsplitData{
+			GlobalID:        data.GlobalID,
+			ShardID:         data.ShardID,
+			SeqNo:           data.SeqNo,
+			VertSeqNo:       data.VertSeqNo,
+			GenUtime:        data.GenUtime,
+			GenLt:           data.GenLt,
+			MinRefMcSeqno:   data.MinRefMcSeqno,
+			OutMsgQueueInfo: data.OutMsgQueueInfo,
+			BeforeSplit:     data.BeforeSplit,
+			Other:           data.Other,
+			Custom:          data.Custom,
+		},
+	}
+	return state, data.Accounts, nil
+}
+
+// DecodeAccountBalances works the same way as ShardState.AccountBalances,
+// but decodes accounts one by one.
+func (s *LazyShardState) DecodeAccountBalances() (map[Bits256]CurrencyCollection, error) {
+	balances := make(map[Bits256]CurrencyCollection)
+	for _, accounts := range s.ShardAccounts {
+		err := accounts.Iterate(func(address Bits256, account ShardAccount, info DepthBalanceInfo) error {
+			c, ok := account.Account.CurrencyCollection()
+			if ok {
+				balances[address] = c
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return balances, nil
+}
@@ -0,0 +1,176 @@
+package tlb
+
+import (
+	"errors"
+	"os"
+	"path"
+	"reflect"
+	"testing"
+
+	"github.com/tonkeeper/tongo/boc"
+)
+
+func TestLazyShardState(t *testing.T) {
+	folders := []string{
+		"testdata/block-1",
+		"testdata/block-2",
+		"testdata/block-3",
+		"testdata/block-4",
+		"testdata/block-5",
+	}
+	for _, folder := range folders {
+		t.Run(folder, func(t *testing.T) {
+			data, err := os.ReadFile(path.Join(folder, "block.bin"))
+			if err != nil {
+				t.Fatalf("ReadFile() failed: %v", err)
+			}
+			cell, err := boc.DeserializeBoc(data)
+			if err != nil {
+				t.Fatalf("boc.DeserializeBoc() failed: %v", err)
+			}
+			// block#11ef55aa global_id:int32 info:^BlockInfo value_flow:^ValueFlow state_update:^(MERKLE_UPDATE ShardState) extra:^BlockExtra
+			toRoot := cell[0].Refs()[2].Refs()[1]
+			var state ShardState
+			if err := Unmarshal(toRoot, &state); err != nil {
+				t.Fatalf("Unmarshal() failed: %v", err)
+			}
+			toRoot.ResetCounters()
+			var lazyState LazyShardState
+			if err := Unmarshal(toRoot, &lazyState); err != nil {
+				t.Fatalf("Unmarshal() failed: %v", err)
+			}
+			if lazyState.SumType != state.SumType {
+				t.Fatalf("want sum type: %v, got: %v", state.SumType, lazyState.SumType)
+			}
+			want := state.UnsplitState.Value.ShardStateUnsplit
+			got := lazyState.UnsplitState.Value.ShardStateUnsplit
+			if got.SeqNo != want.SeqNo || got.GenLt != want.GenLt || !reflect.DeepEqual(got.ShardID, want.ShardID) {
+				t.Fatalf("shard state mismatch")
+			}
+			balances, err := lazyState.DecodeAccountBalances()
+			if err != nil {
+				t.Fatalf("DecodeAccountBalances() failed: %v", err)
+			}
+			if !reflect.DeepEqual(balances, state.AccountBalances()) {
+				t.Fatalf("account balances mismatch")
+			}
+			accounts, err := lazyState.ShardAccounts[0].Decode()
+			if err != nil {
+				t.Fatalf("Decode() failed: %v", err)
+			}
+			if !reflect.DeepEqual(accounts.Keys(), want.Accounts.Keys()) {
+				t.Fatalf("accounts mismatch")
+			}
+			extra, err := lazyState.ShardAccounts[0].Extra()
+			if err != nil {
+				t.Fatalf("Extra() failed: %v", err)
+			}
+			if !reflect.DeepEqual(extra, want.Accounts.Extra()) {
+				t.Fatalf("want extra: %v, got: %v", want.Accounts.Extra(), extra)
+			}
+			existing := make(map[Bits256]struct{})
+			for _, address := range want.Accounts.Keys() {
+				existing[address] = struct{}{}
+			}
+			for i, address := range want.Accounts.Keys() {
+				leaf, err := lazyState.ShardAccounts[0].Lookup(address)
+				if err != nil {
+					t.Fatalf("Lookup() failed: %v", err)
+				}
+				if leaf == nil {
+					t.Fatalf("account %x not found", address)
+				}
+				var account ShardAccount
+				if err := Unmarshal(leaf, &account); err != nil {
+					t.Fatalf("Unmarshal() failed: %v", err)
+				}
+				wantAccount := want.Accounts.Values()[i]
+				if account.LastTransLt != wantAccount.LastTransLt || account.LastTransHash != wantAccount.LastTransHash {
+					t.Fatalf("account %x mismatch", address)
+				}
+				missing := address
+				missing[31] ^= 1
+				if _, ok := existing[missing]; ok {
+					continue
+				}
+				leaf, err = lazyState.ShardAccounts[0].Lookup(missing)
+				if err != nil && !errors.Is(err, ErrPrunedKeyPath) {
+					t.Fatalf("Lookup() failed: %v", err)
+				}
+				if leaf != nil {
+					t.Fatalf("account %x must not exist", missing)
+				}
+			}
+		})
+	}
+}
+
+func TestLazyShardState_SplitState(t *testing.T) {
+	var halves []*boc.Cell
+	for _, folder := range []string{"testdata/block-1", "testdata/block-2"} {
+		data, err := os.ReadFile(path.Join(folder, "block.bin"))
+		if err != nil {
+			t.Fatalf("ReadFile() failed: %v", err)
+		}
+		cell, err := boc.DeserializeBoc(data)
+		if err != nil {
+			t.Fatalf("boc.DeserializeBoc() failed: %v", err)
+		}
+		halves = append(halves, cell[0].Refs()[2].Refs()[1])
+	}
+	// split_state#5f327da5 left:^ShardStateUnsplit right:^ShardStateUnsplit = ShardState;
+	splitState := boc.NewCell()
+	if err := splitState.WriteUint(0x5f327da5, 32); err != nil {
+		t.Fatalf("WriteUint() failed: %v", err)
+	}
+	for _, half := range halves {
+		if err := splitState.AddRef(half); err != nil {
+			t.Fatalf("AddRef() failed: %v", err)
+		}
+	}
+	var state ShardState
+	if err := Unmarshal(splitState, &state); err != nil {
+		t.Fatalf("Unmarshal() failed: %v", err)
+	}
+	splitState.ResetCounters()
+	for _, half := range halves {
+		half.ResetCounters()
+	}
+	var lazyState LazyShardState
+	if err := Unmarshal(splitState, &lazyState); err != nil {
+		t.Fatalf("Unmarshal() failed: %v", err)
+	}
+	if lazyState.SumType != "SplitState" {
+		t.Fatalf("want sum type: SplitState, got: %v", lazyState.SumType)
+	}
+	if len(lazyState.ShardAccounts) != 2 {
+		t.Fatalf("want 2 shard accounts, got: %v", len(lazyState.ShardAccounts))
+	}
+	for i, pair := range [][2]ShardStateUnsplit{
+		{state.SplitState.Left, lazyState.SplitState.Left},
+		{state.SplitState.Right, lazyState.SplitState.Right},
+	} {
+		want, got := pair[0].ShardStateUnsplit, pair[1].ShardStateUnsplit
+		if got.SeqNo != want.SeqNo || got.GenLt != want.GenLt || !reflect.DeepEqual(got.ShardID, want.ShardID) {
+			t.Fatalf("shard state %v mismatch", i)
+		}
+		accounts, err := lazyState.ShardAccounts[i].Decode()
+		if err != nil {
+			t.Fatalf("Decode() failed: %v", err)
+		}
+		if !reflect.DeepEqual(accounts.Keys(), want.Accounts.Keys()) {
+			t.Fatalf("accounts %v mismatch", i)
+		}
+	}
+	balances, err := lazyState.DecodeAccountBalances()
+	if err != nil {
+		t.Fatalf("DecodeAccountBalances() failed: %v", err)
+	}
+	wantBalances := state.AccountBalances()
+	if len(wantBalances) == 0 {
+		t.Fatalf("split state must contain accounts")
+	}
+	if !reflect.DeepEqual(balances, wantBalances) {
+		t.Fatalf("account balances mismatch")
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"math"
 	"math/big"
 	"reflect"
 
@@ -34,7 +35,61 @@ type VmStack []VmStackValue
 // after:^VmCont = VmCont;
 // vmc_pushint$1111 value:int32 next:^VmCont = VmCont;
 type VmCont struct {
-	// TODO: implement
+	SumType
+	VmcStd struct {
+		Cdata VmControlData
+		Code  VmCellSlice
+	} `tlbSumType:"vmc_std$00"`
+	VmcEnvelope struct {
+		Cdata VmControlData
+		Next  *VmCont `tlb:"^"`
+	} `tlbSumType:"vmc_envelope$01"`
+	VmcQuit struct {
+		ExitCode int32
+	} `tlbSumType:"vmc_quit$1000"`
+	VmcQuitExc struct{} `tlbSumType:"vmc_quit_exc$1001"`
+	VmcRepeat  struct {
+		Count Uint63
+		Body  *VmCont `tlb:"^"`
+		After *VmCont `tlb:"^"`
+	} `tlbSumType:"vmc_repeat$10100"`
+	VmcUntil struct {
+		Body  *VmCont `tlb:"^"`
+		After *VmCont `tlb:"^"`
+	} `tlbSumType:"vmc_until$110000"`
+	VmcAgain struct {
+		Body *VmCont `tlb:"^"`
+	} `tlbSumType:"vmc_again$110001"`
+	VmcWhileCond struct {
+		Cond  *VmCont `tlb:"^"`
+		Body  *VmCont `tlb:"^"`
+		After *VmCont `tlb:"^"`
+	} `tlbSumType:"vmc_while_cond$110010"`
+	VmcWhileBody struct {
+		Cond  *VmCont `tlb:"^"`
+		Body  *VmCont `tlb:"^"`
+		After *VmCont `tlb:"^"`
+	} `tlbSumType:"vmc_while_body$110011"`
+	VmcPushInt struct {
+		Value int32
+		Next  *VmCont `tlb:"^"`
+	} `tlbSumType:"vmc_pushint$1111"`
+}
+
+// VmControlData
+// vm_ctl_data$_ nargs:(Maybe uint13) stack:(Maybe VmStack) save:VmSaveList
+// cp:(Maybe int16) = VmControlData;
+type VmControlData struct {
+	Nargs Maybe[Uint13]
+	Stack Maybe[VmStack]
+	Save  VmSaveList
+	Cp    Maybe[Int16]
+}
+
+// VmSaveList
+// _ cregs:(HashmapE 4 VmStackValue) = VmSaveList;
+type VmSaveList struct {
+	Cregs HashmapE[Uint4, VmStackValue]
 }
 
 // VmStkTuple
@@ -60,9 +115,93 @@ type VmTupleRef struct {
 	Ref   *VmTuple      `tlb:"^"`
 }
 
+// NewVmStkTuple returns a tuple containing the given values.
+func NewVmStkTuple(values []VmStackValue) (VmStkTuple, error) {
+	if len(values) > math.MaxUint16 {
+		return VmStkTuple{}, fmt.Errorf("tuple is too long: %v", len(values))
+	}
+	return VmStkTuple{
+		Len:  uint16(len(values)),
+		Data: newVmTuple(values),
+	}, nil
+}
+
+func newVmTuple(values []VmStackValue) *VmTuple {
+	n := len(values)
+	if n == 0 {
+		return nil
+	}
+	tuple := VmTuple{Tail: values[n-1]}
+	switch {
+	case n-1 == 1:
+		tuple.Head.Entry = &values[0]
+	case n-1 > 1:
+		tuple.Head.Ref = newVmTuple(values[:n-1])
+	}
+	return &tuple
+}
+
+// Values returns all values of this tuple.
+func (t VmStkTuple) Values() ([]VmStackValue, error) {
+	if t.Len == 0 {
+		return nil, nil
+	}
+	if t.Data == nil {
+		return nil, fmt.Errorf("tuple of length %v has no data", t.Len)
+	}
+	return t.Data.RecursiveToSlice(int(t.Len))
+}
+
 func (t VmStkTuple) MarshalTLB(c *boc.Cell, encoder *Encoder) error {
-	// TODO: implement
-	return fmt.Errorf("VmStkTuple TLB marshaling not implemented")
+	err := c.WriteUint(uint64(t.Len), 16)
+	if err != nil {
+		return err
+	}
+	return putVmTuple(t.Len, t.Data, c, encoder)
+}
+
+func putVmTuple(n uint16, t *VmTuple, c *boc.Cell, encoder *Encoder) error {
+	// vm_tuple_nil$_ = VmTuple 0;
+	if n == 0 {
+		return nil
+	}
+	// vm_tuple_tcons$_ {n:#} head:(VmTupleRef n) tail:^VmStackValue = VmTuple (n + 1);
+	if t == nil {
+		return fmt.Errorf("tuple of length %v has no data", n)
+	}
+	err := putVmTupleRef(n-1, &t.Head, c, encoder)
+	if err != nil {
+		return err
+	}
+	tail, err := c.NewRef()
+	if err != nil {
+		return err
+	}
+	return encoder.Marshal(tail, t.Tail)
+}
+
+func putVmTupleRef(n uint16, t *VmTupleRef, c *boc.Cell, encoder *Encoder) error {
+	switch {
+	case n == 1:
+		// vm_tupref_single$_ entry:^VmStackValue = VmTupleRef 1;
+		if t.Entry == nil {
+			return fmt.Errorf("tuple entry is missing")
+		}
+		entry, err := c.NewRef()
+		if err != nil {
+			return err
+		}
+		return encoder.Marshal(entry, *t.Entry)
+	case n > 1:
+		// vm_tupref_any$_ {n:#} ref:^(VmTuple (n + 2)) = VmTupleRef (n + 2);
+		ref, err := c.NewRef()
+		if err != nil {
+			return err
+		}
+		return putVmTuple(n, t.Ref, ref, encoder)
+	}
+	// vm_tupref_nil$_ = VmTupleRef 0;
+	return nil
 }
 
 func (t *VmStkTuple) UnmarshalTLB(c *boc.Cell, decoder *Decoder) error {
@@ -71,18 +210,18 @@ func (t *VmStkTuple) UnmarshalTLB(c *boc.Cell, decoder *Decoder) error {
 		return err
 	}
 	t.Len = uint16(l)
-	t.Data, err = vmTupleInner(t.Len, c)
+	t.Data, err = vmTupleInner(t.Len, c, decoder)
 	if err != nil {
 		return err
 	}
 	return nil
 }
 
-func vmTupleInner(n uint16, c *boc.Cell) (*VmTuple, error) {
+func vmTupleInner(n uint16, c *boc.Cell, decoder *Decoder) (*VmTuple, error) {
 	if n > 0 {
 		vmTuple := VmTuple{}
 		n -= 1
-		head, err := vmTupleRefInner(n, c)
+		head, err := vmTupleRefInner(n, c, decoder)
 		if err != nil {
 			return nil, err
 		}
@@ -94,7 +233,7 @@ func vmTupleInner(n uint16, c *boc.Cell) (*VmTuple, error) {
 			return nil, err
 		}
 		vmStackValue := VmStackValue{}
-		err = Unmarshal(c1, &vmStackValue)
+		err = decoder.Unmarshal(c1, &vmStackValue)
 		if err != nil {
 			return nil, err
 		}
@@ -104,7 +243,7 @@ func vmTupleInner(n uint16, c *boc.Cell) (*VmTuple, error) {
 	return nil, nil
 }
 
-func vmTupleRefInner(n uint16, c *boc.Cell) (*VmTupleRef, error) {
+func vmTupleRefInner(n uint16, c *boc.Cell, decoder *Decoder) (*VmTupleRef, error) {
 	vmTupleRef := VmTupleRef{}
 	if n == 1 {
 		c1, err := c.NextRef()
@@ -112,7 +251,10 @@ func vmTupleRefInner(n uint16, c *boc.Cell) (*VmTupleRef, error) {
 			return nil, err
 		}
 		vmStackValue := VmStackValue{}
-		Unmarshal(c1, &vmStackValue)
+		err = decoder.Unmarshal(c1, &vmStackValue)
+		if err != nil {
+			return nil, err
+		}
 		vmTupleRef.Entry = &vmStackValue
 		return &vmTupleRef, nil
 	} else if n > 1 {
@@ -120,7 +262,7 @@ func vmTupleRefInner(n uint16, c *boc.Cell) (*VmTupleRef, error) {
 		if err != nil {
 			return nil, err
 		}
-		ref, err := vmTupleInner(n, c1)
+		ref, err := vmTupleInner(n, c1, decoder)
 		if err != nil {
 			return nil, err
 		}
@@ -130,8 +272,70 @@ func vmTupleRefInner(n uint16, c *boc.Cell) (*VmTupleRef, error) {
 	return nil, nil
 }
 
+// UnmarshalTLB decodes a tuple of an unknown length.
+// The length is restored from the cell layout:
+// a tuple of length 1 has only a tail, a tuple of length 2 has an entry and a tail,
+// and a longer tuple has a reference to a nested tuple which has no data bits unlike any VmStackValue.
+// So all the remaining refs of the cell are considered to belong to the tuple.
 func (t *VmTuple) UnmarshalTLB(c *boc.Cell, decoder *Decoder) error {
-	return fmt.Errorf("VmTuple TLB unmarshaling not implemented")
+	n, err := vmTupleLen(c)
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		*t = VmTuple{}
+		return nil
+	}
+	tuple, err := vmTupleInner(n, c, decoder)
+	if err != nil {
+		return err
+	}
+	*t = *tuple
+	return nil
+}
+
+// MarshalTLB encodes a tuple with a length restored by Len.
+// An empty tuple is encoded as nothing.
+func (t VmTuple) MarshalTLB(c *boc.Cell, encoder *Encoder) error {
+	return putVmTuple(t.Len(), &t, c, encoder)
+}
+
+// Len returns a number of values in this tuple.
+// The zero value of VmTuple is an empty tuple,
+// because a non-empty tuple always has a tail which is a valid VmStackValue.
+func (t VmTuple) Len() uint16 {
+	switch {
+	case t.Head.Ref != nil:
+		return t.Head.Ref.Len() + 1
+	case t.Head.Entry != nil:
+		return 2
+	case t.Tail.SumType == "":
+		return 0
+	}
+	return 1
+}
+
+func vmTupleLen(c *boc.Cell) (uint16, error) {
+	refs := c.Refs()[len(c.Refs())-c.RefsAvailableForRead():]
+	switch len(refs) {
+	case 0:
+		return 0, nil
+	case 1:
+		return 1, nil
+	case 2:
+		if refs[0].BitSize() > 0 {
+			return 2, nil
+		}
+		n, err := vmTupleLen(refs[0])
+		if err != nil {
+			return 0, err
+		}
+		if n < 2 {
+			return 0, fmt.Errorf("invalid nested tuple length: %v", n)
+		}
+		return n + 1, nil
+	}
+	return 0, fmt.Errorf("invalid tuple refs count: %v", len(refs))
 }
 
 // VmCellSlice
@@ -371,16 +575,6 @@ func (s VmCellSlice) Cell() *boc.Cell {
 	return cell
 }
 
-func (ct VmCont) MarshalTLB(c *boc.Cell, encoder *Encoder) error {
-	// TODO: implement
-	return fmt.Errorf("VmCont TLB marshaling not implemented")
-}
-
-func (ct *VmCont) UnmarshalTLB(c *boc.Cell, decoder *Decoder) error {
-	// TODO: implement
-	return fmt.Errorf("VmCont TLB unmarshaling not implemented")
-}
-
 func TlbStructToVmCellSlice(s any) (VmStackValue, error) {
 	cell := boc.NewCell()
 	err := Marshal(cell, s)
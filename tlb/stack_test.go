@@ -73,3 +73,89 @@ func Test_StackUnmarshal(t *testing.T) {
 	}
 
 }
+
+func TestVmCont(t *testing.T) {
+	code := boc.NewCell()
+	if err := code.WriteUint(0xf2a0, 16); err != nil {
+		t.Fatal(err)
+	}
+	codeSlice, err := CellToVmCellSlice(code)
+	if err != nil {
+		t.Fatal(err)
+	}
+	quit := VmCont{SumType: "VmcQuit"}
+	quit.VmcQuit.ExitCode = 11
+	quitExc := VmCont{SumType: "VmcQuitExc"}
+
+	std := VmCont{SumType: "VmcStd"}
+	std.VmcStd.Code = codeSlice.VmStkSlice
+	std.VmcStd.Cdata.Nargs = Maybe[Uint13]{Exists: true, Value: 3}
+	std.VmcStd.Cdata.Stack = Maybe[VmStack]{Exists: true, Value: VmStack{{SumType: "VmStkTinyInt", VmStkTinyInt: 7}}}
+	std.VmcStd.Cdata.Save.Cregs = NewHashmapE([]Uint4{0}, []VmStackValue{{SumType: "VmStkCont", VmStkCont: quit}})
+	std.VmcStd.Cdata.Cp = Maybe[Int16]{Exists: true, Value: 0}
+
+	envelope := VmCont{SumType: "VmcEnvelope"}
+	envelope.VmcEnvelope.Next = &std
+	repeat := VmCont{SumType: "VmcRepeat"}
+	repeat.VmcRepeat.Count = 5
+	repeat.VmcRepeat.Body = &envelope
+	repeat.VmcRepeat.After = &quitExc
+	until := VmCont{SumType: "VmcUntil"}
+	until.VmcUntil.Body = &quit
+	until.VmcUntil.After = &repeat
+	again := VmCont{SumType: "VmcAgain"}
+	again.VmcAgain.Body = &quit
+	whileCond := VmCont{SumType: "VmcWhileCond"}
+	whileCond.VmcWhileCond.Cond = &quit
+	whileCond.VmcWhileCond.Body = &again
+	whileCond.VmcWhileCond.After = &quitExc
+	whileBody := VmCont{SumType: "VmcWhileBody"}
+	whileBody.VmcWhileBody.Cond = &quit
+	whileBody.VmcWhileBody.Body = &again
+	whileBody.VmcWhileBody.After = &quitExc
+	pushInt := VmCont{SumType: "VmcPushInt"}
+	pushInt.VmcPushInt.Value = -1
+	pushInt.VmcPushInt.Next = &until
+
+	var stack VmStack
+	for _, cont := range []VmCont{quit, quitExc, std, envelope, repeat, until, again, whileCond, whileBody, pushInt} {
+		stack = append(stack, VmStackValue{SumType: "VmStkCont", VmStkCont: cont})
+	}
+	cell := boc.NewCell()
+	if err = Marshal(cell, stack); err != nil {
+		t.Fatal(err)
+	}
+	var decoded VmStack
+	if err = Unmarshal(cell, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	// the top of the stack goes first on encoding and last on decoding
+	for i := range stack {
+		if decoded[len(decoded)-1-i].VmStkCont.SumType != stack[i].VmStkCont.SumType {
+			t.Fatalf("want %v, got %v", stack[i].VmStkCont.SumType, decoded[len(decoded)-1-i].VmStkCont.SumType)
+		}
+	}
+	if decoded[0].VmStkCont.VmcPushInt.Next.VmcUntil.After.VmcRepeat.Count != 5 {
+		t.Fatalf("invalid nested continuation")
+	}
+	decodedStd := decoded[7].VmStkCont.VmcStd
+	if decodedStd.Cdata.Nargs.Value != 3 || decodedStd.Cdata.Stack.Value[0].Int64() != 7 {
+		t.Fatalf("invalid control data")
+	}
+	var opcode uint16
+	if err = decodedStd.Code.UnmarshalToTlbStruct(&opcode); err != nil || opcode != 0xf2a0 {
+		t.Fatalf("invalid code")
+	}
+	reversed := make(VmStack, 0, len(decoded))
+	for i := len(decoded) - 1; i >= 0; i-- {
+		reversed = append(reversed, decoded[i])
+	}
+	encoded := boc.NewCell()
+	if err = Marshal(encoded, reversed); err != nil {
+		t.Fatal(err)
+	}
+	wantHash, _ := cell.Hash256()
+	if hash, _ := encoded.Hash256(); hash != wantHash {
+		t.Fatalf("want hash: %x, got: %x", wantHash, hash)
+	}
+}
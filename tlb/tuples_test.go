@@ -47,6 +47,115 @@ func TestTuple(t *testing.T) {
 			t.Errorf("mismatch %v", i)
 		}
 	}
+	encoded := boc.NewCell()
+	if err = Marshal(encoded, stack); err != nil {
+		t.Fatal(err)
+	}
+	wantHash, _ := cells[0].Hash256()
+	if hash, _ := encoded.Hash256(); hash != wantHash {
+		t.Fatalf("want hash: %x, got: %x", wantHash, hash)
+	}
+}
+
+func TestNewVmStkTuple(t *testing.T) {
+	for n := 0; n < 6; n++ {
+		values := make([]VmStackValue, 0, n)
+		for i := 0; i < n; i++ {
+			values = append(values, VmStackValue{SumType: "VmStkTinyInt", VmStkTinyInt: int64(i)})
+		}
+		tuple, err := NewVmStkTuple(values)
+		if err != nil {
+			t.Fatal(err)
+		}
+		stack := VmStack{{SumType: "VmStkTuple", VmStkTuple: tuple}}
+		cell := boc.NewCell()
+		if err = Marshal(cell, stack); err != nil {
+			t.Fatal(err)
+		}
+		var decoded VmStack
+		if err = Unmarshal(cell, &decoded); err != nil {
+			t.Fatal(err)
+		}
+		items, err := decoded[0].VmStkTuple.Values()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(items) != n {
+			t.Fatalf("want %v items, got %v", n, len(items))
+		}
+		for i, item := range items {
+			if item.Int64() != int64(i) {
+				t.Fatalf("want %v, got %v", i, item.Int64())
+			}
+		}
+		if n == 0 {
+			continue
+		}
+		// VmTuple restores its length from the cell layout
+		tupleCell := boc.NewCell()
+		if err = Marshal(tupleCell, *tuple.Data); err != nil {
+			t.Fatal(err)
+		}
+		var vmTuple VmTuple
+		if err = Unmarshal(tupleCell, &vmTuple); err != nil {
+			t.Fatal(err)
+		}
+		if vmTuple.Len() != uint16(n) {
+			t.Fatalf("want tuple length %v, got %v", n, vmTuple.Len())
+		}
+	}
+}
+
+func TestVmTuple_roundTrip(t *testing.T) {
+	for n := 0; n < 4; n++ {
+		t.Run(fmt.Sprintf("length %v", n), func(t *testing.T) {
+			values := make([]VmStackValue, 0, n)
+			for i := 0; i < n; i++ {
+				values = append(values, VmStackValue{SumType: "VmStkTinyInt", VmStkTinyInt: int64(i)})
+			}
+			var tuple VmTuple
+			if data := newVmTuple(values); data != nil {
+				tuple = *data
+			}
+			if tuple.Len() != uint16(n) {
+				t.Fatalf("want tuple length %v, got %v", n, tuple.Len())
+			}
+			cell := boc.NewCell()
+			if err := Marshal(cell, tuple); err != nil {
+				t.Fatal(err)
+			}
+			var decoded VmTuple
+			if err := Unmarshal(cell, &decoded); err != nil {
+				t.Fatal(err)
+			}
+			if decoded.Len() != uint16(n) {
+				t.Fatalf("want decoded tuple length %v, got %v", n, decoded.Len())
+			}
+			encoded := boc.NewCell()
+			if err := Marshal(encoded, decoded); err != nil {
+				t.Fatal(err)
+			}
+			if encoded.RefsSize() != cell.RefsSize() || encoded.BitSize() != cell.BitSize() {
+				t.Fatalf("want %v refs and %v bits, got %v refs and %v bits", cell.RefsSize(), cell.BitSize(), encoded.RefsSize(), encoded.BitSize())
+			}
+			wantHash, _ := cell.Hash256()
+			if hash, _ := encoded.Hash256(); hash != wantHash {
+				t.Fatalf("want hash: %x, got: %x", wantHash, hash)
+			}
+			if n == 0 {
+				return
+			}
+			items, err := decoded.RecursiveToSlice(n)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for i, item := range items {
+				if item.Int64() != int64(i) {
+					t.Fatalf("want %v, got %v", i, item.Int64())
+				}
+			}
+		})
+	}
 }
 
 func TestTuple2(t *testing.T) {
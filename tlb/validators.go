@@ -103,6 +103,38 @@ func (vd ValidatorDescr) PubKey() Bits256 {
 	return vd.ValidatorAddr.PublicKey.PubKey
 }
 
+// Weight returns a weight of the validator.
+func (vd ValidatorDescr) Weight() uint64 {
+	if vd.SumType == "Validator" {
+		return vd.Validator.Weight
+	}
+	return vd.ValidatorAddr.Weight
+}
+
+// AdnlAddr returns an ADNL address of the validator or zero if the description doesn't contain it.
+func (vd ValidatorDescr) AdnlAddr() Bits256 {
+	if vd.SumType == "ValidatorAddr" {
+		return vd.ValidatorAddr.AdnlAddr
+	}
+	return Bits256{}
+}
+
+// Main returns a number of masterchain validators in the set.
+func (vs ValidatorSet) Main() uint16 {
+	if vs.SumType == "Validators" {
+		return vs.Validators.Main
+	}
+	return vs.ValidatorsExt.Main
+}
+
+// List returns validators ordered by their indexes.
+func (vs ValidatorSet) List() []ValidatorDescr {
+	if vs.SumType == "Validators" {
+		return vs.Validators.List.Values()
+	}
+	return vs.ValidatorsExt.List.Values()
+}
+
 type SigPubKey struct {
 	Magic  Magic `tlb:"pubkey#8e81278a"`
 	PubKey Bits256
@@ -183,18 +183,23 @@ func DecodeConfigParams(b []byte) (tlb.ConfigParams, error) {
 	if err != nil {
 		return tlb.ConfigParams{}, err
 	}
-	if len(cells) != 1 {
+	if len(cells) == 0 {
 		return tlb.ConfigParams{}, boc.ErrNotSingleRoot
 	}
-	var proof struct {
-		Proof tlb.MerkleProof[tlb.ShardStateUnsplit]
-	}
-	err = tlb.Unmarshal(cells[0], &proof)
-	if err != nil {
-		return tlb.ConfigParams{}, err
-	}
-	if proof.Proof.VirtualRoot.ShardStateUnsplit.Custom.Exists {
-		return proof.Proof.VirtualRoot.ShardStateUnsplit.Custom.Value.Value.Config, nil
+	// a config proof can be sent along with other proofs in one bag of cells,
+	// so we look for the first proof of a masterchain state.
+	for _, cell := range cells {
+		var proof struct {
+			Proof tlb.MerkleProof[tlb.ShardStateUnsplit]
+		}
+		err = tlb.Unmarshal(cell, &proof)
+		if err != nil {
+			continue
+		}
+		if proof.Proof.VirtualRoot.ShardStateUnsplit.Custom.Exists {
+			return proof.Proof.VirtualRoot.ShardStateUnsplit.Custom.Value.Value.Config, nil
+		}
+		err = fmt.Errorf("empty Custom field")
 	}
-	return tlb.ConfigParams{}, fmt.Errorf("empty Custom field")
+	return tlb.ConfigParams{}, err
 }
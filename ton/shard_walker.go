@@ -0,0 +1,64 @@
+package ton
+
+import (
+	"context"
+	"sort"
+
+	"github.com/tonkeeper/tongo/tlb"
+)
+
+// BlockInfoGetter returns a header of the given block.
+type BlockInfoGetter func(ctx context.Context, blockID BlockIDExt) (tlb.BlockInfo, error)
+
+// WalkShardBlocks returns shard blocks created after the "from" shard blocks up to and including the "to" shard blocks.
+// Usually, "from" and "to" are top shard blocks of two masterchain blocks as returned by ShardIDs.
+// It follows previous blocks of each block including splits and merges
+// and stops at the "from" blocks, so every block is returned once.
+// The result is sorted by seqno, so each block goes after its parents.
+func WalkShardBlocks(ctx context.Context, from, to []BlockIDExt, getInfo BlockInfoGetter) ([]BlockIDExt, error) {
+	visited := make(map[BlockIDExt]struct{}, len(from))
+	// a block created after the "from" blocks has a greater seqno than any of its ancestors,
+	// so older blocks can be skipped even if they are not referenced by "from".
+	minSeqno := make(map[int32]uint32)
+	for _, block := range from {
+		visited[block] = struct{}{}
+		if seqno, ok := minSeqno[block.Workchain]; !ok || block.Seqno < seqno {
+			minSeqno[block.Workchain] = block.Seqno
+		}
+	}
+	var blocks []BlockIDExt
+	stack := append([]BlockIDExt{}, to...)
+	for len(stack) > 0 {
+		block := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if _, ok := visited[block]; ok {
+			continue
+		}
+		visited[block] = struct{}{}
+		if seqno, ok := minSeqno[block.Workchain]; ok && block.Seqno <= seqno {
+			continue
+		}
+		info, err := getInfo(ctx, block)
+		if err != nil {
+			return nil, err
+		}
+		parents, err := GetParents(info)
+		if err != nil {
+			return nil, err
+		}
+		blocks = append(blocks, block)
+		stack = append(stack, parents...)
+	}
+	// seqno of a block is greater than seqno of each of its parents,
+	// so this order is a topological one.
+	sort.Slice(blocks, func(i, j int) bool {
+		if blocks[i].Seqno != blocks[j].Seqno {
+			return blocks[i].Seqno < blocks[j].Seqno
+		}
+		if blocks[i].Workchain != blocks[j].Workchain {
+			return blocks[i].Workchain < blocks[j].Workchain
+		}
+		return blocks[i].Shard < blocks[j].Shard
+	})
+	return blocks, nil
+}
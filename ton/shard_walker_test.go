@@ -0,0 +1,127 @@
+package ton
+
+import (
+	"context"
+	"errors"
+	"math/bits"
+	"reflect"
+	"testing"
+
+	"github.com/tonkeeper/tongo/tlb"
+)
+
+func newTestBlockID(workchain int32, shard uint64, seqno uint32) BlockIDExt {
+	return BlockIDExt{
+		BlockID:  BlockID{Workchain: workchain, Shard: shard, Seqno: seqno},
+		RootHash: Bits256{byte(seqno), byte(shard >> 56)},
+	}
+}
+
+func newTestBlockInfo(block BlockIDExt, afterSplit bool, parents ...BlockIDExt) tlb.BlockInfo {
+	var info tlb.BlockInfo
+	info.SeqNo = block.Seqno
+	info.AfterSplit = afterSplit
+	lowBit := block.Shard & (^block.Shard + 1)
+	info.Shard = tlb.ShardIdent{
+		ShardPfxBits: tlb.Uint6(63 - bits.TrailingZeros64(block.Shard)),
+		WorkchainID:  block.Workchain,
+		ShardPrefix:  block.Shard - lowBit,
+	}
+	ref := func(id BlockIDExt) tlb.ExtBlkRef {
+		return tlb.ExtBlkRef{SeqNo: id.Seqno, RootHash: tlb.Bits256(id.RootHash), FileHash: tlb.Bits256(id.FileHash)}
+	}
+	if len(parents) == 2 {
+		info.AfterMerge = true
+		info.PrevRef.SumType = "PrevBlksInfo"
+		info.PrevRef.PrevBlksInfo = &struct {
+			Prev1 tlb.ExtBlkRef
+			Prev2 tlb.ExtBlkRef
+		}{Prev1: ref(parents[0]), Prev2: ref(parents[1])}
+		return info
+	}
+	info.PrevRef.SumType = "PrevBlkInfo"
+	info.PrevRef.PrevBlkInfo = &struct{ Prev tlb.ExtBlkRef }{Prev: ref(parents[0])}
+	return info
+}
+
+func TestWalkShardBlocks(t *testing.T) {
+	// a shard splits into two halves which merge back later.
+	start := newTestBlockID(0, 0x8000000000000000, 10)
+	beforeSplit := newTestBlockID(0, 0x8000000000000000, 11)
+	left := newTestBlockID(0, 0x4000000000000000, 12)
+	right := newTestBlockID(0, 0xc000000000000000, 12)
+	left2 := newTestBlockID(0, 0x4000000000000000, 13)
+	merged := newTestBlockID(0, 0x8000000000000000, 14)
+	otherWorkchain := newTestBlockID(1, 0x8000000000000000, 5)
+	infos := map[BlockIDExt]tlb.BlockInfo{
+		beforeSplit: newTestBlockInfo(beforeSplit, false, start),
+		left:        newTestBlockInfo(left, true, beforeSplit),
+		right:       newTestBlockInfo(right, true, beforeSplit),
+		left2:       newTestBlockInfo(left2, false, left),
+		merged:      newTestBlockInfo(merged, false, left2, right),
+	}
+	calls := 0
+	getInfo := func(ctx context.Context, blockID BlockIDExt) (tlb.BlockInfo, error) {
+		calls += 1
+		info, ok := infos[blockID]
+		if !ok {
+			return tlb.BlockInfo{}, errors.New("block not found")
+		}
+		return info, nil
+	}
+
+	tests := []struct {
+		name      string
+		from      []BlockIDExt
+		to        []BlockIDExt
+		want      []BlockIDExt
+		wantCalls int
+	}{
+		{
+			name:      "split and merge",
+			from:      []BlockIDExt{start, otherWorkchain},
+			to:        []BlockIDExt{merged, otherWorkchain},
+			want:      []BlockIDExt{beforeSplit, left, right, left2, merged},
+			wantCalls: 5,
+		},
+		{
+			name:      "after split",
+			from:      []BlockIDExt{start},
+			to:        []BlockIDExt{right, left2},
+			want:      []BlockIDExt{beforeSplit, left, right, left2},
+			wantCalls: 4,
+		},
+		{
+			name:      "before merge",
+			from:      []BlockIDExt{left, right},
+			to:        []BlockIDExt{merged},
+			want:      []BlockIDExt{left2, merged},
+			wantCalls: 2,
+		},
+		{
+			name: "nothing new",
+			from: []BlockIDExt{start},
+			to:   []BlockIDExt{start},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls = 0
+			blocks, err := WalkShardBlocks(context.Background(), tt.from, tt.to, getInfo)
+			if err != nil {
+				t.Fatalf("WalkShardBlocks() failed: %v", err)
+			}
+			if !reflect.DeepEqual(blocks, tt.want) {
+				t.Fatalf("want blocks: %v, got: %v", tt.want, blocks)
+			}
+			if calls != tt.wantCalls {
+				t.Fatalf("want %v header requests, got: %v", tt.wantCalls, calls)
+			}
+		})
+	}
+
+	unknown := newTestBlockID(0, 0x8000000000000000, 20)
+	if _, err := WalkShardBlocks(context.Background(), []BlockIDExt{start}, []BlockIDExt{unknown}, getInfo); err == nil {
+		t.Fatalf("want error")
+	}
+}
@@ -0,0 +1,480 @@
+// Package blockchain provides an in-memory blockchain to test contracts and their wrappers without a network.
+//
+// Blockchain executes messages with txemulator and get methods with tvm,
+// so it requires libemulator like these packages do.
+// It implements the methods of liteapi.Client used by wallet, contract/jetton and other packages of tongo:
+//
+//	chain, err := blockchain.New(blockchain.WithAccounts(account))
+//	w, err := wallet.DefaultWalletFromSeed(seed, chain)
+//	err = w.Send(ctx, wallet.SimpleTransfer{Amount: ton.OneTON, Address: destination})
+//	state, err := chain.GetAccountState(ctx, destination)
+package blockchain
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/tonkeeper/tongo/abi"
+	"github.com/tonkeeper/tongo/boc"
+	codePkg "github.com/tonkeeper/tongo/code"
+	"github.com/tonkeeper/tongo/liteapi"
+	"github.com/tonkeeper/tongo/tep64"
+	"github.com/tonkeeper/tongo/tlb"
+	"github.com/tonkeeper/tongo/ton"
+	"github.com/tonkeeper/tongo/tvm"
+	"github.com/tonkeeper/tongo/txemulator"
+	"github.com/tonkeeper/tongo/utils"
+)
+
+// exitCodeAccountNotFound is an exit code of get methods of accounts without code, it is returned by lite servers too.
+const exitCodeAccountNotFound = 0xFFFFFF00
+
+// Blockchain is a local blockchain keeping states of accounts in memory.
+// Every message sent to the blockchain is executed in a new block together with all internal messages it produces,
+// so the blockchain never has messages in flight.
+// Logical time of transactions is chosen by the emulator after the last transaction of an account.
+// Unix time doesn't change by itself, call Advance to move it forward.
+type Blockchain struct {
+	config         string
+	limit          int
+	checkSignature bool
+	libraries      map[ton.Bits256]*boc.Cell
+
+	// mu protects fields below.
+	mu       sync.Mutex
+	accounts map[ton.AccountID]tlb.ShardAccount
+	// transactions are ordered by lt for every account:
+	// Process sorts a trace by lt and the emulator gives every transaction a bigger lt than the account's last transaction has.
+	transactions map[ton.AccountID][]ton.Transaction
+	now          time.Time
+	seqno        uint32
+}
+
+type Options struct {
+	config         string
+	limit          int
+	checkSignature bool
+	now            time.Time
+	accounts       []tlb.ShardAccount
+	libraries      map[ton.Bits256]*boc.Cell
+}
+
+type Option func(o *Options) error
+
+// WithAccounts puts accounts to the blockchain.
+func WithAccounts(accounts ...tlb.ShardAccount) Option {
+	return func(o *Options) error {
+		o.accounts = append(o.accounts, accounts...)
+		return nil
+	}
+}
+
+// WithLibraries sets public libraries available to contracts.
+func WithLibraries(libraries map[ton.Bits256]*boc.Cell) Option {
+	return func(o *Options) error {
+		for hash, cell := range libraries {
+			o.libraries[hash] = cell
+		}
+		return nil
+	}
+}
+
+// WithConfigBase64 sets a blockchain config, txemulator.DefaultConfig is used by default.
+func WithConfigBase64(c string) Option {
+	return func(o *Options) error {
+		o.config = c
+		return nil
+	}
+}
+
+// WithTime sets the initial unix time of the blockchain, the current time is used by default.
+func WithTime(t time.Time) Option {
+	return func(o *Options) error {
+		o.now = t
+		return nil
+	}
+}
+
+// WithLimit limits a number of transactions a single message can produce.
+func WithLimit(l int) Option {
+	return func(o *Options) error {
+		o.limit = l
+		return nil
+	}
+}
+
+// WithSignatureCheck enables checking of signatures, they are not checked by default.
+func WithSignatureCheck() Option {
+	return func(o *Options) error {
+		o.checkSignature = true
+		return nil
+	}
+}
+
+func New(opts ...Option) (*Blockchain, error) {
+	options := Options{
+		config:    txemulator.DefaultConfig,
+		limit:     100,
+		now:       time.Now(),
+		libraries: make(map[ton.Bits256]*boc.Cell),
+	}
+	for _, o := range opts {
+		if err := o(&options); err != nil {
+			return nil, err
+		}
+	}
+	b := &Blockchain{
+		config:         options.config,
+		limit:          options.limit,
+		checkSignature: options.checkSignature,
+		libraries:      options.libraries,
+		accounts:       make(map[ton.AccountID]tlb.ShardAccount),
+		transactions:   make(map[ton.AccountID][]ton.Transaction),
+		now:            options.now,
+	}
+	if err := b.SetAccounts(options.accounts...); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// SetAccounts replaces states of accounts, e.g. to top up their balances.
+func (b *Blockchain) SetAccounts(accounts ...tlb.ShardAccount) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for _, a := range accounts {
+		if a.Account.SumType != "Account" {
+			return fmt.Errorf("account without address can't be added")
+		}
+		id, err := ton.AccountIDFromTlb(a.Account.Account.Addr)
+		if err != nil {
+			return err
+		}
+		if id == nil {
+			return fmt.Errorf("account without address can't be added")
+		}
+		b.accounts[*id] = a
+	}
+	return nil
+}
+
+// Now returns the current unix time of the blockchain.
+func (b *Blockchain) Now() time.Time {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.now
+}
+
+// Advance moves the blockchain's time forward.
+func (b *Blockchain) Advance(d time.Duration) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.now = b.now.Add(d)
+}
+
+// SendMessage executes an external message serialized to a BOC, it is an equivalent of liteapi.Client.SendMessage.
+// The message is rejected if the destination contract doesn't accept it.
+func (b *Blockchain) SendMessage(ctx context.Context, payload []byte) (uint32, error) {
+	if err := liteapi.VerifySendMessagePayload(payload); err != nil {
+		return 0, err
+	}
+	cell, err := boc.DeserializeSingleRootBoc(payload)
+	if err != nil {
+		return 0, err
+	}
+	var msg tlb.Message
+	if err := tlb.Unmarshal(cell, &msg); err != nil {
+		return 0, err
+	}
+	if _, err := b.Process(ctx, msg); err != nil {
+		return 0, err
+	}
+	return 1, nil
+}
+
+// Process executes a message and all internal messages it produces.
+// Unlike SendMessage, it accepts internal messages, so they can be sent from accounts which are not in the blockchain.
+// If any of the messages fails to be executed, states of accounts are not changed.
+func (b *Blockchain) Process(ctx context.Context, msg tlb.Message) (*txemulator.TxTree, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	accounts := make(map[ton.AccountID]tlb.ShardAccount, len(b.accounts))
+	for id, state := range b.accounts {
+		accounts[id] = state
+	}
+	opts := []txemulator.TraceOption{
+		txemulator.WithConfigBase64(b.config),
+		txemulator.WithLimit(b.limit),
+		txemulator.WithTime(b.now.Unix()),
+		txemulator.WithAccountsMap(accounts),
+		txemulator.WithAccountsSource(source{b}),
+	}
+	if b.checkSignature {
+		opts = append(opts, txemulator.WithSignatureCheck())
+	}
+	tracer, err := txemulator.NewTraceBuilder(opts...)
+	if err != nil {
+		return nil, err
+	}
+	tree, err := tracer.Run(ctx, msg)
+	if err != nil {
+		return nil, err
+	}
+	var txs []ton.Transaction
+	if err := collectTransactions(tree, &txs); err != nil {
+		return nil, err
+	}
+	sort.SliceStable(txs, func(i, j int) bool { return txs[i].Lt < txs[j].Lt })
+	b.seqno++
+	for _, tx := range txs {
+		id := ton.AccountID{Workchain: tx.BlockID.Workchain, Address: tx.AccountAddr}
+		tx.BlockID.Seqno = b.seqno
+		b.transactions[id] = append(b.transactions[id], tx)
+	}
+	for id, state := range tracer.FinalStates() {
+		b.accounts[id] = state
+	}
+	return tree, nil
+}
+
+// collectTransactions walks the tree and appends its transactions to txs.
+func collectTransactions(tree *txemulator.TxTree, txs *[]ton.Transaction) error {
+	if tree == nil {
+		return nil
+	}
+	id, err := transactionAccount(tree.TX)
+	if err != nil {
+		return err
+	}
+	*txs = append(*txs, ton.Transaction{
+		Transaction: tree.TX,
+		BlockID: ton.BlockIDExt{BlockID: ton.BlockID{
+			Workchain: id.Workchain,
+			Shard:     0x8000000000000000,
+		}},
+	})
+	for _, child := range tree.Children {
+		if err := collectTransactions(child, txs); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// transactionAccount returns an account of the transaction, it is the destination of the inbound message.
+func transactionAccount(tx tlb.Transaction) (ton.AccountID, error) {
+	if !tx.Msgs.InMsg.Exists {
+		return ton.AccountID{}, fmt.Errorf("transaction without inbound message")
+	}
+	var dest tlb.MsgAddress
+	info := tx.Msgs.InMsg.Value.Value.Info
+	switch info.SumType {
+	case "IntMsgInfo":
+		dest = info.IntMsgInfo.Dest
+	case "ExtInMsgInfo":
+		dest = info.ExtInMsgInfo.Dest
+	default:
+		return ton.AccountID{}, fmt.Errorf("unexpected inbound message: %v", info.SumType)
+	}
+	id, err := ton.AccountIDFromTlb(dest)
+	if err != nil {
+		return ton.AccountID{}, err
+	}
+	if id == nil {
+		return ton.AccountID{}, fmt.Errorf("destination account is null")
+	}
+	return *id, nil
+}
+
+// GetAccountState returns a state of the account, accounts which are not in the blockchain are returned as AccountNone.
+func (b *Blockchain) GetAccountState(ctx context.Context, accountID ton.AccountID) (tlb.ShardAccount, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.accountState(accountID), nil
+}
+
+func (b *Blockchain) accountState(accountID ton.AccountID) tlb.ShardAccount {
+	state, ok := b.accounts[accountID]
+	if !ok {
+		return tlb.ShardAccount{Account: tlb.Account{SumType: "AccountNone"}}
+	}
+	return state
+}
+
+// GetTransactions returns up to count transactions of the account starting from the transaction with the given lt and hash
+// and going back in time.
+func (b *Blockchain) GetTransactions(ctx context.Context, count uint32, accountID ton.AccountID, lt uint64, hash ton.Bits256) ([]ton.Transaction, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	txs := b.transactions[accountID]
+	for i := len(txs) - 1; i >= 0; i-- {
+		if txs[i].Lt != lt || ton.Bits256(txs[i].Hash()) != hash {
+			continue
+		}
+		res := make([]ton.Transaction, 0, count)
+		for ; i >= 0 && len(res) < int(count); i-- {
+			res = append(res, txs[i])
+		}
+		return res, nil
+	}
+	return nil, fmt.Errorf("transaction not found")
+}
+
+// GetLibraries returns public libraries which are set with WithLibraries.
+func (b *Blockchain) GetLibraries(ctx context.Context, libraryList []ton.Bits256) (map[ton.Bits256]*boc.Cell, error) {
+	libs := make(map[ton.Bits256]*boc.Cell, len(libraryList))
+	for _, hash := range libraryList {
+		if cell, ok := b.libraries[hash]; ok {
+			libs[hash] = cell
+		}
+	}
+	return libs, nil
+}
+
+// RunSmcMethodByID runs a get method of the account at the current state of the blockchain.
+// For accounts without code, it returns liteapi.ErrAccountNotFound like liteapi.Client does.
+func (b *Blockchain) RunSmcMethodByID(ctx context.Context, accountID ton.AccountID, methodID int, params tlb.VmStack) (uint32, tlb.VmStack, error) {
+	b.mu.Lock()
+	state := b.accountState(accountID)
+	now := b.now
+	b.mu.Unlock()
+	if state.Account.SumType != "Account" || state.Account.Account.Storage.State.SumType != "AccountActive" {
+		return exitCodeAccountNotFound, nil, liteapi.ErrAccountNotFound
+	}
+	init := state.Account.Account.Storage.State.AccountActive.StateInit
+	if !init.Code.Exists || !init.Data.Exists {
+		return exitCodeAccountNotFound, nil, liteapi.ErrAccountNotFound
+	}
+	code, data := init.Code.Value.Value, init.Data.Value.Value
+	opts := []tvm.Option{
+		tvm.WithBalance(int64(state.Account.Account.Storage.Balance.Grams)),
+		tvm.WithLibraryResolver(b),
+		tvm.WithUnixTime(uint32(now.Unix())),
+	}
+	hashes, err := codePkg.FindLibraries(&code)
+	if err != nil {
+		return 0, nil, err
+	}
+	if len(hashes) > 0 {
+		libs, err := b.GetLibraries(ctx, hashes)
+		if err != nil {
+			return 0, nil, err
+		}
+		libsBoc, err := codePkg.LibrariesToBase64(libs)
+		if err != nil {
+			return 0, nil, err
+		}
+		opts = append(opts, tvm.WithLibrariesBase64(libsBoc))
+	}
+	codeBoc, err := code.ToBocBase64()
+	if err != nil {
+		return 0, nil, err
+	}
+	dataBoc, err := data.ToBocBase64()
+	if err != nil {
+		return 0, nil, err
+	}
+	emulator, err := tvm.NewEmulatorFromBOCsBase64(codeBoc, dataBoc, b.config, opts...)
+	if err != nil {
+		return 0, nil, err
+	}
+	return emulator.RunSmcMethodByID(ctx, accountID, methodID, params)
+}
+
+func (b *Blockchain) RunSmcMethod(ctx context.Context, accountID ton.AccountID, method string, params tlb.VmStack) (uint32, tlb.VmStack, error) {
+	return b.RunSmcMethodByID(ctx, accountID, utils.MethodIdFromName(method), params)
+}
+
+// GetSeqno returns a seqno of a wallet, it is zero for wallets which are not deployed yet.
+func (b *Blockchain) GetSeqno(ctx context.Context, account ton.AccountID) (uint32, error) {
+	errCode, stack, err := b.RunSmcMethod(ctx, account, "seqno", tlb.VmStack{})
+	if errors.Is(err, liteapi.ErrAccountNotFound) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	if errCode != 0 && errCode != 1 {
+		return 0, fmt.Errorf("method execution failed with code: %v", errCode)
+	}
+	if len(stack) != 1 || stack[0].SumType != "VmStkTinyInt" {
+		return 0, fmt.Errorf("invalid stack")
+	}
+	return uint32(stack[0].VmStkTinyInt), nil
+}
+
+// GetJettonWallet returns an address of the owner's jetton wallet.
+func (b *Blockchain) GetJettonWallet(ctx context.Context, master, owner ton.AccountID) (ton.AccountID, error) {
+	_, v, err := abi.GetWalletAddress(ctx, b, master, owner.ToMsgAddress())
+	if err != nil {
+		return ton.AccountID{}, err
+	}
+	res, ok := v.(abi.GetWalletAddressResult)
+	if !ok {
+		return ton.AccountID{}, fmt.Errorf("unexpected get_wallet_address result: %T", v)
+	}
+	addr, err := ton.AccountIDFromTlb(res.JettonWalletAddress)
+	if err != nil {
+		return ton.AccountID{}, err
+	}
+	if addr == nil {
+		return ton.AccountID{}, fmt.Errorf("address none")
+	}
+	return *addr, nil
+}
+
+// GetJettonData returns metadata of a jetton, only onchain metadata is supported like in liteapi.Client.
+func (b *Blockchain) GetJettonData(ctx context.Context, master ton.AccountID) (tep64.Metadata, error) {
+	_, v, err := abi.GetJettonData(ctx, b, master)
+	if err != nil {
+		return tep64.Metadata{}, err
+	}
+	res, ok := v.(abi.GetJettonDataResult)
+	if !ok {
+		return tep64.Metadata{}, fmt.Errorf("unexpected get_jetton_data result: %T", v)
+	}
+	cell := boc.Cell(res.JettonContent)
+	var content tlb.FullContent
+	if err := tlb.Unmarshal(&cell, &content); err != nil {
+		return tep64.Metadata{}, err
+	}
+	if content.SumType != "Onchain" {
+		return tep64.Metadata{}, liteapi.ErrOnchainContentOnly
+	}
+	return tep64.ConvertOnchainData(content)
+}
+
+// GetJettonBalance returns a balance of a jetton wallet, it is zero for wallets which are not deployed yet.
+func (b *Blockchain) GetJettonBalance(ctx context.Context, jettonWallet ton.AccountID) (*big.Int, error) {
+	_, v, err := abi.GetWalletData(ctx, b, jettonWallet)
+	if errors.Is(err, liteapi.ErrAccountNotFound) {
+		return big.NewInt(0), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	res, ok := v.(abi.GetWalletDataResult)
+	if !ok {
+		return nil, fmt.Errorf("unexpected get_wallet_data result: %T", v)
+	}
+	balance := big.Int(res.Balance)
+	return &balance, nil
+}
+
+// source gives the tracer access to the blockchain while Process holds the lock.
+type source struct {
+	b *Blockchain
+}
+
+func (s source) GetAccountState(ctx context.Context, accountID ton.AccountID) (tlb.ShardAccount, error) {
+	return s.b.accountState(accountID), nil
+}
+
+func (s source) GetLibraries(ctx context.Context, libraryList []ton.Bits256) (map[ton.Bits256]*boc.Cell, error) {
+	return s.b.GetLibraries(ctx, libraryList)
+}
@@ -0,0 +1,300 @@
+package blockchain
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"math/big"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/tonkeeper/tongo/abi"
+	"github.com/tonkeeper/tongo/boc"
+	"github.com/tonkeeper/tongo/contract/jetton"
+	"github.com/tonkeeper/tongo/tlb"
+	"github.com/tonkeeper/tongo/ton"
+	"github.com/tonkeeper/tongo/tontest"
+	"github.com/tonkeeper/tongo/wallet"
+)
+
+const SEED = "way label strategy scheme park virtual walnut illegal fringe once state defense museum bone satoshi feel diary buddy notice solve moral maple video local"
+
+const (
+	// jettonMasterCode implements get_jetton_data and get_wallet_address of the standard jetton minter only,
+	// jettons are minted by sending internal_transfer from the master address.
+	jettonMasterCode = "te6ccgEBAwEAXAACICCCAZ4tuuMCIIIBk3m64wIBAgAaMO1E0PoAfwH6QNTUMABuMO1E0PoAMfpAMdQx1DDIcPoCIs8W+CjPFiHPFMl2yMsEWM8UAc8UyfkAdMjLAnABygfL/8nQMQ=="
+	// jettonWalletCode is the code of the standard jetton wallet.
+	jettonWalletCode = "te6ccgECEQEAAyMAART/APSkE/S88sgLAQIBYgIDAgLMBAUAG6D2BdqJofQB9IH0gahhAgHUBgcCASAICQDDCDHAJJfBOAB0NMDAXGwlRNfA/AM4PpA+kAx+gAxcdch+gAx+gAwc6m0AALTH4IQD4p+pVIgupUxNFnwCeCCEBeNRRlSILqWMUREA/AK4DWCEFlfB7y6k1nwC+BfBIQP8vCAAET6RDBwuvLhTYAIBIAoLAIPUAQa5D2omh9AH0gfSBqGAJpj8EIC8aijKkQXUEIPe7L7wndCVj5cWLpn5j9ABgJ0CgR5CgCfQEsZ4sA54tmZPaqQB8VA9M/+gD6QCHwAe1E0PoA+kD6QNQwUTahUirHBfLiwSjC//LiwlQ0QnBUIBNUFAPIUAT6AljPFgHPFszJIsjLARL0APQAywDJIPkAcHTIywLKB8v/ydAE+kD0BDH6ACDXScIA8uLEd4AYyMsFUAjPFnD6AhfLaxPMgMAgEgDQ4AnoIQF41FGcjLHxnLP1AH+gIizxZQBs8WJfoCUAPPFslQBcwjkXKRceJQCKgToIIJycOAoBS88uLFBMmAQPsAECPIUAT6AljPFgHPFszJ7VQC9ztRND6APpA+kDUMAjTP/oAUVGgBfpA+kBTW8cFVHNtcFQgE1QUA8hQBPoCWM8WAc8WzMkiyMsBEvQA9ADLAMn5AHB0yMsCygfL/8nQUA3HBRyx8uLDCvoAUaihggiYloBmtgihggiYloCgGKEnlxBJEDg3XwTjDSXXCwGAPEADXO1E0PoA+kD6QNQwB9M/+gD6QDBRUaFSSccF8uLBJ8L/8uLCBYIJMS0AoBa88uLDghB73ZfeyMsfFcs/UAP6AiLPFgHPFslxgBjIywUkzxZw+gLLaszJgED7AEATyFAE+gJYzxYBzxbMye1UgAHBSeaAYoYIQc2LQnMjLH1Iwyz9Y+gJQB88WUAfPFslxgBDIywUkzxZQBvoCFctqFMzJcfsAECQQIwB8wwAjwgCwjiGCENUydttwgBDIywVQCM8WUAT6AhbLahLLHxLLP8ly+wCTNWwh4gPIUAT6AljPFgHPFszJ7VQ="
+)
+
+func TestBlockchain_SendMessage(t *testing.T) {
+	ctx := context.Background()
+	chain, err := New(WithTime(time.Now()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	w, err := wallet.DefaultWalletFromSeed(SEED, chain)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = chain.SetAccounts(tontest.Account().
+		Address(w.GetAddress()).
+		Balance(ton.OneTON * 10).
+		State(tlb.AccountUninit).
+		MustShardAccount())
+	if err != nil {
+		t.Fatal(err)
+	}
+	recipient := ton.MustParseAccountID("0:e2a1dc1ab3b4d3b5ee1e0bd6b3d2dca8d9a3c3bd5dba2c3a4d1b3f6a5e4c3b2a")
+
+	for i := 0; i < 2; i++ {
+		chain.Advance(time.Minute)
+		err = w.Send(ctx, wallet.SimpleTransfer{Amount: ton.OneTON, Address: recipient})
+		if err != nil {
+			t.Fatalf("Send() failed: %v", err)
+		}
+	}
+	seqno, err := chain.GetSeqno(ctx, w.GetAddress())
+	if err != nil {
+		t.Fatalf("GetSeqno() failed: %v", err)
+	}
+	if seqno != 2 {
+		t.Fatalf("want seqno 2, got %v", seqno)
+	}
+	state, err := chain.GetAccountState(ctx, recipient)
+	if err != nil {
+		t.Fatalf("GetAccountState() failed: %v", err)
+	}
+	if state.Account.SumType != "Account" || state.Account.Account.Storage.Balance.Grams < ton.OneTON*19/10 {
+		t.Fatalf("recipient didn't receive coins: %+v", state.Account)
+	}
+	txs, err := chain.GetTransactions(ctx, 10, recipient, state.LastTransLt, ton.Bits256(state.LastTransHash))
+	if err != nil {
+		t.Fatalf("GetTransactions() failed: %v", err)
+	}
+	if len(txs) != 2 || txs[0].Lt <= txs[1].Lt || txs[0].BlockID.Seqno != 2 {
+		t.Fatalf("unexpected transactions: %v", len(txs))
+	}
+	if _, err := chain.GetSeqno(ctx, recipient); err != nil {
+		t.Fatalf("GetSeqno() of uninit account failed: %v", err)
+	}
+
+	// the wallet rejects a message with the same seqno, so nothing changes
+	walletState, err := chain.GetAccountState(ctx, w.GetAddress())
+	if err != nil {
+		t.Fatalf("GetAccountState() failed: %v", err)
+	}
+	if _, err := w.RawSendV2(ctx, 1, time.Now().Add(time.Minute), nil, nil, 0); err == nil {
+		t.Fatalf("message with an old seqno must be rejected")
+	}
+	newState, err := chain.GetAccountState(ctx, w.GetAddress())
+	if err != nil {
+		t.Fatalf("GetAccountState() failed: %v", err)
+	}
+	if newState.LastTransLt != walletState.LastTransLt {
+		t.Fatalf("rejected message changed the state")
+	}
+}
+
+type jettonMasterData struct {
+	TotalSupply tlb.VarUInteger16
+	Admin       tlb.MsgAddress
+	Content     tlb.FullContent `tlb:"^"`
+}
+
+type jettonWalletData struct {
+	Balance tlb.VarUInteger16
+	Owner   tlb.MsgAddress
+	Master  tlb.MsgAddress
+}
+
+func onchainContent(metadata map[string]string) tlb.FullContent {
+	var keys []tlb.Bits256
+	for key := range metadata {
+		keys = append(keys, sha256.Sum256([]byte(key)))
+	}
+	// a hashmap is encoded from sorted keys
+	sort.Slice(keys, func(i, j int) bool { return bytes.Compare(keys[i][:], keys[j][:]) < 0 })
+	values := make([]tlb.Ref[tlb.ContentData], len(keys))
+	for key, value := range metadata {
+		bits := boc.NewBitString(len(value) * 8)
+		if err := bits.WriteBytes([]byte(value)); err != nil {
+			panic(err)
+		}
+		hash := tlb.Bits256(sha256.Sum256([]byte(key)))
+		i := sort.Search(len(keys), func(i int) bool { return bytes.Compare(keys[i][:], hash[:]) >= 0 })
+		values[i].Value.SumType = "Snake"
+		values[i].Value.Snake.Data = tlb.SnakeData(bits)
+	}
+	var content tlb.FullContent
+	content.SumType = "Onchain"
+	content.Onchain.Data = tlb.NewHashmapE(keys, values)
+	return content
+}
+
+func TestBlockchain_Jetton(t *testing.T) {
+	ctx := context.Background()
+	masterCode, err := boc.DeserializeSinglRootBase64(jettonMasterCode)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// wallets use a library cell as their code like most of jettons do
+	library, err := boc.DeserializeSinglRootBase64(jettonWalletCode)
+	if err != nil {
+		t.Fatal(err)
+	}
+	libraryHash, err := library.Hash256()
+	if err != nil {
+		t.Fatal(err)
+	}
+	walletCode := boc.NewCellExotic(boc.LibraryCell)
+	if err := walletCode.WriteUint(2, 8); err != nil {
+		t.Fatal(err)
+	}
+	if err := walletCode.WriteBytes(libraryHash[:]); err != nil {
+		t.Fatal(err)
+	}
+
+	admin := ton.MustParseAccountID("0:e2a1dc1ab3b4d3b5ee1e0bd6b3d2dca8d9a3c3bd5dba2c3a4d1b3f6a5e4c3b2a")
+	owner := ton.MustParseAccountID("0:a2a1dc1ab3b4d3b5ee1e0bd6b3d2dca8d9a3c3bd5dba2c3a4d1b3f6a5e4c3b2a")
+	recipient := ton.MustParseAccountID("0:b2a1dc1ab3b4d3b5ee1e0bd6b3d2dca8d9a3c3bd5dba2c3a4d1b3f6a5e4c3b2a")
+
+	masterData := boc.NewCell()
+	err = tlb.Marshal(masterData, jettonMasterData{
+		Admin:   admin.ToMsgAddress(),
+		Content: onchainContent(map[string]string{"name": "Test Jetton", "symbol": "TEST", "decimals": "6"}),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := masterData.AddRef(walletCode); err != nil {
+		t.Fatal(err)
+	}
+	masterState := tontest.Account().
+		Balance(ton.OneTON).
+		State(tlb.AccountActive).
+		StateInit(masterCode, masterData).
+		MustShardAccount()
+	master := ton.AccountID{Address: masterState.Account.Account.Addr.AddrStd.Address}
+
+	chain, err := New(
+		WithTime(time.Now()),
+		WithAccounts(masterState),
+		WithLibraries(map[ton.Bits256]*boc.Cell{libraryHash: library}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	libs, err := chain.GetLibraries(ctx, []ton.Bits256{libraryHash})
+	if err != nil {
+		t.Fatalf("GetLibraries() failed: %v", err)
+	}
+	if len(libs) != 1 || libs[libraryHash] != library {
+		t.Fatalf("library not found")
+	}
+	metadata, err := chain.GetJettonData(ctx, master)
+	if err != nil {
+		t.Fatalf("GetJettonData() failed: %v", err)
+	}
+	if metadata.Name != "Test Jetton" || metadata.Symbol != "TEST" || metadata.Decimals != "6" {
+		t.Fatalf("unexpected metadata: %+v", metadata)
+	}
+	j := jetton.New(master, chain)
+	ownerWallet, err := j.GetJettonWallet(ctx, owner)
+	if err != nil {
+		t.Fatalf("GetJettonWallet() failed: %v", err)
+	}
+	balance, err := chain.GetJettonBalance(ctx, ownerWallet)
+	if err != nil {
+		t.Fatalf("GetJettonBalance() failed: %v", err)
+	}
+	if balance.Sign() != 0 {
+		t.Fatalf("want zero balance of not deployed wallet, got %v", balance)
+	}
+
+	// mint: the master deploys the owner's wallet with internal_transfer
+	walletData := boc.NewCell()
+	err = tlb.Marshal(walletData, jettonWalletData{Owner: owner.ToMsgAddress(), Master: master.ToMsgAddress()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := walletData.AddRef(walletCode); err != nil {
+		t.Fatal(err)
+	}
+	body := boc.NewCell()
+	if err := body.WriteUint(0x178d4519, 32); err != nil {
+		t.Fatal(err)
+	}
+	err = tlb.Marshal(body, abi.JettonInternalTransferMsgBody{
+		Amount:          tlb.VarUInteger16(*big.NewInt(1000)),
+		From:            master.ToMsgAddress(),
+		ResponseAddress: admin.ToMsgAddress(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg, _, err := wallet.Message{
+		Amount:  ton.OneTON / 10,
+		Address: ownerWallet,
+		Bounce:  true,
+		Body:    body,
+		Code:    walletCode,
+		Data:    walletData,
+	}.ToInternal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg.Info.IntMsgInfo.Src = master.ToMsgAddress()
+	if _, err := chain.Process(ctx, msg); err != nil {
+		t.Fatalf("Process() failed: %v", err)
+	}
+	balance, err = j.GetBalance(ctx, owner)
+	if err != nil {
+		t.Fatalf("GetBalance() failed: %v", err)
+	}
+	if balance.Int64() != 1000 {
+		t.Fatalf("want minted balance 1000, got %v", balance)
+	}
+
+	// transfer: the owner sends jettons to the recipient, the recipient's wallet is deployed by the owner's one
+	msg, _, err = jetton.TransferMessage{
+		Jetton:              j,
+		Sender:              owner,
+		JettonAmount:        big.NewInt(300),
+		Destination:         recipient,
+		ResponseDestination: &owner,
+		AttachedTon:         ton.OneTON / 2,
+		ForwardTonAmount:    1,
+	}.ToInternal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg.Info.IntMsgInfo.Src = owner.ToMsgAddress()
+	tree, err := chain.Process(ctx, msg)
+	if err != nil {
+		t.Fatalf("Process() failed: %v", err)
+	}
+	if len(tree.Children) != 1 || len(tree.Children[0].Children) != 2 {
+		t.Fatalf("want transfer, notification and excess messages")
+	}
+	for account, want := range map[ton.AccountID]int64{owner: 700, recipient: 300} {
+		balance, err := j.GetBalance(ctx, account)
+		if err != nil {
+			t.Fatalf("GetBalance() failed: %v", err)
+		}
+		if balance.Int64() != want {
+			t.Fatalf("want balance %v of %v, got %v", want, account, balance)
+		}
+	}
+	state, err := chain.GetAccountState(ctx, ownerWallet)
+	if err != nil {
+		t.Fatalf("GetAccountState() failed: %v", err)
+	}
+	txs, err := chain.GetTransactions(ctx, 10, ownerWallet, state.LastTransLt, ton.Bits256(state.LastTransHash))
+	if err != nil {
+		t.Fatalf("GetTransactions() failed: %v", err)
+	}
+	if len(txs) != 2 || txs[0].Lt <= txs[1].Lt {
+		t.Fatalf("unexpected transactions: %v", len(txs))
+	}
+}
@@ -36,6 +36,7 @@ type Emulator struct {
 	c7Set              bool
 	libResolver        libResolver
 	ignoreLibraryCells bool
+	unixTime           uint32
 }
 
 type Config struct {
@@ -51,6 +52,7 @@ type Options struct {
 	libResolver        libResolver
 	ignoreLibraryCells bool
 	config             *Config
+	unixTime           uint32
 }
 
 type Option func(o *Options)
@@ -99,6 +101,13 @@ func WithLibraryResolver(resolver libResolver) Option {
 	}
 }
 
+// WithUnixTime sets a unix time get methods see in C7, by default the current time is used.
+func WithUnixTime(unixTime uint32) Option {
+	return func(o *Options) {
+		o.unixTime = unixTime
+	}
+}
+
 func WithIgnoreLibraryCells(ignore bool) Option {
 	return func(o *Options) {
 		o.ignoreLibraryCells = ignore
@@ -161,6 +170,7 @@ func NewEmulatorFromBOCsBase64(code, data, config string, opts ...Option) (*Emul
 		balance:            uint64(options.balance),
 		libResolver:        options.libResolver,
 		ignoreLibraryCells: options.ignoreLibraryCells,
+		unixTime:           options.unixTime,
 	}
 	if len(options.libraries) > 0 {
 		if err := e.setLibs(options.libraries); err != nil {
@@ -313,7 +323,7 @@ func (e *Emulator) RunSmcMethod(ctx context.Context, accountId ton.AccountID, me
 
 func (e *Emulator) RunSmcMethodByID(ctx context.Context, accountId ton.AccountID, methodID int, params tlb.VmStack) (uint32, tlb.VmStack, error) {
 	if !e.lazyC7 && !e.c7Set {
-		err := e.setC7(accountId.ToRaw(), uint32(time.Now().Unix()))
+		err := e.setC7(accountId.ToRaw(), e.now())
 		if err != nil {
 			return 0, tlb.VmStack{}, err
 		}
@@ -323,7 +333,7 @@ func (e *Emulator) RunSmcMethodByID(ctx context.Context, accountId ton.AccountID
 		return 0, tlb.VmStack{}, err
 	}
 	if res.Success && res.VmExitCode != 0 && res.VmExitCode != 1 && e.lazyC7 && !e.c7Set {
-		err = e.setC7(accountId.ToRaw(), uint32(time.Now().Unix()))
+		err = e.setC7(accountId.ToRaw(), e.now())
 		if err != nil {
 			return 0, tlb.VmStack{}, err
 		}
@@ -367,6 +377,13 @@ func (e *Emulator) RunSmcMethodByID(ctx context.Context, accountId ton.AccountID
 	return uint32(res.VmExitCode), stack, nil
 }
 
+func (e *Emulator) now() uint32 {
+	if e.unixTime != 0 {
+		return e.unixTime
+	}
+	return uint32(time.Now().Unix())
+}
+
 func (e *Emulator) runGetMethod(methodID int, params tlb.VmStack) (result, error) {
 	stack := boc.NewCell()
 	err := tlb.Marshal(stack, params)